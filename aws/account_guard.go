@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+
+	"rolewalkers/internal/db"
+)
+
+// AccountMismatchError is returned when the active profile is in a
+// different AWS account than the environment an operation targets, e.g.
+// switched to the prod profile but passed dev
+type AccountMismatchError struct {
+	Env      string
+	Expected string
+	Profile  string
+	Actual   string
+}
+
+func (e *AccountMismatchError) Error() string {
+	return fmt.Sprintf("account mismatch: %s is in account %s, but the active profile %s is in account %s\n"+
+		"Switch to a profile for %s ('rw switch'), or if the environment's account is wrong, fix it with 'rw env account %s <account-id>'",
+		e.Env, e.Expected, e.Profile, e.Actual, e.Env, e.Env)
+}
+
+// ActiveProfileName returns the profile AWS CLI calls will use: $AWS_PROFILE
+// when set, otherwise rw's active profile
+func ActiveProfileName(pp ProfileProvider) string {
+	return cmp.Or(os.Getenv("AWS_PROFILE"), pp.GetActiveProfile())
+}
+
+// VerifyEnvironmentAccount fails with an *AccountMismatchError when the
+// active profile's account differs from the account recorded for env. It
+// is a local check (no AWS calls) run before destructive operations.
+// Environments without a recorded account and profiles whose account isn't
+// in ~/.aws/config are not checked.
+func VerifyEnvironmentAccount(pp ProfileProvider, repo *db.ConfigRepository, env string) error {
+	if repo == nil {
+		return nil
+	}
+	expected, err := repo.GetEnvironmentAccount(env)
+	if err != nil || expected == "" {
+		return nil
+	}
+
+	profiles, err := pp.GetProfiles()
+	if err != nil {
+		return err
+	}
+	name := ActiveProfileName(pp)
+	profile, err := FindProfileByName(profiles, name)
+	if err != nil {
+		return nil
+	}
+	return checkAccount(env, expected, profile)
+}
+
+func checkAccount(env, expected string, profile *Profile) error {
+	actual := profile.AccountID()
+	if actual == "" || actual == expected {
+		return nil
+	}
+	return &AccountMismatchError{Env: env, Expected: expected, Profile: profile.Name, Actual: actual}
+}
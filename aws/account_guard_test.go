@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"errors"
+	"iter"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestProfileAccountID(t *testing.T) {
+	tests := []struct {
+		profile Profile
+		want    string
+	}{
+		{Profile{SSOAccountID: "111111111111"}, "111111111111"},
+		{Profile{RoleARN: "arn:aws:iam::222222222222:role/deploy"}, "222222222222"},
+		{Profile{RoleARN: "not-an-arn"}, ""},
+		{Profile{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.AccountID(); got != tt.want {
+			t.Errorf("AccountID() for %+v = %q, want %q", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAccount(t *testing.T) {
+	prod := &Profile{Name: "zenith-prod", SSOAccountID: "999999999999"}
+
+	if err := checkAccount("prod", "999999999999", prod); err != nil {
+		t.Errorf("checkAccount() with matching accounts = %v, want nil", err)
+	}
+	if err := checkAccount("dev", "111111111111", &Profile{Name: "keys-only"}); err != nil {
+		t.Errorf("checkAccount() for a profile without an account = %v, want nil", err)
+	}
+
+	err := checkAccount("dev", "111111111111", prod)
+	var mismatch *AccountMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("checkAccount() = %v, want *AccountMismatchError", err)
+	}
+	if mismatch.Profile != "zenith-prod" || mismatch.Actual != "999999999999" || mismatch.Expected != "111111111111" {
+		t.Errorf("mismatch = %+v", mismatch)
+	}
+}
+
+// guardProfiles is a ProfileProvider over a fixed list of profiles
+type guardProfiles struct {
+	profiles []Profile
+	active   string
+}
+
+func (g guardProfiles) GetProfiles() ([]Profile, error) { return g.profiles, nil }
+func (g guardProfiles) Profiles() iter.Seq2[Profile, error] {
+	return func(yield func(Profile, error) bool) {
+		for _, p := range g.profiles {
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+func (g guardProfiles) GetActiveProfile() string { return g.active }
+func (g guardProfiles) NotifyChanged(func())     {}
+
+func TestVerifyEnvironmentAccountGuardsSyncedEnvironment(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	t.Setenv("AWS_PROFILE", "")
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	if err := repo.AddAWSAccount("000000000081", "guarded", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, err := repo.GetAWSAccount("000000000081")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	if err := repo.AddAWSRole(account.ID, "Admin", "", "guarded-admin", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSRole() error: %v", err)
+	}
+	if err := repo.AddEnvironment("guarded", "Guarded", "eu-west-2", "guarded-admin", "guarded-eks"); err != nil {
+		t.Fatalf("AddEnvironment() error: %v", err)
+	}
+
+	pp := guardProfiles{
+		profiles: []Profile{
+			{Name: "guarded-admin", SSOAccountID: "000000000081"},
+			{Name: "other-admin", SSOAccountID: "000000000082"},
+		},
+		active: "other-admin",
+	}
+	var mismatch *AccountMismatchError
+	if err := VerifyEnvironmentAccount(pp, repo, "guarded"); !errors.As(err, &mismatch) {
+		t.Errorf("VerifyEnvironmentAccount() from another account = %v, want *AccountMismatchError", err)
+	}
+	pp.active = "guarded-admin"
+	if err := VerifyEnvironmentAccount(pp, repo, "guarded"); err != nil {
+		t.Errorf("VerifyEnvironmentAccount() from the environment's account = %v, want nil", err)
+	}
+}
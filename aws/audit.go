@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
+)
+
+// Audit log actions recorded by the managers
+const (
+	AuditActionMaintenance   = "maintenance"
+	AuditActionScale         = "scale"
+	AuditActionConfigGen     = "config_generate"
+	AuditActionRoleEdit      = "profile_bulk_edit"
+	AuditActionTunnelConnect = "tunnel_connection"
+)
+
+// recordAudit writes a mutating operation to the audit log. The previous and
+// new states are stored as JSON, the description as a message ID and its
+// parameters. Failures are reported as warnings only — an audit problem
+// should never fail an operation that has already been applied.
+func recordAudit(repo *db.ConfigRepository, action, env, target string, previous, next interface{}, msg messages.Message) {
+	if repo == nil {
+		return
+	}
+
+	prevJSON, err := json.Marshal(previous)
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to encode audit state: %v\n", err)
+		return
+	}
+	nextJSON, err := json.Marshal(next)
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to encode audit state: %v\n", err)
+		return
+	}
+
+	params, err := messages.EncodeParams(msg.Params)
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to encode audit message: %v\n", err)
+		return
+	}
+
+	if _, err := repo.RecordAuditMessage(action, env, target, string(prevJSON), string(nextJSON), string(msg.ID), params); err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record audit entry: %v\n", err)
+	}
+}
+
+// AuditDescription renders an audit entry's description from its message ID
+// and parameters. Entries recorded before descriptions were stored fall back
+// to the action and target.
+func AuditDescription(entry *db.AuditEntry) string {
+	if !entry.MessageID.Valid {
+		return fmt.Sprintf("%s '%s'", entry.Action, entry.Target)
+	}
+	params, err := messages.DecodeParams(entry.MessageParams.String)
+	if err != nil {
+		return fmt.Sprintf("%s '%s'", entry.Action, entry.Target)
+	}
+	return messages.Render(messages.ID(entry.MessageID.String), params)
+}
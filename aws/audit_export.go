@@ -0,0 +1,156 @@
+package aws
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"rolewalkers/internal/db"
+)
+
+// Audit export formats for SIEM ingestion ('rw history export')
+const (
+	AuditFormatJSONLines = "json-lines"
+	AuditFormatCEF       = "cef"
+	AuditFormatCSV       = "csv"
+)
+
+// AuditFormats lists the supported export formats
+var AuditFormats = []string{AuditFormatJSONLines, AuditFormatCEF, AuditFormatCSV}
+
+// auditEvent is an audit entry as exported: description rendered, states
+// kept as JSON where they are JSON, and whether it touched production
+type auditEvent struct {
+	ID          int             `json:"id"`
+	Time        time.Time       `json:"time"`
+	Action      string          `json:"action"`
+	Environment string          `json:"environment"`
+	Target      string          `json:"target"`
+	Description string          `json:"description"`
+	Production  bool            `json:"production"`
+	Previous    json.RawMessage `json:"previous_state,omitempty"`
+	New         json.RawMessage `json:"new_state,omitempty"`
+	UndoneAt    *time.Time      `json:"undone_at,omitempty"`
+}
+
+func newAuditEvent(entry *db.AuditEntry, production []string) auditEvent {
+	ev := auditEvent{
+		ID:          entry.ID,
+		Time:        entry.CreatedAt.UTC(),
+		Action:      entry.Action,
+		Environment: entry.Environment,
+		Target:      entry.Target,
+		Description: AuditDescription(entry),
+		Production:  isProductionIn(production, entry.Environment),
+		Previous:    auditState(entry.PreviousState.String),
+		New:         auditState(entry.NewState.String),
+	}
+	if entry.UndoneAt.Valid {
+		undone := entry.UndoneAt.Time.UTC()
+		ev.UndoneAt = &undone
+	}
+	return ev
+}
+
+// auditState returns a stored state as raw JSON, quoting it when it was
+// recorded as free-form text
+func auditState(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	quoted, _ := json.Marshal(s)
+	return quoted
+}
+
+// ExportAudit writes audit entries in a SIEM-friendly format: JSON lines
+// (Splunk HEC, Datadog), ArcSight CEF, or CSV. version is reported as the
+// CEF device version; entries in the production environments (see
+// ProductionEnvs) are marked as such.
+func ExportAudit(w io.Writer, entries []db.AuditEntry, format, version string, production []string) error {
+	switch format {
+	case AuditFormatJSONLines:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for i := range entries {
+			if err := enc.Encode(newAuditEvent(&entries[i], production)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case AuditFormatCEF:
+		for i := range entries {
+			if _, err := fmt.Fprintln(w, cefLine(newAuditEvent(&entries[i], production), version)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case AuditFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"id", "time", "action", "environment", "target", "description",
+			"production", "previous_state", "new_state", "undone_at"}); err != nil {
+			return err
+		}
+		for i := range entries {
+			ev := newAuditEvent(&entries[i], production)
+			undone := ""
+			if ev.UndoneAt != nil {
+				undone = ev.UndoneAt.Format(time.RFC3339)
+			}
+			if err := cw.Write([]string{strconv.Itoa(ev.ID), ev.Time.Format(time.RFC3339), ev.Action,
+				ev.Environment, ev.Target, ev.Description, strconv.FormatBool(ev.Production),
+				string(ev.Previous), string(ev.New), undone}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown export format: %s\nUse: %s", format, strings.Join(AuditFormats, ", "))
+	}
+}
+
+// cefLine renders an event as
+// CEF:0|rolewalkers|rw|<version>|<action>|<description>|<severity>|<extensions>
+func cefLine(ev auditEvent, version string) string {
+	severity := 3
+	if ev.Production {
+		severity = 7
+	}
+	header := []string{"CEF:0", "rolewalkers", "rw", version, ev.Action, ev.Description, strconv.Itoa(severity)}
+	for i := 1; i < len(header); i++ {
+		header[i] = cefHeaderEscape(header[i])
+	}
+
+	ext := []string{
+		"rt=" + strconv.FormatInt(ev.Time.UnixMilli(), 10),
+		"externalId=" + strconv.Itoa(ev.ID),
+		"act=" + cefExtensionEscape(ev.Action),
+		"cs1Label=environment",
+		"cs1=" + cefExtensionEscape(ev.Environment),
+		"cs2Label=target",
+		"cs2=" + cefExtensionEscape(ev.Target),
+		"msg=" + cefExtensionEscape(ev.Description),
+	}
+	if ev.UndoneAt != nil {
+		ext = append(ext, "cs3Label=undoneAt", "cs3="+cefExtensionEscape(ev.UndoneAt.Format(time.RFC3339)))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " ")
+}
+
+// cefHeaderEscape escapes backslashes and pipes in a CEF header field
+func cefHeaderEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ", "\r", " ").Replace(s)
+}
+
+// cefExtensionEscape escapes backslashes, equals signs and newlines in a CEF
+// extension value
+func cefExtensionEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
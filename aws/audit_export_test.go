@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"rolewalkers/internal/db"
+)
+
+func exportTestEntries() []db.AuditEntry {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []db.AuditEntry{
+		{
+			ID: 1, Action: AuditActionScale, Environment: "prod", Target: "api|web",
+			PreviousState: sql.NullString{String: `{"min":2}`, Valid: true},
+			NewState:      sql.NullString{String: `{"min":4}`, Valid: true},
+			CreatedAt:     created,
+		},
+		{
+			ID: 2, Action: AuditActionConfigGen, Target: "/home/a=b/.aws/config",
+			NewState:  sql.NullString{String: "+ [profile dev]\n", Valid: true},
+			UndoneAt:  sql.NullTime{Time: created.Add(time.Hour), Valid: true},
+			CreatedAt: created.Add(time.Minute),
+		},
+	}
+}
+
+func TestExportAuditJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportAudit(&buf, exportTestEntries(), AuditFormatJSONLines, "1.0.0", []string{"prod"}); err != nil {
+		t.Fatalf("ExportAudit() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("ExportAudit() wrote %d lines, want 2", len(lines))
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line 1 is not JSON: %v", err)
+	}
+	if first["production"] != true || first["time"] != "2026-03-01T12:00:00Z" {
+		t.Errorf("line 1 = %s, want a production event at 2026-03-01T12:00:00Z", lines[0])
+	}
+	if prev, ok := first["previous_state"].(map[string]interface{}); !ok || prev["min"] != float64(2) {
+		t.Errorf("previous_state = %v, want the JSON state embedded as an object", first["previous_state"])
+	}
+
+	var second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("line 2 is not JSON: %v", err)
+	}
+	if second["new_state"] != "+ [profile dev]\n" || second["undone_at"] == nil {
+		t.Errorf("line 2 = %s, want a text state and undone_at", lines[1])
+	}
+}
+
+func TestExportAuditCEF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportAudit(&buf, exportTestEntries(), AuditFormatCEF, "1.0.0", []string{"prod"}); err != nil {
+		t.Fatalf("ExportAudit() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("ExportAudit() wrote %d lines, want 2", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "CEF:0|rolewalkers|rw|1.0.0|scale|") || !strings.Contains(lines[0], "|7|") {
+		t.Errorf("line 1 = %q, want a CEF header with severity 7 for production", lines[0])
+	}
+	if !strings.Contains(lines[0], `cs2=api|web`) || !strings.Contains(lines[0], "rt=1772366400000") {
+		t.Errorf("line 1 = %q, want target and receipt time extensions", lines[0])
+	}
+	if !strings.Contains(lines[1], `cs2=/home/a\=b/.aws/config`) || !strings.Contains(lines[1], "|3|") {
+		t.Errorf("line 2 = %q, want '=' escaped and severity 3", lines[1])
+	}
+}
+
+func TestExportAuditCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportAudit(&buf, exportTestEntries(), AuditFormatCSV, "1.0.0", []string{"prod"}); err != nil {
+		t.Fatalf("ExportAudit() error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not CSV: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "id" {
+		t.Fatalf("ExportAudit() = %v, want a header and 2 rows", records)
+	}
+	if records[2][7] != "" || records[2][8] != `"+ [profile dev]\n"` || records[2][9] != "2026-03-01T13:00:00Z" {
+		t.Errorf("row 2 = %q", records[2])
+	}
+}
+
+func TestExportAuditUnknownFormat(t *testing.T) {
+	if err := ExportAudit(&bytes.Buffer{}, nil, "xml", "1.0.0", []string{"prod"}); err == nil {
+		t.Error("ExportAudit() should reject unknown formats")
+	}
+}
@@ -0,0 +1,186 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+)
+
+// auditSink is one destination audit entries are forwarded to
+type auditSink struct {
+	name string
+	send func(entries []db.AuditEntry) error
+}
+
+// AuditForwarder sends new audit log entries to the webhook and syslog
+// server in the audit_forward config. Each sink remembers the last entry it
+// accepted in the settings table, so a sink that is down catches up once it
+// is back and entries are never sent to the same sink twice.
+type AuditForwarder struct {
+	repo    *db.ConfigRepository
+	cfg     config.AuditForwardConfig
+	version string
+	client  *http.Client
+}
+
+// NewAuditForwarder creates a forwarder. version is reported as the CEF
+// device version.
+func NewAuditForwarder(repo *db.ConfigRepository, cfg config.AuditForwardConfig, version string) *AuditForwarder {
+	return &AuditForwarder{
+		repo:    repo,
+		cfg:     cfg,
+		version: version,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether any sink is configured
+func (f *AuditForwarder) Enabled() bool {
+	return f.cfg.Webhook != "" || f.cfg.Syslog != ""
+}
+
+func (f *AuditForwarder) sinks() []auditSink {
+	var sinks []auditSink
+	if f.cfg.Webhook != "" {
+		sinks = append(sinks, auditSink{name: "webhook", send: f.sendWebhook})
+	}
+	if f.cfg.Syslog != "" {
+		sinks = append(sinks, auditSink{name: "syslog", send: f.sendSyslog})
+	}
+	return sinks
+}
+
+// Forward sends every entry recorded since the last call to each sink and
+// returns how many entries were sent. The first time a sink is seen it
+// starts from the current end of the log; 'rw history export' covers the
+// history before that.
+func (f *AuditForwarder) Forward() (int, error) {
+	sent := 0
+	for _, sink := range f.sinks() {
+		n, err := f.forwardTo(sink)
+		sent += n
+		if err != nil {
+			return sent, fmt.Errorf("audit forward to %s: %w", sink.name, err)
+		}
+	}
+	return sent, nil
+}
+
+func (f *AuditForwarder) forwardTo(sink auditSink) (int, error) {
+	key := db.SettingAuditForwardedPrefix + sink.name
+	last, err := f.repo.GetSetting(key)
+	if err != nil {
+		return 0, err
+	}
+	if last == "" {
+		id, err := f.repo.LastAuditID()
+		if err != nil {
+			return 0, err
+		}
+		return 0, f.repo.SetSetting(key, strconv.Itoa(id))
+	}
+
+	lastID, err := strconv.Atoi(last)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s setting %q", key, last)
+	}
+	entries, err := f.repo.ListAuditEntriesAfter(lastID)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+
+	if err := sink.send(entries); err != nil {
+		return 0, err
+	}
+	return len(entries), f.repo.SetSetting(key, strconv.Itoa(entries[len(entries)-1].ID))
+}
+
+// sendWebhook POSTs the entries as JSON lines in one request
+func (f *AuditForwarder) sendWebhook(entries []db.AuditEntry) error {
+	var body bytes.Buffer
+	if err := ExportAudit(&body, entries, AuditFormatJSONLines, f.version, ProductionEnvs(f.repo)); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, f.cfg.Webhook, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range f.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendSyslog sends each entry as an RFC 5424 message with a CEF body, one
+// per datagram over UDP or newline-terminated over TCP
+func (f *AuditForwarder) sendSyslog(entries []db.AuditEntry) error {
+	network, addr, err := syslogAddr(f.cfg.Syslog)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout(network, addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "-"
+	}
+	production := ProductionEnvs(f.repo)
+	for i := range entries {
+		ev := newAuditEvent(&entries[i], production)
+		_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.WriteString(conn, syslogMessage(ev, host, f.version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syslogAddr splits udp://host[:port] or tcp://host[:port] into a network
+// and an address, defaulting to port 514
+func syslogAddr(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Hostname() == "" {
+		return "", "", fmt.Errorf("invalid syslog address %q (use udp://host:514 or tcp://host:601)", raw)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "514"
+	}
+	return u.Scheme, net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// syslogMessage renders an event as an RFC 5424 message from facility user:
+// warning for production, notice otherwise
+func syslogMessage(ev auditEvent, host, version string) string {
+	severity := 5
+	if ev.Production {
+		severity = 4
+	}
+	return fmt.Sprintf("<%d>1 %s %s rw - - - %s\n",
+		8+severity, ev.Time.Format(time.RFC3339), host, cefLine(ev, version))
+}
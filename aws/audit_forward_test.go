@@ -0,0 +1,131 @@
+package aws
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+)
+
+func TestAuditForwarderWebhook(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	var bodies []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	if _, err := repo.RecordAudit(AuditActionScale, "prod", "before", "", ""); err != nil {
+		t.Fatalf("RecordAudit() error: %v", err)
+	}
+	f := NewAuditForwarder(repo, config.AuditForwardConfig{
+		Webhook: srv.URL,
+		Headers: map[string]string{"Authorization": "Splunk token"},
+	}, "1.0.0")
+
+	// The first run starts from the end of the log
+	if n, err := f.Forward(); err != nil || n != 0 || len(bodies) != 0 {
+		t.Fatalf("first Forward() = %d, %v with %d requests, want nothing sent", n, err, len(bodies))
+	}
+
+	if _, err := repo.RecordAudit(AuditActionScale, "prod", "api", "", ""); err != nil {
+		t.Fatalf("RecordAudit() error: %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if _, err := f.Forward(); err == nil {
+		t.Fatal("Forward() to a failing webhook should fail")
+	}
+
+	// The failed entry is sent again, and only once
+	status = http.StatusOK
+	if n, err := f.Forward(); err != nil || n != 1 {
+		t.Fatalf("Forward() = %d, %v, want 1 entry", n, err)
+	}
+	if n, err := f.Forward(); err != nil || n != 0 {
+		t.Fatalf("Forward() again = %d, %v, want nothing sent", n, err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], `"target":"api"`) {
+		t.Errorf("webhook bodies = %q", bodies)
+	}
+}
+
+func TestAuditForwarderSyslog(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error: %v", err)
+	}
+	defer conn.Close()
+
+	f := NewAuditForwarder(repo, config.AuditForwardConfig{Syslog: "udp://" + conn.LocalAddr().String()}, "1.0.0")
+	if _, err := f.Forward(); err != nil {
+		t.Fatalf("first Forward() error: %v", err)
+	}
+	if _, err := repo.RecordAudit(AuditActionScale, "dev", "api", "", ""); err != nil {
+		t.Fatalf("RecordAudit() error: %v", err)
+	}
+	if n, err := f.Forward(); err != nil || n != 1 {
+		t.Fatalf("Forward() = %d, %v, want 1 entry", n, err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() error: %v", err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<13>1 ") || !strings.Contains(msg, " rw - - - CEF:0|rolewalkers|rw|1.0.0|scale|") {
+		t.Errorf("syslog message = %q", msg)
+	}
+}
+
+func TestSyslogAddr(t *testing.T) {
+	tests := []struct {
+		raw     string
+		network string
+		addr    string
+		wantErr bool
+	}{
+		{raw: "udp://siem.example.com", network: "udp", addr: "siem.example.com:514"},
+		{raw: "tcp://10.0.0.5:601", network: "tcp", addr: "10.0.0.5:601"},
+		{raw: "siem.example.com:514", wantErr: true},
+		{raw: "http://siem.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		network, addr, err := syslogAddr(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("syslogAddr(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if network != tt.network || addr != tt.addr {
+			t.Errorf("syslogAddr(%q) = %q, %q, want %q, %q", tt.raw, network, addr, tt.network, tt.addr)
+		}
+	}
+}
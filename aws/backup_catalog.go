@@ -0,0 +1,137 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+	"strings"
+	"time"
+)
+
+// recordBackup adds a finished backup to the catalog. Failures only warn:
+// the backup itself has already succeeded.
+func (dm *DatabaseManager) recordBackup(config BackupConfig, location string, size int64, duration time.Duration, hash string) {
+	if dm.configRepo == nil {
+		return
+	}
+
+	id, err := dm.configRepo.RecordBackup(db.BackupRecord{
+		Environment: strings.ToLower(config.Environment),
+		Location:    location,
+		SizeBytes:   size,
+		SchemaOnly:  config.SchemaOnly,
+		DurationMS:  duration.Milliseconds(),
+		ContentHash: hash,
+	})
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record backup in catalog: %v\n", err)
+		return
+	}
+	fmt.Printf("  Backup ID: %d (restore with: rw db restore <env> --backup %d)\n", id, id)
+}
+
+// ListBackups returns catalog entries, newest first. An empty env lists all.
+func (dm *DatabaseManager) ListBackups(env string) ([]db.BackupRecord, error) {
+	if dm.configRepo == nil {
+		return nil, fmt.Errorf("backup catalog requires the database")
+	}
+	return dm.configRepo.ListBackups(strings.ToLower(env))
+}
+
+// GetBackup returns a catalog entry by ID
+func (dm *DatabaseManager) GetBackup(id int) (*db.BackupRecord, error) {
+	if dm.configRepo == nil {
+		return nil, fmt.Errorf("backup catalog requires the database")
+	}
+	return dm.configRepo.GetBackup(id)
+}
+
+// DeleteBackup removes the backup file (or S3 object) and its catalog entry.
+// A file that is already gone is not an error.
+func (dm *DatabaseManager) DeleteBackup(record db.BackupRecord) error {
+	if dm.configRepo == nil {
+		return fmt.Errorf("backup catalog requires the database")
+	}
+
+	if IsS3URI(record.Location) {
+		if record.Interrupted() {
+			// The partial object was removed when the backup was interrupted
+			return dm.configRepo.DeleteBackup(record.ID)
+		}
+		if err := removeS3Object(record.Location, dm.kubeManager.getProfileNameForEnv(record.Environment)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", record.Location, err)
+		}
+	} else if err := os.Remove(record.Location); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", record.Location, err)
+	}
+
+	return dm.configRepo.DeleteBackup(record.ID)
+}
+
+// BackupsToPrune returns the backups beyond the newest keep per environment.
+// backups must be ordered newest first, as returned by ListBackups.
+func BackupsToPrune(backups []db.BackupRecord, keep int) []db.BackupRecord {
+	seen := make(map[string]int)
+	var prune []db.BackupRecord
+	for _, b := range backups {
+		seen[b.Environment]++
+		if seen[b.Environment] > keep {
+			prune = append(prune, b)
+		}
+	}
+	return prune
+}
+
+// verifyBackup checks that the input of a catalog restore is still the file
+// that was backed up, so a renamed or overwritten file is never restored.
+func (dm *DatabaseManager) verifyBackup(config RestoreConfig) error {
+	record, err := dm.GetBackup(config.BackupID)
+	if err != nil {
+		return err
+	}
+	if record.Interrupted() {
+		return fmt.Errorf("backup %d was interrupted and is incomplete; start it again with: rw db backups retry %d",
+			record.ID, record.ID)
+	}
+
+	if IsS3URI(record.Location) {
+		// Hashing would mean downloading the object twice; the size is a cheap check
+		size, err := s3ObjectSize(record.Location, dm.kubeManager.getProfileNameForEnv(record.Environment))
+		if err != nil {
+			return err
+		}
+		if size != record.SizeBytes {
+			return fmt.Errorf("backup %d has changed since it was taken: %s is %d bytes, catalog says %d",
+				record.ID, record.Location, size, record.SizeBytes)
+		}
+		return nil
+	}
+
+	hash, err := fileSHA256(record.Location)
+	if err != nil {
+		return fmt.Errorf("backup %d is not readable: %w", record.ID, err)
+	}
+	if hash != record.ContentHash {
+		return fmt.Errorf("backup %d has changed since it was taken: content hash of %s does not match the catalog",
+			record.ID, record.Location)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"rolewalkers/internal/db"
+	"strings"
+	"testing"
+)
+
+func TestBackupsToPrune(t *testing.T) {
+	// Newest first, as returned by ListBackups
+	backups := []db.BackupRecord{
+		{ID: 6, Environment: "dev"},
+		{ID: 5, Environment: "prod"},
+		{ID: 4, Environment: "dev"},
+		{ID: 3, Environment: "dev"},
+		{ID: 2, Environment: "prod"},
+		{ID: 1, Environment: "dev"},
+	}
+
+	tests := []struct {
+		keep int
+		want []int
+	}{
+		{10, nil},
+		{2, []int{3, 1}},
+		{1, []int{4, 3, 2, 1}},
+		{0, []int{6, 5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		got := BackupsToPrune(backups, tt.keep)
+		var ids []int
+		for _, b := range got {
+			ids = append(ids, b.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("BackupsToPrune(keep=%d) = %v, want %v", tt.keep, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("BackupsToPrune(keep=%d) = %v, want %v", tt.keep, ids, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestInterruptedBackup(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+	dm := &DatabaseManager{configRepo: repo}
+
+	partial := filepath.Join(t.TempDir(), "dev.sql"+partialBackupSuffix)
+	if err := os.WriteFile(partial, []byte("-- partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := repo.RecordBackup(db.BackupRecord{Environment: "dev", Location: partial, Status: db.BackupInterrupted})
+	completeID, _ := repo.RecordBackup(db.BackupRecord{Environment: "dev", Location: "/tmp/done.sql", ContentHash: "abc"})
+
+	err = dm.verifyBackup(RestoreConfig{BackupID: int(id)})
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Errorf("verifyBackup() = %v, want an interrupted backup refused", err)
+	}
+	if err := dm.RetryBackup(int(completeID)); err == nil || !strings.Contains(err.Error(), "only interrupted") {
+		t.Errorf("RetryBackup() of a complete backup = %v, want it refused", err)
+	}
+
+	record, _ := repo.GetBackup(int(id))
+	if err := dm.DeleteBackup(*record); err != nil {
+		t.Fatalf("DeleteBackup() error: %v", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("DeleteBackup() left the partial file behind: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/k8s"
+	"rolewalkers/internal/utils"
+)
+
+// partialBackupSuffix marks a local backup that pg_dump is still writing,
+// or that was interrupted
+const partialBackupSuffix = ".partial"
+
+// ErrBackupInterrupted is returned when Ctrl+C or SIGTERM stops a backup
+var ErrBackupInterrupted = errors.New("backup interrupted")
+
+// backupSignalContext is cancelled by Ctrl+C or SIGTERM, which stops
+// kubectl and lets the backup clean up instead of exiting mid-write
+func backupSignalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// backupInterrupted cleans up after a backup was stopped part-way: it
+// deletes the pg_dump pod, which kubectl may not have removed, and records
+// what was written so far as an interrupted backup that can be retried
+func (dm *DatabaseManager) backupInterrupted(pod *k8s.PodSession, config BackupConfig, location string, size int64, duration time.Duration) error {
+	fmt.Fprintln(utils.Stderr, "\nInterrupted, cleaning up...")
+	if err := pod.Close(); err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" %v\n", err)
+	} else {
+		fmt.Fprintf(utils.Stderr, "  Deleted pod %s\n", pod.Name)
+	}
+
+	if !IsS3URI(location) {
+		if abs, err := filepath.Abs(location); err == nil {
+			location = abs
+		}
+		fmt.Fprintf(utils.Stderr, "  Kept the partial dump (%s) at %s\n", utils.FormatBytes(size), location)
+	}
+
+	if dm.configRepo == nil {
+		return fmt.Errorf("%w after %s", ErrBackupInterrupted, duration.Round(time.Second))
+	}
+	id, err := dm.configRepo.RecordBackup(db.BackupRecord{
+		Environment: strings.ToLower(config.Environment),
+		Location:    location,
+		SizeBytes:   size,
+		SchemaOnly:  config.SchemaOnly,
+		DurationMS:  duration.Milliseconds(),
+		Status:      db.BackupInterrupted,
+	})
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record the interrupted backup: %v\n", err)
+		return fmt.Errorf("%w after %s", ErrBackupInterrupted, duration.Round(time.Second))
+	}
+	return fmt.Errorf("%w after %s; start it again with: rw db backups retry %d",
+		ErrBackupInterrupted, duration.Round(time.Second), id)
+}
+
+// RetryBackup runs an interrupted backup again from the start, with the
+// same environment, destination and mode. pg_dump can't continue a dump,
+// so the partial file is overwritten. The interrupted catalog entry is
+// replaced by the new backup's.
+func (dm *DatabaseManager) RetryBackup(id int) error {
+	record, err := dm.GetBackup(id)
+	if err != nil {
+		return err
+	}
+	if !record.Interrupted() {
+		return fmt.Errorf("backup %d completed; only interrupted backups can be retried", id)
+	}
+
+	config := BackupConfig{Environment: record.Environment, SchemaOnly: record.SchemaOnly}
+	if IsS3URI(record.Location) {
+		config.S3URI = record.Location
+	} else {
+		config.OutputFile = strings.TrimSuffix(record.Location, partialBackupSuffix)
+	}
+
+	err = dm.Backup(config)
+	if err == nil || errors.Is(err, ErrBackupInterrupted) {
+		if delErr := dm.configRepo.DeleteBackup(record.ID); delErr != nil {
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to remove interrupted backup %d from the catalog: %v\n", record.ID, delErr)
+		}
+	}
+	return err
+}
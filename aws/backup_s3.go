@@ -0,0 +1,134 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/utils"
+	"strings"
+)
+
+// IsS3URI reports whether a backup location is an S3 URI (s3://bucket/key)
+func IsS3URI(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// parseS3URI splits an s3://bucket/key URI into bucket and key
+func parseS3URI(uri string) (bucket, key string, err error) {
+	rest := strings.TrimPrefix(uri, "s3://")
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return "", "", fmt.Errorf("invalid S3 URI %q (expected s3://bucket/key)", uri)
+	}
+	return bucket, key, nil
+}
+
+// s3ObjectSize returns the size of an S3 object, failing if it doesn't exist
+func s3ObjectSize(uri, profile string) (int64, error) {
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := awscli.CreateCommand("s3api", "head-object",
+		"--bucket", bucket,
+		"--key", key,
+		"--profile", profile,
+		"--output", "json",
+	)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("S3 object not found or not accessible: %s: %s", uri, utils.Redact(strings.TrimSpace(stderr.String())))
+	}
+
+	var head struct {
+		ContentLength int64 `json:"ContentLength"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &head); err != nil {
+		return 0, fmt.Errorf("failed to parse head-object response: %w", err)
+	}
+	return head.ContentLength, nil
+}
+
+// s3Stream is a running 'aws s3 cp' process streaming to or from S3.
+// The AWS CLI switches to multipart upload automatically for large streams.
+type s3Stream struct {
+	cmd    *exec.Cmd
+	pipe   io.Closer
+	stderr bytes.Buffer
+}
+
+// startS3Upload starts streaming stdin of the returned writer to an S3 object
+func startS3Upload(uri, profile string) (*s3Stream, io.WriteCloser, error) {
+	if _, _, err := parseS3URI(uri); err != nil {
+		return nil, nil, err
+	}
+
+	s := &s3Stream{cmd: awscli.CreateCommand("s3", "cp", "-", uri, "--profile", profile, "--only-show-errors")}
+	s.cmd.Stderr = &s.stderr
+	w, err := s.cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.pipe = w
+	if err := s.cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start S3 upload: %w", err)
+	}
+	return s, w, nil
+}
+
+// startS3Download starts streaming an S3 object to the returned reader
+func startS3Download(uri, profile string) (*s3Stream, io.Reader, error) {
+	if _, _, err := parseS3URI(uri); err != nil {
+		return nil, nil, err
+	}
+
+	s := &s3Stream{cmd: awscli.CreateCommand("s3", "cp", uri, "-", "--profile", profile, "--only-show-errors")}
+	s.cmd.Stderr = &s.stderr
+	r, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.pipe = r
+	if err := s.cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start S3 download: %w", err)
+	}
+	return s, r, nil
+}
+
+// Wait closes our end of the pipe and waits for the transfer to finish
+func (s *s3Stream) Wait() error {
+	s.pipe.Close()
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("%w: %s", err, utils.Redact(strings.TrimSpace(s.stderr.String())))
+	}
+	return nil
+}
+
+// removeS3Object deletes an S3 object, e.g. a partial upload after a failed dump
+func removeS3Object(uri, profile string) error {
+	cmd := awscli.CreateCommand("s3", "rm", uri, "--profile", profile, "--only-show-errors")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, utils.Redact(strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,40 @@
+package aws
+
+import "testing"
+
+func TestParseS3URI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		bucket  string
+		key     string
+		wantErr bool
+	}{
+		{"s3://zenith-backups/prod/2024-06-01.sql", "zenith-backups", "prod/2024-06-01.sql", false},
+		{"s3://bucket/file.sql", "bucket", "file.sql", false},
+		{"s3://bucket", "", "", true},
+		{"s3://bucket/", "", "", true},
+		{"s3://bucket/prefix/", "", "", true},
+		{"s3:///key", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			bucket, key, err := parseS3URI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseS3URI(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			}
+			if bucket != tt.bucket || key != tt.key {
+				t.Errorf("parseS3URI(%q) = (%q, %q), want (%q, %q)", tt.uri, bucket, key, tt.bucket, tt.key)
+			}
+		})
+	}
+}
+
+func TestIsS3URI(t *testing.T) {
+	if !IsS3URI("s3://bucket/key") {
+		t.Error("IsS3URI(s3://bucket/key) = false, want true")
+	}
+	if IsS3URI("./backup.sql") {
+		t.Error("IsS3URI(./backup.sql) = true, want false")
+	}
+}
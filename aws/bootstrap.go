@@ -0,0 +1,245 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"rolewalkers/internal/awsarn"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// BootstrapResult holds the results of 'rw bootstrap'
+type BootstrapResult struct {
+	ProfilesImported int
+	ProfilesUpdated  int
+	ContextsImported int
+	ClustersFound    int
+	Environments     int
+	PortMappings     int
+	Errors           []string
+}
+
+// PlannedPortMapping is a port mapping bootstrap would add
+type PlannedPortMapping struct {
+	Service     string
+	Environment string
+	LocalPort   int
+}
+
+// Bootstrap builds a ready-to-use database from what is already on the
+// machine, for a new laptop with existing AWS and kube config:
+// 1. Import profiles from ~/.aws/config
+// 2. Import environments from existing EKS kubeconfig contexts
+// 3. Discover EKS clusters in every account and add missing contexts
+// 4. Generate port mappings for environments that have none
+func (sm *SetupManager) Bootstrap(discoverEKS bool) (*BootstrapResult, error) {
+	if sm.dbRepo == nil {
+		return nil, fmt.Errorf("bootstrap requires the database")
+	}
+	result := &BootstrapResult{}
+
+	// Step 1: Import AWS profiles
+	fmt.Println("Importing profiles from ~/.aws/config...")
+	cs, err := NewConfigSync(sm.dbRepo)
+	if err != nil {
+		return nil, err
+	}
+	if cs.ConfigFileExists() {
+		synced, err := cs.SyncConfigToDB()
+		if err != nil {
+			return nil, fmt.Errorf("config sync failed: %w", err)
+		}
+		result.ProfilesImported = synced.Imported
+		result.ProfilesUpdated = synced.Updated
+		result.Errors = append(result.Errors, synced.Errors...)
+		fmt.Printf("  "+utils.OK()+" %d imported, %d updated\n", synced.Imported, synced.Updated)
+	} else {
+		fmt.Println("  " + utils.Warn() + " ~/.aws/config not found, skipping (run 'rw setup' to log in via SSO)")
+	}
+
+	cm, err := NewConfigManager()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := cm.GetProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	accountProfiles := profilesByAccount(profiles)
+
+	envsBefore, _ := sm.dbRepo.GetAllEnvironments()
+
+	// Step 2: Import existing kubeconfig contexts
+	fmt.Println("\nImporting kubeconfig contexts...")
+	known := make(map[string]bool)
+	contexts, err := NewKubeManager().GetContexts()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to read kubeconfig: %v", err))
+	}
+	for _, ctx := range contexts {
+		account, cluster, ok := parseEKSClusterARN(ctx.Cluster)
+		if !ok {
+			continue
+		}
+		known[cluster] = true
+
+		envName := sm.extractEnvFromCluster(cluster)
+		if envName == "" {
+			continue
+		}
+		profile := accountProfiles[account]
+		if profile == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Context %s: no profile for account %s", ctx.Name, account))
+			continue
+		}
+
+		sm.upsertEnvironment(envName, profile, cluster)
+		result.ContextsImported++
+		fmt.Printf("  "+utils.OK()+" %s "+utils.Arrow()+" %s (%s)\n", cluster, envName, profile)
+	}
+
+	// Step 3: Discover EKS clusters per account
+	if discoverEKS {
+		fmt.Println("\nDiscovering EKS clusters...")
+		accounts := make([]string, 0, len(accountProfiles))
+		for account := range accountProfiles {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+
+		for _, account := range accounts {
+			profile := accountProfiles[account]
+			clusters, err := sm.listEKSClusters(profile)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to list clusters (logged in?)", profile))
+				continue
+			}
+
+			for _, cluster := range clusters {
+				result.ClustersFound++
+				if !known[cluster] {
+					if err := sm.updateKubeconfig(cluster, profile); err != nil {
+						result.Errors = append(result.Errors, fmt.Sprintf("Failed to update kubeconfig for %s: %v", cluster, err))
+						continue
+					}
+					known[cluster] = true
+					fmt.Printf("  "+utils.OK()+" %s "+utils.Arrow()+" %s (context added)\n", profile, cluster)
+				} else {
+					fmt.Printf("  "+utils.OK()+" %s "+utils.Arrow()+" %s\n", profile, cluster)
+				}
+
+				if envName := sm.extractEnvFromCluster(cluster); envName != "" {
+					sm.upsertEnvironment(envName, profile, cluster)
+				}
+			}
+		}
+	}
+
+	envsAfter, err := sm.dbRepo.GetAllEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	result.Environments = len(envsAfter) - len(envsBefore)
+
+	// Step 4: Generate port mappings for new environments
+	fmt.Println("\nGenerating port mappings...")
+	services, err := sm.dbRepo.GetAllServices()
+	if err != nil {
+		return nil, err
+	}
+	existing, err := sm.dbRepo.GetAllPortMappings()
+	if err != nil {
+		return nil, err
+	}
+	for _, pm := range PlanPortMappings(services, envsAfter, existing) {
+		if err := sm.dbRepo.AddPortMapping(pm.Service, pm.Environment, pm.LocalPort, "Generated by rw bootstrap"); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.PortMappings++
+		fmt.Printf("  "+utils.OK()+" %s/%s "+utils.Arrow()+" localhost:%d\n", pm.Service, pm.Environment, pm.LocalPort)
+	}
+	if result.PortMappings == 0 {
+		fmt.Println("  " + utils.OK() + " All environments already have port mappings")
+	}
+
+	return result, nil
+}
+
+// PlanPortMappings returns the mappings needed so every tunnel service has a
+// local port in every environment. New ports continue each service's
+// existing range (max + 1) and never reuse a port mapped to anything else.
+func PlanPortMappings(services []db.Service, envs []db.Environment, existing []db.PortMapping) []PlannedPortMapping {
+	used := make(map[int]bool)
+	mapped := make(map[[2]int]bool)
+	highest := make(map[int]int)
+	for _, pm := range existing {
+		used[pm.LocalPort] = true
+		mapped[[2]int{pm.ServiceID, pm.EnvironmentID}] = true
+		highest[pm.ServiceID] = max(highest[pm.ServiceID], pm.LocalPort)
+	}
+
+	// Deterministic order: environments in creation order
+	envs = append([]db.Environment(nil), envs...)
+	sort.Slice(envs, func(i, j int) bool { return envs[i].ID < envs[j].ID })
+
+	var plan []PlannedPortMapping
+	for _, svc := range services {
+		// gRPC microservices are forwarded on their own port, not mapped per env
+		if svc.ServiceType == "grpc-microservice" {
+			continue
+		}
+
+		next := highest[svc.ID]
+		if next == 0 {
+			next = svc.DefaultRemotePort - 1
+		}
+		for _, env := range envs {
+			if mapped[[2]int{svc.ID, env.ID}] {
+				continue
+			}
+			next++
+			for used[next] {
+				next++
+			}
+			used[next] = true
+			plan = append(plan, PlannedPortMapping{Service: svc.Name, Environment: env.Name, LocalPort: next})
+		}
+	}
+	return plan
+}
+
+// profilesByAccount picks one profile per SSO account for discovery,
+// preferring non-RDS roles and then the alphabetically first name.
+func profilesByAccount(profiles []Profile) map[string]string {
+	sorted := append([]Profile(nil), profiles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	byAccount := make(map[string]string)
+	for _, p := range sorted {
+		if p.SSOAccountID == "" || strings.Contains(p.SSORoleName, "RDS") {
+			continue
+		}
+		if _, ok := byAccount[p.SSOAccountID]; !ok {
+			byAccount[p.SSOAccountID] = p.Name
+		}
+	}
+	return byAccount
+}
+
+// parseEKSClusterARN extracts the account ID and cluster name from an EKS
+// cluster ARN as written by 'aws eks update-kubeconfig', in any partition,
+// e.g. arn:aws:eks:eu-west-2:123456789012:cluster/dev-zenith-eks-cluster
+func parseEKSClusterARN(s string) (account, cluster string, ok bool) {
+	arn, err := awsarn.Parse(s)
+	if err != nil || arn.Service != "eks" {
+		return "", "", false
+	}
+	cluster, found := strings.CutPrefix(arn.Resource, "cluster/")
+	if !found || cluster == "" {
+		return "", "", false
+	}
+	return arn.Account, cluster, true
+}
@@ -0,0 +1,67 @@
+package aws
+
+import (
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestParseEKSClusterARN(t *testing.T) {
+	tests := []struct {
+		arn     string
+		account string
+		cluster string
+		ok      bool
+	}{
+		{"arn:aws:eks:eu-west-2:123456789012:cluster/dev-zenith-eks-cluster", "123456789012", "dev-zenith-eks-cluster", true},
+		{"arn:aws-us-gov:eks:us-gov-west-1:123456789012:cluster/gov", "123456789012", "gov", true},
+		{"kind-local", "", "", false},
+		{"arn:aws:iam::123456789012:role/Admin", "", "", false},
+		{"arn:aws:eks:eu-west-2:123456789012:nodegroup/x", "", "", false},
+	}
+	for _, tt := range tests {
+		account, cluster, ok := parseEKSClusterARN(tt.arn)
+		if account != tt.account || cluster != tt.cluster || ok != tt.ok {
+			t.Errorf("parseEKSClusterARN(%q) = (%q, %q, %v)", tt.arn, account, cluster, ok)
+		}
+	}
+}
+
+func TestProfilesByAccount(t *testing.T) {
+	got := profilesByAccount([]Profile{
+		{Name: "zenith-dev-rds", SSOAccountID: "111", SSORoleName: "RDSReadOnly"},
+		{Name: "zenith-dev", SSOAccountID: "111", SSORoleName: "Admin"},
+		{Name: "zenith-qa", SSOAccountID: "222", SSORoleName: "Admin"},
+		{Name: "static", SSOAccountID: ""},
+	})
+	if len(got) != 2 || got["111"] != "zenith-dev" || got["222"] != "zenith-qa" {
+		t.Errorf("profilesByAccount() = %v", got)
+	}
+}
+
+func TestPlanPortMappings(t *testing.T) {
+	services := []db.Service{
+		{ID: 1, Name: "db", DefaultRemotePort: 5432},
+		{ID: 2, Name: "redis", DefaultRemotePort: 6379},
+		{ID: 3, Name: "candidate", ServiceType: "grpc-microservice", DefaultRemotePort: 5001},
+	}
+	envs := []db.Environment{{ID: 2, Name: "uat"}, {ID: 1, Name: "dev"}}
+	existing := []db.PortMapping{
+		{ServiceID: 1, EnvironmentID: 1, LocalPort: 5432},
+		{ServiceID: 2, EnvironmentID: 1, LocalPort: 5433}, // collides with db's next port
+	}
+
+	plan := PlanPortMappings(services, envs, existing)
+	want := []PlannedPortMapping{
+		{Service: "db", Environment: "uat", LocalPort: 5434},
+		{Service: "redis", Environment: "uat", LocalPort: 5435},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("PlanPortMappings() = %+v, want %+v", plan, want)
+	}
+	for i := range want {
+		if plan[i] != want[i] {
+			t.Errorf("PlanPortMappings()[%d] = %+v, want %+v", i, plan[i], want[i])
+		}
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"bufio"
 	"cmp"
 	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"rolewalkers/internal/awsarn"
 )
 
 // Profile represents an AWS profile configuration
@@ -19,10 +24,28 @@ type Profile struct {
 	SSORegion    string `json:"ssoRegion,omitempty"`
 	SSOAccountID string `json:"ssoAccountId,omitempty"`
 	SSORoleName  string `json:"ssoRoleName,omitempty"`
+	RoleARN      string `json:"roleArn,omitempty"`
 	Region       string `json:"region,omitempty"`
 	Output       string `json:"output,omitempty"`
 	IsSSO        bool   `json:"isSso"`
 	IsActive     bool   `json:"isActive"`
+
+	// hasCredentialSource is set when the config section itself supplies
+	// credentials (keys, role_arn, credential_process, web identity)
+	hasCredentialSource bool
+}
+
+// AccountID returns the AWS account the profile signs in to, from
+// sso_account_id or role_arn, or "" when the config doesn't say
+func (p *Profile) AccountID() string {
+	if p.SSOAccountID != "" {
+		return p.SSOAccountID
+	}
+	// arn:aws:iam::123456789012:role/name, in any partition
+	if arn, err := awsarn.Parse(p.RoleARN); err == nil {
+		return arn.Account
+	}
+	return ""
 }
 
 // ssoSessionConfig holds settings from an [sso-session ...] block
@@ -41,6 +64,34 @@ var (
 type ConfigManager struct {
 	configPath      string
 	credentialsPath string
+
+	// Parsed profiles are cached until the config file's mtime or size
+	// changes, so repeated GetProfiles calls within a command are free.
+	mu        sync.Mutex
+	cache     []Profile // sorted, IsActive not set
+	cacheStat configFileStat
+	loaded    bool
+	stale     bool
+	onChange  []func()
+}
+
+// configFileStat identifies a version of the config file
+type configFileStat struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func statConfigFile(path string) configFileStat {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configFileStat{}
+	}
+	return configFileStat{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+func (s configFileStat) equal(o configFileStat) bool {
+	return s.exists == o.exists && s.modTime.Equal(o.modTime) && s.size == o.size
 }
 
 // NewConfigManager creates a new config manager
@@ -63,6 +114,72 @@ func NewConfigManager() (*ConfigManager, error) {
 
 // GetProfiles returns all configured AWS profiles
 func (cm *ConfigManager) GetProfiles() ([]Profile, error) {
+	parsed, err := cm.loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get active profile
+	activeProfile := cm.GetActiveProfile()
+
+	result := slices.Clone(parsed)
+	for i := range result {
+		result[i].IsActive = result[i].Name == activeProfile
+	}
+
+	return result, nil
+}
+
+// Profiles iterates over all configured AWS profiles in name order. If the
+// config file can't be read, it yields a single zero Profile and the error.
+func (cm *ConfigManager) Profiles() iter.Seq2[Profile, error] {
+	return func(yield func(Profile, error) bool) {
+		profiles, err := cm.GetProfiles()
+		if err != nil {
+			yield(Profile{}, err)
+			return
+		}
+		for _, p := range profiles {
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+
+// NotifyChanged registers fn to be called when GetProfiles finds that the
+// profiles in the config file changed since it was last read, e.g. edited by
+// hand or by another tool. Writes that keep the same profiles, and writes
+// this process reported with Invalidate, are not changes. fn runs
+// synchronously inside GetProfiles and must not call back into it.
+func (cm *ConfigManager) NotifyChanged(fn func()) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.onChange = append(cm.onChange, fn)
+}
+
+// Invalidate drops the cached profiles so the next read re-parses the file.
+// Writers call it so a change within the same mtime tick isn't missed; the
+// re-read isn't reported to NotifyChanged listeners, since the writer knows.
+func (cm *ConfigManager) Invalidate() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.stale = true
+}
+
+// loadProfiles returns the parsed profiles, re-parsing the config file only
+// when it changed since the last read
+func (cm *ConfigManager) loadProfiles() ([]Profile, error) {
+	stat := statConfigFile(cm.configPath)
+
+	cm.mu.Lock()
+	if cm.loaded && !cm.stale && stat.equal(cm.cacheStat) {
+		cached := cm.cache
+		cm.mu.Unlock()
+		return cached, nil
+	}
+	cm.mu.Unlock()
+
 	profiles := make(map[string]*Profile)
 
 	// Parse config file
@@ -70,13 +187,9 @@ func (cm *ConfigManager) GetProfiles() ([]Profile, error) {
 		return nil, err
 	}
 
-	// Get active profile
-	activeProfile := cm.GetActiveProfile()
-
 	// Convert map to slice
 	result := make([]Profile, 0, len(profiles))
 	for _, p := range profiles {
-		p.IsActive = p.Name == activeProfile
 		result = append(result, *p)
 	}
 
@@ -85,6 +198,17 @@ func (cm *ConfigManager) GetProfiles() ([]Profile, error) {
 		return cmp.Compare(a.Name, b.Name)
 	})
 
+	cm.mu.Lock()
+	changed := cm.loaded && !cm.stale && !slices.Equal(cm.cache, result)
+	cm.cache, cm.cacheStat, cm.loaded, cm.stale = result, stat, true, false
+	listeners := slices.Clone(cm.onChange)
+	cm.mu.Unlock()
+
+	if changed {
+		for _, fn := range listeners {
+			fn()
+		}
+	}
 	return result, nil
 }
 
@@ -177,6 +301,11 @@ func (cm *ConfigManager) parseConfigFile(profiles map[string]*Profile) error {
 				currentProfile.Region = value
 			case "output":
 				currentProfile.Output = value
+			case "role_arn":
+				currentProfile.RoleARN = value
+				currentProfile.hasCredentialSource = true
+			case "aws_access_key_id", "credential_process", "web_identity_token_file":
+				currentProfile.hasCredentialSource = true
 			}
 		}
 	}
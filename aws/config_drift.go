@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"rolewalkers/internal/utils"
+)
+
+// configHashFile records the SHA-256 of each AWS config file as rw last
+// wrote it, keyed by path. A file that no longer matches was edited by
+// something else, such as 'aws configure sso', granted or leapp.
+const configHashFile = "aws_config_hashes.json"
+
+// ErrExternalConfigChange is returned instead of overwriting a managed
+// config that another tool has edited since rw last wrote it
+var ErrExternalConfigChange = errors.New("~/.aws/config was edited outside rw; run 'rw config reconcile' to import or discard the changes")
+
+func loadConfigHashes() map[string]string {
+	hashes := make(map[string]string)
+	if data, err := utils.ReadRoleWalkersFile(configHashFile); err == nil {
+		_ = json.Unmarshal(data, &hashes)
+	}
+	return hashes
+}
+
+func configHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordConfigWrite remembers content as what rw last wrote to path
+func recordConfigWrite(path string, content []byte) error {
+	hashes := loadConfigHashes()
+	hashes[path] = configHash(content)
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteRoleWalkersFile(configHashFile, data)
+}
+
+// clearConfigHashes forgets what rw last wrote, so nothing counts as an
+// external change until rw writes the file again
+func clearConfigHashes() error {
+	dir, err := utils.RoleWalkersDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, configHashFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// configChangedExternally reports whether path differs from what rw last
+// wrote to it. A file rw never wrote, or one that's missing, hasn't changed.
+func configChangedExternally(path string) bool {
+	recorded, ok := loadConfigHashes()[path]
+	if !ok {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return configHash(content) != recorded
+}
+
+// ExternalChange reports whether ~/.aws/config is managed by rw and was
+// edited by another tool since rw last wrote it
+func (cs *ConfigSync) ExternalChange() bool {
+	return AWSConfigManaged() && configChangedExternally(cs.configPath)
+}
+
+// AcceptAWSConfig takes the current ~/.aws/config as what rw last wrote, so
+// its edits no longer count as an external change
+func (cs *ConfigSync) AcceptAWSConfig() error {
+	content, err := os.ReadFile(cs.configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	return recordConfigWrite(cs.configPath, content)
+}
+
+// ImportExternalChange imports an externally edited ~/.aws/config into the
+// database and accepts the file as it is
+func (cs *ConfigSync) ImportExternalChange() (*SyncResult, error) {
+	result, err := cs.SyncConfigToDB()
+	if err != nil {
+		return nil, err
+	}
+	return result, cs.AcceptAWSConfig()
+}
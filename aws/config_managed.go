@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
+)
+
+// configManagedFile marks ~/.aws/config as generated from the database.
+// Only 'rw config manage on' creates it; while it exists, rw-tray
+// regenerates the file whenever the database changes.
+const configManagedFile = "aws_config_managed"
+
+// AWSConfigManaged reports whether the database is the source of truth for
+// ~/.aws/config
+func AWSConfigManaged() bool {
+	_, err := utils.ReadRoleWalkersFile(configManagedFile)
+	return err == nil
+}
+
+// SetAWSConfigManaged turns regeneration of ~/.aws/config from the database
+// on or off
+func SetAWSConfigManaged(managed bool) error {
+	if managed {
+		return utils.WriteRoleWalkersFile(configManagedFile, []byte("generated from the rolewalkers database\n"))
+	}
+	dir, err := utils.RoleWalkersDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, configManagedFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return clearConfigHashes()
+}
+
+// RegenerateAWSConfig rewrites ~/.aws/config from the database when it
+// differs from what the database generates, including when the file is
+// missing. The generated config is validated first and written atomically,
+// so tools reading the file never see a partial or broken config. A file
+// another tool has edited since rw wrote it is left alone and
+// ErrExternalConfigChange returned. It reports whether the file was written.
+func (cs *ConfigSync) RegenerateAWSConfig() (bool, error) {
+	if !cs.HasExistingData() {
+		return false, nil
+	}
+	if configChangedExternally(cs.configPath) {
+		return false, ErrExternalConfigChange
+	}
+	generated, err := cs.GenerateAWSConfig()
+	if err != nil {
+		return false, err
+	}
+
+	current, err := os.ReadFile(cs.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read config: %w", err)
+	}
+	if string(current) == generated {
+		return false, nil
+	}
+	if err := ValidateGeneratedConfig(generated); err != nil {
+		return false, fmt.Errorf("generated config is invalid, %s left unchanged: %w", cs.configPath, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cs.configPath), 0700); err != nil {
+		return false, fmt.Errorf("failed to create .aws directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(cs.configPath, []byte(generated), 0600); err != nil {
+		return false, err
+	}
+	if err := recordConfigWrite(cs.configPath, []byte(generated)); err != nil {
+		return true, err
+	}
+
+	if cs.dbRepo != nil {
+		diff := utils.UnifiedDiff(cs.configPath, cs.configPath+" (generated)", string(current), generated)
+		params, _ := messages.EncodeParams(messages.Params{"path": cs.configPath})
+		if _, err := cs.dbRepo.RecordAuditMessage(AuditActionConfigGen, "", cs.configPath, "", diff,
+			string(messages.AuditConfigGenerate), params); err != nil {
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record audit entry: %v\n", err)
+		}
+	}
+	return true, nil
+}
+
+// ValidateGeneratedConfig checks a generated config before it replaces
+// ~/.aws/config: section names are unique, every profile has a region, and
+// SSO profiles refer to a complete sso-session block
+func ValidateGeneratedConfig(content string) error {
+	type section struct {
+		kind, name string
+		keys       map[string]string
+	}
+	var sections []*section
+	var current *section
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			header := strings.Fields(strings.Trim(text, "[]"))
+			current = &section{keys: make(map[string]string)}
+			switch {
+			case len(header) == 1 && header[0] == "default":
+				current.kind, current.name = "profile", "default"
+			case len(header) == 2 && (header[0] == "profile" || header[0] == "sso-session"):
+				current.kind, current.name = header[0], header[1]
+			default:
+				return fmt.Errorf("line %d: unexpected section %s", line, text)
+			}
+			id := current.kind + " " + current.name
+			if seen[id] {
+				return fmt.Errorf("line %d: duplicate section [%s]", line, id)
+			}
+			seen[id] = true
+			sections = append(sections, current)
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || current == nil {
+			return fmt.Errorf("line %d: expected key = value in a section", line)
+		}
+		current.keys[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	var errs []error
+	for _, s := range sections {
+		if s.kind == "sso-session" {
+			for _, key := range []string{"sso_start_url", "sso_region"} {
+				if s.keys[key] == "" {
+					errs = append(errs, fmt.Errorf("[sso-session %s] has no %s", s.name, key))
+				}
+			}
+			continue
+		}
+		if s.keys["region"] == "" {
+			errs = append(errs, fmt.Errorf("profile %s has no region", s.name))
+		}
+		if session := s.keys["sso_session"]; session != "" && !seen["sso-session "+session] {
+			errs = append(errs, fmt.Errorf("profile %s refers to a missing sso-session %s", s.name, session))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestValidateGeneratedConfig(t *testing.T) {
+	valid := `[sso-session d-123]
+sso_start_url = https://d-123.awsapps.com/start
+sso_region = eu-west-2
+
+[default]
+region = eu-west-2
+
+[profile dev]
+sso_session = d-123
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+region = eu-west-2
+`
+	if err := ValidateGeneratedConfig(valid); err != nil {
+		t.Errorf("ValidateGeneratedConfig(valid) error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"duplicate profile", "[profile a]\nregion = x\n[profile a]\nregion = x\n", "duplicate section"},
+		{"missing region", "[profile a]\noutput = json\n", "has no region"},
+		{"missing session", "[profile a]\nregion = x\nsso_session = s\n", "missing sso-session"},
+		{"incomplete session", "[sso-session s]\nsso_region = x\n", "has no sso_start_url"},
+		{"key outside section", "region = x\n", "expected key = value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGeneratedConfig(tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateGeneratedConfig() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegenerateAWSConfig(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	if AWSConfigManaged() {
+		t.Fatal("AWSConfigManaged() = true before SetAWSConfigManaged")
+	}
+	if err := SetAWSConfigManaged(true); err != nil {
+		t.Fatal(err)
+	}
+	if !AWSConfigManaged() {
+		t.Error("AWSConfigManaged() = false after SetAWSConfigManaged(true)")
+	}
+
+	if err := repo.AddAWSAccount("000000000051", "regen", "https://d-51.awsapps.com/start", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, err := repo.GetAWSAccount("000000000051")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	if err := repo.AddAWSRole(account.ID, "ReadOnly", "", "regen-ro", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSRole() error: %v", err)
+	}
+
+	cs := &ConfigSync{configPath: filepath.Join(t.TempDir(), ".aws", "config"), dbRepo: repo}
+	written, err := cs.RegenerateAWSConfig()
+	if err != nil || !written {
+		t.Fatalf("RegenerateAWSConfig() = %v, %v, want the missing file written", written, err)
+	}
+	data, err := os.ReadFile(cs.configPath)
+	if err != nil || !strings.Contains(string(data), "[profile regen-ro]") {
+		t.Fatalf("config = %q, %v, want the generated profile", data, err)
+	}
+
+	if written, err := cs.RegenerateAWSConfig(); err != nil || written {
+		t.Errorf("RegenerateAWSConfig() unchanged = %v, %v, want nothing written", written, err)
+	}
+
+	if cs.ExternalChange() {
+		t.Error("ExternalChange() = true for the file rw wrote")
+	}
+	edited := "[profile granted]\nregion = eu-west-2\n"
+	if err := os.WriteFile(cs.configPath, []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !cs.ExternalChange() {
+		t.Error("ExternalChange() = false after another tool edited the file")
+	}
+	if written, err := cs.RegenerateAWSConfig(); !errors.Is(err, ErrExternalConfigChange) || written {
+		t.Errorf("RegenerateAWSConfig() after an external edit = %v, %v, want ErrExternalConfigChange", written, err)
+	}
+	if data, _ := os.ReadFile(cs.configPath); string(data) != edited {
+		t.Errorf("config = %q, want the external edit kept", data)
+	}
+
+	// Switching profiles rewrites [default] but keeps the change flagged
+	if err := writeDefaultSection(cs.configPath, ProfileSettings{Lines: []string{"region = eu-west-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !cs.ExternalChange() {
+		t.Error("ExternalChange() = false after a switch, want the external edit still flagged")
+	}
+
+	if err := cs.AcceptAWSConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if cs.ExternalChange() {
+		t.Error("ExternalChange() = true after AcceptAWSConfig")
+	}
+	if written, err := cs.RegenerateAWSConfig(); err != nil || !written {
+		t.Errorf("RegenerateAWSConfig() after accepting = %v, %v, want the file rewritten", written, err)
+	}
+	if data, _ := os.ReadFile(cs.configPath); !strings.Contains(string(data), "[default]\nregion = eu-west-1\n") {
+		t.Errorf("config = %q, want the switched [default] kept", data)
+	}
+
+	// A switch made while rw manages the file survives regeneration
+	if err := writeDefaultSection(cs.configPath, ProfileSettings{Lines: []string{"region = us-east-1", "output = json"}}); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := cs.RegenerateAWSConfig(); err != nil || written {
+		t.Errorf("RegenerateAWSConfig() after a switch = %v, %v, want nothing written", written, err)
+	}
+	if data, _ := os.ReadFile(cs.configPath); !strings.Contains(string(data), "[default]\nregion = us-east-1\noutput = json\n") {
+		t.Errorf("config = %q, want the switched [default] kept", data)
+	}
+
+	if err := SetAWSConfigManaged(false); err != nil {
+		t.Fatal(err)
+	}
+	if AWSConfigManaged() {
+		t.Error("AWSConfigManaged() = true after SetAWSConfigManaged(false)")
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"bufio"
 	"cmp"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"rolewalkers/internal/awsarn"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 )
 
 // ConfigSync handles synchronization between ~/.aws/config and SQLite database
@@ -42,11 +46,9 @@ type ConfigProfile struct {
 	IsSSO        bool
 }
 
-// ssoSessionInfo holds the start URL and region for an SSO session block.
-type ssoSessionInfo struct {
-	StartURL string
-	Region   string
-}
+// defaultRegistrationScopes are written for sso-sessions that weren't
+// imported from an existing config
+const defaultRegistrationScopes = "sso:account:access"
 
 // NewConfigSync creates a new config sync manager
 func NewConfigSync(dbRepo *db.ConfigRepository) (*ConfigSync, error) {
@@ -258,8 +260,23 @@ func (cs *ConfigSync) SyncConfigToDB() (*SyncResult, error) {
 			}
 		}
 
+		// GovCloud and China accounts are recognised by their regions; an
+		// account created without an SSO region defaulted to aws
+		if region := cmp.Or(p.SSORegion, p.Region); region != "" {
+			if partition := awsarn.PartitionForRegion(region); partition != account.Partition {
+				if err := cs.dbRepo.SetAccountPartition(account.AccountID, partition); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to set partition: %v", p.Name, err))
+				}
+			}
+		}
+
 		// Check if role already exists
 		existingRole, _ := cs.dbRepo.GetRoleByProfileName(p.Name)
+		if existingRole != nil && existingRole.AccountID != account.ID {
+			// Same profile name, different account: never silently repoint it
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: profile name already used by a role in another account", p.Name))
+			continue
+		}
 		if existingRole != nil {
 			// Update if changed
 			needsUpdate := false
@@ -304,6 +321,16 @@ func (cs *ConfigSync) SyncConfigToDB() (*SyncResult, error) {
 		result.Imported++
 	}
 
+	// Saved once the accounts exist, as sessions no account uses are pruned
+	for _, name := range slices.Sorted(maps.Keys(ssoSessions)) {
+		if ssoSessions[name].StartURL == "" {
+			continue
+		}
+		if _, err := cs.dbRepo.SaveSSOSession(ssoSessions[name]); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("sso-session %s: failed to save: %v", name, err))
+		}
+	}
+
 	return result, nil
 }
 
@@ -314,64 +341,104 @@ func (cs *ConfigSync) GenerateAWSConfig() (string, error) {
 		return "", fmt.Errorf("failed to get accounts: %w", err)
 	}
 
+	stored, err := cs.dbRepo.GetAllSSOSessions()
+	if err != nil {
+		return "", fmt.Errorf("failed to get sso-sessions: %w", err)
+	}
+
 	var sb strings.Builder
 
-	// Collect unique SSO start URLs to generate [sso-session] blocks
-	ssoSessions := make(map[string]string) // sessionName -> startURL
-	ssoSessionRegions := make(map[string]string)
+	// sso-sessions imported from the config are reproduced as they were;
+	// accounts with another start URL get a session derived from the URL
+	ssoSessions := make(map[string]db.SSOSession)
+	sessionByURL := make(map[string]string)
+	for _, s := range stored {
+		ssoSessions[s.Name] = s
+		if _, ok := sessionByURL[s.StartURL]; !ok {
+			sessionByURL[s.StartURL] = s.Name
+		}
+	}
+	sessionName := func(account *db.AWSAccount) string {
+		if name, ok := sessionByURL[account.SSOStartURL.String]; ok {
+			return name
+		}
+		return cs.deriveSSOSessionName(account)
+	}
 	for _, account := range accounts {
 		if account.SSOStartURL.Valid && account.SSOStartURL.String != "" {
-			// Derive a session name from the account name or URL
-			sessionName := cs.deriveSSOSessionName(&account)
-			ssoSessions[sessionName] = account.SSOStartURL.String
-			if account.SSORegion.Valid && account.SSORegion.String != "" {
-				ssoSessionRegions[sessionName] = account.SSORegion.String
+			name := sessionName(&account)
+			if _, ok := ssoSessions[name]; !ok {
+				ssoSessions[name] = db.SSOSession{
+					Name:               name,
+					StartURL:           account.SSOStartURL.String,
+					Region:             account.SSORegion.String,
+					RegistrationScopes: defaultRegistrationScopes,
+				}
 			}
 		}
 	}
 
-	// Write [sso-session] blocks first
-	for sessionName, startURL := range ssoSessions {
-		fmt.Fprintf(&sb, "[sso-session %s]\n", sessionName)
-		fmt.Fprintf(&sb, "sso_start_url = %s\n", startURL)
-		if region, ok := ssoSessionRegions[sessionName]; ok {
-			fmt.Fprintf(&sb, "sso_region = %s\n", region)
+	// Write [sso-session] blocks first, sorted so the output is stable
+	for _, name := range slices.Sorted(maps.Keys(ssoSessions)) {
+		s := ssoSessions[name]
+		fmt.Fprintf(&sb, "[sso-session %s]\n", name)
+		fmt.Fprintf(&sb, "sso_start_url = %s\n", s.StartURL)
+		if s.Region != "" {
+			fmt.Fprintf(&sb, "sso_region = %s\n", s.Region)
+		}
+		if s.RegistrationScopes != "" {
+			fmt.Fprintf(&sb, "sso_registration_scopes = %s\n", s.RegistrationScopes)
+		}
+		for _, setting := range s.Settings {
+			fmt.Fprintf(&sb, "%s = %s\n", setting.Key, setting.Value)
 		}
-		sb.WriteString("sso_registration_scopes = sso:account:access\n")
 		sb.WriteString("\n")
 	}
 
-	// Find the active session to write as [default]
-	_, activeRole, activeAccount, err := cs.dbRepo.GetActiveSession()
-	if err == nil && activeRole != nil && activeAccount != nil {
+	// Profile switches write [default] straight to the file, so it's kept
+	// as it is; without one, the active session is written as [default]
+	if lines := currentDefaultSection(cs.configPath); lines != nil {
+		sb.WriteString("[default]\n")
+		for _, line := range lines {
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("\n")
+	} else if _, activeRole, activeAccount, err := cs.dbRepo.GetActiveSession(); err == nil && activeRole != nil && activeAccount != nil {
 		sb.WriteString("[default]\n")
 		fmt.Fprintf(&sb, "region = %s\n", activeRole.Region)
 		sb.WriteString("output = json\n")
 		if activeAccount.SSOStartURL.Valid && activeAccount.SSOStartURL.String != "" {
-			sessionName := cs.deriveSSOSessionName(activeAccount)
-			fmt.Fprintf(&sb, "sso_session = %s\n", sessionName)
+			fmt.Fprintf(&sb, "sso_session = %s\n", sessionName(activeAccount))
 			fmt.Fprintf(&sb, "sso_account_id = %s\n", activeAccount.AccountID)
 			fmt.Fprintf(&sb, "sso_role_name = %s\n", activeRole.RoleName)
 		}
 		sb.WriteString("\n")
 	}
 
-	// Write all roles as named profiles
+	// Write all roles as named profiles, grouped by account and ordered by
+	// role name. One query rather than one per account.
+	allRoles, err := cs.dbRepo.GetAllAWSRoles()
+	if err != nil {
+		return "", fmt.Errorf("failed to get roles: %w", err)
+	}
+	rolesByAccount := make(map[int][]db.AWSRole)
+	for _, role := range allRoles {
+		rolesByAccount[role.AccountID] = append(rolesByAccount[role.AccountID], role)
+	}
+
 	for _, account := range accounts {
-		roles, err := cs.dbRepo.GetRolesByAccount(account.AccountID)
-		if err != nil {
-			continue
-		}
+		roles := rolesByAccount[account.ID]
+		slices.SortFunc(roles, func(a, b db.AWSRole) int { return strings.Compare(a.RoleName, b.RoleName) })
 
-		sessionName := ""
+		session := ""
 		if account.SSOStartURL.Valid && account.SSOStartURL.String != "" {
-			sessionName = cs.deriveSSOSessionName(&account)
+			session = sessionName(&account)
 		}
 
 		for _, role := range roles {
 			fmt.Fprintf(&sb, "[profile %s]\n", role.ProfileName)
-			if sessionName != "" {
-				fmt.Fprintf(&sb, "sso_session = %s\n", sessionName)
+			if session != "" {
+				fmt.Fprintf(&sb, "sso_session = %s\n", session)
 				fmt.Fprintf(&sb, "sso_account_id = %s\n", account.AccountID)
 				fmt.Fprintf(&sb, "sso_role_name = %s\n", role.RoleName)
 			}
@@ -387,6 +454,28 @@ func (cs *ConfigSync) GenerateAWSConfig() (string, error) {
 	return sb.String(), nil
 }
 
+// currentDefaultSection returns the settings of the [default] section in
+// the config file, or nil when the file has none
+func currentDefaultSection(configPath string) []string {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	inDefault := false
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if matches := configProfileRegex.FindStringSubmatch(trimmed); matches != nil {
+			inDefault = matches[1] == "default"
+			continue
+		}
+		if inDefault && strings.Contains(trimmed, "=") && !strings.HasPrefix(trimmed, "#") {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
 // deriveSSOSessionName generates a consistent sso-session name from an account
 func (cs *ConfigSync) deriveSSOSessionName(account *db.AWSAccount) string {
 	if !account.SSOStartURL.Valid {
@@ -395,7 +484,8 @@ func (cs *ConfigSync) deriveSSOSessionName(account *db.AWSAccount) string {
 	// Use a consistent name based on the SSO URL domain
 	url := account.SSOStartURL.String
 	// Extract the subdomain: https://d-9c67711d98.awsapps.com/start -> d-9c67711d98
-	if strings.Contains(url, "awsapps.com") {
+	// (awsapps.cn in China)
+	if strings.Contains(url, ".awsapps.") {
 		parts := strings.Split(url, "//")
 		if len(parts) > 1 {
 			host := strings.Split(parts[1], ".")[0]
@@ -418,7 +508,27 @@ func (cs *ConfigSync) WriteAWSConfig() error {
 		return fmt.Errorf("failed to create .aws directory: %w", err)
 	}
 
-	return os.WriteFile(cs.configPath, []byte(content), 0600)
+	if err := utils.WriteFileAtomic(cs.configPath, []byte(content), 0600); err != nil {
+		return err
+	}
+	return recordConfigWrite(cs.configPath, []byte(content))
+}
+
+// DiffAWSConfig returns a unified diff between the current ~/.aws/config and
+// the content that WriteAWSConfig would generate. An empty string means the
+// file is already up to date.
+func (cs *ConfigSync) DiffAWSConfig() (string, error) {
+	generated, err := cs.GenerateAWSConfig()
+	if err != nil {
+		return "", err
+	}
+
+	current, err := os.ReadFile(cs.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return utils.UnifiedDiff(cs.configPath, cs.configPath+" (generated)", string(current), generated), nil
 }
 
 // BackupConfigFile creates a backup of the current ~/.aws/config
@@ -451,10 +561,11 @@ func (cs *ConfigSync) deriveAccountName(profileName string) string {
 	return name
 }
 
-// extractSSOSessions parses the config file for [sso-session X] blocks in a single pass,
-// returning both the start URL and region for each session.
-func (cs *ConfigSync) extractSSOSessions() map[string]ssoSessionInfo {
-	result := make(map[string]ssoSessionInfo)
+// extractSSOSessions parses the config file for [sso-session X] blocks in a
+// single pass, returning every setting of each session; keys other than the
+// start URL, region and registration scopes are kept in file order.
+func (cs *ConfigSync) extractSSOSessions() map[string]db.SSOSession {
+	result := make(map[string]db.SSOSession)
 
 	file, err := os.Open(cs.configPath)
 	if err != nil {
@@ -470,6 +581,7 @@ func (cs *ConfigSync) extractSSOSessions() map[string]ssoSessionInfo {
 
 		if matches := configSSOSessionRegex.FindStringSubmatch(line); matches != nil {
 			currentSession = matches[1]
+			result[currentSession] = db.SSOSession{Name: currentSession}
 			continue
 		}
 
@@ -477,6 +589,9 @@ func (cs *ConfigSync) extractSSOSessions() map[string]ssoSessionInfo {
 			currentSession = ""
 			continue
 		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
 
 		if currentSession != "" && strings.Contains(line, "=") {
 			parts := strings.SplitN(line, "=", 2)
@@ -489,6 +604,10 @@ func (cs *ConfigSync) extractSSOSessions() map[string]ssoSessionInfo {
 				info.StartURL = value
 			case "sso_region":
 				info.Region = value
+			case "sso_registration_scopes":
+				info.RegistrationScopes = value
+			default:
+				info.Settings = append(info.Settings, db.SSOSessionSetting{Key: key, Value: value})
 			}
 			result[currentSession] = info
 		}
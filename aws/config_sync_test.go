@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestSSOSessionRoundTrip(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	original := `[sso-session corp]
+sso_start_url = https://d-777.awsapps.com/start
+sso_region = eu-west-1
+# comments are dropped
+sso_registration_scopes = sso:account:access,codewhisperer:completions
+sso_custom_setting = keep-me
+
+[profile corp-dev]
+sso_session = corp
+sso_account_id = 000000000077
+sso_role_name = ReadOnly
+region = eu-west-2
+`
+	if err := os.WriteFile(configPath, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := &ConfigSync{configPath: configPath, dbRepo: db.NewConfigRepository(database)}
+	result, err := cs.SyncConfigToDB()
+	if err != nil || len(result.Errors) > 0 || result.Imported != 1 {
+		t.Fatalf("SyncConfigToDB() = %+v, %v, want one imported profile", result, err)
+	}
+
+	generated, err := cs.GenerateAWSConfig()
+	if err != nil {
+		t.Fatalf("GenerateAWSConfig() error: %v", err)
+	}
+	wantSession := `[sso-session corp]
+sso_start_url = https://d-777.awsapps.com/start
+sso_region = eu-west-1
+sso_registration_scopes = sso:account:access,codewhisperer:completions
+sso_custom_setting = keep-me
+`
+	if !strings.HasPrefix(generated, wantSession) {
+		t.Errorf("generated config starts with %q, want the imported session %q", generated, wantSession)
+	}
+	if strings.Contains(generated, "[sso-session d-777]") {
+		t.Error("generated a derived session next to the imported one")
+	}
+	if !strings.Contains(generated, "[profile corp-dev]\nsso_session = corp\n") {
+		t.Errorf("profile doesn't refer to the imported session:\n%s", generated)
+	}
+	if err := ValidateGeneratedConfig(generated); err != nil {
+		t.Errorf("generated config is invalid: %v", err)
+	}
+}
@@ -1,6 +1,13 @@
 package aws
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
 
 func TestExtractEnvName(t *testing.T) {
 	tests := []struct {
@@ -25,3 +32,73 @@ func TestExtractEnvName(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigManagerProfileCache(t *testing.T) {
+	t.Setenv(utils.StateDirEnv, t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config")
+	cm := &ConfigManager{configPath: configPath}
+
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(configPath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := 0
+	cm.NotifyChanged(func() { changes++ })
+
+	base := time.Now().Add(-time.Hour)
+	write("[profile a]\nregion = eu-west-2\n", base)
+	profiles, err := cm.GetProfiles()
+	if err != nil || len(profiles) != 1 {
+		t.Fatalf("GetProfiles() = %v, %v; want 1 profile", profiles, err)
+	}
+
+	// Unchanged file: served from cache, no notification
+	if _, err := cm.GetProfiles(); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 0 {
+		t.Errorf("changes = %d after re-reading an unchanged file, want 0", changes)
+	}
+
+	write("[profile a]\nregion = eu-west-2\n[profile b]\n", base.Add(time.Second))
+	var names []string
+	for p, err := range cm.Profiles() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, p.Name)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("Profiles() = %v, want [a b]", names)
+	}
+	if changes != 1 {
+		t.Errorf("changes = %d after the file changed, want 1", changes)
+	}
+
+	// Rewritten with the same profiles: not a change
+	write("[profile a]\nregion = eu-west-2\n\n[profile b]\n", base.Add(2*time.Second))
+	if _, err := cm.GetProfiles(); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 1 {
+		t.Errorf("changes = %d after rewriting the same profiles, want 1", changes)
+	}
+
+	// Same size and mtime: only an explicit Invalidate forces a re-read,
+	// which isn't reported since this process made the change
+	write("[profile a]\nregion = eu-west-2\n\n[profile c]\n", base.Add(2*time.Second))
+	cm.Invalidate()
+	profiles, _ = cm.GetProfiles()
+	if len(profiles) != 2 || profiles[1].Name != "c" {
+		t.Errorf("GetProfiles() after Invalidate = %v, want a and c", profiles)
+	}
+	if changes != 1 {
+		t.Errorf("changes = %d after Invalidate, want 1", changes)
+	}
+}
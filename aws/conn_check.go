@@ -0,0 +1,346 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocols 'rw check' can speak after connecting
+const (
+	ProtocolTCP      = "tcp"
+	ProtocolPostgres = "postgres"
+	ProtocolRedis    = "redis"
+	ProtocolKafka    = "kafka"
+)
+
+// CheckProtocols lists the protocols in the order 'rw check --protocol'
+// documents them
+var CheckProtocols = []string{ProtocolTCP, ProtocolPostgres, ProtocolRedis, ProtocolKafka}
+
+// DefaultCheckTimeout bounds each step of a connectivity check
+const DefaultCheckTimeout = 5 * time.Second
+
+// kafkaTLSPorts are the MSK listener ports that use TLS (TLS, SASL/SCRAM
+// and IAM); 9092 is plaintext
+var kafkaTLSPorts = []int{9094, 9096, 9098}
+
+// CheckTarget is an endpoint to check and how it was found
+type CheckTarget struct {
+	Address  string `json:"address"`  // host:port that is dialled
+	Via      string `json:"via"`      // "tunnel", "direct" or "address"
+	Protocol string `json:"protocol"` // one of CheckProtocols
+	TLS      bool   `json:"tls"`
+	// Remote is the service endpoint behind a tunnel, host:port
+	Remote string `json:"remote,omitempty"`
+	// TunnelID is the active tunnel the check goes through
+	TunnelID string `json:"tunnel_id,omitempty"`
+}
+
+// CheckStep is one stage of a connectivity check: the TCP connect, the TLS
+// handshake, then the protocol handshake
+type CheckStep struct {
+	Name      string        `json:"name"`
+	OK        bool          `json:"ok"`
+	Latency   time.Duration `json:"-"`
+	LatencyMS float64       `json:"latency_ms"`
+	Detail    string        `json:"detail,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// CheckResult is the outcome of a connectivity check. It's OK when every
+// step succeeded.
+type CheckResult struct {
+	Target CheckTarget `json:"target"`
+	Steps  []CheckStep `json:"steps"`
+	OK     bool        `json:"ok"`
+}
+
+// ProtocolForService returns the protocol of a service type from the
+// services table (postgresql, redis, kafka, ...)
+func ProtocolForService(serviceType string) string {
+	switch strings.ToLower(serviceType) {
+	case "postgresql", "postgres":
+		return ProtocolPostgres
+	case "redis":
+		return ProtocolRedis
+	case "kafka":
+		return ProtocolKafka
+	default:
+		return ProtocolTCP
+	}
+}
+
+// ProtocolForPort guesses a protocol from a well-known port
+func ProtocolForPort(port int) string {
+	switch {
+	case port == 5432:
+		return ProtocolPostgres
+	case port == 6379:
+		return ProtocolRedis
+	case port == 9092 || slices.Contains(kafkaTLSPorts, port):
+		return ProtocolKafka
+	default:
+		return ProtocolTCP
+	}
+}
+
+// TunnelOnlyDetail is the detail of a plain TCP check through a tunnel
+// whose remote end neither answered nor closed the connection
+const TunnelOnlyDetail = "tunnel only, remote not verified"
+
+// DefaultCheckTLS reports whether a protocol on a remote host and port is
+// spoken over TLS: Redis on an ElastiCache endpoint uses in-transit
+// encryption, and MSK does on its TLS, SCRAM and IAM listeners. Postgres
+// negotiates TLS in-protocol.
+func DefaultCheckTLS(protocol, remoteHost string, remotePort int) bool {
+	switch protocol {
+	case ProtocolRedis:
+		return isElastiCacheHost(remoteHost)
+	case ProtocolKafka:
+		return slices.Contains(kafkaTLSPorts, remotePort)
+	default:
+		return false
+	}
+}
+
+// isElastiCacheHost reports whether host is an ElastiCache endpoint, e.g.
+// master.zenith-dev.abc123.euw2.cache.amazonaws.com
+func isElastiCacheHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return strings.HasSuffix(host, ".cache.amazonaws.com") || strings.HasSuffix(host, ".cache.amazonaws.com.cn")
+}
+
+// ResolveCheckTarget finds where a service in an environment is reached:
+// through its active tunnel if there is one, otherwise directly at the
+// endpoint from SSM, which needs the VPN or a peered network.
+func (tm *TunnelManager) ResolveCheckTarget(service, env string) (CheckTarget, error) {
+	service = strings.ToLower(service)
+	env = strings.ToLower(env)
+
+	protocol := ProtocolTCP
+	if tm.configRepo != nil {
+		if svc, err := tm.configRepo.GetService(service); err == nil {
+			protocol = ProtocolForService(svc.ServiceType)
+		}
+	}
+
+	if tunnel := tm.state.GetByServiceEnv(service, env); tunnel != nil {
+		return CheckTarget{
+			Address:  net.JoinHostPort("localhost", strconv.Itoa(tunnel.LocalPort)),
+			Via:      "tunnel",
+			Protocol: protocol,
+			TLS:      DefaultCheckTLS(protocol, tunnel.RemoteHost, tunnel.RemotePort),
+			Remote:   net.JoinHostPort(tunnel.RemoteHost, strconv.Itoa(tunnel.RemotePort)),
+			TunnelID: tunnel.ID,
+		}, nil
+	}
+
+	if service == "grpc" {
+		return CheckTarget{}, fmt.Errorf("grpc services are reached through 'rw grpc', not an endpoint; check a port-forward with 'rw check localhost:<port>'")
+	}
+	host, err := tm.getRemoteHost(service, env, TunnelConfig{})
+	if err != nil {
+		return CheckTarget{}, fmt.Errorf("failed to get the %s endpoint for %s: %w", service, env, err)
+	}
+	remotePort := tm.remotePort(service)
+	return CheckTarget{
+		Address:  net.JoinHostPort(host, strconv.Itoa(remotePort)),
+		Via:      "direct",
+		Protocol: protocol,
+		TLS:      DefaultCheckTLS(protocol, host, remotePort),
+	}, nil
+}
+
+// CheckConnection connects to target and performs its protocol's
+// handshake, timing each step. Nothing that needs credentials is sent, so
+// a server that asks for authentication still counts as reachable.
+func CheckConnection(target CheckTarget, timeout time.Duration) *CheckResult {
+	result := &CheckResult{Target: target}
+	step := func(name string, run func() (string, error)) bool {
+		started := time.Now()
+		detail, err := run()
+		latency := time.Since(started)
+		s := CheckStep{Name: name, OK: err == nil, Latency: latency, LatencyMS: float64(latency.Microseconds()) / 1000, Detail: detail}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, s)
+		return err == nil
+	}
+
+	var conn net.Conn
+	if !step("TCP connect", func() (string, error) {
+		var err error
+		conn, err = net.DialTimeout("tcp", target.Address, timeout)
+		if err != nil {
+			return "", err
+		}
+		return conn.RemoteAddr().String(), nil
+	}) {
+		return result
+	}
+	defer func() { conn.Close() }()
+
+	if target.TLS {
+		if !step("TLS handshake", func() (string, error) {
+			host, _, _ := net.SplitHostPort(serviceAddress(target))
+			// This checks reachability, not identity: through a tunnel the
+			// name never matches, and nothing secret is sent
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+			tlsConn.SetDeadline(time.Now().Add(timeout))
+			if err := tlsConn.Handshake(); err != nil {
+				return "", err
+			}
+			conn = tlsConn
+			return tls.VersionName(tlsConn.ConnectionState().Version), nil
+		}) {
+			return result
+		}
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	var ok bool
+	switch target.Protocol {
+	case ProtocolPostgres:
+		ok = step("Postgres handshake", func() (string, error) { return postgresHandshake(conn) })
+	case ProtocolRedis:
+		ok = step("Redis PING", func() (string, error) { return redisPing(conn) })
+	case ProtocolKafka:
+		ok = step("Kafka ApiVersions", func() (string, error) { return kafkaAPIVersions(conn) })
+	default:
+		ok = target.Via != "tunnel" || step("Tunnel probe", func() (string, error) {
+			return tunnelProbe(conn, min(timeout, tunnelProbeWait))
+		})
+	}
+	result.OK = ok
+	return result
+}
+
+// tunnelProbeWait is how long a plain TCP check through a tunnel waits for
+// the tunnel to close the connection
+const tunnelProbeWait = 2 * time.Second
+
+// tunnelProbe waits for the first byte on a connection through a tunnel.
+// The local end of a port-forward accepts every connection; the socat pod
+// behind it closes the connection when it can't reach the remote end. A
+// server that sends nothing first leaves the remote end unverified.
+func tunnelProbe(conn net.Conn, wait time.Duration) (string, error) {
+	conn.SetReadDeadline(time.Now().Add(wait))
+	n, err := conn.Read(make([]byte, 1))
+	switch {
+	case n > 0:
+		return "remote sent data", nil
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return TunnelOnlyDetail, nil
+	default:
+		return "", fmt.Errorf("the tunnel closed the connection; the remote end is unreachable (%v)", err)
+	}
+}
+
+// serviceAddress is the service's own address: the remote end of a
+// tunnel, or the address dialled
+func serviceAddress(target CheckTarget) string {
+	if target.Remote != "" {
+		return target.Remote
+	}
+	return target.Address
+}
+
+// postgresSSLRequestCode is the magic number of a Postgres SSLRequest
+const postgresSSLRequestCode = 80877103
+
+// postgresHandshake sends an SSLRequest, the first message of a Postgres
+// session, which any Postgres server answers with S or N before auth
+func postgresHandshake(conn net.Conn) (string, error) {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint32(msg[0:4], 8)
+	binary.BigEndian.PutUint32(msg[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(msg); err != nil {
+		return "", err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return "", fmt.Errorf("no reply to SSLRequest: %w", err)
+	}
+	switch reply[0] {
+	case 'S':
+		return "server speaks Postgres, TLS available", nil
+	case 'N':
+		return "server speaks Postgres, TLS not offered", nil
+	default:
+		return "", fmt.Errorf("unexpected reply %q; not a Postgres server?", reply[0])
+	}
+}
+
+// redisPing sends PING. +PONG, or an error such as NOAUTH when the server
+// wants a password first, both mean Redis is answering.
+func redisPing(conn net.Conn) (string, error) {
+	if _, err := conn.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+		return "", err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("no reply to PING: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "+PONG":
+		return "PONG", nil
+	case strings.HasPrefix(line, "-"):
+		return "server answered: " + strings.TrimPrefix(line, "-"), nil
+	default:
+		return "", fmt.Errorf("unexpected reply %q; not a Redis server?", line)
+	}
+}
+
+// kafkaAPIVersions sends an ApiVersions v0 request, which brokers answer
+// before SASL authentication
+func kafkaAPIVersions(conn net.Conn) (string, error) {
+	const (
+		apiVersionsKey = 18
+		correlationID  = 0x72770001
+		clientID       = "rw-check"
+	)
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, int16(apiVersionsKey))
+	binary.Write(&body, binary.BigEndian, int16(0))
+	binary.Write(&body, binary.BigEndian, int32(correlationID))
+	binary.Write(&body, binary.BigEndian, int16(len(clientID)))
+	body.WriteString(clientID)
+
+	msg := binary.BigEndian.AppendUint32(nil, uint32(body.Len()))
+	if _, err := conn.Write(append(msg, body.Bytes()...)); err != nil {
+		return "", err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", fmt.Errorf("no reply to ApiVersions: %w", err)
+	}
+	size := binary.BigEndian.Uint32(header)
+	if size < 10 || size > 1<<20 {
+		return "", fmt.Errorf("unexpected reply size %d; not a Kafka broker?", size)
+	}
+	reply := make([]byte, size)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return "", fmt.Errorf("truncated ApiVersions reply: %w", err)
+	}
+	if binary.BigEndian.Uint32(reply[0:4]) != correlationID {
+		return "", errors.New("reply doesn't match the request; not a Kafka broker?")
+	}
+	if code := int16(binary.BigEndian.Uint16(reply[4:6])); code != 0 {
+		return "", fmt.Errorf("broker returned error code %d", code)
+	}
+	return fmt.Sprintf("broker supports %d APIs", binary.BigEndian.Uint32(reply[6:10])), nil
+}
@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer answers the first request on each connection with reply
+func fakeServer(t *testing.T, requestSize int, reply func(request []byte) []byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			request := make([]byte, requestSize)
+			if _, err := io.ReadFull(conn, request); err == nil {
+				conn.Write(reply(request))
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestCheckConnection(t *testing.T) {
+	postgres := fakeServer(t, 8, func([]byte) []byte { return []byte("S") })
+	redis := fakeServer(t, 14, func([]byte) []byte { return []byte("-NOAUTH Authentication required.\r\n") })
+	kafka := fakeServer(t, 22, func(request []byte) []byte {
+		// size, then the request's correlation ID, error 0 and 3 APIs
+		reply := binary.BigEndian.AppendUint32(nil, 10)
+		reply = append(reply, request[8:12]...)
+		reply = binary.BigEndian.AppendUint16(reply, 0)
+		return binary.BigEndian.AppendUint32(reply, 3)
+	})
+	notRedis := fakeServer(t, 14, func([]byte) []byte { return []byte("HTTP/1.1 400 Bad Request\r\n") })
+
+	tests := []struct {
+		name     string
+		address  string
+		protocol string
+		ok       bool
+		detail   string
+	}{
+		{"postgres", postgres, ProtocolPostgres, true, "TLS available"},
+		{"redis asking for auth", redis, ProtocolRedis, true, "NOAUTH"},
+		{"kafka", kafka, ProtocolKafka, true, "3 APIs"},
+		{"tcp only", redis, ProtocolTCP, true, ""},
+		{"wrong protocol", notRedis, ProtocolRedis, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckConnection(CheckTarget{Address: tt.address, Protocol: tt.protocol}, time.Second)
+			if result.OK != tt.ok {
+				t.Fatalf("CheckConnection() OK = %v, want %v: %+v", result.OK, tt.ok, result.Steps)
+			}
+			last := result.Steps[len(result.Steps)-1]
+			if !strings.Contains(last.Detail, tt.detail) {
+				t.Errorf("last step detail = %q, want it to contain %q", last.Detail, tt.detail)
+			}
+		})
+	}
+
+	// Nothing listening
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().String()
+	ln.Close()
+	result := CheckConnection(CheckTarget{Address: closed, Protocol: ProtocolPostgres}, time.Second)
+	if result.OK || len(result.Steps) != 1 || result.Steps[0].Error == "" {
+		t.Errorf("CheckConnection(closed port) = %+v, want a failed TCP connect only", result)
+	}
+}
+
+func TestCheckConnectionThroughTunnel(t *testing.T) {
+	// A tunnel whose socat pod can't reach the remote end accepts, then closes
+	closing, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closing.Close()
+	go func() {
+		for {
+			conn, err := closing.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// A server that waits for the client to speak first
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	result := CheckConnection(CheckTarget{Address: closing.Addr().String(), Via: "tunnel", Protocol: ProtocolTCP}, time.Second)
+	if result.OK || len(result.Steps) != 2 || !strings.Contains(result.Steps[1].Error, "remote end is unreachable") {
+		t.Errorf("CheckConnection(closing tunnel) = %+v, want a failed tunnel probe", result.Steps)
+	}
+
+	result = CheckConnection(CheckTarget{Address: silent.Addr().String(), Via: "tunnel", Protocol: ProtocolTCP}, 100*time.Millisecond)
+	if !result.OK || result.Steps[len(result.Steps)-1].Detail != TunnelOnlyDetail {
+		t.Errorf("CheckConnection(silent remote) = %+v, want OK with %q", result.Steps, TunnelOnlyDetail)
+	}
+
+	// Directly there's no tunnel to close the connection
+	result = CheckConnection(CheckTarget{Address: closing.Addr().String(), Via: "direct", Protocol: ProtocolTCP}, time.Second)
+	if !result.OK || len(result.Steps) != 1 {
+		t.Errorf("CheckConnection(direct) = %+v, want the TCP connect only", result.Steps)
+	}
+}
+
+func TestDefaultCheckTLS(t *testing.T) {
+	if !DefaultCheckTLS(ProtocolRedis, "master.zenith-dev.abc123.euw2.cache.amazonaws.com", 6379) || !DefaultCheckTLS(ProtocolKafka, "b-1.msk", 9098) {
+		t.Error("ElastiCache Redis and MSK IAM should default to TLS")
+	}
+	if DefaultCheckTLS(ProtocolRedis, "localhost", 6379) || DefaultCheckTLS(ProtocolRedis, "redis.internal", 6379) {
+		t.Error("Redis outside ElastiCache should not default to TLS")
+	}
+	if DefaultCheckTLS(ProtocolKafka, "b-1.msk", 9092) || DefaultCheckTLS(ProtocolPostgres, "db", 5432) {
+		t.Error("plaintext Kafka and Postgres should not default to TLS")
+	}
+}
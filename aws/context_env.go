@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"strings"
+
+	"rolewalkers/internal/db"
+)
+
+// clusterFromContext returns the cluster part of an EKS context name, e.g.
+// "arn:aws:eks:eu-west-2:123:cluster/dev-zenith-eks-cluster" →
+// "dev-zenith-eks-cluster"
+func clusterFromContext(kubeContext string) string {
+	if i := strings.LastIndex(kubeContext, "/"); i >= 0 {
+		return kubeContext[i+1:]
+	}
+	return kubeContext
+}
+
+// ResolveContextEnvironment returns the environment the active kubectl
+// context or AWS profile points at, using only local state. The context's
+// cluster is matched first; the profile is only used when exactly one
+// environment uses it, since accounts are often shared between environments.
+func ResolveContextEnvironment(envs []db.Environment, profile, kubeContext string) string {
+	cluster := clusterFromContext(kubeContext)
+	if cluster != "" {
+		for _, env := range envs {
+			if env.ClusterName == cluster {
+				return env.Name
+			}
+		}
+		if name := envFromClusterName(cluster); name != "" {
+			return name
+		}
+	}
+
+	match := ""
+	for _, env := range envs {
+		if profile == "" || env.AWSProfile != profile {
+			continue
+		}
+		if match != "" {
+			return ""
+		}
+		match = env.Name
+	}
+	return match
+}
@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestResolveContextEnvironment(t *testing.T) {
+	envs := []db.Environment{
+		{Name: "dev", AWSProfile: "zenith-dev", ClusterName: "dev-zenith-eks-cluster"},
+		{Name: "trg", AWSProfile: "zenith-dev", ClusterName: "trg-zenith-eks-cluster"},
+		{Name: "prod", AWSProfile: "zenith-prod", ClusterName: "prod-cluster"},
+	}
+
+	tests := []struct {
+		name        string
+		profile     string
+		kubeContext string
+		expected    string
+	}{
+		{"cluster from ARN", "zenith-dev", "arn:aws:eks:eu-west-2:123:cluster/trg-zenith-eks-cluster", "trg"},
+		{"cluster name", "", "prod-cluster", "prod"},
+		{"unknown cluster by naming convention", "", "qa-zenith-eks-cluster", "qa"},
+		{"unique profile", "zenith-prod", "", "prod"},
+		{"shared profile is ambiguous", "zenith-dev", "", ""},
+		{"nothing active", "", "", ""},
+	}
+	for _, tt := range tests {
+		if got := ResolveContextEnvironment(envs, tt.profile, tt.kubeContext); got != tt.expected {
+			t.Errorf("%s: ResolveContextEnvironment() = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestReadKubeconfigContext(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	os.WriteFile(first, []byte("contexts:\n- name: dev\n  context:\n    cluster: dev\n"), 0o600)
+	os.WriteFile(second, []byte("current-context: dev\ncontexts:\n- name: dev\n  context:\n    namespace: ignored\n- name: prod\n  context:\n    namespace: zenith\n"), 0o600)
+
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+	ctx, ns := ReadKubeconfigContext()
+	if ctx != "dev" || ns != "default" {
+		t.Errorf("ReadKubeconfigContext() = (%q, %q), want (dev, default): the first definition of a context wins", ctx, ns)
+	}
+
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "missing"))
+	if ctx, ns := ReadKubeconfigContext(); ctx != "" || ns != "default" {
+		t.Errorf("ReadKubeconfigContext() = (%q, %q), want (\"\", default)", ctx, ns)
+	}
+}
@@ -3,13 +3,20 @@ package aws
 import (
 	"bytes"
 	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path/filepath"
 	"rolewalkers/internal/awscli"
 	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/db"
 	"rolewalkers/internal/k8s"
 	"rolewalkers/internal/utils"
 	"strings"
+	"time"
 )
 
 // DatabaseManager handles database connection operations
@@ -17,6 +24,7 @@ type DatabaseManager struct {
 	kubeManager     *KubeManager
 	ssmManager      *SSMManager
 	profileSwitcher *ProfileSwitcher
+	configRepo      *db.ConfigRepository
 }
 
 // DatabaseConfig holds configuration for a database connection
@@ -29,11 +37,12 @@ type DatabaseConfig struct {
 }
 
 // NewDatabaseManagerWithDeps creates a new DatabaseManager with shared dependencies
-func NewDatabaseManagerWithDeps(km *KubeManager, ssm *SSMManager, ps *ProfileSwitcher) *DatabaseManager {
+func NewDatabaseManagerWithDeps(km *KubeManager, ssm *SSMManager, ps *ProfileSwitcher, repo *db.ConfigRepository) *DatabaseManager {
 	return &DatabaseManager{
 		kubeManager:     km,
 		ssmManager:      ssm,
 		profileSwitcher: ps,
+		configRepo:      repo,
 	}
 }
 
@@ -101,13 +110,14 @@ func (dm *DatabaseManager) generateIAMAuthToken(rdsEndpoint, user string) (strin
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%w: %s", err, stderr.String())
+		return "", fmt.Errorf("%w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	token := strings.TrimSpace(out.String())
 	if token == "" {
 		return "", fmt.Errorf("IAM auth token was empty")
 	}
+	utils.TrackSecret(token)
 
 	return token, nil
 }
@@ -167,17 +177,47 @@ func (dm *DatabaseManager) Connect(config DatabaseConfig) error {
 	return dm.runPsqlPod(endpoint, creds.User, creds.Password, sslMode)
 }
 
+// DSN resolves the endpoint and credentials for a database and returns a
+// PostgreSQL connection URL. IAM tokens embedded in the URL expire after 15 minutes.
+func (dm *DatabaseManager) DSN(config DatabaseConfig) (string, error) {
+	env := strings.ToLower(config.Environment)
+	config.NodeType = cmp.Or(strings.ToLower(config.NodeType), "read")
+	config.DBType = cmp.Or(strings.ToLower(config.DBType), "query")
+
+	endpoint, err := dm.ssmManager.GetDatabaseEndpoint(env, config.NodeType, config.DBType)
+	if err != nil {
+		return "", fmt.Errorf("failed to get database endpoint: %w", err)
+	}
+
+	creds, err := dm.resolveDBCredentials(env, config)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := appconfig.Get()
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(creds.User, creds.Password),
+		Host:     fmt.Sprintf("%s:%d", endpoint, cfg.Database.Port),
+		Path:     "/" + cfg.Database.DefaultDB,
+		RawQuery: "sslmode=require",
+	}
+	return u.String(), nil
+}
+
 // runPsqlPod spawns an interactive psql pod
 func (dm *DatabaseManager) runPsqlPod(endpoint, user, password, sslMode string) error {
 	cfg := appconfig.Get()
 	connStr := fmt.Sprintf("host=%s port=%d dbname=%s user=%s sslmode=%s", endpoint, cfg.Database.Port, cfg.Database.DefaultDB, user, sslMode)
-	return k8s.RunPod(k8s.PodSpec{
+	pod := k8s.NewPodSession(k8s.PodSpec{
 		NamePrefix:  "psql",
 		Image:       cfg.Images.Postgres,
 		Interactive: true,
 		Command:     []string{"psql", connStr},
 		Env:         map[string]string{"PGPASSWORD": password},
 	})
+	defer pod.Close()
+	return pod.Run()
 }
 
 
@@ -186,6 +226,7 @@ func (dm *DatabaseManager) runPsqlPod(endpoint, user, password, sslMode string)
 type BackupConfig struct {
 	Environment string
 	OutputFile  string
+	S3URI       string // stream the dump to s3://bucket/key instead of OutputFile
 	SchemaOnly  bool
 }
 
@@ -193,21 +234,32 @@ type BackupConfig struct {
 type RestoreConfig struct {
 	Environment string
 	InputFile   string
+	S3URI       string // restore from s3://bucket/key instead of InputFile
+	BackupID    int    // catalog entry the input was resolved from (verified before restoring)
 	Clean       bool
 }
 
-// Backup performs a database backup using pg_dump via a temporary pod
+// Backup performs a database backup using pg_dump via a temporary pod,
+// tracked as a job
 func (dm *DatabaseManager) Backup(config BackupConfig) error {
+	return runJob(dm.configRepo, db.JobBackup, strings.ToLower(config.Environment), cmp.Or(config.S3URI, config.OutputFile), func(job *Job) error {
+		return dm.backup(config, job)
+	})
+}
+
+func (dm *DatabaseManager) backup(config BackupConfig, job *Job) error {
 	env := strings.ToLower(config.Environment)
 
 	// Switch kubectl context to the environment
 	fmt.Printf("Switching kubectl context to %s...\n", env)
+	job.Progress("Switching kubectl context")
 	if err := dm.kubeManager.SwitchContextForEnvWithProfile(env, dm.profileSwitcher); err != nil {
 		return fmt.Errorf("failed to switch kubectl context: %w", err)
 	}
 
 	// Get database endpoint from SSM (use write node for backup to get latest data)
 	fmt.Println("Fetching database endpoint...")
+	job.Progress("Fetching database endpoint")
 	endpoint, err := dm.ssmManager.GetDatabaseEndpoint(env, "write", "query")
 	if err != nil {
 		return fmt.Errorf("failed to get database endpoint: %w", err)
@@ -225,19 +277,20 @@ func (dm *DatabaseManager) Backup(config BackupConfig) error {
 	fmt.Printf("\nStarting database backup:\n")
 	fmt.Printf("  Environment: %s\n", env)
 	fmt.Printf("  Endpoint:    %s\n", endpoint)
-	fmt.Printf("  Output:      %s\n", config.OutputFile)
+	fmt.Printf("  Output:      %s\n", cmp.Or(config.S3URI, config.OutputFile))
 	if config.SchemaOnly {
 		fmt.Printf("  Mode:        Schema only\n")
 	} else {
 		fmt.Printf("  Mode:        Full backup (schema + data)\n")
 	}
 	fmt.Println("\nRunning pg_dump...")
+	job.Progress("Running pg_dump")
 
 	return dm.runPgDumpPod(endpoint, password, config)
 }
 
 // runPgDumpPod spawns a temporary pod to run pg_dump and captures output to file
-func (dm *DatabaseManager) runPgDumpPod(endpoint, password string, config BackupConfig) (err error) {
+func (dm *DatabaseManager) runPgDumpPod(endpoint, password string, config BackupConfig) error {
 	cfg := appconfig.Get()
 	pgDumpArgs := []string{
 		"pg_dump",
@@ -249,94 +302,215 @@ func (dm *DatabaseManager) runPgDumpPod(endpoint, password string, config Backup
 		pgDumpArgs = append(pgDumpArgs, "--schema-only")
 	}
 
-	// Create output file
-	outFile, err := os.Create(config.OutputFile)
+	if config.S3URI != "" {
+		return dm.runPgDumpToS3(pgDumpArgs, password, config)
+	}
+
+	// Write to a .partial file, renamed once pg_dump has finished, so an
+	// interrupted dump never looks like a complete backup
+	partialFile := config.OutputFile + partialBackupSuffix
+	outFile, err := os.Create(partialFile)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer func() {
-		if cerr := outFile.Close(); cerr != nil && err == nil {
-			err = fmt.Errorf("failed to close file: %w", cerr)
-		}
-	}()
 
+	ctx, stop := backupSignalContext()
+	defer stop()
+
+	out := &countingWriter{w: outFile}
 	var stderr bytes.Buffer
+	hasher := sha256.New()
+	started := time.Now()
 
-	runErr := k8s.RunPod(k8s.PodSpec{
+	pod := k8s.NewPodSession(k8s.PodSpec{
 		NamePrefix: "pgdump",
 		Image:      cfg.Images.Postgres,
 		Command:    pgDumpArgs,
 		Env:        map[string]string{"PGPASSWORD": password},
 		Operation:  "backup",
-		Stdout:     outFile,
+		Stdout:     io.MultiWriter(out, hasher),
 		Stderr:     &stderr,
+		Context:    ctx,
 	})
+	defer pod.Close()
+	runErr := pod.Run()
+	closeErr := outFile.Close()
 
+	if ctx.Err() != nil {
+		return dm.backupInterrupted(pod, config, partialFile, out.n, time.Since(started))
+	}
 	if runErr != nil {
-		outFile.Close()
-		os.Remove(config.OutputFile)
-		return fmt.Errorf("pg_dump failed: %w: %s", runErr, stderr.String())
+		os.Remove(partialFile)
+		return fmt.Errorf("pg_dump failed: %w: %s", runErr, utils.Redact(stderr.String()))
 	}
+	if closeErr != nil {
+		os.Remove(partialFile)
+		return fmt.Errorf("failed to close file: %w", closeErr)
+	}
+	if err := os.Rename(partialFile, config.OutputFile); err != nil {
+		return fmt.Errorf("failed to move the backup into place: %w", err)
+	}
+	size := out.n
 
-	// Get file size
-	fileInfo, _ := os.Stat(config.OutputFile)
-	size := fileInfo.Size()
-
-	fmt.Printf("\n✓ Backup completed successfully!\n")
+	fmt.Print("\n" + utils.OK() + " Backup completed successfully!\n")
 	fmt.Printf("  Output file: %s\n", config.OutputFile)
 	fmt.Printf("  Size: %s\n", utils.FormatBytes(size))
 
+	location := config.OutputFile
+	if abs, absErr := filepath.Abs(location); absErr == nil {
+		location = abs
+	}
+	dm.recordBackup(config, location, size, time.Since(started), hex.EncodeToString(hasher.Sum(nil)))
+
 	return nil
 }
 
-// Restore performs a database restore using psql via a temporary pod
-func (dm *DatabaseManager) Restore(config RestoreConfig) error {
-	env := strings.ToLower(config.Environment)
+// runPgDumpToS3 streams pg_dump output straight to an S3 object using the
+// environment's AWS profile, so the dump never lands on local disk.
+func (dm *DatabaseManager) runPgDumpToS3(pgDumpArgs []string, password string, config BackupConfig) error {
+	cfg := appconfig.Get()
+	profile := dm.kubeManager.getProfileNameForEnv(strings.ToLower(config.Environment))
 
-	// Check if input file exists
-	if _, err := os.Stat(config.InputFile); os.IsNotExist(err) {
-		return fmt.Errorf("input file not found: %s", config.InputFile)
+	upload, w, err := startS3Upload(config.S3URI, profile)
+	if err != nil {
+		return err
 	}
 
-	// Switch kubectl context to the environment
-	fmt.Printf("Switching kubectl context to %s...\n", env)
-	if err := dm.kubeManager.SwitchContextForEnvWithProfile(env, dm.profileSwitcher); err != nil {
-		return fmt.Errorf("failed to switch kubectl context: %w", err)
+	ctx, stop := backupSignalContext()
+	defer stop()
+
+	out := &countingWriter{w: w}
+	hasher := sha256.New()
+	var stderr bytes.Buffer
+	started := time.Now()
+
+	pod := k8s.NewPodSession(k8s.PodSpec{
+		NamePrefix: "pgdump",
+		Image:      cfg.Images.Postgres,
+		Command:    pgDumpArgs,
+		Env:        map[string]string{"PGPASSWORD": password},
+		Operation:  "backup",
+		Stdout:     io.MultiWriter(out, hasher),
+		Stderr:     &stderr,
+		Context:    ctx,
+	})
+	defer pod.Close()
+	runErr := pod.Run()
+	uploadErr := upload.Wait()
+
+	if runErr != nil || ctx.Err() != nil {
+		// Don't leave a truncated dump behind that looks like a valid backup
+		if uploadErr == nil {
+			if err := removeS3Object(config.S3URI, profile); err != nil {
+				fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to remove partial backup %s: %v\n", config.S3URI, err)
+			}
+		}
+		if ctx.Err() != nil {
+			return dm.backupInterrupted(pod, config, config.S3URI, out.n, time.Since(started))
+		}
+		return fmt.Errorf("pg_dump failed: %w: %s", runErr, utils.Redact(stderr.String()))
+	}
+	if uploadErr != nil {
+		return fmt.Errorf("S3 upload failed: %w", uploadErr)
 	}
 
-	// Get database endpoint from SSM (use write node for restore)
-	fmt.Println("Fetching database endpoint...")
-	endpoint, err := dm.ssmManager.GetDatabaseEndpoint(env, "write", "query")
-	if err != nil {
-		return fmt.Errorf("failed to get database endpoint: %w", err)
+	fmt.Print("\n" + utils.OK() + " Backup completed successfully!\n")
+	fmt.Printf("  S3 object: %s\n", config.S3URI)
+	fmt.Printf("  Size: %s\n", utils.FormatBytes(out.n))
+
+	dm.recordBackup(config, config.S3URI, out.n, time.Since(started), hex.EncodeToString(hasher.Sum(nil)))
+
+	return nil
+}
+
+// Restore performs a database restore using psql via a temporary pod,
+// tracked as a job
+func (dm *DatabaseManager) Restore(config RestoreConfig) error {
+	return runJob(dm.configRepo, db.JobRestore, strings.ToLower(config.Environment), cmp.Or(config.S3URI, config.InputFile), func(job *Job) error {
+		return dm.restore(config, job)
+	})
+}
+
+func (dm *DatabaseManager) restore(config RestoreConfig, job *Job) error {
+	env := strings.ToLower(config.Environment)
+
+	if config.BackupID != 0 {
+		job.Progress("Verifying the backup")
+		if err := dm.verifyBackup(config); err != nil {
+			return err
+		}
 	}
 
-	// Get database password from SSM (restore)
-	fmt.Println("Fetching database credentials...")
-	cfg := appconfig.Get()
-	passwordPath := cfg.SSMPath(env, fmt.Sprintf("database/query/db-%s-password", cfg.Database.MasterUser))
-	password, err := dm.ssmManager.GetParameter(passwordPath)
-	if err != nil {
-		return fmt.Errorf("failed to get database password: %w", err)
+	// Check the input exists before touching the cluster
+	var inputSize int64
+	inputName := config.InputFile
+	if config.S3URI != "" {
+		inputName = config.S3URI
+		size, err := s3ObjectSize(config.S3URI, dm.kubeManager.getProfileNameForEnv(env))
+		if err != nil {
+			return err
+		}
+		inputSize = size
+	} else {
+		fileInfo, err := os.Stat(config.InputFile)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("input file not found: %s", config.InputFile)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input file: %w", err)
+		}
+		inputSize = fileInfo.Size()
 	}
 
-	// Get file size for progress info
-	fileInfo, _ := os.Stat(config.InputFile)
+	job.Progress("Connecting to the database")
+	endpoint, password, err := dm.writerConnection(env)
+	if err != nil {
+		return err
+	}
 
 	fmt.Printf("\nStarting database restore:\n")
 	fmt.Printf("  Environment: %s\n", env)
 	fmt.Printf("  Endpoint:    %s\n", endpoint)
-	fmt.Printf("  Input:       %s (%s)\n", config.InputFile, utils.FormatBytes(fileInfo.Size()))
+	fmt.Printf("  Input:       %s (%s)\n", inputName, utils.FormatBytes(inputSize))
 	if config.Clean {
 		fmt.Printf("  Mode:        Clean (drop objects before recreating)\n")
 	} else {
 		fmt.Printf("  Mode:        Standard\n")
 	}
 	fmt.Println("\nRunning psql restore...")
+	job.Progress("Running psql")
 
 	return dm.runPsqlRestorePod(endpoint, password, config)
 }
 
+// writerConnection switches kubectl to the environment and returns the
+// writer endpoint and master password, as used by restores and their checks.
+func (dm *DatabaseManager) writerConnection(env string) (endpoint, password string, err error) {
+	// Switch kubectl context to the environment
+	fmt.Printf("Switching kubectl context to %s...\n", env)
+	if err := dm.kubeManager.SwitchContextForEnvWithProfile(env, dm.profileSwitcher); err != nil {
+		return "", "", fmt.Errorf("failed to switch kubectl context: %w", err)
+	}
+
+	// Get database endpoint from SSM (use write node for restore)
+	fmt.Println("Fetching database endpoint...")
+	endpoint, err = dm.ssmManager.GetDatabaseEndpoint(env, "write", "query")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get database endpoint: %w", err)
+	}
+
+	// Get database password from SSM
+	fmt.Println("Fetching database credentials...")
+	cfg := appconfig.Get()
+	passwordPath := cfg.SSMPath(env, fmt.Sprintf("database/query/db-%s-password", cfg.Database.MasterUser))
+	password, err = dm.ssmManager.GetParameter(passwordPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get database password: %w", err)
+	}
+
+	return endpoint, password, nil
+}
+
 // runPsqlRestorePod spawns a temporary pod to run psql and pipes SQL file to stdin
 func (dm *DatabaseManager) runPsqlRestorePod(endpoint, password string, config RestoreConfig) error {
 	cfg := appconfig.Get()
@@ -348,33 +522,52 @@ func (dm *DatabaseManager) runPsqlRestorePod(endpoint, password string, config R
 		"-v", "ON_ERROR_STOP=1",
 	}
 
-	// Open input file
-	inFile, err := os.Open(config.InputFile)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
+	var input io.Reader
+	var download *s3Stream
+	if config.S3URI != "" {
+		profile := dm.kubeManager.getProfileNameForEnv(strings.ToLower(config.Environment))
+		stream, r, err := startS3Download(config.S3URI, profile)
+		if err != nil {
+			return err
+		}
+		download, input = stream, r
+	} else {
+		inFile, err := os.Open(config.InputFile)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer inFile.Close()
+		input = inFile
 	}
-	defer inFile.Close()
 
 	var stdout, stderr bytes.Buffer
 
-	runErr := k8s.RunPod(k8s.PodSpec{
+	pod := k8s.NewPodSession(k8s.PodSpec{
 		NamePrefix: "psql-restore",
 		Image:      cfg.Images.Postgres,
 		Command:    psqlArgs,
 		Env:        map[string]string{"PGPASSWORD": password},
 		Operation:  "restore",
-		Stdin:      inFile,
+		Stdin:      input,
 		Stdout:     &stdout,
 		Stderr:     &stderr,
 	})
+	defer pod.Close()
+	runErr := pod.Run()
+
+	if download != nil {
+		if err := download.Wait(); err != nil && runErr == nil {
+			return fmt.Errorf("S3 download failed (restore may be incomplete): %w", err)
+		}
+	}
 
 	if runErr != nil {
-		return fmt.Errorf("psql restore failed: %w: %s\n%s", runErr, stderr.String(), stdout.String())
+		return fmt.Errorf("psql restore failed: %w: %s\n%s", runErr, utils.Redact(stderr.String()), utils.Redact(stdout.String()))
 	}
 
-	fmt.Printf("\n✓ Restore completed successfully!\n")
+	fmt.Print("\n" + utils.OK() + " Restore completed successfully!\n")
 	if stdout.Len() > 0 {
-		fmt.Printf("\nOutput:\n%s\n", stdout.String())
+		fmt.Fprintf(utils.Stdout, "\nOutput:\n%s\n", stdout.String())
 	}
 
 	return nil
@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// direnvLibrary defines 'use rolewalkers <env>' for .envrc files. direnv
+// sources every file in its lib directory before an .envrc.
+const direnvLibrary = `# rolewalkers direnv library, installed by 'rw direnv --install'.
+# In an .envrc: use rolewalkers <env>
+use_rolewalkers() {
+  if ! has rw; then
+    log_error "rw not found in PATH"
+    return 1
+  fi
+  local envrc
+  envrc="$(rw direnv "$1")" || return 1
+  eval "$envrc"
+}
+`
+
+// DirenvLibraryPath returns where direnv looks for library functions:
+// $XDG_CONFIG_HOME/direnv/lib, or ~/.config/direnv/lib
+func DirenvLibraryPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "direnv", "lib", "rolewalkers.sh"), nil
+}
+
+// InstallDirenvLibrary writes the 'use rolewalkers' function and returns
+// its path
+func InstallDirenvLibrary() (string, error) {
+	path, err := DirenvLibraryPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := utils.WriteFileAtomic(path, []byte(direnvLibrary), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// DirenvPortVar is the variable a service's local port is exported as,
+// e.g. RW_PORT_DB_COMMAND for db-command
+func DirenvPortVar(service string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, service)
+	return "RW_PORT_" + strings.ToUpper(name)
+}
+
+// GenerateEnvrc returns an .envrc snippet for an environment: its AWS
+// profile and region, RW_ENV for rw's own commands, and the local port of
+// each mapped service. kubectl reads its context from the kubeconfig
+// rather than the environment, so the context is given as a hint.
+func GenerateEnvrc(env db.Environment, ports map[string]int, kubeContext string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s (%s), generated by 'rw direnv %s'\n", envrcComment(env.DisplayName), env.Name, env.Name)
+	sb.WriteString(shellExportVar("bash", "AWS_PROFILE", env.AWSProfile))
+	sb.WriteString(shellExportVar("bash", "AWS_REGION", env.Region))
+	sb.WriteString(shellExportVar("bash", "AWS_DEFAULT_REGION", env.Region))
+	sb.WriteString(shellExportVar("bash", "RW_ENV", env.Name))
+
+	fmt.Fprintf(&sb, "# kubectl: run 'rw kube %s' to use ", env.Name)
+	if kubeContext != "" {
+		fmt.Fprintf(&sb, "context %s", envrcComment(kubeContext))
+	} else {
+		fmt.Fprintf(&sb, "cluster %s", envrcComment(env.ClusterName))
+	}
+	fmt.Fprintf(&sb, " (namespace %s)\n", envrcComment(cmp.Or(env.Namespace, "default")))
+
+	if len(ports) > 0 {
+		sb.WriteString("# Local ports for 'rw tunnel start <service> " + env.Name + "'\n")
+		for _, service := range slices.Sorted(maps.Keys(ports)) {
+			sb.WriteString(shellExportVar("bash", DirenvPortVar(service), fmt.Sprint(ports[service])))
+		}
+	}
+	return sb.String()
+}
+
+// envrcComment makes a value safe inside a comment of the eval'd snippet:
+// a line break would end the comment and run the rest as a command
+func envrcComment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
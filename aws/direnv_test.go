@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestGenerateEnvrc(t *testing.T) {
+	env := db.Environment{Name: "dev", DisplayName: "Development", Region: "eu-west-2",
+		AWSProfile: "zenith-dev", ClusterName: "dev-zenith-eks-cluster", Namespace: "zenith"}
+	got := GenerateEnvrc(env, map[string]int{"redis": 6380, "db-command": 5451}, "")
+
+	for _, want := range []string{
+		"export AWS_PROFILE='zenith-dev'\n",
+		"export AWS_REGION='eu-west-2'\n",
+		"export RW_ENV='dev'\n",
+		"use cluster dev-zenith-eks-cluster (namespace zenith)\n",
+		"export RW_PORT_DB_COMMAND='5451'\nexport RW_PORT_REDIS='6380'\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenerateEnvrc() missing %q in:\n%s", want, got)
+		}
+	}
+
+	// A line break in a name can't end the comment and run as a command
+	env.DisplayName = "Dev\ntouch /tmp/pwned"
+	if got := GenerateEnvrc(env, nil, ""); !strings.HasPrefix(got, "# Dev touch /tmp/pwned (dev)") {
+		t.Errorf("GenerateEnvrc() with a newline in the name = %q", got)
+	}
+
+	got = GenerateEnvrc(env, nil, "arn:aws:eks:eu-west-2:1:cluster/dev")
+	if !strings.Contains(got, "use context arn:aws:eks:eu-west-2:1:cluster/dev") {
+		t.Errorf("GenerateEnvrc() with a context = %q, want the context hint", got)
+	}
+	if strings.Contains(got, "RW_PORT_") {
+		t.Errorf("GenerateEnvrc() without ports exported a port:\n%s", got)
+	}
+}
+
+func TestInstallDirenvLibrary(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path, err := InstallDirenvLibrary()
+	if err != nil {
+		t.Fatalf("InstallDirenvLibrary() error: %v", err)
+	}
+	if want := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "direnv", "lib", "rolewalkers.sh"); path != want {
+		t.Errorf("InstallDirenvLibrary() = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "use_rolewalkers()") {
+		t.Errorf("library = %q, %v, want the use_rolewalkers function", data, err)
+	}
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"rolewalkers/internal/db"
+)
+
+// SuggestClone returns a copy of source named name, with the source name
+// replaced in the display name and cluster name (e.g. cloning sit to sit2
+// suggests cluster sit2-zenith-eks-cluster). Profile, region and namespace
+// are kept: cloned environments normally live in the same account.
+func SuggestClone(source db.Environment, name string) db.Environment {
+	target := source
+	target.ID = 0
+	target.Name = name
+	target.DisplayName = replaceEnvName(source.DisplayName, source.Name, name)
+	target.ClusterName = replaceEnvName(source.ClusterName, source.Name, name)
+	target.Active = true
+	return target
+}
+
+// replaceEnvName replaces the source environment name where it appears as a
+// whole word in s, matching case-insensitively and following the case of the
+// match ("SIT" → "SIT2", "sit-..." → "sit2-..."). If s doesn't contain the
+// source name, the new name is appended to keep the values distinct.
+func replaceEnvName(s, from, to string) string {
+	lower, from := strings.ToLower(s), strings.ToLower(from)
+	for i := strings.Index(lower, from); i >= 0; {
+		end := i + len(from)
+		if isWordBoundary(s, i-1) && isWordBoundary(s, end) {
+			match := s[i:end]
+			if match == strings.ToUpper(match) {
+				to = strings.ToUpper(to)
+			}
+			return s[:i] + to + s[end:]
+		}
+		next := strings.Index(lower[i+1:], from)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return fmt.Sprintf("%s (%s)", s, to)
+}
+
+// isWordBoundary reports whether s[i] is outside s or not a letter or digit
+func isWordBoundary(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return true
+	}
+	r := rune(s[i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// PlanClonePorts picks local ports for a clone of the environment with ID
+// sourceID, keyed by service ID. Each port continues its service's range
+// (max + 1) and never reuses a port that is already mapped.
+func PlanClonePorts(sourceID int, existing []db.PortMapping) map[int]int {
+	used := make(map[int]bool)
+	highest := make(map[int]int)
+	for _, pm := range existing {
+		used[pm.LocalPort] = true
+		highest[pm.ServiceID] = max(highest[pm.ServiceID], pm.LocalPort)
+	}
+
+	ports := make(map[int]int)
+	for _, pm := range existing {
+		if pm.EnvironmentID != sourceID {
+			continue
+		}
+		next := highest[pm.ServiceID] + 1
+		for used[next] {
+			next++
+		}
+		used[next] = true
+		highest[pm.ServiceID] = next
+		ports[pm.ServiceID] = next
+	}
+	return ports
+}
@@ -0,0 +1,61 @@
+package aws
+
+import (
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestSuggestClone(t *testing.T) {
+	source := db.Environment{
+		ID: 3, Name: "sit", DisplayName: "SIT", Region: "eu-west-2",
+		AWSProfile: "zenith-sit", ClusterName: "sit-zenith-eks-cluster", Namespace: "zenith",
+	}
+
+	got := SuggestClone(source, "sit2")
+	want := db.Environment{
+		Name: "sit2", DisplayName: "SIT2", Region: "eu-west-2",
+		AWSProfile: "zenith-sit", ClusterName: "sit2-zenith-eks-cluster", Namespace: "zenith", Active: true,
+	}
+	if got != want {
+		t.Errorf("SuggestClone() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplaceEnvName(t *testing.T) {
+	tests := []struct {
+		s, from, to, want string
+	}{
+		{"Development", "dev", "dev2", "Development (dev2)"},
+		{"sit-zenith-sit", "sit", "sit2", "sit2-zenith-sit"},
+		{"Pre-Production", "preprod", "pp2", "Pre-Production (pp2)"},
+		{"qa-zenith-eks-cluster", "qa", "qa-feature", "qa-feature-zenith-eks-cluster"},
+		{"QA", "qa", "qa-feature", "QA-FEATURE"},
+	}
+	for _, tt := range tests {
+		if got := replaceEnvName(tt.s, tt.from, tt.to); got != tt.want {
+			t.Errorf("replaceEnvName(%q, %q, %q) = %q, want %q", tt.s, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestPlanClonePorts(t *testing.T) {
+	existing := []db.PortMapping{
+		{ServiceID: 1, EnvironmentID: 1, LocalPort: 5432},
+		{ServiceID: 1, EnvironmentID: 2, LocalPort: 5433},
+		{ServiceID: 2, EnvironmentID: 2, LocalPort: 6379},
+		{ServiceID: 3, EnvironmentID: 1, LocalPort: 6380},
+	}
+
+	got := PlanClonePorts(2, existing)
+	// service 1 continues after 5433; service 2 skips 6380 (taken by service 3)
+	want := map[int]int{1: 5434, 2: 6381}
+	if len(got) != len(want) {
+		t.Fatalf("PlanClonePorts() = %v, want %v", got, want)
+	}
+	for svc, port := range want {
+		if got[svc] != port {
+			t.Errorf("PlanClonePorts()[%d] = %d, want %d", svc, got[svc], port)
+		}
+	}
+}
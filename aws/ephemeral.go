@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"rolewalkers/internal/db"
+)
+
+// EnvironmentCleanup reports what was cleaned up for an expired environment
+type EnvironmentCleanup struct {
+	Environment     string
+	ExpiredAt       time.Time
+	TunnelsStopped  int
+	ContextsRemoved []string
+	Errors          []string
+}
+
+// DueExpiries returns the expiries at or before now
+func DueExpiries(expiries []db.EnvironmentExpiry, now time.Time) []db.EnvironmentExpiry {
+	var due []db.EnvironmentExpiry
+	for _, e := range expiries {
+		if !e.ExpiresAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	return due
+}
+
+// ExpireEnvironments cleans up every ephemeral environment whose TTL has
+// passed: its tunnels are stopped, kubectl contexts for its cluster are
+// removed unless another active environment uses the cluster, and its rows
+// are deactivated. tm may be nil to skip tunnels.
+// There is no scheduler process; this runs on the tray's refresh tick and
+// via 'rw env expire', never before other commands, whose output may be
+// read by scripts or the shell prompt.
+func ExpireEnvironments(repo *db.ConfigRepository, tm TunnelManagerI, km *KubeManager, now time.Time) ([]EnvironmentCleanup, error) {
+	if repo == nil {
+		return nil, nil
+	}
+
+	expiries, err := repo.GetEnvironmentExpiries()
+	if err != nil {
+		return nil, err
+	}
+
+	due := DueExpiries(expiries, now)
+	if len(due) == 0 {
+		return nil, nil
+	}
+	envs, err := repo.GetAllEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	inUse := clustersInUse(envs, due)
+
+	var cleanups []EnvironmentCleanup
+	for _, e := range due {
+		cleanup := EnvironmentCleanup{Environment: e.Name, ExpiredAt: e.ExpiresAt}
+
+		env, err := repo.GetEnvironment(e.Name)
+		if err != nil {
+			return cleanups, err
+		}
+
+		if tm != nil {
+			for _, t := range tm.ListTunnels() {
+				if t.Environment != env.Name {
+					continue
+				}
+				if err := tm.Stop(t.Service, t.Environment); err != nil {
+					cleanup.Errors = append(cleanup.Errors, err.Error())
+					continue
+				}
+				cleanup.TunnelsStopped++
+			}
+		}
+
+		// Clones made with --cluster share a cluster, and its contexts, with
+		// environments that are still active
+		if km != nil && !inUse[env.ClusterName] {
+			removed, errs := removeClusterContexts(km, env.ClusterName)
+			cleanup.ContextsRemoved = removed
+			cleanup.Errors = append(cleanup.Errors, errs...)
+		}
+
+		if err := repo.DeactivateEnvironment(env.Name); err != nil {
+			return cleanups, fmt.Errorf("failed to deactivate %s: %w", env.Name, err)
+		}
+		cleanups = append(cleanups, cleanup)
+	}
+	return cleanups, nil
+}
+
+// clustersInUse returns the clusters of the environments that aren't about
+// to expire
+func clustersInUse(envs []db.Environment, due []db.EnvironmentExpiry) map[string]bool {
+	expiring := make(map[string]bool, len(due))
+	for _, e := range due {
+		expiring[e.Name] = true
+	}
+	inUse := make(map[string]bool)
+	for _, env := range envs {
+		if !expiring[env.Name] {
+			inUse[env.ClusterName] = true
+		}
+	}
+	return inUse
+}
+
+// removeClusterContexts deletes the kubectl contexts pointing at an EKS cluster
+func removeClusterContexts(km *KubeManager, clusterName string) (removed, errs []string) {
+	contexts, err := km.GetContexts()
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	for _, ctx := range contexts {
+		cluster := ctx.Cluster
+		if _, name, ok := parseEKSClusterARN(ctx.Cluster); ok {
+			cluster = name
+		}
+		if cluster != clusterName {
+			continue
+		}
+		if err := km.DeleteContext(ctx.Name); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		removed = append(removed, ctx.Name)
+	}
+	return removed, errs
+}
@@ -0,0 +1,37 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"rolewalkers/internal/db"
+)
+
+func TestDueExpiries(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiries := []db.EnvironmentExpiry{
+		{Name: "old", ExpiresAt: now.Add(-time.Hour)},
+		{Name: "now", ExpiresAt: now},
+		{Name: "later", ExpiresAt: now.Add(time.Minute)},
+	}
+
+	due := DueExpiries(expiries, now)
+	if len(due) != 2 || due[0].Name != "old" || due[1].Name != "now" {
+		t.Errorf("DueExpiries() = %+v, want old and now", due)
+	}
+}
+
+func TestClustersInUse(t *testing.T) {
+	envs := []db.Environment{
+		{Name: "sit", ClusterName: "sit-cluster"},
+		{Name: "sit-pr42", ClusterName: "sit-cluster"},
+		{Name: "pr7", ClusterName: "pr7-cluster"},
+		{Name: "pr8", ClusterName: "pr8-cluster"},
+	}
+	due := []db.EnvironmentExpiry{{Name: "sit-pr42"}, {Name: "pr7"}}
+
+	inUse := clustersInUse(envs, due)
+	if !inUse["sit-cluster"] || inUse["pr7-cluster"] || !inUse["pr8-cluster"] {
+		t.Errorf("clustersInUse() = %v, want sit-cluster and pr8-cluster", inUse)
+	}
+}
@@ -0,0 +1,267 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+)
+
+// AuditActionFeatureFlag is the audit log action for 'rw flag set'
+const AuditActionFeatureFlag = "feature_flag"
+
+// featureFlagFolder is where flags live under an environment's SSM prefix,
+// e.g. /prod/zenith/features/new-checkout
+const featureFlagFolder = "features"
+
+// FlagType is the value type of a feature flag
+type FlagType string
+
+const (
+	FlagBool   FlagType = "bool"
+	FlagNumber FlagType = "number"
+	FlagString FlagType = "string"
+)
+
+// FlagTypes lists the supported flag types
+var FlagTypes = []FlagType{FlagBool, FlagNumber, FlagString}
+
+// ParseFlagType parses a --type value
+func ParseFlagType(s string) (FlagType, error) {
+	for _, t := range FlagTypes {
+		if string(t) == strings.ToLower(s) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid flag type: %s (valid: bool, number, string)", s)
+}
+
+// InferFlagType guesses a flag's type from its current value
+func InferFlagType(value string) FlagType {
+	switch strings.ToLower(value) {
+	case "true", "false":
+		return FlagBool
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return FlagNumber
+	}
+	return FlagString
+}
+
+// Normalize checks that value is valid for the type and returns it in
+// canonical form (bools lower-cased)
+func (t FlagType) Normalize(value string) (string, error) {
+	switch t {
+	case FlagBool:
+		switch strings.ToLower(value) {
+		case "true", "false":
+			return strings.ToLower(value), nil
+		}
+		return "", fmt.Errorf("invalid bool value %q (use true or false)", value)
+	case FlagNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("invalid number value %q", value)
+		}
+		return value, nil
+	default:
+		if value == "" {
+			return "", fmt.Errorf("flag values can't be empty (SSM rejects them)")
+		}
+		return value, nil
+	}
+}
+
+// FeatureFlag is an app feature flag stored as an SSM parameter
+type FeatureFlag struct {
+	Name  string   `json:"name"`
+	Path  string   `json:"path"`
+	Value string   `json:"value"`
+	Type  FlagType `json:"type"`
+}
+
+// FlagDiff is a flag whose value differs between environments. Values has
+// no entry for environments where the flag is missing.
+type FlagDiff struct {
+	Name   string
+	Values map[string]string
+}
+
+// parameterStore is the SSM access feature flags need; *SSMManager
+// implements it
+type parameterStore interface {
+	GetParameter(name string) (string, error)
+	GetParametersByPath(prefix string) (map[string]string, error)
+	PutParameter(name, value string) error
+}
+
+// FeatureFlagManager reads and toggles feature flags under
+// <ssm_path_prefix>/features in SSM Parameter Store
+type FeatureFlagManager struct {
+	ssm        parameterStore
+	configRepo *db.ConfigRepository
+}
+
+// NewFeatureFlagManager creates a feature flag manager. Changes are
+// recorded in the audit log when repo is set.
+func NewFeatureFlagManager(ssm *SSMManager, repo *db.ConfigRepository) *FeatureFlagManager {
+	return &FeatureFlagManager{ssm: ssm, configRepo: repo}
+}
+
+var validFlagName = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// Prefix returns the SSM folder holding an environment's flags
+func (fm *FeatureFlagManager) Prefix(env string) string {
+	return config.Get().SSMPath(env, featureFlagFolder)
+}
+
+func (fm *FeatureFlagManager) path(env, name string) (string, error) {
+	if !validFlagName.MatchString(name) || slices.Contains(strings.Split(name, "/"), "..") {
+		return "", fmt.Errorf("invalid flag name %q (letters, digits, '.', '_', '-', nested with '/')", name)
+	}
+	return fm.Prefix(env) + "/" + name, nil
+}
+
+// List returns an environment's flags sorted by name
+func (fm *FeatureFlagManager) List(env string) ([]FeatureFlag, error) {
+	prefix := fm.Prefix(env)
+	values, err := fm.ssm.GetParametersByPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]FeatureFlag, 0, len(values))
+	for path, value := range values {
+		flags = append(flags, FeatureFlag{
+			Name:  strings.TrimPrefix(path, prefix+"/"),
+			Path:  path,
+			Value: value,
+			Type:  InferFlagType(value),
+		})
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+// Get returns a single flag
+func (fm *FeatureFlagManager) Get(env, name string) (*FeatureFlag, error) {
+	path, err := fm.path(env, name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := fm.ssm.GetParameter(path)
+	if err != nil {
+		if IsParameterNotFound(err) {
+			return nil, fmt.Errorf("flag %s not found on %s (%s)", name, env, path)
+		}
+		return nil, err
+	}
+	return &FeatureFlag{Name: name, Path: path, Value: value, Type: InferFlagType(value)}, nil
+}
+
+// FlagChange is a validated flag update, prepared by PrepareSet and
+// written by Apply
+type FlagChange struct {
+	Env string
+	// Before is the current flag, nil when the flag will be created
+	Before *FeatureFlag
+	After  FeatureFlag
+}
+
+// Changed reports whether applying the change would write anything
+func (ch *FlagChange) Changed() bool {
+	return ch.Before == nil || ch.Before.Value != ch.After.Value
+}
+
+// PrepareSet validates a new flag value without writing it. The value must
+// match the flag's current type; typ overrides it and is required when
+// creating a flag.
+func (fm *FeatureFlagManager) PrepareSet(env, name, value string, typ FlagType) (*FlagChange, error) {
+	path, err := fm.path(env, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := &FlagChange{Env: env}
+	current, err := fm.ssm.GetParameter(path)
+	switch {
+	case err == nil:
+		ch.Before = &FeatureFlag{Name: name, Path: path, Value: current, Type: InferFlagType(current)}
+		if typ == "" {
+			typ = ch.Before.Type
+		}
+	case IsParameterNotFound(err):
+		if typ == "" {
+			return nil, fmt.Errorf("flag %s doesn't exist on %s; pass --type (bool, number, string) to create it", name, env)
+		}
+	default:
+		return nil, err
+	}
+
+	normalized, err := typ.Normalize(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is a %s flag: %w", name, typ, err)
+	}
+	ch.After = FeatureFlag{Name: name, Path: path, Value: normalized, Type: typ}
+	return ch, nil
+}
+
+// Apply writes a prepared change and records it in the audit log. An
+// unchanged value is not written.
+func (fm *FeatureFlagManager) Apply(ch *FlagChange) error {
+	if !ch.Changed() {
+		return nil
+	}
+	if err := fm.ssm.PutParameter(ch.After.Path, ch.After.Value); err != nil {
+		return err
+	}
+
+	recordAudit(fm.configRepo, AuditActionFeatureFlag, ch.Env, ch.After.Path, ch.Before, ch.After,
+		messages.New(messages.AuditFeatureFlagSet, "flag", ch.After.Name, "env", ch.Env, "value", ch.After.Value))
+	return nil
+}
+
+// Diff compares flags across environments and returns those whose values
+// differ or that are missing somewhere, sorted by name
+func (fm *FeatureFlagManager) Diff(envs []string) ([]FlagDiff, error) {
+	byName := make(map[string]map[string]string)
+	for _, env := range envs {
+		flags, err := fm.List(env)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env, err)
+		}
+		for _, f := range flags {
+			if byName[f.Name] == nil {
+				byName[f.Name] = make(map[string]string)
+			}
+			byName[f.Name][env] = f.Value
+		}
+	}
+
+	var diffs []FlagDiff
+	for name, values := range byName {
+		if len(values) == len(envs) && allEqual(values) {
+			continue
+		}
+		diffs = append(diffs, FlagDiff{Name: name, Values: values})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs, nil
+}
+
+func allEqual(values map[string]string) bool {
+	first, set := "", false
+	for _, v := range values {
+		if !set {
+			first, set = v, true
+		} else if v != first {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeParameterStore is an in-memory SSM that counts writes
+type fakeParameterStore struct {
+	params map[string]string
+	puts   int
+}
+
+func (f *fakeParameterStore) GetParameter(name string) (string, error) {
+	if v, ok := f.params[name]; ok {
+		return v, nil
+	}
+	return "", errors.New("An error occurred (ParameterNotFound) when calling the GetParameter operation")
+}
+
+func (f *fakeParameterStore) GetParametersByPath(prefix string) (map[string]string, error) {
+	out := make(map[string]string)
+	for k, v := range f.params {
+		if strings.HasPrefix(k, prefix+"/") {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeParameterStore) PutParameter(name, value string) error {
+	f.params[name] = value
+	f.puts++
+	return nil
+}
+
+func newFlagTestManager() (*FeatureFlagManager, *fakeParameterStore) {
+	store := &fakeParameterStore{params: map[string]string{
+		"/dev/zenith/features/new-checkout":     "false",
+		"/dev/zenith/features/checkout-percent": "25",
+		"/dev/zenith/features/banner":           "spring",
+		"/prod/zenith/features/new-checkout":    "true",
+		"/prod/zenith/features/banner":          "spring",
+	}}
+	return &FeatureFlagManager{ssm: store}, store
+}
+
+func TestFlagTypeNormalize(t *testing.T) {
+	tests := []struct {
+		typ     FlagType
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{FlagBool, "TRUE", "true", false},
+		{FlagBool, "yes", "", true},
+		{FlagNumber, "2.5", "2.5", false},
+		{FlagNumber, "many", "", true},
+		{FlagString, "spring", "spring", false},
+		{FlagString, "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := tt.typ.Normalize(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s.Normalize(%q) = %q, %v; want %q, error %v", tt.typ, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	for value, want := range map[string]FlagType{"False": FlagBool, "-3": FlagNumber, "on": FlagString} {
+		if got := InferFlagType(value); got != want {
+			t.Errorf("InferFlagType(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestFeatureFlagPrepareSet(t *testing.T) {
+	fm, store := newFlagTestManager()
+
+	if _, err := fm.PrepareSet("dev", "checkout-percent", "half", ""); err == nil {
+		t.Error("PrepareSet() should reject a non-number for a number flag")
+	}
+	if _, err := fm.PrepareSet("dev", "brand-new", "true", ""); err == nil {
+		t.Error("PrepareSet() should require --type to create a flag")
+	}
+	if _, err := fm.PrepareSet("dev", "../secrets", "true", FlagBool); err == nil {
+		t.Error("PrepareSet() should reject names that escape the features folder")
+	}
+
+	ch, err := fm.PrepareSet("dev", "new-checkout", "True", "")
+	if err != nil {
+		t.Fatalf("PrepareSet() error: %v", err)
+	}
+	if ch.Before.Value != "false" || ch.After.Value != "true" || !ch.Changed() {
+		t.Errorf("PrepareSet() = %+v, want false -> true", ch)
+	}
+	if store.puts != 0 {
+		t.Errorf("PrepareSet() wrote %d parameters, want none", store.puts)
+	}
+	if err := fm.Apply(ch); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if store.params["/dev/zenith/features/new-checkout"] != "true" || store.puts != 1 {
+		t.Errorf("Apply() left %q after %d writes, want true after 1", store.params["/dev/zenith/features/new-checkout"], store.puts)
+	}
+
+	ch, err = fm.PrepareSet("dev", "new-checkout", "true", "")
+	if err != nil {
+		t.Fatalf("PrepareSet() error: %v", err)
+	}
+	if ch.Changed() {
+		t.Error("setting the current value should not be a change")
+	}
+	if err := fm.Apply(ch); err != nil || store.puts != 1 {
+		t.Errorf("Apply() of an unchanged flag wrote %d parameters (err %v), want no write", store.puts-1, err)
+	}
+
+	ch, err = fm.PrepareSet("dev", "rollout/region", "eu", FlagString)
+	if err != nil {
+		t.Fatalf("PrepareSet() error: %v", err)
+	}
+	if ch.Before != nil || ch.After.Path != "/dev/zenith/features/rollout/region" {
+		t.Errorf("PrepareSet() = %+v, want a new nested flag", ch)
+	}
+}
+
+func TestFeatureFlagDiff(t *testing.T) {
+	fm, _ := newFlagTestManager()
+
+	flags, err := fm.List("dev")
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(flags) != 3 || flags[0].Name != "banner" || flags[1].Type != FlagNumber {
+		t.Errorf("List() = %+v, want 3 flags sorted by name with inferred types", flags)
+	}
+
+	diffs, err := fm.Diff([]string{"prod", "dev"})
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Diff() = %+v, want checkout-percent and new-checkout", diffs)
+	}
+	if d := diffs[0]; d.Name != "checkout-percent" || len(d.Values) != 1 {
+		t.Errorf("diff[0] = %+v, want checkout-percent missing on prod", d)
+	}
+	if d := diffs[1]; d.Name != "new-checkout" || d.Values["prod"] != "true" || d.Values["dev"] != "false" {
+		t.Errorf("diff[1] = %+v, want new-checkout true vs false", d)
+	}
+}
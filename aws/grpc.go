@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 	"slices"
 	"strings"
 	"syscall"
@@ -100,13 +101,21 @@ func (gm *GRPCManager) ListServices() string {
 	return sb.String()
 }
 
-// Forward starts port-forwarding to a gRPC service
-func (gm *GRPCManager) Forward(service, env string) error {
+// Forward starts port-forwarding to a gRPC service.
+// If the service's local port is busy the next free port is used, unless
+// strictPort is set.
+func (gm *GRPCManager) Forward(service, env string, strictPort bool) error {
 	service = strings.ToLower(service)
 	env = strings.ToLower(env)
 
 	// Validate service
-	localPort, err := gm.GetServicePort(service)
+	remotePort, err := gm.GetServicePort(service)
+	if err != nil {
+		return err
+	}
+
+	// gRPC services use the same port locally and remotely unless it is busy
+	localPort, err := resolveLocalPort(remotePort, strictPort)
 	if err != nil {
 		return err
 	}
@@ -118,7 +127,6 @@ func (gm *GRPCManager) Forward(service, env string) error {
 	}
 
 	k8sService := gm.GetServiceName(service)
-	remotePort := localPort // gRPC services use the same port locally and remotely
 
 	fmt.Printf("\nStarting gRPC port-forward:\n")
 	fmt.Printf("  Service:   %s\n", k8sService)
@@ -163,7 +171,7 @@ func (gm *GRPCManager) startPortForward(serviceName string, localPort, remotePor
 	err := cmd.Run()
 
 	if ctx.Err() == context.Canceled {
-		fmt.Println("✓ Port-forward stopped")
+		fmt.Println(utils.OK() + " Port-forward stopped")
 		return nil
 	}
 
@@ -179,7 +187,7 @@ func (gm *GRPCManager) CheckServiceExists(service, env string) error {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("service %s not found in namespace %s: %s", k8sService, config.Get().Namespaces.App, stderr.String())
+		return fmt.Errorf("service %s not found in namespace %s: %s", k8sService, config.Get().Namespaces.App, utils.Redact(stderr.String()))
 	}
 
 	return nil
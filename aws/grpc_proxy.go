@@ -0,0 +1,350 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// grpcurlStatusOffset is added to the gRPC status code in grpcurl's exit code
+// when an RPC fails (e.g. 64+5 for NotFound).
+const grpcurlStatusOffset = 64
+
+// maxProxyBody limits the size of a JSON request body accepted by the proxy
+const maxProxyBody = 4 * 1024 * 1024
+
+// grpcCodeNames maps gRPC status codes to their canonical names
+var grpcCodeNames = []string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound",
+	"AlreadyExists", "PermissionDenied", "ResourceExhausted", "FailedPrecondition",
+	"Aborted", "OutOfRange", "Unimplemented", "Internal", "Unavailable", "DataLoss",
+	"Unauthenticated",
+}
+
+// GRPCProxy is an HTTP handler that transcodes JSON requests to gRPC calls
+// against a forwarded service, grpc-gateway style. Methods are resolved via
+// server reflection using grpcurl, so no stubs need to be generated:
+//
+//	GET  /                        list services
+//	GET  /<pkg.Service>           list methods of a service
+//	POST /<pkg.Service>/<Method>  call a method with a JSON body
+//
+// Request headers named Authorization or Grpc-Metadata-<key> are forwarded
+// as gRPC metadata. Requests from web pages on other origins are refused,
+// so a page open in the browser can't call the forwarded service.
+type GRPCProxy struct {
+	target  string // host:port of the forwarded gRPC server
+	grpcurl string // path to the grpcurl binary
+	timeout time.Duration
+}
+
+// NewGRPCProxy creates a proxy for a plaintext gRPC server at target
+func NewGRPCProxy(target, grpcurlPath string) *GRPCProxy {
+	return &GRPCProxy{target: target, grpcurl: grpcurlPath, timeout: 60 * time.Second}
+}
+
+// ServeHTTP implements http.Handler
+func (p *GRPCProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if crossOrigin(r) {
+		writeProxyError(w, http.StatusForbidden, "PermissionDenied", "cross-origin requests are not allowed")
+		return
+	}
+	service, method := parseProxyPath(r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodGet && service == "":
+		p.list(w, r, "services")
+	case r.Method == http.MethodGet && method == "":
+		p.list(w, r, "methods", service)
+	case r.Method == http.MethodPost && method != "":
+		p.invoke(w, r, service+"/"+method)
+	default:
+		writeProxyError(w, http.StatusNotFound, "NotFound",
+			"use GET / to list services, GET /<service> to list methods, POST /<service>/<method> to call one")
+	}
+}
+
+// crossOrigin reports whether a request didn't come from the proxy's own
+// origin on localhost: a browser sent it from another origin, or its Host
+// isn't localhost, as with DNS rebinding. Requests without an Origin
+// header, from curl or scripts, are allowed.
+func crossOrigin(r *http.Request) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return true
+	}
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// list runs 'grpcurl list [service]' and returns the names as a JSON array
+func (p *GRPCProxy) list(w http.ResponseWriter, r *http.Request, key string, service ...string) {
+	args := append([]string{"-plaintext", p.target, "list"}, service...)
+	out, code, msg := p.run(r.Context(), args, nil)
+	if code != "" {
+		writeProxyError(w, grpcCodeToHTTP(code), code, msg)
+		return
+	}
+
+	names := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{key: names})
+}
+
+// invoke calls a method with the request body as the JSON request message
+func (p *GRPCProxy) invoke(w http.ResponseWriter, r *http.Request, fullMethod string) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxProxyBody))
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+
+	args := []string{"-plaintext", "-format", "json", "-d", "@"}
+	for _, h := range proxyMetadata(r.Header) {
+		args = append(args, "-H", h)
+	}
+	args = append(args, p.target, fullMethod)
+
+	out, code, msg := p.run(r.Context(), args, body)
+	if code != "" {
+		writeProxyError(w, grpcCodeToHTTP(code), code, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// run executes grpcurl. On failure it returns the gRPC status code name and message.
+func (p *GRPCProxy) run(ctx context.Context, args []string, stdin []byte) (out []byte, code, msg string) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.grpcurl, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), "", ""
+	}
+
+	msg = utils.Redact(strings.TrimSpace(stderr.String()))
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, "DeadlineExceeded", "request timed out"
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status := exitErr.ExitCode() - grpcurlStatusOffset
+		if status > 0 && status < len(grpcCodeNames) {
+			return nil, grpcCodeNames[status], grpcurlMessage(msg)
+		}
+	}
+	// grpcurl itself failed (bad JSON, unknown method, connection refused...)
+	return nil, "InvalidArgument", msg
+}
+
+// parseProxyPath splits "/pkg.Service/Method" into its parts
+func parseProxyPath(path string) (service, method string) {
+	path = strings.Trim(path, "/")
+	service, method, _ = strings.Cut(path, "/")
+	return service, method
+}
+
+// proxyMetadata converts HTTP headers into grpcurl "-H" values
+func proxyMetadata(header http.Header) []string {
+	var md []string
+	for name, values := range header {
+		key := strings.ToLower(name)
+		switch {
+		case key == "authorization":
+		case strings.HasPrefix(key, "grpc-metadata-"):
+			key = strings.TrimPrefix(key, "grpc-metadata-")
+		default:
+			continue
+		}
+		for _, v := range values {
+			md = append(md, key+": "+v)
+		}
+	}
+	return md
+}
+
+// grpcurlMessage extracts "Message:" from grpcurl's error output
+func grpcurlMessage(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if m, ok := strings.CutPrefix(strings.TrimSpace(line), "Message:"); ok {
+			return strings.TrimSpace(m)
+		}
+	}
+	return stderr
+}
+
+// grpcCodeToHTTP maps gRPC status codes to HTTP statuses, as grpc-gateway does
+func grpcCodeToHTTP(code string) int {
+	switch code {
+	case "OK":
+		return http.StatusOK
+	case "Canceled":
+		return 499
+	case "InvalidArgument", "FailedPrecondition", "OutOfRange":
+		return http.StatusBadRequest
+	case "DeadlineExceeded":
+		return http.StatusGatewayTimeout
+	case "NotFound":
+		return http.StatusNotFound
+	case "AlreadyExists", "Aborted":
+		return http.StatusConflict
+	case "PermissionDenied":
+		return http.StatusForbidden
+	case "Unauthenticated":
+		return http.StatusUnauthorized
+	case "ResourceExhausted":
+		return http.StatusTooManyRequests
+	case "Unimplemented":
+		return http.StatusNotImplemented
+	case "Unavailable":
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeProxyError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
+
+// Proxy port-forwards a gRPC service and serves a local HTTP/JSON proxy in
+// front of it, so it can be called with curl or Postman without stubs.
+func (gm *GRPCManager) Proxy(service, env string, httpPort int, strictPort bool) error {
+	service = strings.ToLower(service)
+	env = strings.ToLower(env)
+
+	grpcurlPath, err := exec.LookPath("grpcurl")
+	if err != nil {
+		return fmt.Errorf("grpcurl not found on PATH (install: https://github.com/fullstorydev/grpcurl#installation)")
+	}
+
+	remotePort, err := gm.GetServicePort(service)
+	if err != nil {
+		return err
+	}
+	grpcPort, err := resolveLocalPort(remotePort, false)
+	if err != nil {
+		return err
+	}
+	httpPort, err = resolveLocalPort(httpPort, strictPort)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Switching kubectl context to %s...\n", env)
+	if err := gm.kubeManager.SwitchContextForEnvWithProfile(env, gm.profileSwitcher); err != nil {
+		return fmt.Errorf("failed to switch kubectl context: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	k8sService := gm.GetServiceName(service)
+	pf := exec.CommandContext(ctx, "kubectl", "port-forward",
+		fmt.Sprintf("svc/%s", k8sService),
+		fmt.Sprintf("%d:%d", grpcPort, remotePort),
+		"-n", config.Get().Namespaces.App,
+	)
+	pf.Stderr = os.Stderr
+	if err := pf.Start(); err != nil {
+		return fmt.Errorf("failed to start port-forward: %w", err)
+	}
+	pfDone := make(chan error, 1)
+	go func() { pfDone <- pf.Wait() }()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		cancel()
+		<-pfDone
+		return fmt.Errorf("failed to listen on port %d: %w", httpPort, err)
+	}
+	server := &http.Server{
+		Handler:           NewGRPCProxy(fmt.Sprintf("localhost:%d", grpcPort), grpcurlPath),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- server.Serve(listener) }()
+
+	base := fmt.Sprintf("http://localhost:%d", httpPort)
+	fmt.Printf("\nStarting gRPC JSON proxy:\n")
+	fmt.Printf("  Service:   %s\n", k8sService)
+	fmt.Printf("  gRPC:      localhost:%d\n", grpcPort)
+	fmt.Printf("  HTTP:      %s\n", base)
+	fmt.Printf("\nTry:\n")
+	fmt.Printf("  curl %s/                                  # list services\n", base)
+	fmt.Printf("  curl %s/<pkg.Service>                     # list methods\n", base)
+	fmt.Printf("  curl -X POST %s/<pkg.Service>/<Method> -d '{}'\n", base)
+	fmt.Println("\nPress Ctrl+C to stop...")
+
+	var runErr error
+	select {
+	case <-sigChan:
+		fmt.Println("\n\nStopping proxy...")
+	case err := <-pfDone:
+		runErr = fmt.Errorf("port-forward exited: %v", err)
+		pfDone <- err
+	case err := <-serveDone:
+		runErr = fmt.Errorf("proxy server stopped: %w", err)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+	cancel()
+	<-pfDone
+
+	if runErr == nil {
+		fmt.Println(utils.OK() + " Proxy stopped")
+	}
+	return runErr
+}
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+func TestParseProxyPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		service string
+		method  string
+	}{
+		{"/", "", ""},
+		{"/candidate.v1.CandidateService", "candidate.v1.CandidateService", ""},
+		{"/candidate.v1.CandidateService/", "candidate.v1.CandidateService", ""},
+		{"/candidate.v1.CandidateService/GetCandidate", "candidate.v1.CandidateService", "GetCandidate"},
+	}
+
+	for _, tt := range tests {
+		service, method := parseProxyPath(tt.path)
+		if service != tt.service || method != tt.method {
+			t.Errorf("parseProxyPath(%q) = (%q, %q), want (%q, %q)", tt.path, service, method, tt.service, tt.method)
+		}
+	}
+}
+
+func TestProxyMetadata(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("Grpc-Metadata-X-Tenant", "zenith")
+	h.Set("Content-Type", "application/json")
+
+	got := proxyMetadata(h)
+	sort.Strings(got)
+	want := []string{"authorization: Bearer abc", "x-tenant: zenith"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("proxyMetadata() = %v, want %v", got, want)
+	}
+}
+
+func TestGRPCCodeToHTTP(t *testing.T) {
+	tests := map[string]int{
+		"NotFound":        http.StatusNotFound,
+		"InvalidArgument": http.StatusBadRequest,
+		"Unauthenticated": http.StatusUnauthorized,
+		"Unavailable":     http.StatusServiceUnavailable,
+		"Internal":        http.StatusInternalServerError,
+	}
+	for code, want := range tests {
+		if got := grpcCodeToHTTP(code); got != want {
+			t.Errorf("grpcCodeToHTTP(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestGrpcurlMessage(t *testing.T) {
+	stderr := "ERROR:\n  Code: NotFound\n  Message: candidate 42 not found\n"
+	if got := grpcurlMessage(stderr); got != "candidate 42 not found" {
+		t.Errorf("grpcurlMessage() = %q", got)
+	}
+}
+
+func TestCrossOrigin(t *testing.T) {
+	tests := []struct {
+		host   string
+		origin string
+		want   bool
+	}{
+		{"127.0.0.1:8080", "", false},
+		{"localhost:8080", "http://localhost:8080", false},
+		{"127.0.0.1:8080", "https://evil.example", true},
+		{"127.0.0.1:8080", "null", true},
+		{"evil.example:8080", "http://evil.example:8080", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", nil)
+		r.Host = tt.host
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := crossOrigin(r); got != tt.want {
+			t.Errorf("crossOrigin(host %q, origin %q) = %v, want %v", tt.host, tt.origin, got, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", nil)
+	r.Host = "127.0.0.1:8080"
+	r.Header.Set("Origin", "https://evil.example")
+	NewGRPCProxy("127.0.0.1:50051", "grpcurl").ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("ServeHTTP() from another origin = %d, want 403", w.Code)
+	}
+}
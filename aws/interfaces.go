@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"iter"
 	"rolewalkers/internal/db"
 	"time"
 )
@@ -10,7 +11,9 @@ import (
 // ProfileProvider reads AWS profile configurations.
 type ProfileProvider interface {
 	GetProfiles() ([]Profile, error)
+	Profiles() iter.Seq2[Profile, error]
 	GetActiveProfile() string
+	NotifyChanged(fn func())
 }
 
 // ProfileSwitcherI switches the active AWS profile.
@@ -27,6 +30,7 @@ type SSOManagerI interface {
 	Login(profileName string) error
 	Logout(profileName string) error
 	IsLoggedIn(profileName string) bool
+	LoginStatuses(profileNames ...string) (map[string]LoginStatus, error)
 	GetSSOProfiles() ([]Profile, error)
 	GetCredentialExpiry(profileName string) (*time.Time, error)
 	ValidateProfile(profileName string) error
@@ -52,6 +56,9 @@ type EndpointResolver interface {
 	GetEndpoint(env, service string) (string, error)
 	GetDatabaseEndpoint(env, nodeType, dbType string) (string, error)
 	ListParameters(prefix string) ([]string, error)
+	ListParameterInfo(prefix string) ([]SSMParameterInfo, error)
+	ListParameterPages(prefix string, recursive bool, page func([]SSMParameterInfo) error) error
+	GetParameterValues(names []string, concurrency int) (map[string]string, error)
 }
 
 // TunnelManagerI manages tunnel lifecycle.
@@ -59,22 +66,32 @@ type TunnelManagerI interface {
 	Start(config TunnelConfig) error
 	Stop(service, env string) error
 	StopAll() error
-	List() string
 	ListTunnels() []*TunnelInfo
+	ListWithStatus() []TunnelStatus
 	CleanupStale() error
 	GetSupportedServices() string
+	ShareManifest(config TunnelConfig) (*TunnelManifest, error)
+	JoinWarnings(m *TunnelManifest) []string
+	ResolveCheckTarget(service, env string) (CheckTarget, error)
 }
 
 // DatabaseManagerI handles database connection operations.
 type DatabaseManagerI interface {
 	Connect(config DatabaseConfig) error
+	DSN(config DatabaseConfig) (string, error)
 	Backup(config BackupConfig) error
 	Restore(config RestoreConfig) error
+	InspectRestoreTarget(config RestoreConfig) (*RestoreCheck, error)
+	ListBackups(env string) ([]db.BackupRecord, error)
+	GetBackup(id int) (*db.BackupRecord, error)
+	DeleteBackup(record db.BackupRecord) error
+	RetryBackup(id int) error
 }
 
 // GRPCManagerI handles gRPC port-forwarding.
 type GRPCManagerI interface {
-	Forward(service, env string) error
+	Forward(service, env string, strictPort bool) error
+	Proxy(service, env string, httpPort int, strictPort bool) error
 	GetServices() string
 	ListServices() string
 }
@@ -86,7 +103,7 @@ type RedisManagerI interface {
 
 // MSKManagerI handles MSK Kafka UI operations.
 type MSKManagerI interface {
-	StartUI(env string, localPort int) error
+	StartUI(env string, localPort int, strictPort bool) error
 	StopUI(env string) error
 	ConnectCLI(env string) error
 }
@@ -94,14 +111,37 @@ type MSKManagerI interface {
 // MaintenanceManagerI handles Fastly maintenance mode.
 type MaintenanceManagerI interface {
 	Toggle(env, serviceType string, enable bool) error
+	Plan(env, serviceType string, enable bool) ([]MaintenanceChange, error)
+	Validate(env string) ([]MaintenanceMapping, error)
 	Status(env string) ([]MaintenanceStatus, error)
 }
 
+// FeatureFlagManagerI reads and toggles SSM-backed feature flags.
+type FeatureFlagManagerI interface {
+	Prefix(env string) string
+	List(env string) ([]FeatureFlag, error)
+	Get(env, name string) (*FeatureFlag, error)
+	PrepareSet(env, name, value string, typ FlagType) (*FlagChange, error)
+	Apply(ch *FlagChange) error
+	Diff(envs []string) ([]FlagDiff, error)
+}
+
 // ScalingManagerI handles HPA scaling operations.
 type ScalingManagerI interface {
 	Scale(env, presetName string) error
 	ScaleService(env, service string, min, max int) error
-	ListHPAs(env string) (string, error)
+	ListHPAs(env string) ([]HPAScaling, error)
+	ListHPAsInContext(env string) ([]HPAScaling, error)
+	MatchHPAs(env string, sel *ServiceSelector) ([]HPAScaling, error)
+	ScaleHPAs(env, pattern string, hpas []HPAScaling, min, max int) error
+	Namespace() string
+}
+
+// UndoManagerI reverts the most recent maintenance or scaling change.
+type UndoManagerI interface {
+	LastChange() (*db.AuditEntry, error)
+	Undo(entry *db.AuditEntry) error
+	Window() time.Duration
 }
 
 // ReplicationManagerI handles Blue-Green deployment operations.
@@ -109,7 +149,8 @@ type ReplicationManagerI interface {
 	Status(env string) (string, error)
 	Switch(env, deploymentID string) error
 	Create(env, name, source string) error
-	Delete(deploymentID string, deleteTarget bool) error
+	DiscoverClusters(env string) ([]DBCluster, error)
+	Delete(env, deploymentID string, deleteTarget bool) error
 }
 
 // ConfigSyncI handles config file ↔ database synchronization.
@@ -119,9 +160,13 @@ type ConfigSyncI interface {
 	SyncConfigToDB() (*SyncResult, error)
 	AnalyzeSync() (*SyncResult, error)
 	WriteAWSConfig() error
+	DiffAWSConfig() (string, error)
 	BackupConfigFile() (string, error)
 	DeleteConfigFile() error
 	GetConfigPath() string
+	ExternalChange() bool
+	AcceptAWSConfig() error
+	ImportExternalChange() (*SyncResult, error)
 }
 
 // --- Consumer-scoped interfaces (ISP) ---
@@ -155,11 +200,12 @@ type PortMappingProvider interface {
 type AccountRoleProvider interface {
 	GetAllAWSAccounts() ([]db.AWSAccount, error)
 	GetAWSAccount(accountID string) (*db.AWSAccount, error)
+	GetAccountByRowID(id int) (*db.AWSAccount, error)
 	GetRolesByAccount(accountID string) ([]db.AWSRole, error)
 	GetRoleByProfileName(profileName string) (*db.AWSRole, error)
 	GetAllAWSRoles() ([]db.AWSRole, error)
 	AddAWSAccount(accountID, accountName, ssoStartURL, ssoRegion, description string) error
 	AddAWSRole(accountID int, roleName, roleARN, profileName, region, description string) error
-	CreateUserSession(roleID int) error
+	CreateUserSession(roleID int) (*db.UserSession, bool, error)
 	GetActiveSession() (*db.UserSession, *db.AWSRole, *db.AWSAccount, error)
 }
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// Job records the progress of a long-running operation in the jobs table,
+// so 'rw jobs list' shows it from any terminal. A nil Job, used when the
+// database is unavailable, records nothing.
+type Job struct {
+	repo *db.ConfigRepository
+	id   int64
+}
+
+// runJob runs an operation as a tracked job: queued, then running in this
+// process, then succeeded, failed or cancelled by how run returns. Failing
+// to record the job never fails the operation.
+func runJob(repo *db.ConfigRepository, kind, env, target string, run func(*Job) error) error {
+	job := startJob(repo, kind, env, target)
+	err := run(job)
+	job.finish(err)
+	return err
+}
+
+func startJob(repo *db.ConfigRepository, kind, env, target string) *Job {
+	if repo == nil {
+		return nil
+	}
+	if _, err := InterruptOrphanedJobs(repo); err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to check for interrupted jobs: %v\n", err)
+	}
+	id, err := repo.CreateJob(kind, env, target)
+	if err == nil {
+		err = repo.StartJob(id, os.Getpid())
+	}
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record the %s job: %v\n", kind, err)
+		return nil
+	}
+	return &Job{repo: repo, id: id}
+}
+
+// Progress records the step the job is on
+func (j *Job) Progress(text string) {
+	if j == nil {
+		return
+	}
+	_ = j.repo.UpdateJobProgress(j.id, text)
+}
+
+func (j *Job) finish(err error) {
+	if j == nil {
+		return
+	}
+	state, errText := db.JobSucceeded, ""
+	switch {
+	case errors.Is(err, ErrBackupInterrupted), errors.Is(err, context.Canceled):
+		state = db.JobCancelled
+	case err != nil:
+		state, errText = db.JobFailed, err.Error()
+	}
+	if err := j.repo.FinishJob(j.id, state, errText); err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record the job's result: %v\n", err)
+	}
+}
+
+// InterruptOrphanedJobs marks jobs whose rw process exited without ending
+// them, e.g. after a crash or kill -9, as interrupted
+func InterruptOrphanedJobs(repo *db.ConfigRepository) ([]db.JobRecord, error) {
+	return repo.InterruptOrphanedJobs(utils.ProcessAlive)
+}
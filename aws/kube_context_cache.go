@@ -0,0 +1,68 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"rolewalkers/internal/utils"
+)
+
+// kubeContextCacheFile holds the current kubectl context and namespace, so
+// 'rw context' and the shell prompt don't have to ask kubectl every time
+const kubeContextCacheFile = "kube_context.json"
+
+// kubeContextCache is what kubeContextCacheFile holds
+type kubeContextCache struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	// Stamp identifies the kubeconfig files as they were when cached; any
+	// change, by rw or another tool, invalidates the cache
+	Stamp string `json:"stamp"`
+}
+
+// kubeconfigStamp returns the path, size and modification time of each
+// kubeconfig file. It only stats the files, so it is cheap enough to check
+// on every prompt.
+func kubeconfigStamp() string {
+	var parts []string
+	for _, path := range kubeconfigPaths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			parts = append(parts, path+":-")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+	}
+	return strings.Join(parts, ";")
+}
+
+// CachedKubeContext returns the current kubectl context and namespace from
+// the cache while the kubeconfig files are unchanged, and otherwise reads
+// them (see ReadKubeconfigContext) and refreshes the cache
+func CachedKubeContext() (string, string) {
+	stamp := kubeconfigStamp()
+	if data, err := utils.ReadRoleWalkersFile(kubeContextCacheFile); err == nil {
+		var cached kubeContextCache
+		if json.Unmarshal(data, &cached) == nil && cached.Stamp == stamp {
+			return cached.Context, cached.Namespace
+		}
+	}
+	return refreshKubeContextCache(stamp)
+}
+
+// RefreshKubeContextCache reads the current context and namespace from the
+// kubeconfig files and caches them. rw calls it whenever it changes either.
+func RefreshKubeContextCache() (string, string) {
+	return refreshKubeContextCache(kubeconfigStamp())
+}
+
+func refreshKubeContextCache(stamp string) (string, string) {
+	kubeContext, namespace := ReadKubeconfigContext()
+	data, err := json.Marshal(kubeContextCache{Context: kubeContext, Namespace: namespace, Stamp: stamp})
+	if err == nil {
+		_ = utils.WriteRoleWalkersFile(kubeContextCacheFile, data)
+	}
+	return kubeContext, namespace
+}
@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedKubeContext(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(kubeconfig, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(kubeconfig, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := time.Now().Add(-time.Hour)
+	write("current-context: dev\ncontexts:\n- name: dev\n  context:\n    namespace: zenith\n", base)
+
+	if ctx, ns := CachedKubeContext(); ctx != "dev" || ns != "zenith" {
+		t.Fatalf("CachedKubeContext() = %q, %q; want dev, zenith", ctx, ns)
+	}
+
+	// Another tool changes the kubeconfig: the cache is invalidated
+	write("current-context: qa\ncontexts:\n- name: qa\n  context:\n    namespace: other\n", base.Add(time.Minute))
+	if ctx, ns := CachedKubeContext(); ctx != "qa" || ns != "other" {
+		t.Errorf("CachedKubeContext() after a kubeconfig change = %q, %q; want qa, other", ctx, ns)
+	}
+
+	// While the kubeconfig is unchanged the cache answers, without reading it
+	cachePath := filepath.Join(os.Getenv("RW_STATE_DIR"), kubeContextCacheFile)
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+	data = []byte(strings.Replace(string(data), `"context":"qa"`, `"context":"from-cache"`, 1))
+	if err := os.WriteFile(cachePath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if ctx, _ := CachedKubeContext(); ctx != "from-cache" {
+		t.Errorf("CachedKubeContext() = %q, want the cached value", ctx)
+	}
+	if ctx, _ := RefreshKubeContextCache(); ctx != "qa" {
+		t.Errorf("RefreshKubeContextCache() = %q, want qa from kubeconfig", ctx)
+	}
+}
+
+func TestSwitchContextSkipsCurrent(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	arn := "arn:aws:eks:eu-west-2:111111111111:cluster/dev-zenith-eks-cluster"
+	if err := os.WriteFile(kubeconfig, []byte("current-context: "+arn+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither call may run kubectl when the context is already current
+	km := NewKubeManager()
+	if err := km.SwitchContext(arn); err != nil {
+		t.Errorf("SwitchContext(current) error: %v", err)
+	}
+	if err := km.SwitchContextForEnv("zenith-dev"); err != nil {
+		t.Errorf("SwitchContextForEnv() on its own cluster error: %v", err)
+	}
+}
+
+func TestContextNameMatchesCluster(t *testing.T) {
+	tests := []struct {
+		context string
+		want    bool
+	}{
+		{"arn:aws:eks:eu-west-2:111111111111:cluster/dev-zenith-eks-cluster", true},
+		{"dev-zenith-eks-cluster", true},
+		{"arn:aws-us-gov:eks:us-gov-west-1:111111111111:cluster/dev-zenith-eks-cluster", true},
+		{"arn:aws-cn:eks:cn-north-1:111111111111:cluster/dev-zenith-eks-cluster", true},
+		{"arn:aws:eks:eu-west-2:111111111111:cluster/dev-zenith-eks-cluster-blue", false},
+		{"arn:aws:eks:eu-west-2:111111111111:cluster/qa-zenith-eks-cluster", false},
+	}
+	for _, tt := range tests {
+		if got := contextNameMatchesCluster(tt.context, "dev-zenith-eks-cluster"); got != tt.want {
+			t.Errorf("contextNameMatchesCluster(%q) = %v, want %v", tt.context, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/k8s"
+)
+
+// RBACRule is one line of 'kubectl auth can-i --list': verbs allowed on
+// resources (or, for cluster endpoints, non-resource URLs). Rules limited
+// to ResourceNames only apply to those objects.
+type RBACRule struct {
+	Resources       []string `json:"resources,omitempty"`
+	NonResourceURLs []string `json:"non_resource_urls,omitempty"`
+	ResourceNames   []string `json:"resource_names,omitempty"`
+	Verbs           []string `json:"verbs"`
+}
+
+// RBACPermission is a verb on a resource that an rw command needs, such as
+// create on pods/portforward
+type RBACPermission struct {
+	Verb     string `json:"verb"`
+	Resource string `json:"resource"`
+}
+
+func (p RBACPermission) String() string { return p.Verb + " " + p.Resource }
+
+// RBACCheck is an rw operation and the permissions it needs in a namespace
+type RBACCheck struct {
+	Operation string           `json:"operation"`
+	Namespace string           `json:"namespace"`
+	Needs     []RBACPermission `json:"needs"`
+	// Missing are the needs no rule grants; empty when the operation works
+	Missing []RBACPermission `json:"missing,omitempty"`
+}
+
+// RBACReport summarizes what a user (or an impersonated one) may do in a
+// cluster namespace
+type RBACReport struct {
+	Context   string      `json:"context,omitempty"`
+	Namespace string      `json:"namespace"`
+	As        string      `json:"as,omitempty"`
+	Rules     []RBACRule  `json:"rules"`
+	Checks    []RBACCheck `json:"checks"`
+}
+
+// rbacChecks are the kubectl operations rw commands perform, with the
+// namespace each runs in
+func rbacChecks(namespace string) []RBACCheck {
+	cfg := config.Get()
+	return []RBACCheck{
+		{Operation: "Tunnels and database connections (rw tunnel, rw db)", Namespace: cfg.Namespaces.Tunnel, Needs: []RBACPermission{
+			{"create", "pods"}, {"get", "pods"}, {"delete", "pods"}, {"create", "pods/portforward"},
+			{"create", "secrets"}, {"delete", "secrets"},
+		}},
+		{Operation: "Tunnel usage report (rw report tunnel-usage)", Namespace: cfg.Namespaces.Tunnel, Needs: []RBACPermission{
+			{"list", "pods"},
+		}},
+		{Operation: "gRPC port-forwards (rw grpc)", Namespace: cfg.Namespaces.App, Needs: []RBACPermission{
+			{"get", "services"}, {"create", "pods/portforward"},
+		}},
+		{Operation: "Scaling (rw scale)", Namespace: cfg.Namespaces.App, Needs: []RBACPermission{
+			{"get", "horizontalpodautoscalers.autoscaling"}, {"patch", "horizontalpodautoscalers.autoscaling"},
+		}},
+		{Operation: "Reading pods and logs in the environment namespace", Namespace: namespace, Needs: []RBACPermission{
+			{"list", "pods"}, {"get", "pods/log"},
+		}},
+	}
+}
+
+// KubeCanI lists what the current user may do in namespace with 'kubectl
+// auth can-i --list', and checks the operations rw performs against it.
+// as impersonates another user or service account
+// (system:serviceaccount:<namespace>:<name>); kubeContext selects the
+// cluster without switching to it, empty for the current context.
+func KubeCanI(kubeContext, namespace, as string) (*RBACReport, error) {
+	rulesByNamespace := make(map[string][]RBACRule)
+	list := func(ns string) ([]RBACRule, error) {
+		if rules, ok := rulesByNamespace[ns]; ok {
+			return rules, nil
+		}
+		args := []string{"auth", "can-i", "--list", "-n", ns}
+		if as != "" {
+			args = append(args, "--as", as)
+		}
+		out, err := k8s.SharedRunner().Run(context.Background(), kubeContext, args...)
+		if err != nil {
+			return nil, fmt.Errorf("kubectl auth can-i failed in %s: %w", ns, err)
+		}
+		rules, err := ParseCanIList(out)
+		if err != nil {
+			return nil, err
+		}
+		rulesByNamespace[ns] = rules
+		return rules, nil
+	}
+
+	rules, err := list(namespace)
+	if err != nil {
+		return nil, err
+	}
+	report := &RBACReport{Context: kubeContext, Namespace: namespace, As: as, Rules: rules}
+	for _, check := range rbacChecks(namespace) {
+		nsRules, err := list(check.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, need := range check.Needs {
+			if !RBACAllows(nsRules, need) {
+				check.Missing = append(check.Missing, need)
+			}
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	return report, nil
+}
+
+// ParseCanIList reads the table 'kubectl auth can-i --list' prints. Columns
+// are found from the header, as resource names may contain spaces.
+func ParseCanIList(out []byte) ([]RBACRule, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	if !scanner.Scan() {
+		return nil, nil
+	}
+	header := scanner.Text()
+	columns := []string{"Resources", "Non-Resource URLs", "Resource Names", "Verbs"}
+	starts := make([]int, len(columns))
+	for i, name := range columns {
+		if starts[i] = strings.Index(header, name); starts[i] < 0 {
+			return nil, fmt.Errorf("unexpected kubectl auth can-i output: %q", header)
+		}
+	}
+
+	field := func(line string, i int) string {
+		if starts[i] >= len(line) {
+			return ""
+		}
+		end := len(line)
+		if i+1 < len(starts) && starts[i+1] < end {
+			end = starts[i+1]
+		}
+		return strings.TrimSpace(line[starts[i]:end])
+	}
+	list := func(s string) []string {
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+		if s == "" {
+			return nil
+		}
+		return strings.Fields(s)
+	}
+
+	var rules []RBACRule
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rule := RBACRule{
+			NonResourceURLs: list(field(line, 1)),
+			ResourceNames:   list(field(line, 2)),
+			Verbs:           list(field(line, 3)),
+		}
+		if r := field(line, 0); r != "" {
+			rule.Resources = []string{r}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// RBACAllows reports whether a rule grants a permission on every object of
+// the resource. Wildcards match, including group wildcards such as *.apps
+// and subresource wildcards such as pods/*.
+func RBACAllows(rules []RBACRule, need RBACPermission) bool {
+	for _, rule := range rules {
+		if len(rule.ResourceNames) > 0 {
+			continue
+		}
+		if !slices.Contains(rule.Verbs, need.Verb) && !slices.Contains(rule.Verbs, "*") {
+			continue
+		}
+		for _, r := range rule.Resources {
+			if rbacResourceMatches(r, need.Resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rbacResourceMatches compares a rule's resource (resource[/sub][.group])
+// with a needed one
+func rbacResourceMatches(rule, need string) bool {
+	if rule == "*" || rule == "*.*" || rule == need {
+		return true
+	}
+	ruleName, ruleGroup, _ := strings.Cut(rule, ".")
+	needName, needGroup, _ := strings.Cut(need, ".")
+	if ruleGroup != needGroup && ruleGroup != "*" {
+		return false
+	}
+	if ruleName == "*" {
+		return true
+	}
+	// pods/* grants every pod subresource
+	if base, ok := strings.CutSuffix(ruleName, "/*"); ok {
+		needBase, _, _ := strings.Cut(needName, "/")
+		return base == needBase
+	}
+	return ruleName == needName
+}
@@ -0,0 +1,44 @@
+package aws
+
+import "testing"
+
+func TestParseCanIList(t *testing.T) {
+	out := `Resources                                       Non-Resource URLs   Resource Names    Verbs
+pods/portforward                                []                  []                [create]
+pods                                            []                  []                [get list watch create delete]
+horizontalpodautoscalers.autoscaling            []                  []                [get]
+configmaps                                      []                  [app-config]      [get patch]
+deployments.*                                   []                  []                [*]
+                                                [/healthz]          []                [get]
+`
+	rules, err := ParseCanIList([]byte(out))
+	if err != nil {
+		t.Fatalf("ParseCanIList() error: %v", err)
+	}
+	if len(rules) != 6 {
+		t.Fatalf("ParseCanIList() = %d rules, want 6", len(rules))
+	}
+	if got := rules[1].Verbs; len(got) != 5 || got[4] != "delete" {
+		t.Errorf("rules[1].Verbs = %v, want five verbs", got)
+	}
+	if rules[5].Resources != nil || len(rules[5].NonResourceURLs) != 1 {
+		t.Errorf("rules[5] = %+v, want a non-resource URL rule", rules[5])
+	}
+
+	tests := []struct {
+		need RBACPermission
+		want bool
+	}{
+		{RBACPermission{"create", "pods/portforward"}, true},
+		{RBACPermission{"delete", "pods"}, true},
+		{RBACPermission{"get", "pods/log"}, false},
+		{RBACPermission{"patch", "horizontalpodautoscalers.autoscaling"}, false},
+		{RBACPermission{"patch", "configmaps"}, false},
+		{RBACPermission{"patch", "deployments.apps"}, true},
+	}
+	for _, tt := range tests {
+		if got := RBACAllows(rules, tt.need); got != tt.want {
+			t.Errorf("RBACAllows(%s) = %v, want %v", tt.need, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// DefaultKubeRefreshConcurrency is how many environments 'rw kube refresh
+// --all' fetches at once, to stay well under the EKS API rate limits
+const DefaultKubeRefreshConcurrency = 4
+
+// KubeRefreshResult is the outcome of refreshing one environment's
+// kubeconfig entry
+type KubeRefreshResult struct {
+	Env      string
+	Profile  string
+	Cluster  string
+	Duration time.Duration
+	Err      error
+}
+
+// RefreshKubeconfigs runs update-kubeconfig for every environment, at
+// most concurrency at a time, using each environment's profile. Each run
+// writes to its own temporary file, since concurrent update-kubeconfig
+// calls would overwrite each other's changes; the fresh entries are then
+// merged into the kubeconfig in one go, keeping the current context.
+// Results are in the order of envs.
+func (km *KubeManager) RefreshKubeconfigs(envs []db.Environment, concurrency int) ([]KubeRefreshResult, error) {
+	paths := kubeconfigPaths()
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("cannot locate the kubeconfig file")
+	}
+	target := paths[0]
+
+	tmpDir, err := os.MkdirTemp("", "rw-kube-refresh-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := refreshConcurrently(envs, concurrency, func(env db.Environment) error {
+		return updateKubeconfigFile(env, filepath.Join(tmpDir, env.Name+".yaml"))
+	})
+
+	var fresh []string
+	for _, r := range results {
+		if r.Err == nil {
+			fresh = append(fresh, filepath.Join(tmpDir, r.Env+".yaml"))
+		}
+	}
+	if len(fresh) == 0 {
+		return results, nil
+	}
+	return results, km.mergeKubeconfigs(target, fresh)
+}
+
+// refreshConcurrently calls refresh for each environment with at most
+// concurrency calls in flight
+func refreshConcurrently(envs []db.Environment, concurrency int, refresh func(db.Environment) error) []KubeRefreshResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]KubeRefreshResult, len(envs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, env := range envs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			err := refresh(env)
+			results[i] = KubeRefreshResult{
+				Env:      env.Name,
+				Profile:  env.AWSProfile,
+				Cluster:  env.ClusterName,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// updateKubeconfigFile writes an environment's cluster entry to path
+func updateKubeconfigFile(env db.Environment, path string) error {
+	if env.ClusterName == "" {
+		return fmt.Errorf("no cluster configured")
+	}
+	args := []string{"eks", "update-kubeconfig", "--name", env.ClusterName, "--kubeconfig", path}
+	if env.Region != "" {
+		args = append(args, "--region", env.Region)
+	}
+	if env.AWSProfile != "" {
+		args = append(args, "--profile", env.AWSProfile)
+	}
+	cmd := awscli.CreateCommand(args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, utils.Redact(strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+// mergeKubeconfigs merges fresh kubeconfig files into target. Entries in
+// the fresh files replace entries of the same name; the current context of
+// target is kept.
+func (km *KubeManager) mergeKubeconfigs(target string, fresh []string) error {
+	current, _ := ReadKubeconfigContext()
+
+	files := fresh
+	if _, err := os.Stat(target); err == nil {
+		files = append(files, target)
+	}
+	cmd := awscli.CreateKubectlCommand("config", "view", "--flatten", "--raw")
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+strings.Join(files, string(os.PathListSeparator)))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to merge kubeconfig: %w: %s", err, utils.Redact(strings.TrimSpace(stderr.String())))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(target, stdout.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+
+	defer RefreshKubeContextCache()
+	if current == "" {
+		return nil
+	}
+	restore := awscli.CreateKubectlCommand("config", "use-context", current)
+	restore.Env = append(os.Environ(), "KUBECONFIG="+target)
+	if out, err := restore.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restore context %s: %w: %s", current, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
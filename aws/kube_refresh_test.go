@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rolewalkers/internal/db"
+)
+
+func TestRefreshConcurrently(t *testing.T) {
+	envs := []db.Environment{
+		{Name: "dev", AWSProfile: "zenith-dev"},
+		{Name: "sit", AWSProfile: "zenith-sit"},
+		{Name: "preprod", AWSProfile: "zenith-preprod"},
+		{Name: "prod", AWSProfile: "zenith-prod"},
+		{Name: "trg", AWSProfile: "zenith-trg"},
+	}
+
+	var running, peak atomic.Int32
+	results := refreshConcurrently(envs, 2, func(env db.Environment) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if env.Name == "preprod" {
+			return errors.New("token expired")
+		}
+		return nil
+	})
+
+	if peak.Load() > 2 {
+		t.Errorf("ran %d refreshes at once, want at most 2", peak.Load())
+	}
+	if len(results) != len(envs) {
+		t.Fatalf("got %d results, want %d", len(results), len(envs))
+	}
+	for i, r := range results {
+		if r.Env != envs[i].Name || r.Profile != envs[i].AWSProfile {
+			t.Errorf("results[%d] = %s (%s), want %s in input order", i, r.Env, r.Profile, envs[i].Name)
+		}
+		if (r.Err != nil) != (r.Env == "preprod") {
+			t.Errorf("results[%d].Err = %v", i, r.Err)
+		}
+	}
+}
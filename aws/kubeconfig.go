@@ -0,0 +1,17 @@
+package aws
+
+import "rolewalkers/internal/k8s"
+
+// kubeconfigPaths returns the files kubectl merges: $KUBECONFIG, else
+// ~/.kube/config
+func kubeconfigPaths() []string {
+	return k8s.KubeconfigPaths()
+}
+
+// ReadKubeconfigContext returns the current kubectl context and namespace by
+// reading the kubeconfig files directly, without running kubectl. Like
+// kubectl, the first file to set a value wins. The namespace defaults to
+// "default"; the context is empty if none is set.
+func ReadKubeconfigContext() (string, string) {
+	return k8s.ReadKubeconfigContext()
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"rolewalkers/internal/awsarn"
 	"rolewalkers/internal/awscli"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 	"strings"
 )
 
@@ -41,7 +43,7 @@ func (km *KubeManager) GetContexts() ([]KubeContext, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to get kubectl contexts: %w: %s", err, stderr.String())
+		return nil, fmt.Errorf("failed to get kubectl contexts: %w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	output := strings.TrimSpace(out.String())
@@ -102,7 +104,7 @@ func (km *KubeManager) GetCurrentContext() (string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to get current context: %w: %s", err, stderr.String())
+		return "", fmt.Errorf("failed to get current context: %w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	return strings.TrimSpace(out.String()), nil
@@ -137,8 +139,9 @@ func (km *KubeManager) SetNamespace(namespace string) error {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to set namespace: %w: %s", err, stderr.String())
+		return fmt.Errorf("failed to set namespace: %w: %s", err, utils.Redact(stderr.String()))
 	}
+	RefreshKubeContextCache()
 
 	return nil
 }
@@ -152,7 +155,7 @@ func (km *KubeManager) ListNamespaces() ([]string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to list namespaces: %w: %s", err, stderr.String())
+		return nil, fmt.Errorf("failed to list namespaces: %w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	output := strings.TrimSpace(out.String())
@@ -171,14 +174,37 @@ func (km *KubeManager) SwitchContext(contextName string) error {
 	if contextName == "" {
 		return fmt.Errorf("context name cannot be empty")
 	}
+	// Already current: skip running kubectl
+	if current, _ := CachedKubeContext(); current == contextName {
+		return nil
+	}
 
 	cmd := exec.Command("kubectl", "config", "use-context", contextName)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to switch context: %w: %s", err, stderr.String())
+		return fmt.Errorf("failed to switch context: %w: %s", err, utils.Redact(stderr.String()))
 	}
+	RefreshKubeContextCache()
+
+	return nil
+}
+
+// DeleteContext removes a kubectl context from kubeconfig
+func (km *KubeManager) DeleteContext(contextName string) error {
+	if contextName == "" {
+		return fmt.Errorf("context name cannot be empty")
+	}
+
+	cmd := exec.Command("kubectl", "config", "delete-context", contextName)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete context: %w: %s", err, utils.Redact(stderr.String()))
+	}
+	RefreshKubeContextCache()
 
 	return nil
 }
@@ -200,17 +226,11 @@ func (km *KubeManager) FindContextForEnv(env string) (string, error) {
 	}
 
 	clusterName := km.getClusterNameForEnv(env)
-	
-	// Pattern to match ARN format contexts
-	arnPattern := regexp.MustCompile(fmt.Sprintf(`arn:aws:eks:[^:]+:\d+:cluster/%s`, regexp.QuoteMeta(clusterName)))
+	arnPattern := clusterARNPattern(clusterName)
 
 	for _, ctx := range contexts {
-		// Check if context name matches ARN pattern with cluster name
-		if arnPattern.MatchString(ctx.Name) {
-			return ctx.Name, nil
-		}
-		// Check if context name is the cluster name directly
-		if ctx.Name == clusterName {
+		// Check if context name is the cluster's ARN or name
+		if contextNameMatchesCluster(ctx.Name, clusterName) {
 			return ctx.Name, nil
 		}
 		// Check cluster field for ARN pattern
@@ -222,6 +242,18 @@ func (km *KubeManager) FindContextForEnv(env string) (string, error) {
 	return "", fmt.Errorf("no matching kubectl context found for '%s' (looking for cluster: %s)", env, clusterName)
 }
 
+// clusterARNPattern matches the ARN of an EKS cluster in any partition,
+// which 'aws eks update-kubeconfig' uses as the context name
+func clusterARNPattern(clusterName string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`arn:%s:eks:[^:]+:\d+:cluster/%s$`, awsarn.Pattern(), regexp.QuoteMeta(clusterName)))
+}
+
+// contextNameMatchesCluster reports whether a context name refers to the
+// cluster, by ARN or by plain cluster name
+func contextNameMatchesCluster(contextName, clusterName string) bool {
+	return contextName == clusterName || clusterARNPattern(clusterName).MatchString(contextName)
+}
+
 // UpdateKubeconfig updates the kubeconfig for the specified EKS cluster
 func (km *KubeManager) UpdateKubeconfig(clusterName, region string) error {
 	if clusterName == "" {
@@ -231,7 +263,7 @@ func (km *KubeManager) UpdateKubeconfig(clusterName, region string) error {
 		region = "eu-west-2" // Default fallback
 	}
 
-	fmt.Printf("Updating kubeconfig for cluster: %s...\n", clusterName)
+	fmt.Fprintf(utils.Stderr, "Updating kubeconfig for cluster: %s...\n", clusterName)
 	
 	cmd := awscli.CreateCommand("eks", "update-kubeconfig",
 		"--name", clusterName,
@@ -242,8 +274,9 @@ func (km *KubeManager) UpdateKubeconfig(clusterName, region string) error {
 	cmd.Stderr = &stderr
 	
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to update kubeconfig: %w: %s", err, stderr.String())
+		return fmt.Errorf("failed to update kubeconfig: %w: %s", err, utils.Redact(stderr.String()))
 	}
+	RefreshKubeContextCache()
 	
 	return nil
 }
@@ -261,6 +294,12 @@ func (km *KubeManager) SwitchContextForEnvWithProfile(env string, profileSwitche
 		return fmt.Errorf("environment name cannot be empty")
 	}
 
+	// Already on the environment's cluster: skip listing contexts, switching
+	// profile and updating kubeconfig
+	if current, _ := CachedKubeContext(); current != "" && contextNameMatchesCluster(current, km.getClusterNameForEnv(env)) {
+		return nil
+	}
+
 	// Get environment config from database
 	if km.configRepo != nil {
 		envConfig, err := km.configRepo.GetEnvironment(env)
@@ -270,7 +309,7 @@ func (km *KubeManager) SwitchContextForEnvWithProfile(env string, profileSwitche
 			if err != nil {
 				// Context not found, need to update kubeconfig from AWS
 				if profileSwitcher != nil {
-					fmt.Printf("Switching to AWS profile: %s...\n", envConfig.AWSProfile)
+					fmt.Fprintf(utils.Stderr, "Switching to AWS profile: %s...\n", envConfig.AWSProfile)
 					if switchErr := profileSwitcher.SwitchProfile(envConfig.AWSProfile); switchErr != nil {
 						return fmt.Errorf("failed to switch AWS profile: %w", switchErr)
 					}
@@ -301,7 +340,7 @@ func (km *KubeManager) SwitchContextForEnvWithProfile(env string, profileSwitche
 		// First, ensure we're using the correct AWS profile
 		if profileSwitcher != nil {
 			profileName := km.getProfileNameForEnv(env)
-			fmt.Printf("Switching to AWS profile: %s...\n", profileName)
+			fmt.Fprintf(utils.Stderr, "Switching to AWS profile: %s...\n", profileName)
 			if switchErr := profileSwitcher.SwitchProfile(profileName); switchErr != nil {
 				return fmt.Errorf("failed to switch AWS profile: %w", switchErr)
 			}
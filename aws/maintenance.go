@@ -1,24 +1,20 @@
 package aws
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/fastly"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
 	"strings"
 	"time"
 )
 
 // MaintenanceManager handles Fastly maintenance mode operations
 type MaintenanceManager struct {
-	apiToken   string
-	baseURL    string
-	httpClient *http.Client
+	fastly     *fastly.Client
 	configRepo *db.ConfigRepository
 }
 
@@ -31,30 +27,82 @@ type MaintenanceStatus struct {
 	Error       string `json:"error,omitempty"`
 }
 
-// fastlyService represents a Fastly service from the API
-type fastlyService struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+// MaintenanceState is the maintenanceMode dictionary value of one service,
+// as recorded in the audit log
+type MaintenanceState struct {
+	ServiceType string `json:"serviceType"`
+	Value       string `json:"value"`
+}
+
+// MaintenanceChange is the dictionary item a maintenance toggle would write
+// on one service, resolved without changing anything (see Plan)
+type MaintenanceChange struct {
+	ServiceType string `json:"serviceType"`
+	ServiceName string `json:"serviceName"`
+	ServiceID   string `json:"serviceId"`
+	Version     int    `json:"version"`
+	Dictionary  string `json:"dictionary"`
+	Key         string `json:"key"`
+	// Current is the item's value, or "" when the item doesn't exist yet
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+	// OtherMatches are further services matching the <env>*<type> name
+	// pattern; only the first match (ServiceName) is changed
+	OtherMatches []string `json:"otherMatches,omitempty"`
 }
 
-// fastlyServiceDetail represents detailed service info
-type fastlyServiceDetail struct {
-	Versions []struct {
-		Number int  `json:"number"`
-		Active bool `json:"active"`
-	} `json:"versions"`
+// Changed reports whether applying the change would modify the item
+func (c MaintenanceChange) Changed() bool {
+	return c.Current != c.Desired
+}
+
+// Effective is the value the service acts on now: a missing item is
+// treated as disabled
+func (c MaintenanceChange) Effective() string {
+	if c.Current == "" {
+		return maintenanceDefault
+	}
+	return c.Current
+}
+
+// MaintenanceMapping is how a service type resolves to Fastly services for
+// an environment (see Validate)
+type MaintenanceMapping struct {
+	ServiceType string `json:"serviceType"`
+	// Matches are the services matching <env>*<type>; toggles use the first
+	Matches []string `json:"matches"`
+	// Error is set when nothing matched, or the first match has no active
+	// version or maintenance dictionary
+	Error string `json:"error,omitempty"`
 }
 
-// fastlyDictionary represents a Fastly dictionary
-type fastlyDictionary struct {
-	ID string `json:"id"`
+// OK reports whether exactly one service matched and it can be toggled
+func (m MaintenanceMapping) OK() bool {
+	return len(m.Matches) == 1 && m.Error == ""
 }
 
-// fastlyDictionaryItem represents a dictionary item response
-type fastlyDictionaryItem struct {
-	ItemValue string `json:"item_value"`
+// maintenanceTarget is the maintenance dictionary of the service matching an
+// environment and service type
+type maintenanceTarget struct {
+	serviceName  string
+	otherMatches []string
+	serviceID    string
+	version      int
+	dictionaryID string
 }
 
+const (
+	// fastlyTimeout bounds each Fastly API operation, including rate-limit retries
+	fastlyTimeout = 60 * time.Second
+
+	// maintenanceDictionary and maintenanceItemKey locate the maintenance flag
+	maintenanceDictionary = "MainConfig"
+	maintenanceItemKey    = "maintenanceMode"
+
+	// maintenanceDefault is the value of a missing maintenanceMode item
+	maintenanceDefault = "false"
+)
+
 // NewMaintenanceManager creates a new maintenance manager
 func NewMaintenanceManager() *MaintenanceManager {
 	return newMaintenanceManager(nil)
@@ -73,13 +121,8 @@ func newMaintenanceManager(repo *db.ConfigRepository) *MaintenanceManager {
 			baseURL = endpoint.BaseURL
 		}
 	}
-	if baseURL == "" {
-		baseURL = "https://api.fastly.com"
-	}
 	return &MaintenanceManager{
-		apiToken:   os.Getenv("FASTLY_API_TOKEN"),
-		baseURL:    baseURL,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		fastly:     fastly.NewClient(baseURL, os.Getenv("FASTLY_API_TOKEN")),
 		configRepo: repo,
 	}
 }
@@ -106,31 +149,150 @@ func (mm *MaintenanceManager) ValidServiceTypes() []string {
 
 // Toggle enables or disables maintenance mode for a service
 func (mm *MaintenanceManager) Toggle(env, serviceType string, enable bool) error {
-	if mm.apiToken == "" {
-		return fmt.Errorf("FASTLY_API_TOKEN environment variable is not set")
+	serviceTypes, err := mm.serviceTypesFor(env, serviceType)
+	if err != nil {
+		return err
+	}
+
+	enableStr := "false"
+	if enable {
+		enableStr = "true"
+	}
+
+	// Track what each service was set to before the change so it can be undone
+	var previous, next []MaintenanceState
+	var toggleErr error
+	for _, svcType := range serviceTypes {
+		prevValue, err := mm.toggleService(env, svcType, enable)
+		if err != nil {
+			toggleErr = err
+			break
+		}
+		previous = append(previous, MaintenanceState{ServiceType: svcType, Value: prevValue})
+		next = append(next, MaintenanceState{ServiceType: svcType, Value: enableStr})
+	}
+
+	if len(previous) > 0 {
+		msgID := messages.AuditMaintenanceDisable
+		if enable {
+			msgID = messages.AuditMaintenanceEnable
+		}
+		recordAudit(mm.configRepo, AuditActionMaintenance, env, serviceType, previous, next,
+			messages.New(msgID, "env", env, "type", serviceType))
+	}
+
+	return toggleErr
+}
+
+// Plan resolves the services, dictionaries and current values a Toggle
+// would touch and returns the changes it would make, without writing
+// anything. It is used by 'rw maintenance --dry-run'.
+func (mm *MaintenanceManager) Plan(env, serviceType string, enable bool) ([]MaintenanceChange, error) {
+	serviceTypes, err := mm.serviceTypesFor(env, serviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := "false"
+	if enable {
+		desired = "true"
+	}
+
+	var changes []MaintenanceChange
+	for _, svcType := range serviceTypes {
+		target, err := mm.resolveTarget(env, svcType)
+		if err != nil {
+			return nil, err
+		}
+		current, err := mm.getMaintenanceModeValue(target.serviceID, target.dictionaryID)
+		if err != nil && !fastly.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to read %s on %s: %w", maintenanceItemKey, target.serviceName, err)
+		}
+		changes = append(changes, MaintenanceChange{
+			ServiceType:  svcType,
+			ServiceName:  target.serviceName,
+			ServiceID:    target.serviceID,
+			Version:      target.version,
+			Dictionary:   maintenanceDictionary,
+			Key:          maintenanceItemKey,
+			Current:      current,
+			Desired:      desired,
+			OtherMatches: target.otherMatches,
+		})
+	}
+	return changes, nil
+}
+
+// Validate resolves the api and pwa services for an environment the way a
+// toggle would, reporting every service that matches so ambiguous or
+// missing mappings are found before maintenance mode is needed. Nothing is
+// changed.
+func (mm *MaintenanceManager) Validate(env string) ([]MaintenanceMapping, error) {
+	if _, err := mm.serviceTypesFor(env, "all"); err != nil {
+		return nil, err
+	}
+
+	var mappings []MaintenanceMapping
+	for _, svcType := range []string{"api", "pwa"} {
+		mapping := MaintenanceMapping{ServiceType: svcType}
+		matches, err := mm.matchServices(env, svcType)
+		if err != nil {
+			mapping.Error = err.Error()
+			mappings = append(mappings, mapping)
+			continue
+		}
+		mapping.Matches = matches
+
+		target := maintenanceTarget{serviceName: matches[0]}
+		if err := mm.resolveDictionary(&target); err != nil {
+			mapping.Error = fmt.Sprintf("%s: %v", target.serviceName, err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// serviceTypesFor validates a toggle's arguments and expands "all"
+func (mm *MaintenanceManager) serviceTypesFor(env, serviceType string) ([]string, error) {
+	if !mm.fastly.HasToken() {
+		return nil, fmt.Errorf("FASTLY_API_TOKEN environment variable is not set")
 	}
 
 	if !mm.isValidEnv(env) {
-		return fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(mm.ValidEnvironments(), ", "))
+		return nil, fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(mm.ValidEnvironments(), ", "))
 	}
 
 	if !mm.isValidServiceType(serviceType) {
-		return fmt.Errorf("invalid service type: %s (valid: %s)", serviceType, strings.Join(mm.ValidServiceTypes(), ", "))
+		return nil, fmt.Errorf("invalid service type: %s (valid: %s)", serviceType, strings.Join(mm.ValidServiceTypes(), ", "))
 	}
 
 	if serviceType == "all" {
-		if err := mm.toggleService(env, "api", enable); err != nil {
+		return []string{"api", "pwa"}, nil
+	}
+	return []string{serviceType}, nil
+}
+
+// Restore sets maintenance mode back to previously recorded values.
+// It is used by 'rw undo' and does not create a new audit entry.
+func (mm *MaintenanceManager) Restore(env string, states []MaintenanceState) error {
+	if !mm.fastly.HasToken() {
+		return fmt.Errorf("FASTLY_API_TOKEN environment variable is not set")
+	}
+
+	for _, st := range states {
+		_, serviceName, err := mm.setMaintenanceMode(env, st.ServiceType, st.Value)
+		if err != nil {
 			return err
 		}
-		return mm.toggleService(env, "pwa", enable)
+		fmt.Printf(utils.OK()+" Maintenance mode restored to %s for %s %s (%s)\n", st.Value, env, st.ServiceType, serviceName)
 	}
 
-	return mm.toggleService(env, serviceType, enable)
+	return nil
 }
 
 // Status returns the current maintenance status for an environment
 func (mm *MaintenanceManager) Status(env string) ([]MaintenanceStatus, error) {
-	if mm.apiToken == "" {
+	if !mm.fastly.HasToken() {
 		return nil, fmt.Errorf("FASTLY_API_TOKEN environment variable is not set")
 	}
 
@@ -160,280 +322,177 @@ func (mm *MaintenanceManager) Status(env string) ([]MaintenanceStatus, error) {
 	return statuses, nil
 }
 
-func (mm *MaintenanceManager) toggleService(env, serviceType string, enable bool) error {
-	// Find service by name pattern
-	serviceName, err := mm.findServiceName(env, serviceType)
-	if err != nil {
-		return fmt.Errorf("failed to find %s service for %s: %w", serviceType, env, err)
-	}
-
-	// Get service ID
-	serviceID, err := mm.getServiceID(serviceName)
-	if err != nil {
-		return fmt.Errorf("failed to get service ID: %w", err)
-	}
-
-	// Get active version
-	activeVersion, err := mm.getActiveVersion(serviceID)
-	if err != nil {
-		return fmt.Errorf("failed to get active version: %w", err)
-	}
-
-	// Get dictionary ID
-	dictionaryID, err := mm.getDictionaryID(serviceID, activeVersion)
-	if err != nil {
-		return fmt.Errorf("failed to get dictionary ID: %w", err)
-	}
-
-	// Update maintenance mode
+// toggleService enables or disables maintenance mode for a single service
+// and returns the value it replaced.
+func (mm *MaintenanceManager) toggleService(env, serviceType string, enable bool) (string, error) {
 	enableStr := "false"
 	if enable {
 		enableStr = "true"
 	}
 
-	if err := mm.updateMaintenanceMode(serviceID, dictionaryID, enableStr); err != nil {
-		return fmt.Errorf("failed to update maintenance mode: %w", err)
+	prevValue, serviceName, err := mm.setMaintenanceMode(env, serviceType, enableStr)
+	if err != nil {
+		return "", err
 	}
 
 	action := "disabled"
 	if enable {
 		action = "enabled"
 	}
-	fmt.Printf("✓ Maintenance mode %s for %s %s (%s)\n", action, env, serviceType, serviceName)
+	fmt.Printf(utils.OK()+" Maintenance mode %s for %s %s (%s)\n", action, env, serviceType, serviceName)
 
-	return nil
+	return prevValue, nil
 }
 
-func (mm *MaintenanceManager) getMaintenanceStatus(env, serviceType string) (bool, string, error) {
-	serviceName, err := mm.findServiceName(env, serviceType)
+// setMaintenanceMode writes the maintenanceMode dictionary item for a service
+// and returns the previous value along with the resolved service name.
+func (mm *MaintenanceManager) setMaintenanceMode(env, serviceType, value string) (string, string, error) {
+	target, err := mm.resolveTarget(env, serviceType)
 	if err != nil {
-		return false, "", err
+		return "", target.serviceName, err
 	}
 
-	serviceID, err := mm.getServiceID(serviceName)
+	// Remember the current value; a missing item is treated as disabled
+	prevValue, err := mm.getMaintenanceModeValue(target.serviceID, target.dictionaryID)
 	if err != nil {
-		return false, serviceName, err
+		prevValue = maintenanceDefault
 	}
 
-	activeVersion, err := mm.getActiveVersion(serviceID)
-	if err != nil {
-		return false, serviceName, err
+	if err := mm.updateMaintenanceMode(target.serviceID, target.dictionaryID, value); err != nil {
+		return "", target.serviceName, fmt.Errorf("failed to update maintenance mode: %w", err)
 	}
 
-	dictionaryID, err := mm.getDictionaryID(serviceID, activeVersion)
+	return prevValue, target.serviceName, nil
+}
+
+func (mm *MaintenanceManager) getMaintenanceStatus(env, serviceType string) (bool, string, error) {
+	target, err := mm.resolveTarget(env, serviceType)
 	if err != nil {
-		return false, serviceName, err
+		return false, target.serviceName, err
 	}
 
-	value, err := mm.getMaintenanceModeValue(serviceID, dictionaryID)
+	value, err := mm.getMaintenanceModeValue(target.serviceID, target.dictionaryID)
 	if err != nil {
-		return false, serviceName, err
+		return false, target.serviceName, err
 	}
 
-	return value == "true", serviceName, nil
+	return value == "true", target.serviceName, nil
 }
 
-func (mm *MaintenanceManager) findServiceName(env, serviceType string) (string, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", mm.baseURL+"/service", nil)
+// resolveTarget finds the service for an environment and service type, its
+// active version and its maintenance dictionary. The service name is set
+// even when a later step fails.
+func (mm *MaintenanceManager) resolveTarget(env, serviceType string) (maintenanceTarget, error) {
+	var target maintenanceTarget
+
+	// Find service by name pattern
+	matches, err := mm.matchServices(env, serviceType)
 	if err != nil {
-		return "", err
+		return target, fmt.Errorf("failed to find %s service for %s: %w", serviceType, env, err)
 	}
-	mm.setHeaders(req)
+	target.serviceName, target.otherMatches = matches[0], matches[1:]
+	return target, mm.resolveDictionary(&target)
+}
 
-	resp, err := mm.httpClient.Do(req)
-	if err != nil {
-		return "", err
+// resolveDictionary fills in the service ID, active version and maintenance
+// dictionary of a target whose service name is known
+func (mm *MaintenanceManager) resolveDictionary(target *maintenanceTarget) error {
+	var err error
+	if target.serviceID, err = mm.getServiceID(target.serviceName); err != nil {
+		return fmt.Errorf("failed to get service ID: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024)) // 10MB max
-	if err != nil {
-		return "", err
+	if target.version, err = mm.getActiveVersion(target.serviceID); err != nil {
+		return fmt.Errorf("failed to get active version: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Fastly API error listing services (HTTP %d): %s", resp.StatusCode, string(body))
+	if target.dictionaryID, err = mm.getDictionaryID(target.serviceID, target.version); err != nil {
+		return fmt.Errorf("failed to get dictionary ID: %w", err)
 	}
 
-	var services []fastlyService
-	if err := json.Unmarshal(body, &services); err != nil {
-		return "", err
+	return nil
+}
+
+// matchServices returns the services matching the pattern <env>*<type>, in
+// the order Fastly lists them; the first one is used
+func (mm *MaintenanceManager) matchServices(env, serviceType string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fastlyTimeout)
+	defer cancel()
+
+	services, err := mm.fastly.ListServices(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Find service matching pattern: <env>.*<type>
 	pattern := strings.ToLower(env)
 	typePattern := strings.ToLower(serviceType)
 
+	var matches []string
 	for _, svc := range services {
 		nameLower := strings.ToLower(svc.Name)
 		if strings.HasPrefix(nameLower, pattern) && strings.Contains(nameLower, typePattern) {
-			return svc.Name, nil
+			matches = append(matches, svc.Name)
 		}
 	}
 
-	return "", fmt.Errorf("no service found matching %s %s", env, serviceType)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no service found matching %s %s", env, serviceType)
+	}
+	return matches, nil
 }
 
 func (mm *MaintenanceManager) getServiceID(serviceName string) (string, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", mm.baseURL+"/service/search?name="+url.QueryEscape(serviceName), nil)
-	if err != nil {
-		return "", err
-	}
-	mm.setHeaders(req)
-
-	resp, err := mm.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), fastlyTimeout)
+	defer cancel()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024)) // 10MB max
+	svc, err := mm.fastly.SearchService(ctx, serviceName)
 	if err != nil {
 		return "", err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Fastly API error searching service %q (HTTP %d): %s", serviceName, resp.StatusCode, string(body))
-	}
-
-	var svc fastlyService
-	if err := json.Unmarshal(body, &svc); err != nil {
-		return "", err
-	}
-
 	return svc.ID, nil
 }
 
 func (mm *MaintenanceManager) getActiveVersion(serviceID string) (int, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", fmt.Sprintf("%s/service/%s", mm.baseURL, serviceID), nil)
-	if err != nil {
-		return 0, err
-	}
-	mm.setHeaders(req)
+	ctx, cancel := context.WithTimeout(context.Background(), fastlyTimeout)
+	defer cancel()
 
-	resp, err := mm.httpClient.Do(req)
+	detail, err := mm.fastly.GetServiceDetail(ctx, serviceID)
 	if err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024)) // 10MB max
-	if err != nil {
-		return 0, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("Fastly API error getting service detail (HTTP %d): %s", resp.StatusCode, string(body))
-	}
-
-	var detail fastlyServiceDetail
-	if err := json.Unmarshal(body, &detail); err != nil {
-		return 0, err
-	}
-
-	for _, v := range detail.Versions {
-		if v.Active {
-			return v.Number, nil
-		}
-	}
-
-	return 0, fmt.Errorf("no active version found")
+	return detail.ActiveVersion()
 }
 
 func (mm *MaintenanceManager) getDictionaryID(serviceID string, version int) (string, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", fmt.Sprintf("%s/service/%s/version/%d/dictionary/MainConfig", mm.baseURL, serviceID, version), nil)
-	if err != nil {
-		return "", err
-	}
-	mm.setHeaders(req)
-
-	resp, err := mm.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), fastlyTimeout)
+	defer cancel()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024)) // 10MB max
+	dict, err := mm.fastly.GetDictionary(ctx, serviceID, version, maintenanceDictionary)
 	if err != nil {
 		return "", err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Fastly API error getting dictionary (HTTP %d): %s", resp.StatusCode, string(body))
-	}
-
-	var dict fastlyDictionary
-	if err := json.Unmarshal(body, &dict); err != nil {
-		return "", err
-	}
-
 	return dict.ID, nil
 }
 
 func (mm *MaintenanceManager) updateMaintenanceMode(serviceID, dictionaryID, value string) error {
-	data := url.Values{}
-	data.Set("item_value", value)
-
-	req, err := http.NewRequestWithContext(context.Background(), "PUT",
-		fmt.Sprintf("%s/service/%s/dictionary/%s/item/maintenanceMode", mm.baseURL, serviceID, dictionaryID),
-		bytes.NewBufferString(data.Encode()))
-	if err != nil {
-		return err
-	}
-	mm.setHeaders(req)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx, cancel := context.WithTimeout(context.Background(), fastlyTimeout)
+	defer cancel()
 
-	resp, err := mm.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	return mm.fastly.UpdateDictionaryItem(ctx, serviceID, dictionaryID, maintenanceItemKey, value)
 }
 
 func (mm *MaintenanceManager) getMaintenanceModeValue(serviceID, dictionaryID string) (string, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET",
-		fmt.Sprintf("%s/service/%s/dictionary/%s/item/maintenanceMode", mm.baseURL, serviceID, dictionaryID),
-		nil)
-	if err != nil {
-		return "", err
-	}
-	mm.setHeaders(req)
-
-	resp, err := mm.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), fastlyTimeout)
+	defer cancel()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024)) // 10MB max
+	item, err := mm.fastly.GetDictionaryItem(ctx, serviceID, dictionaryID, maintenanceItemKey)
 	if err != nil {
 		return "", err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Fastly API error getting maintenance mode value (HTTP %d): %s", resp.StatusCode, string(body))
-	}
-
-	var item fastlyDictionaryItem
-	if err := json.Unmarshal(body, &item); err != nil {
-		return "", err
-	}
-
 	return item.ItemValue, nil
 }
 
-func (mm *MaintenanceManager) setHeaders(req *http.Request) {
-	req.Header.Set("Fastly-Key", mm.apiToken)
-	req.Header.Set("Accept", "application/json")
-}
-
 func (mm *MaintenanceManager) isValidEnv(env string) bool {
 	for _, e := range mm.ValidEnvironments() {
 		if e == env {
@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rolewalkers/internal/fastly"
+)
+
+// newMaintenanceTestManager serves a Fastly account with an ambiguous api
+// mapping for prod and fails the test on any write
+func newMaintenanceTestManager(t *testing.T) *MaintenanceManager {
+	t.Helper()
+	services := map[string]string{"prod-zenith-api": "svc-api", "prod-zenith-api-legacy": "svc-legacy", "prod-zenith-pwa": "svc-pwa"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("sent %s %s, want reads only", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		path := r.URL.Path
+		switch {
+		case path == "/service" && r.URL.Query().Get("page") == "1":
+			fmt.Fprint(w, `[{"id":"svc-api","name":"prod-zenith-api"},{"id":"svc-legacy","name":"prod-zenith-api-legacy"},{"id":"svc-pwa","name":"prod-zenith-pwa"}]`)
+		case path == "/service":
+			fmt.Fprint(w, `[]`)
+		case path == "/service/search":
+			name := r.URL.Query().Get("name")
+			fmt.Fprintf(w, `{"id":%q,"name":%q}`, services[name], name)
+		case strings.HasSuffix(path, "/dictionary/MainConfig"):
+			fmt.Fprint(w, `{"id":"dict","name":"MainConfig"}`)
+		case path == "/service/svc-api/dictionary/dict/item/maintenanceMode":
+			fmt.Fprint(w, `{"item_key":"maintenanceMode","item_value":"false"}`)
+		case path == "/service/svc-pwa/dictionary/dict/item/maintenanceMode":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"msg":"Record not found"}`)
+		case strings.HasPrefix(path, "/service/"):
+			fmt.Fprint(w, `{"versions":[{"number":41,"active":false},{"number":42,"active":true}]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &MaintenanceManager{fastly: fastly.NewClient(srv.URL, "test-token")}
+}
+
+func TestMaintenancePlanDoesNotWrite(t *testing.T) {
+	mm := newMaintenanceTestManager(t)
+	changes, err := mm.Plan("prod", "all", true)
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Plan() = %+v, want api and pwa", changes)
+	}
+
+	api, pwa := changes[0], changes[1]
+	if api.ServiceName != "prod-zenith-api" || api.ServiceID != "svc-api" || api.Version != 42 {
+		t.Errorf("api change = %+v, want prod-zenith-api at version 42", api)
+	}
+	if api.Current != "false" || api.Desired != "true" || !api.Changed() {
+		t.Errorf("api change = %+v, want false -> true", api)
+	}
+	if len(api.OtherMatches) != 1 || api.OtherMatches[0] != "prod-zenith-api-legacy" {
+		t.Errorf("api OtherMatches = %v, want the ambiguous legacy service", api.OtherMatches)
+	}
+	if pwa.Current != "" || pwa.Effective() != "false" || !pwa.Changed() || len(pwa.OtherMatches) != 0 {
+		t.Errorf("pwa change = %+v, want a missing item, effectively false, that would be created", pwa)
+	}
+
+	if _, err := mm.Plan("prod", "cdn", true); err == nil {
+		t.Error("Plan() should reject an unknown service type")
+	}
+}
+
+func TestMaintenanceValidate(t *testing.T) {
+	mm := newMaintenanceTestManager(t)
+
+	mappings, err := mm.Validate("prod")
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if len(mappings) != 2 {
+		t.Fatalf("Validate() = %+v, want api and pwa", mappings)
+	}
+	if api := mappings[0]; api.OK() || len(api.Matches) != 2 {
+		t.Errorf("api mapping = %+v, want two matches reported as not OK", api)
+	}
+	if pwa := mappings[1]; !pwa.OK() || pwa.Matches[0] != "prod-zenith-pwa" {
+		t.Errorf("pwa mapping = %+v, want prod-zenith-pwa", pwa)
+	}
+
+	mappings, err = mm.Validate("dev")
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	for _, m := range mappings {
+		if m.OK() || len(m.Matches) != 0 || m.Error == "" {
+			t.Errorf("%s mapping for dev = %+v, want a missing-service error", m.ServiceType, m)
+		}
+	}
+}
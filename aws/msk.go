@@ -1,11 +1,9 @@
 package aws
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/k8s"
@@ -15,6 +13,9 @@ import (
 	"time"
 )
 
+// kafkaUIPort is the port Kafka UI listens on in its pod
+const kafkaUIPort = 8080
+
 // MSKManager handles MSK Kafka UI operations
 type MSKManager struct {
 	kubeManager     *KubeManager
@@ -31,10 +32,16 @@ func NewMSKManagerWithDeps(km *KubeManager, ssm *SSMManager, ps *ProfileSwitcher
 	}
 }
 
-// StartUI deploys a Kafka UI pod and port-forwards to localhost
-func (mm *MSKManager) StartUI(env string, localPort int) error {
+// StartUI deploys a Kafka UI pod and port-forwards to localhost.
+// If localPort is busy the next free port is used, unless strictPort is set.
+func (mm *MSKManager) StartUI(env string, localPort int, strictPort bool) error {
 	env = strings.ToLower(env)
 
+	localPort, err := resolveLocalPort(localPort, strictPort)
+	if err != nil {
+		return err
+	}
+
 	// Switch kubectl context to the environment
 	fmt.Printf("Switching kubectl context to %s...\n", env)
 	if err := mm.kubeManager.SwitchContextForEnvWithProfile(env, mm.profileSwitcher); err != nil {
@@ -50,43 +57,36 @@ func (mm *MSKManager) StartUI(env string, localPort int) error {
 		return fmt.Errorf("failed to get MSK brokers: %w", err)
 	}
 
-	// Get username for pod name
-	username := utils.GetCurrentUsername()
-	if username == "unknown" {
-		username = "user"
-	}
-
-	podName := fmt.Sprintf("kafka-ui-%s-%s", env, username)
-
 	// Check if pod already exists
-	podMgr := k8s.NewPodManager("default")
-	if podMgr.PodExists(podName) {
-		fmt.Printf("Pod %s already exists, reusing...\n", podName)
+	pod := mm.kafkaUIPod(env, brokers)
+	if pod.Exists() {
+		fmt.Printf("Pod %s already exists, reusing...\n", pod.Name)
 	} else {
 		// Create the Kafka UI pod
-		fmt.Printf("Creating Kafka UI pod: %s\n", podName)
-		if err := mm.createKafkaUIPod(podName, env, brokers); err != nil {
+		fmt.Printf("Creating Kafka UI pod: %s\n", pod.Name)
+		if err := pod.Start(); err != nil {
 			return fmt.Errorf("failed to create Kafka UI pod: %w", err)
 		}
 
 		// Wait for pod to be ready
 		fmt.Println("Waiting for pod to be ready...")
-		if err := podMgr.WaitForPodReady(podName, 120*time.Second); err != nil {
+		if err := pod.WaitReady(120 * time.Second); err != nil {
 			// Cleanup on failure
-			podMgr.DeletePod(podName)
+			pod.Close()
 			return fmt.Errorf("pod failed to start: %w", err)
 		}
+		fmt.Println(utils.OK() + " Pod is running")
 	}
 
 	fmt.Printf("\nStarting Kafka UI port-forward:\n")
-	fmt.Printf("  Pod:       %s\n", podName)
-	fmt.Printf("  Namespace: default\n")
+	fmt.Printf("  Pod:       %s\n", pod.Name)
+	fmt.Printf("  Namespace: %s\n", pod.Namespace)
 	fmt.Printf("  Local:     http://localhost:%d\n", localPort)
 	fmt.Printf("  Brokers:   %s\n", utils.TruncateString(brokers, 60))
 	fmt.Printf("\nPress Ctrl+C to stop (pod will remain running)...")
 	fmt.Printf("To stop the pod later: rw msk stop %s\n\n", env)
 
-	return mm.startPortForward(podName, localPort)
+	return mm.startPortForward(pod, env, localPort)
 }
 
 // StopUI deletes the Kafka UI pod for an environment
@@ -99,58 +99,51 @@ func (mm *MSKManager) StopUI(env string) error {
 		return fmt.Errorf("failed to switch kubectl context: %w", err)
 	}
 
-	// Get username for pod name
-	username := utils.GetCurrentUsername()
-	if username == "unknown" {
-		username = "user"
+	pod := mm.kafkaUIPod(env, "")
+	if !pod.Exists() {
+		return fmt.Errorf("pod %s not found in namespace %s", pod.Name, pod.Namespace)
 	}
 
-	podName := fmt.Sprintf("kafka-ui-%s-%s", env, username)
-
-	podMgr := k8s.NewPodManager("default")
-	if !podMgr.PodExists(podName) {
-		return fmt.Errorf("pod %s not found in namespace default", podName)
-	}
-
-	fmt.Printf("Deleting Kafka UI pod: %s\n", podName)
-	if err := podMgr.DeletePod(podName); err != nil {
+	fmt.Printf("Deleting Kafka UI pod: %s\n", pod.Name)
+	if err := pod.Close(); err != nil {
 		return fmt.Errorf("failed to delete pod: %w", err)
 	}
 
-	fmt.Printf("✓ Kafka UI pod stopped: %s\n", podName)
+	fmt.Printf(utils.OK()+" Kafka UI pod stopped: %s\n", pod.Name)
 	return nil
 }
 
-// createKafkaUIPod creates the Kafka UI pod with IAM authentication
-func (mm *MSKManager) createKafkaUIPod(podName, env, brokers string) error {
+// kafkaUIPod describes an environment's Kafka UI pod with IAM
+// authentication. Its name is fixed per user and environment, and it's kept
+// running after the port-forward stops so the next 'rw msk ui' reuses it.
+func (mm *MSKManager) kafkaUIPod(env, brokers string) *k8s.PodSession {
 	cfg := config.Get()
-	labels := k8s.CreatorLabels()
-
-	cmd := exec.Command("kubectl", "run", podName,
-		"--restart=Never",
-		fmt.Sprintf("--image=%s", cfg.Images.KafkaUI),
-		"--labels", labels,
-		fmt.Sprintf("--env=KAFKA_CLUSTERS_0_NAME=%s", env),
-		fmt.Sprintf("--env=KAFKA_CLUSTERS_0_BOOTSTRAPSERVERS=%s", brokers),
-		"--env=KAFKA_CLUSTERS_0_PROPERTIES_SECURITY_PROTOCOL=SASL_SSL",
-		"--env=KAFKA_CLUSTERS_0_PROPERTIES_SASL_MECHANISM=AWS_MSK_IAM",
-		"--env=KAFKA_CLUSTERS_0_PROPERTIES_SASL_JAAS_CONFIG=software.amazon.msk.auth.iam.IAMLoginModule required;",
-		"--env=KAFKA_CLUSTERS_0_PROPERTIES_SASL_CLIENT_CALLBACK_HANDLER_CLASS=software.amazon.msk.auth.iam.IAMClientCallbackHandler",
-		"-n", "default",
-	)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("kubectl error: %s", stderr.String())
+
+	// Get username for pod name
+	username := utils.GetCurrentUsername()
+	if username == "unknown" {
+		username = "user"
 	}
 
-	return nil
+	return k8s.NewPodSession(k8s.PodSpec{
+		Name:      fmt.Sprintf("kafka-ui-%s-%s", env, username),
+		Image:     cfg.Images.KafkaUI,
+		Namespace: "default",
+		Port:      kafkaUIPort,
+		Keep:      true,
+		Env: map[string]string{
+			"KAFKA_CLUSTERS_0_NAME":                                          env,
+			"KAFKA_CLUSTERS_0_BOOTSTRAPSERVERS":                              brokers,
+			"KAFKA_CLUSTERS_0_PROPERTIES_SECURITY_PROTOCOL":                  "SASL_SSL",
+			"KAFKA_CLUSTERS_0_PROPERTIES_SASL_MECHANISM":                     "AWS_MSK_IAM",
+			"KAFKA_CLUSTERS_0_PROPERTIES_SASL_JAAS_CONFIG":                   "software.amazon.msk.auth.iam.IAMLoginModule required;",
+			"KAFKA_CLUSTERS_0_PROPERTIES_SASL_CLIENT_CALLBACK_HANDLER_CLASS": "software.amazon.msk.auth.iam.IAMClientCallbackHandler",
+		},
+	})
 }
 
 // startPortForward runs kubectl port-forward with interrupt handling
-func (mm *MSKManager) startPortForward(podName string, localPort int) error {
+func (mm *MSKManager) startPortForward(pod *k8s.PodSession, env string, localPort int) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -170,20 +163,11 @@ func (mm *MSKManager) startPortForward(podName string, localPort int) error {
 		}
 	}()
 
-	cmd := exec.CommandContext(ctx, "kubectl", "port-forward",
-		fmt.Sprintf("pod/%s", podName),
-		fmt.Sprintf("%d:8080", localPort),
-		"-n", "default",
-	)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	err := cmd.Run()
+	err := pod.PortForward(ctx, localPort, kafkaUIPort)
 
 	if ctx.Err() == context.Canceled {
-		fmt.Println("✓ Port-forward stopped")
-		fmt.Printf("  Pod %s is still running. Use 'rw msk stop %s' to delete it.\n", podName, strings.TrimPrefix(podName, "kafka-ui-"))
+		fmt.Println(utils.OK() + " Port-forward stopped")
+		fmt.Printf("  Pod %s is still running. Use 'rw msk stop %s' to delete it.\n", pod.Name, env)
 		return nil
 	}
 
@@ -243,7 +227,7 @@ echo "Use --command-config /tmp/client.properties with kafka-* commands"
 exec /bin/bash
 `, brokers)
 
-	return k8s.RunPod(k8s.PodSpec{
+	pod := k8s.NewPodSession(k8s.PodSpec{
 		NamePrefix:  "msk-cli",
 		Image:       cfg.Images.KafkaCLI,
 		Namespace:   TunnelAccessNamespace(),
@@ -253,4 +237,6 @@ exec /bin/bash
 			"BOOTSTRAP_SERVERS": brokers,
 		},
 	})
+	defer pod.Close()
+	return pod.Run()
 }
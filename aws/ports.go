@@ -3,6 +3,7 @@ package aws
 import (
 	"fmt"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 	"slices"
 	"strings"
 )
@@ -45,6 +46,65 @@ func (pc *PortConfig) GetPort(service, env string) ([]int, error) {
 	return nil, fmt.Errorf("port mapping not found for service: %s in environment: %s", service, env)
 }
 
+// DefaultPortName names a service's own port, as opposed to the named
+// ports of its companion services
+const DefaultPortName = "default"
+
+// NamedPort is one of a service's local ports in an environment
+type NamedPort struct {
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	Port    int    `json:"port"`
+}
+
+// GetNamedPorts returns every local port of a service in env: its own
+// port, named "default", and one per companion service named
+// <service>-<name>, such as db-command for the command database. The
+// default port comes first, then the rest by name.
+func (pc *PortConfig) GetNamedPorts(service, env string) ([]NamedPort, error) {
+	service = strings.ToLower(service)
+	env = strings.ToLower(env)
+
+	if pc.configRepo == nil {
+		return nil, fmt.Errorf("port mapping not found for service: %s in environment: %s", service, env)
+	}
+	services, err := pc.configRepo.GetAllServices()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = s.Name
+	}
+
+	var ports []NamedPort
+	for _, p := range servicePortNames(service, names) {
+		if pm, err := pc.configRepo.GetPortMapping(p.Service, env); err == nil {
+			p.Port = pm.LocalPort
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("port mapping not found for service: %s in environment: %s", service, env)
+	}
+	return ports, nil
+}
+
+// servicePortNames names the ports of service among the known services:
+// the default port first, then companion services by name
+func servicePortNames(service string, services []string) []NamedPort {
+	var ports, named []NamedPort
+	for _, s := range services {
+		if s == service {
+			ports = append(ports, NamedPort{Name: DefaultPortName, Service: s})
+		} else if name, ok := strings.CutPrefix(s, service+"-"); ok && name != "" {
+			named = append(named, NamedPort{Name: name, Service: s})
+		}
+	}
+	slices.SortFunc(named, func(a, b NamedPort) int { return strings.Compare(a.Name, b.Name) })
+	return append(ports, named...)
+}
+
 // GetServices returns all available services
 func (pc *PortConfig) GetServices() string {
 	if pc.configRepo != nil {
@@ -110,3 +170,29 @@ func (pc *PortConfig) ListAll() string {
 	sb.WriteString("Database not available. Please initialize the database.\n")
 	return sb.String()
 }
+
+// resolveLocalPort checks that a local port is free before a port-forward
+// binds it. When the port is busy it reports the owning process (if known)
+// and picks the next free port, unless strict is set.
+func resolveLocalPort(port int, strict bool) (int, error) {
+	if utils.IsPortAvailable(port) {
+		return port, nil
+	}
+
+	msg := fmt.Sprintf("local port %d is already in use", port)
+	if owner := utils.PortOwner(port); owner != "" {
+		msg += " by " + owner
+	}
+
+	if strict {
+		return 0, fmt.Errorf("%s (remove --strict-port to pick the next free port)", msg)
+	}
+
+	free, err := utils.FindFreePort(port+1, 100)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", msg, err)
+	}
+
+	fmt.Printf(utils.Warn()+" %s, using %d instead\n", msg, free)
+	return free, nil
+}
@@ -0,0 +1,26 @@
+package aws
+
+import "testing"
+
+func TestServicePortNames(t *testing.T) {
+	services := []string{"redis", "db-command", "db", "db-replica", "dbt", "grpc"}
+
+	ports := servicePortNames("db", services)
+	want := []NamedPort{
+		{Name: DefaultPortName, Service: "db"},
+		{Name: "command", Service: "db-command"},
+		{Name: "replica", Service: "db-replica"},
+	}
+	if len(ports) != len(want) {
+		t.Fatalf("servicePortNames(db) = %+v, want %+v", ports, want)
+	}
+	for i := range want {
+		if ports[i] != want[i] {
+			t.Errorf("servicePortNames(db)[%d] = %+v, want %+v", i, ports[i], want[i])
+		}
+	}
+
+	if ports := servicePortNames("redis", services); len(ports) != 1 || ports[0].Name != DefaultPortName {
+		t.Errorf("servicePortNames(redis) = %+v, want only the default port", ports)
+	}
+}
@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"slices"
+	"strings"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+)
+
+// ProductionEnvs returns every production environment: those listed in
+// production_envs in config.yaml and those flagged with 'rw env production'.
+// repo may be nil when the database is unavailable.
+func ProductionEnvs(repo *db.ConfigRepository) []string {
+	envs := slices.Clone(config.Get().ProductionEnvs)
+	if repo == nil {
+		return envs
+	}
+	flagged, err := repo.ProductionEnvironments()
+	if err != nil {
+		return envs
+	}
+	for _, name := range flagged {
+		if !slices.Contains(envs, name) {
+			envs = append(envs, name)
+		}
+	}
+	return envs
+}
+
+// IsProductionEnv reports whether env is one of ProductionEnvs(repo),
+// ignoring case
+func IsProductionEnv(repo *db.ConfigRepository, env string) bool {
+	return isProductionIn(ProductionEnvs(repo), env)
+}
+
+func isProductionIn(production []string, env string) bool {
+	return slices.ContainsFunc(production, func(p string) bool { return strings.EqualFold(p, env) })
+}
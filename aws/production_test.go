@@ -0,0 +1,41 @@
+package aws
+
+import (
+	"slices"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestProductionEnvs(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	if err := repo.AddEnvironment("perf", "Perf", "eu-west-2", "perf-admin", "perf-eks"); err != nil {
+		t.Fatalf("AddEnvironment() error: %v", err)
+	}
+	if err := repo.SetEnvironmentProduction("perf", true); err != nil {
+		t.Fatalf("SetEnvironmentProduction() error: %v", err)
+	}
+
+	// production_envs from the default config, plus the flagged environment
+	got := ProductionEnvs(repo)
+	if !slices.Contains(got, "prod") || !slices.Contains(got, "perf") {
+		t.Errorf("ProductionEnvs() = %v, want prod and perf", got)
+	}
+	for env, want := range map[string]bool{"prod": true, "PROD": true, "perf": true, "dev": false} {
+		if IsProductionEnv(repo, env) != want {
+			t.Errorf("IsProductionEnv(%q) = %v, want %v", env, !want, want)
+		}
+	}
+
+	// Without a database only production_envs applies
+	if IsProductionEnv(nil, "perf") || !IsProductionEnv(nil, "prod") {
+		t.Error("IsProductionEnv(nil) should only use production_envs")
+	}
+}
@@ -0,0 +1,196 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultProfileNameTemplate reproduces the historical naming scheme:
+// "Zenith Dev" + "AdministratorAccess" → "zenith-dev",
+// "Zenith Dev" + "ZenithDevReadOnlyAccess" → "zenith-dev-read-only".
+const DefaultProfileNameTemplate = "{account_name}-{role_short}"
+
+// invalidProfileChars matches anything not allowed in a generated profile name
+var invalidProfileChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// ProfileNamer generates profile names from a naming template and detects
+// collisions, so two different account/role pairs never share a profile name.
+//
+// Supported placeholders:
+//
+//	{account_name}  normalized account name ("Zenith (QA)" → "zenith-qa")
+//	{account_id}    12-digit AWS account ID
+//	{role_name}     role name in kebab-case
+//	{role_short}    role name without the account prefix and "Access" suffix;
+//	                empty for AdministratorAccess
+type ProfileNamer struct {
+	template string
+	owners   map[string]string // profile name -> "accountID/roleName"
+	assigned map[string]string // "accountID/roleName" -> profile name
+}
+
+// NewProfileNamer creates a ProfileNamer for the given template.
+// An empty template uses DefaultProfileNameTemplate.
+func NewProfileNamer(template string) *ProfileNamer {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultProfileNameTemplate
+	}
+	return &ProfileNamer{
+		template: template,
+		owners:   make(map[string]string),
+		assigned: make(map[string]string),
+	}
+}
+
+// Reserve records an existing profile name for an account/role pair, e.g.
+// one already stored in the database. Reserved pairs keep their name so that
+// re-running discovery never renames profiles users already rely on.
+func (pn *ProfileNamer) Reserve(profileName, accountID, roleName string) {
+	key := ownerKey(accountID, roleName)
+	if _, ok := pn.owners[profileName]; ok {
+		return
+	}
+	pn.owners[profileName] = key
+	if _, ok := pn.assigned[key]; !ok {
+		pn.assigned[key] = profileName
+	}
+}
+
+// Name returns the profile name for an account/role pair and reserves it.
+// If the rendered name is already owned by a different pair, the account ID
+// (and then a counter) is appended and collided is reported as true.
+func (pn *ProfileNamer) Name(accountID, accountName, roleName string) (name string, collided bool) {
+	key := ownerKey(accountID, roleName)
+	if existing, ok := pn.assigned[key]; ok {
+		return existing, false
+	}
+
+	base := RenderProfileName(pn.template, accountID, accountName, roleName)
+	name = base
+	for i := 1; ; i++ {
+		if _, taken := pn.owners[name]; !taken {
+			break
+		}
+		if i == 1 {
+			name = base + "-" + accountID
+		} else {
+			name = fmt.Sprintf("%s-%s-%d", base, accountID, i)
+		}
+	}
+
+	pn.owners[name] = key
+	pn.assigned[key] = name
+	return name, name != base
+}
+
+// RenderProfileName expands a naming template for an account/role pair and
+// normalizes the result to lowercase kebab-case.
+func RenderProfileName(template, accountID, accountName, roleName string) string {
+	r := strings.NewReplacer(
+		"{account_name}", normalizeAccountName(accountName),
+		"{account_id}", accountID,
+		"{role_name}", camelToKebab(roleName),
+		"{role_short}", roleShortName(accountName, roleName),
+	)
+
+	name := strings.ToLower(r.Replace(template))
+	name = invalidProfileChars.ReplaceAllString(name, "-")
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	name = strings.Trim(name, "-")
+
+	if name == "" {
+		return accountID + "-" + camelToKebab(roleName)
+	}
+	return name
+}
+
+// normalizeAccountName converts an account name into a profile-safe form:
+// "Zenith (QA)" → "zenith-qa", "Zenith Dev" → "zenith-dev"
+func normalizeAccountName(accountName string) string {
+	name := strings.ToLower(accountName)
+	name = strings.ReplaceAll(name, "(", "")
+	name = strings.ReplaceAll(name, ")", "")
+	name = strings.TrimSpace(name)
+	name = strings.ReplaceAll(name, " ", "-")
+	name = strings.ReplaceAll(name, "_", "-")
+	// Collapse multiple dashes
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	return strings.Trim(name, "-")
+}
+
+// roleShortName returns the role part of a profile name. The default admin
+// role maps to an empty string so its profile is just the account name.
+func roleShortName(accountName, roleName string) string {
+	roleLower := strings.ToLower(roleName)
+	if roleLower == "administratoraccess" || roleLower == "admin" {
+		return ""
+	}
+
+	// Strip the account-specific prefix from role names
+	// e.g. "ZenithDevRDSAdminAccess" → "rds-admin"
+	if suffix := cleanRoleSuffix(accountName, roleName); suffix != "" {
+		return suffix
+	}
+	return roleLower
+}
+
+// cleanRoleSuffix strips the account-specific prefix from a role name
+// and converts to kebab-case.
+func cleanRoleSuffix(accountName, roleName string) string {
+	// Build possible prefixes to strip: "ZenithDev", "ZenithQA", "Zenith", etc.
+	words := strings.Fields(accountName)
+	prefixes := []string{}
+
+	// Try full account name without spaces: "ZenithDev", "ZenithQA"
+	joined := ""
+	for _, w := range words {
+		// Skip parenthesized words for prefix building
+		w = strings.Trim(w, "()")
+		joined += w
+	}
+	prefixes = append(prefixes, joined)
+
+	// Try just the first word: "Zenith"
+	if len(words) > 0 {
+		prefixes = append(prefixes, words[0])
+	}
+
+	// Strip the prefix (case-insensitive)
+	role := roleName
+	for _, prefix := range prefixes {
+		if len(role) > len(prefix) && strings.EqualFold(role[:len(prefix)], prefix) {
+			role = role[len(prefix):]
+			break
+		}
+	}
+
+	// Strip common suffixes
+	role = strings.TrimSuffix(role, "Access")
+
+	// Convert CamelCase to kebab-case
+	result := camelToKebab(role)
+	result = strings.Trim(result, "-")
+
+	return result
+}
+
+// camelToKebab converts CamelCase to kebab-case.
+func camelToKebab(s string) string {
+	var result strings.Builder
+	for i, r := range s {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			result.WriteByte('-')
+		}
+		result.WriteRune(r)
+	}
+	return strings.ToLower(result.String())
+}
+
+func ownerKey(accountID, roleName string) string {
+	return accountID + "/" + roleName
+}
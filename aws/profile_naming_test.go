@@ -0,0 +1,63 @@
+package aws
+
+import "testing"
+
+func TestRenderProfileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		accountName string
+		roleName    string
+		expected    string
+	}{
+		{"admin role", DefaultProfileNameTemplate, "Zenith Dev", "AdministratorAccess", "zenith-dev"},
+		{"parenthesized account", DefaultProfileNameTemplate, "Zenith (QA)", "AdministratorAccess", "zenith-qa"},
+		{"prefixed role", DefaultProfileNameTemplate, "Zenith Dev", "ZenithDevReadOnlyAccess", "zenith-dev-read-only"},
+		{"account id", "{account_id}-{role_name}", "Zenith Dev", "ReadOnly", "123456789012-read-only"},
+		{"invalid chars", "team/{account_name}", "Zenith Dev", "AdministratorAccess", "team-zenith-dev"},
+		{"empty result", "{role_short}", "Zenith Dev", "AdministratorAccess", "123456789012-administrator-access"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RenderProfileName(tt.template, "123456789012", tt.accountName, tt.roleName)
+			if result != tt.expected {
+				t.Errorf("RenderProfileName(%q) = %q, want %q", tt.template, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProfileNamerCollisions(t *testing.T) {
+	namer := NewProfileNamer("{account_name}")
+
+	first, collided := namer.Name("111111111111", "Zenith Dev", "AdministratorAccess")
+	if first != "zenith-dev" || collided {
+		t.Errorf("first Name() = %q, %v; want %q, false", first, collided, "zenith-dev")
+	}
+
+	second, collided := namer.Name("111111111111", "Zenith Dev", "ReadOnly")
+	if second != "zenith-dev-111111111111" || !collided {
+		t.Errorf("second Name() = %q, %v; want %q, true", second, collided, "zenith-dev-111111111111")
+	}
+
+	third, collided := namer.Name("111111111111", "Zenith Dev", "Billing")
+	if third != "zenith-dev-111111111111-2" || !collided {
+		t.Errorf("third Name() = %q, %v; want %q, true", third, collided, "zenith-dev-111111111111-2")
+	}
+
+	again, _ := namer.Name("111111111111", "Zenith Dev", "ReadOnly")
+	if again != second {
+		t.Errorf("Name() for the same pair = %q, want stable %q", again, second)
+	}
+}
+
+func TestProfileNamerKeepsReservedNames(t *testing.T) {
+	namer := NewProfileNamer(DefaultProfileNameTemplate)
+	namer.Reserve("my-old-dev", "111111111111", "AdministratorAccess")
+
+	name, collided := namer.Name("111111111111", "Zenith Dev", "AdministratorAccess")
+	if name != "my-old-dev" || collided {
+		t.Errorf("Name() = %q, %v; want reserved %q, false", name, collided, "my-old-dev")
+	}
+}
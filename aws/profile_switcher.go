@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+
+	"rolewalkers/internal/utils"
 )
 
 // ProfileSwitcher handles switching between AWS profiles
@@ -35,7 +38,7 @@ func (ps *ProfileSwitcher) SwitchProfile(profileName string) error {
 
 	// Update the [default] section in config using shared helper
 	settings := ProfileSettings{Lines: ps.formatProfileSettings(targetProfile)}
-	if err := writeDefaultSection(ps.configManager.configPath, settings); err != nil {
+	if err := ps.configManager.writeDefaultSection(settings); err != nil {
 		return err
 	}
 
@@ -47,7 +50,7 @@ func (ps *ProfileSwitcher) SwitchProfile(profileName string) error {
 	// Set persistent environment variable (Windows User level, or export file for Unix)
 	if err := ps.setPersistentEnv(profileName, targetProfile.Region); err != nil {
 		// Non-fatal - just warn
-		fmt.Printf("⚠ Could not set persistent environment: %v\n", err)
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Could not set persistent environment: %v\n", err)
 	}
 
 	// Apply env vars and write env file using shared helper
@@ -58,6 +61,27 @@ func (ps *ProfileSwitcher) SwitchProfile(profileName string) error {
 	return nil
 }
 
+// ClearProfile empties the [default] section and removes the active
+// identity and env files, so no profile is active. It undoes a switch made
+// when none was active before.
+func (ps *ProfileSwitcher) ClearProfile() error {
+	if err := ps.configManager.writeDefaultSection(ProfileSettings{}); err != nil {
+		return err
+	}
+	os.Unsetenv("AWS_PROFILE")
+
+	dir, err := utils.RoleWalkersDir()
+	if err != nil {
+		return err
+	}
+	for _, name := range switchStateFiles {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 // setPersistentEnv clears AWS_PROFILE from User environment on Windows
 // so that AWS CLI uses the [default] profile from config file
 func (ps *ProfileSwitcher) setPersistentEnv(profileName, region string) error {
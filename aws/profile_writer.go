@@ -15,11 +15,14 @@ type ProfileSettings struct {
 
 // writeDefaultSection rewrites the [default] section in the AWS config file
 // with the given settings. If no [default] section exists, one is prepended.
+// An edit made by another tool since rw last wrote the file stays flagged
+// as an external change.
 func writeDefaultSection(configPath string, settings ProfileSettings) error {
 	content, err := os.ReadFile(configPath)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
+	changed := configChangedExternally(configPath)
 
 	lines := strings.Split(string(content), "\n")
 	var newLines []string
@@ -38,6 +41,7 @@ func writeDefaultSection(configPath string, settings ProfileSettings) error {
 				defaultWritten = true
 				newLines = append(newLines, "[default]")
 				newLines = append(newLines, settings.Lines...)
+				newLines = append(newLines, "")
 				continue
 			}
 		}
@@ -58,7 +62,21 @@ func writeDefaultSection(configPath string, settings ProfileSettings) error {
 		newLines = append(header, newLines...)
 	}
 
-	return os.WriteFile(configPath, []byte(strings.Join(newLines, "\n")), 0600)
+	updated := []byte(strings.Join(newLines, "\n"))
+	if err := utils.WriteFileAtomic(configPath, updated, 0600); err != nil {
+		return err
+	}
+	if changed {
+		return nil
+	}
+	return recordConfigWrite(configPath, updated)
+}
+
+// writeDefaultSection rewrites the [default] section of the managed config
+// file and drops the cached profiles.
+func (cm *ConfigManager) writeDefaultSection(settings ProfileSettings) error {
+	defer cm.Invalidate()
+	return writeDefaultSection(cm.configPath, settings)
 }
 
 // applyProfileEnv sets the standard AWS environment variables for the current
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -185,21 +186,24 @@ _rw_prompt_info() {
   _rw_k8s=""
   _rw_git=""
 
+  # kubectl context/namespace and the production flag, from the cache rw
+  # keeps, so rendering the prompt doesn't run kubectl
+  local rw_ctx k8s_ctx k8s_ns rw_prod
+  rw_ctx=$(rw context --format short --fields prod 2>/dev/null)
+  IFS='|' read -r _ _ k8s_ctx k8s_ns rw_prod <<< "$rw_ctx"
+
   # AWS profile
   local aws_profile="${AWS_PROFILE:-$(aws configure get profile 2>/dev/null)}"
   if [[ -n "$aws_profile" ]]; then
     _rw_aws="%%F{yellow}☁ ${aws_profile}%%f"
+    # Red when rw flags the active environment as production
+    if [[ "$rw_prod" == 1 ]]; then
+      _rw_aws="%%B%%F{red}☁ ${aws_profile} PROD%%f%%b"
+    fi
   fi
 
   # Kubernetes context/namespace
-  local k8s_ctx
-  k8s_ctx=$(kubectl config current-context 2>/dev/null)
   if [[ -n "$k8s_ctx" ]]; then
-    # Shorten ARN-style context names
-    k8s_ctx="${k8s_ctx##*/}"
-    local k8s_ns
-    k8s_ns=$(kubectl config view --minify --output 'jsonpath={..namespace}' 2>/dev/null)
-    k8s_ns="${k8s_ns:-default}"
     _rw_k8s="%%F{magenta}⎈ ${k8s_ctx}/${k8s_ns}%%f"
   fi
 
@@ -245,20 +249,24 @@ _rw_prompt_info() {
   _rw_k8s=""
   _rw_git=""
 
+  # kubectl context/namespace and the production flag, from the cache rw
+  # keeps, so rendering the prompt doesn't run kubectl
+  local rw_ctx k8s_ctx k8s_ns rw_prod
+  rw_ctx=$(rw context --format short --fields prod 2>/dev/null)
+  IFS='|' read -r _ _ k8s_ctx k8s_ns rw_prod <<< "$rw_ctx"
+
   # AWS profile
   local aws_profile="${AWS_PROFILE:-$(aws configure get profile 2>/dev/null)}"
   if [[ -n "$aws_profile" ]]; then
     _rw_aws="\[\e[33m\]☁ ${aws_profile}\[\e[0m\]"
+    # Red when rw flags the active environment as production
+    if [[ "$rw_prod" == 1 ]]; then
+      _rw_aws="\[\e[1;31m\]☁ ${aws_profile} PROD\[\e[0m\]"
+    fi
   fi
 
   # Kubernetes context/namespace
-  local k8s_ctx
-  k8s_ctx=$(kubectl config current-context 2>/dev/null)
   if [[ -n "$k8s_ctx" ]]; then
-    k8s_ctx="${k8s_ctx##*/}"
-    local k8s_ns
-    k8s_ns=$(kubectl config view --minify --output 'jsonpath={..namespace}' 2>/dev/null)
-    k8s_ns="${k8s_ns:-default}"
     _rw_k8s="\[\e[35m\]⎈ ${k8s_ctx}/${k8s_ns}\[\e[0m\]"
   fi
 
@@ -286,9 +294,9 @@ func (pm *PromptManager) generatePowerShellPrompt(components []PromptComponent)
 		case PromptFolder:
 			parts = append(parts, `Write-Host (Split-Path -Leaf (Get-Location)) -ForegroundColor Blue -NoNewline`)
 		case PromptAWS:
-			parts = append(parts, `$awsProfile = $env:AWS_PROFILE; if ($awsProfile) { Write-Host "☁ $awsProfile" -ForegroundColor Yellow -NoNewline }`)
+			parts = append(parts, `$awsProfile = $env:AWS_PROFILE; if ($awsProfile) { if ($rwCtx.Count -ge 5 -and $rwCtx[4] -eq '1') { Write-Host "☁ $awsProfile PROD" -ForegroundColor Red -NoNewline } else { Write-Host "☁ $awsProfile" -ForegroundColor Yellow -NoNewline } }`)
 		case PromptK8s:
-			parts = append(parts, `$k8sCtx = kubectl config current-context 2>$null; if ($k8sCtx) { $k8sCtx = ($k8sCtx -split '/')[-1]; $k8sNs = kubectl config view --minify -o 'jsonpath={..namespace}' 2>$null; if (-not $k8sNs) { $k8sNs = "default" }; Write-Host "⎈ $k8sCtx/$k8sNs" -ForegroundColor Magenta -NoNewline }`)
+			parts = append(parts, `if ($rwCtx.Count -ge 4 -and $rwCtx[2]) { Write-Host "⎈ $($rwCtx[2])/$($rwCtx[3])" -ForegroundColor Magenta -NoNewline }`)
 		case PromptGit:
 			parts = append(parts, `$gitBranch = git symbolic-ref --short HEAD 2>$null; if ($gitBranch) { Write-Host " $gitBranch" -ForegroundColor Green -NoNewline }`)
 		}
@@ -307,6 +315,8 @@ func (pm *PromptManager) generatePowerShellPrompt(components []PromptComponent)
 %s
 # Shell prompt managed by rw - do not edit manually
 function prompt {
+    # kubectl context/namespace and the production flag, cached by rw
+    $rwCtx = (rw context --format short --fields prod 2>$null) -split '\|'
     Write-Host ""
 %s    Write-Host ""
     Write-Host "❯ " -NoNewline -ForegroundColor White
@@ -315,3 +325,70 @@ function prompt {
 %s
 `, promptBlockStart, body, promptBlockEnd)
 }
+
+// PromptShells are the shells 'rw set prompt' can install into
+func PromptShells() []string {
+	return []string{"zsh", "bash", "powershell"}
+}
+
+// ParsePromptComponents validates component names; none means all
+func ParsePromptComponents(names []string) ([]PromptComponent, error) {
+	if len(names) == 0 {
+		return AllPromptComponents(), nil
+	}
+	var components []PromptComponent
+	for _, name := range names {
+		comp := PromptComponent(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(AllPromptComponents(), comp) {
+			return nil, fmt.Errorf("unknown prompt component: %s\nAvailable: time, folder, aws, k8s, git", name)
+		}
+		components = append(components, comp)
+	}
+	return components, nil
+}
+
+// PromptStatus describes the rw prompt in one shell's profile
+type PromptStatus struct {
+	Shell       string `json:"shell"`
+	ProfilePath string `json:"profile_path"`
+	// Installed is set when the profile contains an rw prompt block
+	Installed bool `json:"installed"`
+	// Recorded is set when the database has the shell's components
+	Recorded   bool              `json:"recorded"`
+	Components []PromptComponent `json:"components,omitempty"`
+	// Current is set when the installed block is what this version of rw
+	// generates for the recorded components
+	Current bool `json:"current"`
+}
+
+// Status compares a shell's profile with the components recorded for it
+// (nil when none are recorded)
+func (pm *PromptManager) Status(shell string, recorded []PromptComponent) (*PromptStatus, error) {
+	profilePath, err := pm.GetShellProfilePath(shell)
+	if err != nil {
+		return nil, err
+	}
+	status := &PromptStatus{Shell: shell, ProfilePath: profilePath, Recorded: recorded != nil, Components: recorded}
+
+	content, err := os.ReadFile(profilePath)
+	if err != nil {
+		return status, nil
+	}
+	block, ok := pm.findPromptBlock(string(content))
+	status.Installed = ok
+	status.Current = ok && recorded != nil && block == strings.TrimSpace(pm.generatePromptBlock(shell, recorded))
+	return status, nil
+}
+
+// findPromptBlock returns the rw prompt block in a profile, markers included
+func (pm *PromptManager) findPromptBlock(content string) (string, bool) {
+	startIdx := strings.Index(content, promptBlockStart)
+	if startIdx == -1 {
+		return "", false
+	}
+	endIdx := strings.Index(content[startIdx:], promptBlockEnd)
+	if endIdx == -1 {
+		return "", false
+	}
+	return content[startIdx : startIdx+endIdx+len(promptBlockEnd)], true
+}
@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPromptStatus(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm := NewPromptManager()
+	components := []PromptComponent{PromptAWS, PromptGit}
+
+	status, err := pm.Status("zsh", components)
+	if err != nil {
+		t.Fatalf("Status() error: %v", err)
+	}
+	if status.Installed || !status.Recorded || status.Current {
+		t.Errorf("Status() with no profile = %+v, want recorded but not installed", status)
+	}
+
+	if err := pm.InstallPrompt("zsh", components); err != nil {
+		t.Fatalf("InstallPrompt() error: %v", err)
+	}
+	if status, _ = pm.Status("zsh", components); !status.Installed || !status.Current {
+		t.Errorf("Status() after install = %+v, want installed and current", status)
+	}
+	if status, _ = pm.Status("zsh", []PromptComponent{PromptTime}); !status.Installed || status.Current {
+		t.Errorf("Status() with other components = %+v, want installed but not current", status)
+	}
+	if status, _ = pm.Status("zsh", nil); !status.Installed || status.Recorded || status.Current {
+		t.Errorf("Status() unrecorded = %+v, want installed and untracked", status)
+	}
+
+	content, _ := os.ReadFile(status.ProfilePath)
+	if err := os.WriteFile(status.ProfilePath, append([]byte("export A=1\n"), content...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ = pm.Status("zsh", components); !status.Current {
+		t.Errorf("Status() with other profile lines = %+v, want current", status)
+	}
+}
+
+func TestParsePromptComponents(t *testing.T) {
+	all, err := ParsePromptComponents(nil)
+	if err != nil || len(all) != len(AllPromptComponents()) {
+		t.Errorf("ParsePromptComponents(nil) = %v, %v, want all components", all, err)
+	}
+	if got, err := ParsePromptComponents([]string{"AWS", "git"}); err != nil || len(got) != 2 || got[0] != PromptAWS {
+		t.Errorf("ParsePromptComponents() = %v, %v, want aws, git", got, err)
+	}
+	if _, err := ParsePromptComponents([]string{"weather"}); err == nil {
+		t.Error("ParsePromptComponents() should reject unknown components")
+	}
+}
@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"rolewalkers/provider"
+)
+
+// ProviderName qualifies AWS profiles in the provider registry
+const ProviderName = "aws"
+
+// CloudProvider adapts the AWS config, switcher and SSO managers to the
+// provider.Provider interface
+type CloudProvider struct {
+	configManager   *ConfigManager
+	profileSwitcher *ProfileSwitcher
+	ssoManager      *SSOManager
+}
+
+var _ provider.Provider = (*CloudProvider)(nil)
+
+// NewCloudProvider creates the AWS provider from the shared managers
+func NewCloudProvider(cm *ConfigManager, ps *ProfileSwitcher, sm *SSOManager) *CloudProvider {
+	return &CloudProvider{configManager: cm, profileSwitcher: ps, ssoManager: sm}
+}
+
+// Name returns "aws"
+func (p *CloudProvider) Name() string { return ProviderName }
+
+// DisplayName returns "AWS"
+func (p *CloudProvider) DisplayName() string { return "AWS" }
+
+// Available reports whether ~/.aws/config exists or the AWS CLI is installed
+func (p *CloudProvider) Available() bool {
+	if _, err := os.Stat(p.configManager.configPath); err == nil {
+		return true
+	}
+	_, err := exec.LookPath("aws")
+	return err == nil
+}
+
+// Profiles returns the AWS profiles from ~/.aws/config
+func (p *CloudProvider) Profiles() ([]provider.Profile, error) {
+	profiles, err := p.configManager.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]provider.Profile, 0, len(profiles))
+	for _, prof := range profiles {
+		result = append(result, provider.Profile{
+			Provider: ProviderName,
+			Name:     prof.Name,
+			Account:  prof.SSOAccountID,
+			Identity: prof.SSORoleName,
+			Region:   prof.Region,
+			Active:   prof.IsActive,
+		})
+	}
+	return result, nil
+}
+
+// ActiveProfile returns the active AWS profile
+func (p *CloudProvider) ActiveProfile() string {
+	return p.configManager.GetActiveProfile()
+}
+
+// Login runs SSO login for the profile
+func (p *CloudProvider) Login(profile string) error {
+	return p.ssoManager.Login(profile)
+}
+
+// Switch makes profile the default AWS profile. Kubectl contexts are not
+// changed; 'rw switch' does both together.
+func (p *CloudProvider) Switch(profile string) error {
+	return p.profileSwitcher.SwitchProfile(profile)
+}
+
+// ExportEnvironment returns AWS_PROFILE and region variables for profile
+func (p *CloudProvider) ExportEnvironment(profile string) (map[string]string, error) {
+	return p.profileSwitcher.ExportEnvironment(profile)
+}
+
+// FormatShellExports renders environment variables as export statements
+// for the given shell (bash/zsh, powershell or cmd), sorted by name
+func FormatShellExports(shell string, env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(shellExportVar(shell, k, env[k]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,162 @@
+package aws
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+)
+
+// Operations limited by quotas (see config.QuotaConfig). Each run is
+// recorded in the audit log under the operation's name, which is what the
+// quotas count.
+const (
+	QuotaTunnelWrite = "tunnel_write"
+	QuotaDBRestore   = "db_restore"
+)
+
+// AuditActionQuotaOverride records the reason given for exceeding a quota
+const AuditActionQuotaOverride = "quota_override"
+
+// quotaOnExceedReason makes an exceeded quota require a reason
+const quotaOnExceedReason = "reason"
+
+// QuotaStatus is a quota and how often its operation already ran today
+type QuotaStatus struct {
+	Quota config.QuotaConfig
+	Used  int
+}
+
+// RequiresReason reports whether exceeding the quota needs a reason
+func (s *QuotaStatus) RequiresReason() bool {
+	return s.Quota.OnExceed == quotaOnExceedReason
+}
+
+// String describes the exceeded quota, e.g. "3 tunnel_write runs on prod today (quota: 2)"
+func (s *QuotaStatus) String() string {
+	return fmt.Sprintf("%d %s runs on %s today (quota: %d)", s.Used, s.Quota.Operation, s.Quota.Environment, s.Quota.PerDay)
+}
+
+// QuotaExceededError refuses a run that exceeds a quota requiring a
+// reason when none was given
+type QuotaExceededError struct {
+	Status *QuotaStatus
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s; a reason is required to exceed it", e.Status)
+}
+
+// QuotaManager checks and records runs of quota-limited operations
+type QuotaManager struct {
+	configRepo *db.ConfigRepository
+	quotas     func() []config.QuotaConfig
+	isProd     func(env string) bool
+	now        func() time.Time
+}
+
+// NewQuotaManager creates a quota manager using the quotas in config.yaml
+func NewQuotaManager(repo *db.ConfigRepository) *QuotaManager {
+	return &QuotaManager{
+		configRepo: repo,
+		quotas:     func() []config.QuotaConfig { return config.Get().Quotas },
+		isProd:     func(env string) bool { return IsProductionEnv(repo, env) },
+		now:        time.Now,
+	}
+}
+
+// applies reports whether a quota covers an operation on env and service.
+// Quotas without an environment cover every production environment.
+func (qm *QuotaManager) applies(q config.QuotaConfig, operation, env, service string) bool {
+	if q.Operation != operation || q.PerDay <= 0 {
+		return false
+	}
+	if q.Environment == "" && !qm.isProd(env) || q.Environment != "" && q.Environment != env {
+		return false
+	}
+	return q.Service == "" || q.Service == service
+}
+
+// Check returns the quota that running the operation now would exceed, or
+// nil when there is none. When several are exceeded, one that requires a
+// reason wins. Without a database there is no usage to count.
+func (qm *QuotaManager) Check(operation, env, service string) (*QuotaStatus, error) {
+	if qm.configRepo == nil {
+		return nil, nil
+	}
+
+	now := qm.now()
+	year, month, day := now.Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	var exceeded *QuotaStatus
+	for _, q := range qm.quotas() {
+		if !qm.applies(q, operation, env, service) {
+			continue
+		}
+		used, err := qm.configRepo.CountAuditEntries(operation, env, q.Service, startOfDay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count today's %s runs: %w", operation, err)
+		}
+		if used < q.PerDay {
+			continue
+		}
+		q.Environment = env
+		status := &QuotaStatus{Quota: q, Used: used}
+		if exceeded == nil || status.RequiresReason() && !exceeded.RequiresReason() {
+			exceeded = status
+		}
+	}
+	return exceeded, nil
+}
+
+// Record counts a run of the operation toward its quotas. target is the
+// service for tunnels and the backup source for restores. When the run
+// exceeds a quota, the reason given for it is recorded alongside it.
+func (qm *QuotaManager) Record(operation, env, target string, exceeded *QuotaStatus, reason string) {
+	var msg messages.Message
+	switch operation {
+	case QuotaTunnelWrite:
+		msg = messages.New(messages.AuditTunnelWrite, "service", target, "env", env)
+	case QuotaDBRestore:
+		msg = messages.New(messages.AuditDatabaseRestore, "env", env, "source", target)
+	}
+	recordAudit(qm.configRepo, operation, env, target, nil, nil, msg)
+
+	if exceeded == nil || reason == "" {
+		return
+	}
+	override := map[string]interface{}{
+		"operation": operation,
+		"used":      exceeded.Used,
+		"per_day":   exceeded.Quota.PerDay,
+		"reason":    reason,
+	}
+	recordAudit(qm.configRepo, AuditActionQuotaOverride, env, target, nil, override,
+		messages.New(messages.AuditQuotaOverride,
+			"operation", operation, "env", env,
+			"used", strconv.Itoa(exceeded.Used+1), "limit", strconv.Itoa(exceeded.Quota.PerDay),
+			"reason", reason))
+}
+
+// Enforce checks a run of the operation against its quotas and records it.
+// A run that exceeds a quota requiring a reason is refused with a
+// *QuotaExceededError when reason is empty; otherwise the exceeded quota,
+// if any, is returned.
+func (qm *QuotaManager) Enforce(operation, env, service, target, reason string) (*QuotaStatus, error) {
+	if qm == nil {
+		return nil, nil
+	}
+	exceeded, err := qm.Check(operation, env, service)
+	if err != nil {
+		return nil, err
+	}
+	if exceeded != nil && exceeded.RequiresReason() && reason == "" {
+		return exceeded, &QuotaExceededError{Status: exceeded}
+	}
+	qm.Record(operation, env, target, exceeded, reason)
+	return exceeded, nil
+}
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+)
+
+func newQuotaTestManager(t *testing.T, quotas ...config.QuotaConfig) *QuotaManager {
+	t.Helper()
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	return &QuotaManager{
+		configRepo: db.NewConfigRepository(database),
+		quotas:     func() []config.QuotaConfig { return quotas },
+		isProd:     func(env string) bool { return env == "prod" },
+		now:        time.Now,
+	}
+}
+
+func TestQuotaCheck(t *testing.T) {
+	qm := newQuotaTestManager(t,
+		config.QuotaConfig{Operation: QuotaTunnelWrite, PerDay: 2},
+		config.QuotaConfig{Operation: QuotaTunnelWrite, Service: "db", PerDay: 1, OnExceed: "reason"},
+		config.QuotaConfig{Operation: QuotaDBRestore, Environment: "staging", PerDay: 1},
+	)
+
+	if q, err := qm.Check(QuotaTunnelWrite, "prod", "db"); err != nil || q != nil {
+		t.Fatalf("Check() before any runs = %v, %v; want nil", q, err)
+	}
+
+	qm.Record(QuotaTunnelWrite, "prod", "db", nil, "")
+	q, err := qm.Check(QuotaTunnelWrite, "prod", "db")
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if q == nil || !q.RequiresReason() || q.Used != 1 {
+		t.Fatalf("Check() = %+v, want the db quota requiring a reason", q)
+	}
+	if q, _ := qm.Check(QuotaTunnelWrite, "prod", "redis"); q != nil {
+		t.Errorf("Check() for redis = %+v, want nil (1 of 2 used)", q)
+	}
+
+	qm.Record(QuotaTunnelWrite, "prod", "db", q, "incident 42")
+	q, _ = qm.Check(QuotaTunnelWrite, "prod", "redis")
+	if q == nil || q.RequiresReason() || q.Used != 2 {
+		t.Errorf("Check() for redis = %+v, want the warn-only quota with 2 used", q)
+	}
+	if q, _ := qm.Check(QuotaTunnelWrite, "dev", "db"); q != nil {
+		t.Errorf("Check() outside production = %+v, want nil", q)
+	}
+
+	entry, err := qm.configRepo.GetLastAuditEntry(AuditActionQuotaOverride)
+	if err != nil {
+		t.Fatalf("GetLastAuditEntry() error: %v", err)
+	}
+	if got, want := AuditDescription(entry), "Exceeded the tunnel_write quota on prod (2/1 today): incident 42"; got != want {
+		t.Errorf("override description = %q, want %q", got, want)
+	}
+
+	qm.Record(QuotaDBRestore, "staging", "backup.sql", nil, "")
+	if q, _ := qm.Check(QuotaDBRestore, "staging", ""); q == nil {
+		t.Error("Check() for a second staging restore = nil, want the staging quota")
+	}
+}
+
+func TestQuotaEnforce(t *testing.T) {
+	qm := newQuotaTestManager(t, config.QuotaConfig{Operation: QuotaTunnelWrite, PerDay: 1, OnExceed: "reason"})
+
+	if q, err := qm.Enforce(QuotaTunnelWrite, "prod", "db", "db", ""); err != nil || q != nil {
+		t.Fatalf("Enforce() within the quota = %v, %v; want nil", q, err)
+	}
+	q, err := qm.Enforce(QuotaTunnelWrite, "prod", "db", "db", "")
+	var exceeded *QuotaExceededError
+	if !errors.As(err, &exceeded) || q == nil {
+		t.Fatalf("Enforce() over the quota without a reason = %v, %v; want a QuotaExceededError", q, err)
+	}
+	if q, _ := qm.Check(QuotaTunnelWrite, "prod", "db"); q == nil || q.Used != 1 {
+		t.Errorf("Check() after a refused run = %+v, want the refused run not counted", q)
+	}
+	if _, err := qm.Enforce(QuotaTunnelWrite, "prod", "db", "db", "incident 42"); err != nil {
+		t.Errorf("Enforce() with a reason error: %v", err)
+	}
+
+	var none *QuotaManager
+	if q, err := none.Enforce(QuotaTunnelWrite, "prod", "db", "db", ""); err != nil || q != nil {
+		t.Errorf("nil Enforce() = %v, %v; want nil", q, err)
+	}
+}
@@ -81,11 +81,13 @@ func (rm *RedisManager) runRedisPod(host, password string) error {
 	fmt.Println()
 
 	port := fmt.Sprintf("%d", cfg.Database.RedisPort)
-	return k8s.RunPod(k8s.PodSpec{
+	pod := k8s.NewPodSession(k8s.PodSpec{
 		NamePrefix:  "redis-temp",
 		Image:       cfg.Images.Redis,
 		Interactive: true,
 		Command:     []string{"redis-cli", "-h", host, "-p", port, "-c", "--tls", "--user", cfg.Database.RedisUser},
 		Env:         map[string]string{"REDISCLI_AUTH": password},
 	})
+	defer pod.Close()
+	return pod.Run()
 }
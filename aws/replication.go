@@ -5,15 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"rolewalkers/internal/awsarn"
 	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 )
 
-// ReplicationManager handles RDS Blue-Green deployment operations
+// ReplicationManager handles RDS Blue-Green deployment operations in each
+// environment's region
 type ReplicationManager struct {
-	region     string
+	region     string // used when no environment is given or it has none
 	configRepo *db.ConfigRepository
 }
 
@@ -42,10 +48,26 @@ type BlueGreenDeploymentsResponse struct {
 	BlueGreenDeployments []BlueGreenDeployment `json:"BlueGreenDeployments"`
 }
 
+// DBCluster is an RDS/Aurora cluster that can be a Blue-Green source
+type DBCluster struct {
+	Identifier string    `json:"DBClusterIdentifier"`
+	ARN        string    `json:"DBClusterArn"`
+	Engine     string    `json:"Engine"`
+	Status     string    `json:"Status"`
+	CreateTime time.Time `json:"ClusterCreateTime"`
+	TagList    []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	} `json:"TagList"`
+}
+
+// envTagKeys are the cluster tags checked for an environment name
+var envTagKeys = []string{"environment", "env", "stage"}
+
 // NewReplicationManager creates a new ReplicationManager instance
 func NewReplicationManager() *ReplicationManager {
 	return &ReplicationManager{
-		region:     "eu-west-2",
+		region:     config.Get().Region,
 		configRepo: nil,
 	}
 }
@@ -53,11 +75,22 @@ func NewReplicationManager() *ReplicationManager {
 // NewReplicationManagerWithRepo creates a new ReplicationManager with a shared config repository
 func NewReplicationManagerWithRepo(repo *db.ConfigRepository) *ReplicationManager {
 	return &ReplicationManager{
-		region:     "eu-west-2",
+		region:     config.Get().Region,
 		configRepo: repo,
 	}
 }
 
+// regionFor returns the environment's region from the database, or the
+// default region
+func (rm *ReplicationManager) regionFor(env string) string {
+	if rm.configRepo != nil && env != "" {
+		if e, err := rm.configRepo.GetEnvironment(env); err == nil && e.Region != "" {
+			return e.Region
+		}
+	}
+	return rm.region
+}
+
 // ValidEnvironments returns the list of valid environments
 func (rm *ReplicationManager) ValidEnvironments() []string {
 	if rm.configRepo != nil {
@@ -101,7 +134,7 @@ func (rm *ReplicationManager) Status(env string) (string, error) {
 		}
 		fmt.Fprintf(&sb, "  Source:      %s\n", rm.extractClusterName(d.Source))
 		fmt.Fprintf(&sb, "  Target:      %s\n", rm.extractClusterName(d.Target))
-		fmt.Fprintf(&sb, "  Created:     %s\n", d.CreateTime.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(&sb, "  Created:     %s\n", utils.FormatTimeRelative(d.CreateTime, time.Now()))
 
 		if len(d.Tasks) > 0 {
 			sb.WriteString("  Tasks:\n")
@@ -114,14 +147,22 @@ func (rm *ReplicationManager) Status(env string) (string, error) {
 	return sb.String(), nil
 }
 
-// Switch performs a switchover of a Blue-Green deployment
+// Switch performs a switchover of a Blue-Green deployment, tracked as a job.
+// env picks the region; empty uses the default region.
 func (rm *ReplicationManager) Switch(env, deploymentID string) error {
-	if !rm.isValidEnv(env) {
+	return runJob(rm.configRepo, db.JobSwitchover, env, deploymentID, func(job *Job) error {
+		return rm.switchover(env, deploymentID, job)
+	})
+}
+
+func (rm *ReplicationManager) switchover(env, deploymentID string, job *Job) error {
+	if env != "" && !rm.isValidEnv(env) {
 		return fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(rm.ValidEnvironments(), ", "))
 	}
+	region := rm.regionFor(env)
 
 	// Get deployment to verify it exists and is in correct state
-	deployment, err := rm.getDeployment(deploymentID)
+	deployment, err := rm.getDeployment(region, deploymentID)
 	if err != nil {
 		return fmt.Errorf("failed to get deployment: %w", err)
 	}
@@ -141,27 +182,28 @@ func (rm *ReplicationManager) Switch(env, deploymentID string) error {
 	fmt.Println()
 
 	// Execute switchover
+	job.Progress("Starting switchover")
 	cmd := awscli.CreateCommand("rds", "switchover-blue-green-deployment",
 		"--blue-green-deployment-identifier", deploymentID,
-		"--region", rm.region,
+		"--region", region,
 	)
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("switchover failed: %s", stderr.String())
+		return fmt.Errorf("switchover failed: %s", utils.Redact(stderr.String()))
 	}
 
-	fmt.Println("✓ Switchover initiated successfully")
+	fmt.Println(utils.OK() + " Switchover initiated successfully")
 	fmt.Println("\nMonitoring progress...")
 
 	// Monitor progress
-	return rm.monitorSwitchover(deploymentID)
+	return rm.monitorSwitchover(region, deploymentID, job)
 }
 
 // monitorSwitchover monitors the switchover progress until completion
-func (rm *ReplicationManager) monitorSwitchover(deploymentID string) error {
+func (rm *ReplicationManager) monitorSwitchover(region, deploymentID string, job *Job) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 
@@ -175,37 +217,54 @@ func (rm *ReplicationManager) monitorSwitchover(deploymentID string) error {
 		case <-ctx.Done():
 			return fmt.Errorf("switchover timed out after 30 minutes")
 		case <-ticker.C:
-			deployment, err := rm.getDeployment(deploymentID)
+			deployment, err := rm.getDeployment(region, deploymentID)
 			if err != nil {
-				fmt.Printf("  ⚠ Error checking status: %v\n", err)
+				fmt.Fprintf(utils.Stdout, "  "+utils.Warn()+" Error checking status: %v\n", err)
 				continue
 			}
 
 			if deployment == nil {
 				// Deployment may have been deleted after successful switchover
-				fmt.Println("\n✓ Switchover completed - deployment cleaned up")
+				fmt.Println("\n" + utils.OK() + " Switchover completed - deployment cleaned up")
 				return nil
 			}
 
 			if deployment.Status != lastStatus {
 				lastStatus = deployment.Status
 				fmt.Printf("  Status: %s\n", rm.formatStatus(deployment.Status))
+				job.Progress("Deployment status: " + deployment.Status)
 			}
 
 			switch deployment.Status {
 			case "SWITCHOVER_COMPLETED":
-				fmt.Println("\n✓ Switchover completed successfully!")
+				fmt.Println("\n" + utils.OK() + " Switchover completed successfully!")
 				return nil
 			case "SWITCHOVER_FAILED":
 				return fmt.Errorf("switchover failed: %s", deployment.StatusDetails)
 			case "DELETING", "DELETED":
-				fmt.Println("\n✓ Switchover completed - deployment being cleaned up")
+				fmt.Println("\n" + utils.OK() + " Switchover completed - deployment being cleaned up")
 				return nil
 			}
 		}
 	}
 }
 
+// clusterARN builds the ARN of an RDS cluster in the environment's region.
+// The partition and account come from the environment's AWS account when
+// one is recorded, else the partition is that of the region.
+func (rm *ReplicationManager) clusterARN(env, cluster string) string {
+	arn := awsarn.ARN{Service: "rds", Region: rm.regionFor(env), Resource: "cluster:" + cluster}
+	if rm.configRepo != nil {
+		if accountID, err := rm.configRepo.GetEnvironmentAccount(env); err == nil && accountID != "" {
+			arn.Account = accountID
+			if account, err := rm.configRepo.GetAWSAccount(accountID); err == nil {
+				arn.Partition = account.Partition
+			}
+		}
+	}
+	return arn.String()
+}
+
 // Create creates a new Blue-Green deployment
 func (rm *ReplicationManager) Create(env, name, sourceCluster string) error {
 	if !rm.isValidEnv(env) {
@@ -224,7 +283,7 @@ func (rm *ReplicationManager) Create(env, name, sourceCluster string) error {
 	sourceARN := sourceCluster
 	if !strings.HasPrefix(sourceCluster, "arn:") {
 		// Assume it's a cluster identifier, build the ARN
-		sourceARN = fmt.Sprintf("arn:aws:rds:%s::cluster:%s", rm.region, sourceCluster)
+		sourceARN = rm.clusterARN(env, sourceCluster)
 	}
 
 	fmt.Printf("Creating Blue-Green deployment:\n")
@@ -235,7 +294,7 @@ func (rm *ReplicationManager) Create(env, name, sourceCluster string) error {
 	cmd := awscli.CreateCommand("rds", "create-blue-green-deployment",
 		"--blue-green-deployment-name", name,
 		"--source", sourceARN,
-		"--region", rm.region,
+		"--region", rm.regionFor(env),
 	)
 
 	var stdout, stderr bytes.Buffer
@@ -243,7 +302,7 @@ func (rm *ReplicationManager) Create(env, name, sourceCluster string) error {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create deployment: %s", stderr.String())
+		return fmt.Errorf("failed to create deployment: %s", utils.Redact(stderr.String()))
 	}
 
 	// Parse response to get deployment ID
@@ -251,11 +310,11 @@ func (rm *ReplicationManager) Create(env, name, sourceCluster string) error {
 		BlueGreenDeployment BlueGreenDeployment `json:"BlueGreenDeployment"`
 	}
 	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
-		fmt.Println("✓ Deployment creation initiated")
+		fmt.Println(utils.OK() + " Deployment creation initiated")
 		return nil
 	}
 
-	fmt.Printf("✓ Deployment created successfully!\n")
+	fmt.Print(utils.OK() + " Deployment created successfully!\n")
 	fmt.Printf("  Identifier: %s\n", response.BlueGreenDeployment.Identifier)
 	fmt.Printf("  Status:     %s\n", rm.formatStatus(response.BlueGreenDeployment.Status))
 	fmt.Println("\nUse 'rw replication status' to monitor progress")
@@ -263,14 +322,73 @@ func (rm *ReplicationManager) Create(env, name, sourceCluster string) error {
 	return nil
 }
 
-// Delete deletes a Blue-Green deployment
-func (rm *ReplicationManager) Delete(deploymentID string, deleteTarget bool) error {
+// DiscoverClusters returns the DB clusters belonging to an environment,
+// most recently created first. A cluster belongs to the environment when
+// an Environment/Env/Stage tag or a '-'/'_' separated part of its
+// identifier equals the environment name.
+func (rm *ReplicationManager) DiscoverClusters(env string) ([]DBCluster, error) {
+	if !rm.isValidEnv(env) {
+		return nil, fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(rm.ValidEnvironments(), ", "))
+	}
+
+	cmd := awscli.CreateCommand("rds", "describe-db-clusters",
+		"--region", rm.regionFor(env),
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to list DB clusters: %s", utils.Redact(stderr.String()))
+	}
+
+	var response struct {
+		DBClusters []DBCluster `json:"DBClusters"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return clustersForEnv(response.DBClusters, env), nil
+}
+
+// clustersForEnv filters clusters to an environment and sorts them newest
+// first
+func clustersForEnv(clusters []DBCluster, env string) []DBCluster {
+	matched := make([]DBCluster, 0)
+	for _, c := range clusters {
+		if c.belongsTo(env) {
+			matched = append(matched, c)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreateTime.After(matched[j].CreateTime) })
+	return matched
+}
+
+func (c DBCluster) belongsTo(env string) bool {
+	for _, tag := range c.TagList {
+		if slices.Contains(envTagKeys, strings.ToLower(tag.Key)) {
+			return strings.EqualFold(tag.Value, env)
+		}
+	}
+	parts := strings.FieldsFunc(c.Identifier, func(r rune) bool { return r == '-' || r == '_' })
+	return slices.ContainsFunc(parts, func(p string) bool { return strings.EqualFold(p, env) })
+}
+
+// Delete deletes a Blue-Green deployment. env picks the region; empty uses
+// the default region.
+func (rm *ReplicationManager) Delete(env, deploymentID string, deleteTarget bool) error {
 	if deploymentID == "" {
 		return fmt.Errorf("deployment identifier is required")
 	}
+	if env != "" && !rm.isValidEnv(env) {
+		return fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(rm.ValidEnvironments(), ", "))
+	}
+	region := rm.regionFor(env)
 
 	// Verify deployment exists
-	deployment, err := rm.getDeployment(deploymentID)
+	deployment, err := rm.getDeployment(region, deploymentID)
 	if err != nil {
 		return fmt.Errorf("failed to get deployment: %w", err)
 	}
@@ -283,7 +401,7 @@ func (rm *ReplicationManager) Delete(deploymentID string, deleteTarget bool) err
 
 	args := []string{"rds", "delete-blue-green-deployment",
 		"--blue-green-deployment-identifier", deploymentID,
-		"--region", rm.region,
+		"--region", region,
 	}
 
 	if deleteTarget {
@@ -296,17 +414,17 @@ func (rm *ReplicationManager) Delete(deploymentID string, deleteTarget bool) err
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to delete deployment: %s", stderr.String())
+		return fmt.Errorf("failed to delete deployment: %s", utils.Redact(stderr.String()))
 	}
 
-	fmt.Println("✓ Deployment deletion initiated")
+	fmt.Println(utils.OK() + " Deployment deletion initiated")
 	return nil
 }
 
 // listDeployments lists all Blue-Green deployments, optionally filtered by environment
 func (rm *ReplicationManager) listDeployments(env string) ([]BlueGreenDeployment, error) {
 	cmd := awscli.CreateCommand("rds", "describe-blue-green-deployments",
-		"--region", rm.region,
+		"--region", rm.regionFor(env),
 	)
 
 	var stdout, stderr bytes.Buffer
@@ -314,7 +432,7 @@ func (rm *ReplicationManager) listDeployments(env string) ([]BlueGreenDeployment
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to list deployments: %s", stderr.String())
+		return nil, fmt.Errorf("failed to list deployments: %s", utils.Redact(stderr.String()))
 	}
 
 	var response BlueGreenDeploymentsResponse
@@ -341,10 +459,10 @@ func (rm *ReplicationManager) listDeployments(env string) ([]BlueGreenDeployment
 }
 
 // getDeployment retrieves a specific deployment by ID
-func (rm *ReplicationManager) getDeployment(deploymentID string) (*BlueGreenDeployment, error) {
+func (rm *ReplicationManager) getDeployment(region, deploymentID string) (*BlueGreenDeployment, error) {
 	cmd := awscli.CreateCommand("rds", "describe-blue-green-deployments",
 		"--blue-green-deployment-identifier", deploymentID,
-		"--region", rm.region,
+		"--region", region,
 	)
 
 	var stdout, stderr bytes.Buffer
@@ -356,7 +474,7 @@ func (rm *ReplicationManager) getDeployment(deploymentID string) (*BlueGreenDepl
 		if strings.Contains(stderr.String(), "BlueGreenDeploymentNotFoundFault") {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to get deployment: %s", stderr.String())
+		return nil, fmt.Errorf("failed to get deployment: %s", utils.Redact(stderr.String()))
 	}
 
 	var response BlueGreenDeploymentsResponse
@@ -375,19 +493,19 @@ func (rm *ReplicationManager) getDeployment(deploymentID string) (*BlueGreenDepl
 func (rm *ReplicationManager) formatStatus(status string) string {
 	switch status {
 	case "AVAILABLE":
-		return "✓ AVAILABLE (ready for switchover)"
+		return utils.OK() + " AVAILABLE (ready for switchover)"
 	case "PROVISIONING":
-		return "⏳ PROVISIONING"
+		return utils.Emoji("⏳ ", "") + "PROVISIONING"
 	case "SWITCHOVER_IN_PROGRESS":
-		return "🔄 SWITCHOVER_IN_PROGRESS"
+		return utils.Emoji("🔄 ", "") + "SWITCHOVER_IN_PROGRESS"
 	case "SWITCHOVER_COMPLETED":
-		return "✓ SWITCHOVER_COMPLETED"
+		return utils.OK() + " SWITCHOVER_COMPLETED"
 	case "SWITCHOVER_FAILED":
-		return "✗ SWITCHOVER_FAILED"
+		return utils.Fail() + " SWITCHOVER_FAILED"
 	case "DELETING":
-		return "🗑 DELETING"
+		return utils.Emoji("🗑 ", "") + "DELETING"
 	case "DELETED":
-		return "🗑 DELETED"
+		return utils.Emoji("🗑 ", "") + "DELETED"
 	default:
 		return status
 	}
@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+func TestClustersForEnv(t *testing.T) {
+	var clusters []DBCluster
+	raw := `[
+		{"DBClusterIdentifier": "zenith-dev-db", "ClusterCreateTime": "2025-01-10T09:00:00Z"},
+		{"DBClusterIdentifier": "zenith-preprod-db", "ClusterCreateTime": "2025-06-01T09:00:00Z"},
+		{"DBClusterIdentifier": "zenith_dev_db_v2", "ClusterCreateTime": "2025-05-01T09:00:00Z"},
+		{"DBClusterIdentifier": "reporting", "ClusterCreateTime": "2025-04-01T09:00:00Z",
+		 "TagList": [{"Key": "Environment", "Value": "DEV"}]},
+		{"DBClusterIdentifier": "dev-copy-for-prod", "ClusterCreateTime": "2025-07-01T09:00:00Z",
+		 "TagList": [{"Key": "env", "Value": "prod"}]}
+	]`
+	if err := json.Unmarshal([]byte(raw), &clusters); err != nil {
+		t.Fatal(err)
+	}
+
+	got := clustersForEnv(clusters, "dev")
+	want := []string{"zenith_dev_db_v2", "reporting", "zenith-dev-db"}
+	if len(got) != len(want) {
+		t.Fatalf("clustersForEnv(dev) = %+v, want %v", got, want)
+	}
+	for i, id := range want {
+		if got[i].Identifier != id {
+			t.Errorf("clustersForEnv(dev)[%d] = %s, want %s (newest first)", i, got[i].Identifier, id)
+		}
+	}
+
+	prod := clustersForEnv(clusters, "prod")
+	if len(prod) != 1 || prod[0].Identifier != "dev-copy-for-prod" {
+		t.Errorf("clustersForEnv(prod) = %+v, want only the prod-tagged cluster (not preprod)", prod)
+	}
+}
+
+func TestReplicationClusterARN(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	rm := NewReplicationManagerWithRepo(repo)
+	if got, want := rm.clusterARN("dev", "zenith-dev-db"), "arn:aws:rds:eu-west-2::cluster:zenith-dev-db"; got != want {
+		t.Errorf("clusterARN() without an account = %q, want %q", got, want)
+	}
+
+	if err := repo.AddAWSAccount("000000000081", "gov", "", "us-gov-west-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddEnvironment("gov", "GovCloud", "us-gov-west-1", "gov-admin", "gov-eks"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetEnvironmentAccount("gov", "000000000081"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rm.clusterARN("gov", "zenith-gov-db"), "arn:aws-us-gov:rds:us-gov-west-1:000000000081:cluster:zenith-gov-db"; got != want {
+		t.Errorf("clusterARN() in a GovCloud environment = %q, want %q", got, want)
+	}
+
+	// An unknown environment falls back to the configured region
+	if got, want := rm.clusterARN("", "zenith-db"), "arn:aws:rds:eu-west-2::cluster:zenith-db"; got != want {
+		t.Errorf("clusterARN() without an environment = %q, want %q", got, want)
+	}
+}
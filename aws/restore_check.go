@@ -0,0 +1,184 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/k8s"
+	"rolewalkers/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// RestoreCheck describes the target database and the dump before a restore
+type RestoreCheck struct {
+	// TargetTables is the number of user tables in the target database
+	TargetTables int
+
+	// TargetVersion is the highest schema_migrations version in the target,
+	// or "" when the table doesn't exist
+	TargetVersion string
+
+	// DumpInspected is false when the dump could not be read ahead of time
+	// (e.g. S3 objects, which would have to be downloaded twice)
+	DumpInspected bool
+
+	// DumpServerVersion is the PostgreSQL version the dump was taken from
+	DumpServerVersion string
+
+	// DumpVersion is the highest schema_migrations version in the dump
+	DumpVersion string
+
+	// Warnings lists likely mistakes; empty when the restore looks safe
+	Warnings []string
+}
+
+// InspectRestoreTarget inspects the target database and the dump header so
+// the caller can warn about the most common destructive restore mistakes:
+// restoring over a non-empty database without --clean, and restoring an
+// older schema over a newer database.
+func (dm *DatabaseManager) InspectRestoreTarget(config RestoreConfig) (*RestoreCheck, error) {
+	env := strings.ToLower(config.Environment)
+	check := &RestoreCheck{}
+
+	if config.S3URI == "" {
+		f, err := os.Open(config.InputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+		check.DumpServerVersion, check.DumpVersion, err = inspectDump(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dump: %w", err)
+		}
+		check.DumpInspected = true
+	}
+
+	endpoint, password, err := dm.writerConnection(env)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Inspecting target database...")
+	out, err := dm.runPsqlQuery(endpoint, password, `
+		SELECT count(*),
+		       to_regclass('public.schema_migrations') IS NOT NULL
+		FROM information_schema.tables
+		WHERE table_type = 'BASE TABLE'
+		  AND table_schema NOT IN ('pg_catalog', 'information_schema')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect target database: %w", err)
+	}
+
+	count, hasMigrations, _ := strings.Cut(strings.TrimSpace(out), "|")
+	check.TargetTables, _ = strconv.Atoi(count)
+	if hasMigrations == "t" {
+		version, err := dm.runPsqlQuery(endpoint, password, `SELECT max(version)::text FROM schema_migrations`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read target schema version: %w", err)
+		}
+		check.TargetVersion = strings.TrimSpace(version)
+	}
+
+	check.evaluate(config.Clean)
+	return check, nil
+}
+
+// evaluate fills in Warnings from the inspected state
+func (rc *RestoreCheck) evaluate(clean bool) {
+	rc.Warnings = nil
+
+	if rc.TargetTables > 0 && !clean {
+		rc.Warnings = append(rc.Warnings, fmt.Sprintf(
+			"target database is not empty (%d tables) and --clean was not given; the restore will likely fail or mix old and new data",
+			rc.TargetTables))
+	}
+
+	if rc.DumpVersion != "" && rc.TargetVersion != "" && compareSchemaVersions(rc.DumpVersion, rc.TargetVersion) < 0 {
+		rc.Warnings = append(rc.Warnings, fmt.Sprintf(
+			"dump schema version %s is older than the target's %s; restoring will roll back migrations",
+			rc.DumpVersion, rc.TargetVersion))
+	}
+}
+
+// runPsqlQuery runs a single query in a temporary pod and returns the
+// unaligned, tuples-only output ("a|b" per row)
+func (dm *DatabaseManager) runPsqlQuery(endpoint, password, query string) (string, error) {
+	cfg := appconfig.Get()
+	var stdout, stderr bytes.Buffer
+
+	pod := k8s.NewPodSession(k8s.PodSpec{
+		NamePrefix: "psql-check",
+		Image:      cfg.Images.Postgres,
+		Command: []string{
+			"psql",
+			"-h", endpoint,
+			"-U", cfg.Database.MasterUser,
+			"-d", cfg.Project,
+			"-At", "-F", "|",
+			"-v", "ON_ERROR_STOP=1",
+			"-c", query,
+		},
+		Env:    map[string]string{"PGPASSWORD": password},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	defer pod.Close()
+	if err := pod.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, utils.Redact(strings.TrimSpace(stderr.String())))
+	}
+	return stdout.String(), nil
+}
+
+// inspectDump reads a plain-SQL pg_dump and returns the server version from
+// its header and the highest schema_migrations version in its data.
+func inspectDump(r io.Reader) (serverVersion, schemaVersion string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	inMigrations := false
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if inMigrations {
+			if line == `\.` {
+				inMigrations = false
+				continue
+			}
+			version, _, _ := strings.Cut(line, "\t")
+			if schemaVersion == "" || compareSchemaVersions(version, schemaVersion) > 0 {
+				schemaVersion = version
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "-- Dumped from database version "):
+			serverVersion = strings.TrimPrefix(line, "-- Dumped from database version ")
+		case strings.HasPrefix(line, "COPY public.schema_migrations ") && strings.HasSuffix(line, "FROM stdin;"):
+			inMigrations = true
+		}
+	}
+
+	return serverVersion, schemaVersion, scanner.Err()
+}
+
+// compareSchemaVersions compares migration versions numerically when both
+// are integers (e.g. timestamps like 20240601120000), otherwise as strings.
+func compareSchemaVersions(a, b string) int {
+	ai, aErr := strconv.ParseInt(a, 10, 64)
+	bi, bErr := strconv.ParseInt(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleDump = `--
+-- PostgreSQL database dump
+--
+
+-- Dumped from database version 15.4
+-- Dumped by pg_dump version 15.6
+
+SET statement_timeout = 0;
+
+COPY public.schema_migrations (version, dirty) FROM stdin;
+20240101120000	f
+20240315093000	f
+20240210000000	f
+\.
+
+COPY public.users (id, name) FROM stdin;
+99999999999999	not a migration
+\.
+`
+
+func TestInspectDump(t *testing.T) {
+	server, version, err := inspectDump(strings.NewReader(sampleDump))
+	if err != nil {
+		t.Fatalf("inspectDump() error = %v", err)
+	}
+	if server != "15.4" {
+		t.Errorf("server version = %q, want %q", server, "15.4")
+	}
+	if version != "20240315093000" {
+		t.Errorf("schema version = %q, want %q", version, "20240315093000")
+	}
+}
+
+func TestInspectDumpWithoutMigrations(t *testing.T) {
+	_, version, err := inspectDump(strings.NewReader("-- Dumped from database version 14.1\nSELECT 1;\n"))
+	if err != nil {
+		t.Fatalf("inspectDump() error = %v", err)
+	}
+	if version != "" {
+		t.Errorf("schema version = %q, want empty", version)
+	}
+}
+
+func TestCompareSchemaVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"9", "10", -1},
+		{"20240315093000", "20240101120000", 1},
+		{"42", "42", 0},
+		{"v1.2", "v1.10", 1}, // non-numeric falls back to string comparison
+	}
+	for _, tt := range tests {
+		if got := compareSchemaVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareSchemaVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreCheckEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    RestoreCheck
+		clean    bool
+		warnings int
+	}{
+		{"empty target", RestoreCheck{DumpVersion: "2", TargetVersion: ""}, false, 0},
+		{"non-empty without clean", RestoreCheck{TargetTables: 12}, false, 1},
+		{"non-empty with clean", RestoreCheck{TargetTables: 12}, true, 0},
+		{"older dump", RestoreCheck{TargetTables: 12, DumpVersion: "5", TargetVersion: "7"}, true, 1},
+		{"older dump, not clean", RestoreCheck{TargetTables: 12, DumpVersion: "5", TargetVersion: "7"}, false, 2},
+		{"newer dump", RestoreCheck{TargetTables: 12, DumpVersion: "8", TargetVersion: "7"}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.evaluate(tt.clean)
+			if len(tt.check.Warnings) != tt.warnings {
+				t.Errorf("evaluate() warnings = %v, want %d", tt.check.Warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 )
 
 // RoleSwitcher handles switching between AWS roles
@@ -20,23 +21,29 @@ func NewRoleSwitcher(cm *ConfigManager, dbRepo *db.ConfigRepository) *RoleSwitch
 	}
 }
 
-// SwitchRole switches to a specific role by profile name
-func (rs *RoleSwitcher) SwitchRole(profileName string) error {
+// SwitchRole switches to a specific role by profile name and returns the
+// active session. Switching to the role that is already active is a no-op
+// that returns the existing session.
+func (rs *RoleSwitcher) SwitchRole(profileName string) (*db.UserSession, error) {
 	// Get role from database
 	role, err := rs.dbRepo.GetRoleByProfileName(profileName)
 	if err != nil {
-		return fmt.Errorf("failed to get role: %w", err)
+		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
 
 	// Get the AWS account for this role
 	account, err := rs.getAccountForRole(role)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Create session in database
-	if err := rs.dbRepo.CreateUserSession(role.ID); err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+	session, created, err := rs.dbRepo.CreateUserSession(role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if !created {
+		return session, nil
 	}
 
 	// Generate AWS config from database (rw manages the config)
@@ -44,51 +51,36 @@ func (rs *RoleSwitcher) SwitchRole(profileName string) error {
 	if err == nil {
 		if err := configSync.WriteAWSConfig(); err != nil {
 			// Non-fatal: fall back to manual update
-			fmt.Printf("⚠ Could not regenerate config from DB: %v\n", err)
+			fmt.Fprintf(utils.Stdout, utils.Warn()+" Could not regenerate config from DB: %v\n", err)
 			settings := ProfileSettings{Lines: rs.formatRoleSettings(role, account)}
-			if err := writeDefaultSection(rs.configManager.configPath, settings); err != nil {
-				return fmt.Errorf("failed to update AWS config: %w", err)
+			if err := rs.configManager.writeDefaultSection(settings); err != nil {
+				return nil, fmt.Errorf("failed to update AWS config: %w", err)
 			}
 		}
 	} else {
 		// Fall back to manual update
 		settings := ProfileSettings{Lines: rs.formatRoleSettings(role, account)}
-		if err := writeDefaultSection(rs.configManager.configPath, settings); err != nil {
-			return fmt.Errorf("failed to update AWS config: %w", err)
+		if err := rs.configManager.writeDefaultSection(settings); err != nil {
+			return nil, fmt.Errorf("failed to update AWS config: %w", err)
 		}
 	}
 
 	// Write unified active identity file
 	if err := writeActiveIdentityFile(profileName); err != nil {
-		return fmt.Errorf("failed to write active identity file: %w", err)
+		return nil, fmt.Errorf("failed to write active identity file: %w", err)
 	}
 
 	// Apply env vars and write env file using shared helper
 	if err := applyProfileEnv(profileName, role.Region); err != nil {
-		return fmt.Errorf("failed to apply environment: %w", err)
+		return nil, fmt.Errorf("failed to apply environment: %w", err)
 	}
 
-	return nil
+	return session, nil
 }
 
 // getAccountForRole finds the AWS account for a given role
 func (rs *RoleSwitcher) getAccountForRole(role *db.AWSRole) (*db.AWSAccount, error) {
-	account, err := rs.dbRepo.GetAWSAccount(fmt.Sprintf("%d", role.AccountID))
-	if err != nil {
-		accounts, err := rs.dbRepo.GetAllAWSAccounts()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get accounts: %w", err)
-		}
-
-		for _, acc := range accounts {
-			if acc.ID == role.AccountID {
-				return &acc, nil
-			}
-		}
-
-		return nil, fmt.Errorf("account not found for role")
-	}
-	return account, nil
+	return rs.dbRepo.GetAccountByRowID(role.AccountID)
 }
 
 
@@ -0,0 +1,207 @@
+package aws
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
+)
+
+// ServiceSelector picks HPAs by service name patterns. A pattern is a glob
+// (candidate*, *-worker) or, between slashes, a regular expression
+// (/^candidate-(worker|consumer)$/). Patterns match the service name, i.e.
+// the HPA name without its -microservice-hpa or -hpa suffix, or the full
+// HPA name.
+type ServiceSelector struct {
+	include []servicePattern
+	exclude []servicePattern
+}
+
+// servicePattern matches one glob or regular expression
+type servicePattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+func (p servicePattern) match(name string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(name)
+	}
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
+
+// IsServicePattern reports whether a --service value selects HPAs by
+// pattern, rather than naming a single service
+func IsServicePattern(s string) bool {
+	return strings.ContainsAny(s, "*?[,") || isRegexPattern(s)
+}
+
+func isRegexPattern(s string) bool {
+	return len(s) > 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/")
+}
+
+// ParseServiceSelector parses comma-separated include and exclude patterns
+func ParseServiceSelector(include, exclude string) (*ServiceSelector, error) {
+	sel := &ServiceSelector{}
+	var err error
+	if sel.include, err = parseServicePatterns(include); err != nil {
+		return nil, err
+	}
+	if len(sel.include) == 0 {
+		return nil, fmt.Errorf("no service pattern given")
+	}
+	if sel.exclude, err = parseServicePatterns(exclude); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+func parseServicePatterns(list string) ([]servicePattern, error) {
+	var patterns []servicePattern
+	for _, s := range splitServicePatterns(list) {
+		s = strings.TrimSpace(s)
+		switch {
+		case s == "":
+			continue
+		case isRegexPattern(s):
+			re, err := regexp.Compile(s[1 : len(s)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %w", s, err)
+			}
+			patterns = append(patterns, servicePattern{regex: re})
+		default:
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", s, err)
+			}
+			patterns = append(patterns, servicePattern{glob: s})
+		}
+	}
+	return patterns, nil
+}
+
+// splitServicePatterns splits a pattern list on commas, except inside a
+// /regex/, which may have commas of its own (/^candidate-\d{1,2}$/)
+func splitServicePatterns(list string) []string {
+	var parts []string
+	for list != "" {
+		list = strings.TrimLeft(list, " \t")
+		end := strings.IndexByte(list, ',')
+		if strings.HasPrefix(list, "/") {
+			end = regexPatternEnd(list)
+		}
+		if end < 0 {
+			parts = append(parts, list)
+			break
+		}
+		parts = append(parts, list[:end])
+		list = list[end+1:]
+	}
+	return parts
+}
+
+// regexPatternEnd returns the index of the comma that follows the /regex/
+// at the start of s, or -1 if it runs to the end of s. A regex with no
+// closing slash falls back to the first comma.
+func regexPatternEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] != '/' {
+			continue
+		}
+		rest := strings.TrimLeft(s[i+1:], " \t")
+		if rest == "" {
+			return -1
+		}
+		if rest[0] == ',' {
+			return len(s) - len(rest)
+		}
+	}
+	return strings.IndexByte(s, ',')
+}
+
+// Match reports whether an HPA is selected: some include pattern matches
+// and no exclude pattern does
+func (sel *ServiceSelector) Match(hpaName string) bool {
+	names := []string{hpaName}
+	if service := serviceFromHPAName(hpaName); service != hpaName {
+		names = append(names, service)
+	}
+	matchesAny := func(patterns []servicePattern) bool {
+		for _, p := range patterns {
+			for _, name := range names {
+				if p.match(name) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return matchesAny(sel.include) && !matchesAny(sel.exclude)
+}
+
+// serviceFromHPAName is the inverse of buildHPAName
+func serviceFromHPAName(name string) string {
+	if s, ok := strings.CutSuffix(name, "-microservice-hpa"); ok {
+		return s
+	}
+	if s, ok := strings.CutSuffix(name, "-hpa"); ok {
+		return s
+	}
+	return name
+}
+
+// MatchHPAs switches to env's context and returns the HPAs sel selects,
+// for a preview before ScaleHPAs
+func (sm *ScalingManager) MatchHPAs(env string, sel *ServiceSelector) ([]HPAScaling, error) {
+	hpas, err := sm.ListHPAs(env)
+	if err != nil {
+		return nil, err
+	}
+	var matched []HPAScaling
+	for _, hpa := range hpas {
+		if sel.Match(hpa.Name) {
+			matched = append(matched, hpa)
+		}
+	}
+	return matched, nil
+}
+
+// ScaleHPAs sets min/max on HPAs returned by MatchHPAs, recorded as one
+// change so 'rw undo' reverts them together. pattern describes the
+// selection in the audit log.
+func (sm *ScalingManager) ScaleHPAs(env, pattern string, hpas []HPAScaling, min, max int) error {
+	if min < 0 || max < 0 {
+		return fmt.Errorf("min and max must be non-negative")
+	}
+	if min > max {
+		return fmt.Errorf("min (%d) cannot be greater than max (%d)", min, max)
+	}
+
+	var errors []string
+	var previous, next []HPAState
+	for _, hpa := range hpas {
+		if err := sm.patchHPA(hpa.Name, min, max); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", hpa.Name, err))
+			continue
+		}
+		fmt.Printf("  "+utils.OK()+" %s\n", hpa.Name)
+		previous = append(previous, HPAState{Name: hpa.Name, Min: hpa.MinReplicas, Max: hpa.MaxReplicas})
+		next = append(next, HPAState{Name: hpa.Name, Min: min, Max: max})
+	}
+
+	if len(previous) > 0 {
+		recordAudit(sm.configRepo, AuditActionScale, env, pattern, previous, next,
+			messages.New(messages.AuditScaleServices, "env", env, "pattern", pattern,
+				"count", strconv.Itoa(len(previous)), "min", strconv.Itoa(min), "max", strconv.Itoa(max)))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("some HPAs failed to scale:\n  %s", strings.Join(errors, "\n  "))
+	}
+	fmt.Printf("\n"+utils.OK()+" Scaled %d HPAs to min=%d, max=%d\n", len(previous), min, max)
+	return nil
+}
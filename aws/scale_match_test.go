@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestServiceSelector(t *testing.T) {
+	hpas := []string{
+		"candidate-microservice-hpa",
+		"candidate-worker-microservice-hpa",
+		"candidate-consumer-microservice-hpa",
+		"jobs-microservice-hpa",
+		"gateway-hpa",
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude string
+		want             []string
+	}{
+		{"glob", "candidate*", "", hpas[:3]},
+		{"glob with exclude", "candidate*", "*-consumer", hpas[:2]},
+		{"several patterns", "jobs,gateway", "", hpas[3:]},
+		{"full HPA name", "*-hpa", "candidate*", hpas[3:]},
+		{"regex", "/^candidate-(worker|consumer)$/", "", hpas[1:3]},
+		{"regex with comma among globs", `jobs*, /^candidate-\w{1,6}$/,gateway`, "", []string{hpas[1], hpas[3], hpas[4]}},
+		{"regex with comma in exclude", "candidate*", `/-\w{7,8}$/, *-worker`, hpas[:1]},
+		{"no match", "billing*", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := ParseServiceSelector(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("ParseServiceSelector() error: %v", err)
+			}
+			var got []string
+			for _, name := range hpas {
+				if sel.Match(name) {
+					got = append(got, name)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("matched %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	for _, bad := range []string{"/candidate(/", "[", ""} {
+		if _, err := ParseServiceSelector(bad, ""); err == nil {
+			t.Errorf("ParseServiceSelector(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSplitServicePatterns(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"jobs,gateway", []string{"jobs", "gateway"}},
+		{`/^candidate-\d{1,2}$/`, []string{`/^candidate-\d{1,2}$/`}},
+		{`candidate*, /^a{1,2}$/ ,b*`, []string{"candidate*", "/^a{1,2}$/ ", "b*"}},
+		{"/a/b,c/,d", []string{"/a/b,c/", "d"}},
+		{"/unclosed,jobs", []string{"/unclosed", "jobs"}},
+	}
+	for _, tt := range tests {
+		got := splitServicePatterns(tt.list)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitServicePatterns(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestIsServicePattern(t *testing.T) {
+	for s, want := range map[string]bool{
+		"candidate":          false,
+		"candidate-worker":   false,
+		"candidate*":         true,
+		"candidate,jobs":     true,
+		"/^candidate-.*$/":   true,
+		"candidate-svc-[ab]": true,
+	} {
+		if got := IsServicePattern(s); got != want {
+			t.Errorf("IsServicePattern(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package aws
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/k8s"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
+	"strconv"
 	"strings"
 )
 
@@ -35,6 +38,21 @@ type HPAInfo struct {
 	} `json:"spec"`
 }
 
+// HPAState is the min/max replica setting of one HPA, as recorded in the audit log
+type HPAState struct {
+	Name string `json:"name"`
+	Min  int    `json:"min"`
+	Max  int    `json:"max"`
+}
+
+// HPAScaling is an HPA's current scaling, as returned by ListHPAs
+type HPAScaling struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	MinReplicas int    `json:"min_replicas"`
+	MaxReplicas int    `json:"max_replicas"`
+}
+
 // HPAList represents the kubectl get hpa output
 type HPAList struct {
 	Items []HPAInfo `json:"items"`
@@ -132,7 +150,7 @@ func (sm *ScalingManager) Scale(env, presetName string) error {
 	fmt.Printf("Using kubectl context: %s\n", ctx)
 
 	// Get all HPAs
-	hpas, err := sm.listHPAs()
+	hpas, err := sm.listHPAs("")
 	if err != nil {
 		return fmt.Errorf("failed to list HPAs: %w", err)
 	}
@@ -143,21 +161,29 @@ func (sm *ScalingManager) Scale(env, presetName string) error {
 
 	fmt.Printf("Scaling %d HPAs to preset '%s' (min=%d, max=%d)...\n", len(hpas), presetName, preset.Min, preset.Max)
 
-	// Patch each HPA
+	// Patch each HPA, remembering the previous settings for undo
 	var errors []string
+	var previous, next []HPAState
 	for _, hpa := range hpas {
 		if err := sm.patchHPA(hpa.Metadata.Name, preset.Min, preset.Max); err != nil {
 			errors = append(errors, fmt.Sprintf("%s: %v", hpa.Metadata.Name, err))
 		} else {
-			fmt.Printf("  ✓ %s\n", hpa.Metadata.Name)
+			fmt.Printf("  "+utils.OK()+" %s\n", hpa.Metadata.Name)
+			previous = append(previous, HPAState{Name: hpa.Metadata.Name, Min: hpa.Spec.MinReplicas, Max: hpa.Spec.MaxReplicas})
+			next = append(next, HPAState{Name: hpa.Metadata.Name, Min: preset.Min, Max: preset.Max})
 		}
 	}
 
+	if len(previous) > 0 {
+		recordAudit(sm.configRepo, AuditActionScale, env, presetName, previous, next,
+			messages.New(messages.AuditScalePreset, "env", env, "preset", presetName))
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("some HPAs failed to scale:\n  %s", strings.Join(errors, "\n  "))
 	}
 
-	fmt.Printf("\n✓ Successfully scaled all HPAs to '%s' preset\n", presetName)
+	fmt.Printf("\n"+utils.OK()+" Successfully scaled all HPAs to '%s' preset\n", presetName)
 	return nil
 }
 
@@ -186,8 +212,9 @@ func (sm *ScalingManager) ScaleService(env, service string, min, max int) error
 	// Build HPA name from service name
 	hpaName := sm.buildHPAName(service)
 
-	// Verify HPA exists
-	if !sm.hpaExists(hpaName) {
+	// Verify HPA exists and capture its current settings
+	hpa, err := sm.getHPA(hpaName)
+	if err != nil {
 		return fmt.Errorf("HPA '%s' not found in namespace %s", hpaName, sm.namespace)
 	}
 
@@ -196,56 +223,107 @@ func (sm *ScalingManager) ScaleService(env, service string, min, max int) error
 		return fmt.Errorf("failed to scale %s: %w", hpaName, err)
 	}
 
-	fmt.Printf("✓ Scaled %s to min=%d, max=%d\n", hpaName, min, max)
+	recordAudit(sm.configRepo, AuditActionScale, env, hpaName,
+		[]HPAState{{Name: hpaName, Min: hpa.Spec.MinReplicas, Max: hpa.Spec.MaxReplicas}},
+		[]HPAState{{Name: hpaName, Min: min, Max: max}},
+		messages.New(messages.AuditScaleService, "env", env, "service", service,
+			"min", strconv.Itoa(min), "max", strconv.Itoa(max)))
+
+	fmt.Printf(utils.OK()+" Scaled %s to min=%d, max=%d\n", hpaName, min, max)
 	return nil
 }
 
-// ListHPAs returns formatted list of HPAs and their current scaling
-func (sm *ScalingManager) ListHPAs(env string) (string, error) {
+// Restore patches HPAs back to previously recorded min/max values.
+// It is used by 'rw undo' and does not create a new audit entry.
+func (sm *ScalingManager) Restore(env string, states []HPAState) error {
 	if !sm.isValidEnv(env) {
-		return "", fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(sm.ValidEnvironments(), ", "))
+		return fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(sm.ValidEnvironments(), ", "))
 	}
 
 	// Switch to correct kubectl context
 	if err := sm.kubeManager.SwitchContextForEnvWithProfile(env, sm.profileSwitcher); err != nil {
-		return "", fmt.Errorf("failed to switch kubectl context: %w", err)
+		return fmt.Errorf("failed to switch kubectl context: %w", err)
 	}
 
-	hpas, err := sm.listHPAs()
-	if err != nil {
-		return "", err
+	var errors []string
+	for _, st := range states {
+		if err := sm.patchHPA(st.Name, st.Min, st.Max); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", st.Name, err))
+		} else {
+			fmt.Printf("  "+utils.OK()+" %s restored to min=%d, max=%d\n", st.Name, st.Min, st.Max)
+		}
 	}
 
-	if len(hpas) == 0 {
-		return fmt.Sprintf("No HPAs found in namespace %s", sm.namespace), nil
+	if len(errors) > 0 {
+		return fmt.Errorf("some HPAs failed to restore:\n  %s", strings.Join(errors, "\n  "))
 	}
 
-	var sb strings.Builder
-	fmt.Fprintf(&sb, "HPAs in %s namespace:\n", sm.namespace)
-	sb.WriteString(strings.Repeat("-", 60) + "\n")
-	fmt.Fprintf(&sb, "%-40s %s\n", "NAME", "MIN/MAX")
-	sb.WriteString(strings.Repeat("-", 60) + "\n")
+	return nil
+}
 
-	for _, hpa := range hpas {
-		fmt.Fprintf(&sb, "%-40s %d/%d\n", hpa.Metadata.Name, hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+// ListHPAs returns the HPAs in an environment's namespace with their
+// current scaling
+func (sm *ScalingManager) ListHPAs(env string) ([]HPAScaling, error) {
+	if !sm.isValidEnv(env) {
+		return nil, fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(sm.ValidEnvironments(), ", "))
+	}
+
+	// Switch to correct kubectl context
+	if err := sm.kubeManager.SwitchContextForEnvWithProfile(env, sm.profileSwitcher); err != nil {
+		return nil, fmt.Errorf("failed to switch kubectl context: %w", err)
+	}
+
+	return sm.hpaScalings("")
+}
+
+// ListHPAsInContext is ListHPAs without switching: it reads the HPAs
+// through the environment's existing kubectl context with --context and
+// leaves the current context and AWS profile alone, for servers such as
+// 'rw slack serve' that answer for several environments at once
+func (sm *ScalingManager) ListHPAsInContext(env string) ([]HPAScaling, error) {
+	if !sm.isValidEnv(env) {
+		return nil, fmt.Errorf("invalid environment: %s (valid: %s)", env, strings.Join(sm.ValidEnvironments(), ", "))
 	}
 
-	return sb.String(), nil
+	kubeContext, err := sm.kubeManager.FindContextForEnv(env)
+	if err != nil {
+		return nil, fmt.Errorf("%w; add it with 'rw kube refresh %s'", err, env)
+	}
+	return sm.hpaScalings(kubeContext)
 }
 
-func (sm *ScalingManager) listHPAs() ([]HPAInfo, error) {
-	cmd := exec.Command("kubectl", "get", "hpa", "-n", sm.namespace, "-o", "json")
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
+// hpaScalings lists the HPAs in kubeContext ("" for the current context)
+func (sm *ScalingManager) hpaScalings(kubeContext string) ([]HPAScaling, error) {
+	hpas, err := sm.listHPAs(kubeContext)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("kubectl error: %s", stderr.String())
+	result := make([]HPAScaling, len(hpas))
+	for i, hpa := range hpas {
+		result[i] = HPAScaling{
+			Name:        hpa.Metadata.Name,
+			Namespace:   sm.namespace,
+			MinReplicas: hpa.Spec.MinReplicas,
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		}
+	}
+	return result, nil
+}
+
+// Namespace returns the namespace whose HPAs are scaled
+func (sm *ScalingManager) Namespace() string {
+	return sm.namespace
+}
+
+func (sm *ScalingManager) listHPAs(kubeContext string) ([]HPAInfo, error) {
+	out, err := k8s.SharedRunner().Run(context.Background(), kubeContext, "get", "hpa", "-n", sm.namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl error: %w", err)
 	}
 
 	var hpaList HPAList
-	if err := json.Unmarshal(out.Bytes(), &hpaList); err != nil {
+	if err := json.Unmarshal(out, &hpaList); err != nil {
 		return nil, fmt.Errorf("failed to parse HPA list: %w", err)
 	}
 
@@ -255,20 +333,25 @@ func (sm *ScalingManager) listHPAs() ([]HPAInfo, error) {
 func (sm *ScalingManager) patchHPA(name string, min, max int) error {
 	patch := fmt.Sprintf(`{"spec":{"minReplicas":%d,"maxReplicas":%d}}`, min, max)
 
-	cmd := exec.Command("kubectl", "patch", "hpa", name, "-n", sm.namespace, "--type=merge", "-p", patch)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("kubectl error: %s", stderr.String())
+	if _, err := k8s.SharedRunner().Run(context.Background(), "", "patch", "hpa", name, "-n", sm.namespace, "--type=merge", "-p", patch); err != nil {
+		return fmt.Errorf("kubectl error: %w", err)
 	}
 
 	return nil
 }
 
-func (sm *ScalingManager) hpaExists(name string) bool {
-	cmd := exec.Command("kubectl", "get", "hpa", name, "-n", sm.namespace)
-	return cmd.Run() == nil
+func (sm *ScalingManager) getHPA(name string) (*HPAInfo, error) {
+	out, err := k8s.SharedRunner().Run(context.Background(), "", "get", "hpa", name, "-n", sm.namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl error: %w", err)
+	}
+
+	var hpa HPAInfo
+	if err := json.Unmarshal(out, &hpa); err != nil {
+		return nil, fmt.Errorf("failed to parse HPA: %w", err)
+	}
+
+	return &hpa, nil
 }
 
 func (sm *ScalingManager) buildHPAName(service string) string {
@@ -2,16 +2,20 @@ package aws
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"rolewalkers/internal/awscli"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 )
 
 // SetupManager handles automatic discovery and configuration.
@@ -48,6 +52,21 @@ type SetupResult struct {
 	Errors      []string
 }
 
+// DiscoverOptions controls LoginAndDiscoverWithOptions
+type DiscoverOptions struct {
+	// ShowDeviceCode, when set, logs in with the SSO OIDC device flow
+	// instead of 'aws sso login', so nothing reads stdin and ~/.aws/config
+	// is untouched until the login succeeds. It is called with the URL and
+	// code to approve.
+	ShowDeviceCode func(DeviceAuthorization)
+	// Merge adds the discovered profiles to ~/.aws/config, replacing only
+	// sections of the same name, instead of rewriting the whole file
+	Merge bool
+	// Lock, when set, is held from the end of the login until the
+	// database, ~/.aws/config and kubeconfig have been written
+	Lock sync.Locker
+}
+
 // LoginAndDiscover performs the full setup flow:
 // 1. Write a temporary SSO config
 // 2. Login via browser
@@ -55,53 +74,78 @@ type SetupResult struct {
 // 4. Discover EKS clusters
 // 5. Generate AWS config and kubeconfig
 func (sm *SetupManager) LoginAndDiscover(startURL, ssoRegion string) (*SetupResult, error) {
+	return sm.LoginAndDiscoverWithOptions(startURL, ssoRegion, DiscoverOptions{})
+}
+
+// LoginAndDiscoverWithOptions is LoginAndDiscover with control over the
+// login and how ~/.aws/config is written
+func (sm *SetupManager) LoginAndDiscoverWithOptions(startURL, ssoRegion string, opts DiscoverOptions) (*SetupResult, error) {
 	cfg := config.Get()
 	result := &SetupResult{}
 
 	// Derive a session name from the start URL
 	sessionName := deriveSessionName(startURL)
 
-	// Step 1: Write a minimal temporary AWS config with sso-session for login
-	fmt.Println("Setting up SSO configuration...")
-	if err := sm.writeTempSSOConfig(sessionName, startURL, ssoRegion); err != nil {
-		return nil, fmt.Errorf("failed to write SSO config: %w", err)
-	}
+	var accessToken string
+	if opts.ShowDeviceCode != nil {
+		token, err := sm.deviceLogin(sessionName, startURL, ssoRegion, opts.ShowDeviceCode)
+		if err != nil {
+			return nil, fmt.Errorf("SSO login failed: %w", err)
+		}
+		accessToken = token
+	} else {
+		// Step 1: Write a minimal temporary AWS config with sso-session for login
+		fmt.Println("Setting up SSO configuration...")
+		if err := sm.writeTempSSOConfig(sessionName, startURL, ssoRegion); err != nil {
+			return nil, fmt.Errorf("failed to write SSO config: %w", err)
+		}
 
-	// Step 2: Login via browser
-	fmt.Println("\nOpening browser for SSO authentication...")
-	fmt.Println("Please complete the login in your browser.")
-	if err := sm.ssoLogin(sessionName); err != nil {
-		return nil, fmt.Errorf("SSO login failed: %w", err)
+		// Step 2: Login via browser
+		fmt.Println("\nOpening browser for SSO authentication...")
+		fmt.Println("Please complete the login in your browser.")
+		if err := sm.ssoLogin(sessionName); err != nil {
+			return nil, fmt.Errorf("SSO login failed: %w", err)
+		}
+		fmt.Println(utils.OK() + " SSO login successful")
+
+		// Step 3: Get the access token from cache
+		fmt.Println("\nRetrieving access token...")
+		cm, _ := NewConfigManager()
+		ssoMgr, _ := NewSSOManager(cm)
+		token, err := ssoMgr.findCachedToken(sessionName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SSO token after login: %w", err)
+		}
+		accessToken = token.AccessToken
 	}
-	fmt.Println("✓ SSO login successful")
 
-	// Step 3: Get the access token from cache
-	fmt.Println("\nRetrieving access token...")
-	cm, _ := NewConfigManager()
-	ssoMgr, _ := NewSSOManager(cm)
-	token, err := ssoMgr.findCachedToken(sessionName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get SSO token after login: %w", err)
+	if opts.Lock != nil {
+		opts.Lock.Lock()
+		defer opts.Lock.Unlock()
 	}
 
 	// Step 4: Discover accounts
 	fmt.Println("\nDiscovering AWS accounts...")
-	accounts, err := sm.listAccounts(token.AccessToken, ssoRegion)
+	accounts, err := sm.listAccounts(accessToken, ssoRegion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list accounts: %w", err)
 	}
 	fmt.Printf("  Found %d account(s)\n", len(accounts))
 	result.Accounts = len(accounts)
 
-	// Step 5: Discover roles per account and save to DB
+	// Step 5: Discover roles per account and save to DB.
+	// Accounts and roles are sorted so collision suffixes are deterministic.
 	fmt.Println("\nDiscovering roles and setting up profiles...")
+	namer := sm.newProfileNamer()
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].AccountID < accounts[j].AccountID })
 	var allProfiles []Profile
 	for _, acc := range accounts {
-		roles, err := sm.listAccountRoles(token.AccessToken, acc.AccountID, ssoRegion)
+		roles, err := sm.listAccountRoles(accessToken, acc.AccountID, ssoRegion)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Account %s: failed to list roles: %v", acc.AccountID, err))
 			continue
 		}
+		sort.Slice(roles, func(i, j int) bool { return roles[i].RoleName < roles[j].RoleName })
 
 		// Save account to DB
 		if sm.dbRepo != nil {
@@ -109,7 +153,10 @@ func (sm *SetupManager) LoginAndDiscover(startURL, ssoRegion string) (*SetupResu
 		}
 
 		for _, role := range roles {
-			profileName := sm.buildProfileName(acc.AccountName, role.RoleName)
+			profileName, collided := namer.Name(acc.AccountID, acc.AccountName, role.RoleName)
+			if collided {
+				result.Errors = append(result.Errors, fmt.Sprintf("Profile name for %s/%s collides with another profile; using %s", acc.AccountName, role.RoleName, profileName))
+			}
 
 			// Save role to DB
 			if sm.dbRepo != nil {
@@ -129,17 +176,21 @@ func (sm *SetupManager) LoginAndDiscover(startURL, ssoRegion string) (*SetupResu
 			})
 			result.Roles++
 
-			fmt.Printf("  ✓ %s → %s (%s)\n", acc.AccountName, role.RoleName, profileName)
+			fmt.Printf("  "+utils.OK()+" %s "+utils.Arrow()+" %s (%s)\n", acc.AccountName, role.RoleName, profileName)
 		}
 	}
 	result.Profiles = len(allProfiles)
 
 	// Step 6: Generate ~/.aws/config
 	fmt.Println("\nGenerating AWS config file...")
-	if err := sm.writeAWSConfig(sessionName, startURL, ssoRegion, allProfiles); err != nil {
+	writeConfig := sm.writeAWSConfig
+	if opts.Merge {
+		writeConfig = sm.mergeAWSConfig
+	}
+	if err := writeConfig(sessionName, startURL, ssoRegion, allProfiles); err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Failed to write AWS config: %v", err))
 	} else {
-		fmt.Println("  ✓ ~/.aws/config written")
+		fmt.Println("  " + utils.OK() + " ~/.aws/config written")
 	}
 
 	// Step 7: Discover EKS clusters per account
@@ -156,7 +207,7 @@ func (sm *SetupManager) LoginAndDiscover(startURL, ssoRegion string) (*SetupResu
 		}
 
 		for _, cluster := range clusters {
-			fmt.Printf("  ✓ %s → %s\n", p.Name, cluster)
+			fmt.Printf("  "+utils.OK()+" %s "+utils.Arrow()+" %s\n", p.Name, cluster)
 			result.Clusters++
 
 			if err := sm.updateKubeconfig(cluster, p.Name); err != nil {
@@ -188,6 +239,32 @@ func (sm *SetupManager) ssoLogin(sessionName string) error {
 	return cmd.Run()
 }
 
+// deviceLogin logs in to the start URL with the device code flow and caches
+// the token under the session name, where 'aws sso login' would put it. It
+// returns the access token.
+func (sm *SetupManager) deviceLogin(sessionName, startURL, ssoRegion string, show func(DeviceAuthorization)) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), deviceLoginTimeout)
+	defer cancel()
+
+	token, err := newSSOOIDCClient(ssoRegion).deviceLogin(ctx, startURL, show)
+	if err != nil {
+		return "", err
+	}
+	cm, err := NewConfigManager()
+	if err != nil {
+		return "", err
+	}
+	ssoMgr, err := NewSSOManager(cm)
+	if err != nil {
+		return "", err
+	}
+	profile := &Profile{SSOStartURL: startURL, SSORegion: ssoRegion}
+	if err := ssoMgr.writeCachedToken(sessionName, profile, token); err != nil {
+		return "", fmt.Errorf("failed to cache the SSO token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
 // listAccounts calls aws sso list-accounts using the access token.
 func (sm *SetupManager) listAccounts(accessToken, ssoRegion string) ([]ssoAccountInfo, error) {
 	cmd := awscli.CreateCommand("sso", "list-accounts",
@@ -201,7 +278,7 @@ func (sm *SetupManager) listAccounts(accessToken, ssoRegion string) ([]ssoAccoun
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%w: %s", err, stderr.String())
+		return nil, fmt.Errorf("%w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	var resp struct {
@@ -228,7 +305,7 @@ func (sm *SetupManager) listAccountRoles(accessToken, accountID, ssoRegion strin
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%w: %s", err, stderr.String())
+		return nil, fmt.Errorf("%w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	var resp struct {
@@ -254,7 +331,7 @@ func (sm *SetupManager) listEKSClusters(profileName string) ([]string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%w: %s", err, stderr.String())
+		return nil, fmt.Errorf("%w: %s", err, utils.Redact(stderr.String()))
 	}
 
 	var resp struct {
@@ -279,7 +356,7 @@ func (sm *SetupManager) updateKubeconfig(clusterName, profileName string) error
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%w: %s", err, stderr.String())
+		return fmt.Errorf("%w: %s", err, utils.Redact(stderr.String()))
 	}
 	return nil
 }
@@ -316,7 +393,10 @@ sso_role_name = placeholder
 region = %s
 `, sessionName, startURL, ssoRegion, sessionName, sm.region)
 
-	return os.WriteFile(cm.configPath, []byte(content), 0600)
+	if err := utils.WriteFileAtomic(cm.configPath, []byte(content), 0600); err != nil {
+		return err
+	}
+	return recordConfigWrite(cm.configPath, []byte(content))
 }
 
 // writeAWSConfig generates the full ~/.aws/config from discovered profiles.
@@ -352,102 +432,126 @@ func (sm *SetupManager) writeAWSConfig(sessionName, startURL, ssoRegion string,
 		fmt.Fprintf(&sb, "output = json\n\n")
 	}
 
-	return os.WriteFile(cm.configPath, []byte(sb.String()), 0600)
+	if err := utils.WriteFileAtomic(cm.configPath, []byte(sb.String()), 0600); err != nil {
+		return err
+	}
+	return recordConfigWrite(cm.configPath, []byte(sb.String()))
 }
 
-// buildProfileName creates a clean profile name from account name and role.
-// Examples:
-//   "Zenith Dev" + "AdministratorAccess" → "zenith-dev"
-//   "Zenith (QA)" + "AdministratorAccess" → "zenith-qa"
-//   "Zenith Dev" + "ZenithDevRDSAdminAccess" → "zenith-dev-rds-admin"
-//   "Zenith Live" + "ZenithLiveRDSReadOnlyAccess" → "zenith-live-rds-readonly"
-func (sm *SetupManager) buildProfileName(accountName, roleName string) string {
-	// Normalize account name: "Zenith (QA)" → "zenith-qa", "Zenith Dev" → "zenith-dev"
-	name := strings.ToLower(accountName)
-	name = strings.ReplaceAll(name, "(", "")
-	name = strings.ReplaceAll(name, ")", "")
-	name = strings.TrimSpace(name)
-	name = strings.ReplaceAll(name, " ", "-")
-	name = strings.ReplaceAll(name, "_", "-")
-	// Collapse multiple dashes
-	for strings.Contains(name, "--") {
-		name = strings.ReplaceAll(name, "--", "-")
-	}
-	name = strings.Trim(name, "-")
-
-	// For the default admin role, just use the account name
-	roleLower := strings.ToLower(roleName)
-	if roleLower == "administratoraccess" || roleLower == "admin" {
-		return name
-	}
-
-	// For other roles, build a clean suffix
-	// Strip the account-specific prefix from role names
-	// e.g. "ZenithDevRDSAdminAccess" → "rds-admin"
-	// e.g. "ZenithQARDSReadOnlyAccess" → "rds-readonly"
-	roleSuffix := sm.cleanRoleSuffix(accountName, roleName)
-	if roleSuffix != "" {
-		return name + "-" + roleSuffix
-	}
-
-	return name + "-" + strings.ToLower(roleName)
+// mergeAWSConfig adds the sso-session and discovered profiles to
+// ~/.aws/config, replacing sections of the same name and keeping every
+// other section, [default] included.
+func (sm *SetupManager) mergeAWSConfig(sessionName, startURL, ssoRegion string, profiles []Profile) error {
+	cm, err := NewConfigManager()
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(cm.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	sections := []string{fmt.Sprintf("[sso-session %s]\nsso_start_url = %s\nsso_region = %s\nsso_registration_scopes = sso:account:access\n",
+		sessionName, startURL, ssoRegion)}
+	for _, p := range profiles {
+		sections = append(sections, fmt.Sprintf("[profile %s]\nsso_session = %s\nsso_account_id = %s\nsso_role_name = %s\nregion = %s\noutput = json\n",
+			p.Name, sessionName, p.SSOAccountID, p.SSORoleName, p.Region))
+	}
+
+	merged := []byte(mergeConfigSections(string(existing), sections))
+	if err := utils.WriteFileAtomic(cm.configPath, merged, 0600); err != nil {
+		return err
+	}
+	return recordConfigWrite(cm.configPath, merged)
 }
 
-// cleanRoleSuffix strips the account-specific prefix from a role name
-// and converts to kebab-case.
-func (sm *SetupManager) cleanRoleSuffix(accountName, roleName string) string {
-	// Build possible prefixes to strip: "ZenithDev", "ZenithQA", "Zenith", etc.
-	words := strings.Fields(accountName)
-	prefixes := []string{}
-
-	// Try full account name without spaces: "ZenithDev", "ZenithQA"
-	joined := ""
-	for _, w := range words {
-		// Skip parenthesized words for prefix building
-		w = strings.Trim(w, "()")
-		joined += w
-	}
-	prefixes = append(prefixes, joined)
-
-	// Try just the first word: "Zenith"
-	if len(words) > 0 {
-		prefixes = append(prefixes, words[0])
-	}
-
-	// Strip the prefix (case-insensitive)
-	role := roleName
-	for _, prefix := range prefixes {
-		if len(role) > len(prefix) && strings.EqualFold(role[:len(prefix)], prefix) {
-			role = role[len(prefix):]
-			break
+// mergeConfigSections replaces each section of content whose header matches
+// one of sections (each a header line and its settings), and appends the
+// sections that aren't there yet
+func mergeConfigSections(content string, sections []string) string {
+	replacement := make(map[string]string, len(sections))
+	var order []string
+	for _, section := range sections {
+		header, _, _ := strings.Cut(section, "\n")
+		key := strings.Join(strings.Fields(header), " ")
+		if _, ok := replacement[key]; !ok {
+			order = append(order, key)
 		}
+		replacement[key] = section
 	}
 
-	// Strip common suffixes
-	role = strings.TrimSuffix(role, "Access")
-
-	// Convert CamelCase to kebab-case
-	result := camelToKebab(role)
-	result = strings.Trim(result, "-")
+	var sb strings.Builder
+	skipping := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			key := strings.Join(strings.Fields(trimmed), " ")
+			section, ok := replacement[key]
+			skipping = ok
+			if ok {
+				sb.WriteString(section)
+				sb.WriteString("\n")
+				delete(replacement, key)
+				continue
+			}
+		}
+		if skipping || (line == "" && sb.Len() == 0) {
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
 
-	return result
+	for _, key := range order {
+		section, ok := replacement[key]
+		if !ok {
+			continue
+		}
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(section)
+	}
+	return sb.String()
 }
 
-// camelToKebab converts CamelCase to kebab-case.
-func camelToKebab(s string) string {
-	var result strings.Builder
-	for i, r := range s {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result.WriteByte('-')
-		}
-		result.WriteRune(r)
+// newProfileNamer creates a ProfileNamer for the configured naming template,
+// pre-loaded with the profile names already stored in the database.
+func (sm *SetupManager) newProfileNamer() *ProfileNamer {
+	namer := NewProfileNamer(config.Get().ProfileNameTemplate)
+	if sm.dbRepo == nil {
+		return namer
+	}
+
+	accounts, err := sm.dbRepo.GetAllAWSAccounts()
+	if err != nil {
+		return namer
+	}
+	accountIDs := make(map[int]string, len(accounts))
+	for _, acc := range accounts {
+		accountIDs[acc.ID] = acc.AccountID
+	}
+
+	roles, err := sm.dbRepo.GetAllAWSRoles()
+	if err != nil {
+		return namer
 	}
-	return strings.ToLower(result.String())
+	for _, role := range roles {
+		namer.Reserve(role.ProfileName, accountIDs[role.AccountID], role.RoleName)
+	}
+
+	return namer
 }
 
 // extractEnvFromCluster extracts the environment name from a cluster name.
 // e.g. "dev-zenith-eks-cluster" → "dev"
 func (sm *SetupManager) extractEnvFromCluster(clusterName string) string {
+	return envFromClusterName(clusterName)
+}
+
+// envFromClusterName implements extractEnvFromCluster for callers without a
+// SetupManager.
+func envFromClusterName(clusterName string) string {
 	cfg := config.Get()
 	suffix := fmt.Sprintf("-%s-eks-cluster", cfg.Project)
 	if strings.HasSuffix(clusterName, suffix) {
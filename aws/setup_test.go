@@ -0,0 +1,49 @@
+package aws
+
+import "testing"
+
+func TestMergeConfigSections(t *testing.T) {
+	existing := `[default]
+region = eu-west-2
+
+[profile static]
+aws_access_key_id = AKIAEXAMPLE
+
+[profile zenith-dev]
+sso_session = old
+sso_role_name = Old
+
+[profile chained]
+role_arn = arn:aws:iam::000000000000:role/Admin
+source_profile = static
+`
+	got := mergeConfigSections(existing, []string{
+		"[sso-session zenith]\nsso_start_url = https://zenith.awsapps.com/start\n",
+		"[profile zenith-dev]\nsso_session = zenith\nsso_role_name = Admin\n",
+	})
+
+	want := `[default]
+region = eu-west-2
+
+[profile static]
+aws_access_key_id = AKIAEXAMPLE
+
+[profile zenith-dev]
+sso_session = zenith
+sso_role_name = Admin
+
+[profile chained]
+role_arn = arn:aws:iam::000000000000:role/Admin
+source_profile = static
+
+[sso-session zenith]
+sso_start_url = https://zenith.awsapps.com/start
+`
+	if got != want {
+		t.Errorf("mergeConfigSections() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := mergeConfigSections("", []string{"[profile a]\nregion = eu-west-2\n"}); got != "[profile a]\nregion = eu-west-2\n" {
+		t.Errorf("mergeConfigSections() into an empty file = %q", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"rolewalkers/internal/awscli"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 	"strings"
 )
 
@@ -31,6 +32,7 @@ func NewSSMManagerWithRepo(repo *db.ConfigRepository) *SSMManager {
 // ssmResponse represents the AWS SSM get-parameter response
 type ssmResponse struct {
 	Parameter struct {
+		Type  string `json:"Type"`
 		Value string `json:"Value"`
 	} `json:"Parameter"`
 }
@@ -49,7 +51,7 @@ func (sm *SSMManager) GetParameter(name string) (string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to get SSM parameter %s: %w: %s", name, err, stderr.String())
+		return "", fmt.Errorf("failed to get SSM parameter %s: %w: %s", name, err, utils.Redact(stderr.String()))
 	}
 
 	var resp ssmResponse
@@ -61,9 +63,19 @@ func (sm *SSMManager) GetParameter(name string) (string, error) {
 		return "", fmt.Errorf("SSM parameter %s exists but has empty value", name)
 	}
 
+	trackSSMSecret(name, resp.Parameter.Type, resp.Parameter.Value)
+
 	return resp.Parameter.Value, nil
 }
 
+// trackSSMSecret registers a parameter value for redaction when it is a
+// SecureString or its name looks like a secret
+func trackSSMSecret(name, paramType, value string) {
+	if paramType == "SecureString" || utils.IsSecretPath(name) {
+		utils.TrackSecret(value)
+	}
+}
+
 // GetEndpoint retrieves a service endpoint from SSM for a given environment
 func (sm *SSMManager) GetEndpoint(env, service string) (string, error) {
 	// Map service names to SSM parameter paths
@@ -122,19 +134,22 @@ func (sm *SSMManager) GetDatabaseEndpoint(env, nodeType, dbType string) (string,
 	return sm.GetParameter(paramPath)
 }
 
-// ssmListResponse represents the AWS SSM get-parameters-by-path response
-type ssmListResponse struct {
+// ssmPathResponse is a page of the get-parameters-by-path response
+type ssmPathResponse struct {
 	Parameters []struct {
-		Name string `json:"Name"`
-		Type string `json:"Type"`
+		Name  string `json:"Name"`
+		Type  string `json:"Type"`
+		Value string `json:"Value"`
 	} `json:"Parameters"`
 }
 
-// ListParameters lists all parameters under a given path prefix
-func (sm *SSMManager) ListParameters(prefix string) ([]string, error) {
+// GetParametersByPath returns the names and values of all parameters under
+// a path prefix. SecureString and secret-path values are tracked for redaction.
+func (sm *SSMManager) GetParametersByPath(prefix string) (map[string]string, error) {
 	cmd := awscli.CreateCommand("ssm", "get-parameters-by-path",
 		"--path", prefix,
 		"--recursive",
+		"--with-decryption",
 		"--region", sm.region,
 	)
 
@@ -144,18 +159,97 @@ func (sm *SSMManager) ListParameters(prefix string) ([]string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to list SSM parameters at %s: %w: %s", prefix, err, stderr.String())
+		return nil, fmt.Errorf("failed to get SSM parameters at %s: %w: %s", prefix, err, utils.Redact(stderr.String()))
 	}
 
-	var resp ssmListResponse
+	var resp ssmPathResponse
 	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
 		return nil, fmt.Errorf("failed to parse SSM response: %w", err)
 	}
 
-	names := make([]string, len(resp.Parameters))
-	for i, p := range resp.Parameters {
-		names[i] = p.Name
+	values := make(map[string]string, len(resp.Parameters))
+	for _, p := range resp.Parameters {
+		trackSSMSecret(p.Name, p.Type, p.Value)
+		values[p.Name] = p.Value
+	}
+	return values, nil
+}
+
+// ssmParameterMeta is the type and KMS key of an existing parameter
+type ssmParameterMeta struct {
+	Type  string `json:"Type"`
+	KeyID string `json:"KeyId"`
+}
+
+// describeParameter returns the type and KMS key of a parameter, or nil
+// when it doesn't exist
+func (sm *SSMManager) describeParameter(name string) (*ssmParameterMeta, error) {
+	cmd := awscli.CreateCommand("ssm", "describe-parameters",
+		"--parameter-filters", "Key=Name,Option=Equals,Values="+name,
+		"--query", "Parameters[0].{Type: Type, KeyId: KeyId}",
+		"--output", "json",
+		"--region", sm.region,
+	)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to describe SSM parameter %s: %w: %s", name, err, utils.Redact(stderr.String()))
+	}
+
+	var meta *ssmParameterMeta
+	if err := json.Unmarshal(out.Bytes(), &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse SSM response: %w", err)
+	}
+	if meta == nil || meta.Type == "" {
+		return nil, nil
+	}
+	return meta, nil
+}
+
+// putParameterArgs returns the put-parameter arguments, keeping an existing
+// parameter's type and KMS key so a SecureString stays encrypted. New
+// parameters are created as String.
+func putParameterArgs(name, value, region string, existing *ssmParameterMeta) []string {
+	paramType := "String"
+	if existing != nil {
+		paramType = existing.Type
+	}
+	args := []string{"ssm", "put-parameter",
+		"--name", name,
+		"--value", value,
+		"--type", paramType,
+		"--overwrite",
+		"--region", region,
+	}
+	if paramType == "SecureString" && existing.KeyID != "" {
+		args = append(args, "--key-id", existing.KeyID)
+	}
+	return args
+}
+
+// PutParameter writes a parameter, creating it as a String or overwriting
+// the current value with the parameter's existing type and KMS key
+func (sm *SSMManager) PutParameter(name, value string) error {
+	existing, err := sm.describeParameter(name)
+	if err != nil {
+		return err
+	}
+	cmd := awscli.CreateCommand(putParameterArgs(name, value, sm.region, existing)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to put SSM parameter %s: %w: %s", name, err, utils.Redact(stderr.String()))
 	}
+	return nil
+}
 
-	return names, nil
+// IsParameterNotFound reports whether an SSM error is for a missing parameter
+func IsParameterNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "ParameterNotFound")
 }
@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+	"sort"
+	"strings"
+)
+
+// SSMParameterInfo describes a parameter without its value
+type SSMParameterInfo struct {
+	Name string `json:"Name"`
+	Type string `json:"Type"`
+}
+
+// IsSecure reports whether the parameter is a SecureString
+func (p SSMParameterInfo) IsSecure() bool {
+	return p.Type == "SecureString"
+}
+
+// ListParameterInfo lists parameter names and types under a path without
+// fetching or decrypting any values.
+func (sm *SSMManager) ListParameterInfo(prefix string) ([]SSMParameterInfo, error) {
+	cmd := awscli.CreateCommand("ssm", "describe-parameters",
+		"--parameter-filters", fmt.Sprintf("Key=Path,Option=Recursive,Values=%s", strings.TrimSuffix(prefix, "/")+"/"),
+		"--query", "Parameters[].{Name:Name,Type:Type}",
+		"--output", "json",
+		"--region", sm.region,
+	)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to list SSM parameters at %s: %w: %s", prefix, err, utils.Redact(stderr.String()))
+	}
+
+	var params []SSMParameterInfo
+	if err := json.Unmarshal(out.Bytes(), &params); err != nil {
+		return nil, fmt.Errorf("failed to parse SSM response: %w", err)
+	}
+	return params, nil
+}
+
+// SSMTree lazily loads parameter names for the browser. A path is fetched
+// the first time it (or an ancestor) is opened and served from cache after.
+type SSMTree struct {
+	ssm    EndpointResolver
+	loaded map[string][]SSMParameterInfo
+}
+
+// NewSSMTree creates a tree browser backed by an SSM manager
+func NewSSMTree(sm EndpointResolver) *SSMTree {
+	return &SSMTree{ssm: sm, loaded: make(map[string][]SSMParameterInfo)}
+}
+
+// Children returns the folders and parameters directly under prefix
+func (t *SSMTree) Children(prefix string) (folders []string, params []SSMParameterInfo, err error) {
+	prefix = NormalizeSSMFolder(prefix)
+
+	all, ok := t.cached(prefix)
+	if !ok {
+		all, err = t.ssm.ListParameterInfo(prefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		t.loaded[prefix] = all
+	}
+
+	folders, params = SSMChildren(all, prefix)
+	return folders, params, nil
+}
+
+// Refresh drops cached listings so the next Children call refetches
+func (t *SSMTree) Refresh() {
+	t.loaded = make(map[string][]SSMParameterInfo)
+}
+
+// cached returns parameters under prefix from the nearest loaded ancestor
+func (t *SSMTree) cached(prefix string) ([]SSMParameterInfo, bool) {
+	for loadedPrefix, params := range t.loaded {
+		if strings.HasPrefix(prefix, loadedPrefix) {
+			var under []SSMParameterInfo
+			for _, p := range params {
+				if strings.HasPrefix(p.Name, prefix) {
+					under = append(under, p)
+				}
+			}
+			return under, true
+		}
+	}
+	return nil, false
+}
+
+// SSMChildren groups parameters under prefix into immediate subfolders
+// (with a trailing "/") and parameters at that level, both sorted.
+func SSMChildren(params []SSMParameterInfo, prefix string) ([]string, []SSMParameterInfo) {
+	seen := make(map[string]bool)
+	var folders []string
+	var leaves []SSMParameterInfo
+
+	for _, p := range params {
+		rest, ok := strings.CutPrefix(p.Name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if dir, _, isFolder := strings.Cut(rest, "/"); isFolder {
+			if !seen[dir] {
+				seen[dir] = true
+				folders = append(folders, dir+"/")
+			}
+			continue
+		}
+		leaves = append(leaves, p)
+	}
+
+	sort.Strings(folders)
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].Name < leaves[j].Name })
+	return folders, leaves
+}
+
+// NormalizeSSMFolder returns prefix with leading and trailing slashes
+func NormalizeSSMFolder(prefix string) string {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix != "/" {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// ParentSSMFolder returns the folder above prefix ("/" at the top)
+func ParentSSMFolder(prefix string) string {
+	trimmed := strings.TrimSuffix(NormalizeSSMFolder(prefix), "/")
+	if i := strings.LastIndex(trimmed, "/"); i > 0 {
+		return trimmed[:i+1]
+	}
+	return "/"
+}
+
+// SSMEnvironmentOf returns the environment whose SSM prefix path starts with,
+// and the subpath below that prefix.
+func SSMEnvironmentOf(path string, envs []string) (env, subpath string, ok bool) {
+	cfg := config.Get()
+	for _, e := range envs {
+		if sub, found := strings.CutPrefix(path, cfg.SSMPath(e, "")); found {
+			return e, sub, true
+		}
+	}
+	return "", "", false
+}
+
+// SwitchSSMEnvironment maps path to the same subpath in another environment
+func SwitchSSMEnvironment(path string, envs []string, target string) (string, error) {
+	_, sub, ok := SSMEnvironmentOf(path, envs)
+	if !ok {
+		return "", fmt.Errorf("%s is not under a known environment prefix", path)
+	}
+	return config.Get().SSMPath(target, sub), nil
+}
@@ -0,0 +1,57 @@
+package aws
+
+import "testing"
+
+func TestSSMChildren(t *testing.T) {
+	params := []SSMParameterInfo{
+		{Name: "/dev/zenith/database/query/db-read-endpoint", Type: "String"},
+		{Name: "/dev/zenith/database/query/db-zenithmaster-password", Type: "SecureString"},
+		{Name: "/dev/zenith/redis/cluster-endpoint", Type: "String"},
+		{Name: "/dev/zenith/api-url", Type: "String"},
+	}
+
+	folders, leaves := SSMChildren(params, "/dev/zenith/")
+	if len(folders) != 2 || folders[0] != "database/" || folders[1] != "redis/" {
+		t.Errorf("folders = %v, want [database/ redis/]", folders)
+	}
+	if len(leaves) != 1 || leaves[0].Name != "/dev/zenith/api-url" {
+		t.Errorf("leaves = %v, want [/dev/zenith/api-url]", leaves)
+	}
+
+	folders, leaves = SSMChildren(params, "/dev/zenith/database/query/")
+	if len(folders) != 0 || len(leaves) != 2 || !leaves[1].IsSecure() {
+		t.Errorf("SSMChildren(query/) = %v, %v", folders, leaves)
+	}
+}
+
+func TestSSMFolders(t *testing.T) {
+	tests := []struct {
+		in, normalized, parent string
+	}{
+		{"", "/", "/"},
+		{"/dev/zenith", "/dev/zenith/", "/dev/"},
+		{"dev/zenith/database/", "/dev/zenith/database/", "/dev/zenith/"},
+		{"/dev/", "/dev/", "/"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSSMFolder(tt.in); got != tt.normalized {
+			t.Errorf("NormalizeSSMFolder(%q) = %q, want %q", tt.in, got, tt.normalized)
+		}
+		if got := ParentSSMFolder(tt.in); got != tt.parent {
+			t.Errorf("ParentSSMFolder(%q) = %q, want %q", tt.in, got, tt.parent)
+		}
+	}
+}
+
+func TestSwitchSSMEnvironment(t *testing.T) {
+	envs := []string{"dev", "dev2", "prod"}
+
+	got, err := SwitchSSMEnvironment("/dev2/zenith/database/query/db-read-endpoint", envs, "prod")
+	if err != nil || got != "/prod/zenith/database/query/db-read-endpoint" {
+		t.Errorf("SwitchSSMEnvironment() = %q, %v", got, err)
+	}
+
+	if _, err := SwitchSSMEnvironment("/shared/thing", envs, "prod"); err == nil {
+		t.Error("SwitchSSMEnvironment() expected error for path outside environment prefixes")
+	}
+}
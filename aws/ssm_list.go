@@ -0,0 +1,201 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ssmListPageSize is how many parameters one aws CLI call returns; the
+	// CLI makes as many API calls as it needs to fill a page
+	ssmListPageSize = 500
+
+	// ssmGetParametersBatch is the most names get-parameters accepts
+	ssmGetParametersBatch = 10
+
+	// DefaultSSMValueConcurrency is how many get-parameters calls run at
+	// once when listing values
+	DefaultSSMValueConcurrency = 8
+)
+
+// ssmPageResponse is one page of get-parameters-by-path
+type ssmPageResponse struct {
+	Parameters []SSMParameterInfo `json:"Parameters"`
+	NextToken  string             `json:"NextToken"`
+}
+
+// ListParameterPages lists the parameters directly under prefix, or with
+// recursive every parameter below it, calling page for each page as it
+// arrives. Prefixes with thousands of parameters are fetched a page at a
+// time instead of in a single call.
+func (sm *SSMManager) ListParameterPages(prefix string, recursive bool, page func([]SSMParameterInfo) error) error {
+	token := ""
+	for {
+		args := []string{"ssm", "get-parameters-by-path",
+			"--path", prefix,
+			"--max-items", strconv.Itoa(ssmListPageSize),
+			"--query", "{Parameters: Parameters[].{Name: Name, Type: Type}, NextToken: NextToken}",
+			"--output", "json",
+			"--region", sm.region,
+		}
+		if recursive {
+			args = append(args, "--recursive")
+		}
+		if token != "" {
+			args = append(args, "--starting-token", token)
+		}
+		cmd := awscli.CreateCommand(args...)
+
+		var out bytes.Buffer
+		var stderr bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to list SSM parameters at %s: %w: %s", prefix, err, utils.Redact(stderr.String()))
+		}
+
+		var resp ssmPageResponse
+		if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+			return fmt.Errorf("failed to parse SSM response: %w", err)
+		}
+		if err := page(resp.Parameters); err != nil {
+			return err
+		}
+		if resp.NextToken == "" {
+			return nil
+		}
+		token = resp.NextToken
+	}
+}
+
+// ssmValuesResponse is the get-parameters response
+type ssmValuesResponse struct {
+	Parameters []struct {
+		Name  string `json:"Name"`
+		Type  string `json:"Type"`
+		Value string `json:"Value"`
+	} `json:"Parameters"`
+}
+
+// GetParameterValues fetches the decrypted values of names, batching them
+// into get-parameters calls with at most concurrency calls in flight.
+// SecureString and secret-path values are tracked for redaction.
+func (sm *SSMManager) GetParameterValues(names []string, concurrency int) (map[string]string, error) {
+	values := make(map[string]string, len(names))
+	var mu sync.Mutex
+	err := runBatches(names, ssmGetParametersBatch, concurrency, func(batch []string) error {
+		args := append([]string{"ssm", "get-parameters", "--with-decryption", "--region", sm.region, "--names"}, batch...)
+		cmd := awscli.CreateCommand(args...)
+
+		var out bytes.Buffer
+		var stderr bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to get SSM parameters: %w: %s", err, utils.Redact(stderr.String()))
+		}
+
+		var resp ssmValuesResponse
+		if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+			return fmt.Errorf("failed to parse SSM response: %w", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range resp.Parameters {
+			trackSSMSecret(p.Name, p.Type, p.Value)
+			values[p.Name] = p.Value
+		}
+		return nil
+	})
+	return values, err
+}
+
+// runBatches splits items into batches of size and calls fetch for each
+// from a pool of concurrency workers. It returns the first error; batches
+// not yet started are skipped after one fails.
+func runBatches(items []string, size, concurrency int, fetch func([]string) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	batches := make(chan []string)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+				if err := fetch(batch); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for start := 0; start < len(items); start += size {
+		batches <- items[start:min(start+size, len(items))]
+	}
+	close(batches)
+	wg.Wait()
+	return firstErr
+}
+
+// SSMListFilter selects which parameters 'rw ssm list' shows
+type SSMListFilter struct {
+	// MaxDepth limits how many levels below the prefix are shown; 0 is
+	// unlimited, 1 is only the parameters directly under it
+	MaxDepth int
+	// Contains keeps names containing this substring, ignoring case
+	Contains string
+}
+
+// Match reports whether a parameter under prefix passes the filter
+func (f SSMListFilter) Match(prefix string, p SSMParameterInfo) bool {
+	rest, ok := strings.CutPrefix(p.Name, NormalizeSSMFolder(prefix))
+	if !ok {
+		// The parameter is the prefix itself
+		rest = p.Name
+	}
+	if f.MaxDepth > 0 && strings.Count(rest, "/")+1 > f.MaxDepth {
+		return false
+	}
+	return f.Contains == "" || strings.Contains(strings.ToLower(p.Name), strings.ToLower(f.Contains))
+}
+
+// ListParameters lists all parameters under a given path prefix
+func (sm *SSMManager) ListParameters(prefix string) ([]string, error) {
+	var names []string
+	err := sm.ListParameterPages(prefix, true, func(page []SSMParameterInfo) error {
+		for _, p := range page {
+			names = append(names, p.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSSMListFilter(t *testing.T) {
+	params := []SSMParameterInfo{
+		{Name: "/dev/zenith/api-url"},
+		{Name: "/dev/zenith/redis/cluster-endpoint"},
+		{Name: "/dev/zenith/database/query/db-read-endpoint"},
+	}
+
+	tests := []struct {
+		filter SSMListFilter
+		want   int
+	}{
+		{SSMListFilter{}, 3},
+		{SSMListFilter{MaxDepth: 1}, 1},
+		{SSMListFilter{MaxDepth: 2}, 2},
+		{SSMListFilter{Contains: "ENDPOINT"}, 2},
+		{SSMListFilter{MaxDepth: 2, Contains: "endpoint"}, 1},
+	}
+	for _, tt := range tests {
+		got := 0
+		for _, p := range params {
+			if tt.filter.Match("/dev/zenith", p) {
+				got++
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%+v matched %d parameters, want %d", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestRunBatches(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}
+
+	var calls, seen atomic.Int32
+	err := runBatches(items, 3, 2, func(batch []string) error {
+		calls.Add(1)
+		seen.Add(int32(len(batch)))
+		return nil
+	})
+	if err != nil || calls.Load() != 4 || seen.Load() != int32(len(items)) {
+		t.Errorf("runBatches() = %v after %d calls for %d items, want 4 calls for %d", err, calls.Load(), seen.Load(), len(items))
+	}
+
+	failure := errors.New("throttled")
+	err = runBatches(items, 1, 1, func(batch []string) error {
+		if batch[0] == "c" {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("runBatches() = %v, want the batch's error", err)
+	}
+}
@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"rolewalkers/internal/utils"
+	"slices"
+	"testing"
+)
+
+func TestPutParameterArgsKeepsType(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *ssmParameterMeta
+		wantType string
+		wantKey  string
+	}{
+		{"new parameter", nil, "String", ""},
+		{"string", &ssmParameterMeta{Type: "String"}, "String", ""},
+		{"secure string", &ssmParameterMeta{Type: "SecureString", KeyID: "alias/zenith"}, "SecureString", "alias/zenith"},
+		{"secure string, default key", &ssmParameterMeta{Type: "SecureString"}, "SecureString", ""},
+	}
+	for _, tt := range tests {
+		args := putParameterArgs("/dev/zenith/db-password", "s3cret", "eu-west-2", tt.existing)
+
+		i := slices.Index(args, "--type")
+		if i < 0 || args[i+1] != tt.wantType {
+			t.Errorf("%s: args = %v, want --type %s", tt.name, args, tt.wantType)
+		}
+		k := slices.Index(args, "--key-id")
+		switch {
+		case tt.wantKey == "" && k >= 0:
+			t.Errorf("%s: args = %v, want no --key-id", tt.name, args)
+		case tt.wantKey != "" && (k < 0 || args[k+1] != tt.wantKey):
+			t.Errorf("%s: args = %v, want --key-id %s", tt.name, args, tt.wantKey)
+		}
+	}
+}
+
+func TestTrackSSMSecret(t *testing.T) {
+	trackSSMSecret("/dev/zenith/stripe/webhook", "SecureString", "whsec-4f1c9e")
+	trackSSMSecret("/dev/zenith/jwt-secret", "String", "jwt-8d2b7a")
+	trackSSMSecret("/dev/zenith/redis/cluster-endpoint", "String", "redis.internal")
+
+	got := utils.Redact("whsec-4f1c9e jwt-8d2b7a redis.internal")
+	if want := "******** ******** redis.internal"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"rolewalkers/internal/utils"
 	"runtime"
 	"strings"
 	"time"
@@ -49,8 +50,14 @@ func NewSSOManager(cm *ConfigManager) (*SSOManager, error) {
 	}, nil
 }
 
-// Login initiates SSO login for a profile
+// Login initiates SSO login for a profile. On headless machines (SSH
+// sessions, no display) it falls back to the device code flow.
 func (sm *SSOManager) Login(profileName string) error {
+	return sm.LoginWithOptions(profileName, LoginOptions{NoBrowser: utils.IsHeadless()})
+}
+
+// LoginWithOptions initiates SSO login for a profile
+func (sm *SSOManager) LoginWithOptions(profileName string, opts LoginOptions) error {
 	profiles, err := sm.configManager.GetProfiles()
 	if err != nil {
 		return err
@@ -65,6 +72,15 @@ func (sm *SSOManager) Login(profileName string) error {
 		return fmt.Errorf("profile '%s' is not an SSO profile", profileName)
 	}
 
+	if opts.NoBrowser {
+		return sm.loginWithDeviceCode(profile, opts.ShowDeviceCode)
+	}
+
+	// Start from the IdP portal (Azure AD, Okta, ...) when one is configured
+	if idpURL := IdPLoginURL(profile); idpURL != "" {
+		return sm.loginViaIdP(profile, idpURL)
+	}
+
 	// Use AWS CLI for SSO login
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -139,6 +155,9 @@ func (sm *SSOManager) findCachedToken(cacheKey string) (*SSOCache, error) {
 // readCacheFile reads and validates a single SSO cache file by its hash name.
 func (sm *SSOManager) readCacheFile(hashName string) (*SSOCache, error) {
 	cacheFile := filepath.Join(sm.cacheDir, hashName+".json")
+	if err := utils.CheckOwnership(cacheFile); err != nil {
+		return nil, err
+	}
 
 	data, err := os.ReadFile(cacheFile)
 	if err != nil {
@@ -204,13 +223,11 @@ func (sm *SSOManager) Logout(profileName string) error {
 
 // GetSSOProfiles returns only SSO-enabled profiles
 func (sm *SSOManager) GetSSOProfiles() ([]Profile, error) {
-	profiles, err := sm.configManager.GetProfiles()
-	if err != nil {
-		return nil, err
-	}
-
 	ssoProfiles := make([]Profile, 0)
-	for _, p := range profiles {
+	for p, err := range sm.configManager.Profiles() {
+		if err != nil {
+			return nil, err
+		}
 		if p.IsSSO {
 			ssoProfiles = append(ssoProfiles, p)
 		}
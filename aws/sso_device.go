@@ -0,0 +1,327 @@
+package aws
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rolewalkers/internal/awsarn"
+	"rolewalkers/internal/utils"
+)
+
+const (
+	// deviceGrantType is the OAuth grant for the device authorization flow
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// deviceClientName is the client name registered with SSO OIDC
+	deviceClientName = "rolewalkers"
+
+	// defaultDeviceInterval is how often the token endpoint is polled when
+	// the authorization doesn't say
+	defaultDeviceInterval = 5 * time.Second
+
+	// deviceLoginTimeout bounds a device login; codes usually expire sooner
+	deviceLoginTimeout = 15 * time.Minute
+)
+
+// ssoAccessScope is the scope granting access to SSO accounts and roles
+var ssoAccessScope = []string{"sso:account:access"}
+
+// DeviceAuthorization is what the user needs to approve a device login:
+// a URL to open on any device and the code to confirm there
+type DeviceAuthorization struct {
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresAt               time.Time
+}
+
+// URL returns the verification URL with the code filled in, if the
+// service provided one
+func (d DeviceAuthorization) URL() string {
+	return cmp.Or(d.VerificationURIComplete, d.VerificationURI)
+}
+
+// LoginOptions controls how Login authenticates
+type LoginOptions struct {
+	// NoBrowser skips the AWS CLI and its browser: rw runs the SSO OIDC
+	// device flow itself and shows the URL and code to open elsewhere
+	NoBrowser bool
+	// ShowDeviceCode is called with the device authorization once it has
+	// been issued. Nil prints the URL and code.
+	ShowDeviceCode func(DeviceAuthorization)
+}
+
+// ssoDeviceToken is the result of a completed device authorization
+type ssoDeviceToken struct {
+	AccessToken           string
+	RefreshToken          string
+	ExpiresAt             time.Time
+	ClientID              string
+	ClientSecret          string
+	RegistrationExpiresAt time.Time
+}
+
+// ssoTokenCacheFile is a token in the AWS CLI's SSO cache format, so the
+// AWS CLI and SDKs pick up a login done by rw
+type ssoTokenCacheFile struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// loginWithDeviceCode runs the SSO OIDC device authorization flow: it
+// registers a client, shows the verification URL and code, polls until the
+// user approves, and writes the token to the AWS CLI's SSO cache
+func (sm *SSOManager) loginWithDeviceCode(profile *Profile, show func(DeviceAuthorization)) error {
+	if profile.SSOStartURL == "" || profile.SSORegion == "" {
+		return fmt.Errorf("profile '%s' has no sso_start_url or sso_region", profile.Name)
+	}
+	if show == nil {
+		show = printDeviceAuthorization
+	}
+	if idpURL := IdPLoginURL(profile); idpURL != "" {
+		fmt.Printf("Sign in to your identity provider first: %s\n", idpURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deviceLoginTimeout)
+	defer cancel()
+
+	token, err := newSSOOIDCClient(profile.SSORegion).deviceLogin(ctx, profile.SSOStartURL, show)
+	if err != nil {
+		return err
+	}
+	return sm.writeCachedToken(ssoCacheKey(profile), profile, token)
+}
+
+// printDeviceAuthorization is the default ShowDeviceCode
+func printDeviceAuthorization(auth DeviceAuthorization) {
+	fmt.Printf("Open this URL on any device and confirm the code:\n  %s\n  Code: %s\n", auth.URL(), auth.UserCode)
+}
+
+// writeCachedToken stores a device login token where the AWS CLI looks
+// for it: SHA1 of the sso-session name, or of the start URL
+func (sm *SSOManager) writeCachedToken(cacheKey string, profile *Profile, token *ssoDeviceToken) error {
+	entry := ssoTokenCacheFile{
+		StartURL:     profile.SSOStartURL,
+		Region:       profile.SSORegion,
+		AccessToken:  token.AccessToken,
+		ExpiresAt:    token.ExpiresAt.UTC().Format(time.RFC3339),
+		ClientID:     token.ClientID,
+		ClientSecret: token.ClientSecret,
+		RefreshToken: token.RefreshToken,
+	}
+	if !token.RegistrationExpiresAt.IsZero() {
+		entry.RegistrationExpiresAt = token.RegistrationExpiresAt.UTC().Format(time.RFC3339)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(sm.cacheDir, 0700); err != nil {
+		return fmt.Errorf("failed to create SSO cache directory: %w", err)
+	}
+	return utils.WriteFileAtomic(filepath.Join(sm.cacheDir, sha1Hex(cacheKey)+".json"), data, 0600)
+}
+
+// ssoOIDCClient calls the SSO OIDC API. Its device flow operations are
+// unauthenticated, so no request signing is needed.
+type ssoOIDCClient struct {
+	endpoint string
+	client   *http.Client
+	// pollInterval overrides the interval the service asks for (tests)
+	pollInterval time.Duration
+}
+
+func newSSOOIDCClient(region string) *ssoOIDCClient {
+	domain := "amazonaws.com"
+	if awsarn.PartitionForRegion(region) == awsarn.PartitionChina {
+		domain = "amazonaws.com.cn"
+	}
+	return &ssoOIDCClient{
+		endpoint: fmt.Sprintf("https://oidc.%s.%s", region, domain),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// oidcError is an error response from SSO OIDC, e.g. authorization_pending
+type oidcError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e *oidcError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("SSO OIDC error %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("SSO OIDC error %s (HTTP %d)", e.Code, e.Status)
+}
+
+// oidcErrorTypes maps the x-amzn-ErrorType header to OAuth error codes
+var oidcErrorTypes = map[string]string{
+	"AuthorizationPendingException": "authorization_pending",
+	"SlowDownException":             "slow_down",
+	"ExpiredTokenException":         "expired_token",
+	"AccessDeniedException":         "access_denied",
+}
+
+// call POSTs a JSON request and decodes the JSON response into out
+func (c *ssoOIDCClient) call(ctx context.Context, path string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SSO OIDC request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+			Message          string `json:"message"`
+		}
+		json.Unmarshal(data, &e)
+		errType, _, _ := strings.Cut(resp.Header.Get("x-amzn-ErrorType"), ":")
+		return &oidcError{
+			Status:      resp.StatusCode,
+			Code:        cmp.Or(e.Error, oidcErrorTypes[errType], errType, "unknown"),
+			Description: cmp.Or(e.ErrorDescription, e.Message),
+		}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse SSO OIDC response: %w", err)
+	}
+	return nil
+}
+
+// deviceLogin registers a client, starts a device authorization for the
+// start URL, and polls for the token until the user approves or the code
+// expires
+func (c *ssoOIDCClient) deviceLogin(ctx context.Context, startURL string, show func(DeviceAuthorization)) (*ssoDeviceToken, error) {
+	var reg struct {
+		ClientID              string `json:"clientId"`
+		ClientSecret          string `json:"clientSecret"`
+		ClientSecretExpiresAt int64  `json:"clientSecretExpiresAt"`
+	}
+	err := c.call(ctx, "/client/register", map[string]any{
+		"clientName": deviceClientName,
+		"clientType": "public",
+		"scopes":     ssoAccessScope,
+	}, &reg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register SSO client: %w", err)
+	}
+
+	var auth struct {
+		DeviceCode              string `json:"deviceCode"`
+		UserCode                string `json:"userCode"`
+		VerificationURI         string `json:"verificationUri"`
+		VerificationURIComplete string `json:"verificationUriComplete"`
+		ExpiresIn               int    `json:"expiresIn"`
+		Interval                int    `json:"interval"`
+	}
+	err = c.call(ctx, "/device_authorization", map[string]string{
+		"clientId":     reg.ClientID,
+		"clientSecret": reg.ClientSecret,
+		"startUrl":     startURL,
+	}, &auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	expiresAt := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	show(DeviceAuthorization{
+		UserCode:                auth.UserCode,
+		VerificationURI:         auth.VerificationURI,
+		VerificationURIComplete: auth.VerificationURIComplete,
+		ExpiresAt:               expiresAt,
+	})
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDeviceInterval
+	}
+	if c.pollInterval > 0 {
+		interval = c.pollInterval
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, expiresAt)
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("device code expired before the login was approved")
+		case <-time.After(interval):
+		}
+
+		var tok struct {
+			AccessToken  string `json:"accessToken"`
+			RefreshToken string `json:"refreshToken"`
+			ExpiresIn    int    `json:"expiresIn"`
+		}
+		err := c.call(ctx, "/token", map[string]string{
+			"clientId":     reg.ClientID,
+			"clientSecret": reg.ClientSecret,
+			"grantType":    deviceGrantType,
+			"deviceCode":   auth.DeviceCode,
+		}, &tok)
+
+		var oe *oidcError
+		switch {
+		case err == nil:
+			token := &ssoDeviceToken{
+				AccessToken:  tok.AccessToken,
+				RefreshToken: tok.RefreshToken,
+				ExpiresAt:    time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second),
+				ClientID:     reg.ClientID,
+				ClientSecret: reg.ClientSecret,
+			}
+			if reg.ClientSecretExpiresAt > 0 {
+				token.RegistrationExpiresAt = time.Unix(reg.ClientSecretExpiresAt, 0)
+			}
+			return token, nil
+		case errors.As(err, &oe) && oe.Code == "authorization_pending":
+		case errors.As(err, &oe) && oe.Code == "slow_down":
+			interval += defaultDeviceInterval
+		case errors.As(err, &oe) && oe.Code == "access_denied":
+			return nil, fmt.Errorf("the login request was denied")
+		case errors.As(err, &oe) && oe.Code == "expired_token":
+			return nil, fmt.Errorf("device code expired before the login was approved")
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("device code expired before the login was approved")
+		default:
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newDeviceTestServer serves the SSO OIDC device flow, answering the first
+// pending token polls with authorization_pending
+func newDeviceTestServer(t *testing.T, pending int, final string) *httptest.Server {
+	t.Helper()
+	polls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/client/register":
+			fmt.Fprint(w, `{"clientId":"cid","clientSecret":"secret","clientSecretExpiresAt":4102444800}`)
+		case "/device_authorization":
+			if body["startUrl"] != "https://zenith.awsapps.com/start" {
+				t.Errorf("startUrl = %v", body["startUrl"])
+			}
+			fmt.Fprint(w, `{"deviceCode":"dev-code","userCode":"ABCD-EFGH","verificationUri":"https://device.sso.eu-west-2.amazonaws.com/","verificationUriComplete":"https://device.sso.eu-west-2.amazonaws.com/?user_code=ABCD-EFGH","expiresIn":600,"interval":5}`)
+		case "/token":
+			if body["deviceCode"] != "dev-code" || body["grantType"] != deviceGrantType {
+				t.Errorf("token request = %v", body)
+			}
+			if polls++; polls <= pending {
+				w.Header().Set("x-amzn-ErrorType", "AuthorizationPendingException:http://internal.amazon.com/coral/com.amazonaws.ssooidc/")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"authorization_pending"}`)
+				return
+			}
+			w.Write([]byte(final))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDeviceLogin(t *testing.T) {
+	srv := newDeviceTestServer(t, 2, `{"accessToken":"token","refreshToken":"refresh","expiresIn":3600}`)
+	defer srv.Close()
+	client := &ssoOIDCClient{endpoint: srv.URL, client: srv.Client(), pollInterval: time.Millisecond}
+
+	var shown DeviceAuthorization
+	token, err := client.deviceLogin(context.Background(), "https://zenith.awsapps.com/start", func(a DeviceAuthorization) { shown = a })
+	if err != nil {
+		t.Fatalf("deviceLogin() error: %v", err)
+	}
+	if shown.UserCode != "ABCD-EFGH" || shown.URL() != "https://device.sso.eu-west-2.amazonaws.com/?user_code=ABCD-EFGH" {
+		t.Errorf("shown authorization = %+v", shown)
+	}
+	if token.AccessToken != "token" || token.RefreshToken != "refresh" || token.ClientID != "cid" {
+		t.Errorf("token = %+v", token)
+	}
+	if d := time.Until(token.ExpiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("token expires in %s, want about 1h", d)
+	}
+
+	// The cached token is found the way the AWS CLI and IsLoggedIn look it up
+	sm := &SSOManager{cacheDir: filepath.Join(t.TempDir(), "cache")}
+	profile := &Profile{Name: "zenith-dev", SSOSession: "zenith", SSOStartURL: "https://zenith.awsapps.com/start", SSORegion: "eu-west-2"}
+	if err := sm.writeCachedToken(ssoCacheKey(profile), profile, token); err != nil {
+		t.Fatalf("writeCachedToken() error: %v", err)
+	}
+	cache, err := sm.findCachedToken("zenith")
+	if err != nil || cache.AccessToken != "token" || cache.Region != "eu-west-2" {
+		t.Errorf("findCachedToken() = %+v, %v", cache, err)
+	}
+	info, err := os.Stat(filepath.Join(sm.cacheDir, sha1Hex("zenith")+".json"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func TestDeviceLoginDenied(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/client/register":
+			fmt.Fprint(w, `{"clientId":"cid","clientSecret":"secret"}`)
+		case "/device_authorization":
+			fmt.Fprint(w, `{"deviceCode":"dev-code","userCode":"ABCD-EFGH","expiresIn":600}`)
+		default:
+			w.Header().Set("x-amzn-ErrorType", "AccessDeniedException")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":"denied"}`)
+		}
+	}))
+	defer srv.Close()
+	client := &ssoOIDCClient{endpoint: srv.URL, client: srv.Client(), pollInterval: time.Millisecond}
+
+	if _, err := client.deviceLogin(context.Background(), "https://zenith.awsapps.com/start", func(DeviceAuthorization) {}); err == nil {
+		t.Error("deviceLogin() should fail when the request is denied")
+	}
+}
+
+func TestSSOOIDCEndpoint(t *testing.T) {
+	if got := newSSOOIDCClient("eu-west-2").endpoint; got != "https://oidc.eu-west-2.amazonaws.com" {
+		t.Errorf("endpoint = %s", got)
+	}
+	if got := newSSOOIDCClient("cn-north-1").endpoint; got != "https://oidc.cn-north-1.amazonaws.com.cn" {
+		t.Errorf("endpoint = %s", got)
+	}
+}
@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+)
+
+// idpLoginTimeout bounds how long 'rw login' waits for an IdP-initiated login
+const idpLoginTimeout = 5 * time.Minute
+
+// IdPLoginURL returns the IdP-initiated login URL configured for a profile
+// (idp_login_urls in config.yaml), keyed by its sso-session name or, for
+// profiles without one, its start URL. It returns "" if none is configured.
+func IdPLoginURL(p *Profile) string {
+	urls := config.Get().IdPLoginURLs
+	if p.SSOSession != "" {
+		if url := urls[p.SSOSession]; url != "" {
+			return url
+		}
+	}
+	return urls[p.SSOStartURL]
+}
+
+// ssoCacheKey returns the key the AWS CLI caches a profile's token under
+func ssoCacheKey(p *Profile) string {
+	if p.SSOSession != "" {
+		return p.SSOSession
+	}
+	return p.SSOStartURL
+}
+
+// loginViaIdP starts 'aws sso login' without its browser, opens the IdP
+// portal instead, and waits until a new token appears in the SSO cache.
+// The device authorization printed by the AWS CLI completes once the user
+// has signed in through the IdP.
+func (sm *SSOManager) loginViaIdP(profile *Profile, idpURL string) error {
+	cacheKey := ssoCacheKey(profile)
+	previous := time.Time{}
+	if cache, err := sm.findCachedToken(cacheKey); err == nil {
+		previous = cache.ExpiresAt
+	}
+
+	cmd := awscli.CreateCommand("sso", "login", "--no-browser", "--profile", profile.Name)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start aws sso login: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	fmt.Printf("Opening your identity provider: %s\n", idpURL)
+	if err := utils.OpenBrowser(idpURL); err != nil {
+		fmt.Fprintf(utils.Stdout, utils.Warn()+" Could not open a browser (%v); open the URL above manually\n", err)
+	}
+	fmt.Println("Sign in there, then approve the AWS device request shown above.")
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	timeout := time.After(idpLoginTimeout)
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+			if !sm.tokenRefreshed(cacheKey, previous) {
+				return fmt.Errorf("aws sso login finished but no new token was cached for %s", profile.Name)
+			}
+			return nil
+		case <-ticker.C:
+			if sm.tokenRefreshed(cacheKey, previous) {
+				// The CLI exits by itself once it has written the token
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					cmd.Process.Kill()
+				}
+				return nil
+			}
+		case <-timeout:
+			cmd.Process.Kill()
+			return fmt.Errorf("timed out after %s waiting for SSO login to complete", idpLoginTimeout)
+		}
+	}
+}
+
+// tokenRefreshed reports whether the cache holds a valid token newer than
+// the one present before login started
+func (sm *SSOManager) tokenRefreshed(cacheKey string, previous time.Time) bool {
+	cache, err := sm.findCachedToken(cacheKey)
+	return err == nil && cache.ExpiresAt.After(previous)
+}
@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rolewalkers/internal/config"
+)
+
+func TestIdPLoginURL(t *testing.T) {
+	cfg := config.Get()
+	saved := cfg.IdPLoginURLs
+	defer func() { cfg.IdPLoginURLs = saved }()
+
+	cfg.IdPLoginURLs = map[string]string{
+		"zenith":                           "https://myapps.microsoft.com/signin/aws/123",
+		"https://legacy.awsapps.com/start": "https://example.okta.com/home/amazon_aws/0oa1",
+	}
+
+	tests := []struct {
+		name     string
+		profile  Profile
+		expected string
+	}{
+		{"session", Profile{SSOSession: "zenith", SSOStartURL: "https://zenith.awsapps.com/start"}, "https://myapps.microsoft.com/signin/aws/123"},
+		{"start url", Profile{SSOStartURL: "https://legacy.awsapps.com/start"}, "https://example.okta.com/home/amazon_aws/0oa1"},
+		{"session falls back to start url", Profile{SSOSession: "other", SSOStartURL: "https://legacy.awsapps.com/start"}, "https://example.okta.com/home/amazon_aws/0oa1"},
+		{"not configured", Profile{SSOSession: "other"}, ""},
+	}
+	for _, tt := range tests {
+		if got := IdPLoginURL(&tt.profile); got != tt.expected {
+			t.Errorf("%s: IdPLoginURL() = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSSOManagerTokenRefreshed(t *testing.T) {
+	dir := t.TempDir()
+	sm := &SSOManager{cacheDir: dir}
+
+	previous := time.Now().Add(-time.Hour)
+	if sm.tokenRefreshed("zenith", previous) {
+		t.Error("tokenRefreshed() = true with an empty cache")
+	}
+
+	data, _ := json.Marshal(SSOCache{
+		StartURL:    "https://zenith.awsapps.com/start",
+		AccessToken: "token",
+		ExpiresAt:   time.Now().Add(8 * time.Hour),
+	})
+	if err := os.WriteFile(filepath.Join(dir, sha1Hex("zenith")+".json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !sm.tokenRefreshed("zenith", previous) {
+		t.Error("tokenRefreshed() = false after a new token was cached")
+	}
+	if sm.tokenRefreshed("zenith", time.Now().Add(9*time.Hour)) {
+		t.Error("tokenRefreshed() = true for a token no newer than the previous one")
+	}
+}
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"os"
+	"time"
+)
+
+// LoginStatus is the SSO login state of a profile
+type LoginStatus struct {
+	LoggedIn bool
+	// ExpiresAt is when the cached token expires; zero when not logged in
+	ExpiresAt time.Time
+}
+
+// LoginStatuses returns the login state of several profiles from one read
+// of ~/.aws/config and one scan of the SSO token cache, instead of a lookup
+// (and possibly a cache directory scan) per profile. With no names it
+// reports every SSO profile. Unknown and non-SSO profiles are reported as
+// not logged in.
+func (sm *SSOManager) LoginStatuses(profileNames ...string) (map[string]LoginStatus, error) {
+	profiles, err := sm.configManager.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+	tokens := sm.scanTokenCache()
+
+	all := len(profileNames) == 0
+	byName := make(map[string]Profile, len(profiles))
+	for _, p := range profiles {
+		byName[p.Name] = p
+		if all && p.IsSSO {
+			profileNames = append(profileNames, p.Name)
+		}
+	}
+
+	statuses := make(map[string]LoginStatus, len(profileNames))
+	for _, name := range profileNames {
+		p, ok := byName[name]
+		if !ok || !p.IsSSO {
+			statuses[name] = LoginStatus{}
+			continue
+		}
+		cacheKey := p.SSOStartURL
+		if p.SSOSession != "" {
+			cacheKey = p.SSOSession
+		}
+		if cache := tokens.lookup(cacheKey); cache != nil {
+			statuses[name] = LoginStatus{LoggedIn: true, ExpiresAt: cache.ExpiresAt}
+		} else {
+			statuses[name] = LoginStatus{}
+		}
+	}
+	return statuses, nil
+}
+
+// tokenCache holds the valid tokens in the SSO cache directory
+type tokenCache struct {
+	byHash     map[string]*SSOCache
+	byStartURL map[string]*SSOCache
+}
+
+// scanTokenCache reads every valid token in the SSO cache directory once
+func (sm *SSOManager) scanTokenCache() tokenCache {
+	tokens := tokenCache{
+		byHash:     make(map[string]*SSOCache),
+		byStartURL: make(map[string]*SSOCache),
+	}
+	entries, err := os.ReadDir(sm.cacheDir)
+	if err != nil {
+		return tokens
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isJSONFile(entry.Name()) {
+			continue
+		}
+		hashName := trimJSONExt(entry.Name())
+		cache, err := sm.readCacheFile(hashName)
+		if err != nil {
+			continue
+		}
+		tokens.byHash[hashName] = cache
+		if cache.StartURL != "" {
+			tokens.byStartURL[cache.StartURL] = cache
+		}
+	}
+	return tokens
+}
+
+// lookup mirrors findCachedToken: the AWS CLI's SHA1 cache key first, then
+// a token whose start URL matches
+func (t tokenCache) lookup(cacheKey string) *SSOCache {
+	if cache, ok := t.byHash[sha1Hex(cacheKey)]; ok {
+		return cache
+	}
+	return t.byStartURL[cacheKey]
+}
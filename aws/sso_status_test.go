@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSSOManagerLoginStatuses(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	config := `[profile dev]
+sso_session = zenith
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = eu-west-1
+sso_account_id = 222222222222
+sso_role_name = Admin
+
+[profile expired]
+sso_start_url = https://expired.awsapps.com/start
+sso_region = eu-west-1
+sso_account_id = 333333333333
+sso_role_name = Admin
+
+[profile static]
+region = eu-west-1
+
+[sso-session zenith]
+sso_start_url = https://zenith.awsapps.com/start
+sso_region = eu-west-1
+`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := filepath.Join(dir, "cache")
+	if err := os.Mkdir(cacheDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	sm := &SSOManager{configManager: &ConfigManager{configPath: configPath}, cacheDir: cacheDir}
+
+	expires := time.Now().Add(8 * time.Hour).Truncate(time.Second)
+	writeToken := func(name string, cache SSOCache) {
+		data, _ := json.Marshal(cache)
+		if err := os.WriteFile(filepath.Join(cacheDir, name+".json"), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeToken(sha1Hex("zenith"), SSOCache{AccessToken: "a", ExpiresAt: expires})
+	// Cached under a key rw doesn't compute; found by start URL
+	writeToken("other", SSOCache{StartURL: "https://legacy.awsapps.com/start", AccessToken: "b", ExpiresAt: expires})
+	writeToken(sha1Hex("https://expired.awsapps.com/start"), SSOCache{AccessToken: "c", ExpiresAt: time.Now().Add(-time.Hour)})
+
+	statuses, err := sm.LoginStatuses("dev", "legacy", "expired", "static", "missing")
+	if err != nil {
+		t.Fatalf("LoginStatuses() error: %v", err)
+	}
+	want := map[string]bool{"dev": true, "legacy": true, "expired": false, "static": false, "missing": false}
+	for name, loggedIn := range want {
+		if got := statuses[name]; got.LoggedIn != loggedIn {
+			t.Errorf("LoginStatuses()[%s].LoggedIn = %v, want %v", name, got.LoggedIn, loggedIn)
+		}
+		if loggedIn != sm.IsLoggedIn(name) {
+			t.Errorf("LoginStatuses()[%s] disagrees with IsLoggedIn()", name)
+		}
+	}
+	if !statuses["dev"].ExpiresAt.Equal(expires) {
+		t.Errorf("LoginStatuses()[dev].ExpiresAt = %v, want %v", statuses["dev"].ExpiresAt, expires)
+	}
+
+	all, err := sm.LoginStatuses()
+	if err != nil {
+		t.Fatalf("LoginStatuses() error: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("LoginStatuses() with no names = %v, want the 3 SSO profiles", all)
+	}
+}
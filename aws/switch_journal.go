@@ -0,0 +1,235 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+// switchJournalFile records a profile switch while it is being applied
+const switchJournalFile = "switch_journal.json"
+
+// SwitchJournalStale is how old a journal must be before it is treated as
+// left behind by a crashed switch rather than one still running
+const SwitchJournalStale = time.Minute
+
+// switchStateFiles are the state directory files a profile switch writes
+var switchStateFiles = []string{"active_identity", "active_profile", "active_role", "env"}
+
+// fileSnapshot is a file's content before a switch
+type fileSnapshot struct {
+	Data    []byte `json:"data,omitempty"`
+	Existed bool   `json:"existed"`
+}
+
+// SwitchJournal is an intent log for a profile switch: the planned change
+// and the files it touches, saved before anything is written. A journal left
+// behind means the switch was interrupted, and 'rw repair' can restore the
+// previous state or apply the switch again.
+type SwitchJournal struct {
+	StartedAt   time.Time `json:"started_at"`
+	FromProfile string    `json:"from_profile"`
+	FromContext string    `json:"from_context"`
+	ToProfile   string    `json:"to_profile"`
+	ToContext   string    `json:"to_context"`
+	SkipKube    bool      `json:"skip_kube"`
+	// Environment is set when the context was the environment's rather
+	// than the profile's
+	Environment string `json:"environment,omitempty"`
+	// FallbackEnv is set when no context was known up front, so the
+	// switch looks up (or adds to kubeconfig) this environment's context
+	FallbackEnv string `json:"fallback_env,omitempty"`
+	// FromSessionID is the session active before the switch, 0 for none
+	FromSessionID int `json:"from_session_id,omitempty"`
+
+	// Files holds the previous content of ~/.aws/config and the state
+	// files, keyed by path
+	Files map[string]fileSnapshot `json:"files"`
+}
+
+// Stale reports whether the journal is old enough to have been left behind
+// by a crashed switch
+func (j *SwitchJournal) Stale(now time.Time) bool {
+	return now.Sub(j.StartedAt) >= SwitchJournalStale
+}
+
+func switchJournalPath() (string, error) {
+	dir, err := utils.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, switchJournalFile), nil
+}
+
+// LoadSwitchJournal returns the journal of an unfinished switch, or nil
+// when there is none
+func LoadSwitchJournal() (*SwitchJournal, error) {
+	data, err := utils.ReadRoleWalkersFile(switchJournalFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var j SwitchJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("corrupt switch journal (%s): %w", switchJournalFile, err)
+	}
+	return &j, nil
+}
+
+// begin snapshots the files the switch writes (configPaths plus the state
+// files) and saves the journal. The journal is created atomically, so of
+// two switches starting at once (the tray and the CLI, say) only one
+// proceeds; the other is refused while the journal exists.
+func (j *SwitchJournal) begin(configPaths ...string) error {
+	dir, err := utils.RoleWalkersDir()
+	if err != nil {
+		return err
+	}
+	paths := append([]string(nil), configPaths...)
+	for _, name := range switchStateFiles {
+		paths = append(paths, filepath.Join(dir, name))
+	}
+
+	j.StartedAt = time.Now()
+	j.Files = make(map[string]fileSnapshot)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			j.Files[path] = fileSnapshot{Data: data, Existed: true}
+		case errors.Is(err, os.ErrNotExist):
+			j.Files[path] = fileSnapshot{}
+		default:
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	// Write the journal in full under a temporary name, then link it into
+	// place: the link fails if a journal exists, and a reader never sees
+	// a partly written one
+	tmp, err := os.CreateTemp(dir, switchJournalFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write the switch journal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the switch journal: %w", err)
+	}
+
+	err = os.Link(tmp.Name(), filepath.Join(dir, switchJournalFile))
+	if errors.Is(err, os.ErrExist) {
+		existing, loadErr := LoadSwitchJournal()
+		if loadErr != nil || existing == nil {
+			return fmt.Errorf("another switch is in progress")
+		}
+		if existing.Stale(time.Now()) {
+			return fmt.Errorf("an earlier switch from %s to %s was interrupted; run 'rw repair' first",
+				existing.FromProfile, existing.ToProfile)
+		}
+		return fmt.Errorf("another switch (to %s) is in progress", existing.ToProfile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the switch journal: %w", err)
+	}
+	return nil
+}
+
+// finish removes the journal once the switch is complete, or rolled back
+func (j *SwitchJournal) finish() error {
+	path, err := switchJournalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// restoreFiles puts back the files as they were before the switch. A file
+// rw tracks the hash of (~/.aws/config) is only put back while it still
+// holds what rw wrote, so edits made since the switch started are kept;
+// the paths left alone are returned.
+func (j *SwitchJournal) restoreFiles() ([]string, error) {
+	hashes := loadConfigHashes()
+	var skipped []string
+	var errs []error
+	for path, snap := range j.Files {
+		var err error
+		if recorded, tracked := hashes[path]; tracked {
+			current, readErr := os.ReadFile(path)
+			switch {
+			case readErr == nil && snap.Existed && configHash(current) == configHash(snap.Data):
+				// Unchanged by the switch
+				continue
+			case readErr != nil || configHash(current) != recorded:
+				skipped = append(skipped, path)
+				continue
+			}
+		}
+		if snap.Existed {
+			_, tracked := hashes[path]
+			if err = utils.WriteFileAtomic(path, snap.Data, 0600); err == nil && tracked {
+				// Restoring the AWS config rw wrote isn't an external change
+				err = recordConfigWrite(path, snap.Data)
+			}
+		} else if err = os.Remove(path); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	sort.Strings(skipped)
+	return skipped, errors.Join(errs...)
+}
+
+// RollbackSwitch restores the files, active session and kubectl context
+// recorded before an interrupted switch, then removes the journal. It
+// returns the files left as they are because they were edited after the
+// switch started.
+func RollbackSwitch(j *SwitchJournal, km *KubeManager) ([]string, error) {
+	skipped, err := j.restoreFiles()
+	if err != nil {
+		return skipped, fmt.Errorf("failed to restore files: %w", err)
+	}
+	if km.configRepo != nil {
+		if err := km.configRepo.RestoreUserSession(j.FromSessionID); err != nil {
+			return skipped, fmt.Errorf("failed to restore the active session: %w", err)
+		}
+	}
+	if j.FromContext != "" {
+		if current, _ := km.GetCurrentContext(); current != j.FromContext {
+			if err := km.SwitchContext(j.FromContext); err != nil {
+				return skipped, err
+			}
+		}
+	}
+	return skipped, j.finish()
+}
+
+// ReplaySwitch applies an interrupted switch again. The journal is removed
+// first, since the new switch writes its own.
+func ReplaySwitch(j *SwitchJournal, ps *ProfileSwitcher, km *KubeManager) (*SwitchOutcome, error) {
+	if err := j.finish(); err != nil {
+		return nil, err
+	}
+	return SwitchProfileAndContext(ps, km, j.ToProfile, SwitchOptions{SkipKube: j.SkipKube, Reapply: true, Environment: j.Environment})
+}
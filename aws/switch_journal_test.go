@@ -0,0 +1,138 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSwitchJournalRestoresFiles(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("RW_STATE_DIR", stateDir)
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte("[default]\nregion = eu-west-2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(stateDir, "active_identity")
+	if err := os.WriteFile(identity, []byte("zenith-qa"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	j := &SwitchJournal{FromProfile: "zenith-qa", ToProfile: "zenith-dev"}
+	if err := j.begin(configPath); err != nil {
+		t.Fatalf("begin() error: %v", err)
+	}
+
+	// The switch gets part-way: config rewritten, identity written, env
+	// file created
+	os.WriteFile(configPath, []byte("[default]\nregion = us-east-1\n"), 0600)
+	os.WriteFile(identity, []byte("zenith-dev"), 0600)
+	os.WriteFile(filepath.Join(stateDir, "env"), []byte("export AWS_PROFILE='zenith-dev'\n"), 0600)
+
+	loaded, err := LoadSwitchJournal()
+	if err != nil || loaded == nil {
+		t.Fatalf("LoadSwitchJournal() = %v, %v; want the journal", loaded, err)
+	}
+	if loaded.FromProfile != "zenith-qa" || loaded.ToProfile != "zenith-dev" {
+		t.Errorf("LoadSwitchJournal() = %+v, want qa -> dev", loaded)
+	}
+
+	if skipped, err := loaded.restoreFiles(); err != nil || len(skipped) > 0 {
+		t.Fatalf("restoreFiles() = %v, %v; want every file restored", skipped, err)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.Contains(string(data), "eu-west-2") {
+		t.Errorf("config = %q, want the original", data)
+	}
+	if data, _ := os.ReadFile(identity); string(data) != "zenith-qa" {
+		t.Errorf("active_identity = %q, want zenith-qa", data)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "env")); !os.IsNotExist(err) {
+		t.Errorf("env file should be removed, since it didn't exist before; Stat() = %v", err)
+	}
+
+	if err := loaded.finish(); err != nil {
+		t.Fatalf("finish() error: %v", err)
+	}
+	if j, err := LoadSwitchJournal(); err != nil || j != nil {
+		t.Errorf("LoadSwitchJournal() after finish = %v, %v; want nil", j, err)
+	}
+}
+
+func TestSwitchJournalRefusesConcurrentSwitch(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config")
+
+	first := &SwitchJournal{FromProfile: "zenith-qa", ToProfile: "zenith-dev"}
+	if err := first.begin(configPath); err != nil {
+		t.Fatalf("begin() error: %v", err)
+	}
+	second := &SwitchJournal{FromProfile: "zenith-qa", ToProfile: "zenith-sit"}
+	if err := second.begin(configPath); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("begin() during another switch = %v, want an in-progress error", err)
+	}
+
+	if first.Stale(first.StartedAt.Add(time.Second)) {
+		t.Error("Stale() = true for a journal a second old")
+	}
+	if !first.Stale(first.StartedAt.Add(SwitchJournalStale)) {
+		t.Error("Stale() = false for an old journal")
+	}
+}
+
+func TestSwitchJournalKeepsLaterConfigEdits(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config")
+	original := []byte("[default]\nregion = eu-west-2\n")
+	if err := os.WriteFile(configPath, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+	recordConfigWrite(configPath, original)
+
+	j := &SwitchJournal{FromProfile: "zenith-qa", ToProfile: "zenith-dev"}
+	if err := j.begin(configPath); err != nil {
+		t.Fatalf("begin() error: %v", err)
+	}
+
+	// The switch writes the config, then another tool edits it
+	if err := writeDefaultSection(configPath, ProfileSettings{Lines: []string{"region = us-east-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	edited := []byte("[default]\nregion = us-east-1\n\n[profile added-later]\nregion = eu-west-1\n")
+	os.WriteFile(configPath, edited, 0600)
+
+	skipped, err := j.restoreFiles()
+	if err != nil {
+		t.Fatalf("restoreFiles() error: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != configPath {
+		t.Errorf("restoreFiles() skipped %v, want the edited config", skipped)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != string(edited) {
+		t.Errorf("config = %q, want the later edit kept", data)
+	}
+	j.finish()
+}
+
+func TestSwitchJournalBeginIsExclusive(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config")
+
+	errs := make(chan error, 8)
+	for range cap(errs) {
+		go func() {
+			j := &SwitchJournal{FromProfile: "zenith-qa", ToProfile: "zenith-dev"}
+			errs <- j.begin(configPath)
+		}()
+	}
+	started := 0
+	for range cap(errs) {
+		if err := <-errs; err == nil {
+			started++
+		}
+	}
+	if started != 1 {
+		t.Errorf("%d switches started at once, want 1", started)
+	}
+}
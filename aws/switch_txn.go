@@ -0,0 +1,260 @@
+package aws
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+
+	"rolewalkers/internal/db"
+)
+
+// SwitchOutcome is the end state of a profile switch
+type SwitchOutcome struct {
+	// Profile and Context are what is active after the switch (or rollback)
+	Profile string
+	Context string
+
+	// RolledBack is set when the switch failed and the previous profile and
+	// context were restored
+	RolledBack bool
+
+	// Incomplete is set when the rollback failed too; the switch journal is
+	// kept so 'rw repair' can restore the previous state
+	Incomplete bool
+
+	// Unchanged is set when the profile and context were already active,
+	// so nothing was written
+	Unchanged bool
+}
+
+// switchTxn applies a profile and kubectl context as one unit: if the
+// context cannot be applied, the previous profile and context are restored.
+type switchTxn struct {
+	switchProfile func(name string) error
+	// clearProfile empties [default], restoring "no profile active"
+	clearProfile func() error
+	useContext   func(name string) error
+	// findContext, when set, applies a context that wasn't known up front
+	// (looking it up, or adding it to kubeconfig) and returns its name
+	findContext func() (string, error)
+}
+
+// run switches from (prevProfile, prevContext) to (profile, context).
+// An empty context applies findContext if set, else switches the profile
+// only.
+func (t switchTxn) run(prevProfile, prevContext, profile, context string) (*SwitchOutcome, error) {
+	if err := t.switchProfile(profile); err != nil {
+		// SwitchProfile may have written some files before failing
+		outcome := t.rollback(prevProfile, prevContext, false)
+		return outcome, fmt.Errorf("failed to switch profile to %s: %w", profile, err)
+	}
+
+	if context == "" && t.findContext != nil {
+		found, err := t.findContext()
+		if err != nil {
+			outcome := t.rollback(prevProfile, prevContext, true)
+			return outcome, fmt.Errorf("failed to switch kubectl context: %w", err)
+		}
+		return &SwitchOutcome{Profile: profile, Context: found}, nil
+	}
+
+	if context == "" {
+		return &SwitchOutcome{Profile: profile, Context: prevContext}, nil
+	}
+
+	if err := t.useContext(context); err != nil {
+		outcome := t.rollback(prevProfile, prevContext, true)
+		return outcome, fmt.Errorf("failed to switch kubectl context to %s: %w", context, err)
+	}
+
+	return &SwitchOutcome{Profile: profile, Context: context}, nil
+}
+
+// rollback restores the previous profile and context, best effort. A
+// previous profile of "" or "default" means none was active, so [default]
+// is cleared. The outcome reports what is actually active afterwards.
+func (t switchTxn) rollback(prevProfile, prevContext string, contextChanged bool) *SwitchOutcome {
+	outcome := &SwitchOutcome{Profile: prevProfile, Context: prevContext, RolledBack: true}
+
+	var err error
+	if prevProfile == "" || prevProfile == "default" {
+		outcome.Profile = "default"
+		err = t.clearProfile()
+	} else {
+		err = t.switchProfile(prevProfile)
+	}
+	if err != nil {
+		outcome.Profile = "(unknown - previous profile could not be restored)"
+		outcome.Incomplete = true
+	}
+	if contextChanged && prevContext != "" && t.useContext(prevContext) != nil {
+		outcome.Context = "(unknown - previous context could not be restored)"
+		outcome.Incomplete = true
+	}
+	return outcome
+}
+
+// ResolveProfileContext returns the kubectl context to apply with a profile:
+// the context stored for the profile, else an existing context matching the
+// profile's environment. It returns "" when neither is known. A stored
+// context that no longer exists in kubeconfig is an error, so a switch never
+// starts towards a target it can't reach.
+func (km *KubeManager) ResolveProfileContext(profileName string) (string, error) {
+	if km.configRepo != nil {
+		if named, err := km.configRepo.GetProfileKubeContext(profileName); err == nil && named != "" {
+			contexts, err := km.GetContexts()
+			if err != nil {
+				return "", err
+			}
+			for _, c := range contexts {
+				if c.Name == named {
+					return named, nil
+				}
+			}
+			return "", fmt.Errorf("kubectl context %q stored for profile %s does not exist\n"+
+				"  Update it with 'rw kube set context %s <context>' or clear it with 'rw kube set context %s --clear'",
+				named, profileName, profileName, profileName)
+		}
+	}
+
+	contextName, err := km.FindContextForEnv(profileName)
+	if err != nil {
+		return "", nil
+	}
+	return contextName, nil
+}
+
+// SwitchOptions control SwitchProfileAndContext
+type SwitchOptions struct {
+	// SkipKube switches the AWS profile only
+	SkipKube bool
+	// Force skips ValidateSwitch, switching even if the profile's
+	// credentials can't be resolved
+	Force bool
+	// Reapply writes the profile and context even if they are already
+	// active, e.g. to finish an interrupted switch
+	Reapply bool
+	// Environment switches to this environment's kubectl context rather
+	// than the profile's, for environments that share a profile (clones)
+	Environment string
+}
+
+// SwitchProfileAndContext switches the AWS profile and kubectl context as a
+// single transaction. The profile is validated and both targets are computed
+// before anything changes; if applying the context fails, the previous
+// profile and context are restored. When the profile has no known context,
+// the environment's context is looked up (or added to kubeconfig) as part of
+// the transaction, and the journal records the environment for 'rw repair'.
+// When the profile and context are already active, nothing is written.
+func SwitchProfileAndContext(ps *ProfileSwitcher, km *KubeManager, profileName string, opts SwitchOptions) (*SwitchOutcome, error) {
+	profiles, err := ps.configManager.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := FindProfileByName(profiles, profileName); err != nil {
+		return nil, err
+	}
+	if !opts.Force {
+		if err := ps.ValidateSwitch(profileName); err != nil {
+			return nil, err
+		}
+	}
+	skipKube := opts.SkipKube
+
+	prevProfile := ps.configManager.GetActiveProfile()
+	prevContext, _ := CachedKubeContext()
+	if prevContext == "" {
+		prevContext, _ = km.GetCurrentContext()
+	}
+
+	var targetContext string
+	switch {
+	case skipKube:
+	case opts.Environment != "":
+		// Not found yet: looked up, or added to kubeconfig, after the switch
+		targetContext, _ = km.FindContextForEnv(opts.Environment)
+	default:
+		targetContext, err = km.ResolveProfileContext(profileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// No known context: the environment's context is found, or added to
+	// kubeconfig, inside the transaction
+	var fallbackEnv string
+	if !skipKube && targetContext == "" {
+		fallbackEnv = cmp.Or(opts.Environment, profileName)
+	}
+
+	alreadyActive := prevProfile == profileName && !opts.Reapply
+	if alreadyActive && (skipKube || targetContext == prevContext) {
+		RecordSession(km.configRepo, profileName)
+		return &SwitchOutcome{Profile: profileName, Context: prevContext, Unchanged: true}, nil
+	}
+
+	// The journal outlives a crash part-way through, for 'rw repair'
+	journal := &SwitchJournal{
+		FromProfile: prevProfile,
+		FromContext: prevContext,
+		ToProfile:   profileName,
+		ToContext:   targetContext,
+		SkipKube:    skipKube,
+		Environment: opts.Environment,
+		FallbackEnv: fallbackEnv,
+	}
+	if km.configRepo != nil {
+		if session, _, _, err := km.configRepo.GetActiveSession(); err == nil && session != nil {
+			journal.FromSessionID = session.ID
+		}
+	}
+	if err := journal.begin(ps.configManager.configPath); err != nil {
+		return nil, err
+	}
+
+	txn := switchTxn{switchProfile: ps.SwitchProfile, clearProfile: ps.ClearProfile, useContext: km.SwitchContext}
+	if alreadyActive {
+		// Only the context changes; rolling back leaves the profile as is
+		txn.switchProfile = func(string) error { return nil }
+		txn.clearProfile = func() error { return nil }
+	}
+	if fallbackEnv != "" {
+		txn.findContext = func() (string, error) {
+			if err := km.SwitchContextForEnv(fallbackEnv); err != nil {
+				return "", err
+			}
+			return km.GetCurrentContext()
+		}
+	}
+	outcome, err := txn.run(prevProfile, prevContext, profileName, targetContext)
+	if err == nil {
+		// Still journaled, so 'rw repair' restores the previous session
+		RecordSession(km.configRepo, profileName)
+	}
+	if outcome != nil && !outcome.Incomplete {
+		if finishErr := journal.finish(); finishErr != nil && err == nil {
+			err = finishErr
+		}
+	}
+	return outcome, err
+}
+
+// RecordSession makes the profile's role the active session in the
+// database, which the idle timeout and the generated [default] section
+// rely on. It is idempotent, so switching to the active profile again
+// keeps its session. Switching to a profile rw has no role for closes the
+// active session, and a failure never fails the switch.
+func RecordSession(repo *db.ConfigRepository, profileName string) {
+	if repo == nil {
+		return
+	}
+	role, err := repo.GetRoleByProfileName(profileName)
+	if errors.Is(err, db.ErrRoleNotFound) {
+		_ = repo.RestoreUserSession(0)
+		return
+	}
+	if err != nil {
+		return
+	}
+	_, _, _ = repo.CreateUserSession(role.ID)
+}
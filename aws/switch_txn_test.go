@@ -0,0 +1,173 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"rolewalkers/internal/db"
+)
+
+// fakeSwitchState records the active profile/context for switchTxn tests
+type fakeSwitchState struct {
+	profile, context string
+	failProfile      string
+	failContext      string
+	// found is the context findContext applies; empty makes it fail
+	found string
+}
+
+func (f *fakeSwitchState) txn() switchTxn {
+	return switchTxn{
+		switchProfile: func(name string) error {
+			if name == f.failProfile {
+				return errors.New("write failed")
+			}
+			f.profile = name
+			return nil
+		},
+		clearProfile: func() error {
+			f.profile = "default"
+			return nil
+		},
+		useContext: func(name string) error {
+			if name == f.failContext {
+				return errors.New("no such context")
+			}
+			f.context = name
+			return nil
+		},
+	}
+}
+
+func TestSwitchTxnSuccess(t *testing.T) {
+	state := &fakeSwitchState{profile: "zenith-qa", context: "qa-ctx"}
+	outcome, err := state.txn().run("zenith-qa", "qa-ctx", "zenith-dev", "dev-ctx")
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if outcome.RolledBack || outcome.Profile != "zenith-dev" || outcome.Context != "dev-ctx" {
+		t.Errorf("outcome = %+v", outcome)
+	}
+	if state.profile != "zenith-dev" || state.context != "dev-ctx" {
+		t.Errorf("state = %s/%s, want zenith-dev/dev-ctx", state.profile, state.context)
+	}
+}
+
+func TestSwitchTxnRollsBackOnContextFailure(t *testing.T) {
+	state := &fakeSwitchState{profile: "zenith-qa", context: "qa-ctx", failContext: "dev-ctx"}
+	outcome, err := state.txn().run("zenith-qa", "qa-ctx", "zenith-dev", "dev-ctx")
+	if err == nil {
+		t.Fatal("run() expected error")
+	}
+	if !outcome.RolledBack || outcome.Profile != "zenith-qa" || outcome.Context != "qa-ctx" {
+		t.Errorf("outcome = %+v", outcome)
+	}
+	if state.profile != "zenith-qa" || state.context != "qa-ctx" {
+		t.Errorf("state = %s/%s, want previous zenith-qa/qa-ctx", state.profile, state.context)
+	}
+}
+
+func TestSwitchTxnReportsFailedRollback(t *testing.T) {
+	state := &fakeSwitchState{profile: "zenith-qa", context: "qa-ctx", failContext: "dev-ctx", failProfile: "zenith-qa"}
+	outcome, err := state.txn().run("zenith-qa", "qa-ctx", "zenith-dev", "dev-ctx")
+	if err == nil {
+		t.Fatal("run() expected error")
+	}
+	if outcome.Profile == "zenith-qa" {
+		t.Errorf("outcome.Profile = %q, want it reported as not restored", outcome.Profile)
+	}
+}
+
+func TestSwitchTxnProfileOnly(t *testing.T) {
+	state := &fakeSwitchState{profile: "zenith-qa", context: "qa-ctx"}
+	outcome, err := state.txn().run("zenith-qa", "qa-ctx", "zenith-mgmt", "")
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if outcome.Profile != "zenith-mgmt" || outcome.Context != "qa-ctx" || state.context != "qa-ctx" {
+		t.Errorf("outcome = %+v, state context = %s", outcome, state.context)
+	}
+}
+
+func TestSwitchTxnClearsDefaultOnRollback(t *testing.T) {
+	state := &fakeSwitchState{profile: "default", context: "qa-ctx", failContext: "dev-ctx"}
+	outcome, err := state.txn().run("default", "qa-ctx", "zenith-dev", "dev-ctx")
+	if err == nil {
+		t.Fatal("run() expected error")
+	}
+	if outcome.Incomplete || outcome.Profile != "default" || state.profile != "default" {
+		t.Errorf("outcome = %+v, state profile = %s; want [default] cleared", outcome, state.profile)
+	}
+}
+
+func TestSwitchTxnFindContext(t *testing.T) {
+	state := &fakeSwitchState{profile: "zenith-qa", context: "qa-ctx", found: "dev-ctx"}
+	txn := state.txn()
+	txn.findContext = func() (string, error) {
+		if state.found == "" {
+			return "", errors.New("no context for the environment")
+		}
+		state.context = state.found
+		return state.found, nil
+	}
+
+	outcome, err := txn.run("zenith-qa", "qa-ctx", "zenith-dev", "")
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if outcome.Context != "dev-ctx" || state.context != "dev-ctx" {
+		t.Errorf("outcome = %+v, state context = %s; want dev-ctx", outcome, state.context)
+	}
+
+	state.found = ""
+	outcome, err = txn.run("zenith-dev", "dev-ctx", "zenith-sit", "")
+	if err == nil {
+		t.Fatal("run() expected error when no context is found")
+	}
+	if !outcome.RolledBack || state.profile != "zenith-dev" || state.context != "dev-ctx" {
+		t.Errorf("outcome = %+v, state = %s/%s; want rolled back to zenith-dev/dev-ctx", outcome, state.profile, state.context)
+	}
+}
+
+func TestRecordSession(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := db.NewConfigRepository(database)
+
+	if err := repo.AddAWSAccount("000000000061", "sessions", "https://d-61.awsapps.com/start", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, err := repo.GetAWSAccount("000000000061")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	if err := repo.AddAWSRole(account.ID, "ReadOnly", "", "sessions-ro", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSRole() error: %v", err)
+	}
+
+	RecordSession(repo, "unknown-profile")
+	if session, _, _, err := repo.GetActiveSession(); err == nil && session != nil {
+		t.Errorf("GetActiveSession() = %+v after an unknown profile, want none", session)
+	}
+
+	RecordSession(repo, "sessions-ro")
+	first, role, _, err := repo.GetActiveSession()
+	if err != nil || first == nil || role.ProfileName != "sessions-ro" {
+		t.Fatalf("GetActiveSession() = %+v, %+v, %v, want the sessions-ro role", first, role, err)
+	}
+	RecordSession(repo, "sessions-ro")
+	if again, _, _, _ := repo.GetActiveSession(); again == nil || again.ID != first.ID {
+		t.Errorf("GetActiveSession() after switching again = %+v, want session %d kept", again, first.ID)
+	}
+
+	// A profile without a role leaves no session active
+	RecordSession(repo, "unknown-profile")
+	if session, _, _, err := repo.GetActiveSession(); err == nil && session != nil {
+		t.Errorf("GetActiveSession() = %+v after switching to an unknown profile, want none", session)
+	}
+	RecordSession(nil, "sessions-ro")
+}
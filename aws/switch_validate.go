@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SwitchValidationError reports why a profile would be unusable as the
+// default profile. Switching anyway is possible with --force.
+type SwitchValidationError struct {
+	Profile  string
+	Problems []string
+	// NeedsLogin is set when an SSO login would fix the problems
+	NeedsLogin bool
+}
+
+func (e *SwitchValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "profile %s can't be used yet:\n", e.Profile)
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "  - %s\n", p)
+	}
+	sb.WriteString("Switching now would leave the default profile unusable: AWS CLI calls, EKS\n")
+	sb.WriteString("authentication for kubectl, tunnels and database commands would fail.\n")
+	if e.NeedsLogin {
+		fmt.Fprintf(&sb, "Run 'rw login %s' first, or re-run with --force to switch anyway", e.Profile)
+	} else {
+		sb.WriteString("Fix the profile in ~/.aws/config, or re-run with --force to switch anyway")
+	}
+	return sb.String()
+}
+
+// ValidateSwitch checks that a profile can be used before it is made the
+// default: it must exist, and its credentials must be resolvable locally
+// (a valid SSO token, or keys, role_arn or credential_process configured).
+// It makes no AWS calls. Problems are returned as a *SwitchValidationError.
+func (ps *ProfileSwitcher) ValidateSwitch(profileName string) error {
+	profiles, err := ps.configManager.GetProfiles()
+	if err != nil {
+		return err
+	}
+	profile, err := FindProfileByName(profiles, profileName)
+	if err != nil {
+		return err
+	}
+
+	verr := &SwitchValidationError{Profile: profileName}
+	if profile.IsSSO {
+		var missing []string
+		for _, setting := range []struct{ key, value string }{
+			{"sso_start_url", profile.SSOStartURL},
+			{"sso_region", profile.SSORegion},
+			{"sso_account_id", profile.SSOAccountID},
+			{"sso_role_name", profile.SSORoleName},
+		} {
+			if setting.value == "" {
+				missing = append(missing, setting.key)
+			}
+		}
+		if len(missing) > 0 {
+			verr.Problems = append(verr.Problems, "SSO settings missing: "+strings.Join(missing, ", "))
+		} else {
+			sm, err := NewSSOManager(ps.configManager)
+			if err != nil {
+				return err
+			}
+			if !sm.IsLoggedIn(profileName) {
+				verr.Problems = append(verr.Problems, "SSO session is expired or not logged in")
+				verr.NeedsLogin = true
+			}
+		}
+	} else if !profile.hasCredentialSource && !credentialsFileHasProfile(profileName) {
+		verr.Problems = append(verr.Problems,
+			"no credentials found (no keys in ~/.aws/credentials, and no role_arn or credential_process in ~/.aws/config)")
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// credentialsFileHasProfile reports whether the shared credentials file
+// ($AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials) has a section for
+// the profile
+func credentialsFileHasProfile(profileName string) bool {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "["+profileName+"]" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileSwitcherValidateSwitch(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	awsDir := filepath.Join(home, ".aws")
+	if err := os.MkdirAll(awsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(awsDir, "config")
+	os.WriteFile(configPath, []byte(`[profile sso-expired]
+sso_start_url = https://zenith.awsapps.com/start
+sso_region = eu-west-2
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile sso-incomplete]
+sso_start_url = https://zenith.awsapps.com/start
+sso_region = eu-west-2
+
+[profile assumed]
+role_arn = arn:aws:iam::111111111111:role/deploy
+source_profile = keys
+
+[profile keys]
+region = eu-west-2
+
+[profile nothing]
+region = eu-west-2
+`), 0o600)
+	os.WriteFile(filepath.Join(awsDir, "credentials"), []byte("[keys]\naws_access_key_id = AKIA...\n"), 0o600)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "")
+
+	ps := NewProfileSwitcher(&ConfigManager{configPath: configPath})
+
+	for _, name := range []string{"assumed", "keys"} {
+		if err := ps.ValidateSwitch(name); err != nil {
+			t.Errorf("ValidateSwitch(%s) error: %v", name, err)
+		}
+	}
+
+	var verr *SwitchValidationError
+	if err := ps.ValidateSwitch("sso-expired"); !errors.As(err, &verr) || !verr.NeedsLogin {
+		t.Errorf("ValidateSwitch(sso-expired) = %v, want a validation error needing login", err)
+	}
+	if err := ps.ValidateSwitch("sso-incomplete"); !errors.As(err, &verr) || verr.NeedsLogin {
+		t.Errorf("ValidateSwitch(sso-incomplete) = %v, want a validation error for missing settings", err)
+	}
+	if err := ps.ValidateSwitch("nothing"); !errors.As(err, &verr) {
+		t.Errorf("ValidateSwitch(nothing) = %v, want a validation error for missing credentials", err)
+	}
+	if err := ps.ValidateSwitch("missing"); err == nil || errors.As(err, &verr) {
+		t.Errorf("ValidateSwitch(missing) = %v, want a not found error", err)
+	}
+}
@@ -1,13 +1,13 @@
 package aws
 
 import (
-	"bytes"
 	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"math/rand/v2"
+	"net"
 	"os"
-	"os/exec"
 	"os/signal"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
@@ -31,6 +31,10 @@ type TunnelManager struct {
 	state           *TunnelState
 	profileSwitcher *ProfileSwitcher
 	configRepo      *db.ConfigRepository
+	quotaManager    *QuotaManager
+
+	// out receives progress messages; stdout unless SetOutput changed it
+	out io.Writer
 }
 
 // TunnelConfig holds configuration for a tunnel
@@ -39,6 +43,21 @@ type TunnelConfig struct {
 	Environment string
 	NodeType    string // for db: read/write
 	DBType      string // for db: query/command
+	LocalPort   int    // overrides the configured port mapping when set
+
+	// RateLimit caps the tunnel's bandwidth in each direction, in bytes per
+	// second; 0 is unlimited
+	RateLimit int64
+	// Keepalive is the TCP keepalive interval for the tunnel's connections;
+	// 0 leaves keepalives off
+	Keepalive time.Duration
+	// AuditConnections records the local process behind every connection
+	// to the tunnel in the audit log. It is turned on for production
+	// environments when audit_tunnel_connections is set in config.yaml.
+	AuditConnections bool
+	// Reason is recorded in the audit log when a write tunnel exceeds a
+	// quota that requires one
+	Reason string
 }
 
 // NewTunnelManagerWithDeps creates a new tunnel manager with shared dependencies
@@ -55,12 +74,49 @@ func NewTunnelManagerWithDeps(km *KubeManager, ssm *SSMManager, ps *ProfileSwitc
 		state:           state,
 		profileSwitcher: ps,
 		configRepo:      repo,
+		quotaManager:    NewQuotaManager(repo),
+		out:             os.Stdout,
 	}, nil
 }
 
+// SetOutput sends the manager's progress messages to w instead of stdout
+func (tm *TunnelManager) SetOutput(w io.Writer) {
+	tm.out = w
+}
 
-// Start creates and starts a tunnel
+// Start creates a tunnel and forwards its local port until interrupted
+// (Ctrl+C), then deletes the tunnel's pod
 func (tm *TunnelManager) Start(config TunnelConfig) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Setup signal handling with buffered channel to prevent goroutine leak
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan) // Cleanup signal notification
+
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Fprintln(tm.out, "\n\nInterrupted, cleaning up tunnel...")
+			cancel()
+		case <-ctx.Done():
+			// Context cancelled, exit goroutine
+			return
+		}
+	}()
+
+	err := tm.StartContext(ctx, config)
+	if ctx.Err() != nil {
+		return nil // Normal interrupt
+	}
+	return err
+}
+
+// StartContext is Start for callers that handle signals themselves: the
+// tunnel is forwarded until ctx is done or kubectl exits. A cancelled ctx
+// also stops creating the tunnel's pod.
+func (tm *TunnelManager) StartContext(ctx context.Context, config TunnelConfig) error {
 	service := strings.ToLower(config.Service)
 	env := strings.ToLower(config.Environment)
 
@@ -71,6 +127,14 @@ func (tm *TunnelManager) Start(config TunnelConfig) error {
 			tunnelID, existing.PodName, existing.LocalPort, service, env)
 	}
 
+	// Every entry point (rw tunnel start and join, the Go API) counts write
+	// tunnels toward their quota here
+	if config.NodeType == "write" {
+		if _, err := tm.quotaManager.Enforce(QuotaTunnelWrite, env, service, service, config.Reason); err != nil {
+			return err
+		}
+	}
+
 	// Switch kubectl context to the environment
 	if err := tm.kubeManager.SwitchContextForEnvWithProfile(env, tm.profileSwitcher); err != nil {
 		return fmt.Errorf("failed to switch kubectl context: %w", err)
@@ -82,27 +146,14 @@ func (tm *TunnelManager) Start(config TunnelConfig) error {
 		return fmt.Errorf("failed to get remote endpoint: %w", err)
 	}
 
-	// Get local port from port config
-	localPorts, err := tm.portConfig.GetPort(service, env)
-	if err != nil {
-		return fmt.Errorf("failed to get local port: %w", err)
-	}
-	if len(localPorts) == 0 {
-		return fmt.Errorf("no port mapping found for service %s in environment %s", service, env)
-	}
-	localPort := localPorts[0] // Use first port
-
-	// Get remote port
-	var remotePort int
-	if tm.configRepo != nil {
-		svc, err := tm.configRepo.GetService(service)
-		if err == nil {
-			remotePort = svc.DefaultRemotePort
+	localPort := config.LocalPort
+	if localPort == 0 {
+		localPort, err = tm.localPort(service, env)
+		if err != nil {
+			return err
 		}
 	}
-	if remotePort == 0 {
-		remotePort = 5432 // default fallback
-	}
+	remotePort := tm.remotePort(service)
 
 	// Generate pod name
 	username := utils.GetCurrentUsernamePodSafe()
@@ -111,20 +162,33 @@ func (tm *TunnelManager) Start(config TunnelConfig) error {
 	}
 	podName := fmt.Sprintf("%stunnel-%s-%d", service, username, rand.IntN(10000))
 
-	fmt.Printf("Creating tunnel: %s\n", tunnelID)
-	fmt.Printf("  Pod: %s\n", podName)
-	fmt.Printf("  Local: localhost:%d\n", localPort)
-	fmt.Printf("  Remote: %s:%d\n", remoteHost, remotePort)
+	fmt.Fprintf(tm.out, "Creating tunnel: %s\n", tunnelID)
+	fmt.Fprintf(tm.out, "  Pod: %s\n", podName)
+	fmt.Fprintf(tm.out, "  Local: localhost:%d\n", localPort)
+	fmt.Fprintf(tm.out, "  Remote: %s:%d\n", remoteHost, remotePort)
+	if config.RateLimit > 0 {
+		fmt.Fprintf(tm.out, "  Rate limit: %s each way\n", FormatRate(config.RateLimit))
+	}
+	if config.Keepalive > 0 {
+		fmt.Fprintf(tm.out, "  Keepalive: %s\n", config.Keepalive)
+	}
+	if auditConnectionsByDefault(tm.configRepo, env) {
+		config.AuditConnections = true
+	}
+	if config.AuditConnections {
+		fmt.Fprintln(tm.out, "  Connections: recorded in the audit log")
+	}
 
 	// Create the socat pod
-	if err := tm.createSocatPod(podName, remoteHost, remotePort); err != nil {
+	pod, err := tm.createSocatPod(ctx, podName, remoteHost, remotePort, config.Keepalive)
+	if err != nil {
 		return fmt.Errorf("failed to create tunnel pod: %w", err)
 	}
 
 	// Wait for pod to be ready
-	fmt.Println("Waiting for pod to be ready...")
-	if err := tm.waitForPod(podName); err != nil {
-		tm.deletePod(podName)
+	fmt.Fprintln(tm.out, "Waiting for pod to be ready...")
+	if err := pod.WaitReady(90 * time.Second); err != nil {
+		pod.Close()
 		return fmt.Errorf("pod failed to start: %w", err)
 	}
 
@@ -138,19 +202,43 @@ func (tm *TunnelManager) Start(config TunnelConfig) error {
 		RemoteHost:  remoteHost,
 		RemotePort:  remotePort,
 		StartedAt:   time.Now(),
+		KubeContext: pod.KubeContext(),
 	}
 
 	if err := tm.state.Add(tunnel); err != nil {
-		tm.deletePod(podName)
+		pod.Close()
 		return fmt.Errorf("failed to save tunnel state: %w", err)
 	}
 
-	fmt.Printf("\n✓ Tunnel created successfully!\n")
-	fmt.Printf("  Connect to: localhost:%d\n", localPort)
-	fmt.Println("\nStarting port-forward (press Ctrl+C to stop)...")
+	fmt.Fprint(tm.out, "\n"+utils.OK()+" Tunnel created successfully!\n")
+	fmt.Fprintf(tm.out, "  Connect to: localhost:%d\n", localPort)
+	fmt.Fprintln(tm.out, "\nStarting port-forward (press Ctrl+C to stop)...")
 
 	// Start port-forward with interrupt handling
-	return tm.startPortForward(tunnel)
+	return tm.startPortForward(ctx, pod, tunnel, config)
+}
+
+// localPort returns the configured local port for a service/env
+func (tm *TunnelManager) localPort(service, env string) (int, error) {
+	localPorts, err := tm.portConfig.GetPort(service, env)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get local port: %w", err)
+	}
+	if len(localPorts) == 0 {
+		return 0, fmt.Errorf("no port mapping found for service %s in environment %s", service, env)
+	}
+	return localPorts[0], nil // Use first port
+}
+
+// remotePort returns the remote port a service listens on
+func (tm *TunnelManager) remotePort(service string) int {
+	if tm.configRepo != nil {
+		svc, err := tm.configRepo.GetService(service)
+		if err == nil && svc.DefaultRemotePort != 0 {
+			return svc.DefaultRemotePort
+		}
+	}
+	return 5432 // default fallback
 }
 
 // getRemoteHost retrieves the remote host for a service
@@ -171,101 +259,80 @@ func (tm *TunnelManager) getRemoteHost(service, env string, config TunnelConfig)
 	}
 }
 
-// createSocatPod creates a socat pod for tunneling
-func (tm *TunnelManager) createSocatPod(podName, remoteHost string, remotePort int) error {
-	cfg := config.Get()
-	labels := k8s.CreatorLabelsWithName(podName)
-
-	cmd := exec.Command("kubectl", "-n", TunnelAccessNamespace(), "run", podName,
-		"--port", fmt.Sprintf("%d", remotePort),
-		"--image", cfg.Images.Socat,
-		"--image-pull-policy", "IfNotPresent",
-		"--labels", labels,
-		"--command", "--",
-		"socat", fmt.Sprintf("tcp-listen:%d,fork,reuseaddr", remotePort),
-		fmt.Sprintf("tcp:%s:%d", remoteHost, remotePort),
-	)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%s: %s", err, stderr.String())
+// createSocatPod starts a socat pod for tunneling
+func (tm *TunnelManager) createSocatPod(ctx context.Context, podName, remoteHost string, remotePort int, keepalive time.Duration) (*k8s.PodSession, error) {
+	pod := k8s.NewPodSession(k8s.PodSpec{
+		Context:         ctx,
+		Stdout:          tm.out,
+		Name:            podName,
+		Namespace:       TunnelAccessNamespace(),
+		Image:           config.Get().Images.Socat,
+		ImagePullPolicy: "IfNotPresent",
+		Port:            remotePort,
+		Command:         socatArgs(remoteHost, remotePort, keepalive),
+	})
+	if err := pod.Start(); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return pod, nil
 }
 
-// waitForPod waits for a pod to be ready
-func (tm *TunnelManager) waitForPod(podName string) error {
-	cmd := exec.Command("kubectl", "-n", TunnelAccessNamespace(), "wait", "pods",
-		"-l", fmt.Sprintf("name=%s", podName),
-		"--for", "condition=Ready",
-		"--timeout", "90s",
-	)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%s: %s", err, stderr.String())
-	}
-
-	return nil
+// tunnelPod refers to an existing tunnel's pod, in the context it was
+// created in
+func tunnelPod(tunnel *TunnelInfo) *k8s.PodSession {
+	return k8s.NewPodSession(k8s.PodSpec{Name: tunnel.PodName, Namespace: TunnelAccessNamespace(), KubeContext: tunnel.KubeContext})
 }
 
-// startPortForward starts kubectl port-forward with interrupt handling
-func (tm *TunnelManager) startPortForward(tunnel *TunnelInfo) error {
-	ctx, cancel := context.WithCancel(context.Background())
+// startPortForward runs kubectl port-forward until ctx is done. With a rate
+// limit or connection audit, port-forward binds a spare port and a local
+// proxy on the tunnel's port forwards to it, limiting the rate and
+// recording each connection's process.
+func (tm *TunnelManager) startPortForward(ctx context.Context, pod *k8s.PodSession, tunnel *TunnelInfo, config TunnelConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Setup signal handling with buffered channel to prevent goroutine leak
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(sigChan) // Cleanup signal notification
-
-	go func() {
-		select {
-		case <-sigChan:
-			fmt.Println("\n\nInterrupted, cleaning up tunnel...")
-			cancel()
-		case <-ctx.Done():
-			// Context cancelled, exit goroutine
-			return
+	forwardPort := tunnel.LocalPort
+	if config.RateLimit > 0 || config.AuditConnections {
+		port, err := freeLocalPort()
+		if err != nil {
+			tm.cleanup(pod, tunnel)
+			return fmt.Errorf("failed to find a port for the tunnel proxy: %w", err)
 		}
-	}()
-
-	cmd := exec.CommandContext(ctx, "kubectl", "-n", TunnelAccessNamespace(), "port-forward",
-		fmt.Sprintf("pod/%s", tunnel.PodName),
-		fmt.Sprintf("%d:%d", tunnel.LocalPort, tunnel.RemotePort),
-	)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+		proxy, err := listenTunnelProxy(tunnel.LocalPort, port, config.RateLimit, config.Keepalive)
+		if err != nil {
+			tm.cleanup(pod, tunnel)
+			return fmt.Errorf("failed to listen on localhost:%d: %w", tunnel.LocalPort, err)
+		}
+		if config.AuditConnections {
+			proxy.onConnect = func(conn net.Conn) { tm.auditConnection(tunnel, conn) }
+		}
+		go proxy.serve(ctx)
+		forwardPort = port
+	}
 
-	err := cmd.Run()
+	err := pod.PortForward(ctx, forwardPort, tunnel.RemotePort)
 
 	// Cleanup on exit
-	tm.cleanup(tunnel)
+	tm.cleanup(pod, tunnel)
 
-	if ctx.Err() == context.Canceled {
-		return nil // Normal interrupt
+	if ctx.Err() != nil {
+		return nil // Interrupted or stopped by the caller
 	}
 
 	return err
 }
 
-// cleanup removes the tunnel pod and state
-func (tm *TunnelManager) cleanup(tunnel *TunnelInfo) {
-	fmt.Printf("Cleaning up tunnel: %s\n", tunnel.ID)
-	tm.deletePod(tunnel.PodName)
+// cleanup deletes the tunnel's pod through the session that created it and
+// removes the tunnel from the state
+func (tm *TunnelManager) cleanup(pod *k8s.PodSession, tunnel *TunnelInfo) {
+	fmt.Fprintf(tm.out, "Cleaning up tunnel: %s\n", tunnel.ID)
+	pod.Close()
 	tm.state.Remove(tunnel.ID)
 }
 
-// deletePod deletes a kubernetes pod
-func (tm *TunnelManager) deletePod(podName string) error {
-	cmd := exec.Command("kubectl", "-n", TunnelAccessNamespace(), "delete", "pod", podName)
-	return cmd.Run()
+// deletePod deletes a tunnel's pod
+func (tm *TunnelManager) deletePod(tunnel *TunnelInfo) error {
+	return tunnelPod(tunnel).Close()
 }
 
 // Stop stops a specific tunnel
@@ -278,11 +345,11 @@ func (tm *TunnelManager) Stop(service, env string) error {
 		return fmt.Errorf("no active tunnel found for %s-%s", service, env)
 	}
 
-	fmt.Printf("Stopping tunnel: %s\n", tunnel.ID)
+	fmt.Fprintf(tm.out, "Stopping tunnel: %s\n", tunnel.ID)
 
 	// Delete the pod
-	if err := tm.deletePod(tunnel.PodName); err != nil {
-		fmt.Printf("Warning: failed to delete pod %s: %v\n", tunnel.PodName, err)
+	if err := tm.deletePod(tunnel); err != nil {
+		fmt.Fprintf(tm.out, "Warning: failed to delete pod %s: %v\n", tunnel.PodName, err)
 	}
 
 	// Remove from state
@@ -290,7 +357,7 @@ func (tm *TunnelManager) Stop(service, env string) error {
 		return fmt.Errorf("failed to update state: %w", err)
 	}
 
-	fmt.Printf("✓ Tunnel stopped: %s\n", tunnel.ID)
+	fmt.Fprintf(tm.out, utils.OK()+" Tunnel stopped: %s\n", tunnel.ID)
 	return nil
 }
 
@@ -298,16 +365,16 @@ func (tm *TunnelManager) Stop(service, env string) error {
 func (tm *TunnelManager) StopAll() error {
 	tunnels := tm.state.List()
 	if len(tunnels) == 0 {
-		fmt.Println("No active tunnels to stop.")
+		fmt.Fprintln(tm.out, "No active tunnels to stop.")
 		return nil
 	}
 
-	fmt.Printf("Stopping %d tunnel(s)...\n", len(tunnels))
+	fmt.Fprintf(tm.out, "Stopping %d tunnel(s)...\n", len(tunnels))
 
 	for _, tunnel := range tunnels {
-		fmt.Printf("  Stopping %s...\n", tunnel.ID)
-		if err := tm.deletePod(tunnel.PodName); err != nil {
-			fmt.Printf("    Warning: failed to delete pod %s: %v\n", tunnel.PodName, err)
+		fmt.Fprintf(tm.out, "  Stopping %s...\n", tunnel.ID)
+		if err := tm.deletePod(tunnel); err != nil {
+			fmt.Fprintf(tm.out, "    Warning: failed to delete pod %s: %v\n", tunnel.PodName, err)
 		}
 	}
 
@@ -316,7 +383,7 @@ func (tm *TunnelManager) StopAll() error {
 		return fmt.Errorf("failed to clear state: %w", err)
 	}
 
-	fmt.Println("✓ All tunnels stopped")
+	fmt.Fprintln(tm.out, utils.OK()+" All tunnels stopped")
 	return nil
 }
 
@@ -325,62 +392,60 @@ func (tm *TunnelManager) ListTunnels() []*TunnelInfo {
 	return tm.state.List()
 }
 
-// List returns formatted list of active tunnels
-func (tm *TunnelManager) List() string {
-	tunnels := tm.state.List()
-	if len(tunnels) == 0 {
-		return "No active tunnels.\n\nStart a tunnel with: rw tunnel start <service> <env>"
-	}
-
-	var sb strings.Builder
-	sb.WriteString("Active Tunnels:\n")
-	sb.WriteString(strings.Repeat("-", 70) + "\n")
+// TunnelStatus is an active tunnel with the phase of its access pod
+type TunnelStatus struct {
+	*TunnelInfo
+	PodStatus string `json:"pod_status"`
+}
 
-	for _, t := range tunnels {
-		status := tm.checkPodStatus(t.PodName)
-		fmt.Fprintf(&sb, "\n%s:\n", t.ID)
-		fmt.Fprintf(&sb, "  Pod:     %s (%s)\n", t.PodName, status)
-		fmt.Fprintf(&sb, "  Local:   localhost:%d\n", t.LocalPort)
-		fmt.Fprintf(&sb, "  Remote:  %s:%d\n", t.RemoteHost, t.RemotePort)
-		fmt.Fprintf(&sb, "  Started: %s\n", t.StartedAt.Format("2006-01-02 15:04:05"))
+// ListWithStatus returns all active tunnels with their pod status. Unlike
+// ListTunnels it calls kubectl once per tunnel.
+func (tm *TunnelManager) ListWithStatus() []TunnelStatus {
+	tunnels := tm.state.List()
+	statuses := make([]TunnelStatus, len(tunnels))
+	for i, t := range tunnels {
+		statuses[i] = TunnelStatus{TunnelInfo: t, PodStatus: tm.checkPodStatus(t)}
 	}
-
-	return sb.String()
+	return statuses
 }
 
 // checkPodStatus checks if a pod is running
-func (tm *TunnelManager) checkPodStatus(podName string) string {
-	cmd := exec.Command("kubectl", "-n", TunnelAccessNamespace(), "get", "pod", podName,
-		"-o", "jsonpath={.status.phase}")
-
-	var out bytes.Buffer
-	cmd.Stdout = &out
-
-	if err := cmd.Run(); err != nil {
+func (tm *TunnelManager) checkPodStatus(tunnel *TunnelInfo) string {
+	phase, err := tunnelPod(tunnel).Phase()
+	if err != nil {
 		return "unknown"
 	}
-
-	return strings.TrimSpace(out.String())
+	return phase
 }
 
-// CleanupStale removes tunnels whose pods no longer exist
+// CleanupStale removes tunnels whose pods no longer exist, and deletes pods
+// orphaned by rw processes that exited without deleting them
 func (tm *TunnelManager) CleanupStale() error {
 	tunnels := tm.state.List()
 	cleaned := 0
 
 	for _, tunnel := range tunnels {
-		status := tm.checkPodStatus(tunnel.PodName)
+		status := tm.checkPodStatus(tunnel)
 		if status == "unknown" || status == "" {
-			fmt.Printf("Removing stale tunnel: %s (pod not found)\n", tunnel.ID)
+			fmt.Fprintf(tm.out, "Removing stale tunnel: %s (pod not found)\n", tunnel.ID)
 			tm.state.Remove(tunnel.ID)
 			cleaned++
 		}
 	}
 
 	if cleaned > 0 {
-		fmt.Printf("✓ Cleaned up %d stale tunnel(s)\n", cleaned)
+		fmt.Fprintf(tm.out, utils.OK()+" Cleaned up %d stale tunnel(s)\n", cleaned)
 	} else {
-		fmt.Println("No stale tunnels found.")
+		fmt.Fprintln(tm.out, "No stale tunnels found.")
+	}
+
+	// Pods left behind by rw processes that were killed
+	swept, err := k8s.SweepOrphanPods()
+	for _, name := range swept {
+		fmt.Fprintf(tm.out, "Deleted orphaned pod: %s\n", name)
+	}
+	if err != nil {
+		fmt.Fprintf(tm.out, utils.Warn()+" %v\n", err)
 	}
 
 	return nil
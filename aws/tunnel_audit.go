@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+)
+
+// TunnelClient is a local process that connected to a tunnel's port
+type TunnelClient struct {
+	PID     int    `json:"pid,omitempty"`
+	Command string `json:"command"`
+	User    string `json:"user,omitempty"`
+	// SourcePort is the client's end of the connection
+	SourcePort int `json:"source_port"`
+}
+
+// auditConnectionsByDefault reports whether connections to tunnels for env
+// are audited without --audit-connections: audit_tunnel_connections is set
+// and env is a production environment in the config or the database
+func auditConnectionsByDefault(repo *db.ConfigRepository, env string) bool {
+	return config.Get().AuditTunnelConnections && IsProductionEnv(repo, env)
+}
+
+// auditConnection records which local process opened a connection to a
+// tunnel. A process that can't be identified is still recorded, as unknown.
+func (tm *TunnelManager) auditConnection(tunnel *TunnelInfo, conn net.Conn) {
+	client := connectingProcess(conn)
+	fmt.Printf("Connection from %s\n", client)
+	recordAudit(tm.configRepo, AuditActionTunnelConnect, tunnel.Environment, tunnel.ID, nil, client,
+		messages.New(messages.AuditTunnelConnect,
+			"command", client.Command, "pid", strconv.Itoa(client.PID),
+			"service", tunnel.Service, "env", tunnel.Environment, "port", strconv.Itoa(tunnel.LocalPort)))
+}
+
+func (c *TunnelClient) String() string {
+	s := c.Command
+	if c.PID > 0 {
+		s += fmt.Sprintf(" (pid %d", c.PID)
+		if c.User != "" {
+			s += ", user " + c.User
+		}
+		s += ")"
+	}
+	return s
+}
+
+// connectingProcess identifies the process on the client end of a
+// connection accepted on localhost: from /proc on Linux, lsof elsewhere
+func connectingProcess(conn net.Conn) *TunnelClient {
+	remote, _ := conn.RemoteAddr().(*net.TCPAddr)
+	local, _ := conn.LocalAddr().(*net.TCPAddr)
+	if remote == nil || local == nil {
+		return &TunnelClient{Command: "unknown"}
+	}
+
+	var found *TunnelClient
+	if runtime.GOOS == "linux" {
+		found = procNetProcess("/proc", remote.Port, local.Port)
+	} else if out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", remote.Port),
+		"-sTCP:ESTABLISHED", "-FpcL").Output(); err == nil {
+		found = parseLsofProcess(out, os.Getpid())
+	}
+	if found == nil {
+		found = &TunnelClient{Command: "unknown"}
+	}
+	found.SourcePort = remote.Port
+	return found
+}
+
+// procNetProcess finds the socket with the given local and remote ports in
+// /proc/net/tcp{,6}, then the process holding it
+func procNetProcess(procRoot string, localPort, remotePort int) *TunnelClient {
+	for _, name := range []string{"tcp", "tcp6"} {
+		data, err := os.ReadFile(filepath.Join(procRoot, "net", name))
+		if err != nil {
+			continue
+		}
+		inode, uid, ok := findProcNetSocket(data, localPort, remotePort)
+		if !ok {
+			continue
+		}
+		client := &TunnelClient{Command: "unknown", User: uidName(uid)}
+		if pid := findSocketOwner(procRoot, inode); pid > 0 {
+			client.PID = pid
+			if comm, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm")); err == nil {
+				client.Command = strings.TrimSpace(string(comm))
+			}
+		}
+		return client
+	}
+	return nil
+}
+
+// findProcNetSocket returns the inode and owner of the socket with the
+// given ports in a /proc/net/tcp table. Addresses are hex ip:port.
+func findProcNetSocket(data []byte, localPort, remotePort int) (inode, uid string, ok bool) {
+	port := func(addr string) int {
+		_, hex, _ := strings.Cut(addr, ":")
+		n, _ := strconv.ParseInt(hex, 16, 32)
+		return int(n)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[0] == "sl" {
+			continue
+		}
+		if port(fields[1]) == localPort && port(fields[2]) == remotePort {
+			return fields[9], fields[7], true
+		}
+	}
+	return "", "", false
+}
+
+// findSocketOwner returns the PID with a file descriptor for the socket
+// inode, or 0. Other users' processes can't be read and are skipped.
+func findSocketOwner(procRoot, inode string) int {
+	target := "socket:[" + inode + "]"
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return 0
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join(procRoot, e.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(procRoot, e.Name(), "fd", fd.Name())); err == nil && link == target {
+				return pid
+			}
+		}
+	}
+	return 0
+}
+
+// parseLsofProcess reads 'lsof -FpcL' output and returns the first process
+// other than skipPID (rw itself holds the other end of the connection)
+func parseLsofProcess(out []byte, skipPID int) *TunnelClient {
+	var current *TunnelClient
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		value := line[1:]
+		switch line[0] {
+		case 'p':
+			if current != nil && current.PID != skipPID {
+				return current
+			}
+			pid, _ := strconv.Atoi(value)
+			current = &TunnelClient{PID: pid, Command: "unknown"}
+		case 'c':
+			if current != nil {
+				current.Command = value
+			}
+		case 'L':
+			if current != nil {
+				current.User = value
+			}
+		}
+	}
+	if current != nil && current.PID != skipPID {
+		return current
+	}
+	return nil
+}
+
+// uidName returns the user name for a numeric uid, or the uid itself
+func uidName(uid string) string {
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
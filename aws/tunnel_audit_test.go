@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"context"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFindProcNetSocket(t *testing.T) {
+	table := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1538 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 4100 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:D431 0100007F:1538 01 00000000:00000000 00:00000000 00000000  1000        0 4242 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:1538 0100007F:D431 01 00000000:00000000 00:00000000 00000000  1000        0 4243 1 0000000000000000 20 4 30 10 -1
+`)
+	// Client port 0xD431 = 54321 connected to the tunnel on 0x1538 = 5432
+	inode, uid, ok := findProcNetSocket(table, 54321, 5432)
+	if !ok || inode != "4242" || uid != "1000" {
+		t.Errorf("findProcNetSocket() = %q, %q, %v; want 4242, 1000, true", inode, uid, ok)
+	}
+	if _, _, ok := findProcNetSocket(table, 54322, 5432); ok {
+		t.Error("findProcNetSocket() matched a port with no socket")
+	}
+}
+
+func TestParseLsofProcess(t *testing.T) {
+	out := []byte("p100\ncrw\nLalice\np200\ncpsql\nLalice\n")
+	got := parseLsofProcess(out, 100)
+	if got == nil || got.PID != 200 || got.Command != "psql" || got.User != "alice" {
+		t.Errorf("parseLsofProcess() = %+v, want psql (pid 200, alice)", got)
+	}
+	if got := parseLsofProcess([]byte("p100\ncrw\n"), 100); got != nil {
+		t.Errorf("parseLsofProcess() = %+v, want nil when only rw holds the port", got)
+	}
+}
+
+// TestTunnelProxyReportsConnectingProcess connects to a tunnel proxy from
+// this process and checks the proxy identifies it
+func TestTunnelProxyReportsConnectingProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process lookup via /proc is Linux only")
+	}
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	port, err := freeLocalPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy, err := listenTunnelProxy(port, target.Addr().(*net.TCPAddr).Port, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := make(chan *TunnelClient, 1)
+	proxy.onConnect = func(conn net.Conn) { clients <- connectingProcess(conn) }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go proxy.serve(ctx)
+
+	conn, err := net.Dial("tcp", proxy.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	select {
+	case client := <-clients:
+		if client.PID != os.Getpid() || client.Command == "unknown" {
+			t.Errorf("connectingProcess() = %+v, want pid %d", client, os.Getpid())
+		}
+		if client.SourcePort != conn.LocalAddr().(*net.TCPAddr).Port {
+			t.Errorf("SourcePort = %d, want %d", client.SourcePort, conn.LocalAddr().(*net.TCPAddr).Port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy never reported the connection")
+	}
+}
@@ -0,0 +1,246 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateUnits are the suffixes ParseRate accepts, in bytes per second. Bit
+// units follow tc: 1mbit is 1,000,000 bits per second.
+var rateUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"gbit", 1e9 / 8}, {"mbit", 1e6 / 8}, {"kbit", 1e3 / 8},
+	{"gb", 1e9}, {"mb", 1e6}, {"kb", 1e3}, {"b", 1},
+}
+
+// ParseRate parses a bandwidth such as "10mbit", "512kbit" or "2mb" (bytes)
+// into bytes per second. A plain number is bytes per second.
+func ParseRate(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	number, multiplier := s, 1.0
+	for _, u := range rateUnits {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			number, multiplier = n, u.bytes
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid rate %q (e.g. 10mbit, 512kbit, 2mb)", s)
+	}
+	rate := int64(value * multiplier)
+	if rate < 1 {
+		return 0, fmt.Errorf("rate %q is below 1 byte per second", s)
+	}
+	return rate, nil
+}
+
+// FormatRate formats bytes per second in bits, e.g. "10mbit"
+func FormatRate(bytesPerSecond int64) string {
+	bits := float64(bytesPerSecond) * 8
+	switch {
+	case bits >= 1e9:
+		return strconv.FormatFloat(bits/1e9, 'f', -1, 64) + "gbit"
+	case bits >= 1e6:
+		return strconv.FormatFloat(bits/1e6, 'f', -1, 64) + "mbit"
+	default:
+		return strconv.FormatFloat(bits/1e3, 'f', -1, 64) + "kbit"
+	}
+}
+
+// socatArgs returns the socat command run in the tunnel pod. With a
+// keepalive, both the connection from port-forward and the one to the
+// remote host send TCP keepalives, so NAT gateways on the way don't drop
+// idle connections. socat takes whole seconds, so a sub-second keepalive is
+// rounded up rather than disabled.
+func socatArgs(remoteHost string, remotePort int, keepalive time.Duration) []string {
+	options := ""
+	if secs := int(math.Ceil(keepalive.Seconds())); secs > 0 {
+		options = fmt.Sprintf(",keepalive,keepidle=%d,keepintvl=%d", secs, secs)
+	}
+	return []string{
+		"socat",
+		fmt.Sprintf("tcp-listen:%d,fork,reuseaddr%s", remotePort, options),
+		fmt.Sprintf("tcp:%s:%d%s", remoteHost, remotePort, options),
+	}
+}
+
+// rateLimiter is a token bucket shared by every connection of a tunnel, so
+// the limit applies to the tunnel as a whole
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	rate := float64(bytesPerSecond)
+	// A tenth of a second's worth, but at least one copy buffer
+	burst := max(rate/10, 32*1024)
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until n bytes may be sent; n must not exceed the burst
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedCopy copies src to dst no faster than the limiter allows; a nil
+// limiter copies at full speed
+func limitedCopy(ctx context.Context, dst io.Writer, src io.Reader, limiter *rateLimiter) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if limiter != nil {
+				if waitErr := limiter.wait(ctx, n); waitErr != nil {
+					return waitErr
+				}
+			}
+			if _, writeErr := dst.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tunnelProxy listens on a local port and forwards connections to
+// port-forward's port. It limits each direction to a rate, when one is set,
+// and reports each new connection to onConnect, when set.
+type tunnelProxy struct {
+	listener  net.Listener
+	target    string
+	upload    *rateLimiter
+	download  *rateLimiter
+	keepalive time.Duration
+	onConnect func(net.Conn)
+}
+
+// listenTunnelProxy reserves the tunnel's local port for the proxy.
+// bytesPerSecond of 0 leaves the tunnel unlimited.
+func listenTunnelProxy(localPort, targetPort int, bytesPerSecond int64, keepalive time.Duration) (*tunnelProxy, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", localPort))
+	if err != nil {
+		return nil, err
+	}
+	p := &tunnelProxy{
+		listener:  ln,
+		target:    fmt.Sprintf("127.0.0.1:%d", targetPort),
+		keepalive: keepalive,
+	}
+	if bytesPerSecond > 0 {
+		p.upload = newRateLimiter(bytesPerSecond)
+		p.download = newRateLimiter(bytesPerSecond)
+	}
+	return p, nil
+}
+
+// serve accepts connections until ctx is done
+func (p *tunnelProxy) serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		p.listener.Close()
+	}()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(ctx, conn)
+	}
+}
+
+// closeWriter is a connection that can shut down its sending side alone
+type closeWriter interface {
+	CloseWrite() error
+}
+
+func (p *tunnelProxy) handle(ctx context.Context, client net.Conn) {
+	defer client.Close()
+	if p.onConnect != nil {
+		p.onConnect(client)
+	}
+	dialer := net.Dialer{Timeout: 10 * time.Second, KeepAlive: p.keepalive}
+	upstream, err := dialer.DialContext(ctx, "tcp", p.target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	if tcp, ok := client.(*net.TCPConn); ok && p.keepalive > 0 {
+		tcp.SetKeepAlive(true)
+		tcp.SetKeepAlivePeriod(p.keepalive)
+	}
+
+	// A side that finishes sending is half-closed on the other connection,
+	// so protocols that shut down writes to end a request still get the
+	// response; an error on either side ends the connection
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn, limiter *rateLimiter) {
+		err := limitedCopy(ctx, dst, src, limiter)
+		if cw, ok := dst.(closeWriter); ok && err == nil {
+			cw.CloseWrite()
+		} else {
+			client.Close()
+			upstream.Close()
+		}
+		done <- struct{}{}
+	}
+	go pipe(upstream, client, p.upload)
+	go pipe(client, upstream, p.download)
+	for range 2 {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// freeLocalPort asks the OS for an unused port for port-forward to bind
+// behind the proxy
+func freeLocalPort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
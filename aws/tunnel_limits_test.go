@@ -0,0 +1,131 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"10mbit", 1_250_000},
+		{"512kbit", 64_000},
+		{"1gbit", 125_000_000},
+		{"2mb", 2_000_000},
+		{"1.5MB", 1_500_000},
+		{"4096", 4096},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil {
+			t.Errorf("ParseRate(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "fast", "-1mbit", "0", "mbit"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	for _, s := range []string{"10mbit", "512kbit", "1gbit"} {
+		rate, err := ParseRate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatRate(rate); got != s {
+			t.Errorf("FormatRate(ParseRate(%q)) = %q", s, got)
+		}
+	}
+}
+
+func TestSocatArgs(t *testing.T) {
+	got := strings.Join(socatArgs("db.internal", 5432, 0), " ")
+	if want := "socat tcp-listen:5432,fork,reuseaddr tcp:db.internal:5432"; got != want {
+		t.Errorf("without keepalive = %q, want %q", got, want)
+	}
+
+	got = strings.Join(socatArgs("db.internal", 5432, 30*time.Second), " ")
+	want := "socat tcp-listen:5432,fork,reuseaddr,keepalive,keepidle=30,keepintvl=30 tcp:db.internal:5432,keepalive,keepidle=30,keepintvl=30"
+	if got != want {
+		t.Errorf("with keepalive = %q, want %q", got, want)
+	}
+	// Sub-second keepalives round up rather than turning keepalive off
+	got = strings.Join(socatArgs("db.internal", 5432, 500*time.Millisecond), " ")
+	if !strings.Contains(got, "keepidle=1,keepintvl=1") {
+		t.Errorf("with a 500ms keepalive = %q, want keepidle=1", got)
+	}
+}
+
+func TestLimitedCopy(t *testing.T) {
+	// 64KB at 128KB/s with a 32KB burst takes about a quarter of a second
+	data := bytes.Repeat([]byte("x"), 64*1024)
+	var out bytes.Buffer
+	start := time.Now()
+	if err := limitedCopy(context.Background(), &out, bytes.NewReader(data), newRateLimiter(128*1024)); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if out.Len() != len(data) {
+		t.Fatalf("copied %d bytes, want %d", out.Len(), len(data))
+	}
+	if elapsed < 200*time.Millisecond {
+		t.Errorf("copy took %v, expected the limit to slow it to ~250ms", elapsed)
+	}
+}
+
+func TestTunnelProxyHalfClose(t *testing.T) {
+	// An upstream that answers only once the client has finished sending
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, _ := io.ReadAll(conn)
+		conn.Write(append([]byte("got "), request...))
+	}()
+
+	localPort, err := freeLocalPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := listenTunnelProxy(localPort, upstream.Addr().(*net.TCPAddr).Port, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.serve(ctx)
+
+	conn, err := net.Dial("tcp", p.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("ping"))
+	conn.(*net.TCPConn).CloseWrite()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := io.ReadAll(conn)
+	if err != nil || string(reply) != "got ping" {
+		t.Errorf("reply = %q, %v, want %q after a half-close", reply, err, "got ping")
+	}
+}
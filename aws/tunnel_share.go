@@ -0,0 +1,152 @@
+package aws
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"rolewalkers/internal/utils"
+	"strings"
+	"time"
+)
+
+// tunnelManifestVersion is bumped when the manifest format changes incompatibly
+const tunnelManifestVersion = 1
+
+// TunnelManifest describes a tunnel setup so a teammate can reproduce it on
+// their machine. It holds configuration only, never credentials or endpoints:
+// the joining side resolves those with its own profile.
+type TunnelManifest struct {
+	Version     int       `json:"version"`
+	Service     string    `json:"service"`
+	Environment string    `json:"environment"`
+	Profile     string    `json:"profile"`
+	NodeType    string    `json:"node_type,omitempty"`
+	DBType      string    `json:"db_type,omitempty"`
+	LocalPort   int       `json:"local_port"`
+	RemotePort  int       `json:"remote_port"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ShareManifest builds a manifest for the given tunnel configuration
+func (tm *TunnelManager) ShareManifest(config TunnelConfig) (*TunnelManifest, error) {
+	service := strings.ToLower(config.Service)
+	env := strings.ToLower(config.Environment)
+
+	localPort := config.LocalPort
+	if localPort == 0 {
+		var err error
+		localPort, err = tm.localPort(service, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &TunnelManifest{
+		Version:     tunnelManifestVersion,
+		Service:     service,
+		Environment: env,
+		Profile:     tm.kubeManager.getProfileNameForEnv(env),
+		NodeType:    config.NodeType,
+		DBType:      config.DBType,
+		LocalPort:   localPort,
+		RemotePort:  tm.remotePort(service),
+		CreatedBy:   utils.GetCurrentUsername(),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// LoadTunnelManifest reads and validates a manifest file
+func LoadTunnelManifest(path string) (*TunnelManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return ParseTunnelManifest(data)
+}
+
+// ParseTunnelManifest decodes and validates a manifest
+func ParseTunnelManifest(data []byte) (*TunnelManifest, error) {
+	var m TunnelManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that the manifest can be applied
+func (m *TunnelManifest) Validate() error {
+	if m.Version != tunnelManifestVersion {
+		return fmt.Errorf("unsupported manifest version %d (expected %d); upgrade rw", m.Version, tunnelManifestVersion)
+	}
+	if m.Service == "" || m.Environment == "" {
+		return fmt.Errorf("invalid manifest: service and environment are required")
+	}
+	if m.LocalPort < 1 || m.LocalPort > 65535 {
+		return fmt.Errorf("invalid manifest: local_port %d is out of range", m.LocalPort)
+	}
+	switch m.NodeType {
+	case "", "read", "write":
+	default:
+		return fmt.Errorf("invalid manifest: node_type must be read or write, got %q", m.NodeType)
+	}
+	switch m.DBType {
+	case "", "query", "command":
+	default:
+		return fmt.Errorf("invalid manifest: db_type must be query or command, got %q", m.DBType)
+	}
+	return nil
+}
+
+// TunnelConfig converts the manifest into a tunnel configuration
+func (m *TunnelManifest) TunnelConfig() TunnelConfig {
+	return TunnelConfig{
+		Service:     m.Service,
+		Environment: m.Environment,
+		NodeType:    cmp.Or(m.NodeType, "read"),
+		DBType:      cmp.Or(m.DBType, "query"),
+		LocalPort:   m.LocalPort,
+	}
+}
+
+// JoinWarnings reports differences between the manifest and this machine
+// that would stop the tunnel from matching the sharer's setup.
+func (tm *TunnelManager) JoinWarnings(m *TunnelManifest) []string {
+	var warnings []string
+
+	if local := tm.kubeManager.getProfileNameForEnv(m.Environment); m.Profile != "" && local != m.Profile {
+		warnings = append(warnings, fmt.Sprintf(
+			"environment %s uses profile %s here, but the manifest expects %s", m.Environment, local, m.Profile))
+	}
+
+	if m.Profile != "" && tm.profileSwitcher != nil {
+		profiles, err := tm.profileSwitcher.configManager.GetProfiles()
+		if err == nil && !hasProfile(profiles, m.Profile) {
+			warnings = append(warnings, fmt.Sprintf(
+				"profile %s is not configured in ~/.aws/config; log in with it before joining", m.Profile))
+		}
+	}
+
+	if !utils.IsPortAvailable(m.LocalPort) {
+		msg := fmt.Sprintf("local port %d is already in use", m.LocalPort)
+		if owner := utils.PortOwner(m.LocalPort); owner != "" {
+			msg += " by " + owner
+		}
+		warnings = append(warnings, msg)
+	}
+
+	return warnings
+}
+
+func hasProfile(profiles []Profile, name string) bool {
+	for _, p := range profiles {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
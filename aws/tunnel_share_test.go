@@ -0,0 +1,46 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTunnelManifest(t *testing.T) {
+	data := []byte(`{"version":1,"service":"db","environment":"dev","profile":"zenith-dev","node_type":"write","local_port":5433,"remote_port":5432}`)
+
+	m, err := ParseTunnelManifest(data)
+	if err != nil {
+		t.Fatalf("ParseTunnelManifest() error = %v", err)
+	}
+
+	cfg := m.TunnelConfig()
+	if cfg.Service != "db" || cfg.Environment != "dev" || cfg.LocalPort != 5433 {
+		t.Errorf("TunnelConfig() = %+v", cfg)
+	}
+	if cfg.NodeType != "write" || cfg.DBType != "query" {
+		t.Errorf("TunnelConfig() node/db type = %s/%s, want write/query", cfg.NodeType, cfg.DBType)
+	}
+}
+
+func TestParseTunnelManifestInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not json", `nope`, "invalid manifest"},
+		{"future version", `{"version":2,"service":"db","environment":"dev","local_port":5433}`, "unsupported manifest version"},
+		{"missing service", `{"version":1,"environment":"dev","local_port":5433}`, "service and environment are required"},
+		{"bad port", `{"version":1,"service":"db","environment":"dev","local_port":70000}`, "out of range"},
+		{"bad node type", `{"version":1,"service":"db","environment":"dev","local_port":5433,"node_type":"primary"}`, "node_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTunnelManifest([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseTunnelManifest() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
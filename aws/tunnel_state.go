@@ -22,6 +22,9 @@ type TunnelInfo struct {
 	RemotePort  int       `json:"remote_port"`
 	StartedAt   time.Time `json:"started_at"`
 	PID         int       `json:"pid,omitempty"` // port-forward process ID
+	// KubeContext is the kubectl context the tunnel's pod runs in, so it's
+	// found and deleted there after the current context changed
+	KubeContext string `json:"kube_context,omitempty"`
 }
 
 // tunnelStateData is the JSON-serialisable subset of TunnelState.
@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"rolewalkers/internal/k8s"
+	"rolewalkers/internal/utils"
+)
+
+// TunnelAccessPod is a pod in the tunnel-access namespace, as reported by
+// 'rw report tunnel-usage'
+type TunnelAccessPod struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner"`
+	Email     string    `json:"email,omitempty"`
+	Operation string    `json:"operation,omitempty"`
+	Phase     string    `json:"phase"`
+	CreatedAt time.Time `json:"created_at"`
+	// Stale pods are older than the report's threshold or no longer
+	// running: likely left behind by an rw process that didn't clean up
+	Stale bool `json:"stale"`
+}
+
+// TunnelUsageOwner summarizes one user's pods
+type TunnelUsageOwner struct {
+	Owner  string    `json:"owner"`
+	Pods   int       `json:"pods"`
+	Stale  int       `json:"stale"`
+	Oldest time.Time `json:"oldest"`
+}
+
+// TunnelUsageReport lists the pods in the tunnel-access namespace of a
+// cluster, grouped by the user who created them
+type TunnelUsageReport struct {
+	Context     string             `json:"context,omitempty"`
+	Namespace   string             `json:"namespace"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	StaleAfter  string             `json:"stale_after"`
+	Pods        []TunnelAccessPod  `json:"pods"`
+	Owners      []TunnelUsageOwner `json:"owners"`
+}
+
+// TunnelUsage lists the pods in the tunnel-access namespace across all
+// users. kubeContext selects the cluster without switching to it; empty
+// uses the current context. Pods older than staleAfter are flagged.
+func TunnelUsage(kubeContext string, staleAfter time.Duration) (*TunnelUsageReport, error) {
+	out, err := k8s.SharedRunner().Run(context.Background(), kubeContext, "-n", TunnelAccessNamespace(), "get", "pods", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl get pods failed: %w", err)
+	}
+
+	now := time.Now()
+	pods, err := parseTunnelAccessPods(out, now, staleAfter)
+	if err != nil {
+		return nil, err
+	}
+	return &TunnelUsageReport{
+		Context:     kubeContext,
+		Namespace:   TunnelAccessNamespace(),
+		GeneratedAt: now.UTC(),
+		StaleAfter:  utils.FormatDuration(staleAfter),
+		Pods:        pods,
+		Owners:      summarizeTunnelUsage(pods),
+	}, nil
+}
+
+// parseTunnelAccessPods reads 'kubectl get pods -o json' output. Owners
+// come from the created-by label rw puts on every pod it creates.
+func parseTunnelAccessPods(data []byte, now time.Time, staleAfter time.Duration) ([]TunnelAccessPod, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name              string            `json:"name"`
+				CreationTimestamp time.Time         `json:"creationTimestamp"`
+				Labels            map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pod list: %w", err)
+	}
+
+	pods := make([]TunnelAccessPod, 0, len(list.Items))
+	for _, item := range list.Items {
+		labels := item.Metadata.Labels
+		pod := TunnelAccessPod{
+			Name:      item.Metadata.Name,
+			Owner:     cmp.Or(labels["created-by"], "unknown"),
+			Email:     labels["creator-email"],
+			Operation: labels["operation"],
+			Phase:     item.Status.Phase,
+			CreatedAt: item.Metadata.CreationTimestamp,
+		}
+		pod.Stale = (staleAfter > 0 && now.Sub(pod.CreatedAt) > staleAfter) ||
+			pod.Phase == "Succeeded" || pod.Phase == "Failed"
+		pods = append(pods, pod)
+	}
+	slices.SortFunc(pods, func(a, b TunnelAccessPod) int {
+		return cmp.Or(strings.Compare(a.Owner, b.Owner), a.CreatedAt.Compare(b.CreatedAt))
+	})
+	return pods, nil
+}
+
+// summarizeTunnelUsage counts pods per owner, owners with the most pods
+// first
+func summarizeTunnelUsage(pods []TunnelAccessPod) []TunnelUsageOwner {
+	byOwner := make(map[string]*TunnelUsageOwner)
+	var owners []*TunnelUsageOwner
+	for _, pod := range pods {
+		o, ok := byOwner[pod.Owner]
+		if !ok {
+			o = &TunnelUsageOwner{Owner: pod.Owner, Oldest: pod.CreatedAt}
+			byOwner[pod.Owner] = o
+			owners = append(owners, o)
+		}
+		o.Pods++
+		if pod.Stale {
+			o.Stale++
+		}
+		if pod.CreatedAt.Before(o.Oldest) {
+			o.Oldest = pod.CreatedAt
+		}
+	}
+
+	summary := make([]TunnelUsageOwner, len(owners))
+	for i, o := range owners {
+		summary[i] = *o
+	}
+	slices.SortStableFunc(summary, func(a, b TunnelUsageOwner) int { return b.Pods - a.Pods })
+	return summary
+}
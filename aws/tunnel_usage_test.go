@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseTunnelAccessPods(t *testing.T) {
+	data := []byte(`{"items": [
+		{"metadata": {"name": "dbtunnel-bob-1", "creationTimestamp": "2026-01-01T09:00:00Z",
+			"labels": {"created-by": "bob", "creator-email": "bob@example.com"}}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "psql-alice-2", "creationTimestamp": "2026-01-02T11:00:00Z",
+			"labels": {"created-by": "alice", "operation": "backup"}}, "status": {"phase": "Failed"}},
+		{"metadata": {"name": "dbtunnel-bob-3", "creationTimestamp": "2026-01-02T10:00:00Z",
+			"labels": {"created-by": "bob"}}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "debug", "creationTimestamp": "2026-01-02T11:30:00Z"}, "status": {"phase": "Running"}}
+	]}`)
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	pods, err := parseTunnelAccessPods(data, now, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("parseTunnelAccessPods() error: %v", err)
+	}
+	var names []string
+	stale := map[string]bool{}
+	for _, p := range pods {
+		names = append(names, p.Name)
+		stale[p.Name] = p.Stale
+	}
+	if want := []string{"psql-alice-2", "dbtunnel-bob-1", "dbtunnel-bob-3", "debug"}; !slices.Equal(names, want) {
+		t.Errorf("pods = %v, want %v (by owner, then age)", names, want)
+	}
+	for name, want := range map[string]bool{"dbtunnel-bob-1": true, "psql-alice-2": true, "dbtunnel-bob-3": false, "debug": false} {
+		if stale[name] != want {
+			t.Errorf("%s stale = %v, want %v", name, stale[name], want)
+		}
+	}
+
+	owners := summarizeTunnelUsage(pods)
+	if len(owners) != 3 || owners[0].Owner != "bob" || owners[0].Pods != 2 || owners[0].Stale != 1 {
+		t.Fatalf("summary = %+v, want bob first with 2 pods, 1 stale", owners)
+	}
+	if !owners[0].Oldest.Equal(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("bob's oldest pod = %v", owners[0].Oldest)
+	}
+	if owners[2].Owner != "unknown" {
+		t.Errorf("unlabelled pod owner = %q, want unknown", owners[2].Owner)
+	}
+}
@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+	"time"
+)
+
+// UndoManager reverts the most recent maintenance or scaling change using
+// the audit log as the record of what the previous state was
+type UndoManager struct {
+	configRepo         *db.ConfigRepository
+	maintenanceManager *MaintenanceManager
+	scalingManager     *ScalingManager
+	window             time.Duration
+}
+
+// NewUndoManagerWithDeps creates a new UndoManager with shared dependencies
+func NewUndoManagerWithDeps(repo *db.ConfigRepository, mm *MaintenanceManager, sm *ScalingManager) *UndoManager {
+	return &UndoManager{
+		configRepo:         repo,
+		maintenanceManager: mm,
+		scalingManager:     sm,
+		window:             config.Get().UndoWindowDuration(),
+	}
+}
+
+// Window returns how long after a change it can still be undone
+func (um *UndoManager) Window() time.Duration {
+	return um.window
+}
+
+// LastChange returns the most recent change that can still be undone
+func (um *UndoManager) LastChange() (*db.AuditEntry, error) {
+	if um.configRepo == nil {
+		return nil, fmt.Errorf("undo requires the database (audit log unavailable)")
+	}
+
+	entry, err := um.configRepo.GetLastAuditEntry(AuditActionMaintenance, AuditActionScale)
+	if err != nil {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	age := time.Since(entry.CreatedAt)
+	if age > um.window {
+		return nil, fmt.Errorf("nothing to undo: last change (%s on %s) was %s, outside the %s undo window",
+			entry.Action, entry.Environment, utils.FormatRelative(entry.CreatedAt, time.Now()), um.window)
+	}
+
+	return entry, nil
+}
+
+// Undo restores the previous state recorded in the given audit entry and
+// marks the entry as undone
+func (um *UndoManager) Undo(entry *db.AuditEntry) error {
+	if !entry.PreviousState.Valid {
+		return fmt.Errorf("audit entry %d has no previous state recorded", entry.ID)
+	}
+
+	switch entry.Action {
+	case AuditActionMaintenance:
+		var states []MaintenanceState
+		if err := json.Unmarshal([]byte(entry.PreviousState.String), &states); err != nil {
+			return fmt.Errorf("failed to parse previous state: %w", err)
+		}
+		if err := um.maintenanceManager.Restore(entry.Environment, states); err != nil {
+			return err
+		}
+	case AuditActionScale:
+		var states []HPAState
+		if err := json.Unmarshal([]byte(entry.PreviousState.String), &states); err != nil {
+			return fmt.Errorf("failed to parse previous state: %w", err)
+		}
+		if err := um.scalingManager.Restore(entry.Environment, states); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot undo action: %s", entry.Action)
+	}
+
+	return um.configRepo.MarkAuditUndone(entry.ID)
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rolewalkers/internal/utils"
+)
+
+// validAliasName restricts alias names to simple shell-friendly words.
+var validAliasName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+func isBuiltinCommand(name string) bool {
+	for _, cmd := range builtinCommands {
+		if cmd == name {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAlias replaces a leading user-defined alias with its expansion.
+// Built-in commands always win and expansion happens once, so an alias
+// cannot refer to another alias.
+func (c *CLI) expandAlias(args []string) []string {
+	if len(args) == 0 || c.dbRepo == nil || isBuiltinCommand(args[0]) {
+		return args
+	}
+
+	alias, err := c.dbRepo.GetAlias(args[0])
+	if err != nil {
+		return args
+	}
+
+	expanded, err := splitCommandLine(alias.Expansion)
+	if err != nil || len(expanded) == 0 {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Ignoring invalid alias %q: %s\n", alias.Name, alias.Expansion)
+		return args
+	}
+
+	return append(expanded, args[1:]...)
+}
+
+func (c *CLI) alias(args []string) error {
+	if err := c.requireDB("rw alias"); err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return c.aliasList()
+	}
+
+	switch args[0] {
+	case "add", "set":
+		return c.aliasAdd(args[1:])
+	case "list", "ls":
+		return c.aliasList()
+	case "remove", "rm", "delete":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: rw alias remove <name>")
+		}
+		if err := c.dbRepo.DeleteAlias(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf(utils.OK()+" Removed alias '%s'\n", args[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown alias subcommand: %s\nUse: add, list, remove", args[0])
+	}
+}
+
+func (c *CLI) aliasAdd(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: rw alias add <name> \"<command> [args...]\"\n\nExample:\n  rw alias add pdb \"db connect prod --write\"")
+	}
+
+	name := args[0]
+	expansion := strings.Join(args[1:], " ")
+
+	if !validAliasName.MatchString(name) {
+		return fmt.Errorf("invalid alias name: %s (letters, digits, '-' and '_' only)", name)
+	}
+	if isBuiltinCommand(name) {
+		return fmt.Errorf("'%s' is a built-in command and cannot be used as an alias", name)
+	}
+
+	parts, err := splitCommandLine(expansion)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("alias expansion cannot be empty")
+	}
+	if parts[0] == "rw" {
+		parts = parts[1:]
+		expansion = strings.TrimSpace(strings.TrimPrefix(expansion, "rw"))
+	}
+	if len(parts) == 0 || !isBuiltinCommand(parts[0]) {
+		return fmt.Errorf("alias must expand to a built-in command (got '%s')\nRun 'rw help' for available commands", strings.Join(parts, " "))
+	}
+
+	if existing, err := c.dbRepo.GetAlias(name); err == nil {
+		fmt.Printf("  Replacing existing alias '%s' (was: %s)\n", name, existing.Expansion)
+	}
+
+	if err := c.dbRepo.SetAlias(name, expansion); err != nil {
+		return fmt.Errorf("failed to save alias: %w", err)
+	}
+
+	fmt.Printf(utils.OK()+" Alias '%s' "+utils.Arrow()+" rw %s\n", name, expansion)
+	return nil
+}
+
+func (c *CLI) aliasList() error {
+	aliases, err := c.dbRepo.GetAllAliases()
+	if err != nil {
+		return err
+	}
+
+	if len(aliases) == 0 {
+		fmt.Println("No aliases defined.")
+		fmt.Println("  Add one with: rw alias add <name> \"<command> [args...]\"")
+		return nil
+	}
+
+	fmt.Println("Aliases:")
+	fmt.Println(strings.Repeat("-", 50))
+	for _, a := range aliases {
+		fmt.Printf("  %-15s rw %s\n", a.Name, a.Expansion)
+	}
+	return nil
+}
+
+// splitCommandLine splits a command string into arguments, honouring single
+// and double quotes (e.g. `ssm get "/dev/zenith/my param"`).
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in: %s", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// bannerSkipped are commands that never print the production banner:
+// prompt scripts run 'rw context' on every prompt, and the rest don't act
+// on an environment
+var bannerSkipped = []string{"help", "version", "example", "completion", "docs", "context", "tray"}
+
+// productionBanner prints a red banner to stderr when the active AWS profile
+// or kubectl context belongs to an environment flagged as production, so a
+// command typed in the wrong terminal is noticed before it does damage
+func (c *CLI) productionBanner(command string) {
+	info, ok := lookupCommand(command)
+	if !ok || slices.Contains(bannerSkipped, info.Name) {
+		return
+	}
+	env := c.activeProductionEnv()
+	if env == "" {
+		return
+	}
+
+	text := fmt.Sprintf("PRODUCTION: %s (profile %s)", strings.ToUpper(env), c.configManager.GetActiveProfile())
+	if utils.PlainEnabled() {
+		fmt.Fprintln(utils.Stderr, "Warning: "+text)
+		return
+	}
+	siren := utils.Emoji("🚨", "!!")
+	fmt.Fprintln(utils.Stderr, utils.Danger(fmt.Sprintf(" %s  %s  %s ", siren, text, siren)))
+}
+
+// activeProductionEnv returns the environment the active profile or kubectl
+// context points at when it is flagged as production, or ""
+func (c *CLI) activeProductionEnv() string {
+	if c.dbRepo == nil {
+		return ""
+	}
+	production := aws.ProductionEnvs(c.dbRepo)
+	if len(production) == 0 {
+		return ""
+	}
+	envs, err := c.dbRepo.GetAllEnvironments()
+	if err != nil {
+		return ""
+	}
+	kubeContext, _ := aws.CachedKubeContext()
+	env := aws.ResolveContextEnvironment(envs, c.configManager.GetActiveProfile(), kubeContext)
+	if env == "" || !aws.IsProductionEnv(c.dbRepo, env) {
+		return ""
+	}
+	return env
+}
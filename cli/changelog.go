@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"rolewalkers/internal/changelog"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// releaseNoticeSkipped are commands that don't announce an upgrade: they
+// only print text, or their output is read by other programs
+var releaseNoticeSkipped = []string{"help", "version", "example", "completion", "docs", "changelog", clipboardClearCommand}
+
+// checkUpgrade records the version that ran, and says once when it differs
+// from the last one, pointing at 'rw changelog' for what changed
+func (c *CLI) checkUpgrade(command string) {
+	if c.dbRepo == nil {
+		return
+	}
+	if info, ok := lookupCommand(command); ok && slices.Contains(releaseNoticeSkipped, info.Name) {
+		return
+	}
+	last, err := c.dbRepo.GetSetting(db.SettingLastRunVersion)
+	if err != nil || last == Version {
+		return
+	}
+	if err := c.dbRepo.SetSetting(db.SettingLastRunVersion, Version); err != nil {
+		return
+	}
+	if last == "" {
+		// A new install, or the first version to record itself
+		return
+	}
+
+	// Keep the notes of the releases in between for 'rw changelog'
+	if seen, _ := c.dbRepo.GetSetting(db.SettingChangelogVersion); seen == "" {
+		c.dbRepo.SetSetting(db.SettingChangelogVersion, last)
+	}
+	if !changelog.Less(last, Version) {
+		return
+	}
+	fmt.Fprintf(utils.Stderr, utils.OK()+" rw was upgraded from v%s to v%s. Run 'rw changelog' to see what changed.\n", last, Version)
+}
+
+// changelogCmd shows the release notes since the last ones shown, and the
+// commands and flags scheduled for removal
+func (c *CLI) changelogCmd(args []string) error {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+
+	releases, err := changelog.Releases()
+	if err != nil {
+		return fmt.Errorf("failed to read the release notes: %w", err)
+	}
+	all := fs.Bool("all")
+	explicit := strings.TrimPrefix(fs.String("since", ""), "v")
+	since := explicit
+	if since == "" && c.dbRepo != nil {
+		since, _ = c.dbRepo.GetSetting(db.SettingChangelogVersion)
+	}
+
+	var shown []changelog.Release
+	switch {
+	case all:
+		shown = changelog.Between(releases, "", Version)
+	case since != "":
+		shown = changelog.Between(releases, since, Version)
+	}
+	if len(shown) == 0 && !all && explicit == "" {
+		// Nothing unread: show this version's notes
+		if current := changelog.Between(releases, "", Version); len(current) > 0 {
+			shown = current[:1]
+		}
+	}
+
+	if c.dbRepo != nil {
+		c.dbRepo.SetSetting(db.SettingChangelogVersion, Version)
+	}
+
+	if format == "json" {
+		return printJSON(struct {
+			Releases     []changelog.Release `json:"releases"`
+			Deprecations []deprecation       `json:"deprecations"`
+		}{shown, deprecations})
+	}
+
+	if len(shown) == 0 {
+		fmt.Printf("No release notes after v%s.\n", since)
+	}
+	for i, r := range shown {
+		if i > 0 {
+			fmt.Println()
+		}
+		heading := "v" + r.Version
+		if r.Date != "" {
+			heading += " (" + r.Date + ")"
+		}
+		fmt.Println(utils.Bold(heading))
+		for _, line := range strings.Split(r.Notes, "\n") {
+			if section, ok := strings.CutPrefix(line, "### "); ok {
+				line = utils.Bold(section + ":")
+			}
+			if line == "" {
+				fmt.Println()
+			} else {
+				fmt.Println("  " + line)
+			}
+		}
+	}
+
+	if len(deprecations) > 0 {
+		fmt.Println()
+		fmt.Println(utils.Bold("Scheduled for removal:"))
+		for _, d := range deprecations {
+			fmt.Printf("  %-28s removed in v%s; %s\n", d.usage(), d.RemoveIn, d.Replacement)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// check connects to a service, or any host:port, and performs its
+// protocol's handshake, to tell a broken service from a broken local setup
+func (c *CLI) check(args []string) error {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+	timeout := aws.DefaultCheckTimeout
+	if v := fs.String("timeout", ""); v != "" {
+		if timeout, err = utils.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid --timeout: %w", err)
+		}
+	}
+
+	target, label, err := c.checkTarget(fs)
+	if err != nil {
+		return err
+	}
+	if protocol := fs.String("protocol", ""); protocol != "" {
+		if !slices.Contains(aws.CheckProtocols, protocol) {
+			return fmt.Errorf("unknown protocol: %s\nUse: %s", protocol, strings.Join(aws.CheckProtocols, ", "))
+		}
+		target.Protocol = protocol
+	}
+	if fs.Bool("tls") {
+		target.TLS = true
+	}
+	if fs.Bool("no-tls") {
+		target.TLS = false
+	}
+
+	if format == formatText {
+		switch target.Via {
+		case "tunnel":
+			fmt.Printf("Checking %s through tunnel %s (%s %s %s)\n", label, target.TunnelID, target.Address, utils.Arrow(), target.Remote)
+		case "direct":
+			fmt.Printf("Checking %s directly at %s\n", label, target.Address)
+		default:
+			fmt.Printf("Checking %s\n", target.Address)
+		}
+	}
+
+	result := aws.CheckConnection(target, timeout)
+	if format == formatJSON {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+		if !result.OK {
+			return fmt.Errorf("%s is not reachable", label)
+		}
+		return nil
+	}
+
+	var total time.Duration
+	for _, s := range result.Steps {
+		total += s.Latency
+		mark, detail := utils.OK(), s.Detail
+		if !s.OK {
+			mark, detail = utils.Fail(), s.Error
+		}
+		fmt.Printf("  %s %-20s %9s  %s\n", mark, s.Name, s.Latency.Round(10*time.Microsecond), detail)
+	}
+	fmt.Println()
+	if result.OK {
+		if last := result.Steps[len(result.Steps)-1]; last.Detail == aws.TunnelOnlyDetail {
+			fmt.Printf(utils.Warn()+" The tunnel for %s is open, but the remote end wasn't verified; pass --protocol to check it\n", label)
+			return nil
+		}
+		fmt.Printf(utils.OK()+" %s is reachable (%s)\n", label, total.Round(10*time.Microsecond))
+		return nil
+	}
+	fmt.Println(checkHint(result))
+	return fmt.Errorf("%s is not reachable", label)
+}
+
+// checkTarget resolves the check's arguments: a host:port, or a service
+// and environment (picked interactively when missing)
+func (c *CLI) checkTarget(fs *FlagSet) (aws.CheckTarget, string, error) {
+	if args := fs.Positional(); len(args) == 1 && strings.Contains(args[0], ":") {
+		host, portStr, err := net.SplitHostPort(args[0])
+		port, portErr := strconv.Atoi(portStr)
+		if err != nil || portErr != nil || host == "" {
+			return aws.CheckTarget{}, "", fmt.Errorf("invalid address %q, want host:port", args[0])
+		}
+		protocol := aws.ProtocolForPort(port)
+		return aws.CheckTarget{
+			Address:  args[0],
+			Via:      "address",
+			Protocol: protocol,
+			TLS:      aws.DefaultCheckTLS(protocol, host, port),
+		}, args[0], nil
+	}
+
+	if err := c.requireDB("rw check <service> <env>"); err != nil {
+		return aws.CheckTarget{}, "", err
+	}
+	service, env := fs.Arg(0), fs.Arg(1)
+	if service == "" {
+		picked, err := c.pickService(false)
+		if err != nil {
+			return aws.CheckTarget{}, "", err
+		}
+		service = picked
+	}
+	if env == "" {
+		picked, err := c.pickEnvironment()
+		if err != nil {
+			return aws.CheckTarget{}, "", err
+		}
+		env = picked
+	}
+
+	target, err := c.tunnelManager.ResolveCheckTarget(service, env)
+	if err != nil {
+		return aws.CheckTarget{}, "", err
+	}
+	return target, fmt.Sprintf("%s in %s", service, env), nil
+}
+
+// checkHint says where a failed check points: the local setup, the
+// network path, or the service itself
+func checkHint(result *aws.CheckResult) string {
+	target := result.Target
+	connected := len(result.Steps) > 0 && result.Steps[0].OK
+	switch {
+	case target.Via == "tunnel" && !connected:
+		return "The tunnel's port-forward isn't accepting connections. Check 'rw tunnel list' and restart the tunnel."
+	case target.Via == "tunnel":
+		// kubectl port-forward accepts locally before reaching the pod
+		return "The tunnel is up but the service didn't answer through it: the problem is between the tunnel pod\n" +
+			"and the service (the service itself, its security group, or a stale endpoint in SSM)."
+	case target.Via == "direct" && !connected:
+		return "Direct connections need the VPN. Connect to it, or start a tunnel: rw tunnel start <service> <env>"
+	case !connected:
+		return "Nothing answered at that address. Check the host and port, and that a tunnel or port-forward is running."
+	default:
+		return "Something is listening but didn't answer as expected. Check --protocol and --tls/--no-tls."
+	}
+}
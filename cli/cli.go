@@ -3,9 +3,14 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"rolewalkers/aws"
+	"rolewalkers/gcp"
 	appconfig "rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+	"rolewalkers/provider"
+	"slices"
 	"strings"
 )
 
@@ -17,6 +22,7 @@ type CLI struct {
 	kubeManager        *aws.KubeManager
 	tunnelManager      aws.TunnelManagerI
 	ssmManager         aws.EndpointResolver
+	featureFlags       aws.FeatureFlagManagerI
 	grpcManager        aws.GRPCManagerI
 	dbManager          aws.DatabaseManagerI
 	redisManager       aws.RedisManagerI
@@ -24,9 +30,13 @@ type CLI struct {
 	maintenanceManager aws.MaintenanceManagerI
 	scalingManager     aws.ScalingManagerI
 	replicationManager aws.ReplicationManagerI
+	undoManager        aws.UndoManagerI
+	quotaManager       *aws.QuotaManager
 	dbRepo             *db.ConfigRepository
 	database           *db.DB
+	dbErr              error // why the database is unavailable (degraded mode)
 	configSync         aws.ConfigSyncI
+	providers          *provider.Registry
 }
 
 // NewCLI creates a new CLI instance
@@ -50,9 +60,10 @@ func NewCLI() (*CLI, error) {
 	if err == nil {
 		dbRepo = db.NewConfigRepository(database)
 	} else {
-		fmt.Fprintf(os.Stderr, "⚠ Database initialization failed: %v\n", err)
-		fmt.Fprintf(os.Stderr, "  Some features may be unavailable. Run 'rw config status' for details.\n")
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Database unavailable (%s), running in degraded mode.\n", dbProblem(err).Description())
+		fmt.Fprintf(utils.Stderr, "  Commands that need it will fail. Run 'rw doctor' for repair steps.\n")
 	}
+	dbErr := err
 
 	// Create shared managers with injected dependencies
 	km := aws.NewKubeManagerWithRepo(dbRepo)
@@ -64,19 +75,20 @@ func NewCLI() (*CLI, error) {
 	}
 
 	grpc := aws.NewGRPCManagerWithDeps(km, ps, dbRepo)
-	dbMgr := aws.NewDatabaseManagerWithDeps(km, ssm, ps)
+	dbMgr := aws.NewDatabaseManagerWithDeps(km, ssm, ps, dbRepo)
 	redisMgr := aws.NewRedisManagerWithDeps(km, ssm, ps)
 	mskMgr := aws.NewMSKManagerWithDeps(km, ssm, ps)
 	maintMgr := aws.NewMaintenanceManagerWithRepo(dbRepo)
 	scaleMgr := aws.NewScalingManagerWithDeps(km, ps, dbRepo)
 	replMgr := aws.NewReplicationManagerWithRepo(dbRepo)
+	undoMgr := aws.NewUndoManagerWithDeps(dbRepo, maintMgr, scaleMgr)
 
 	// Initialize config sync
 	var configSync aws.ConfigSyncI
 	if dbRepo != nil {
 		cs, csErr := aws.NewConfigSync(dbRepo)
 		if csErr != nil {
-			fmt.Fprintf(os.Stderr, "⚠ Config sync initialization failed: %v\n", csErr)
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Config sync initialization failed: %v\n", csErr)
 		} else {
 			configSync = cs
 		}
@@ -89,6 +101,7 @@ func NewCLI() (*CLI, error) {
 		kubeManager:        km,
 		tunnelManager:      tm,
 		ssmManager:         ssm,
+		featureFlags:       aws.NewFeatureFlagManager(ssm, dbRepo),
 		grpcManager:        grpc,
 		dbManager:          dbMgr,
 		redisManager:       redisMgr,
@@ -96,19 +109,23 @@ func NewCLI() (*CLI, error) {
 		maintenanceManager: maintMgr,
 		scalingManager:     scaleMgr,
 		replicationManager: replMgr,
+		undoManager:        undoMgr,
+		quotaManager:       aws.NewQuotaManager(dbRepo),
 		dbRepo:             dbRepo,
 		database:           database,
+		dbErr:              dbErr,
 		configSync:         configSync,
+		providers:          provider.NewRegistry(aws.NewCloudProvider(cm, ps, sm), gcp.NewProvider()),
 	}
 
 	// Auto-sync on first run: if config file exists but DB has no accounts/roles, import
 	if configSync != nil && configSync.ConfigFileExists() && !configSync.HasExistingData() {
 		result, err := configSync.SyncConfigToDB()
 		if err == nil && result.Imported > 0 {
-			fmt.Printf("✓ First run: imported %d profiles from ~/.aws/config into database\n", result.Imported)
+			fmt.Printf(utils.OK()+" First run: imported %d profiles from ~/.aws/config into database\n", result.Imported)
 			if len(result.Errors) > 0 {
 				for _, e := range result.Errors {
-					fmt.Printf("  ⚠ %s\n", e)
+					fmt.Printf("  "+utils.Warn()+" %s\n", e)
 				}
 			}
 			fmt.Println("  Run 'rw config status' to review, or 'rw config generate' to let rw manage the config file")
@@ -126,18 +143,55 @@ func (c *CLI) Close() {
 	}
 }
 
+// heartbeatSkipped are commands that only read local state, so running
+// them doesn't count as using the active session
+var heartbeatSkipped = []string{"help", "version", "example", "completion", "docs", "changelog", "context",
+	"current", "status", "list", "providers", "settings", "doctor", "check", "alias", "history", "jobs", "port", "portmap",
+	clipboardClearCommand}
+
+// heartbeat marks the active role session as in use, closing it first if
+// it has been idle longer than session_idle_timeout. Failures are ignored so
+// a busy database never blocks a command.
+func (c *CLI) heartbeat(command string, args []string) {
+	if c.dbRepo == nil || machineReadable(command, args) {
+		return
+	}
+	if info, ok := lookupCommand(command); !ok || slices.Contains(heartbeatSkipped, info.Name) {
+		return
+	}
+	_, _ = c.dbRepo.TouchActiveSession(appconfig.Get().SessionIdleTimeoutDuration())
+}
+
 // Run executes the CLI with given arguments
 func (c *CLI) Run(args []string) error {
 	if len(args) < 1 {
 		return c.current()
 	}
 
+	args = c.expandAlias(args)
 	command := args[0]
 	cmdArgs := args[1:]
 
+	c.heartbeat(command, cmdArgs)
+
+	checkTools(command, cmdArgs)
+	checkSwitchJournal(command)
+	c.checkUpgrade(command)
+	warnDeprecated(command, cmdArgs)
+	c.checkExternalConfigChange(command, cmdArgs)
+	c.productionBanner(command)
+
+	if info, ok := lookupCommand(command); ok && info.NeedsDB {
+		if err := c.requireDB("rw " + info.Name); err != nil {
+			return err
+		}
+	}
+
 	switch command {
 	case "list", "ls", "l":
-		return c.listProfiles()
+		return c.listProfiles(cmdArgs)
+	case "profile":
+		return c.profile(cmdArgs)
 	case "switch", "use", "s":
 		return c.switchCmd(cmdArgs)
 	case "login", "li":
@@ -158,6 +212,12 @@ func (c *CLI) Run(args []string) error {
 		return c.tunnel(cmdArgs)
 	case "port", "p":
 		return c.port(cmdArgs)
+	case "portmap":
+		return c.portmap(cmdArgs)
+	case "report":
+		return c.report(cmdArgs)
+	case "check":
+		return c.check(cmdArgs)
 	case "grpc", "g":
 		return c.grpc(cmdArgs)
 	case "redis", "r":
@@ -170,27 +230,68 @@ func (c *CLI) Run(args []string) error {
 		return c.scale(cmdArgs)
 	case "replication", "rep":
 		return c.replication(cmdArgs)
+	case "undo":
+		return c.undo(cmdArgs)
+	case "history":
+		return c.history(cmdArgs)
+	case "jobs":
+		return c.jobs(cmdArgs)
+	case "alias":
+		return c.alias(cmdArgs)
 	case "keygen", "kg":
 		return c.keygen(cmdArgs)
 	case "ssm":
 		return c.ssm(cmdArgs)
+	case "flag":
+		return c.flag(cmdArgs)
 	case "set":
 		return c.set(cmdArgs)
+	case "settings":
+		return c.settings(cmdArgs)
 	case "config", "cfg":
 		return c.config(cmdArgs)
 	case "setup":
 		return c.setup(cmdArgs)
+	case "bootstrap":
+		return c.bootstrap(cmdArgs)
+	case "env":
+		return c.env(cmdArgs)
+	case "pin":
+		return c.pinCmd(cmdArgs)
+	case "direnv":
+		return c.direnv(cmdArgs)
+	case "providers", "provider":
+		return c.providersCmd(cmdArgs)
 	case "web", "w":
 		return fmt.Errorf("'rw web' has been removed. Use 'rw tray start' for the system tray app instead")
 	case "tray":
 		return c.trayCmd(cmdArgs)
+	case "slack":
+		return c.slackCmd(cmdArgs)
+	case "completion":
+		return c.completion(cmdArgs)
+	case "docs":
+		return c.docs(cmdArgs)
+	case "migrate":
+		return c.migrateCmd(cmdArgs)
+	case "doctor":
+		return c.doctor(cmdArgs)
+	case "changelog":
+		return c.changelogCmd(cmdArgs)
+	case "repair":
+		return c.repair(cmdArgs)
 	case "help", "--help", "-h":
-		return c.showHelp()
+		return c.showHelp(cmdArgs)
 	case "version", "--version", "-v":
 		return c.showVersion()
 	case "example", "examples", "ex":
 		return c.example()
+	case clipboardClearCommand:
+		return c.clipboardClear(cmdArgs)
 	default:
+		if c.dbRepo == nil {
+			return fmt.Errorf("unknown command: %s\nAliases can't be expanded while the database is unavailable. Run 'rw doctor' for repair steps", command)
+		}
 		return fmt.Errorf("unknown command: %s\nRun 'rw help' for usage", command)
 	}
 }
@@ -202,7 +303,10 @@ func (c *CLI) switchCmd(args []string) error {
 	fs := ParseFlags(args)
 	skipKube := fs.Bool("no-kube") || fs.Bool("skip-kube")
 
-	profileName := fs.Arg(0)
+	p, profileName, ok := c.otherProvider(fs.Arg(0))
+	if ok {
+		return c.switchProviderProfile(p, profileName)
+	}
 	if profileName == "" {
 		// Interactive picker
 		picked, err := c.pickProfile(false)
@@ -219,15 +323,21 @@ func (c *CLI) switchCmd(args []string) error {
 		profileName = resolved
 	}
 
-	return c.switchProfile(profileName, skipKube)
+	return c.switchProfile(profileName, aws.SwitchOptions{SkipKube: skipKube, Force: fs.Bool("force")})
 }
 
 // loginCmd wraps the login command with argument validation.
 // If no profile name is given, shows an interactive picker (SSO profiles only).
 // Supports partial profile name matching.
 func (c *CLI) loginCmd(args []string) error {
+	fs := ParseFlags(args)
+	opts := aws.LoginOptions{NoBrowser: fs.Bool("no-browser") || fs.Bool("qr") || utils.IsHeadless()}
+	if fs.Bool("qr") {
+		opts.ShowDeviceCode = showDeviceCodeQR
+	}
+
 	var profileName string
-	if len(args) < 1 {
+	if fs.Arg(0) == "" {
 		// Interactive picker — SSO profiles only
 		picked, err := c.pickProfile(true)
 		if err != nil {
@@ -235,14 +345,18 @@ func (c *CLI) loginCmd(args []string) error {
 		}
 		profileName = picked
 	} else {
-		resolved, err := c.resolveProfileName(args[0])
+		p, profile, ok := c.otherProvider(fs.Arg(0))
+		if ok {
+			return c.loginProviderProfile(p, profile)
+		}
+		resolved, err := c.resolveProfileName(profile)
 		if err != nil {
 			return err
 		}
 		profileName = resolved
 	}
 
-	return c.login(profileName)
+	return c.login(profileName, opts)
 }
 
 // logoutCmd wraps the logout command with argument validation.
@@ -277,19 +391,99 @@ func (c *CLI) extractAccountName(profileName string) string {
 	return name
 }
 
+// extractShowSecrets removes the global --show-secrets flag from args and
+// enables printing secret values for this invocation.
+func extractShowSecrets(args []string) []string {
+	filtered := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--show-secrets" {
+			utils.SetShowSecrets(true)
+			globalFlagsUsed["--show-secrets"] = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered
+}
+
+// extractNoColor removes the global --no-color flag from args and disables
+// colored output for this invocation.
+func extractNoColor(args []string) []string {
+	filtered := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--no-color" {
+			utils.SetNoColor(true)
+			globalFlagsUsed["--no-color"] = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered
+}
+
+// extractPlain removes the global --plain flag from args and turns on plain
+// output for this invocation.
+func extractPlain(args []string) []string {
+	filtered := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--plain" {
+			utils.SetPlain(true)
+			globalFlagsUsed["--plain"] = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered
+}
+
+// extractStateDir removes the global --state-dir flag from args and exports
+// it as RW_STATE_DIR, before the database is opened.
+func extractStateDir(args []string) ([]string, error) {
+	filtered := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		dir, ok := strings.CutPrefix(args[i], "--state-dir=")
+		if !ok {
+			if args[i] != "--state-dir" {
+				filtered = append(filtered, args[i])
+				continue
+			}
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("usage: --state-dir <dir>")
+			}
+			i++
+			dir = args[i]
+		}
+
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --state-dir: %w", err)
+		}
+		os.Setenv(utils.StateDirEnv, abs)
+		globalFlagsUsed["--state-dir"] = true
+	}
+	return filtered, nil
+}
+
 // RunCLI is the main entry point called from cmd/rw/main.go.
 func RunCLI() {
 	if err := runCLI(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(utils.Stderr, "Error: %s\n", utils.RedactOutput(err.Error()))
 		os.Exit(1)
 	}
 }
 
 func runCLI() error {
+	args, err := extractStateDir(os.Args[1:])
+	if err != nil {
+		return err
+	}
+	args = extractNoColor(args)
+	args = extractPlain(args)
+
 	cli, err := NewCLI()
 	if err != nil {
 		return err
 	}
 	defer cli.Close()
-	return cli.Run(os.Args[1:])
+	return cli.Run(extractShowSecrets(args))
 }
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+	"syscall"
+	"time"
+)
+
+// clipboardClearCommand is the hidden command used by a detached child
+// process to clear a copied secret once the parent has exited.
+const clipboardClearCommand = "__clipboard-clear"
+
+// copyOutput places a value on the clipboard instead of printing it. Secrets
+// are cleared again after the configured clipboard_clear duration, so they
+// don't linger in the clipboard or in terminal scrollback.
+func copyOutput(value string, secret bool) error {
+	if err := utils.CopyToClipboard(value); err != nil {
+		return err
+	}
+
+	clearAfter := appconfig.Get().ClipboardClearDuration()
+	if !secret || clearAfter == 0 {
+		fmt.Println(utils.OK() + " Copied to clipboard")
+		return nil
+	}
+
+	if err := scheduleClipboardClear(value, clearAfter); err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Copied to clipboard, but could not schedule clearing: %v\n", err)
+		return nil
+	}
+	fmt.Printf(utils.OK()+" Copied to clipboard (clears in %s)\n", clearAfter)
+	return nil
+}
+
+// scheduleClipboardClear starts a detached rw process that clears the
+// clipboard after the given delay. Only a hash of the value is passed on the
+// command line, so the secret never shows up in the process list.
+func scheduleClipboardClear(value string, after time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, clipboardClearCommand, after.String(), clipboardHash(value))
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// clipboardClear waits, then clears the clipboard if it still holds the
+// copied value. Anything the user copied in the meantime is left alone.
+func (c *CLI) clipboardClear(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: rw %s <duration> <sha256>", clipboardClearCommand)
+	}
+	after, err := time.ParseDuration(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid duration: %s", args[0])
+	}
+
+	// Keep running when the terminal that started us is closed
+	signal.Ignore(syscall.SIGHUP)
+	time.Sleep(after)
+
+	current, err := utils.ReadClipboard()
+	if err == nil && clipboardHash(current) != args[1] {
+		return nil
+	}
+	return utils.ClearClipboard()
+}
+
+func clipboardHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,587 @@
+package cli
+
+// Version is the rw release version, set at build time with
+// -ldflags "-X rolewalkers/cli.Version=1.2.3"
+var Version = "1.1.0"
+
+// commandInfo describes a top-level command: the builtin command list,
+// shell completions, man pages and reference docs are generated from it.
+type commandInfo struct {
+	Name    string
+	Aliases []string
+	// Args is the synopsis after the command name, e.g. "<env>"
+	Args        string
+	Summary     string
+	Subcommands []subcommandInfo
+	Flags       []flagInfo
+	// NeedsDB commands fail with a uniform error when the database is
+	// unavailable instead of running on built-in defaults
+	NeedsDB bool
+	// Hidden commands are routed by Run but not documented or completed
+	Hidden bool
+}
+
+type subcommandInfo struct {
+	Name    string
+	Args    string
+	Summary string
+}
+
+type flagInfo struct {
+	Name string
+	// Arg names the flag's value; empty for boolean flags
+	Arg   string
+	Usage string
+}
+
+// commandCatalog lists every command routed by Run. Keep in sync with the
+// switch in Run and with 'rw help'.
+var commandCatalog = []commandInfo{
+	{
+		Name: "list", Aliases: []string{"ls", "l"}, Summary: "List all AWS profiles",
+		Flags: []flagInfo{{Name: "--long", Usage: "Include account names, descriptions and owners"}},
+	},
+	{
+		Name: "profile", Summary: "Show or set a profile's description and ownership", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "annotate", Args: "[profile]", Summary: "Show or set a profile's description and ownership"},
+			{Name: "bulk-edit", Summary: "Set region or description on every role matching a filter"},
+		},
+		Flags: []flagInfo{
+			{Name: "--description", Arg: "value", Usage: "Set the description (\"\" clears it)"},
+			{Name: "--owner", Arg: "value", Usage: "Set the owner (\"\" clears it)"},
+			{Name: "--team", Arg: "value", Usage: "Set the team (\"\" clears it)"},
+			{Name: "--contact", Arg: "value", Usage: "Set the contact (\"\" clears it)"},
+			{Name: "--account", Usage: "Annotate the profile's AWS account instead"},
+			{Name: "--filter", Arg: "field=value,...", Usage: "With bulk-edit, the roles to change (* matches anything)"},
+			{Name: "--set", Arg: "field=value", Usage: "With bulk-edit, the region or description to set"},
+			{Name: "--dry-run", Usage: "With bulk-edit, preview the changes without applying them"},
+			{Name: "--yes", Usage: "With bulk-edit, skip the confirmation prompt"},
+		},
+	},
+	{
+		Name: "switch", Aliases: []string{"use", "s"}, Args: "[profile]",
+		Summary: "Switch to a profile (updates default + kubectl context)",
+		Flags: []flagInfo{
+			{Name: "--no-kube", Usage: "Skip kubectl context switch"},
+			{Name: "--force", Usage: "Switch even if the profile's credentials can't be resolved"},
+		},
+	},
+	{
+		Name: "login", Aliases: []string{"li"}, Args: "[profile]", Summary: "SSO login for a profile",
+		Flags: []flagInfo{
+			{Name: "--no-browser", Usage: "Print the device login URL and code instead of opening a browser"},
+			{Name: "--qr", Usage: "Also show the device login URL as a QR code (implies --no-browser)"},
+		},
+	},
+	{Name: "logout", Aliases: []string{"lo"}, Args: "[profile]", Summary: "SSO logout for a profile"},
+	{Name: "status", Aliases: []string{"st"}, Summary: "Show login status for all SSO profiles"},
+	{Name: "current", Aliases: []string{"c"}, Summary: "Show current active profile"},
+	{
+		Name: "context", Aliases: []string{"ctx"}, Summary: "Show compact context (profile, account, eks, namespace)",
+		Flags: []flagInfo{
+			{Name: "--format", Arg: "short|json", Usage: "Compact format for shell prompts, or JSON"},
+			{Name: "--fields", Arg: "sso,tunnels,prod", Usage: "Append SSO minutes left, tunnel count and a production flag to the short format"},
+			{Name: "--fresh", Usage: "Ask kubectl for the context and namespace instead of the cache"},
+		},
+	},
+	{
+		Name: "providers", Aliases: []string{"provider"}, Summary: "List cloud providers (aws, gcp) and their profiles",
+		Subcommands: []subcommandInfo{
+			{Name: "list", Summary: "List cloud providers and their profiles"},
+			{Name: "env", Args: "<provider:profile>", Summary: "Print shell exports selecting a profile in this shell only"},
+		},
+		Flags: []flagInfo{{Name: "--shell", Arg: "shell", Usage: "Override shell detection"}},
+	},
+	{
+		Name: "kube", Aliases: []string{"k8s", "k"}, Args: "<env>", Summary: "Switch kubectl context to an environment",
+		Subcommands: []subcommandInfo{
+			{Name: "list", Summary: "List available kubectl contexts"},
+			{Name: "current", Summary: "Show the current kubectl context"},
+			{Name: "refresh", Args: "<env>|--all", Summary: "Re-run update-kubeconfig for an environment or all of them"},
+			{Name: "set", Args: "namespace", Summary: "Interactively set default namespace"},
+			{Name: "set", Args: "context [profile] [context]", Summary: "Pin the kubectl context 'switch' applies for a profile"},
+			{Name: "can-i", Args: "[env]", Summary: "Show what your role (or another user's) can do in an environment's namespace"},
+		},
+		Flags: []flagInfo{
+			{Name: "--force", Usage: "Switch even if the environment's profile can't be used"},
+			{Name: "--clear", Usage: "With set context, derive the context from the environment again"},
+			{Name: "--all", Usage: "With refresh, refresh every active environment"},
+			{Name: "--concurrency", Arg: "n", Usage: "With refresh --all, environments refreshed at once (default: 4)"},
+			{Name: "--namespace", Arg: "ns", Usage: "With can-i, the namespace to check (default: the environment's)"},
+			{Name: "--as", Arg: "user", Usage: "With can-i, impersonate a user"},
+			{Name: "--sa", Arg: "[ns:]name", Usage: "With can-i, impersonate a service account"},
+			{Name: "--rules", Usage: "With can-i, list every rule in the namespace"},
+			{Name: "--format", Arg: "format", Usage: "With can-i, output format: text or json"},
+		},
+	},
+	{
+		Name: "port", Aliases: []string{"p"}, Args: "<svc> <env>", Summary: "Get local port for a service/env", NeedsDB: true,
+		Flags: []flagInfo{
+			{Name: "--list", Usage: "List all port mappings"},
+			{Name: "--copy", Usage: "Copy to clipboard instead of printing"},
+			{Name: "--name", Arg: "name", Usage: "Only this named port, e.g. command for db"},
+			{Name: "--first", Usage: "Print only the first port, even in a terminal"},
+			{Name: "--json", Usage: "JSON output (same as --format json)"},
+			{Name: "--format", Arg: "format", Usage: "Output format: text or json"},
+		},
+	},
+	{
+		Name: "portmap", Summary: "Change local port mappings and find ports mapped twice", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "list", Summary: "List all port mappings"},
+			{Name: "set", Args: "<svc> <env> [port]", Summary: "Map a service's local port (default: the next free port)"},
+			{Name: "conflicts", Summary: "List local ports mapped to several services in an environment"},
+		},
+	},
+	{
+		Name: "check", Args: "<svc> <env>|<host:port>", Summary: "Check a service answers, through its tunnel if one is running",
+		Flags: []flagInfo{
+			{Name: "--protocol", Arg: "protocol", Usage: "Handshake to perform: tcp, postgres, redis or kafka (default: from the service)"},
+			{Name: "--tls", Usage: "Connect with TLS"},
+			{Name: "--no-tls", Usage: "Connect without TLS (Redis and MSK default to TLS)"},
+			{Name: "--timeout", Arg: "duration", Usage: "Timeout for each step (default: 5s)"},
+			{Name: "--format", Arg: "format", Usage: "Output format: text or json"},
+		},
+	},
+	{
+		Name: "report", Summary: "Reports for the platform team",
+		Subcommands: []subcommandInfo{
+			{Name: "tunnel-usage", Summary: "List tunnel-access pods across users, with their age and owner"},
+		},
+		Flags: []flagInfo{
+			{Name: "--cluster", Arg: "env", Usage: "Report on this environment's cluster (default: current context)"},
+			{Name: "--older-than", Arg: "duration", Usage: "Flag pods older than this as likely leaked (default: 24h)"},
+			{Name: "--format", Arg: "format", Usage: "Output format: text or json"},
+		},
+	},
+	{
+		Name: "tunnel", Aliases: []string{"t"}, Summary: "Start, stop, list and share tunnels to services", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "start", Args: "<svc> <env>", Summary: "Start a tunnel to a service"},
+			{Name: "stop", Args: "<svc> <env>", Summary: "Stop a specific tunnel (--all stops every tunnel)"},
+			{Name: "list", Summary: "List active tunnels"},
+			{Name: "cleanup", Summary: "Remove state for tunnels that are no longer running and delete orphaned pods"},
+			{Name: "share", Args: "<svc> <env>", Summary: "Print a manifest to reproduce a tunnel setup"},
+			{Name: "join", Args: "<manifest>", Summary: "Start the tunnel described by a manifest"},
+		},
+		Flags: []flagInfo{
+			{Name: "--all", Usage: "With stop, stop all tunnels"},
+			{Name: "--output", Arg: "file", Usage: "With share, write the manifest to a file"},
+			{Name: "--write", Usage: "Tunnel to the database write node (default: read)"},
+			{Name: "--command", Usage: "Tunnel to the command database (default: query)"},
+			{Name: "--reason", Arg: "text", Usage: "With start --write, why a daily quota is being exceeded"},
+			{Name: "--rate-limit", Arg: "rate", Usage: "With start, cap bandwidth each way (10mbit, 512kbit, 2mb)"},
+			{Name: "--keepalive", Arg: "duration", Usage: "With start, TCP keepalive interval so idle connections survive NAT"},
+			{Name: "--audit-connections", Usage: "With start, record the local process behind each connection in the audit log"},
+			{Name: "--format", Arg: "fmt", Usage: "With list, text (default) or json"},
+		},
+	},
+	{
+		Name: "db", Aliases: []string{"d"}, Summary: "Connect to, back up and restore databases", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "connect", Args: "<env>", Summary: "Connect to database via interactive psql"},
+			{Name: "backup", Args: "<env>", Summary: "Backup database to local file or S3"},
+			{Name: "restore", Args: "<env>", Summary: "Restore database from local file, S3 or a cataloged backup"},
+			{Name: "dsn", Args: "<env>", Summary: "Print a connection URL (accepts the connect flags)"},
+			{Name: "backups", Args: "list [env]", Summary: "List cataloged backups (location, size, hash, duration)"},
+			{Name: "backups", Args: "prune [env] --keep <n>", Summary: "Delete all but the newest n backups per environment"},
+			{Name: "backups", Args: "retry <id>", Summary: "Run an interrupted backup again from the start"},
+		},
+		Flags: []flagInfo{
+			{Name: "--write", Usage: "Connect to write node (default: read)"},
+			{Name: "--command", Usage: "Connect to command database (default: query)"},
+			{Name: "--readonly", Usage: "Connect as read-only user (IAM auth)"},
+			{Name: "--admin", Usage: "Connect as admin user (IAM auth)"},
+			{Name: "--iam", Usage: "Force IAM authentication"},
+			{Name: "--output", Arg: "file", Usage: "Backup output file path"},
+			{Name: "--input", Arg: "file", Usage: "Restore input file path"},
+			{Name: "--s3", Arg: "uri", Usage: "Stream the backup to, or restore from, s3://bucket/key"},
+			{Name: "--backup", Arg: "id", Usage: "Restore a cataloged backup (verified against its hash)"},
+			{Name: "--schema-only", Usage: "Backup schema only, no data"},
+			{Name: "--force", Usage: "With --yes, restore even when pre-restore checks warn"},
+			{Name: "--skip-checks", Usage: "Skip inspecting the target DB before restoring"},
+			{Name: "--clean", Usage: "Drop objects before recreating"},
+			{Name: "--reason", Arg: "text", Usage: "With restore, why a daily quota is being exceeded"},
+			{Name: "--keep", Arg: "n", Usage: "Backups to keep per environment when pruning"},
+			{Name: "--copy", Usage: "Copy the DSN to clipboard instead of printing"},
+			{Name: "--yes", Usage: "Skip confirmation prompt"},
+		},
+	},
+	{
+		Name: "redis", Aliases: []string{"r"}, Summary: "Connect to Redis cluster via interactive redis-cli",
+		Subcommands: []subcommandInfo{{Name: "connect", Args: "<env>", Summary: "Connect to the Redis cluster"}},
+	},
+	{
+		Name: "msk", Aliases: []string{"m"}, Summary: "Kafka UI and CLI sessions for MSK clusters",
+		Subcommands: []subcommandInfo{
+			{Name: "ui", Args: "<env>", Summary: "Start Kafka UI for MSK cluster"},
+			{Name: "connect", Args: "<env>", Summary: "Interactive Kafka CLI session (IAM auth)"},
+			{Name: "stop", Args: "<env>", Summary: "Stop the Kafka UI pod"},
+		},
+		Flags: []flagInfo{
+			{Name: "--port", Arg: "port", Usage: "Local port (default: 8080)"},
+			{Name: "--strict-port", Usage: "Fail if the port is busy instead of using the next free one"},
+		},
+	},
+	{
+		Name: "maintenance", Aliases: []string{"mt"}, Args: "<env> --type <type> --enable|--disable", NeedsDB: true,
+		Summary: "Toggle Fastly maintenance mode",
+		Subcommands: []subcommandInfo{
+			{Name: "status", Args: "<env>", Summary: "Check maintenance mode status"},
+			{Name: "validate", Args: "<env>", Summary: "Check that api and pwa each match exactly one Fastly service"},
+		},
+		Flags: []flagInfo{
+			{Name: "--type", Arg: "type", Usage: "Maintenance type"},
+			{Name: "--enable", Usage: "Enable maintenance mode"},
+			{Name: "--disable", Usage: "Disable maintenance mode"},
+			{Name: "--dry-run", Usage: "Show which services and dictionary values would change"},
+		},
+	},
+	{
+		Name: "scale", Aliases: []string{"sc"}, Args: "<env>", Summary: "Scale HPAs with a preset or per service", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "list", Args: "<env>", Summary: "List HPAs and current scaling"},
+		},
+		Flags: []flagInfo{
+			{Name: "--preset", Arg: "preset", Usage: "Scale all HPAs using a preset"},
+			{Name: "--service", Arg: "svc", Usage: "Scale a service's HPA, or every HPA matching globs or /regex/ patterns"},
+			{Name: "--exclude", Arg: "pattern", Usage: "Skip HPAs matching these patterns"},
+			{Name: "--min", Arg: "n", Usage: "Minimum replicas"},
+			{Name: "--max", Arg: "n", Usage: "Maximum replicas"},
+			{Name: "--dry-run", Usage: "With patterns, show the matching HPAs without scaling"},
+			{Name: "--yes", Usage: "With patterns, skip the confirmation outside production"},
+			{Name: "--format", Arg: "fmt", Usage: "With list, text (default) or json"},
+		},
+	},
+	{
+		Name: "replication", Aliases: []string{"rep"}, Summary: "Manage Blue-Green deployments", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "status", Args: "<env>", Summary: "Show Blue-Green deployment status"},
+			{Name: "switch", Args: "<id>", Summary: "Switchover a Blue-Green deployment"},
+			{Name: "create", Args: "<env> --name <name> [--source <cluster>]", Summary: "Create a new Blue-Green deployment"},
+			{Name: "delete", Args: "<id>", Summary: "Delete a Blue-Green deployment"},
+		},
+		Flags: []flagInfo{
+			{Name: "--name", Arg: "name", Usage: "Name of the new deployment"},
+			{Name: "--source", Arg: "cluster", Usage: "Source cluster (default: discovered from the environment)"},
+			{Name: "--env", Arg: "env", Usage: "With switch or delete, the environment whose region the deployment is in (default: the configured region)"},
+			{Name: "--delete-target", Usage: "With delete, also delete the green environment"},
+			{Name: "--yes", Usage: "Skip confirmation prompt"},
+		},
+	},
+	{
+		Name: "undo", Summary: "Revert the last maintenance or scaling change (within the undo window, default 15m)", NeedsDB: true,
+		Flags: []flagInfo{{Name: "--yes", Usage: "Skip confirmation prompt"}},
+	},
+	{
+		Name: "history", Summary: "Export the audit log of operational changes", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "export", Summary: "Write audit entries as JSON lines, CEF or CSV for a SIEM"},
+		},
+		Flags: []flagInfo{
+			{Name: "--format", Arg: "fmt", Usage: "json-lines (default), cef or csv"},
+			{Name: "--since", Arg: "age", Usage: "Only entries newer than this, e.g. 30d, 12h"},
+			{Name: "--output", Arg: "file", Usage: "Write to a file instead of stdout"},
+		},
+	},
+	{
+		Name: "jobs", Summary: "List backups, restores and switchovers run from any terminal", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "list", Summary: "List jobs, newest first, with their state and progress"},
+		},
+		Flags: []flagInfo{
+			{Name: "--active", Usage: "Only queued and running jobs"},
+			{Name: "--limit", Arg: "n", Usage: "Jobs to list (default: 20)"},
+			{Name: "--format", Arg: "format", Usage: "Output format: text or json"},
+		},
+	},
+	{
+		Name: "grpc", Aliases: []string{"g"}, Args: "<service> <env>", Summary: "Port-forward to a gRPC microservice", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "list", Summary: "List available gRPC services"},
+			{Name: "proxy", Args: "<service> <env>", Summary: "Forward a service and serve an HTTP/JSON proxy (needs grpcurl)"},
+		},
+		Flags: []flagInfo{
+			{Name: "--port", Arg: "port", Usage: "With proxy, local HTTP port (default: 8081)"},
+			{Name: "--strict-port", Usage: "Fail if the local port is busy instead of using the next free one"},
+		},
+	},
+	{
+		Name: "ssm", Summary: "Get, list and browse SSM parameters",
+		Subcommands: []subcommandInfo{
+			{Name: "get", Args: "<path>", Summary: "Get SSM parameter value"},
+			{Name: "list", Args: "<prefix>", Summary: "List parameters under a path prefix"},
+			{Name: "browse", Args: "[prefix]", Summary: "Browse parameters as a tree"},
+		},
+		Flags: []flagInfo{
+			{Name: "--decrypt", Usage: "Decrypt SecureString (default: enabled)"},
+			{Name: "--copy", Usage: "Copy to clipboard instead of printing"},
+			{Name: "--max-depth", Arg: "n", Usage: "With list, only include subfolders up to n levels deep (default: all)"},
+			{Name: "--contains", Arg: "text", Usage: "With list, only names containing text"},
+			{Name: "--values", Usage: "With list, fetch values too (secrets masked)"},
+			{Name: "--concurrency", Arg: "n", Usage: "With list --values, requests run at once (default: 8)"},
+			{Name: "--format", Arg: "format", Usage: "With list, output format: text or json"},
+		},
+	},
+	{
+		Name: "flag", Summary: "List, compare and toggle feature flags in SSM",
+		Subcommands: []subcommandInfo{
+			{Name: "list", Args: "<env>", Summary: "List feature flags with their types"},
+			{Name: "get", Args: "<env> <flag>", Summary: "Show a feature flag"},
+			{Name: "set", Args: "<env> <flag> <value>", Summary: "Set a feature flag (audited; confirms on production)"},
+		},
+		Flags: []flagInfo{
+			{Name: "--diff-envs", Arg: "envs", Usage: "With list, show flags that differ from these environments"},
+			{Name: "--type", Arg: "type", Usage: "With set, bool, number or string (required to create a flag)"},
+		},
+	},
+	{
+		Name: "env", Summary: "List, clone and expire environments", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "list", Summary: "List environments and upcoming expirations"},
+			{Name: "clone", Args: "<source> <name>", Summary: "Copy an environment with its port and cluster mappings"},
+			{Name: "create-ephemeral", Args: "<name> --ttl <duration>", Summary: "Clone an environment that expires after the TTL"},
+			{Name: "expire", Summary: "Clean up expired environments now"},
+			{Name: "account", Args: "<env> [account-id]", Summary: "Show or set the AWS account checked before destructive operations"},
+			{Name: "production", Args: "<env> [on|off]", Summary: "Show or set whether an environment is production"},
+		},
+		Flags: []flagInfo{
+			{Name: "--display-name", Arg: "name", Usage: "Display name (default: derived from source)"},
+			{Name: "--cluster", Arg: "name", Usage: "EKS cluster name (default: derived from source)"},
+			{Name: "--profile", Arg: "profile", Usage: "Override the profile copied from the source"},
+			{Name: "--region", Arg: "region", Usage: "Override the region copied from the source"},
+			{Name: "--namespace", Arg: "namespace", Usage: "Override the namespace copied from the source"},
+			{Name: "--from", Arg: "env", Usage: "With create-ephemeral, the environment to clone"},
+			{Name: "--ttl", Arg: "duration", Usage: "With create-ephemeral, time until the environment expires"},
+			{Name: "--yes", Usage: "Accept suggested values without prompting"},
+			{Name: "--clear", Usage: "With account, stop checking the environment's account"},
+		},
+	},
+	{
+		Name: "pin", Args: "[env]", Summary: "Pin an environment in this terminal for commands that leave it out",
+		Flags: []flagInfo{
+			{Name: "--clear", Usage: "Unpin the environment"},
+		},
+	},
+	{
+		Name: "direnv", Args: "[env]", Summary: "Print an .envrc snippet configuring an environment",
+		Flags: []flagInfo{
+			{Name: "--install", Usage: "Install the 'use rolewalkers <env>' direnv library function"},
+		},
+	},
+	{
+		Name: "config", Aliases: []string{"cfg"}, Summary: "Sync profiles between ~/.aws/config and the database", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "status", Summary: "Show sync status between config file and database"},
+			{Name: "sync", Summary: "Import profiles from ~/.aws/config into database"},
+			{Name: "generate", Summary: "Generate ~/.aws/config from database"},
+			{Name: "delete", Summary: "Backup and delete ~/.aws/config (use DB only)"},
+			{Name: "manage", Args: "[on|off]", Summary: "Show or set whether rw-tray regenerates ~/.aws/config from the database"},
+			{Name: "reconcile", Summary: "Import or discard edits other tools made to a managed ~/.aws/config"},
+		},
+		Flags: []flagInfo{
+			{Name: "--dry-run", Usage: "With generate or reconcile, show a diff of the changes without writing"},
+			{Name: "--yes", Usage: "Skip confirmation prompt"},
+			{Name: "--import", Usage: "With reconcile, import the edits into the database"},
+			{Name: "--regenerate", Usage: "With reconcile, regenerate the file from the database"},
+			{Name: "--unmanage", Usage: "With reconcile, keep the file and stop managing it"},
+		},
+	},
+	{
+		Name: "settings", Summary: "Show each setting's effective value and where it came from",
+		Subcommands: []subcommandInfo{
+			{Name: "effective", Summary: "List every setting with its value and source (flag, env, config.yaml, state or default)"},
+		},
+		Flags: []flagInfo{
+			{Name: "--format", Arg: "format", Usage: "Output format: text or json"},
+		},
+	},
+	{
+		Name: "set", Summary: "Configure shell prompt",
+		Subcommands: []subcommandInfo{
+			{Name: "prompt", Args: "[components]", Summary: "Configure shell prompt (time, folder, aws, k8s, git)"},
+		},
+		Flags: []flagInfo{
+			{Name: "--reset", Usage: "Remove prompt customization"},
+			{Name: "--shell", Arg: "shell", Usage: "Override shell detection"},
+			{Name: "--status", Usage: "Show which shells have the prompt installed, and where"},
+			{Name: "--restore", Usage: "Reinstall the prompts recorded in the database"},
+			{Name: "--format", Arg: "format", Usage: "With --status, output format: text or json"},
+		},
+	},
+	{
+		Name: "alias", Summary: "Define command shortcuts", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "add", Args: "<name> \"<command>\"", Summary: "Define a shortcut"},
+			{Name: "list", Summary: "List defined aliases"},
+			{Name: "remove", Args: "<name>", Summary: "Remove an alias"},
+		},
+	},
+	{Name: "setup", Summary: "Auto-discover accounts, roles, and EKS clusters via SSO", NeedsDB: true},
+	{
+		Name: "bootstrap", Summary: "Build the database from existing ~/.aws/config and kubeconfig", NeedsDB: true,
+		Flags: []flagInfo{
+			{Name: "--skip-eks", Usage: "Don't call eks list-clusters per account"},
+			{Name: "--yes", Usage: "Skip the confirmation prompt"},
+		},
+	},
+	{
+		Name: "keygen", Aliases: []string{"kg"}, Args: "[count]", Summary: "Generate cryptographically secure API keys",
+		Flags: []flagInfo{
+			{Name: "--format", Arg: "fmt", Usage: "hex (default), base64, uuid or passphrase"},
+			{Name: "--bytes", Arg: "n", Usage: "Random bytes for hex/base64 (default: 16)"},
+			{Name: "--words", Arg: "n", Usage: "Words in a passphrase (default: 6)"},
+			{Name: "--prefix", Arg: "str", Usage: "Prepend a prefix, e.g. zk_live_"},
+			{Name: "--output", Arg: "file", Usage: "Write keys to a file (mode 0600)"},
+			{Name: "--copy", Usage: "Copy to clipboard instead of printing"},
+		},
+	},
+	{
+		Name: "tray", Summary: "Start, stop and check the system tray app",
+		Subcommands: []subcommandInfo{
+			{Name: "start", Summary: "Start the system tray app in the background"},
+			{Name: "stop", Summary: "Stop the running tray app"},
+			{Name: "status", Summary: "Check if the tray app is running"},
+			{Name: "restart", Summary: "Restart the tray app"},
+		},
+	},
+	{
+		Name: "slack", Summary: "Serve read-only status commands to a Slack slash command", NeedsDB: true,
+		Subcommands: []subcommandInfo{
+			{Name: "serve", Summary: "Serve POST /slack (status, maintenance status, replication status, scale list)"},
+		},
+		Flags: []flagInfo{
+			{Name: "--addr", Arg: "host:port", Usage: "Listen address (default: 127.0.0.1:8090)"},
+		},
+	},
+	{
+		Name: "completion", Args: "<shell>", Summary: "Print a shell completion script",
+		Subcommands: []subcommandInfo{
+			{Name: "bash"}, {Name: "zsh"}, {Name: "fish"}, {Name: "powershell"},
+		},
+	},
+	{
+		Name: "docs", Summary: "Generate man pages and markdown reference docs",
+		Subcommands: []subcommandInfo{
+			{Name: "man", Summary: "Write rw(1) and a page per command"},
+			{Name: "markdown", Summary: "Write a markdown page per command"},
+			{Name: "json", Summary: "Print the command tree (names, args, flags, descriptions) as JSON"},
+		},
+		Flags: []flagInfo{{Name: "--output", Arg: "dir", Usage: "Output directory (default: ./man or ./docs); with json, a file"}},
+	},
+	{
+		Name: "migrate", Summary: "Move rw's database and config to a new machine in an encrypted bundle",
+		Subcommands: []subcommandInfo{
+			{Name: "export", Summary: "Write a passphrase-encrypted bundle (no SSO tokens or credentials)"},
+			{Name: "import", Args: "<file>", Summary: "Replace this machine's database and config.yaml with a bundle"},
+		},
+		Flags: []flagInfo{
+			{Name: "--output", Arg: "file", Usage: "With export, the bundle file to write"},
+			{Name: "--yes", Usage: "With import, skip the confirmation prompt"},
+			{Name: "--no-prompt", Usage: "With import, don't reinstall the recorded shell prompts"},
+		},
+	},
+	{
+		Name: "doctor", Summary: "Check the state directory, database and tool versions, and show repair steps",
+		Flags: []flagInfo{
+			{Name: "--fix", Usage: "Move a corrupt database aside and create a fresh one"},
+			{Name: "--yes", Usage: "Skip the confirmation prompt"},
+		},
+	},
+	{
+		Name: "changelog", Summary: "Show the release notes since you last read them, and what is scheduled for removal",
+		Flags: []flagInfo{
+			{Name: "--since", Arg: "version", Usage: "Show the releases after this version"},
+			{Name: "--all", Usage: "Show every release"},
+			{Name: "--format", Arg: "format", Usage: "Output format: text or json"},
+		},
+	},
+	{
+		Name: "repair", Summary: "Roll back or replay a profile switch that was interrupted part-way",
+		Flags: []flagInfo{
+			{Name: "--replay", Usage: "Apply the interrupted switch again instead of rolling it back"},
+			{Name: "--force", Usage: "Repair a switch that started less than a minute ago"},
+			{Name: "--yes", Usage: "Skip the confirmation prompt"},
+		},
+	},
+	{
+		Name: "help", Args: "[topic]", Summary: "Show help",
+		Subcommands: []subcommandInfo{
+			{Name: "env", Summary: "Environment variables for headless use"},
+		},
+	},
+	{Name: "version", Summary: "Show the rw version"},
+	{Name: "example", Aliases: []string{"examples", "ex"}, Summary: "Show usage examples"},
+	{Name: "web", Aliases: []string{"w"}, Summary: "Removed, use 'rw tray start'", Hidden: true},
+	{Name: clipboardClearCommand, Summary: "Clear a copied secret from the clipboard", Hidden: true},
+}
+
+// globalFlags are accepted before or after any command
+var globalFlags = []flagInfo{
+	{Name: "--no-color", Usage: "Disable colored output (also off when piped, or with NO_COLOR set)"},
+	{Name: "--plain", Usage: "Screen-reader friendly output: no color, emoji or redrawing pickers"},
+	{Name: "--show-secrets", Usage: "Print secret values instead of masking them"},
+	{Name: "--state-dir", Arg: "dir", Usage: "Keep state in dir instead of ~/.rolewalkers"},
+}
+
+// builtinCommands lists every command word routed by Run, including short
+// forms. Aliases may not shadow these.
+var builtinCommands = commandWords()
+
+func commandWords() []string {
+	var words []string
+	for _, cmd := range commandCatalog {
+		words = append(words, cmd.names()...)
+	}
+	return words
+}
+
+// lookupCommand returns the catalog entry for a command word or alias
+func lookupCommand(word string) (commandInfo, bool) {
+	for _, cmd := range commandCatalog {
+		for _, name := range cmd.names() {
+			if name == word {
+				return cmd, true
+			}
+		}
+	}
+	return commandInfo{}, false
+}
+
+// names returns the command name followed by its aliases
+func (cmd commandInfo) names() []string {
+	return append([]string{cmd.Name}, cmd.Aliases...)
+}
+
+// subcommandNames returns the distinct subcommand words, in order
+func (cmd commandInfo) subcommandNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, sub := range cmd.Subcommands {
+		if !seen[sub.Name] {
+			seen[sub.Name] = true
+			names = append(names, sub.Name)
+		}
+	}
+	return names
+}
+
+// visibleCommands returns the documented commands, in catalog order
+func visibleCommands() []commandInfo {
+	var cmds []commandInfo
+	for _, cmd := range commandCatalog {
+		if !cmd.Hidden {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+func flagNames(flags []flagInfo) []string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.Name
+	}
+	return names
+}
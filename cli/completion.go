@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// completionShells are the shells 'rw completion' can generate scripts for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+func (c *CLI) completion(args []string) error {
+	fs := ParseFlags(args)
+	shell := fs.Arg(0)
+	if shell == "" {
+		return fmt.Errorf("usage: rw completion <%s>\n\nExample: source <(rw completion bash)", strings.Join(completionShells, "|"))
+	}
+
+	script, err := Completion(shell)
+	if err != nil {
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// Completion returns the completion script for a shell. Commands,
+// subcommands and flags come from the command catalog, so new commands
+// are completed (and packaged by the release generator) automatically.
+func Completion(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(), nil
+	case "zsh":
+		return zshCompletion(), nil
+	case "fish":
+		return fishCompletion(), nil
+	case "powershell", "pwsh":
+		return powershellCompletion(), nil
+	default:
+		return "", fmt.Errorf("unsupported shell: %s\nSupported: %s", shell, strings.Join(completionShells, ", "))
+	}
+}
+
+// completionWords returns the words offered after a command
+func (cmd commandInfo) completionWords() []string {
+	return append(cmd.subcommandNames(), flagNames(cmd.Flags)...)
+}
+
+func bashCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("# bash completion for rw (generated by 'rw completion bash')\n\n")
+	sb.WriteString("_rw() {\n")
+	sb.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	sb.WriteString("    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&sb, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(completedCommandWords(), " "))
+	sb.WriteString("        return\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("    local words=\"\"\n")
+	sb.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range visibleCommands() {
+		if words := cmd.completionWords(); len(words) > 0 {
+			fmt.Fprintf(&sb, "        %s) words=%q ;;\n", strings.Join(cmd.names(), "|"), strings.Join(words, " "))
+		}
+	}
+	sb.WriteString("    esac\n")
+	fmt.Fprintf(&sb, "    COMPREPLY=($(compgen -W \"$words %s\" -- \"$cur\"))\n", strings.Join(flagNames(globalFlags), " "))
+	sb.WriteString("}\n\n")
+	sb.WriteString("complete -o default -F _rw rw\n")
+	return sb.String()
+}
+
+func zshCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("#compdef rw\n# zsh completion for rw (generated by 'rw completion zsh')\n\n")
+	sb.WriteString("_rw() {\n")
+	sb.WriteString("    local -a commands\n")
+	sb.WriteString("    commands=(\n")
+	for _, cmd := range visibleCommands() {
+		for _, name := range cmd.names() {
+			fmt.Fprintf(&sb, "        '%s:%s'\n", name, zshEscape(cmd.Summary))
+		}
+	}
+	sb.WriteString("    )\n")
+	sb.WriteString("    if (( CURRENT == 2 )); then\n")
+	sb.WriteString("        _describe 'command' commands\n")
+	sb.WriteString("        return\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("    case $words[2] in\n")
+	for _, cmd := range visibleCommands() {
+		if words := cmd.completionWords(); len(words) > 0 {
+			fmt.Fprintf(&sb, "        %s) compadd -- %s ;;\n", strings.Join(cmd.names(), "|"), strings.Join(words, " "))
+		}
+	}
+	sb.WriteString("    esac\n")
+	fmt.Fprintf(&sb, "    compadd -- %s\n", strings.Join(flagNames(globalFlags), " "))
+	sb.WriteString("    _files\n")
+	sb.WriteString("}\n\n")
+	sb.WriteString("if [[ \"$funcstack[1]\" == \"_rw\" ]]; then\n")
+	sb.WriteString("    _rw \"$@\"\n")
+	sb.WriteString("else\n")
+	sb.WriteString("    compdef _rw rw\n")
+	sb.WriteString("fi\n")
+	return sb.String()
+}
+
+func fishCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("# fish completion for rw (generated by 'rw completion fish')\n\n")
+	sb.WriteString("complete -c rw -f\n")
+	for _, flag := range globalFlags {
+		fmt.Fprintf(&sb, "complete -c rw -l %s -d '%s'\n", strings.TrimPrefix(flag.Name, "--"), fishEscape(flag.Usage))
+	}
+	for _, cmd := range visibleCommands() {
+		fmt.Fprintf(&sb, "complete -c rw -n __fish_use_subcommand -a %s -d '%s'\n", cmd.Name, fishEscape(cmd.Summary))
+		for _, alias := range cmd.Aliases {
+			fmt.Fprintf(&sb, "complete -c rw -n __fish_use_subcommand -a %s -d '%s'\n", alias, fishEscape(cmd.Summary))
+		}
+		seen := strings.Join(cmd.names(), " ")
+		if subs := cmd.subcommandNames(); len(subs) > 0 {
+			fmt.Fprintf(&sb, "complete -c rw -n '__fish_seen_subcommand_from %s' -a '%s'\n", seen, strings.Join(subs, " "))
+		}
+		for _, flag := range cmd.Flags {
+			fmt.Fprintf(&sb, "complete -c rw -n '__fish_seen_subcommand_from %s' -l %s -d '%s'\n", seen, strings.TrimPrefix(flag.Name, "--"), fishEscape(flag.Usage))
+		}
+	}
+	return sb.String()
+}
+
+func powershellCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("# PowerShell completion for rw (generated by 'rw completion powershell')\n\n")
+	sb.WriteString("Register-ArgumentCompleter -Native -CommandName rw -ScriptBlock {\n")
+	sb.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	sb.WriteString("    $elements = $commandAst.CommandElements | ForEach-Object { $_.ToString() }\n")
+	sb.WriteString("    $words = @()\n")
+	sb.WriteString("    if ($elements.Count -lt 2 -or ($elements.Count -eq 2 -and $wordToComplete)) {\n")
+	fmt.Fprintf(&sb, "        $words = %s\n", powershellArray(completedCommandWords()))
+	sb.WriteString("    } else {\n")
+	sb.WriteString("        switch ($elements[1]) {\n")
+	for _, cmd := range visibleCommands() {
+		words := cmd.completionWords()
+		if len(words) == 0 {
+			continue
+		}
+		for _, name := range cmd.names() {
+			fmt.Fprintf(&sb, "            '%s' { $words = %s }\n", name, powershellArray(words))
+		}
+	}
+	sb.WriteString("        }\n")
+	fmt.Fprintf(&sb, "        $words += %s\n", powershellArray(flagNames(globalFlags)))
+	sb.WriteString("    }\n")
+	sb.WriteString("    $words | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	sb.WriteString("        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	sb.WriteString("    }\n")
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// completedCommandWords returns the command words offered in first position
+func completedCommandWords() []string {
+	var words []string
+	for _, cmd := range visibleCommands() {
+		words = append(words, cmd.names()...)
+	}
+	return words
+}
+
+func zshEscape(s string) string {
+	s = strings.ReplaceAll(s, "'", "'\\''")
+	return strings.ReplaceAll(s, ":", "\\:")
+}
+
+func fishEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "\\'")
+}
+
+func powershellArray(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = "'" + w + "'"
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
@@ -2,17 +2,23 @@ package cli
 
 import (
 	"fmt"
+	"rolewalkers/aws"
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/messages"
 	"rolewalkers/internal/utils"
 	"strings"
 )
 
 func (c *CLI) config(args []string) error {
+	if err := c.requireDB("rw config"); err != nil {
+		return err
+	}
 	if c.configSync == nil {
-		return fmt.Errorf("database not initialized")
+		return fmt.Errorf("config sync is not available")
 	}
 
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw config <status|sync|generate|delete>\n\nSubcommands:\n  status     Show sync status between ~/.aws/config and database\n  sync       Import/update profiles from ~/.aws/config into database\n  generate   Generate ~/.aws/config from database (rw manages the config)\n  delete     Backup and delete ~/.aws/config (use database only)")
+		return fmt.Errorf("usage: rw config <status|sync|generate|delete|manage|reconcile>\n\nSubcommands:\n  status     Show sync status between ~/.aws/config and database\n  sync       Import/update profiles from ~/.aws/config into database\n  generate   Generate ~/.aws/config from database (rw manages the config)\n             [--dry-run] show the diff only, [--yes] skip confirmation\n  delete     Backup and delete ~/.aws/config (use database only)\n  manage     Show or set [on|off] whether rw-tray regenerates ~/.aws/config\n  reconcile  Import or discard edits other tools made to ~/.aws/config\n             [--import|--regenerate|--unmanage], [--dry-run] show the diff only")
 	}
 
 	switch args[0] {
@@ -21,11 +27,15 @@ func (c *CLI) config(args []string) error {
 	case "sync":
 		return c.configSyncCmd()
 	case "generate":
-		return c.configGenerate()
+		return c.configGenerate(args[1:])
 	case "delete":
 		return c.configDelete()
+	case "manage":
+		return c.configManage(args[1:])
+	case "reconcile":
+		return c.configReconcile(args[1:])
 	default:
-		return fmt.Errorf("unknown config subcommand: %s\nUse: status, sync, generate, delete", args[0])
+		return fmt.Errorf("unknown config subcommand: %s\nUse: status, sync, generate, delete, manage, reconcile", args[0])
 	}
 }
 
@@ -37,17 +47,25 @@ func (c *CLI) configStatus() error {
 	fmt.Println(strings.Repeat("-", 50))
 
 	if hasConfig {
-		fmt.Printf("  ~/.aws/config:  ✓ exists (%s)\n", c.configSync.GetConfigPath())
+		fmt.Printf("  ~/.aws/config:  "+utils.OK()+" exists (%s)\n", c.configSync.GetConfigPath())
 	} else {
-		fmt.Println("  ~/.aws/config:  ✗ not found")
+		fmt.Println("  ~/.aws/config:  " + utils.Fail() + " not found")
 	}
 
 	if hasData {
 		accounts, _ := c.dbRepo.GetAllAWSAccounts()
 		roles, _ := c.dbRepo.GetAllAWSRoles()
-		fmt.Printf("  Database:       ✓ %d accounts, %d roles\n", len(accounts), len(roles))
+		fmt.Printf("  Database:       "+utils.OK()+" %d accounts, %d roles\n", len(accounts), len(roles))
+	} else {
+		fmt.Println("  Database:       " + utils.Fail() + " no accounts/roles")
+	}
+
+	if aws.AWSConfigManaged() && c.configSync.ExternalChange() {
+		fmt.Println("  Managed:        " + utils.Warn() + " edited outside rw; run 'rw config reconcile'")
+	} else if aws.AWSConfigManaged() {
+		fmt.Println("  Managed:        " + utils.OK() + " generated from the database (rw-tray regenerates it)")
 	} else {
-		fmt.Println("  Database:       ✗ no accounts/roles")
+		fmt.Println("  Managed:        no (see 'rw config manage')")
 	}
 
 	if hasConfig && hasData {
@@ -66,7 +84,7 @@ func (c *CLI) configStatus() error {
 			fmt.Println("  Run 'rw config sync' to synchronize")
 		} else {
 			fmt.Println()
-			fmt.Println("  ✓ Database is in sync with config file")
+			fmt.Println("  " + utils.OK() + " Database is in sync with config file")
 			fmt.Println("  You can run 'rw config delete' to remove the config file")
 			fmt.Println("  and let rw manage it via 'rw config generate'")
 		}
@@ -76,7 +94,7 @@ func (c *CLI) configStatus() error {
 		fmt.Println("  Run 'rw config sync' to import profiles into the database.")
 	} else if !hasConfig && hasData {
 		fmt.Println()
-		fmt.Println("  ✓ Running from database (no config file)")
+		fmt.Println("  " + utils.OK() + " Running from database (no config file)")
 		fmt.Println("  Run 'rw config generate' when you need ~/.aws/config for AWS CLI")
 	}
 
@@ -103,13 +121,13 @@ func (c *CLI) configSyncCmd() error {
 		fmt.Println()
 		fmt.Println("  Errors:")
 		for _, e := range result.Errors {
-			fmt.Printf("    ⚠ %s\n", e)
+			fmt.Printf("    "+utils.Warn()+" %s\n", e)
 		}
 	}
 
 	if result.Imported > 0 || result.Updated > 0 {
 		fmt.Println()
-		fmt.Println("✓ Database updated. You can now:")
+		fmt.Println(utils.OK() + " Database updated. You can now:")
 		fmt.Println("  rw config delete     # Remove ~/.aws/config (backup created)")
 		fmt.Println("  rw config generate   # Regenerate config from database anytime")
 	}
@@ -117,11 +135,51 @@ func (c *CLI) configSyncCmd() error {
 	return nil
 }
 
-func (c *CLI) configGenerate() error {
+func (c *CLI) configGenerate(args []string) error {
+	fs := ParseFlags(args)
+	dryRun := fs.Bool("dry-run")
+	skipConfirm := fs.AssumeYes()
+
 	if !c.configSync.HasExistingData() {
 		return fmt.Errorf("no accounts/roles in database. Run 'rw config sync' first")
 	}
 
+	diff, err := c.configSync.DiffAWSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to generate config: %w", err)
+	}
+
+	if diff == "" {
+		fmt.Println(utils.OK() + " ~/.aws/config is already up to date")
+		suggestConfigManaged()
+		return nil
+	}
+
+	fmt.Print(diff)
+	fmt.Println()
+
+	if dryRun {
+		fmt.Println("Dry run: no changes written. Re-run without --dry-run to apply.")
+		return nil
+	}
+
+	if !skipConfirm {
+		if !confirm.Yes(messages.Render(messages.ConfirmConfigOverwrite, nil) + " ") {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if err := c.writeGeneratedConfig(diff); err != nil {
+		return err
+	}
+	suggestConfigManaged()
+	return nil
+}
+
+// writeGeneratedConfig backs up ~/.aws/config, replaces it with the config
+// generated from the database and audits the diff
+func (c *CLI) writeGeneratedConfig(diff string) error {
 	if c.configSync.ConfigFileExists() {
 		backupPath, err := c.configSync.BackupConfigFile()
 		if err != nil {
@@ -134,11 +192,67 @@ func (c *CLI) configGenerate() error {
 		return fmt.Errorf("failed to generate config: %w", err)
 	}
 
-	fmt.Printf("✓ Generated ~/.aws/config from database\n")
+	if c.dbRepo != nil {
+		path := c.configSync.GetConfigPath()
+		params, _ := messages.EncodeParams(messages.Params{"path": path})
+		if _, err := c.dbRepo.RecordAuditMessage(aws.AuditActionConfigGen, "", path, "", diff,
+			string(messages.AuditConfigGenerate), params); err != nil {
+			fmt.Fprintf(utils.Stdout, "  "+utils.Warn()+" Failed to record audit entry: %v\n", err)
+		}
+	}
+
+	fmt.Print(utils.OK() + " Generated ~/.aws/config from database\n")
 	fmt.Printf("  Path: %s\n", c.configSync.GetConfigPath())
 	return nil
 }
 
+// suggestConfigManaged points to 'rw config manage on' after the file was
+// generated or deleted by hand. Managing it is opt-in: rw-tray then
+// rewrites the file whenever it differs from the database.
+func suggestConfigManaged() {
+	if aws.AWSConfigManaged() {
+		return
+	}
+	fmt.Println("  To have rw-tray keep it in step with the database: rw config manage on")
+}
+
+// configManage shows or sets whether ~/.aws/config is generated from the
+// database. While on, rw-tray regenerates the file whenever it differs
+// from the database, validating it and writing it atomically.
+func (c *CLI) configManage(args []string) error {
+	if len(args) == 0 {
+		if aws.AWSConfigManaged() {
+			fmt.Println("~/.aws/config is generated from the database; rw-tray regenerates it when the database changes")
+		} else {
+			fmt.Println("~/.aws/config is not managed by rw; 'rw config manage on' to generate it from the database")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "on":
+		if !c.configSync.HasExistingData() {
+			return fmt.Errorf("no accounts/roles in database. Run 'rw config sync' first")
+		}
+		if err := aws.SetAWSConfigManaged(true); err != nil {
+			return err
+		}
+		if err := c.configSync.AcceptAWSConfig(); err != nil {
+			return err
+		}
+		fmt.Println(utils.OK() + " ~/.aws/config is now generated from the database")
+		fmt.Println("  rw-tray regenerates it when the database changes; run 'rw config generate' to update it now")
+	case "off":
+		if err := aws.SetAWSConfigManaged(false); err != nil {
+			return err
+		}
+		fmt.Println(utils.OK() + " ~/.aws/config is no longer regenerated; edits to it are kept")
+	default:
+		return fmt.Errorf("usage: rw config manage [on|off]")
+	}
+	return nil
+}
+
 func (c *CLI) configDelete() error {
 	if !c.configSync.ConfigFileExists() {
 		fmt.Println("~/.aws/config doesn't exist, nothing to delete")
@@ -155,7 +269,7 @@ func (c *CLI) configDelete() error {
 	}
 	fmt.Printf("  Backed up to: %s\n", backupPath)
 
-	if !utils.ConfirmAction("Delete ~/.aws/config? (rw will generate it when needed)") {
+	if !confirm.Yes(messages.Render(messages.ConfirmConfigDelete, nil) + " ") {
 		fmt.Println("Cancelled.")
 		return nil
 	}
@@ -164,8 +278,9 @@ func (c *CLI) configDelete() error {
 		return fmt.Errorf("failed to delete config: %w", err)
 	}
 
-	fmt.Println("✓ Deleted ~/.aws/config")
+	fmt.Println(utils.OK() + " Deleted ~/.aws/config")
 	fmt.Println("  rw will generate it automatically when switching profiles")
 	fmt.Println("  Or run 'rw config generate' to recreate it manually")
+	suggestConfigManaged()
 	return nil
 }
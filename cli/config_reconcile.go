@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// configCheckSkipped are commands that don't check ~/.aws/config for
+// external changes: 'rw config' reconciles them itself, and the rest only
+// print text or have their output read by other programs
+var configCheckSkipped = []string{"help", "version", "example", "completion", "docs", "changelog", "config", "setup", clipboardClearCommand}
+
+// Ways to reconcile an externally edited ~/.aws/config
+const (
+	reconcileImport     = "Import the changes into the database"
+	reconcileRegenerate = "Regenerate ~/.aws/config from the database (a backup is kept)"
+	reconcileUnmanage   = "Keep the file and stop generating it from the database"
+	reconcileLater      = "Decide later"
+)
+
+// checkExternalConfigChange notices when another tool, such as 'aws
+// configure sso', granted or leapp, edited ~/.aws/config while rw manages
+// it, and points to 'rw config reconcile'. It only ever writes one line to
+// stderr: it runs before commands whose output is read by the prompt or
+// scripts.
+func (c *CLI) checkExternalConfigChange(command string, args []string) {
+	if c.configSync == nil || machineReadable(command, args) {
+		return
+	}
+	if info, ok := lookupCommand(command); !ok || slices.Contains(configCheckSkipped, info.Name) {
+		return
+	}
+	if !c.configSync.ExternalChange() {
+		return
+	}
+	fmt.Fprintf(utils.Stderr, utils.Warn()+" %s was edited outside rw; run 'rw config reconcile' to import or discard the edits\n", c.configSync.GetConfigPath())
+}
+
+// configReconcile shows how an externally edited ~/.aws/config differs from
+// the database, and imports the edit, regenerates the file or stops
+// managing it
+func (c *CLI) configReconcile(args []string) error {
+	fs := ParseFlags(args)
+	action := ""
+	for flag, choice := range map[string]string{
+		"import":     reconcileImport,
+		"regenerate": reconcileRegenerate,
+		"unmanage":   reconcileUnmanage,
+	} {
+		if fs.Bool(flag) {
+			if action != "" {
+				return fmt.Errorf("use only one of --import, --regenerate and --unmanage")
+			}
+			action = choice
+		}
+	}
+
+	if !c.configSync.ExternalChange() {
+		fmt.Println(utils.OK() + " ~/.aws/config has no changes made outside rw")
+		return nil
+	}
+	if fs.Bool("dry-run") {
+		diff, err := c.configSync.DiffAWSConfig()
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+		fmt.Println()
+		fmt.Println("Dry run: nothing changed. Re-run without --dry-run to reconcile.")
+		return nil
+	}
+	return c.reconcileConfig(action)
+}
+
+// reconcileConfig shows the diff between ~/.aws/config and the database,
+// then applies action, asking for one when it's empty
+func (c *CLI) reconcileConfig(action string) error {
+	diff, err := c.configSync.DiffAWSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to generate config: %w", err)
+	}
+
+	if diff == "" {
+		// The edit matches the database, e.g. it was undone
+		if err := c.configSync.AcceptAWSConfig(); err != nil {
+			return err
+		}
+		fmt.Println(utils.OK() + " ~/.aws/config matches the database again")
+		return nil
+	}
+
+	fmt.Println("Lines marked - are only in the file, + only in the database:")
+	fmt.Print(diff)
+	fmt.Println()
+
+	if action == "" {
+		choice, ok := utils.SelectFromList("What should rw do with the changes?",
+			[]string{reconcileImport, reconcileRegenerate, reconcileUnmanage, reconcileLater})
+		if !ok {
+			choice = reconcileLater
+		}
+		action = choice
+	}
+
+	switch action {
+	case reconcileImport:
+		result, err := c.configSync.ImportExternalChange()
+		if err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+		fmt.Printf(utils.OK()+" Imported the changes: %d new, %d updated\n", result.Imported, result.Updated)
+		for _, e := range result.Errors {
+			fmt.Printf("  "+utils.Warn()+" %s\n", e)
+		}
+		if remaining, _ := c.configSync.DiffAWSConfig(); remaining != "" {
+			// Profiles removed from the file, or settings the database
+			// doesn't hold, such as static credentials
+			fmt.Println("  The file still differs from the database; the next regeneration drops what wasn't imported")
+			fmt.Println("  ('rw config generate --dry-run' shows it, 'rw config manage off' keeps the file as it is)")
+		}
+	case reconcileRegenerate:
+		return c.writeGeneratedConfig(diff)
+	case reconcileUnmanage:
+		if err := aws.SetAWSConfigManaged(false); err != nil {
+			return err
+		}
+		fmt.Println(utils.OK() + " ~/.aws/config is no longer regenerated; edits to it are kept")
+	default:
+		fmt.Println("Left unchanged. rw-tray won't regenerate the file until you run 'rw config reconcile'.")
+	}
+	return nil
+}
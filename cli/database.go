@@ -1,16 +1,22 @@
 package cli
 
 import (
+	"cmp"
 	"fmt"
+	"net/url"
 	"rolewalkers/aws"
 	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/messages"
 	"rolewalkers/internal/utils"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func (c *CLI) db(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw db <connect|backup|restore> <env> [options]\n\nSubcommands:\n  connect <env>  Connect to database via interactive psql\n  backup <env>   Backup database to local file\n  restore <env>  Restore database from local file\n\nConnect flags:\n  --write, -w       Connect to write node (default: read)\n  --command, -c     Connect to command database (default: query)\n  --readonly, --ro  Connect as read-only user (IAM auth)\n  --admin           Connect as admin user (IAM auth)\n  --iam             Force IAM authentication with master user\n\nBackup flags:\n  --output, -o <file>  Output file path (required)\n  --schema-only        Backup schema only, no data\n\nRestore flags:\n  --input, -i <file>   Input file path (required)\n  --clean              Drop objects before recreating\n  --yes, -y            Skip confirmation prompt\n\nExamples:\n  rw db connect dev              # Connect as zenithmaster (password)\n  rw db connect dev --readonly   # Connect as zenith-ro (IAM auth)\n  rw db connect prod --admin     # Connect as zenith-admin (IAM auth)\n  rw db connect prod --write --command  # Write node, command DB\n  rw db backup dev --output ./backup.sql\n  rw db restore dev --input ./backup.sql --clean --yes")
+		return fmt.Errorf("usage: rw db <connect|backup|restore|dsn|backups> <env> [options]\n\nSubcommands:\n  connect <env>  Connect to database via interactive psql\n  backup <env>   Backup database to local file\n  restore <env>  Restore database from local file\n  dsn <env>      Print a connection URL (accepts the connect flags)\n  backups list   List cataloged backups\n  backups prune  Delete old backups, keeping the newest per env\n  backups retry  Run an interrupted backup again\n\nConnect flags:\n  --write, -w       Connect to write node (default: read)\n  --command, -c     Connect to command database (default: query)\n  --readonly, --ro  Connect as read-only user (IAM auth)\n  --admin           Connect as admin user (IAM auth)\n  --iam             Force IAM authentication with master user\n\nBackup flags:\n  --output, -o <file>  Output file path\n  --s3 <uri>           Stream to s3://bucket/key instead of a local file\n  --schema-only        Backup schema only, no data\n\nRestore flags:\n  --input, -i <file>   Input file path\n  --s3 <uri>           Restore from s3://bucket/key\n  --backup <id>        Restore a cataloged backup (see: rw db backups list)\n  --force              With --yes, restore even when checks report warnings\n  --skip-checks        Don't inspect the target database first\n  --clean              Drop objects before recreating\n  --yes, -y            Skip confirmation prompt\n\nDSN flags:\n  --copy               Copy to clipboard instead of printing\n\nExamples:\n  rw db connect dev              # Connect as zenithmaster (password)\n  rw db connect dev --readonly   # Connect as zenith-ro (IAM auth)\n  rw db connect prod --admin     # Connect as zenith-admin (IAM auth)\n  rw db connect prod --write --command  # Write node, command DB\n  rw db backup dev --output ./backup.sql\n  rw db backup prod --s3 s3://zenith-backups/prod/2024-06-01.sql\n  rw db restore dev --input ./backup.sql --clean --yes\n  rw db dsn dev --readonly --copy")
 	}
 
 	subCmd := args[0]
@@ -23,8 +29,12 @@ func (c *CLI) db(args []string) error {
 		return c.dbBackup(subArgs)
 	case "restore":
 		return c.dbRestore(subArgs)
+	case "dsn":
+		return c.dbDSN(subArgs)
+	case "backups":
+		return c.dbBackups(subArgs)
 	default:
-		return fmt.Errorf("unknown db subcommand: %s\nUse: connect, backup, restore", subCmd)
+		return fmt.Errorf("unknown db subcommand: %s\nUse: connect, backup, restore, dsn, backups", subCmd)
 	}
 }
 
@@ -94,6 +104,58 @@ func (c *CLI) dbConnect(args []string) error {
 	return c.dbManager.Connect(config)
 }
 
+// dbDSN prints (or copies) a connection URL including credentials
+func (c *CLI) dbDSN(args []string) error {
+	fs := ParseFlags(args)
+	config := aws.DatabaseConfig{
+		Environment: fs.Arg(0),
+		NodeType:    "read",
+		DBType:      "query",
+		UseIAM:      fs.Bool("iam"),
+	}
+	if fs.Bool("write") || fs.Bool("w") {
+		config.NodeType = "write"
+	}
+	if fs.Bool("command") || fs.Bool("c") {
+		config.DBType = "command"
+	}
+	if fs.Bool("readonly") || fs.Bool("ro") {
+		config.Role = "readonly"
+		config.UseIAM = true
+	}
+	if fs.Bool("admin") {
+		config.Role = "admin"
+		config.UseIAM = true
+		config.NodeType = "write"
+	}
+
+	if config.Environment == "" {
+		picked, err := c.pickEnvironment()
+		if err != nil {
+			return err
+		}
+		config.Environment = picked
+	}
+
+	dsn, err := c.dbManager.DSN(config)
+	if err != nil {
+		return err
+	}
+
+	if fs.Bool("copy") {
+		return copyOutput(dsn, true)
+	}
+	if !utils.ShowSecrets() {
+		if u, err := url.Parse(dsn); err == nil {
+			fmt.Println(strings.Replace(u.Redacted(), "xxxxx", utils.RedactedValue, 1))
+			fmt.Fprintln(utils.Stderr, utils.Warn()+" Password hidden. Use --copy to copy the full DSN, or --show-secrets to print it.")
+			return nil
+		}
+	}
+	fmt.Println(dsn)
+	return nil
+}
+
 // isProdLikeEnv returns true for environments that have separate query/command clusters.
 func isProdLikeEnv(env string) bool {
 	cfg := appconfig.Get()
@@ -105,8 +167,12 @@ func (c *CLI) dbBackup(args []string) error {
 	config := aws.BackupConfig{
 		Environment: fs.Arg(0),
 		OutputFile:  fs.String("output", fs.String("o", "")),
+		S3URI:       fs.String("s3", ""),
 		SchemaOnly:  fs.Bool("schema-only"),
 	}
+	if aws.IsS3URI(config.OutputFile) {
+		config.S3URI, config.OutputFile = config.OutputFile, ""
+	}
 
 	if config.Environment == "" {
 		picked, err := c.pickEnvironment()
@@ -116,8 +182,11 @@ func (c *CLI) dbBackup(args []string) error {
 		config.Environment = picked
 	}
 
-	if config.OutputFile == "" {
-		return fmt.Errorf("--output is required\n\nUsage: rw db backup <env> --output <file>")
+	if config.OutputFile == "" && config.S3URI == "" {
+		return fmt.Errorf("--output or --s3 is required\n\nUsage: rw db backup <env> --output <file>\n       rw db backup <env> --s3 s3://bucket/key")
+	}
+	if config.OutputFile != "" && config.S3URI != "" {
+		return fmt.Errorf("use either --output or --s3, not both")
 	}
 
 	return c.dbManager.Backup(config)
@@ -128,9 +197,38 @@ func (c *CLI) dbRestore(args []string) error {
 	config := aws.RestoreConfig{
 		Environment: fs.Arg(0),
 		InputFile:   fs.String("input", fs.String("i", "")),
+		S3URI:       fs.String("s3", ""),
 		Clean:       fs.Bool("clean"),
 	}
-	skipConfirm := fs.Bool("yes") || fs.Bool("y")
+	if aws.IsS3URI(config.InputFile) {
+		config.S3URI, config.InputFile = config.InputFile, ""
+	}
+	skipConfirm := fs.AssumeYes()
+
+	if idStr := fs.String("backup", ""); idStr != "" {
+		if config.InputFile != "" || config.S3URI != "" {
+			return fmt.Errorf("use either --backup or --input/--s3, not both")
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return fmt.Errorf("invalid backup ID: %s", idStr)
+		}
+		record, err := c.dbManager.GetBackup(id)
+		if err != nil {
+			return err
+		}
+		config.BackupID = record.ID
+		if aws.IsS3URI(record.Location) {
+			config.S3URI = record.Location
+		} else {
+			config.InputFile = record.Location
+		}
+		fmt.Printf("Backup %d: %s backup taken %s (%s)\n", record.ID, record.Environment,
+			record.CreatedAt.Local().Format("2006-01-02 15:04"), utils.FormatBytes(record.SizeBytes))
+		if record.SchemaOnly {
+			fmt.Println(utils.Warn() + " This is a schema-only backup; no data will be restored.")
+		}
+	}
 
 	if config.Environment == "" {
 		picked, err := c.pickEnvironment()
@@ -140,20 +238,210 @@ func (c *CLI) dbRestore(args []string) error {
 		config.Environment = picked
 	}
 
-	if config.InputFile == "" {
-		return fmt.Errorf("--input is required\n\nUsage: rw db restore <env> --input <file>")
+	if config.InputFile == "" && config.S3URI == "" {
+		return fmt.Errorf("--input, --s3 or --backup is required\n\nUsage: rw db restore <env> --input <file>\n       rw db restore <env> --s3 s3://bucket/key\n       rw db restore <env> --backup <id>")
+	}
+	if config.InputFile != "" && config.S3URI != "" {
+		return fmt.Errorf("use either --input or --s3, not both")
+	}
+
+	if err := c.verifyAccount(config.Environment); err != nil {
+		return err
+	}
+
+	// Inspecting the target switches to its kubectl context (and profile,
+	// if the context is missing) before the confirmation; a restore that
+	// doesn't go ahead switches back
+	prevProfile := c.configManager.GetActiveProfile()
+	prevContext, _ := c.kubeManager.GetCurrentContext()
+	switchBack := func() {
+		if profile := c.configManager.GetActiveProfile(); profile != prevProfile && prevProfile != "" {
+			if err := c.profileSwitcher.SwitchProfile(prevProfile); err != nil {
+				fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to switch back to profile %s: %v\n", prevProfile, err)
+			}
+		}
+		if current, _ := c.kubeManager.GetCurrentContext(); current != prevContext && prevContext != "" {
+			if err := c.kubeManager.SwitchContext(prevContext); err != nil {
+				fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to switch back to kubectl context %s: %v\n", prevContext, err)
+			}
+		}
+	}
+
+	if !fs.Bool("skip-checks") {
+		check, err := c.dbManager.InspectRestoreTarget(config)
+		if err != nil {
+			switchBack()
+			return fmt.Errorf("%w\nUse --skip-checks to restore without inspecting the target", err)
+		}
+		printRestoreCheck(check)
+		if len(check.Warnings) > 0 && skipConfirm && !fs.Bool("force") {
+			switchBack()
+			return fmt.Errorf("refusing to restore non-interactively with warnings; re-run with --force to proceed anyway")
+		}
 	}
 
 	if !skipConfirm {
-		if !confirmProd(config.Environment, "Database Restore") {
-			fmt.Println("Operation cancelled.")
+		if !c.confirmProd(config.Environment, messages.Render(messages.OpDatabaseRestore, nil)) {
+			switchBack()
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
 			return nil
 		}
-		if !utils.ConfirmDatabaseRestore(config.Environment, config.InputFile) {
+		if !confirm.Confirm(confirm.DatabaseRestore{Env: config.Environment, Input: cmp.Or(config.S3URI, config.InputFile)}) {
+			switchBack()
 			fmt.Println("Restore cancelled.")
 			return nil
 		}
 	}
 
-	return c.dbManager.Restore(config)
+	reason, err := c.quotaReason(aws.QuotaDBRestore, config.Environment, "", fs.String("reason", ""), skipConfirm)
+	if err != nil {
+		switchBack()
+		return err
+	}
+
+	if err := c.dbManager.Restore(config); err != nil {
+		return err
+	}
+	// Counted once it succeeded, so a failed restore can be retried
+	c.recordQuota(aws.QuotaDBRestore, config.Environment, "", cmp.Or(config.S3URI, config.InputFile), reason)
+	return nil
+}
+
+func (c *CLI) dbBackups(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw db backups <list|prune|retry> [env]\n\nSubcommands:\n  list [env]                  List cataloged backups, newest first\n  prune [env] --keep <n>      Delete all but the newest n backups per environment\n  retry <id>                  Run an interrupted backup again from the start\n\nPrune flags:\n  --keep <n>                  Backups to keep per environment (default: 10)\n  --yes, -y                   Skip confirmation prompt")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.dbBackupsList(args[1:])
+	case "prune":
+		return c.dbBackupsPrune(args[1:])
+	case "retry":
+		return c.dbBackupsRetry(args[1:])
+	default:
+		return fmt.Errorf("unknown backups subcommand: %s\nUse: list, prune, retry", args[0])
+	}
+}
+
+func (c *CLI) dbBackupsList(args []string) error {
+	fs := ParseFlags(args)
+	backups, err := c.dbManager.ListBackups(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if len(backups) == 0 {
+		fmt.Println("No backups recorded. Create one with 'rw db backup <env> --output <file>'.")
+		return nil
+	}
+
+	fmt.Printf("%-5s %-8s %-17s %-10s %-7s %-12s %-9s %-12s %s\n", "ID", "ENV", "CREATED", "SIZE", "MODE", "STATUS", "DURATION", "HASH", "LOCATION")
+	fmt.Println(strings.Repeat("-", 113))
+	interrupted := 0
+	for _, b := range backups {
+		mode := "full"
+		if b.SchemaOnly {
+			mode = "schema"
+		}
+		if b.Interrupted() {
+			interrupted++
+		}
+		duration := (time.Duration(b.DurationMS) * time.Millisecond).Round(time.Second)
+		fmt.Printf("%-5d %-8s %-17s %-10s %-7s %-12s %-9s %-12s %s\n",
+			b.ID, b.Environment, b.CreatedAt.Local().Format("2006-01-02 15:04"),
+			utils.FormatBytes(b.SizeBytes), mode, b.Status, duration, cmp.Or(shortHash(b.ContentHash), "-"), b.Location)
+	}
+	if interrupted > 0 {
+		fmt.Println("\nInterrupted backups can't be restored; start one again with 'rw db backups retry <id>'.")
+	}
+
+	return nil
+}
+
+func (c *CLI) dbBackupsRetry(args []string) error {
+	fs := ParseFlags(args)
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("usage: rw db backups retry <id>")
+	}
+	return c.dbManager.RetryBackup(id)
+}
+
+func (c *CLI) dbBackupsPrune(args []string) error {
+	fs := ParseFlags(args)
+	keep, err := fs.Int("keep", 10)
+	if err != nil || keep < 0 {
+		return fmt.Errorf("invalid --keep value (must be a non-negative integer)")
+	}
+
+	backups, err := c.dbManager.ListBackups(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	prune := aws.BackupsToPrune(backups, keep)
+	if len(prune) == 0 {
+		fmt.Printf("Nothing to prune (keeping the newest %d backups per environment).\n", keep)
+		return nil
+	}
+
+	fmt.Printf("Backups to delete (keeping the newest %d per environment):\n", keep)
+	for _, b := range prune {
+		fmt.Printf("  %d  %s  %s  %s\n", b.ID, b.Environment, b.CreatedAt.Local().Format("2006-01-02 15:04"), b.Location)
+	}
+
+	if !fs.AssumeYes() {
+		prompt := messages.New(messages.ConfirmBackupPrune, "count", strconv.Itoa(len(prune)))
+		if !confirm.Yes(fmt.Sprintf("\n%s %s ", prompt, messages.Render(messages.ConfirmTypeYes, nil))) {
+			fmt.Println("Prune cancelled.")
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, b := range prune {
+		if err := c.dbManager.DeleteBackup(b); err != nil {
+			fmt.Fprintf(utils.Stdout, utils.Fail()+" Backup %d: %v\n", b.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf(utils.OK()+" Deleted backup %d\n", b.ID)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d backup(s) could not be deleted", failed)
+	}
+	return nil
+}
+
+// printRestoreCheck shows what a restore is about to overwrite
+func printRestoreCheck(check *aws.RestoreCheck) {
+	fmt.Println("\nRestore checks:")
+	fmt.Printf("  Target tables:   %d\n", check.TargetTables)
+	fmt.Printf("  Target schema:   %s\n", cmp.Or(check.TargetVersion, "(no schema_migrations)"))
+	if check.DumpInspected {
+		fmt.Printf("  Dump schema:     %s\n", cmp.Or(check.DumpVersion, "(no schema_migrations)"))
+		if check.DumpServerVersion != "" {
+			fmt.Printf("  Dumped from:     PostgreSQL %s\n", check.DumpServerVersion)
+		}
+	} else {
+		fmt.Println("  Dump schema:     (not inspected for S3 backups)")
+	}
+
+	if len(check.Warnings) == 0 {
+		fmt.Println(utils.OK() + " No problems found")
+		return
+	}
+	for _, w := range check.Warnings {
+		fmt.Printf(utils.Warn()+" %s\n", w)
+	}
+}
+
+// shortHash abbreviates a content hash for display, like a git short SHA
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
 }
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"rolewalkers/internal/utils"
+)
+
+// deprecation is a command or flag that still works but is scheduled for
+// removal. Using it prints a warning naming the replacement, so removing it
+// in a later release doesn't come as a surprise. Remove the entry together
+// with the command or flag.
+type deprecation struct {
+	// Command is the command's catalog name, e.g. "switch"
+	Command string `json:"command"`
+	// Subcommand limits a flag to one subcommand, e.g. "prompt"
+	Subcommand string `json:"subcommand,omitempty"`
+	// Flag is the deprecated flag, e.g. "--skip-kube"; empty when the
+	// whole command is deprecated
+	Flag string `json:"flag,omitempty"`
+	// RemoveIn is the release that removes it
+	RemoveIn string `json:"remove_in"`
+	// Replacement says what to use instead
+	Replacement string `json:"replacement"`
+}
+
+// deprecations are listed by 'rw changelog'
+var deprecations = []deprecation{
+	{Command: "switch", Flag: "--skip-kube", RemoveIn: "2.0.0", Replacement: "use --no-kube"},
+	{Command: "set", Subcommand: "prompt", Flag: "--remove", RemoveIn: "2.0.0", Replacement: "use --reset"},
+}
+
+// usage is how the deprecated command or flag is typed
+func (d deprecation) usage() string {
+	return strings.Join(slices.DeleteFunc([]string{"rw", d.Command, d.Subcommand, d.Flag}, func(s string) bool { return s == "" }), " ")
+}
+
+// warnDeprecated warns about each deprecated command or flag in a command line
+func warnDeprecated(command string, args []string) {
+	info, ok := lookupCommand(command)
+	if !ok {
+		return
+	}
+	for _, d := range deprecations {
+		if d.Command != info.Name || (d.Subcommand != "" && (len(args) == 0 || args[0] != d.Subcommand)) {
+			continue
+		}
+		if d.Flag != "" && !hasFlag(args, d.Flag) {
+			continue
+		}
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" '%s' is deprecated and will be removed in v%s; %s.\n", d.usage(), d.RemoveIn, d.Replacement)
+	}
+}
+
+// hasFlag reports whether args contain flag, as "--flag" or "--flag=value",
+// before any "--"
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// direnv prints an .envrc snippet for an environment, or with --install
+// installs the direnv library that lets an .envrc say 'use rolewalkers
+// <env>'. A flag rather than a subcommand, so any name can be an
+// environment.
+func (c *CLI) direnv(args []string) error {
+	fs := ParseFlags(args)
+	if fs.Bool("install") {
+		return c.direnvInstall()
+	}
+
+	env := fs.EnvArg(0)
+	if err := c.requireDB("rw direnv"); err != nil {
+		return err
+	}
+	if env == "" {
+		picked, err := c.pickEnvironment()
+		if err != nil {
+			return err
+		}
+		env = picked
+	}
+	env = strings.ToLower(env)
+
+	environment, err := c.dbRepo.GetEnvironment(env)
+	if err != nil {
+		return fmt.Errorf("unknown environment: %s", env)
+	}
+
+	services, err := c.dbRepo.GetAllServices()
+	if err != nil {
+		return err
+	}
+	names := make(map[int]string, len(services))
+	for _, s := range services {
+		names[s.ID] = s.Name
+	}
+	mappings, err := c.dbRepo.GetAllPortMappings()
+	if err != nil {
+		return err
+	}
+	ports := make(map[string]int)
+	for _, pm := range mappings {
+		if pm.EnvironmentID == environment.ID && names[pm.ServiceID] != "" {
+			ports[names[pm.ServiceID]] = pm.LocalPort
+		}
+	}
+
+	// A missing context only loses the hint; the cluster name is shown instead
+	kubeContext, _ := c.kubeManager.FindContextForEnv(env)
+
+	fmt.Print(aws.GenerateEnvrc(*environment, ports, kubeContext))
+	return nil
+}
+
+// direnvInstall writes the 'use rolewalkers' function to direnv's lib
+// directory
+func (c *CLI) direnvInstall() error {
+	path, err := aws.InstallDirenvLibrary()
+	if err != nil {
+		return err
+	}
+	fmt.Printf(utils.OK()+" Installed the direnv library: %s\n", path)
+	fmt.Println("\nIn a project's .envrc:")
+	fmt.Println("  use rolewalkers dev")
+	fmt.Println("\nThen run 'direnv allow' in that directory.")
+	return nil
+}
@@ -0,0 +1,376 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+// docs writes man pages or markdown reference docs generated from the
+// command catalog, so packages and the website match the binary
+func (c *CLI) docs(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw docs <man|markdown|json> [--output <dir|file>]\n\nExample: rw docs man --output ./man")
+	}
+
+	fs := ParseFlags(args[1:])
+	if args[0] == "json" {
+		return c.docsJSON(fs.String("output", ""))
+	}
+	var pages map[string]string
+	var dir string
+	switch args[0] {
+	case "man":
+		pages = ManPages(time.Now())
+		dir = fs.String("output", "man")
+	case "markdown", "md":
+		pages = MarkdownDocs()
+		dir = fs.String("output", "docs")
+	default:
+		return fmt.Errorf("unknown docs format: %s\nUse: man, markdown, json", args[0])
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for name, content := range pages {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	fmt.Printf(utils.OK()+" Wrote %d page(s) to %s\n", len(pages), dir)
+	return nil
+}
+
+// usageExample is an entry from usageExamples
+type usageExample struct {
+	Command string
+	Comment string
+}
+
+// exampleCommand matches the rw command word in an example line
+var exampleCommand = regexp.MustCompile(`(?:^|[^\w-])rw ([\w-]+)`)
+
+// examples returns the usage examples that run this command
+func (cmd commandInfo) examples() []usageExample {
+	names := make(map[string]bool)
+	for _, name := range cmd.names() {
+		names[name] = true
+	}
+
+	var examples []usageExample
+	for _, line := range usageExamples {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := exampleCommand.FindStringSubmatch(line)
+		if m == nil || !names[m[1]] {
+			continue
+		}
+		command, comment, _ := strings.Cut(line, " # ")
+		examples = append(examples, usageExample{
+			Command: strings.TrimSpace(command),
+			Comment: strings.TrimSpace(comment),
+		})
+	}
+	return examples
+}
+
+// synopsis returns the usage lines for the command and its subcommands
+func (cmd commandInfo) synopsis() []string {
+	var lines []string
+	if cmd.Args != "" || len(cmd.Subcommands) == 0 {
+		lines = append(lines, strings.TrimSpace("rw "+cmd.Name+" "+cmd.Args))
+	}
+	for _, sub := range cmd.Subcommands {
+		lines = append(lines, strings.TrimSpace("rw "+cmd.Name+" "+sub.Name+" "+sub.Args))
+	}
+	return lines
+}
+
+// pageName returns the man page / markdown page name, e.g. "rw-db"
+func (cmd commandInfo) pageName() string {
+	return "rw-" + cmd.Name
+}
+
+func (f flagInfo) String() string {
+	if f.Arg == "" {
+		return f.Name
+	}
+	return f.Name + " <" + f.Arg + ">"
+}
+
+// ManPages returns rw(1) and a page per command (rw-db(1), ...) in roff
+// format, keyed by file name. date is printed in the page footer; pass a
+// fixed date (e.g. from SOURCE_DATE_EPOCH) for reproducible packages.
+func ManPages(date time.Time) map[string]string {
+	footer := fmt.Sprintf("%q %q \"User Commands\"", date.UTC().Format("2006-01-02"), "rolewalkers "+Version)
+	pages := map[string]string{"rw.1": mainManPage(footer)}
+	for _, cmd := range visibleCommands() {
+		pages[cmd.pageName()+".1"] = commandManPage(cmd, footer)
+	}
+	return pages
+}
+
+func mainManPage(footer string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ".TH RW 1 %s\n", footer)
+	sb.WriteString(".SH NAME\n")
+	sb.WriteString("rw \\- AWS profile & SSO manager\n")
+	sb.WriteString(".SH SYNOPSIS\n")
+	sb.WriteString(".B rw\n")
+	sb.WriteString(".I command\n")
+	sb.WriteString("[\\fIarguments\\fR]\n")
+	sb.WriteString(".SH DESCRIPTION\n")
+	sb.WriteString("rolewalkers manages AWS profiles and SSO logins, switches kubectl contexts with\n")
+	sb.WriteString("them, and runs tunnels, database and operations commands against environments.\n")
+	sb.WriteString(".SH COMMANDS\n")
+	for _, cmd := range visibleCommands() {
+		fmt.Fprintf(&sb, ".TP\n.BR %s (1)\n%s\n", roffEscape(cmd.pageName()), roffEscape(cmd.Summary))
+	}
+	sb.WriteString(".SH OPTIONS\n")
+	writeManFlags(&sb, globalFlags)
+	sb.WriteString(".SH ENVIRONMENT\n")
+	sb.WriteString("See \\fBrw help env\\fR.\n")
+	sb.WriteString(".SH FILES\n")
+	sb.WriteString(".TP\n.I ~/.aws/config\nAWS profiles\n")
+	sb.WriteString(".TP\n.I ~/.rolewalkers\nDatabase, tunnel state and configuration\n")
+	sb.WriteString(".SH SEE ALSO\n")
+	sb.WriteString(".BR aws (1),\n.BR kubectl (1)\n")
+	return sb.String()
+}
+
+func commandManPage(cmd commandInfo, footer string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ".TH %s 1 %s\n", strings.ToUpper(roffEscape(cmd.pageName())), footer)
+	sb.WriteString(".SH NAME\n")
+	fmt.Fprintf(&sb, "%s \\- %s\n", roffEscape(cmd.pageName()), roffEscape(cmd.Summary))
+	sb.WriteString(".SH SYNOPSIS\n.nf\n")
+	for _, line := range cmd.synopsis() {
+		fmt.Fprintf(&sb, "%s\n", roffEscape(line))
+	}
+	sb.WriteString(".fi\n")
+	sb.WriteString(".SH DESCRIPTION\n")
+	fmt.Fprintf(&sb, "%s.\n", roffEscape(cmd.Summary))
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&sb, ".PP\nAliases: %s\n", roffEscape(strings.Join(cmd.Aliases, ", ")))
+	}
+	if len(cmd.Subcommands) > 0 {
+		sb.WriteString(".SH COMMANDS\n")
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(&sb, ".TP\n.B %s\n", roffEscape(strings.TrimSpace(sub.Name+" "+sub.Args)))
+			if sub.Summary != "" {
+				fmt.Fprintf(&sb, "%s\n", roffEscape(sub.Summary))
+			}
+		}
+	}
+	if len(cmd.Flags) > 0 {
+		sb.WriteString(".SH OPTIONS\n")
+		writeManFlags(&sb, cmd.Flags)
+	}
+	if examples := cmd.examples(); len(examples) > 0 {
+		sb.WriteString(".SH EXAMPLES\n")
+		for _, ex := range examples {
+			fmt.Fprintf(&sb, ".TP\n.B %s\n", roffEscape(ex.Command))
+			if ex.Comment != "" {
+				fmt.Fprintf(&sb, "%s\n", roffEscape(ex.Comment))
+			}
+		}
+	}
+	sb.WriteString(".SH SEE ALSO\n")
+	sb.WriteString(".BR rw (1)\n")
+	return sb.String()
+}
+
+func writeManFlags(sb *strings.Builder, flags []flagInfo) {
+	for _, f := range flags {
+		if f.Arg == "" {
+			fmt.Fprintf(sb, ".TP\n.B %s\n", roffEscape(f.Name))
+		} else {
+			fmt.Fprintf(sb, ".TP\n.BI %s \" %s\"\n", roffEscape(f.Name), roffEscape(f.Arg))
+		}
+		fmt.Fprintf(sb, "%s\n", roffEscape(f.Usage))
+	}
+}
+
+// roffEscape escapes backslashes and hyphens, and protects lines that would
+// start with a roff control character
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	s = strings.ReplaceAll(s, "-", "\\-")
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = "\\&" + s
+	}
+	return s
+}
+
+// MarkdownDocs returns a markdown reference page per command plus an index
+// (rw.md), keyed by file name
+func MarkdownDocs() map[string]string {
+	var index strings.Builder
+	index.WriteString("# rw\n\n")
+	index.WriteString("AWS profile & SSO manager. Generated by `rw docs markdown`; do not edit.\n\n")
+	index.WriteString("| Command | Description |\n|---|---|\n")
+
+	pages := make(map[string]string)
+	for _, cmd := range visibleCommands() {
+		fmt.Fprintf(&index, "| [rw %s](%s.md) | %s |\n", cmd.Name, cmd.pageName(), markdownCell(cmd.Summary))
+		pages[cmd.pageName()+".md"] = commandMarkdown(cmd)
+	}
+
+	index.WriteString("\n## Global flags\n\n")
+	writeMarkdownFlags(&index, globalFlags)
+	pages["rw.md"] = index.String()
+	return pages
+}
+
+func commandMarkdown(cmd commandInfo) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# rw %s\n\n%s.\n\n", cmd.Name, cmd.Summary)
+	if len(cmd.Aliases) > 0 {
+		aliases := make([]string, len(cmd.Aliases))
+		for i, a := range cmd.Aliases {
+			aliases[i] = "`" + a + "`"
+		}
+		fmt.Fprintf(&sb, "Aliases: %s\n\n", strings.Join(aliases, ", "))
+	}
+
+	sb.WriteString("## Usage\n\n```\n")
+	for _, line := range cmd.synopsis() {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("```\n")
+
+	if len(cmd.Subcommands) > 0 {
+		sb.WriteString("\n## Subcommands\n\n| Command | Description |\n|---|---|\n")
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(&sb, "| `%s` | %s |\n", markdownCell(strings.TrimSpace(sub.Name+" "+sub.Args)), markdownCell(sub.Summary))
+		}
+	}
+	if len(cmd.Flags) > 0 {
+		sb.WriteString("\n## Flags\n\n")
+		writeMarkdownFlags(&sb, cmd.Flags)
+	}
+	if examples := cmd.examples(); len(examples) > 0 {
+		sb.WriteString("\n## Examples\n\n```bash\n")
+		width := 0
+		for _, ex := range examples {
+			width = max(width, len(ex.Command))
+		}
+		for _, ex := range examples {
+			if ex.Comment == "" {
+				sb.WriteString(ex.Command + "\n")
+				continue
+			}
+			fmt.Fprintf(&sb, "%-*s  # %s\n", width, ex.Command, ex.Comment)
+		}
+		sb.WriteString("```\n")
+	}
+	sb.WriteString("\nSee also: [rw](rw.md)\n")
+	return sb.String()
+}
+
+func writeMarkdownFlags(sb *strings.Builder, flags []flagInfo) {
+	sb.WriteString("| Flag | Description |\n|---|---|\n")
+	for _, f := range flags {
+		fmt.Fprintf(sb, "| `%s` | %s |\n", markdownCell(f.String()), markdownCell(f.Usage))
+	}
+}
+
+// markdownCell escapes pipes so text can sit in a table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// commandDoc is a command in 'rw docs json'
+type commandDoc struct {
+	Name        string          `json:"name"`
+	Aliases     []string        `json:"aliases,omitempty"`
+	Args        string          `json:"args,omitempty"`
+	Summary     string          `json:"summary"`
+	Subcommands []subcommandDoc `json:"subcommands,omitempty"`
+	Flags       []flagDoc       `json:"flags,omitempty"`
+	// Slack lists the forms of the command 'rw slack serve' runs remotely
+	Slack []string `json:"slack,omitempty"`
+}
+
+type subcommandDoc struct {
+	Name    string `json:"name"`
+	Args    string `json:"args,omitempty"`
+	Summary string `json:"summary"`
+}
+
+type flagDoc struct {
+	Name  string `json:"name"`
+	Arg   string `json:"arg,omitempty"`
+	Usage string `json:"usage"`
+}
+
+// commandTree is the command catalog in 'rw docs json'
+type commandTree struct {
+	Version     string       `json:"version"`
+	Commands    []commandDoc `json:"commands"`
+	GlobalFlags []flagDoc    `json:"global_flags"`
+}
+
+func flagDocs(flags []flagInfo) []flagDoc {
+	docs := make([]flagDoc, 0, len(flags))
+	for _, f := range flags {
+		docs = append(docs, flagDoc{Name: f.Name, Arg: f.Arg, Usage: f.Usage})
+	}
+	return docs
+}
+
+// buildCommandTree returns the documented commands, with the usages of the
+// read-only subset served to Slack, for tools such as launchers and
+// command palettes that drive rw
+func buildCommandTree(slackUsages []string) commandTree {
+	tree := commandTree{Version: Version, GlobalFlags: flagDocs(globalFlags)}
+	for _, cmd := range visibleCommands() {
+		doc := commandDoc{
+			Name:    cmd.Name,
+			Aliases: cmd.Aliases,
+			Args:    cmd.Args,
+			Summary: cmd.Summary,
+			Flags:   flagDocs(cmd.Flags),
+		}
+		for _, sub := range cmd.Subcommands {
+			doc.Subcommands = append(doc.Subcommands, subcommandDoc{Name: sub.Name, Args: sub.Args, Summary: sub.Summary})
+		}
+		for _, usage := range slackUsages {
+			if word, _, _ := strings.Cut(usage, " "); word == cmd.Name {
+				doc.Slack = append(doc.Slack, usage)
+			}
+		}
+		tree.Commands = append(tree.Commands, doc)
+	}
+	return tree
+}
+
+// docsJSON prints the command tree as JSON, or writes it to output
+func (c *CLI) docsJSON(output string) error {
+	var slackUsages []string
+	for _, cmd := range c.slackCommands() {
+		slackUsages = append(slackUsages, cmd.Usage)
+	}
+	data, err := json.MarshalIndent(buildCommandTree(slackUsages), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	fmt.Printf(utils.OK()+" Wrote the command tree to %s\n", output)
+	return nil
+}
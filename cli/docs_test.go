@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exampleValues are the environments and services usage examples pass as
+// positional arguments
+var exampleValues = []string{"dev", "prod", "staging", "sit", "db", "redis", "candidate"}
+
+func TestUsageExamplesResolve(t *testing.T) {
+	// Shortcuts defined by an 'rw alias add' example
+	aliases := make(map[string]bool)
+	for _, line := range usageExamples {
+		if fields := strings.Fields(line); len(fields) > 3 && fields[0] == "rw" && fields[1] == "alias" && fields[2] == "add" {
+			aliases[fields[3]] = true
+		}
+	}
+
+	for _, line := range usageExamples {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		command, _, _ := strings.Cut(line, " # ")
+		i := strings.Index(command, "rw ")
+		if i < 0 {
+			t.Errorf("example %q doesn't run rw", line)
+			continue
+		}
+		words := strings.Fields(strings.TrimRight(command[i:], ")"))[1:]
+		if aliases[words[0]] {
+			continue
+		}
+
+		cmd, ok := lookupCommand(words[0])
+		if !ok {
+			t.Errorf("example %q: unknown command %q", line, words[0])
+			continue
+		}
+		if len(words) < 2 || strings.HasPrefix(words[1], "-") {
+			continue
+		}
+		arg := words[1]
+		switch {
+		case slices.Contains(cmd.subcommandNames(), arg):
+		case strings.HasPrefix(cmd.Args, "<env>"), strings.HasPrefix(cmd.Args, "[env]"),
+			strings.HasPrefix(cmd.Args, "<svc>"), strings.HasPrefix(cmd.Args, "<service>"):
+			if !slices.Contains(exampleValues, arg) {
+				t.Errorf("example %q: %q is neither a subcommand of rw %s nor a known environment or service", line, arg, cmd.Name)
+			}
+		case cmd.Args == "" && len(cmd.Subcommands) > 0:
+			t.Errorf("example %q: rw %s has no subcommand %q", line, cmd.Name, arg)
+		case cmd.Args == "":
+			t.Errorf("example %q: rw %s takes no arguments", line, cmd.Name)
+		}
+	}
+}
+
+func TestManPages(t *testing.T) {
+	pages := ManPages(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	main, ok := pages["rw.1"]
+	if !ok {
+		t.Fatal("ManPages() has no rw.1")
+	}
+	if !strings.HasPrefix(main, ".TH RW 1 \"2026-01-02\"") {
+		t.Errorf("rw.1 header = %q", strings.SplitN(main, "\n", 2)[0])
+	}
+	for _, cmd := range visibleCommands() {
+		page, ok := pages[cmd.pageName()+".1"]
+		if !ok {
+			t.Errorf("ManPages() has no page for rw %s", cmd.Name)
+			continue
+		}
+		if !strings.Contains(main, ".BR "+roffEscape(cmd.pageName())+" (1)") {
+			t.Errorf("rw.1 doesn't list %s", cmd.pageName())
+		}
+		for _, line := range strings.Split(page, "\n") {
+			if strings.HasPrefix(line, ".") && !strings.HasPrefix(line, ".TH") && !strings.HasPrefix(line, ".SH") &&
+				!strings.HasPrefix(line, ".TP") && !strings.HasPrefix(line, ".B") && !strings.HasPrefix(line, ".PP") &&
+				!strings.HasPrefix(line, ".nf") && !strings.HasPrefix(line, ".fi") {
+				t.Errorf("%s.1: unexpected roff request %q", cmd.pageName(), line)
+			}
+		}
+	}
+
+	db := pages["rw-db.1"]
+	if !strings.Contains(db, ".SH EXAMPLES") || !strings.Contains(db, `rw db connect`) {
+		t.Errorf("rw-db.1 has no examples:\n%s", db)
+	}
+}
+
+func TestMarkdownDocs(t *testing.T) {
+	pages := MarkdownDocs()
+
+	index, ok := pages["rw.md"]
+	if !ok {
+		t.Fatal("MarkdownDocs() has no rw.md")
+	}
+	for _, cmd := range visibleCommands() {
+		if !strings.Contains(index, "("+cmd.pageName()+".md)") {
+			t.Errorf("rw.md doesn't link %s.md", cmd.pageName())
+		}
+		page, ok := pages[cmd.pageName()+".md"]
+		if !ok {
+			t.Errorf("MarkdownDocs() has no page for rw %s", cmd.Name)
+			continue
+		}
+		if !strings.HasPrefix(page, "# rw "+cmd.Name+"\n") {
+			t.Errorf("%s.md starts %q", cmd.pageName(), strings.SplitN(page, "\n", 2)[0])
+		}
+		if strings.Count(page, "```")%2 != 0 {
+			t.Errorf("%s.md has an unterminated code block", cmd.pageName())
+		}
+	}
+
+	if kube := pages["rw-kube.md"]; !strings.Contains(kube, "rw kube set namespace") {
+		t.Errorf("rw-kube.md examples = %s", kube)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		script, err := Completion(shell)
+		if err != nil {
+			t.Errorf("Completion(%s) error: %v", shell, err)
+			continue
+		}
+		for _, cmd := range visibleCommands() {
+			if !strings.Contains(script, cmd.Name) {
+				t.Errorf("Completion(%s) doesn't complete %s", shell, cmd.Name)
+			}
+		}
+	}
+
+	bash, _ := Completion("bash")
+	if !strings.Contains(bash, "complete -o default -F _rw rw") {
+		t.Error("bash completion doesn't register _rw")
+	}
+	if !strings.Contains(bash, "kube|k8s|k) words=") || !strings.Contains(bash, "can-i") {
+		t.Error("bash completion doesn't offer kube's subcommands")
+	}
+
+	if _, err := Completion("tcsh"); err == nil {
+		t.Error("Completion(tcsh) should fail")
+	}
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/toolcheck"
+	"rolewalkers/internal/utils"
+)
+
+// dbProblem classifies why the database couldn't be opened
+func dbProblem(err error) db.Problem {
+	var unavailable *db.UnavailableError
+	if errors.As(err, &unavailable) {
+		return unavailable.Problem()
+	}
+	return db.Classify(err)
+}
+
+// requireDB returns the uniform degraded-mode error when the database is
+// unavailable, so every command that needs it fails the same way
+func (c *CLI) requireDB(command string) error {
+	if c.dbRepo != nil {
+		return nil
+	}
+	reason := "not initialized"
+	if c.dbErr != nil {
+		reason = dbProblem(c.dbErr).Description()
+	}
+	return fmt.Errorf("'%s' needs the rolewalkers database, which is unavailable (%s)\n"+
+		"Commands that only read ~/.aws/config (list, switch, login, status, context, kube) still work.\n"+
+		"Run 'rw doctor' to see how to repair it", command, reason)
+}
+
+// doctor checks the state directory, database and AWS config, and explains
+// how to repair a database rw can't open. --fix moves a corrupt database
+// aside so a fresh one is created.
+func (c *CLI) doctor(args []string) error {
+	fs := ParseFlags(args)
+	fix := fs.Bool("fix")
+
+	fmt.Println("rolewalkers doctor")
+	fmt.Println()
+
+	stateDir, err := utils.StateDir()
+	if err != nil {
+		fmt.Fprintf(utils.Stdout, "  "+utils.Fail()+" State directory: %v\n", err)
+	} else {
+		fmt.Printf("  "+utils.OK()+" State directory: %s\n", stateDir)
+	}
+
+	path, _ := db.Path()
+	problem, healthy := c.doctorDatabase(path)
+
+	if profiles, err := c.configManager.GetProfiles(); err != nil {
+		fmt.Fprintf(utils.Stdout, "  "+utils.Fail()+" AWS config: %v\n", err)
+	} else {
+		fmt.Printf("  "+utils.OK()+" AWS config: %d profile(s) in ~/.aws/config\n", len(profiles))
+	}
+	findings := toolcheck.New().Check()
+	if len(findings) == 0 {
+		fmt.Println("  " + utils.OK() + " Tools: aws CLI and kubectl are recent enough")
+	}
+	for _, f := range findings {
+		fmt.Printf("  "+utils.Warn()+" %s: %s\n      %s\n", f.Tool, f.Problem, f.Hint)
+	}
+	fmt.Println()
+
+	if healthy {
+		if len(findings) == 0 {
+			fmt.Println("No problems found.")
+		}
+		return nil
+	}
+
+	if !fix {
+		fmt.Println("To repair:")
+		for _, step := range db.RepairSteps(problem, path) {
+			fmt.Printf("  %s\n", step)
+		}
+		return nil
+	}
+
+	if problem != db.ProblemCorrupt {
+		return fmt.Errorf("--fix only repairs a corrupt database (this one is %s); follow the steps above", problem.Description())
+	}
+	if path == "" {
+		return fmt.Errorf("cannot resolve the database path")
+	}
+	if !fs.AssumeYes() && !confirm.Yes(messages.Render(messages.ConfirmDatabaseMoveAside, messages.Params{"path": path})+" "+messages.Render(messages.ConfirmTypeYes, nil)+" ") {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if c.database != nil {
+		c.database.Close()
+	}
+	moved, err := db.MoveAside(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(utils.OK()+" Moved the damaged database to %s\n", moved)
+
+	database, err := db.NewDB()
+	if err != nil {
+		return fmt.Errorf("failed to create a fresh database: %w", err)
+	}
+	defer database.Close()
+	fmt.Printf(utils.OK()+" Created a fresh database at %s\n", path)
+	fmt.Println("  Re-import your profiles with 'rw config sync' (or 'rw bootstrap').")
+	return nil
+}
+
+// doctorDatabase prints the database status and reports whether it is
+// usable and, if not, what the problem is: the error NewDB failed with, the
+// error checking it, or a failed integrity check
+func (c *CLI) doctorDatabase(path string) (db.Problem, bool) {
+	if c.database == nil {
+		problem := dbProblem(c.dbErr)
+		fmt.Printf("  "+utils.Fail()+" Database: %s (%s)\n", path, problem.Description())
+		if c.dbErr != nil {
+			fmt.Printf("      %v\n", c.dbErr)
+		}
+		return problem, false
+	}
+
+	health, err := c.database.Check()
+	if err != nil {
+		fmt.Fprintf(utils.Stdout, "  "+utils.Fail()+" Database: %s: %v\n", path, err)
+		return dbProblem(err), false
+	}
+	if health.Integrity != "ok" {
+		fmt.Printf("  "+utils.Fail()+" Database: %s (integrity check failed: %s)\n", path, health.Integrity)
+		return db.ProblemCorrupt, false
+	}
+	if latest := db.LatestSchemaVersion(); health.SchemaVersion < latest {
+		fmt.Printf("  "+utils.Warn()+" Database: %s (schema v%d, expected v%d)\n", path, health.SchemaVersion, latest)
+		return "", true
+	}
+	fmt.Printf("  "+utils.OK()+" Database: %s (schema v%d, integrity ok)\n", path, health.SchemaVersion)
+	return "", true
+}
+
+// toolCheckEnv turns off the daily check of external tool versions
+const toolCheckEnv = "RW_NO_TOOL_CHECK"
+
+// toolCheckSkipped are commands that never run the aws CLI or kubectl
+// ('rw doctor' checks the tools itself, every time), and 'rw context',
+// which the shell prompt runs on every render
+var toolCheckSkipped = []string{"help", "version", "example", "completion", "docs", "doctor", "context"}
+
+// checkTools warns, at most once a day, when the aws CLI, kubectl or the
+// session-manager-plugin is missing or too old for rw. Running the tools
+// takes a moment, so commands read by scripts are never held up by it.
+func checkTools(command string, args []string) {
+	info, ok := lookupCommand(command)
+	if !ok || slices.Contains(toolCheckSkipped, info.Name) || machineReadable(command, args) || utils.EnvBool(toolCheckEnv) {
+		return
+	}
+	findings, _ := toolcheck.CheckDaily(time.Now())
+	for _, f := range findings {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" %s: %s\n    %s\n", f.Tool, f.Problem, f.Hint)
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(utils.Stderr, "  (checked once a day; run 'rw doctor' to check again, or set %s=1)\n", toolCheckEnv)
+	}
+}
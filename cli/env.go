@@ -0,0 +1,328 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"rolewalkers/aws"
+	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+func (c *CLI) env(args []string) error {
+	if err := c.requireDB("rw env"); err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw env <list|clone|create-ephemeral|expire|account|production>\n\nSubcommands:\n  list                    List environments and upcoming expirations\n  clone <source> <name>   Copy an environment with its port and cluster mappings\n  create-ephemeral <name> --ttl <duration>\n                          Clone an environment that is cleaned up after the TTL\n  expire                  Clean up ephemeral environments whose TTL has passed\n  account <env> [id]      Show or set the AWS account an environment lives in\n  production <env> [on|off]\n                          Show or set whether an environment is production")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.envList()
+	case "clone":
+		return c.envClone(args[1:])
+	case "create-ephemeral":
+		return c.envCreateEphemeral(args[1:])
+	case "expire":
+		return c.envExpire()
+	case "account":
+		return c.envAccount(args[1:])
+	case "production", "prod":
+		return c.envProduction(args[1:])
+	default:
+		return fmt.Errorf("unknown env subcommand: %s\nUse: list, clone, create-ephemeral, expire, account, production", args[0])
+	}
+}
+
+func (c *CLI) envList() error {
+	envs, err := c.dbRepo.GetAllEnvironments()
+	if err != nil {
+		return err
+	}
+	expiries, err := c.dbRepo.GetEnvironmentExpiries()
+	if err != nil {
+		return err
+	}
+	expiresAt := make(map[string]time.Time)
+	for _, e := range expiries {
+		expiresAt[e.Name] = e.ExpiresAt
+	}
+	production := aws.ProductionEnvs(c.dbRepo)
+
+	fmt.Println("Environments:")
+	fmt.Println(strings.Repeat("-", 96))
+	fmt.Printf("  %-12s %-18s %-18s %-28s %-5s %s\n", "NAME", "DISPLAY NAME", "PROFILE", "CLUSTER", "PROD", "EXPIRES")
+	for _, env := range envs {
+		prod := "-"
+		if slices.Contains(production, env.Name) {
+			prod = "yes"
+		}
+		expires := "-"
+		if t, ok := expiresAt[env.Name]; ok {
+			expires = utils.FormatRelative(t, time.Now())
+			if !t.After(time.Now()) {
+				expires = "expired"
+			}
+		}
+		fmt.Printf("  %-12s %-18s %-18s %-28s %-5s %s\n", env.Name, env.DisplayName, env.AWSProfile, env.ClusterName, prod, expires)
+	}
+
+	if len(expiries) > 0 {
+		fmt.Println()
+		fmt.Println("Upcoming expirations:")
+		for _, e := range expiries {
+			fmt.Printf("  "+utils.Warn()+" %-12s %s\n", e.Name, utils.FormatTimeRelative(e.ExpiresAt, time.Now()))
+		}
+	}
+	return nil
+}
+
+func (c *CLI) envClone(args []string) error {
+	fs := ParseFlags(args)
+	sourceName, name := fs.Arg(0), fs.Arg(1)
+	if sourceName == "" || name == "" {
+		return fmt.Errorf("usage: rw env clone <source> <name> [--display-name <name>] [--cluster <name>] [--profile <profile>] [--region <region>] [--namespace <ns>] [--yes]")
+	}
+
+	target, err := c.cloneEnvironment(fs, sourceName, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("Add the kubectl context with: aws eks update-kubeconfig --name %s --profile %s\n", target.ClusterName, target.AWSProfile)
+	return nil
+}
+
+// envCreateEphemeral clones an environment and schedules its cleanup
+func (c *CLI) envCreateEphemeral(args []string) error {
+	fs := ParseFlags(args)
+	name := fs.Arg(0)
+	ttlFlag := fs.String("ttl", "")
+	if name == "" || ttlFlag == "" {
+		return fmt.Errorf("usage: rw env create-ephemeral <name> --ttl <duration> [--from <env>] [clone flags]\n\nExample: rw env create-ephemeral sit-pr42 --ttl 72h --from sit")
+	}
+
+	ttl, err := time.ParseDuration(ttlFlag)
+	if err != nil || ttl <= 0 {
+		return fmt.Errorf("invalid --ttl value: %s (use a duration such as 72h or 90m)", ttlFlag)
+	}
+
+	sourceName := fs.String("from", "")
+	if sourceName == "" {
+		if sourceName, err = c.pickEnvironment(); err != nil {
+			return err
+		}
+	}
+
+	target, err := c.cloneEnvironment(fs, sourceName, name)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if err := c.dbRepo.SetEnvironmentExpiry(target.Name, expiresAt); err != nil {
+		// Don't leave an environment behind that would never expire
+		c.dbRepo.DeactivateEnvironment(target.Name)
+		return fmt.Errorf("failed to schedule expiry: %w", err)
+	}
+
+	fmt.Printf("  Expires:   %s\n", utils.FormatTimeRelative(expiresAt, time.Now()))
+	fmt.Println()
+	fmt.Println("After the TTL, rw stops its tunnels, removes its kubectl contexts and")
+	fmt.Println("deactivates it (on the next rw command or tray refresh, or 'rw env expire').")
+	fmt.Println()
+	fmt.Printf("Add the kubectl context with: aws eks update-kubeconfig --name %s --profile %s\n", target.ClusterName, target.AWSProfile)
+	return nil
+}
+
+func (c *CLI) envExpire() error {
+	cleanups, err := aws.ExpireEnvironments(c.dbRepo, c.tunnelManager, c.kubeManager, time.Now())
+	printEnvironmentCleanups(cleanups)
+	if err != nil {
+		return err
+	}
+	if len(cleanups) == 0 {
+		fmt.Println("No expired environments")
+	}
+	return nil
+}
+
+// envAccount shows or sets the AWS account an environment is expected to
+// live in. Scaling, maintenance and restores refuse to run when the active
+// profile is in a different account.
+func (c *CLI) envAccount(args []string) error {
+	fs := ParseFlags(args)
+	env, accountID := fs.EnvArg(0), fs.Arg(1)
+	if env == "" {
+		return fmt.Errorf("usage: rw env account <env> [<account-id>|--clear]")
+	}
+
+	switch {
+	case fs.Bool("clear"):
+		if err := c.dbRepo.SetEnvironmentAccount(env, ""); err != nil {
+			return err
+		}
+		fmt.Printf(utils.OK()+" Cleared the account for %s; destructive operations won't check it\n", env)
+	case accountID != "":
+		if !awsAccountID.MatchString(accountID) {
+			return fmt.Errorf("invalid AWS account ID %q (expected 12 digits)", accountID)
+		}
+		if err := c.dbRepo.SetEnvironmentAccount(env, accountID); err != nil {
+			return err
+		}
+		fmt.Printf(utils.OK()+" %s lives in account %s\n", env, accountID)
+	default:
+		current, err := c.dbRepo.GetEnvironmentAccount(env)
+		if err != nil {
+			return err
+		}
+		if current == "" {
+			fmt.Printf("No account recorded for %s. Set one with 'rw env account %s <account-id>'.\n", env, env)
+			return nil
+		}
+		fmt.Println(current)
+	}
+	return nil
+}
+
+var awsAccountID = regexp.MustCompile(`^\d{12}$`)
+
+// envProduction shows or sets an environment's production flag. Commands
+// run while a production environment is active print a banner, and the
+// shell prompt turns red.
+func (c *CLI) envProduction(args []string) error {
+	fs := ParseFlags(args)
+	env, value := fs.EnvArg(0), fs.Arg(1)
+	if env == "" {
+		return fmt.Errorf("usage: rw env production <env> [on|off]")
+	}
+
+	switch value {
+	case "":
+		if _, err := c.dbRepo.GetEnvironment(env); err != nil {
+			return err
+		}
+		if aws.IsProductionEnv(c.dbRepo, env) {
+			fmt.Printf("%s is a production environment\n", env)
+		} else {
+			fmt.Printf("%s is not a production environment\n", env)
+		}
+		return nil
+	case "on", "off":
+	default:
+		return fmt.Errorf("usage: rw env production <env> [on|off]")
+	}
+
+	if err := c.dbRepo.SetEnvironmentProduction(env, value == "on"); err != nil {
+		return err
+	}
+	if value == "on" {
+		fmt.Printf(utils.OK()+" %s is now a production environment\n", env)
+	} else {
+		fmt.Printf(utils.OK()+" %s is no longer flagged as production\n", env)
+		if appconfig.Get().IsProductionEnv(env) {
+			fmt.Printf(utils.Warn()+" %s is still production: it is listed in production_envs in ~/.rolewalkers/config.yaml\n", env)
+		}
+	}
+	return nil
+}
+
+func printEnvironmentCleanups(cleanups []aws.EnvironmentCleanup) {
+	for _, cl := range cleanups {
+		fmt.Printf(utils.OK()+" Ephemeral environment %s expired (%s): deactivated", cl.Environment, utils.FormatTime(cl.ExpiredAt))
+		if cl.TunnelsStopped > 0 {
+			fmt.Printf(", %d tunnel(s) stopped", cl.TunnelsStopped)
+		}
+		if len(cl.ContextsRemoved) > 0 {
+			fmt.Printf(", removed context(s) %s", strings.Join(cl.ContextsRemoved, ", "))
+		}
+		fmt.Println()
+		for _, e := range cl.Errors {
+			fmt.Printf("  "+utils.Warn()+" %s\n", e)
+		}
+	}
+}
+
+// cloneEnvironment copies an environment under a new name. Values that are
+// expected to differ (display name, cluster name) are prompted for with a
+// suggested default; everything else is copied unless overridden by a flag.
+func (c *CLI) cloneEnvironment(fs *FlagSet, sourceName, name string) (*db.Environment, error) {
+	source, err := c.dbRepo.GetEnvironment(sourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	target := aws.SuggestClone(*source, name)
+	target.AWSProfile = fs.String("profile", target.AWSProfile)
+	target.Region = fs.String("region", target.Region)
+	target.Namespace = fs.String("namespace", target.Namespace)
+
+	reader := bufio.NewReader(os.Stdin)
+	prompt := func(flag, label, suggested string) string {
+		if v := fs.String(flag, ""); v != "" {
+			return v
+		}
+		if fs.AssumeYes() {
+			return suggested
+		}
+		fmt.Printf("  %s [%s]: ", label, suggested)
+		input, _ := reader.ReadString('\n')
+		if input = strings.TrimSpace(input); input != "" {
+			return input
+		}
+		return suggested
+	}
+
+	fmt.Printf("Cloning %s "+utils.Arrow()+" %s\n", source.Name, name)
+	target.DisplayName = prompt("display-name", "Display name", target.DisplayName)
+	target.ClusterName = prompt("cluster", "Cluster name", target.ClusterName)
+
+	existing, err := c.dbRepo.GetAllPortMappings()
+	if err != nil {
+		return nil, err
+	}
+	ports := aws.PlanClonePorts(source.ID, existing)
+
+	copied, err := c.dbRepo.CloneEnvironment(source.Name, target, ports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone environment: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf(utils.OK()+" Created environment %s (%s)\n", target.Name, target.DisplayName)
+	fmt.Printf("  Profile:   %s\n", target.AWSProfile)
+	fmt.Printf("  Region:    %s\n", target.Region)
+	fmt.Printf("  Cluster:   %s\n", target.ClusterName)
+	fmt.Printf("  Namespace: %s\n", target.Namespace)
+
+	if copied > 0 {
+		serviceNames := make(map[int]string)
+		if services, err := c.dbRepo.GetAllServices(); err == nil {
+			for _, s := range services {
+				serviceNames[s.ID] = s.Name
+			}
+		}
+		var lines []string
+		for serviceID, port := range ports {
+			lines = append(lines, fmt.Sprintf("    %-20s localhost:%d", serviceNames[serviceID], port))
+		}
+		sort.Strings(lines)
+
+		fmt.Printf("  Port mappings (%d):\n", copied)
+		for _, l := range lines {
+			fmt.Println(l)
+		}
+	}
+
+	return &target, nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
+)
+
+// flag reads and toggles app feature flags stored in SSM under
+// <ssm_path_prefix>/features
+func (c *CLI) flag(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw flag <list|get|set> <env> [flag] [value]\n\nSubcommands:\n  list <env> [--diff-envs <env2,env3>]   List flags, or the ones that differ across environments\n  get <env> <flag>                       Show a flag's value and type\n  set <env> <flag> <value> [--type <t>]  Set a flag (types: bool, number, string)\n\nExamples:\n  rw flag list dev\n  rw flag list prod --diff-envs preprod,dev\n  rw flag set dev new-checkout true")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.flagList(args[1:])
+	case "get":
+		return c.flagGet(args[1:])
+	case "set":
+		return c.flagSet(args[1:])
+	default:
+		return fmt.Errorf("unknown flag subcommand: %s\nUse: list, get, set", args[0])
+	}
+}
+
+func (c *CLI) flagList(args []string) error {
+	fs := ParseFlags(args)
+	env := fs.EnvArg(0)
+	if env == "" {
+		return fmt.Errorf("usage: rw flag list <env> [--diff-envs <env2,env3>]")
+	}
+	if others := fs.String("diff-envs", ""); others != "" {
+		envs := []string{env}
+		for _, e := range strings.Split(others, ",") {
+			if e = strings.TrimSpace(e); e != "" && e != env {
+				envs = append(envs, e)
+			}
+		}
+		return c.flagDiff(envs)
+	}
+
+	flags, err := c.featureFlags.List(env)
+	if err != nil {
+		return err
+	}
+	if len(flags) == 0 {
+		fmt.Printf("No feature flags found under: %s\n", c.featureFlags.Prefix(env))
+		return nil
+	}
+
+	fmt.Printf("Feature flags for %s (%s):\n", env, c.featureFlags.Prefix(env))
+	fmt.Println(strings.Repeat("-", 60))
+	for _, f := range flags {
+		fmt.Printf("  %-32s %-8s %s\n", f.Name, f.Type, f.Value)
+	}
+	return nil
+}
+
+func (c *CLI) flagDiff(envs []string) error {
+	if len(envs) < 2 {
+		return fmt.Errorf("--diff-envs needs at least one other environment")
+	}
+	diffs, err := c.featureFlags.Diff(envs)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		fmt.Printf(utils.OK()+" Feature flags match across %s\n", strings.Join(envs, ", "))
+		return nil
+	}
+
+	fmt.Printf("Feature flags that differ across %s:\n", strings.Join(envs, ", "))
+	fmt.Println(strings.Repeat("-", 32+16*len(envs)))
+	fmt.Printf("  %-32s", "FLAG")
+	for _, env := range envs {
+		fmt.Printf(" %-15s", strings.ToUpper(env))
+	}
+	fmt.Println()
+	for _, d := range diffs {
+		fmt.Printf("  %-32s", d.Name)
+		for _, env := range envs {
+			value, ok := d.Values[env]
+			if !ok {
+				value = "(missing)"
+			}
+			fmt.Printf(" %-15s", value)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func (c *CLI) flagGet(args []string) error {
+	fs := ParseFlags(args)
+	env, name := fs.Arg(0), fs.Arg(1)
+	if env == "" || name == "" {
+		return fmt.Errorf("usage: rw flag get <env> <flag>")
+	}
+
+	f, err := c.featureFlags.Get(env, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s = %s (%s)\n", f.Path, f.Value, f.Type)
+	return nil
+}
+
+func (c *CLI) flagSet(args []string) error {
+	if err := c.requireDB("rw flag set"); err != nil {
+		return err
+	}
+
+	fs := ParseFlags(args)
+	env, name, value := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	if env == "" || name == "" || value == "" {
+		return fmt.Errorf("usage: rw flag set <env> <flag> <value> [--type <bool|number|string>]\n\nThe value must match the flag's current type. --type is required to create a flag.")
+	}
+
+	var typ aws.FlagType
+	if t := fs.String("type", ""); t != "" {
+		parsed, err := aws.ParseFlagType(t)
+		if err != nil {
+			return err
+		}
+		typ = parsed
+	}
+
+	change, err := c.featureFlags.PrepareSet(env, name, value, typ)
+	if err != nil {
+		return err
+	}
+	if !change.Changed() {
+		fmt.Printf(utils.OK()+" %s is already %s on %s\n", name, change.After.Value, env)
+		return nil
+	}
+
+	before := "(new)"
+	if change.Before != nil {
+		before = change.Before.Value
+	}
+	fmt.Printf("%s: %s %s %s\n", change.After.Path, before, utils.Arrow(), change.After.Value)
+
+	if !c.confirmProd(env, messages.New(messages.OpFeatureFlagSet, "flag", name, "value", change.After.Value).String()) {
+		fmt.Println(messages.Render(messages.OperationCancelled, nil))
+		return nil
+	}
+	if err := c.featureFlags.Apply(change); err != nil {
+		return err
+	}
+	fmt.Printf(utils.OK()+" Set %s to %s on %s\n", name, change.After.Value, env)
+	return nil
+}
@@ -3,6 +3,17 @@ package cli
 import (
 	"strconv"
 	"strings"
+
+	"rolewalkers/internal/utils"
+)
+
+// Environment variables that stand in for common flags and arguments, so
+// wrappers and CI can configure rw without building argument strings.
+// See 'rw help env'.
+const (
+	envVarEnv       = "RW_ENV"        // default environment
+	envVarProfile   = "RW_PROFILE"    // default profile
+	envVarAssumeYes = "RW_ASSUME_YES" // same as --yes
 )
 
 // FlagSet provides simple flag parsing for CLI commands.
@@ -46,6 +57,13 @@ func (fs *FlagSet) String(name string, defaultVal string) string {
 	return defaultVal
 }
 
+// Lookup returns the value of a string flag and whether it was given, so
+// an explicitly empty value (--owner "") can be told apart from no flag.
+func (fs *FlagSet) Lookup(name string) (string, bool) {
+	v, ok := fs.flags[name]
+	return v, ok
+}
+
 // Bool returns true if a boolean flag was set.
 func (fs *FlagSet) Bool(name string) bool {
 	return fs.boolFlags[name]
@@ -69,3 +87,19 @@ func (fs *FlagSet) Arg(i int) string {
 	}
 	return ""
 }
+
+// EnvArg returns the positional argument at index i, falling back to $RW_ENV
+// and then the environment pinned in this terminal. Use it where an
+// environment argument is required.
+func (fs *FlagSet) EnvArg(i int) string {
+	if env := fs.Arg(i); env != "" {
+		return env
+	}
+	return defaultEnv()
+}
+
+// AssumeYes reports whether confirmation prompts should be skipped, via
+// --yes, -y or $RW_ASSUME_YES.
+func (fs *FlagSet) AssumeYes() bool {
+	return fs.Bool("yes") || fs.Bool("y") || utils.EnvBool(envVarAssumeYes)
+}
@@ -5,40 +5,138 @@ import (
 	"rolewalkers/aws"
 )
 
-func (c *CLI) showHelp() error {
+func (c *CLI) showHelp(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "env":
+			return c.showEnvHelp()
+		default:
+			return fmt.Errorf("unknown help topic: %s\nTopics: env", args[0])
+		}
+	}
+
 	help := `rolewalkers (rw) - AWS Profile & SSO Manager
 
 Usage: rw <command> [arguments]
 
 Profile Management:
   list, ls, l             List all AWS profiles
+    --long                  Include account names, descriptions and owners
+  profile annotate [profile]
+                          Show or set a profile's description and ownership
+    --description, --owner, --team, --contact <value>
+                            Set a value ("" clears it)
+    --account               Annotate the profile's AWS account instead
+  profile bulk-edit --filter <field=value,...> --set <field=value>
+                          Preview, then set region or description on every
+                          role matching account_name, account_id, role_name,
+                          profile_name or region (* matches anything)
+    --dry-run               Preview only
+    --yes, -y               Skip confirmation prompt
   switch, use, s [profile]
                           Switch to a profile (updates default + kubectl context)
                           No args: interactive picker. Supports partial names.
+                          If the context can't be applied, both roll back.
     --no-kube               Skip kubectl context switch
+    --force                 Switch even if the profile's credentials can't
+                            be resolved (expired SSO session, no keys)
   login, li [profile]     SSO login for a profile
                           No args: interactive picker (SSO profiles only)
+                          Opens the IdP link from idp_login_urls in
+                          config.yaml instead of the start URL, if set
+                          Over SSH or without a display, prints a URL
+                          and code to open on another device instead
+    --no-browser            Use the device code flow even with a browser
+    --qr                    Show the device login URL as a QR code
   logout, lo [profile]    SSO logout for a profile
                           No args: interactive picker (SSO profiles only)
   status, st              Show login status for all SSO profiles
   current, c              Show current active profile
   context, ctx [--format] Show compact context (profile, account, eks, namespace)
-    --format short          Compact format for shell prompts
+    --format short          Compact format for shell prompts (reads only
+                            local state, no kubectl/aws calls)
+    --fields sso,tunnels,prod
+                            Append SSO minutes left, active tunnel count and
+                            a production flag (1/0) to the short format
     --format json           JSON output
+    --fresh                 Ask kubectl for the context and namespace instead
+                            of the cache rw keeps (refreshed when rw changes
+                            them or the kubeconfig file changes)
+
+Other Clouds:
+  providers               List cloud providers (aws, gcp) and their profiles
+  providers env <provider:profile>
+                          Print shell exports selecting a profile in this
+                          shell only, e.g. eval "$(rw providers env gcp:dev)"
+    --shell <shell>         Override shell detection
+  switch gcp:<config>     Activate a gcloud configuration
+  login gcp:<config>      Run 'gcloud auth login' for a configuration
 
 Kubernetes:
   kube, k <env>           Switch kubectl context to environment
   kube list               List available kubectl contexts
+  kube refresh <env>|--all
+                          Re-run update-kubeconfig with each environment's
+                          profile, e.g. after clusters were recreated
+    --concurrency <n>       Environments refreshed at once (default: 4)
   kube set namespace      Interactively set default namespace
+  kube set context [profile] [context]
+                          Pin the kubectl context 'switch' applies for a profile
+    --clear                 Derive the context from the environment again
+  kube can-i [env]        Show which rw operations your role can perform in
+                          the environment's namespace (kubectl auth can-i)
+    --namespace <ns>        Check another namespace
+    --as <user>             Impersonate a user
+    --sa <[ns:]name>        Impersonate a service account
+    --rules                 List every rule in the namespace
+    --format <format>       Output format: text or json
 
 Port & Tunnel:
-  port, p <svc> <env>     Get local port for a service/env
+  port, p <svc> <env>     Get local port for a service/env. Piped, it
+                          prints exactly one port (the default one); in a
+                          terminal a service with named ports, such as db's
+                          command port, lists each as "<port> <name>"
+    --name <name>           Only this named port (default, command, ...)
+    --first                 Print only the first port, even in a terminal
+    --json                  JSON: {service, environment, ports: [{name, service, port}]}
+    --copy                  Copy to clipboard instead of printing
   port --list             List all port mappings
+  portmap set <svc> <env> [port]
+                          Map a service's local port; without a port, or
+                          when it is taken, the next free one is suggested
+  portmap conflicts       List local ports mapped twice in an environment
+  check <svc> <env>       Connect to a service, through its tunnel if one is
+                          running and directly otherwise, and perform its
+                          handshake (Postgres SSLRequest, Redis PING, Kafka
+                          ApiVersions), timing each step
+  check <host:port>       Check any address (protocol guessed from the port)
+    --protocol <p>          tcp, postgres, redis or kafka
+    --tls, --no-tls         Force TLS on or off (Redis and MSK default to TLS)
+    --timeout <dur>         Timeout for each step (default: 5s)
+    --format json           JSON output
   tunnel, t start <svc> <env>
                           Start a tunnel to a service
+    --write                 Tunnel to the database write node (default: read)
+    --reason <text>         Why a daily quota is being exceeded (audited)
+    --rate-limit <rate>     Cap bandwidth each way, e.g. 10mbit or 2mb (bytes)
+    --keepalive <dur>       Send TCP keepalives at this interval, e.g. 30s
+    --audit-connections     Record which local process (name, PID, user)
+                            opens each connection in the audit log; on by
+                            default for production environments with
+                            audit_tunnel_connections: true in config.yaml
   tunnel stop <svc> <env> Stop a specific tunnel
   tunnel stop --all       Stop all tunnels
   tunnel list             List active tunnels
+    --format json           JSON output
+  tunnel share <svc> <env>
+                          Print a manifest to reproduce a tunnel setup
+    --output <file>         Write the manifest to a file
+  tunnel join <manifest>  Start the tunnel described by a manifest
+  report tunnel-usage     List tunnel-access pods across users with their
+                          owner and age, flagging likely leaked pods
+    --cluster <env>         Environment's cluster (default: current context)
+    --older-than <dur>      Age after which pods are flagged (default: 24h)
+    --format json           JSON output, e.g. for a scheduled job
 
 Database:
   db, d connect <env>     Connect to database via interactive psql
@@ -48,12 +146,24 @@ Database:
     --admin                 Connect as admin user (IAM auth)
     --iam                   Force IAM authentication
   db backup <env>         Backup database to local file
-    --output, -o <file>     Output file path (required)
+    --output, -o <file>     Output file path
+    --s3 <uri>              Stream the dump to s3://bucket/key (uses the env's profile)
     --schema-only           Backup schema only, no data
   db restore <env>        Restore database from local file
-    --input, -i <file>      Input file path (required)
+    --input, -i <file>      Input file path
+    --s3 <uri>              Restore from s3://bucket/key
+    --backup <id>           Restore a cataloged backup (verified against its hash)
+    --force                 With --yes, restore even when pre-restore checks warn
+    --skip-checks           Skip inspecting the target DB (tables, schema version)
     --clean                 Drop objects before recreating
+    --reason <text>         Why a daily quota is being exceeded (audited)
     --yes, -y               Skip confirmation prompt
+  db backups list [env]   List cataloged backups (location, size, hash, duration)
+  db backups prune [env] --keep <n>
+                          Delete all but the newest n backups per environment
+  db backups retry <id>   Run an interrupted backup again from the start
+  db dsn <env>            Print a connection URL (accepts the connect flags)
+    --copy                  Copy to clipboard instead of printing
 
 Redis:
   redis, r connect <env>  Connect to Redis cluster via interactive redis-cli
@@ -61,53 +171,189 @@ Redis:
 Kafka (MSK):
   msk, m ui <env>         Start Kafka UI for MSK cluster
     --port <port>           Local port (default: 8080)
+    --strict-port           Fail if the port is busy instead of using the next free one
   msk connect <env>       Interactive Kafka CLI session (IAM auth)
   msk stop <env>          Stop the Kafka UI pod
 
 Maintenance:
   maintenance, mt <env> --type <type> --enable|--disable
                           Toggle Fastly maintenance mode
+    --dry-run               Show the services and values that would change
   maintenance status <env>
                           Check maintenance mode status
+  maintenance validate <env>
+                          Check api/pwa each match exactly one Fastly service
 
 Scaling:
   scale, sc <env> --preset <preset>
                           Scale all HPAs using a preset
   scale <env> --service <svc> --min <n> --max <n>
-                          Scale a specific service's HPA
+                          Scale a specific service's HPA. Globs (candidate*)
+                          and /regex/ patterns, comma-separated, scale every
+                          matching HPA after a preview
+    --exclude <pattern>     Skip HPAs matching these patterns
+    --dry-run               Show the matching HPAs without scaling
+    --yes                   Skip the confirmation (outside production)
   scale list <env>        List HPAs and current scaling
+    --format json           JSON output
+
+Undo & History:
+  undo [--yes]            Revert the last maintenance or scaling change
+                          (only within the undo window, default 15m)
+  history export          Export the audit log for SIEM ingestion
+    --format <fmt>          json-lines (default), cef or csv
+    --since <age>           Only entries newer than this, e.g. 30d, 12h
+    --output <file>         Write to a file instead of stdout
+  jobs [list]             List backups, restores and switchovers, with their
+                          state (running, succeeded, failed, cancelled, or
+                          interrupted when rw exited mid-job) and progress
+    --active                Only queued and running jobs
+    --limit <n>             Jobs to list (default: 20)
+    --format json           JSON output
 
 Replication (Blue-Green):
   replication, rep status <env>
                           Show Blue-Green deployment status
-  replication switch <id> [--yes]
-                          Switchover a Blue-Green deployment
-  replication create <env> --name <name> --source <cluster>
-                          Create a new Blue-Green deployment
-  replication delete <id> [--delete-target] [--yes]
+  replication switch <id> [--env <env>] [--yes]
+                          Switchover a Blue-Green deployment in the
+                          environment's region
+  replication create <env> --name <name> [--source <cluster>]
+                          Create a new Blue-Green deployment; without
+                          --source, pick from the environment's clusters
+  replication delete <id> [--env <env>] [--delete-target] [--yes]
                           Delete a Blue-Green deployment
 
 gRPC:
   grpc, g <service> <env> Port-forward to a gRPC microservice
+    --strict-port           Fail if the local port is busy instead of using the next free one
   grpc list               List available gRPC services
+  grpc proxy <service> <env>
+                          Forward a service and serve an HTTP/JSON proxy (needs grpcurl)
+    --port <port>           Local HTTP port (default: 8081)
+    --strict-port           Fail if the HTTP port is busy
 
 SSM Parameters:
   ssm get <path>          Get SSM parameter value
     --decrypt               Decrypt SecureString (default: enabled)
-  ssm list <prefix>       List parameters under a path prefix
+    --copy                  Copy to clipboard instead of printing
+  ssm list <prefix>       List parameters under a path prefix, recursively
+    --max-depth <n>         Only include subfolders up to n levels deep
+    --contains <text>       Only names containing text (ignoring case)
+    --values                Fetch values too (secrets masked)
+    --concurrency <n>       Value requests run at once (default: 8)
+    --format <format>       Output format: text or json
+  ssm browse [prefix]     Browse parameters as a tree (reveal, copy, and
+                          jump to the same path in another environment)
+
+Feature Flags:
+  flag list <env>         List feature flags under <ssm prefix>/features
+    --diff-envs <envs>      Show flags that differ from these environments
+  flag get <env> <flag>   Show a feature flag
+  flag set <env> <flag> <value>
+                          Set a feature flag (audited; confirms on production)
+    --type <type>           bool, number or string (required to create a flag)
+
+Environments:
+  env list                List environments and upcoming expirations
+  env clone <source> <name>
+                          Copy an environment with its port and cluster
+                          mappings, prompting for the values that differ
+    --display-name <name>   Display name (default: derived from source)
+    --cluster <name>        EKS cluster name (default: derived from source)
+    --profile, --region, --namespace
+                            Override values copied from the source
+    --yes                   Accept suggested values without prompting
+  env create-ephemeral <name> --ttl <duration>
+                          Clone an environment (--from <env>, or pick one)
+                          that expires after the TTL: its tunnels are
+                          stopped, kube contexts removed and rows deactivated
+  env expire              Clean up expired environments now (rw-tray also
+                          does on every refresh)
+  env account <env> [account-id]
+                          Show or set the AWS account the environment lives
+                          in; scale, maintenance and db restore refuse to run
+                          when the active profile is in another account
+    --clear                 Stop checking the environment's account
+  env production <env> [on|off]
+                          Show or set whether the environment is production;
+                          commands print a red banner while it is active and
+                          'rw set prompt' shows it in red
+  pin [env]               Pin an environment in this terminal (12h); commands
+                          use it when their <env> is left out. No argument
+                          shows the pin
+    --clear                 Unpin the environment
+  direnv [env]            Print an .envrc snippet: AWS_PROFILE, AWS_REGION,
+                          RW_ENV, RW_PORT_<SERVICE> and a kube context hint
+    --install               Install 'use rolewalkers <env>' for .envrc files
 
 Configuration:
   config, cfg status      Show sync status between config file and database
   config sync             Import profiles from ~/.aws/config into database
   config generate         Generate ~/.aws/config from database
+    --dry-run               Show a diff of the changes without writing
+    --yes, -y               Skip confirmation prompt
   config delete           Backup and delete ~/.aws/config (use DB only)
+  config manage [on|off]  Show or set whether rw-tray regenerates
+                          ~/.aws/config when the database changes (off
+                          until turned on)
+  config reconcile        Import or discard edits other tools (aws
+                          configure sso, granted, leapp) made to a managed
+                          ~/.aws/config; other commands warn about them
+    --import                Import the edits into the database
+    --regenerate            Regenerate the file from the database
+    --unmanage              Keep the file and stop managing it
+    --dry-run               Show the diff only
+  settings effective      Every setting's effective value and where it came
+                          from: flag > env > config.yaml > state > default.
+                          Compare two machines that behave differently
+    --format json           Machine-readable output
   set prompt [components] Configure shell prompt (time, folder, aws, k8s, git)
     --reset                 Remove prompt customization
     --shell <shell>         Override shell detection
+    --status                Show which shells have the prompt, and where
+    --restore               Reinstall the prompts recorded in the database
+
+Aliases:
+  alias add <name> "<command>"
+                          Define a shortcut, e.g. rw alias add pdb "db connect prod --write"
+  alias list              List defined aliases
+  alias remove <name>     Remove an alias
 
 Utilities:
   setup                   Auto-discover accounts, roles, and EKS clusters via SSO
+  bootstrap               Build the database from existing ~/.aws/config and
+                          kubeconfig: profiles, contexts, EKS clusters, ports
+    --skip-eks              Don't call eks list-clusters per account
+    --yes                   Skip the confirmation prompt
   keygen, kg [count]      Generate cryptographically secure API keys
+    --format <fmt>          hex (default), base64, uuid or passphrase
+    --bytes <n>             Random bytes for hex/base64 (default: 16)
+    --words <n>             Words in a passphrase (default: 6)
+    --prefix <str>          Prepend a prefix, e.g. zk_live_
+    --output <file>         Write keys to a file (mode 0600)
+    --copy                  Copy to clipboard instead of printing
+  completion <shell>      Print a completion script (bash, zsh, fish, powershell)
+  docs man|markdown       Write man pages or markdown reference docs
+    --output <dir>          Output directory (default: ./man or ./docs)
+  docs json               Print the command tree as JSON, marking the
+                          commands 'rw slack serve' runs
+  doctor                  Check the state directory, database and tool versions,
+                          show repair steps
+    --fix                   Move a corrupt database aside and create a fresh one
+  changelog               Show the release notes since you last read them,
+                          and the commands and flags scheduled for removal
+    --since <version>       Show the releases after this version
+    --all                   Show every release
+  repair                  Restore the state from before an interrupted switch
+    --replay                Apply the interrupted switch again instead
+    --force                 Repair a switch that started under a minute ago
+    --yes                   Skip the confirmation prompt
+  migrate export --output <file>
+                          Write the database and config.yaml to an encrypted
+                          bundle for a new machine (no tokens or credentials)
+  migrate import <file>   Replace this machine's database and config.yaml,
+                          and reinstall the recorded shell prompts
+    --no-prompt             Leave shell profiles alone
   help, -h                Show this help message
   example, ex             Show usage examples
 
@@ -117,6 +363,27 @@ System Tray:
   tray status             Check if the tray app is running
   tray restart            Restart the tray app
 
+ChatOps:
+  slack serve             Serve a Slack slash command (POST /slack) with
+                          read-only status <env>, maintenance status,
+                          replication status and scale list; requests are
+                          verified with $SLACK_SIGNING_SECRET
+    --addr <host:port>      Listen address (default: 127.0.0.1:8090)
+
+Help Topics:
+  help env                Environment variables for headless use (RW_ENV, ...)
+
+Global Options:
+  --no-color              Disable colored output (also off when piped,
+                          or with NO_COLOR set)
+  --plain                 Plain output for screen readers and log files: no
+                          color, emoji or banners, and numbered lists
+                          instead of arrow-key pickers
+  --show-secrets          Print secret values (passwords, tokens) instead of
+                          masking them in output and error messages
+  --state-dir <dir>       Keep state (database, tunnels, config) in <dir>
+                          instead of ~/.rolewalkers, e.g. on shared hosts
+
 Tunnel Services: ` + aws.DefaultServices + `
 gRPC Services:   ` + aws.DefaultGRPCServices + `
 `
@@ -124,77 +391,179 @@ gRPC Services:   ` + aws.DefaultGRPCServices + `
 	return nil
 }
 
+func (c *CLI) showEnvHelp() error {
+	help := `Environment variables
+
+These stand in for common arguments and flags, so wrappers and CI can
+configure rw without building argument strings. Explicit arguments and
+flags always take precedence.
+
+  RW_ENV=<env>            Environment used when a command's <env> argument
+                          is omitted, instead of the interactive picker
+                          e.g. RW_ENV=dev rw db connect. Takes precedence
+                          over an environment pinned with 'rw pin'
+  RW_PROFILE=<profile>    Profile used by switch, login and logout when no
+                          profile is given (partial names are matched)
+  RW_ASSUME_YES=1         Same as --yes: skip confirmation prompts on
+                          commands that accept --yes (db restore, undo,
+                          config generate, replication)
+  RW_STATE_DIR=<dir>      Same as --state-dir. Otherwise ~/.rolewalkers is
+                          used if it exists, then $XDG_STATE_HOME/rolewalkers
+  NO_COLOR=1              Same as --no-color (https://no-color.org)
+  RW_ASCII=1              Print ASCII symbols ([ok], [!], [x], ->) instead
+                          of ✓ ⚠ ✗ →, for terminals that can't render them
+  RW_PLAIN=1              Same as --plain
+  RW_LANG=<lang>          Language for confirmation prompts and audit
+                          descriptions; falls back to English (en)
+  FASTLY_API_TOKEN        Fastly API token for maintenance commands
+  SLACK_SIGNING_SECRET    Slack app signing secret for 'rw slack serve'
+  RW_MIGRATE_PASSPHRASE   Passphrase for 'rw migrate export/import' bundles
+  RW_NO_TOOL_CHECK=1      Skip the daily check of aws CLI, kubectl and
+                          session-manager-plugin versions
+  EMAIL                   Creator email recorded on temporary pod labels
+
+Production confirmations for maintenance and scaling are never skipped.`
+	fmt.Println(help)
+	return nil
+}
+
 func (c *CLI) showVersion() error {
-	fmt.Println("rolewalkers v1.0.0")
+	fmt.Println("rolewalkers v" + Version)
 	return nil
 }
 
-func (c *CLI) example() error {
-	examples := []string{
-		"# Profile Management",
-		"rw list                          # List all available AWS profiles",
-		"rw switch                        # Interactive profile picker",
-		"rw switch dev                    # Switch to profile matching 'dev'",
-		"rw switch prod --no-kube         # Switch to prod without kubectl context",
-		"rw login                         # Interactive SSO login picker",
-		"rw login staging                 # Login to profile matching 'staging'",
-		"rw logout                        # Interactive SSO logout picker",
-		"rw status                        # Show status of all profiles",
-		"rw current                       # Show current active profile",
-		"rw context                       # Show compact context info",
-		"rw context --format short        # Output for shell prompts",
-		"rw context --format json         # JSON output",
-		"",
-		"# Kubernetes",
-		"rw kube                          # Show current kubectl context",
-		"rw kube set-namespace            # Set default namespace",
-		"rw kube pods                     # List pods in current namespace",
-		"",
-		"# Database",
-		"rw db connect                    # Connect to database",
-		"rw db backup                     # Create database backup",
-		"rw db restore backup.sql         # Restore from backup",
-		"",
-		"# Tunnels & Port Forwarding",
-		"rw tunnel start db               # Start database tunnel",
-		"rw tunnel stop db                # Stop database tunnel",
-		"rw port list                     # List available port forwards",
-		"",
-		"# Services",
-		"rw grpc                          # Connect to gRPC service",
-		"rw redis connect                 # Connect to Redis",
-		"rw msk ui                        # Open Kafka UI",
-		"",
-		"# Maintenance & Scaling",
-		"rw maintenance status            # Check maintenance mode",
-		"rw maintenance on                # Enable maintenance mode",
-		"rw scale list                    # List scalable resources",
-		"rw scale deployment api 3        # Scale API deployment to 3 replicas",
-		"",
-		"# SSM Parameters",
-		"rw ssm get /app/config           # Get SSM parameter",
-		"rw ssm list /app/                # List SSM parameters",
-		"",
-		"# Replication",
-		"rw replication status            # Check replication status",
-		"rw replication switch primary    # Switch to primary",
-		"",
-		"# Shell Prompt",
-		"rw set prompt                    # Enable prompt with all components",
-		"rw set prompt time folder aws    # Pick specific components",
-		"rw set prompt --reset            # Remove prompt customization",
-		"rw set prompt --shell bash       # Force a specific shell",
-		"",
-		"# Config Management",
-		"rw config status                 # Show sync status",
-		"rw config sync                   # Import ~/.aws/config into database",
-		"rw config generate               # Generate config from database",
-		"rw config delete                 # Backup and remove config file",
-	}
+// usageExamples are shown by 'rw example' and, per command, in the
+// generated man pages and reference docs
+var usageExamples = []string{
+	"# Profile Management",
+	"rw list                          # List all available AWS profiles",
+	"rw list --long                   # Include descriptions and owners",
+	"rw profile annotate zenith-dev --owner alice --team platform  # Record ownership",
+	"rw profile bulk-edit --filter account_name=Dev --set region=eu-west-1  # Every Dev role",
+	"rw switch                        # Interactive profile picker",
+	"rw switch dev                    # Switch to profile matching 'dev'",
+	"rw switch prod --no-kube         # Switch to prod without kubectl context",
+	"rw login                         # Interactive SSO login picker",
+	"rw login staging                 # Login to profile matching 'staging'",
+	"rw login staging --qr            # Login from an SSH session via your phone",
+	"rw logout                        # Interactive SSO logout picker",
+	"rw status                        # Show status of all profiles",
+	"rw current                       # Show current active profile",
+	"rw context                       # Show compact context info",
+	"rw context --format short        # Output for shell prompts",
+	"rw ctx --format short --fields sso,prod  # ...plus SSO minutes left and prod flag",
+	"rw context --format json         # JSON output",
+	"",
+	"# Kubernetes",
+	"rw kube                          # Show current kubectl context",
+	"rw kube set namespace            # Set default namespace",
+	"rw kube list                     # List kubectl contexts",
+	"rw kube refresh --all            # Re-fetch every environment's cluster",
+	"rw kube can-i prod --sa deployer # What a service account may do in prod",
+	"",
+	"# Database",
+	"rw db connect                    # Connect to database",
+	"rw db backup                     # Create database backup",
+	"rw db restore backup.sql         # Restore from backup",
+	"",
+	"# Tunnels & Port Forwarding",
+	"rw tunnel start db               # Start database tunnel",
+	"rw tunnel stop db                # Stop database tunnel",
+	"rw tunnel share db dev           # Share tunnel setup with a teammate",
+	"rw t start db dev --rate-limit 10mbit  # Cap an export's bandwidth",
+	"rw port --list                   # List all port mappings",
+	"rw portmap set redis dev         # Move redis to the next free port",
+	"rw check db dev                  # Is it me or the service?",
+	"rw report tunnel-usage --cluster dev  # Who has pods in tunnel-access",
+	"",
+	"# Services",
+	"rw grpc                          # Connect to gRPC service",
+	"rw grpc proxy candidate dev      # HTTP/JSON proxy for curl/Postman",
+	"rw redis connect                 # Connect to Redis",
+	"rw msk ui                        # Open Kafka UI",
+	"",
+	"# Maintenance & Scaling",
+	"rw maintenance status dev        # Check maintenance mode",
+	"rw maintenance dev --type all --enable  # Enable maintenance mode",
+	"rw maintenance prod --type all --enable --dry-run  # Preview matched services",
+	"rw maintenance validate prod     # Fail on missing/ambiguous Fastly services",
+	"rw scale list dev                # List HPAs and current scaling",
+	"rw scale list dev --format json  # HPAs as JSON",
+	"rw scale dev --service api --min 3 --max 3  # Pin the API at 3 replicas",
+	"rw scale dev --service 'candidate*' --exclude '*-consumer' --min 3 --max 6",
+	"rw undo                          # Revert the last maintenance/scale change",
+	"rw history export --format cef --since 30d  # Audit log for Splunk/ArcSight",
+	"rw jobs list --active            # Backups/restores running in other terminals",
+	"",
+	"# SSM Parameters",
+	"rw ssm get /app/config           # Get SSM parameter",
+	"rw ssm list /app/                # List SSM parameters",
+	"rw ssm list /app/ --contains endpoint --values  # Search a tree",
+	"rw ssm browse /dev/zenith/       # Browse SSM parameters interactively",
+	"rw ssm get /app/secret --copy    # Copy a secret (cleared after 30s)",
+	"rw flag list prod --diff-envs dev  # Feature flags that differ",
+	"rw flag set dev new-checkout true  # Toggle a feature flag",
+	"",
+	"# Replication",
+	"rw replication status dev        # Check replication status",
+	"rw replication switch bgd-0a1b2c3d4e5f  # Switch over a Blue-Green deployment",
+	"",
+	"# Shell Prompt",
+	"rw set prompt                    # Enable prompt with all components",
+	"rw set prompt time folder aws    # Pick specific components",
+	"rw set prompt --reset            # Remove prompt customization",
+	"rw set prompt --shell bash       # Force a specific shell",
+	"rw set prompt --status           # Which shells have the prompt installed",
+	"",
+	"# Aliases",
+	"rw alias add pdb \"db connect prod --write\"  # Define a shortcut",
+	"rw pdb                           # Runs: rw db connect prod --write",
+	"rw alias list                    # List aliases",
+	"",
+	"# Config Management",
+	"rw config status                 # Show sync status",
+	"rw config sync                   # Import ~/.aws/config into database",
+	"rw config generate               # Generate config from database",
+	"rw config generate --dry-run     # Preview changes as a unified diff",
+	"rw config delete                 # Backup and remove config file",
+	"rw config reconcile --import     # Keep edits 'aws configure sso' made",
+	"rw settings effective            # Each setting's value and its source",
+	"",
+	"# Environments",
+	"rw env clone sit sit2            # Copy sit with new ports, prompting for cluster",
+	"rw env create-ephemeral sit-pr42 --ttl 72h --from sit  # Expires in 3 days",
+	"rw env list                      # Show environments and expirations",
+	"rw env account prod 123456789012 # Guard prod operations against the wrong account",
+	"rw env production perf on        # Banner and red prompt while perf is active",
+	"rw pin dev                       # Then rw db connect, rw scale list, ... use dev",
+	"rw direnv --install              # Then 'use rolewalkers dev' in an .envrc",
+	"rw providers                     # List AWS profiles and gcloud configurations",
+	"rw switch gcp:staging            # Activate the 'staging' gcloud configuration",
+	"",
+	"# ChatOps",
+	"rw slack serve                   # Read-only Slack slash command on :8090",
+	"",
+	"# Shell Completion & Docs",
+	"source <(rw completion bash)     # Tab-complete commands, subcommands and flags",
+	"rw docs man --output ./man       # Write man pages (rw.1, rw-db.1, ...)",
+	"rw docs markdown --output ./docs # Write markdown reference docs",
+	"rw docs json > commands.json     # Command tree for launchers",
+	"",
+	"# Troubleshooting",
+	"rw doctor                        # Check the database and show repair steps",
+	"rw doctor --fix                  # Move a corrupt database aside, start fresh",
+	"rw repair                        # Undo a switch that crashed part-way",
+	"rw changelog                     # What changed since the last upgrade",
+	"",
+	"# New Machine",
+	"rw migrate export -o rw.bundle   # Encrypted bundle of the database and config",
+	"rw migrate import rw.bundle      # On the new machine, then rw config generate",
+}
 
+func (c *CLI) example() error {
 	fmt.Println("Examples:")
 	fmt.Println()
-	for _, example := range examples {
+	for _, example := range usageExamples {
 		fmt.Println(example)
 	}
 	return nil
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// history exports the audit log (prod scaling, maintenance toggles, config
+// generation, ...) for SIEM ingestion
+func (c *CLI) history(args []string) error {
+	if len(args) < 1 || args[0] != "export" {
+		return fmt.Errorf("usage: rw history export [--format <%s>] [--since <age>] [--output <file>]\n\nExample: rw history export --format cef --since 30d", strings.Join(aws.AuditFormats, "|"))
+	}
+	return c.historyExport(args[1:])
+}
+
+func (c *CLI) historyExport(args []string) error {
+	fs := ParseFlags(args)
+	format := fs.String("format", aws.AuditFormatJSONLines)
+	output := fs.String("output", "")
+	if !slices.Contains(aws.AuditFormats, format) {
+		return fmt.Errorf("unknown export format: %s\nUse: %s", format, strings.Join(aws.AuditFormats, ", "))
+	}
+
+	var since time.Time
+	if age := fs.String("since", ""); age != "" {
+		d, err := utils.ParseDuration(age)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid --since %q (use e.g. 30d, 12h)", age)
+		}
+		since = time.Now().Add(-d)
+	}
+
+	entries, err := c.dbRepo.ListAuditEntries(since)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := aws.ExportAudit(w, entries, format, Version, aws.ProductionEnvs(c.dbRepo)); err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Printf(utils.OK()+" Exported %d audit entries to %s\n", len(entries), output)
+	}
+	return nil
+}
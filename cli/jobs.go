@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// jobs lists the long-running operations (backups, restores, switchovers)
+// rw processes have run, from any terminal
+func (c *CLI) jobs(args []string) error {
+	if len(args) == 0 {
+		return c.jobsList(nil)
+	}
+	switch args[0] {
+	case "list", "ls":
+		return c.jobsList(args[1:])
+	default:
+		return fmt.Errorf("unknown jobs subcommand: %s\nUsage: rw jobs list [--active] [--limit <n>] [--format json]", args[0])
+	}
+}
+
+// jobJSON is a job as printed by 'rw jobs list --format json'
+type jobJSON struct {
+	ID          int        `json:"id"`
+	Kind        string     `json:"kind"`
+	Environment string     `json:"environment,omitempty"`
+	Target      string     `json:"target,omitempty"`
+	State       string     `json:"state"`
+	Progress    string     `json:"progress,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	PID         int        `json:"pid,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+}
+
+func (c *CLI) jobsList(args []string) error {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+	limit, err := fs.Int("limit", 20)
+	if err != nil || limit < 1 {
+		return fmt.Errorf("invalid --limit value (must be a positive integer)")
+	}
+
+	// Jobs whose process died are found by whichever rw runs next
+	orphaned, err := aws.InterruptOrphanedJobs(c.dbRepo)
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to check for interrupted jobs: %v\n", err)
+	}
+	for _, j := range orphaned {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Job %d (%s %s) was interrupted: its process %d exited without finishing it\n", j.ID, j.Kind, j.Environment, j.PID)
+	}
+
+	jobs, err := c.dbRepo.ListJobs(fs.Bool("active"), limit)
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	if format == formatJSON {
+		out := make([]jobJSON, len(jobs))
+		for i, j := range jobs {
+			out[i] = jobJSON{
+				ID: j.ID, Kind: j.Kind, Environment: j.Environment, Target: j.Target,
+				State: j.State, Progress: j.Progress, Error: j.Error, PID: j.PID, CreatedAt: j.CreatedAt,
+			}
+			if j.StartedAt.Valid {
+				out[i].StartedAt = &j.StartedAt.Time
+			}
+			if j.EndedAt.Valid {
+				out[i].EndedAt = &j.EndedAt.Time
+			}
+		}
+		return printJSON(out)
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("No jobs recorded. Backups, restores and replication switchovers are listed here.")
+		return nil
+	}
+
+	fmt.Printf("%-5s %-11s %-8s %-12s %-17s %-9s %s\n", "ID", "KIND", "ENV", "STATE", "STARTED", "DURATION", "DETAIL")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, j := range jobs {
+		started, duration := "-", "-"
+		if j.StartedAt.Valid {
+			started = j.StartedAt.Time.Local().Format("2006-01-02 15:04")
+			end := time.Now()
+			if j.EndedAt.Valid {
+				end = j.EndedAt.Time
+			}
+			duration = end.Sub(j.StartedAt.Time).Round(time.Second).String()
+		}
+		detail := j.Progress
+		if j.State == db.JobFailed {
+			detail = j.Error
+		}
+		fmt.Printf("%-5d %-11s %-8s %-12s %-17s %-9s %s\n",
+			j.ID, j.Kind, cmp.Or(j.Environment, "-"), j.State, started, duration, cmp.Or(firstLine(detail), "-"))
+	}
+	return nil
+}
+
+// firstLine returns s up to its first line break
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
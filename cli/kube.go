@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"cmp"
 	"fmt"
+	"rolewalkers/aws"
+	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/db"
 	"rolewalkers/internal/utils"
 	"strings"
+	"time"
 )
 
 func (c *CLI) kube(args []string) error {
@@ -36,35 +41,32 @@ func (c *CLI) kube(args []string) error {
 		return nil
 	}
 
+	if subCmd == "refresh" {
+		return c.kubeRefresh(args[1:])
+	}
+
+	if subCmd == "can-i" {
+		return c.kubeCanI(args[1:])
+	}
+
 	if subCmd == "set" {
 		if len(args) < 2 {
-			return fmt.Errorf("usage: rw kube set namespace")
+			return fmt.Errorf("usage: rw kube set <namespace|context>")
 		}
 		if args[1] == "namespace" || args[1] == "ns" {
 			return c.kubeSetNamespace()
 		}
-		return fmt.Errorf("unknown set option: %s\nUse: namespace", args[1])
+		if args[1] == "context" || args[1] == "ctx" {
+			return c.kubeSetContext(args[2:])
+		}
+		return fmt.Errorf("unknown set option: %s\nUse: namespace, context", args[1])
 	}
 
 	// Otherwise treat as environment name
 	env := subCmd
 	profileName := c.kubeManager.GetProfileNameForEnv(env)
 
-	if err := c.profileSwitcher.SwitchProfile(profileName); err != nil {
-		return fmt.Errorf("failed to switch AWS profile: %w", err)
-	}
-
-	if err := c.kubeManager.SwitchContextForEnvWithProfile(env, c.profileSwitcher); err != nil {
-		return err
-	}
-
-	namespace := c.kubeManager.GetCurrentNamespace()
-	if namespace == "" {
-		namespace = "default"
-	}
-
-	fmt.Println()
-	return c.showKubeContext(namespace)
+	return c.switchProfile(profileName, aws.SwitchOptions{Environment: env, Force: ParseFlags(args).Bool("force")})
 }
 
 func (c *CLI) kubeSetNamespace() error {
@@ -88,12 +90,57 @@ func (c *CLI) kubeSetNamespace() error {
 	}
 
 	fmt.Println()
-	fmt.Println("✓ Namespace set successfully!")
+	fmt.Println(utils.OK() + " Namespace set successfully!")
 	fmt.Println()
 
 	return c.showKubeContext(selectedNS)
 }
 
+// kubeSetContext stores the kubectl context applied when switching to a
+// profile, instead of the one derived from the environment.
+func (c *CLI) kubeSetContext(args []string) error {
+	if err := c.requireDB("rw kube set context"); err != nil {
+		return err
+	}
+
+	fs := ParseFlags(args)
+	profileName := fs.Arg(0)
+	if profileName == "" {
+		profileName = c.configManager.GetActiveProfile()
+	}
+
+	if fs.Bool("clear") {
+		if err := c.dbRepo.SetProfileKubeContext(profileName, ""); err != nil {
+			return err
+		}
+		fmt.Printf(utils.OK()+" Cleared kubectl context for %s (derived from its environment again)\n", profileName)
+		return nil
+	}
+
+	contextName := fs.Arg(1)
+	if contextName == "" {
+		contexts, err := c.kubeManager.GetContexts()
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(contexts))
+		for i, ctx := range contexts {
+			names[i] = ctx.Name
+		}
+		picked, ok := utils.SelectFromList(fmt.Sprintf("Context for %s:", profileName), names)
+		if !ok {
+			return fmt.Errorf("selection cancelled")
+		}
+		contextName = picked
+	}
+
+	if err := c.dbRepo.SetProfileKubeContext(profileName, contextName); err != nil {
+		return err
+	}
+	fmt.Printf(utils.OK()+" %s will switch kubectl to %s\n", profileName, contextName)
+	return nil
+}
+
 func (c *CLI) showKubeContext(namespace string) error {
 	activeProfile := c.configManager.GetActiveProfile()
 	region := c.profileSwitcher.GetDefaultRegion()
@@ -106,17 +153,17 @@ func (c *CLI) showKubeContext(namespace string) error {
 		fmt.Printf("AWS Region:      %s\n", region)
 	}
 
-	profiles, err := c.configManager.GetProfiles()
-	if err == nil {
-		for _, p := range profiles {
-			if p.Name == activeProfile && p.IsSSO {
-				fmt.Printf("Account ID:      %s\n", p.SSOAccountID)
-				accountName := c.extractAccountName(p.Name)
-				if accountName != "" {
-					fmt.Printf("Account Name:    %s\n", accountName)
-				}
-				break
+	for p, err := range c.configManager.Profiles() {
+		if err != nil {
+			break
+		}
+		if p.Name == activeProfile && p.IsSSO {
+			fmt.Printf("Account ID:      %s\n", p.SSOAccountID)
+			accountName := c.extractAccountName(p.Name)
+			if accountName != "" {
+				fmt.Printf("Account Name:    %s\n", accountName)
 			}
+			break
 		}
 	}
 
@@ -131,3 +178,145 @@ func (c *CLI) showKubeContext(namespace string) error {
 
 	return nil
 }
+
+// kubeRefresh re-runs update-kubeconfig for one or every active
+// environment, e.g. after clusters were recreated and every context went
+// stale at once
+func (c *CLI) kubeRefresh(args []string) error {
+	fs := ParseFlags(args)
+	if err := c.requireDB("rw kube refresh"); err != nil {
+		return err
+	}
+
+	var envs []db.Environment
+	switch {
+	case fs.Bool("all"):
+		all, err := c.dbRepo.GetAllEnvironments()
+		if err != nil {
+			return err
+		}
+		envs = all
+	case fs.Arg(0) != "":
+		env, err := c.dbRepo.GetEnvironment(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		envs = []db.Environment{*env}
+	default:
+		return fmt.Errorf("usage: rw kube refresh --all [--concurrency <n>]\n       rw kube refresh <env>")
+	}
+	if len(envs) == 0 {
+		return fmt.Errorf("no active environments; run 'rw setup' to discover them")
+	}
+
+	concurrency, err := fs.Int("concurrency", aws.DefaultKubeRefreshConcurrency)
+	if err != nil || concurrency < 1 {
+		return fmt.Errorf("--concurrency must be a positive number")
+	}
+	fmt.Printf("Refreshing kubeconfig for %d environment(s), %d at a time...\n", len(envs), concurrency)
+	results, err := c.kubeManager.RefreshKubeconfigs(envs, concurrency)
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("  %s %-12s %-24s %v\n", utils.Fail(), r.Env, r.Profile, r.Err)
+			continue
+		}
+		fmt.Printf("  %s %-12s %-24s %s (%s)\n", utils.OK(), r.Env, r.Profile, r.Cluster, r.Duration.Round(time.Millisecond))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d refreshed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d environment(s) could not be refreshed; check 'rw login' for their profiles", failed)
+	}
+	return nil
+}
+
+// kubeCanI summarizes what the current role, or an impersonated user or
+// service account, may do in an environment's namespace, and which rw
+// operations that rules out. Useful when someone's rw commands fail with
+// RBAC errors.
+func (c *CLI) kubeCanI(args []string) error {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+
+	kubeContext, namespace := "", c.kubeManager.GetCurrentNamespace()
+	if env := fs.EnvArg(0); env != "" {
+		if kubeContext, err = c.kubeManager.FindContextForEnv(env); err != nil {
+			return fmt.Errorf("no kubectl context for %s: %w\nRun 'rw kube refresh %s' to add it", env, err, env)
+		}
+		namespace = appconfig.Get().Namespaces.App
+		if c.dbRepo != nil {
+			if e, err := c.dbRepo.GetEnvironment(env); err == nil && e.Namespace != "" {
+				namespace = e.Namespace
+			}
+		}
+	}
+	namespace = cmp.Or(fs.String("namespace", ""), namespace, "default")
+
+	as := fs.String("as", "")
+	if sa := fs.String("sa", ""); sa != "" {
+		if as != "" {
+			return fmt.Errorf("use either --as or --sa")
+		}
+		saNamespace, name, ok := strings.Cut(sa, ":")
+		if !ok {
+			saNamespace, name = namespace, sa
+		}
+		as = fmt.Sprintf("system:serviceaccount:%s:%s", saNamespace, name)
+	}
+
+	report, err := aws.KubeCanI(kubeContext, namespace, as)
+	if err != nil {
+		return err
+	}
+	if format == formatJSON {
+		return printJSON(report)
+	}
+
+	who := "you"
+	if report.As != "" {
+		who = report.As
+	}
+	where := "the current context"
+	if report.Context != "" {
+		where = report.Context
+	}
+	fmt.Printf("What %s can do on %s:\n\n", who, where)
+	for _, check := range report.Checks {
+		if len(check.Missing) == 0 {
+			fmt.Printf("  %s %s (%s)\n", utils.OK(), check.Operation, check.Namespace)
+			continue
+		}
+		missing := make([]string, len(check.Missing))
+		for i, m := range check.Missing {
+			missing[i] = m.String()
+		}
+		fmt.Printf("  %s %s (%s)\n", utils.Fail(), check.Operation, check.Namespace)
+		fmt.Printf("      missing: %s\n", strings.Join(missing, ", "))
+	}
+
+	if !fs.Bool("rules") {
+		fmt.Println()
+		fmt.Printf("Run with --rules for every rule in %s.\n", report.Namespace)
+		return nil
+	}
+	fmt.Println()
+	fmt.Printf("Rules in %s:\n", report.Namespace)
+	fmt.Printf("  %-45s %-25s %s\n", "RESOURCE", "NAMES", "VERBS")
+	for _, rule := range report.Rules {
+		resource := strings.Join(rule.Resources, " ")
+		if resource == "" {
+			resource = strings.Join(rule.NonResourceURLs, " ")
+		}
+		fmt.Printf("  %-45s %-25s %s\n", resource, strings.Join(rule.ResourceNames, " "), strings.Join(rule.Verbs, " "))
+	}
+	return nil
+}
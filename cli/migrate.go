@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/migrate"
+	"rolewalkers/internal/utils"
+)
+
+// migratePassphraseEnv supplies the bundle passphrase without a prompt
+const migratePassphraseEnv = "RW_MIGRATE_PASSPHRASE"
+
+// migrateDBFile is the database's name inside a bundle
+const migrateDBFile = "config.db"
+
+// migrateFiles are the state directory files carried over besides the
+// database, which holds environments, profiles, port mappings, aliases,
+// installed shell prompts and the audit log. Session state (active profile,
+// running tunnels, the tray's pid) belongs to the old machine and is left
+// behind.
+var migrateFiles = []string{"config.yaml"}
+
+// migrateCmd moves rw's state between machines in an encrypted bundle
+func (c *CLI) migrateCmd(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw migrate export --output <file>\n       rw migrate import <file> [--yes] [--no-prompt]\n\nMoves the rolewalkers database and config.yaml to a new machine in a\npassphrase-encrypted bundle. Import reinstalls the shell prompts set up with\n'rw set prompt' unless --no-prompt is given. SSO tokens and AWS credentials\nare not included.\n\nPassphrase: prompted for, or set %s", migratePassphraseEnv)
+	}
+
+	fs := ParseFlags(args[1:])
+	switch args[0] {
+	case "export":
+		return c.migrateExport(fs)
+	case "import":
+		return c.migrateImport(fs)
+	default:
+		return fmt.Errorf("unknown migrate command: %s (use export or import)", args[0])
+	}
+}
+
+func (c *CLI) migrateExport(fs *FlagSet) error {
+	output := fs.String("output", fs.String("o", ""))
+	if output == "" {
+		return fmt.Errorf("usage: rw migrate export --output <file>")
+	}
+	if err := c.requireDB("rw migrate export"); err != nil {
+		return err
+	}
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists; choose another --output", output)
+	}
+
+	passphrase, err := migratePassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rw-migrate-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	snapshot := filepath.Join(tmpDir, migrateDBFile)
+	if err := c.database.Snapshot(snapshot); err != nil {
+		return err
+	}
+	dbData, err := os.ReadFile(snapshot)
+	if err != nil {
+		return err
+	}
+
+	bundle := &migrate.Bundle{
+		CreatedAt: time.Now().UTC(),
+		Version:   Version,
+		Files:     map[string][]byte{migrateDBFile: dbData},
+	}
+	bundle.Hostname, _ = os.Hostname()
+	for _, name := range migrateFiles {
+		content, err := utils.ReadRoleWalkersFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		bundle.Files[name] = content
+	}
+
+	sealed, err := migrate.Seal(bundle, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+
+	fmt.Printf(utils.OK()+" Exported %s to %s (%s)\n", strings.Join(bundleFileNames(bundle), ", "),
+		output, utils.FormatBytes(int64(len(sealed))))
+	fmt.Println("  SSO tokens and AWS credentials are not included.")
+	fmt.Printf("  On the new machine: rw migrate import %s\n", output)
+	return nil
+}
+
+func (c *CLI) migrateImport(fs *FlagSet) error {
+	input := fs.Arg(0)
+	if input == "" {
+		return fmt.Errorf("usage: rw migrate import <file> [--yes] [--no-prompt]")
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	passphrase, err := migratePassphrase(false)
+	if err != nil {
+		return err
+	}
+	bundle, err := migrate.Open(data, passphrase)
+	if err != nil {
+		return err
+	}
+	dbData, ok := bundle.Files[migrateDBFile]
+	if !ok {
+		return fmt.Errorf("the bundle has no database")
+	}
+
+	host := cmp.Or(bundle.Hostname, "another machine")
+	created := bundle.CreatedAt.Local().Format("2006-01-02 15:04")
+	fmt.Printf("Bundle from %s, exported %s by rolewalkers v%s: %s\n", host, created,
+		cmp.Or(bundle.Version, "?"), strings.Join(bundleFileNames(bundle), ", "))
+	prompt := messages.Render(messages.ConfirmMigrateImport, messages.Params{"host": host, "created": created})
+	if !fs.AssumeYes() && !confirm.Yes(prompt+" "+messages.Render(messages.ConfirmTypeYes, nil)+" ") {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	path, err := db.Path()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), migrateDBFile+".import-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(dbData)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the imported database: %w", err)
+	}
+
+	if c.database != nil {
+		c.database.Close()
+	}
+	previous, err := db.Replace(path, tmp.Name())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if previous != "" {
+		fmt.Printf("  Kept the previous database at %s\n", previous)
+	}
+	database, err := db.NewDB()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	fmt.Printf(utils.OK()+" Imported the database to %s\n", path)
+
+	// The database records which shells had the rw prompt; install the same
+	// prompts into this machine's profiles
+	if !fs.Bool("no-prompt") {
+		if _, err := restorePrompts(db.NewConfigRepository(database)); err != nil {
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Prompt not restored: %v\n", err)
+		}
+	}
+
+	stamp := time.Now().Format("20060102-150405")
+	for _, name := range migrateFiles {
+		content, ok := bundle.Files[name]
+		if !ok {
+			continue
+		}
+		if old, err := utils.ReadRoleWalkersFile(name); err == nil && !bytes.Equal(old, content) {
+			backup := name + ".pre-import-" + stamp
+			if err := utils.WriteRoleWalkersFile(backup, old); err != nil {
+				return fmt.Errorf("failed to keep the previous %s: %w", name, err)
+			}
+			fmt.Printf("  Kept the previous %s as %s\n", name, backup)
+		}
+		if err := utils.WriteRoleWalkersFile(name, content); err != nil {
+			return fmt.Errorf("failed to import %s: %w", name, err)
+		}
+		fmt.Printf(utils.OK()+" Imported %s\n", name)
+	}
+
+	fmt.Println("\nNext steps:")
+	fmt.Println("  rw config generate   # write ~/.aws/config from the imported profiles")
+	fmt.Println("  rw login             # SSO tokens are not part of the bundle")
+	return nil
+}
+
+// migratePassphrase reads the bundle passphrase from the environment or a
+// prompt. repeat asks for it twice, for a new bundle.
+func migratePassphrase(repeat bool) (string, error) {
+	if p := os.Getenv(migratePassphraseEnv); p != "" {
+		return p, nil
+	}
+	p, err := utils.ReadPassphrase("Bundle passphrase")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the passphrase (set %s for non-interactive use): %w", migratePassphraseEnv, err)
+	}
+	if repeat {
+		if len(p) < migrate.MinPassphraseLength {
+			return "", fmt.Errorf("passphrase must be at least %d characters", migrate.MinPassphraseLength)
+		}
+		again, err := utils.ReadPassphrase("Repeat passphrase")
+		if err != nil {
+			return "", err
+		}
+		if again != p {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+func bundleFileNames(b *migrate.Bundle) []string {
+	names := make([]string, 0, len(b.Files))
+	for name := range b.Files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -2,21 +2,30 @@ package cli
 
 import (
 	"fmt"
-	appconfig "rolewalkers/internal/config"
+	"os"
+	"rolewalkers/aws"
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/messages"
 	"rolewalkers/internal/utils"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // --- Maintenance ---
 
 func (c *CLI) maintenance(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw maintenance <env> --type <api|pwa|all> --enable|--disable\n       rw maintenance status <env>\n\nSubcommands:\n  <env> --type <type> --enable   Enable maintenance mode\n  <env> --type <type> --disable  Disable maintenance mode\n  status <env>                   Check current maintenance status\n\nTypes: api, pwa, all\nEnvironments: snd, dev, sit, preprod, trg, prod\n\nRequires: FASTLY_API_TOKEN environment variable")
+		return fmt.Errorf("usage: rw maintenance <env> --type <api|pwa|all> --enable|--disable [--dry-run]\n       rw maintenance status <env>\n\nSubcommands:\n  <env> --type <type> --enable   Enable maintenance mode\n  <env> --type <type> --disable  Disable maintenance mode\n  --dry-run                      Show which services and values would change\n  status <env>                   Check current maintenance status\n  validate <env>                 Check which Fastly services api/pwa resolve to\n\nTypes: api, pwa, all\nEnvironments: snd, dev, sit, preprod, trg, prod\n\nRequires: FASTLY_API_TOKEN environment variable")
 	}
 
 	if args[0] == "status" {
 		return c.maintenanceStatus(args[1:])
 	}
+	if args[0] == "validate" {
+		return c.maintenanceValidate(args[1:])
+	}
 
 	return c.maintenanceToggle(args)
 }
@@ -37,26 +46,62 @@ func (c *CLI) maintenanceStatus(args []string) error {
 		return err
 	}
 
-	fmt.Printf("Maintenance Mode Status for %s:\n", env)
+	renderMaintenanceStatus(os.Stdout, env, statuses)
+	return nil
+}
+
+// maintenanceValidate checks that api and pwa each match exactly one Fastly
+// service with a maintenance dictionary, and fails when a mapping is missing
+// or ambiguous
+func (c *CLI) maintenanceValidate(args []string) error {
+	env := ""
+	if len(args) >= 1 {
+		env = args[0]
+	} else {
+		picked, err := c.pickEnvironment()
+		if err != nil {
+			return err
+		}
+		env = picked
+	}
+	mappings, err := c.maintenanceManager.Validate(env)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Fastly service mapping for %s:\n", env)
 	fmt.Println(strings.Repeat("-", 50))
 
-	for _, s := range statuses {
-		status := "✗ Disabled"
-		if s.Enabled {
-			status = "✓ Enabled"
+	problems := 0
+	for _, m := range mappings {
+		label := strings.ToUpper(m.ServiceType)
+		switch {
+		case len(m.Matches) == 0:
+			fmt.Printf("  "+utils.Fail()+" %s: %s\n", label, m.Error)
+		case len(m.Matches) > 1:
+			fmt.Printf("  "+utils.Fail()+" %s: ambiguous, %d services match: %s\n", label, len(m.Matches), strings.Join(m.Matches, ", "))
+			fmt.Printf("      Toggles would use %s. Rename the others so only one starts with %q and contains %q.\n", m.Matches[0], env, m.ServiceType)
+		case m.Error != "":
+			fmt.Printf("  "+utils.Fail()+" %s: %s\n", label, m.Error)
+		default:
+			fmt.Printf("  "+utils.OK()+" %s: %s\n", label, m.Matches[0])
 		}
-		if s.Error != "" {
-			status = fmt.Sprintf("⚠ Error: %s", s.Error)
+		if !m.OK() {
+			problems++
 		}
-		fmt.Printf("  %s (%s): %s\n", strings.ToUpper(s.ServiceType), s.ServiceName, status)
 	}
 
+	if problems > 0 {
+		return fmt.Errorf("%d of %d maintenance mapping(s) for %s need fixing before maintenance mode can be relied on", problems, len(mappings), env)
+	}
+	fmt.Println()
+	fmt.Println(utils.OK() + " Maintenance mode can be toggled for " + env)
 	return nil
 }
 
 func (c *CLI) maintenanceToggle(args []string) error {
 	fs := ParseFlags(args)
-	env := fs.Arg(0)
+	env := fs.EnvArg(0)
 	serviceType := fs.String("type", fs.String("t", ""))
 	enable := fs.Bool("enable")
 	disable := fs.Bool("disable")
@@ -74,23 +119,78 @@ func (c *CLI) maintenanceToggle(args []string) error {
 		return fmt.Errorf("cannot use both --enable and --disable")
 	}
 
-	operation := "Enable Maintenance Mode"
+	if fs.Bool("dry-run") {
+		return c.maintenanceDryRun(env, serviceType, enable)
+	}
+
+	if err := c.verifyAccount(env); err != nil {
+		return err
+	}
+
+	operation := messages.OpMaintenanceEnable
 	if disable {
-		operation = "Disable Maintenance Mode"
+		operation = messages.OpMaintenanceDisable
 	}
-	if !confirmProd(env, operation) {
-		fmt.Println("Operation cancelled.")
+	if !c.confirmProd(env, messages.Render(operation, nil)) {
+		fmt.Println(messages.Render(messages.OperationCancelled, nil))
 		return nil
 	}
 
 	return c.maintenanceManager.Toggle(env, serviceType, enable)
 }
 
+// maintenanceDryRun prints the services, dictionary items and values a
+// toggle would change, without touching anything
+func (c *CLI) maintenanceDryRun(env, serviceType string, enable bool) error {
+	changes, err := c.maintenanceManager.Plan(env, serviceType, enable)
+	if err != nil {
+		return err
+	}
+
+	action := "disable"
+	if enable {
+		action = "enable"
+	}
+	fmt.Printf("Dry run: maintenance %s for %s (%s), nothing was changed\n", action, env, serviceType)
+	fmt.Println(strings.Repeat("-", 50))
+
+	pending := 0
+	for _, ch := range changes {
+		fmt.Printf("  %s: %s (service %s, active version %d)\n", strings.ToUpper(ch.ServiceType), ch.ServiceName, ch.ServiceID, ch.Version)
+		current := ch.Current
+		if current == "" {
+			current = ch.Effective() + " (not set)"
+		}
+		item := ch.Dictionary + "." + ch.Key
+		switch {
+		case ch.Effective() != ch.Desired:
+			pending++
+			fmt.Printf("    %s: %s %s %s\n", item, current, utils.Arrow(), ch.Desired)
+		case ch.Changed():
+			pending++
+			fmt.Printf("    %s: %s (unchanged; the item would be created)\n", item, current)
+		default:
+			fmt.Printf("    %s: %s (unchanged)\n", item, current)
+		}
+		if len(ch.OtherMatches) > 0 {
+			fmt.Printf("    "+utils.Warn()+" Also matches %s; only %s would be changed\n", strings.Join(ch.OtherMatches, ", "), ch.ServiceName)
+		}
+	}
+
+	fmt.Println()
+	if pending == 0 {
+		fmt.Println("No changes: maintenance mode is already " + action + "d.")
+	} else {
+		fmt.Printf("%d item(s) would change. Run without --dry-run to apply.\n", pending)
+	}
+	return nil
+}
+
 // --- Scaling ---
 
 func (c *CLI) scale(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw scale <env> --preset <preset>\n       rw scale <env> --service <svc> --min <n> --max <n>\n       rw scale list <env>\n\nPresets: normal (2/10), performance (10/50), minimal (1/3)\nEnvironments: snd, dev, sit, preprod, trg, prod, qa, stage\n\nExamples:\n  rw scale preprod --preset performance\n  rw scale prod --preset normal\n  rw scale dev --service candidate --min 5 --max 10\n  rw scale list dev")
+		return fmt.Errorf("usage: rw scale <env> --preset <preset>\n       rw scale <env> --service <svc> --min <n> --max <n>\n       rw scale <env> --service <pattern> [--exclude <pattern>] --min <n> --max <n> [--dry-run]\n       rw scale list <env>\n\nPresets: normal (2/10), performance (10/50), minimal (1/3)\nEnvironments: snd, dev, sit, preprod, trg, prod, qa, stage\nPatterns: globs (candidate*) or regular expressions between slashes\n(/^candidate-(worker|consumer)$/), comma-separated\n\nExamples:\n  rw scale preprod --preset performance\n  rw scale prod --preset normal\n  rw scale dev --service candidate --min 5 --max 10\n  rw scale dev --service 'candidate*' --exclude '*-consumer' --min 3 --max 6\n  rw scale list dev")
 	}
 
 	if args[0] == "list" || args[0] == "ls" {
@@ -98,17 +198,22 @@ func (c *CLI) scale(args []string) error {
 	}
 
 	fs := ParseFlags(args)
-	env := fs.Arg(0)
+	env := fs.EnvArg(0)
 	preset := fs.String("preset", fs.String("p", ""))
 	service := fs.String("service", fs.String("s", ""))
 
 	if env == "" {
 		return fmt.Errorf("environment is required")
 	}
+	if preset != "" || service != "" {
+		if err := c.verifyAccount(env); err != nil {
+			return err
+		}
+	}
 
 	if preset != "" {
-		if !confirmProd(env, fmt.Sprintf("Scale using preset '%s'", preset)) {
-			fmt.Println("Operation cancelled.")
+		if !c.confirmProd(env, messages.New(messages.OpScalePreset, "preset", preset).String()) {
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
 			return nil
 		}
 		return c.scalingManager.Scale(env, preset)
@@ -127,8 +232,15 @@ func (c *CLI) scale(args []string) error {
 			return fmt.Errorf("--min and --max are required when using --service")
 		}
 
-		if !confirmProd(env, fmt.Sprintf("Scale service '%s' to min=%d max=%d", service, minReplicas, maxReplicas)) {
-			fmt.Println("Operation cancelled.")
+		exclude := fs.String("exclude", "")
+		if aws.IsServicePattern(service) || exclude != "" {
+			return c.scaleMatching(env, service, exclude, minReplicas, maxReplicas, fs.Bool("dry-run"), fs.AssumeYes())
+		}
+
+		op := messages.New(messages.OpScaleService, "service", service,
+			"min", strconv.Itoa(minReplicas), "max", strconv.Itoa(maxReplicas))
+		if !c.confirmProd(env, op.String()) {
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
 			return nil
 		}
 
@@ -138,11 +250,58 @@ func (c *CLI) scale(args []string) error {
 	return fmt.Errorf("either --preset or --service with --min/--max is required")
 }
 
+// scaleMatching scales every HPA matching the --service patterns and none
+// of the --exclude ones, after showing which HPAs those are
+func (c *CLI) scaleMatching(env, include, exclude string, minReplicas, maxReplicas int, dryRun, assumeYes bool) error {
+	sel, err := aws.ParseServiceSelector(include, exclude)
+	if err != nil {
+		return err
+	}
+	pattern := include
+	if exclude != "" {
+		pattern += " excluding " + exclude
+	}
+
+	hpas, err := c.scalingManager.MatchHPAs(env, sel)
+	if err != nil {
+		return err
+	}
+	if len(hpas) == 0 {
+		return fmt.Errorf("no HPAs in %s match %s", c.scalingManager.Namespace(), pattern)
+	}
+
+	renderHPAs(os.Stdout, c.scalingManager.Namespace(), hpas)
+	fmt.Printf("\n%d HPAs match %s; new scaling: min=%d, max=%d\n", len(hpas), pattern, minReplicas, maxReplicas)
+	if dryRun {
+		return nil
+	}
+
+	op := messages.New(messages.OpScaleServices, "count", strconv.Itoa(len(hpas)), "pattern", pattern,
+		"min", strconv.Itoa(minReplicas), "max", strconv.Itoa(maxReplicas))
+	if aws.IsProductionEnv(c.dbRepo, env) {
+		if !c.confirmProd(env, op.String()) {
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
+			return nil
+		}
+	} else if !assumeYes {
+		if !confirm.Yes(messages.Render(messages.ConfirmTypeYes, nil) + " ") {
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
+			return nil
+		}
+	}
+
+	return c.scalingManager.ScaleHPAs(env, pattern, hpas, minReplicas, maxReplicas)
+}
+
 func (c *CLI) scaleList(args []string) error {
-	env := ""
-	if len(args) >= 1 {
-		env = args[0]
-	} else {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+
+	env := fs.Arg(0)
+	if env == "" {
 		picked, err := c.pickEnvironment()
 		if err != nil {
 			return err
@@ -150,12 +309,15 @@ func (c *CLI) scaleList(args []string) error {
 		env = picked
 	}
 
-	output, err := c.scalingManager.ListHPAs(env)
+	hpas, err := c.scalingManager.ListHPAs(env)
 	if err != nil {
 		return err
 	}
 
-	fmt.Print(output)
+	if format == formatJSON {
+		return printJSON(hpas)
+	}
+	renderHPAs(os.Stdout, c.scalingManager.Namespace(), hpas)
 	return nil
 }
 
@@ -163,7 +325,7 @@ func (c *CLI) scaleList(args []string) error {
 
 func (c *CLI) replication(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw replication <status|switch|create|delete> [options]\n\nSubcommands:\n  status <env>           Show Blue-Green deployment status\n  switch <id> [--yes]    Switchover a deployment\n  create <env> --name <name> --source <cluster>\n                         Create a new Blue-Green deployment\n  delete <id> [--delete-target] [--yes]\n                         Delete a Blue-Green deployment\n\nExamples:\n  rw replication status dev\n  rw replication switch bgd-abc123\n  rw replication create dev --name my-bg --source prod-db-cluster\n  rw replication delete bgd-abc123 --yes")
+		return fmt.Errorf("usage: rw replication <status|switch|create|delete> [options]\n\nSubcommands:\n  status <env>           Show Blue-Green deployment status\n  switch <id> [--yes]    Switchover a deployment\n  create <env> --name <name> [--source <cluster>]\n                         Create a new Blue-Green deployment\n  delete <id> [--delete-target] [--yes]\n                         Delete a Blue-Green deployment\n\nExamples:\n  rw replication status dev\n  rw replication switch bgd-abc123\n  rw replication create dev --name my-bg --source prod-db-cluster\n  rw replication delete bgd-abc123 --yes")
 	}
 
 	subCmd := args[0]
@@ -206,37 +368,38 @@ func (c *CLI) replicationStatus(args []string) error {
 
 func (c *CLI) replicationSwitch(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw replication switch <deployment-id> [--yes]\n\nExample:\n  rw replication switch bgd-abc123def456")
+		return fmt.Errorf("usage: rw replication switch <deployment-id> [--env <env>] [--yes]\n\nExample:\n  rw replication switch bgd-abc123def456 --env prod")
 	}
 
 	fs := ParseFlags(args)
 	deploymentID := fs.Arg(0)
-	skipConfirm := fs.Bool("yes") || fs.Bool("y")
+	env := fs.String("env", "")
+	skipConfirm := fs.AssumeYes()
 
 	if deploymentID == "" {
 		return fmt.Errorf("deployment identifier is required")
 	}
 
 	if !skipConfirm {
-		if !utils.ConfirmReplicationSwitch(deploymentID, "(source)", "(target)") {
+		if !confirm.Confirm(confirm.ReplicationSwitch{Deployment: deploymentID, Source: "(source)", Target: "(target)"}) {
 			fmt.Println("Switchover cancelled.")
 			return nil
 		}
 	}
 
-	return c.replicationManager.Switch("", deploymentID)
+	return c.replicationManager.Switch(env, deploymentID)
 }
 
 func (c *CLI) replicationCreate(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw replication create <env> --name <name> --source <cluster>\n\nExample:\n  rw replication create dev --name my-blue-green --source prod-db-cluster")
+		return fmt.Errorf("usage: rw replication create <env> --name <name> [--source <cluster>]\n\nWithout --source, the environment's DB clusters are discovered (by\nEnvironment tag or identifier) and picked from, newest first.\n\nExamples:\n  rw replication create dev --name my-blue-green\n  rw replication create dev --name my-blue-green --source prod-db-cluster")
 	}
 
 	fs := ParseFlags(args)
-	env := fs.Arg(0)
+	env := fs.EnvArg(0)
 	name := fs.String("name", fs.String("n", ""))
 	source := fs.String("source", fs.String("s", ""))
-	skipConfirm := fs.Bool("yes") || fs.Bool("y")
+	skipConfirm := fs.AssumeYes()
 
 	if env == "" {
 		return fmt.Errorf("environment is required")
@@ -245,11 +408,15 @@ func (c *CLI) replicationCreate(args []string) error {
 		return fmt.Errorf("--name is required")
 	}
 	if source == "" {
-		return fmt.Errorf("--source is required")
+		discovered, err := c.pickSourceCluster(env, skipConfirm)
+		if err != nil {
+			return err
+		}
+		source = discovered
 	}
 
 	if !skipConfirm {
-		if !utils.ConfirmReplicationCreate(name, source) {
+		if !confirm.Confirm(confirm.ReplicationCreate{Name: name, Source: source}) {
 			fmt.Println("Creation cancelled.")
 			return nil
 		}
@@ -258,32 +425,144 @@ func (c *CLI) replicationCreate(args []string) error {
 	return c.replicationManager.Create(env, name, source)
 }
 
+// pickSourceCluster finds the environment's DB clusters for 'replication
+// create' without --source. Several matches show a picker, newest first;
+// with --yes the newest is used.
+func (c *CLI) pickSourceCluster(env string, newest bool) (string, error) {
+	clusters, err := c.replicationManager.DiscoverClusters(env)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(clusters) == 0:
+		return "", fmt.Errorf("no DB clusters found for %s (by Environment tag or identifier); pass --source <cluster>", env)
+	case len(clusters) == 1 || newest:
+		fmt.Printf("Using source cluster %s (created %s)\n", clusters[0].Identifier, utils.FormatTimeRelative(clusters[0].CreateTime, time.Now()))
+		return clusters[0].ARN, nil
+	}
+
+	items := make([]string, len(clusters))
+	for i, cl := range clusters {
+		items[i] = fmt.Sprintf("%s (%s, %s, created %s)", cl.Identifier, cl.Engine, cl.Status, utils.FormatTimeRelative(cl.CreateTime, time.Now()))
+	}
+	selected, ok := utils.SelectFromList("Select the source cluster:", items)
+	if !ok {
+		return "", fmt.Errorf("selection cancelled")
+	}
+	return clusters[slices.Index(items, selected)].ARN, nil
+}
+
 func (c *CLI) replicationDelete(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw replication delete <deployment-id> [--delete-target] [--yes]\n\nExample:\n  rw replication delete bgd-abc123def456 --yes")
+		return fmt.Errorf("usage: rw replication delete <deployment-id> [--env <env>] [--delete-target] [--yes]\n\nExample:\n  rw replication delete bgd-abc123def456 --env dev --yes")
 	}
 
 	fs := ParseFlags(args)
 	deploymentID := fs.Arg(0)
+	env := fs.String("env", "")
 	deleteTarget := fs.Bool("delete-target")
-	skipConfirm := fs.Bool("yes") || fs.Bool("y")
+	skipConfirm := fs.AssumeYes()
 
 	if deploymentID == "" {
 		return fmt.Errorf("deployment identifier is required")
 	}
 
 	if !skipConfirm {
-		if !utils.ConfirmReplicationDelete(deploymentID, deleteTarget) {
+		if !confirm.Confirm(confirm.ReplicationDelete{Deployment: deploymentID, DeleteTarget: deleteTarget}) {
 			fmt.Println("Deletion cancelled.")
 			return nil
 		}
 	}
 
-	return c.replicationManager.Delete(deploymentID, deleteTarget)
+	return c.replicationManager.Delete(env, deploymentID, deleteTarget)
+}
+
+// --- Undo ---
+
+func (c *CLI) undo(args []string) error {
+	fs := ParseFlags(args)
+	skipConfirm := fs.AssumeYes()
+
+	entry, err := c.undoManager.LastChange()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Last change:")
+	fmt.Println(strings.Repeat("-", 50))
+	fmt.Printf("  Change:      %s\n", aws.AuditDescription(entry))
+	fmt.Printf("  Action:      %s\n", entry.Action)
+	fmt.Printf("  Environment: %s\n", entry.Environment)
+	fmt.Printf("  Target:      %s\n", entry.Target)
+	fmt.Printf("  When:        %s (undo window: %s)\n", utils.FormatTimeRelative(entry.CreatedAt, time.Now()), c.undoManager.Window())
+	fmt.Println()
+
+	operation := messages.New(messages.OpUndo, "change", aws.AuditDescription(entry)).String()
+	if aws.IsProductionEnv(c.dbRepo, entry.Environment) {
+		if !c.confirmProd(entry.Environment, operation) {
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
+			return nil
+		}
+	} else if !skipConfirm {
+		if !confirm.Yes(messages.Render(messages.ConfirmUndo, nil) + " ") {
+			fmt.Println(messages.Render(messages.OperationCancelled, nil))
+			return nil
+		}
+	}
+
+	if err := c.undoManager.Undo(entry); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n"+utils.OK()+" Reverted %s on %s\n", entry.Action, entry.Environment)
+	return nil
+}
+
+// confirmProd wraps confirm.Production with the production environments
+// from config.yaml and the database.
+func (c *CLI) confirmProd(env, operation string) bool {
+	return confirm.Production(env, operation, aws.ProductionEnvs(c.dbRepo)...)
+}
+
+// verifyAccount refuses a destructive operation when the active profile is
+// in a different AWS account than the one recorded for env.
+func (c *CLI) verifyAccount(env string) error {
+	return aws.VerifyEnvironmentAccount(c.configManager, c.dbRepo, env)
+}
+
+// recordQuota counts a finished run of operation toward the soft daily
+// quotas ('quotas' in config.yaml), with the reason given for exceeding one
+// (see quotaReason). service selects per-service quotas; target is what the
+// audit entry records.
+func (c *CLI) recordQuota(operation, env, service, target, reason string) {
+	exceeded, err := c.quotaManager.Check(operation, env, service)
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" Could not count the run toward its quota: %v\n", err)
+		return
+	}
+	c.quotaManager.Record(operation, env, target, exceeded, reason)
 }
 
-// confirmProd wraps ConfirmProductionOperation with the configured production env list.
-func confirmProd(env, operation string) bool {
-	cfg := appconfig.Get()
-	return utils.ConfirmProductionOperation(env, operation, cfg.ProductionEnvs...)
+// quotaReason warns when a run of operation would exceed a quota and asks
+// for the reason when the quota requires one, without recording the run.
+// Write tunnels are recorded by the tunnel manager, which enforces the
+// quota for every caller; restores by recordQuota once they succeed.
+func (c *CLI) quotaReason(operation, env, service, reason string, assumeYes bool) (string, error) {
+	exceeded, err := c.quotaManager.Check(operation, env, service)
+	if err != nil || exceeded == nil {
+		return reason, err
+	}
+
+	fmt.Printf("%s Quota exceeded: %s\n", utils.Warn(), exceeded)
+	if exceeded.RequiresReason() && reason == "" {
+		if assumeYes {
+			return "", fmt.Errorf("quota exceeded; re-run with --reason <text> to record why")
+		}
+		reason, _ = confirm.Line("Reason for exceeding the quota (recorded in the audit log): ")
+		if reason == "" {
+			return "", fmt.Errorf("a reason is required to exceed the quota")
+		}
+	}
+	return reason, nil
 }
@@ -63,6 +63,25 @@ func (c *CLI) pickService(grpcOnly bool) (string, error) {
 
 // pickEnvironment shows an interactive environment picker.
 func (c *CLI) pickEnvironment() (string, error) {
+	if env := defaultEnv(); env != "" {
+		return env, nil
+	}
+
+	items := c.environmentNames()
+	if len(items) == 0 {
+		return "", fmt.Errorf("no environments available")
+	}
+
+	selected, ok := utils.SelectFromList("Select an environment:", items)
+	if !ok {
+		return "", fmt.Errorf("selection cancelled")
+	}
+	return selected, nil
+}
+
+// environmentNames returns configured environment names, falling back to
+// the defaults when the database is unavailable or empty.
+func (c *CLI) environmentNames() []string {
 	var items []string
 
 	if c.dbRepo != nil {
@@ -74,20 +93,10 @@ func (c *CLI) pickEnvironment() (string, error) {
 		}
 	}
 
-	// Fallback to defaults if DB unavailable or empty
 	if len(items) == 0 {
 		items = aws.DefaultEnvironments
 	}
-
-	if len(items) == 0 {
-		return "", fmt.Errorf("no environments available")
-	}
-
-	selected, ok := utils.SelectFromList("Select an environment:", items)
-	if !ok {
-		return "", fmt.Errorf("selection cancelled")
-	}
-	return selected, nil
+	return items
 }
 
 // pickActiveTunnel shows a picker of currently active tunnels for stopping.
@@ -100,7 +109,7 @@ func (c *CLI) pickActiveTunnel() (*tunnelChoice, error) {
 	// Build labels for the picker
 	labels := make([]string, len(tunnels))
 	for i, t := range tunnels {
-		labels[i] = fmt.Sprintf("%s-%s  (localhost:%d → %s)", t.Service, t.Environment, t.LocalPort, t.RemoteHost)
+		labels[i] = fmt.Sprintf("%s-%s  (localhost:%d "+utils.Arrow()+" %s)", t.Service, t.Environment, t.LocalPort, t.RemoteHost)
 	}
 
 	selected, ok := utils.SelectFromList("Select tunnel to stop:", labels)
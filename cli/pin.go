@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"rolewalkers/internal/pin"
+	"rolewalkers/internal/utils"
+)
+
+// pinCmd pins an environment in this terminal, so commands can leave out
+// their environment argument
+func (c *CLI) pinCmd(args []string) error {
+	fs := ParseFlags(args)
+
+	if fs.Bool("clear") {
+		p, err := pin.Clear()
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			fmt.Println("No environment is pinned in this terminal.")
+			return nil
+		}
+		fmt.Printf(utils.OK()+" Unpinned %s\n", p.Environment)
+		return nil
+	}
+
+	env := fs.Arg(0)
+	if env == "" {
+		p, err := pin.Get()
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			fmt.Println("No environment is pinned in this terminal. Pin one with: rw pin <env>")
+			return nil
+		}
+		now := time.Now()
+		fmt.Printf("%s (pinned %s, expires %s)\n", p.Environment,
+			utils.FormatRelative(p.PinnedAt, now), utils.FormatRelative(p.PinnedAt.Add(pin.MaxAge), now))
+		return nil
+	}
+
+	if !slices.Contains(c.environmentNames(), env) {
+		return fmt.Errorf("unknown environment: %s", env)
+	}
+	if err := pin.Set(env); err != nil {
+		if errors.Is(err, pin.ErrNoTerminal) {
+			return fmt.Errorf("%w; set %s=%s instead", err, envVarEnv, env)
+		}
+		return err
+	}
+	fmt.Printf(utils.OK()+" Pinned %s in this terminal until %s\n", env, time.Now().Add(pin.MaxAge).Local().Format("15:04"))
+	fmt.Println("  Commands use it when the environment is left out, e.g. rw db connect")
+	fmt.Println("  Unpin with: rw pin --clear")
+	return nil
+}
+
+// pinNotice says once per command which pinned environment is being used
+var pinNotice sync.Once
+
+// defaultEnv is the environment used when a command's environment argument
+// is left out: $RW_ENV, or the environment pinned in this terminal
+func defaultEnv() string {
+	if env := os.Getenv(envVarEnv); env != "" {
+		return env
+	}
+	p, err := pin.Get()
+	if err != nil || p == nil {
+		return ""
+	}
+	pinNotice.Do(func() {
+		fmt.Fprintf(utils.Stderr, "Using pinned environment %s (rw pin --clear to unpin)\n", utils.Bold(p.Environment))
+	})
+	return p.Environment
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
+)
+
+// portmap changes local port mappings. A local port can be mapped to only
+// one service per environment; conflicts from before that was enforced
+// are listed by 'rw portmap conflicts'.
+func (c *CLI) portmap(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw portmap <list|set|conflicts>\n\nSubcommands:\n  list                        List all port mappings\n  set <svc> <env> [port]      Map a service's local port (default: the next free port)\n  conflicts                   List local ports mapped to several services in an environment")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		fmt.Print(aws.NewPortConfigWithRepo(c.dbRepo).ListAll())
+		return nil
+	case "set":
+		return c.portmapSet(args[1:])
+	case "conflicts":
+		return c.portmapConflicts()
+	default:
+		return fmt.Errorf("unknown portmap subcommand: %s\nUse: list, set, conflicts", args[0])
+	}
+}
+
+// portmapSet maps a service's local port in an environment. Without a port,
+// the next free one from the current mapping (or the service's default
+// port) is used.
+func (c *CLI) portmapSet(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: rw portmap set <svc> <env> [port]")
+	}
+	service, env := strings.ToLower(args[0]), strings.ToLower(args[1])
+
+	port := 0
+	if len(args) >= 3 {
+		p, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid port: %s", args[2])
+		}
+		port = p
+	} else {
+		from, mapped := 0, false
+		if pm, err := c.dbRepo.GetPortMapping(service, env); err == nil {
+			from, mapped = pm.LocalPort, true
+		} else if svc, err := c.dbRepo.GetService(service); err == nil {
+			from = svc.DefaultRemotePort
+		} else {
+			return err
+		}
+		next, err := c.dbRepo.NextFreeLocalPort(env, from, service)
+		if err != nil {
+			return err
+		}
+		if mapped && next == from {
+			fmt.Printf(utils.OK()+" %s in %s already has a free local port: %d\n", service, env, from)
+			return nil
+		}
+		port = next
+		fmt.Printf("Using the next free local port in %s: %d\n", env, port)
+	}
+
+	if err := c.dbRepo.SetPortMapping(service, env, port); err != nil {
+		var inUse *db.LocalPortInUseError
+		if errors.As(err, &inUse) && inUse.Suggested > 0 {
+			return fmt.Errorf("%w\n  rw portmap set %s %s %d", err, service, env, inUse.Suggested)
+		}
+		return err
+	}
+	fmt.Printf(utils.OK()+" %s in %s %s localhost:%d\n", service, env, utils.Arrow(), port)
+	if !utils.IsPortAvailable(port) {
+		fmt.Printf("  "+utils.Warn()+" Port %d is in use on this machine right now\n", port)
+	}
+	return nil
+}
+
+// portmapConflicts lists local ports shared by services in an environment
+func (c *CLI) portmapConflicts() error {
+	conflicts, err := c.dbRepo.PortConflicts()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		fmt.Println(utils.OK() + " No local port is mapped twice in an environment")
+		return nil
+	}
+
+	fmt.Printf("%-12s %-8s %s\n", "ENV", "PORT", "SERVICES")
+	fmt.Println(strings.Repeat("-", 50))
+	for _, conflict := range conflicts {
+		fmt.Printf("%-12s %-8d %s\n", conflict.Environment, conflict.Port, strings.Join(conflict.Services, ", "))
+	}
+	fmt.Printf("\n"+utils.Warn()+" %d conflict(s): only one of each group's tunnels can run at a time.\n", len(conflicts))
+	fmt.Println("  Move a service with 'rw portmap set <svc> <env>' (picks the next free port)")
+	return nil
+}
@@ -1,28 +1,51 @@
 package cli
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"rolewalkers/aws"
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/qrcode"
 	"rolewalkers/internal/utils"
 )
 
-func (c *CLI) listProfiles() error {
+func (c *CLI) listProfiles(args []string) error {
+	long := ParseFlags(args).Bool("long")
+
 	profiles, err := c.configManager.GetProfiles()
 	if err != nil {
 		return err
 	}
 
+	// --long adds descriptions and ownership from the database
+	var annotations map[string]db.ProfileAnnotation
+	if long && c.dbRepo != nil {
+		annotations, err = c.dbRepo.GetProfileAnnotations()
+		if err != nil {
+			return fmt.Errorf("failed to load profile metadata: %w", err)
+		}
+	}
+
 	if len(profiles) == 0 {
 		fmt.Println("No AWS profiles found.")
 		return nil
 	}
 
+	logins, err := c.ssoManager.LoginStatuses()
+	if err != nil {
+		return err
+	}
+
 	fmt.Println("AWS Profiles:")
 	fmt.Println(strings.Repeat("-", 80))
 
@@ -34,19 +57,8 @@ func (c *CLI) listProfiles() error {
 
 		ssoStatus := ""
 		if p.IsSSO {
-			if c.ssoManager.IsLoggedIn(p.Name) {
-				ssoStatus = " (SSO: logged in"
-				if expiry, err := c.ssoManager.GetCredentialExpiry(p.Name); err == nil {
-					remaining := time.Until(*expiry)
-					hours := int(remaining.Hours())
-					minutes := int(remaining.Minutes()) % 60
-					if hours > 0 {
-						ssoStatus += fmt.Sprintf(", %dh %dm left", hours, minutes)
-					} else if minutes > 0 {
-						ssoStatus += fmt.Sprintf(", %dm left", minutes)
-					}
-				}
-				ssoStatus += ")"
+			if login := logins[p.Name]; login.LoggedIn {
+				ssoStatus = " (SSO: logged in, expires " + utils.FormatRelative(login.ExpiresAt, time.Now()) + ")"
 			} else {
 				ssoStatus = " (SSO: expired)"
 			}
@@ -60,11 +72,182 @@ func (c *CLI) listProfiles() error {
 		if p.IsSSO {
 			fmt.Printf("    Account: %s | Role: %s\n", p.SSOAccountID, p.SSORoleName)
 		}
+		if a, ok := annotations[p.Name]; ok {
+			printProfileAnnotation(a)
+		}
 	}
 
 	return nil
 }
 
+// printProfileAnnotation prints the description and ownership of a profile
+// for 'rw list --long'
+func printProfileAnnotation(a db.ProfileAnnotation) {
+	own := a.Effective()
+	if a.AccountName != "" {
+		account := a.AccountName
+		if a.Account.Description != "" {
+			account += " - " + a.Account.Description
+		}
+		fmt.Printf("    Account name: %s\n", account)
+	}
+	if own.Description != "" {
+		fmt.Printf("    Description: %s\n", own.Description)
+	}
+	var owners []string
+	if own.Owner != "" {
+		owners = append(owners, "Owner: "+own.Owner)
+	}
+	if own.Team != "" {
+		owners = append(owners, "Team: "+own.Team)
+	}
+	if own.Contact != "" {
+		owners = append(owners, "Contact: "+own.Contact)
+	}
+	if len(owners) > 0 {
+		fmt.Printf("    %s\n", strings.Join(owners, " | "))
+	}
+}
+
+func (c *CLI) profile(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw profile <annotate|bulk-edit>\n\nSubcommands:\n  annotate [profile]   Set description, owner, team and contact for a profile or its account\n  bulk-edit --filter <field=value,...> --set <field=value>\n                       Set region or description on every matching role")
+	}
+
+	switch args[0] {
+	case "annotate":
+		return c.profileAnnotate(args[1:])
+	case "bulk-edit":
+		return c.profileBulkEdit(args[1:])
+	default:
+		return fmt.Errorf("unknown profile subcommand: %s\nUse: annotate, bulk-edit", args[0])
+	}
+}
+
+// profileAnnotate sets ownership metadata on a profile's role, or with
+// --account on its AWS account. Without metadata flags it shows the
+// current values.
+func (c *CLI) profileAnnotate(args []string) error {
+	if err := c.requireDB("rw profile annotate"); err != nil {
+		return err
+	}
+
+	fs := ParseFlags(args)
+	profileName := fs.Arg(0)
+	var err error
+	if profileName == "" {
+		profileName, err = c.pickProfile(false)
+	} else {
+		profileName, err = c.resolveProfileName(profileName)
+	}
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[string]string)
+	for _, column := range []string{"description", "owner", "team", "contact"} {
+		if v, ok := fs.Lookup(column); ok {
+			updates[column] = strings.TrimSpace(v)
+		}
+	}
+
+	annotations, err := c.dbRepo.GetProfileAnnotations()
+	if err != nil {
+		return err
+	}
+	a, ok := annotations[profileName]
+	if !ok {
+		return fmt.Errorf("profile %s is not in the database\nRun 'rw config sync' to import it", profileName)
+	}
+
+	if len(updates) == 0 {
+		fmt.Printf("%s\n", profileName)
+		printProfileAnnotation(a)
+		if a.Effective().IsZero() {
+			fmt.Println("    (no description or ownership set)")
+		}
+		fmt.Println()
+		fmt.Println("Set values with: rw profile annotate <profile> --owner <name> --team <team> --contact <email> --description <text>")
+		fmt.Println("Add --account to annotate the AWS account instead; pass \"\" to clear a value")
+		return nil
+	}
+
+	target := "role " + profileName
+	if fs.Bool("account") {
+		err = c.dbRepo.AnnotateAccount(a.AccountID, updates)
+		target = fmt.Sprintf("account %s (%s)", a.AccountName, a.AccountID)
+	} else {
+		err = c.dbRepo.AnnotateRole(profileName, updates)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(utils.OK()+" Updated %s\n", target)
+	return nil
+}
+
+// profileBulkEdit sets a role field on every role matching a filter. The
+// changes are previewed, then applied in one transaction and audited.
+func (c *CLI) profileBulkEdit(args []string) error {
+	fs := ParseFlags(args)
+	filterArg, assignment := fs.String("filter", ""), fs.String("set", "")
+	field, value, ok := strings.Cut(assignment, "=")
+	if filterArg == "" || !ok {
+		return fmt.Errorf("usage: rw profile bulk-edit --filter <field=value,...> --set <field=value> [--dry-run] [--yes]\n\n" +
+			"Filter fields: account_name, account_id, role_name, profile_name, region (* matches anything)\n" +
+			"Settable fields: region, description")
+	}
+	field, value = strings.TrimSpace(field), strings.TrimSpace(value)
+
+	filter, err := db.ParseRoleFilter(filterArg)
+	if err != nil {
+		return err
+	}
+	edits, err := c.dbRepo.PlanRoleEdits(filter, field, value)
+	if err != nil {
+		return err
+	}
+	if len(edits) == 0 {
+		fmt.Printf(utils.OK()+" No roles matching %s need %s changed\n", filterArg, field)
+		return nil
+	}
+
+	fmt.Printf("%-30s %-20s %s\n", "PROFILE", "ACCOUNT", strings.ToUpper(field))
+	fmt.Println(strings.Repeat("-", 80))
+	for _, e := range edits {
+		fmt.Printf("%-30s %-20s %s %s %s\n", e.ProfileName, e.AccountName, cmp.Or(e.Old, "(none)"), utils.Arrow(), cmp.Or(e.New, "(none)"))
+	}
+	fmt.Println()
+
+	if fs.Bool("dry-run") {
+		fmt.Println("Dry run: no changes written. Re-run without --dry-run to apply.")
+		return nil
+	}
+	count := strconv.Itoa(len(edits))
+	if !fs.AssumeYes() && !confirm.Yes(messages.Render(messages.ConfirmRoleBulkEdit, messages.Params{"count": count})+" ") {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if err := c.dbRepo.ApplyRoleEdits(edits); err != nil {
+		return err
+	}
+
+	prev, _ := json.Marshal(edits)
+	params, _ := messages.EncodeParams(messages.Params{"field": field, "value": value, "count": count, "filter": filterArg})
+	if _, err := c.dbRepo.RecordAuditMessage(aws.AuditActionRoleEdit, "", filterArg, string(prev), "",
+		string(messages.AuditRoleBulkEdit), params); err != nil {
+		fmt.Fprintf(utils.Stdout, "  "+utils.Warn()+" Failed to record audit entry: %v\n", err)
+	}
+
+	fmt.Printf(utils.OK()+" Updated %s on %d roles\n", field, len(edits))
+	if !aws.AWSConfigManaged() {
+		fmt.Println("  Run 'rw config generate' to write the changes to ~/.aws/config")
+	}
+	return nil
+}
+
 // resolveProfileName finds a profile by exact name or partial match.
 // If multiple profiles match the partial input, it returns an error listing them.
 func (c *CLI) resolveProfileName(input string) (string, error) {
@@ -105,6 +288,10 @@ func (c *CLI) resolveProfileName(input string) (string, error) {
 
 // pickProfile shows an interactive profile picker and returns the selected profile name.
 func (c *CLI) pickProfile(ssoOnly bool) (string, error) {
+	if profile := os.Getenv(envVarProfile); profile != "" {
+		return c.resolveProfileName(profile)
+	}
+
 	profiles, err := c.configManager.GetProfiles()
 	if err != nil {
 		return "", err
@@ -156,7 +343,7 @@ func (c *CLI) pickProfile(ssoOnly bool) (string, error) {
 func (c *CLI) postSwitch(profileName string, skipKube bool) {
 	if !skipKube {
 		if err := c.kubeManager.SwitchContextForEnv(profileName); err != nil {
-			fmt.Printf("⚠ Failed to switch kubectl context: %v\n", err)
+			fmt.Fprintf(utils.Stdout, utils.Warn()+" Failed to switch kubectl context: %v\n", err)
 		}
 	}
 
@@ -169,7 +356,7 @@ func (c *CLI) postSwitch(profileName string, skipKube bool) {
 	c.showKubeContext(namespace)
 
 	if envProfile := os.Getenv("AWS_PROFILE"); envProfile != "" && envProfile != profileName {
-		fmt.Println("\n⚠ AWS_PROFILE environment variable is set and overrides the config.")
+		fmt.Println("\n" + utils.Warn() + " AWS_PROFILE environment variable is set and overrides the config.")
 		fmt.Println("  Clear it for this terminal:")
 		if runtime.GOOS == "windows" {
 			fmt.Println("    Remove-Item Env:AWS_PROFILE")
@@ -180,42 +367,78 @@ func (c *CLI) postSwitch(profileName string, skipKube bool) {
 	}
 }
 
-func (c *CLI) switchProfile(profileName string, skipKube bool) error {
-	if err := c.profileSwitcher.SwitchProfile(profileName); err != nil {
+// switchProfile switches the profile and kubectl context together; if the
+// context can't be applied, both are rolled back to what was active before.
+func (c *CLI) switchProfile(profileName string, opts aws.SwitchOptions) error {
+	outcome, err := aws.SwitchProfileAndContext(c.profileSwitcher, c.kubeManager, profileName, opts)
+	if err != nil {
+		if outcome != nil && outcome.RolledBack {
+			fmt.Println(utils.Fail() + " Switch failed, rolled back to:")
+			fmt.Printf("  AWS Profile:  %s\n", outcome.Profile)
+			fmt.Printf("  Kube Context: %s\n", outcome.Context)
+			if outcome.Incomplete {
+				fmt.Println("  Run 'rw repair' to restore the previous state.")
+			}
+		}
 		return err
 	}
 
-	fmt.Printf("✓ Switched to: %s\n", profileName)
-	c.postSwitch(profileName, skipKube)
+	if outcome.Unchanged {
+		fmt.Printf(utils.OK()+" Already on: %s\n", profileName)
+	} else {
+		fmt.Printf(utils.OK()+" Switched to: %s\n", profileName)
+	}
+	c.postSwitch(profileName, true)
 	return nil
 }
 
-func (c *CLI) login(profileName string) error {
+func (c *CLI) login(profileName string, opts aws.LoginOptions) error {
 	fmt.Printf("Initiating SSO login for profile: %s\n", profileName)
-	fmt.Println("A browser window will open for authentication...")
+	if opts.NoBrowser {
+		fmt.Println("Using the device code flow (no browser)...")
+	} else {
+		fmt.Println("A browser window will open for authentication...")
+	}
 
-	if err := c.ssoManager.Login(profileName); err != nil {
+	if err := c.ssoManager.LoginWithOptions(profileName, opts); err != nil {
 		return fmt.Errorf("login failed: %w", err)
 	}
 
-	fmt.Printf("✓ Successfully logged in to: %s\n", profileName)
+	fmt.Printf(utils.OK()+" Successfully logged in to: %s\n", profileName)
 
 	if err := c.profileSwitcher.SwitchProfile(profileName); err != nil {
-		fmt.Printf("⚠ Logged in but could not set default profile: %v\n", err)
+		fmt.Fprintf(utils.Stdout, utils.Warn()+" Logged in but could not set default profile: %v\n", err)
 		fmt.Printf("  Run 'rw switch %s' manually, or use --profile %s\n", profileName, profileName)
 		return nil
 	}
+	aws.RecordSession(c.dbRepo, profileName)
 
 	c.postSwitch(profileName, false)
 	return nil
 }
 
+// showDeviceCodeQR prints the device login URL and code with a QR code of
+// the URL, for opening it on a phone. Plain output has no QR code.
+func showDeviceCodeQR(auth aws.DeviceAuthorization) {
+	if utils.PlainEnabled() {
+		fmt.Printf("Open the login page on any device:\n  %s\n  Code: %s\n", auth.URL(), auth.UserCode)
+		return
+	}
+	fmt.Printf("Scan to open the login page, or open it on any device:\n  %s\n", auth.URL())
+	if code, err := qrcode.Encode(auth.URL()); err == nil {
+		fmt.Print("\n" + code.Terminal(utils.ColorEnabled()) + "\n")
+	} else {
+		fmt.Fprintf(utils.Stdout, utils.Warn()+" Could not render a QR code: %v\n", err)
+	}
+	fmt.Printf("  Code: %s\n", auth.UserCode)
+}
+
 func (c *CLI) logout(profileName string) error {
 	if err := c.ssoManager.Logout(profileName); err != nil {
 		return fmt.Errorf("logout failed: %w", err)
 	}
 
-	fmt.Printf("✓ Logged out from: %s\n", profileName)
+	fmt.Printf(utils.OK()+" Logged out from: %s\n", profileName)
 	return nil
 }
 
@@ -233,13 +456,16 @@ func (c *CLI) status() error {
 	fmt.Println("SSO Profile Status:")
 	fmt.Println(strings.Repeat("-", 60))
 
+	logins, err := c.ssoManager.LoginStatuses()
+	if err != nil {
+		return err
+	}
+
 	for _, p := range profiles {
-		status := "✗ Not logged in"
-		if c.ssoManager.IsLoggedIn(p.Name) {
-			status = "✓ Logged in"
-			if expiry, err := c.ssoManager.GetCredentialExpiry(p.Name); err == nil {
-				status += fmt.Sprintf(" (expires: %s)", expiry.Format("15:04:05"))
-			}
+		status := utils.Fail() + " Not logged in"
+		if login := logins[p.Name]; login.LoggedIn {
+			status = utils.OK() + " Logged in"
+			status += fmt.Sprintf(" (expires %s)", utils.FormatTimeRelative(login.ExpiresAt, time.Now()))
 		}
 
 		active := ""
@@ -267,10 +493,10 @@ func (c *CLI) current() error {
 	region := c.profileSwitcher.GetDefaultRegion()
 
 	if envProfile := os.Getenv("AWS_PROFILE"); envProfile != "" && envProfile != active {
-		fmt.Printf("\n⚠ AWS_PROFILE env override: %s\n", envProfile)
+		fmt.Printf("\n"+utils.Warn()+" AWS_PROFILE env override: %s\n", envProfile)
 	}
 	if envRegion := os.Getenv("AWS_DEFAULT_REGION"); envRegion != "" && envRegion != region {
-		fmt.Printf("⚠ AWS_DEFAULT_REGION env override: %s\n", envRegion)
+		fmt.Printf(utils.Warn()+" AWS_DEFAULT_REGION env override: %s\n", envRegion)
 	}
 
 	return nil
@@ -279,6 +505,10 @@ func (c *CLI) current() error {
 func (c *CLI) context(args []string) error {
 	fs := ParseFlags(args)
 	format := fs.String("format", "default")
+	fresh := fs.Bool("fresh")
+	if format == "short" {
+		return c.contextShort(fs.String("fields", ""), fresh)
+	}
 
 	activeProfile := c.configManager.GetActiveProfile()
 	region := c.profileSwitcher.GetDefaultRegion()
@@ -296,27 +526,22 @@ func (c *CLI) context(args []string) error {
 		}
 	}
 
-	kubeContext := ""
-	namespace := ""
-	if ctx, err := c.kubeManager.GetCurrentContext(); err == nil {
-		kubeContext = ctx
-		if strings.Contains(kubeContext, "/") {
-			parts := strings.Split(kubeContext, "/")
-			kubeContext = parts[len(parts)-1]
-		}
-	}
-
-	ns := c.kubeManager.GetCurrentNamespace()
-	if ns != "" {
-		namespace = ns
+	// kubectl is only asked with --fresh; otherwise the cache kept by rw
+	// (and invalidated by any kubeconfig change) answers
+	var kubeContext, namespace string
+	if fresh {
+		kubeContext, _ = c.kubeManager.GetCurrentContext()
+		namespace = cmp.Or(c.kubeManager.GetCurrentNamespace(), "default")
+		aws.RefreshKubeContextCache()
 	} else {
-		namespace = "default"
+		kubeContext, namespace = aws.CachedKubeContext()
+	}
+	if strings.Contains(kubeContext, "/") {
+		parts := strings.Split(kubeContext, "/")
+		kubeContext = parts[len(parts)-1]
 	}
 
 	switch format {
-	case "short":
-		fmt.Printf("%s|%s|%s|%s\n", activeProfile, accountName, kubeContext, namespace)
-
 	case "json":
 		jsonOutput := map[string]string{
 			"profile":      activeProfile,
@@ -350,3 +575,76 @@ func (c *CLI) context(args []string) error {
 
 	return nil
 }
+
+// contextFields are the optional values 'rw context --format short --fields'
+// appends, for prompt scripts that render warnings
+var contextFields = []string{"sso", "tunnels", "prod"}
+
+// contextShort prints profile|account|context|namespace followed by any
+// requested fields. It is run on every prompt, so it reads only local state
+// (config files, the SSO token cache, tunnel state, the database, the
+// cached kubectl context) and never calls kubectl or AWS. fresh re-reads the
+// kubeconfig files instead of trusting the cache.
+func (c *CLI) contextShort(fieldList string, fresh bool) error {
+	var fields []string
+	if fieldList != "" {
+		for _, f := range strings.Split(fieldList, ",") {
+			f = strings.TrimSpace(f)
+			if !slices.Contains(contextFields, f) {
+				return fmt.Errorf("unknown field: %s (valid: %s)", f, strings.Join(contextFields, ", "))
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	activeProfile := c.configManager.GetActiveProfile()
+	accountName := ""
+	var active *aws.Profile
+	if profiles, err := c.configManager.GetProfiles(); err == nil {
+		if p, err := aws.FindProfileByName(profiles, activeProfile); err == nil {
+			active = p
+			if p.IsSSO {
+				accountName = c.extractAccountName(p.Name)
+			}
+		}
+	}
+
+	var kubeContext, namespace string
+	if fresh {
+		kubeContext, namespace = aws.RefreshKubeContextCache()
+	} else {
+		kubeContext, namespace = aws.CachedKubeContext()
+	}
+	shortContext := kubeContext
+	if i := strings.LastIndex(shortContext, "/"); i >= 0 {
+		shortContext = shortContext[i+1:]
+	}
+	values := []string{activeProfile, accountName, shortContext, namespace}
+
+	for _, f := range fields {
+		switch f {
+		case "sso":
+			// Minutes until the SSO token expires; 0 once expired or logged
+			// out, empty for profiles that don't use SSO
+			value := ""
+			if active != nil && active.IsSSO {
+				value = "0"
+				if expiry, err := c.ssoManager.GetCredentialExpiry(active.Name); err == nil {
+					value = strconv.Itoa(max(0, int(time.Until(*expiry).Minutes())))
+				}
+			}
+			values = append(values, value)
+		case "tunnels":
+			values = append(values, strconv.Itoa(len(c.tunnelManager.ListTunnels())))
+		case "prod":
+			value := "0"
+			if c.activeProductionEnv() != "" {
+				value = "1"
+			}
+			values = append(values, value)
+		}
+	}
+
+	fmt.Println(strings.Join(values, "|"))
+	return nil
+}
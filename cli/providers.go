@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+	"rolewalkers/provider"
+)
+
+func (c *CLI) providersCmd(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "env":
+			return c.providerEnv(args[1:])
+		case "list", "ls":
+		default:
+			return fmt.Errorf("unknown providers subcommand: %s\nUse: list, env", args[0])
+		}
+	}
+
+	for i, p := range c.providers.All() {
+		if i > 0 {
+			fmt.Println()
+		}
+		if !p.Available() {
+			fmt.Printf("%s (%s): not configured\n", p.DisplayName(), p.Name())
+			continue
+		}
+
+		profiles, err := p.Profiles()
+		if err != nil {
+			fmt.Fprintf(utils.Stdout, "%s (%s): %s %v\n", p.DisplayName(), p.Name(), utils.Warn(), err)
+			continue
+		}
+		fmt.Printf("%s (%s): %d profile(s), active: %s\n", p.DisplayName(), p.Name(), len(profiles), p.ActiveProfile())
+		fmt.Println(strings.Repeat("-", 70))
+		for _, prof := range profiles {
+			marker := "  "
+			if prof.Active {
+				marker = "* "
+			}
+			fmt.Printf("%s%-30s %-22s %s\n", marker, prof.QualifiedName(), prof.Account, prof.Identity)
+		}
+	}
+	return nil
+}
+
+// providerEnv prints shell exports that select a profile in the current
+// shell only, e.g. eval "$(rw providers env gcp:staging)"
+func (c *CLI) providerEnv(args []string) error {
+	fs := ParseFlags(args)
+	name := fs.Arg(0)
+	if name == "" {
+		return fmt.Errorf("usage: rw providers env <provider:profile> [--shell <bash|zsh|powershell|cmd>]\n\nExample: eval \"$(rw providers env gcp:staging)\"")
+	}
+
+	p, profile, err := c.providers.Resolve(name)
+	if err != nil {
+		return err
+	}
+	if p.Name() == aws.ProviderName {
+		if profile, err = c.resolveProfileName(profile); err != nil {
+			return err
+		}
+	}
+
+	env, err := p.ExportEnvironment(profile)
+	if err != nil {
+		return err
+	}
+	shell := fs.String("shell", aws.NewPromptManager().DetectShell())
+	fmt.Print(aws.FormatShellExports(shell, env))
+	return nil
+}
+
+// otherProvider returns the provider for a qualified profile name that
+// belongs to a provider other than AWS (e.g. gcp:staging). AWS profiles,
+// qualified or not, return ok=false with the "aws:" prefix removed.
+func (c *CLI) otherProvider(name string) (p provider.Provider, profile string, ok bool) {
+	p, profile = c.providers.Split(name)
+	if p == nil || p.Name() == aws.ProviderName {
+		return nil, profile, false
+	}
+	return p, profile, true
+}
+
+func (c *CLI) switchProviderProfile(p provider.Provider, profile string) error {
+	if !p.Available() {
+		return fmt.Errorf("%s is not configured on this machine", p.DisplayName())
+	}
+	if err := p.Switch(profile); err != nil {
+		return err
+	}
+	fmt.Printf(utils.OK()+" Switched %s to: %s\n", p.DisplayName(), profile)
+	return nil
+}
+
+func (c *CLI) loginProviderProfile(p provider.Provider, profile string) error {
+	if !p.Available() {
+		return fmt.Errorf("%s is not configured on this machine", p.DisplayName())
+	}
+	fmt.Printf("Initiating %s login for: %s\n", p.DisplayName(), profile)
+	if err := p.Login(profile); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	fmt.Printf(utils.OK()+" Successfully logged in to: %s%s%s\n", p.Name(), provider.Separator, profile)
+	return nil
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// Output formats for list commands (--format)
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// outputFormat returns the --format flag, rejecting unknown values
+func outputFormat(fs *FlagSet) (string, error) {
+	switch format := fs.String("format", formatText); format {
+	case formatText, formatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format: %s\nUse: text, json", format)
+	}
+}
+
+// machineReadableCommands are commands whose stdout is read by programs:
+// the shell prompt's 'rw context', the eval'd 'rw direnv', 'rw port' in
+// scripts, and exports
+var machineReadableCommands = []string{"context", "direnv", "port", "history", "docs", "completion", clipboardClearCommand}
+
+// machineReadable reports whether an invocation's output is read by a
+// program rather than a person, so the checks that run before every
+// command must stay silent and never prompt
+func machineReadable(command string, args []string) bool {
+	if info, ok := lookupCommand(command); ok && slices.Contains(machineReadableCommands, info.Name) {
+		return true
+	}
+	for i, arg := range args {
+		switch {
+		case arg == "--json":
+			return true
+		case arg == "--format" && i+1 < len(args) && args[i+1] != formatText:
+			return true
+		case strings.HasPrefix(arg, "--format=") && arg != "--format="+formatText:
+			return true
+		}
+	}
+	return false
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
+
+func renderHPAs(w io.Writer, namespace string, hpas []aws.HPAScaling) {
+	if len(hpas) == 0 {
+		fmt.Fprintf(w, "No HPAs found in namespace %s\n", namespace)
+		return
+	}
+
+	fmt.Fprintf(w, "HPAs in %s namespace:\n", namespace)
+	fmt.Fprintln(w, strings.Repeat("-", 60))
+	fmt.Fprintf(w, "%-40s %s\n", "NAME", "MIN/MAX")
+	fmt.Fprintln(w, strings.Repeat("-", 60))
+	for _, hpa := range hpas {
+		fmt.Fprintf(w, "%-40s %d/%d\n", hpa.Name, hpa.MinReplicas, hpa.MaxReplicas)
+	}
+}
+
+func renderMaintenanceStatus(w io.Writer, env string, statuses []aws.MaintenanceStatus) {
+	fmt.Fprintf(w, "Maintenance Mode Status for %s:\n", env)
+	fmt.Fprintln(w, strings.Repeat("-", 50))
+
+	for _, s := range statuses {
+		status := utils.Fail() + " Disabled"
+		if s.Enabled {
+			status = utils.OK() + " Enabled"
+		}
+		if s.Error != "" {
+			status = fmt.Sprintf(utils.Warn()+" Error: %s", s.Error)
+		}
+		fmt.Fprintf(w, "  %s (%s): %s\n", strings.ToUpper(s.ServiceType), s.ServiceName, status)
+	}
+}
+
+func renderTunnels(tunnels []aws.TunnelStatus) {
+	if len(tunnels) == 0 {
+		fmt.Println("No active tunnels.\n\nStart a tunnel with: rw tunnel start <service> <env>")
+		return
+	}
+
+	fmt.Println("Active Tunnels:")
+	fmt.Println(strings.Repeat("-", 70))
+	for _, t := range tunnels {
+		fmt.Printf("\n%s:\n", t.ID)
+		fmt.Printf("  Pod:     %s (%s)\n", t.PodName, t.PodStatus)
+		fmt.Printf("  Local:   localhost:%d\n", t.LocalPort)
+		fmt.Printf("  Remote:  %s:%d\n", t.RemoteHost, t.RemotePort)
+		fmt.Printf("  Started: %s\n", utils.FormatTimeRelative(t.StartedAt, time.Now()))
+	}
+}
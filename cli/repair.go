@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/utils"
+)
+
+// journalCheckSkipped are commands that don't warn about an interrupted
+// switch: 'rw repair' handles it, and the rest only print text
+var journalCheckSkipped = []string{"help", "version", "example", "completion", "docs", "repair"}
+
+// checkSwitchJournal warns when a profile switch was interrupted part-way,
+// leaving ~/.aws/config, the active profile and the kubectl context
+// disagreeing
+func checkSwitchJournal(command string) {
+	info, ok := lookupCommand(command)
+	if !ok || slices.Contains(journalCheckSkipped, info.Name) {
+		return
+	}
+	journal, err := aws.LoadSwitchJournal()
+	if err != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" %v\n", err)
+		return
+	}
+	if journal == nil || !journal.Stale(time.Now()) {
+		return
+	}
+	fmt.Fprintf(utils.Stderr, utils.Warn()+" The switch from %s to %s at %s was interrupted.\n",
+		journal.FromProfile, journal.ToProfile, journal.StartedAt.Local().Format("2006-01-02 15:04"))
+	fmt.Fprintln(utils.Stderr, "  Run 'rw repair' to restore the previous state, or 'rw repair --replay' to finish it.")
+}
+
+// repair rolls back, or with --replay applies again, a profile switch that
+// was interrupted part-way
+func (c *CLI) repair(args []string) error {
+	fs := ParseFlags(args)
+	replay := fs.Bool("replay")
+
+	journal, err := aws.LoadSwitchJournal()
+	if err != nil {
+		return err
+	}
+	if journal == nil {
+		fmt.Println(utils.OK() + " Nothing to repair: no interrupted switch")
+		return nil
+	}
+	if !journal.Stale(time.Now()) && !fs.Bool("force") {
+		return fmt.Errorf("a switch to %s started %s and may still be running; retry shortly or pass --force",
+			journal.ToProfile, utils.FormatTimeRelative(journal.StartedAt, time.Now()))
+	}
+
+	fmt.Println("Interrupted switch:")
+	fmt.Printf("  Started:  %s\n", utils.FormatTimeRelative(journal.StartedAt, time.Now()))
+	fmt.Printf("  From:     %s  %s\n", journal.FromProfile, journal.FromContext)
+	toContext := journal.ToContext
+	if toContext == "" && journal.FallbackEnv != "" {
+		toContext = fmt.Sprintf("(context for %s)", journal.FallbackEnv)
+	}
+	fmt.Printf("  To:       %s  %s\n", journal.ToProfile, toContext)
+	fmt.Println()
+
+	if replay {
+		outcome, err := aws.ReplaySwitch(journal, c.profileSwitcher, c.kubeManager)
+		if err != nil {
+			if outcome != nil && outcome.RolledBack {
+				fmt.Printf(utils.Fail()+" Replay failed, rolled back to %s / %s\n", outcome.Profile, outcome.Context)
+			}
+			return err
+		}
+		fmt.Printf(utils.OK()+" Switched to %s", outcome.Profile)
+		if outcome.Context != "" {
+			fmt.Printf(" (%s)", outcome.Context)
+		}
+		fmt.Println()
+		return nil
+	}
+
+	if !fs.AssumeYes() && !confirm.Yes(fmt.Sprintf("Restore %s and the files as they were before the switch? Type 'yes' to confirm: ", journal.FromProfile)) {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+	skipped, err := aws.RollbackSwitch(journal, c.kubeManager)
+	for _, path := range skipped {
+		fmt.Printf(utils.Warn()+" Left %s as it is: it was edited after the switch started\n", path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf(utils.OK()+" Restored %s", journal.FromProfile)
+	if journal.FromContext != "" {
+		fmt.Printf(" (%s)", journal.FromContext)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+)
+
+// defaultStaleAfter is how old a tunnel-access pod must be before
+// 'rw report tunnel-usage' flags it as likely leaked
+const defaultStaleAfter = 24 * time.Hour
+
+// report prints reports for the platform team
+func (c *CLI) report(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw report <tunnel-usage>\n\nSubcommands:\n  tunnel-usage [--cluster <env>]   List tunnel-access pods across users, with their age and owner")
+	}
+
+	switch args[0] {
+	case "tunnel-usage":
+		return c.reportTunnelUsage(args[1:])
+	default:
+		return fmt.Errorf("unknown report: %s\nUse: tunnel-usage", args[0])
+	}
+}
+
+// reportTunnelUsage lists every pod in the tunnel-access namespace, who
+// created it and how old it is, so leaked pods can be spotted
+func (c *CLI) reportTunnelUsage(args []string) error {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+	staleAfter := defaultStaleAfter
+	if v := fs.String("older-than", ""); v != "" {
+		if staleAfter, err = utils.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+
+	kubeContext := ""
+	if env := fs.String("cluster", ""); env != "" {
+		if kubeContext, err = c.kubeManager.FindContextForEnv(env); err != nil {
+			return fmt.Errorf("no kubectl context for %s: %w\nRun 'rw kube refresh %s' to add it", env, err, env)
+		}
+	}
+
+	report, err := aws.TunnelUsage(kubeContext, staleAfter)
+	if err != nil {
+		return err
+	}
+	if format == formatJSON {
+		return printJSON(report)
+	}
+	renderTunnelUsage(report)
+	return nil
+}
+
+func renderTunnelUsage(r *aws.TunnelUsageReport) {
+	where := "the current context"
+	if r.Context != "" {
+		where = r.Context
+	}
+	if len(r.Pods) == 0 {
+		fmt.Printf("No pods in %s on %s.\n", r.Namespace, where)
+		return
+	}
+
+	fmt.Printf("Pods in %s on %s:\n", r.Namespace, where)
+	fmt.Println(strings.Repeat("-", 90))
+	fmt.Printf("%-40s %-16s %-10s %-10s %s\n", "POD", "OWNER", "PHASE", "AGE", "OPERATION")
+	stale := 0
+	for _, p := range r.Pods {
+		marker := ""
+		if p.Stale {
+			marker = " " + utils.Warn()
+			stale++
+		}
+		fmt.Printf("%-40s %-16s %-10s %-10s %s%s\n", p.Name, p.Owner, p.Phase,
+			utils.FormatDuration(r.GeneratedAt.Sub(p.CreatedAt)), p.Operation, marker)
+	}
+
+	fmt.Printf("\n%-16s %-6s %-6s %s\n", "OWNER", "PODS", "STALE", "OLDEST")
+	for _, o := range r.Owners {
+		fmt.Printf("%-16s %-6d %-6d %s\n", o.Owner, o.Pods, o.Stale, utils.FormatDuration(r.GeneratedAt.Sub(o.Oldest)))
+	}
+
+	fmt.Printf("\n%d pod(s) from %d user(s)", len(r.Pods), len(r.Owners))
+	if stale > 0 {
+		fmt.Printf("; "+utils.Warn()+" %d older than %s or finished, likely leaked", stale, r.StaleAfter)
+	}
+	fmt.Println()
+}
@@ -9,18 +9,18 @@ func (c *CLI) grpc(args []string) error {
 		fmt.Print(c.grpcManager.ListServices())
 		return nil
 	}
+	if len(args) >= 1 && args[0] == "proxy" {
+		return c.grpcProxy(args[1:])
+	}
 
-	service := ""
-	env := ""
+	fs := ParseFlags(args)
+	strictPort := fs.Bool("strict-port")
+	service := fs.Arg(0)
+	env := fs.Arg(1)
 
-	if len(args) >= 2 {
-		service = args[0]
-		env = args[1]
-	} else {
+	if service == "" || env == "" {
 		// Interactive picker for missing arguments
-		if len(args) >= 1 {
-			service = args[0]
-		} else {
+		if service == "" {
 			picked, err := c.pickService(true)
 			if err != nil {
 				return err
@@ -34,7 +34,37 @@ func (c *CLI) grpc(args []string) error {
 		env = picked
 	}
 
-	return c.grpcManager.Forward(service, env)
+	return c.grpcManager.Forward(service, env, strictPort)
+}
+
+// grpcProxy forwards a gRPC service and serves an HTTP/JSON proxy for it
+func (c *CLI) grpcProxy(args []string) error {
+	fs := ParseFlags(args)
+	strictPort := fs.Bool("strict-port")
+	service := fs.Arg(0)
+	env := fs.Arg(1)
+
+	httpPort, err := fs.Int("port", 8081)
+	if err != nil || httpPort < 1 || httpPort > 65535 {
+		return fmt.Errorf("invalid --port value (must be 1-65535)")
+	}
+
+	if service == "" {
+		picked, err := c.pickService(true)
+		if err != nil {
+			return err
+		}
+		service = picked
+	}
+	if env == "" {
+		picked, err := c.pickEnvironment()
+		if err != nil {
+			return err
+		}
+		env = picked
+	}
+
+	return c.grpcManager.Proxy(service, env, httpPort, strictPort)
 }
 
 func (c *CLI) redis(args []string) error {
@@ -64,7 +94,7 @@ func (c *CLI) redis(args []string) error {
 
 func (c *CLI) msk(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw msk <ui|connect|stop> <env>\n\nSubcommands:\n  ui <env>      Start Kafka UI for MSK cluster\n  connect <env> Start interactive Kafka CLI session (IAM auth)\n  stop <env>    Stop the Kafka UI pod\n\nExamples:\n  rw msk ui dev              # Start Kafka UI on localhost:8080\n  rw msk ui prod --port 9090 # Start on custom port\n  rw msk connect dev         # Interactive Kafka CLI\n  rw msk stop dev            # Stop the Kafka UI pod")
+		return fmt.Errorf("usage: rw msk <ui|connect|stop> <env>\n\nSubcommands:\n  ui <env>      Start Kafka UI for MSK cluster\n  connect <env> Start interactive Kafka CLI session (IAM auth)\n  stop <env>    Stop the Kafka UI pod\n\nExamples:\n  rw msk ui dev              # Start Kafka UI on localhost:8080\n  rw msk ui prod --port 9090 # Start on custom port\n  rw msk ui dev --strict-port # Fail instead of picking another port if busy\n  rw msk connect dev         # Interactive Kafka CLI\n  rw msk stop dev            # Stop the Kafka UI pod")
 	}
 
 	subCmd := args[0]
@@ -109,7 +139,7 @@ func (c *CLI) mskUI(args []string) error {
 		return fmt.Errorf("invalid port: %s", fs.String("port", ""))
 	}
 
-	return c.mskManager.StartUI(env, port)
+	return c.mskManager.StartUI(env, port, fs.Bool("strict-port"))
 }
 
 func (c *CLI) mskConnect(args []string) error {
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"rolewalkers/aws"
+	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/pin"
+	"rolewalkers/internal/utils"
+)
+
+// globalFlagsUsed records which global flags (--no-color, --plain,
+// --show-secrets, --state-dir) this invocation was given, for
+// 'rw settings effective'
+var globalFlagsUsed = map[string]bool{}
+
+// settings explains the values rw is running with
+func (c *CLI) settings(args []string) error {
+	if len(args) == 0 {
+		return c.settingsEffective(nil)
+	}
+	switch args[0] {
+	case "effective":
+		return c.settingsEffective(args[1:])
+	default:
+		return fmt.Errorf("unknown settings subcommand: %s\nUsage: rw settings effective [--format json]", args[0])
+	}
+}
+
+// settingsJSON is the output of 'rw settings effective --format json'
+type settingsJSON struct {
+	ConfigFile string              `json:"config_file"`
+	Settings   []appconfig.Setting `json:"settings"`
+	Unknown    []string            `json:"unknown_keys,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// settingsEffective prints every setting's effective value and where it
+// came from, to explain why rw behaves differently on two machines
+func (c *CLI) settingsEffective(args []string) error {
+	fs := ParseFlags(args)
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+
+	res, cfgErr := appconfig.Effective()
+	if res == nil {
+		return fmt.Errorf("failed to resolve settings: %w", cfgErr)
+	}
+	settings := append(c.runtimeSettings(), res.Settings...)
+
+	if format == formatJSON {
+		out := settingsJSON{ConfigFile: res.Path, Settings: settings, Unknown: res.Unknown}
+		if cfgErr != nil {
+			out.Error = cfgErr.Error()
+		}
+		return printJSON(out)
+	}
+
+	if cfgErr != nil {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" %v\n", cfgErr)
+	}
+	for _, key := range res.Unknown {
+		fmt.Fprintf(utils.Stderr, utils.Warn()+" %s: unknown key %q is ignored\n", res.Path, key)
+	}
+
+	fmt.Printf("%-34s %-24s %s\n", "SETTING", "VALUE", "SOURCE")
+	for _, s := range settings {
+		source := s.Source
+		if s.Detail != "" {
+			source += " (" + s.Detail + ")"
+		}
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Printf("%-34s %-24s %s\n", s.Key, value, source)
+	}
+	fmt.Println()
+	fmt.Println("Precedence: flag > env > user config > state > default")
+	return nil
+}
+
+// runtimeSettings resolves the settings that come from flags, environment
+// variables and rw's state rather than config.yaml
+func (c *CLI) runtimeSettings() []appconfig.Setting {
+	var out []appconfig.Setting
+	add := func(key, value, source, detail string) {
+		out = append(out, appconfig.Setting{Key: key, Value: value, Source: source, Detail: detail})
+	}
+	// fromEnv adds a setting that only an environment variable changes
+	fromEnv := func(key, env, value string) {
+		if os.Getenv(env) != "" {
+			add(key, value, appconfig.SourceEnv, env)
+			return
+		}
+		add(key, value, appconfig.SourceDefault, "")
+	}
+
+	stateDir, _ := utils.StateDir()
+	switch {
+	case globalFlagsUsed["--state-dir"]:
+		add("state_dir", stateDir, appconfig.SourceFlag, "--state-dir")
+	case os.Getenv(utils.StateDirEnv) != "":
+		add("state_dir", stateDir, appconfig.SourceEnv, utils.StateDirEnv)
+	default:
+		add("state_dir", stateDir, appconfig.SourceDefault, "")
+	}
+
+	if env := os.Getenv(envVarEnv); env != "" {
+		add("environment", env, appconfig.SourceEnv, envVarEnv)
+	} else if p, _ := pin.Get(); p != nil {
+		add("environment", p.Environment, appconfig.SourceState, "rw pin")
+	} else {
+		add("environment", "", appconfig.SourceDefault, "picked interactively")
+	}
+	fromEnv("profile", envVarProfile, os.Getenv(envVarProfile))
+	fromEnv("assume_yes", envVarAssumeYes, strconv.FormatBool(utils.EnvBool(envVarAssumeYes)))
+
+	plain := strconv.FormatBool(utils.PlainEnabled())
+	if globalFlagsUsed["--plain"] {
+		add("plain", plain, appconfig.SourceFlag, "--plain")
+	} else {
+		fromEnv("plain", utils.PlainEnv, plain)
+	}
+
+	color := strconv.FormatBool(utils.ColorEnabled())
+	switch {
+	case globalFlagsUsed["--no-color"]:
+		add("color", color, appconfig.SourceFlag, "--no-color")
+	case globalFlagsUsed["--plain"]:
+		add("color", color, appconfig.SourceFlag, "--plain")
+	case utils.PlainEnabled():
+		add("color", color, appconfig.SourceEnv, utils.PlainEnv)
+	case os.Getenv(utils.NoColorEnv) != "":
+		add("color", color, appconfig.SourceEnv, utils.NoColorEnv)
+	case os.Getenv("TERM") == "dumb":
+		add("color", color, appconfig.SourceEnv, "TERM=dumb")
+	case !utils.StdoutIsTerminal():
+		add("color", color, appconfig.SourceDefault, "stdout is not a terminal")
+	default:
+		add("color", color, appconfig.SourceDefault, "")
+	}
+
+	fromEnv("ascii", utils.ASCIIEnv, strconv.FormatBool(!utils.UnicodeEnabled()))
+
+	if globalFlagsUsed["--show-secrets"] {
+		add("show_secrets", "true", appconfig.SourceFlag, "--show-secrets")
+	} else {
+		add("show_secrets", "false", appconfig.SourceDefault, "")
+	}
+
+	fromEnv("language", messages.LanguageEnv, messages.Language())
+	fromEnv("tool_check", toolCheckEnv, strconv.FormatBool(!utils.EnvBool(toolCheckEnv)))
+
+	if aws.AWSConfigManaged() {
+		add("aws_config_managed", "true", appconfig.SourceState, "rw config generate")
+	} else {
+		add("aws_config_managed", "false", appconfig.SourceDefault, "")
+	}
+	if c.dbRepo != nil {
+		for _, key := range []string{db.SettingLastRunVersion, db.SettingChangelogVersion} {
+			if v, err := c.dbRepo.GetSetting(key); err == nil && v != "" {
+				add(key, v, appconfig.SourceState, "database")
+			}
+		}
+	}
+	return out
+}
@@ -8,6 +8,9 @@ import (
 
 	"rolewalkers/aws"
 	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/confirm"
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
 )
 
 func (c *CLI) setup(args []string) error {
@@ -80,7 +83,7 @@ func (c *CLI) setup(args []string) error {
 		fmt.Println()
 		fmt.Println("  Warnings:")
 		for _, e := range result.Errors {
-			fmt.Printf("    ⚠ %s\n", e)
+			fmt.Printf("    "+utils.Warn()+" %s\n", e)
 		}
 	}
 
@@ -92,3 +95,61 @@ func (c *CLI) setup(args []string) error {
 
 	return nil
 }
+
+// bootstrap builds the database from the AWS config and kubeconfig already
+// on this machine, for engineers who don't need the SSO discovery of 'setup'.
+func (c *CLI) bootstrap(args []string) error {
+	fs := ParseFlags(args)
+
+	fmt.Println("rolewalkers bootstrap")
+	fmt.Println(strings.Repeat("=", 40))
+	fmt.Println()
+	fmt.Println("This will:")
+	fmt.Println("  1. Import profiles from ~/.aws/config")
+	fmt.Println("  2. Import environments from existing EKS kubeconfig contexts")
+	if !fs.Bool("skip-eks") {
+		fmt.Println("  3. Discover EKS clusters in each account and add missing contexts")
+	}
+	fmt.Println("  4. Generate port mappings for new environments")
+	fmt.Println()
+
+	if !fs.AssumeYes() && !confirm.Yes(messages.Render(messages.ConfirmSetup, nil)+" ") {
+		fmt.Println("Bootstrap cancelled")
+		return nil
+	}
+	fmt.Println()
+
+	setupMgr := aws.NewSetupManager(c.dbRepo)
+	result, err := setupMgr.Bootstrap(!fs.Bool("skip-eks"))
+	if err != nil {
+		return err
+	}
+
+	// Write default config file if it doesn't exist
+	appconfig.WriteDefault()
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 40))
+	fmt.Println("Bootstrap complete!")
+	fmt.Printf("  Profiles:      %d imported, %d updated\n", result.ProfilesImported, result.ProfilesUpdated)
+	fmt.Printf("  Contexts:      %d imported\n", result.ContextsImported)
+	fmt.Printf("  Clusters:      %d found\n", result.ClustersFound)
+	fmt.Printf("  Environments:  %d new\n", result.Environments)
+	fmt.Printf("  Port mappings: %d generated\n", result.PortMappings)
+
+	if len(result.Errors) > 0 {
+		fmt.Println()
+		fmt.Println("  Warnings:")
+		for _, e := range result.Errors {
+			fmt.Printf("    "+utils.Warn()+" %s\n", e)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("You can now use:")
+	fmt.Println("  rw list          # See all profiles")
+	fmt.Println("  rw port --list   # See port mappings")
+	fmt.Println("  rw switch dev    # Switch to an environment")
+
+	return nil
+}
@@ -3,12 +3,14 @@ package cli
 import (
 	"fmt"
 	"rolewalkers/aws"
+	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 	"strings"
 )
 
 func (c *CLI) set(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw set <prompt> [options]\n\nSubcommands:\n  prompt [components...]  Configure shell prompt\n    Components: time, folder, aws, k8s, git\n    --reset               Remove rw prompt customization\n    --shell <shell>       Override shell detection (zsh, bash, powershell)\n\nExamples:\n  rw set prompt                          # Enable all components\n  rw set prompt time folder aws git      # Pick specific components\n  rw set prompt --reset                  # Remove prompt customization")
+		return fmt.Errorf("usage: rw set <prompt> [options]\n\nSubcommands:\n  prompt [components...]  Configure shell prompt\n    Components: time, folder, aws, k8s, git\n    --reset               Remove rw prompt customization\n    --status              Show which shells have the prompt installed\n    --restore             Reinstall the prompts recorded in the database\n    --shell <shell>       Override shell detection (zsh, bash, powershell)\n\nExamples:\n  rw set prompt                          # Enable all components\n  rw set prompt time folder aws git      # Pick specific components\n  rw set prompt --reset                  # Remove prompt customization\n  rw set prompt --status                 # What's installed where")
 	}
 
 	switch args[0] {
@@ -26,20 +28,26 @@ func (c *CLI) setPrompt(args []string) error {
 	shell := fs.String("shell", pm.DetectShell())
 	reset := fs.Bool("reset") || fs.Bool("remove")
 
-	var components []aws.PromptComponent
-	for _, name := range fs.Positional() {
-		comp := aws.PromptComponent(strings.ToLower(name))
-		valid := false
-		for _, c := range aws.AllPromptComponents() {
-			if comp == c {
-				valid = true
-				break
-			}
+	if fs.Bool("status") {
+		return c.promptStatus(pm, fs)
+	}
+	if fs.Bool("restore") {
+		if err := c.requireDB("rw set prompt --restore"); err != nil {
+			return err
 		}
-		if !valid {
-			return fmt.Errorf("unknown prompt component: %s\nAvailable: time, folder, aws, k8s, git", name)
+		restored, err := restorePrompts(c.dbRepo)
+		if err != nil {
+			return err
 		}
-		components = append(components, comp)
+		if restored == 0 {
+			fmt.Println("No prompt installations recorded. Use 'rw set prompt' to install one.")
+		}
+		return nil
+	}
+
+	components, err := aws.ParsePromptComponents(fs.Positional())
+	if err != nil {
+		return err
 	}
 
 	profilePath, err := pm.GetShellProfilePath(shell)
@@ -51,33 +59,136 @@ func (c *CLI) setPrompt(args []string) error {
 		if err := pm.RemovePrompt(shell); err != nil {
 			return fmt.Errorf("failed to remove prompt: %w", err)
 		}
-		fmt.Printf("✓ Removed rw prompt from: %s\n", profilePath)
+		if c.dbRepo != nil {
+			if err := c.dbRepo.DeleteShellIntegration(shell); err != nil {
+				fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to forget the %s prompt: %v\n", shell, err)
+			}
+		}
+		fmt.Printf(utils.OK()+" Removed rw prompt from: %s\n", profilePath)
 		fmt.Printf("\nReload your shell:\n  source %s\n", profilePath)
 		return nil
 	}
 
-	if len(components) == 0 {
-		components = aws.AllPromptComponents()
-	}
-
 	if err := pm.InstallPrompt(shell, components); err != nil {
 		return fmt.Errorf("failed to install prompt: %w", err)
 	}
+	if c.dbRepo != nil {
+		if err := c.dbRepo.SaveShellIntegration(shell, promptComponentNames(components), profilePath); err != nil {
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record the %s prompt: %v\n", shell, err)
+		}
+	}
 
-	fmt.Printf("✓ Prompt installed to: %s\n", profilePath)
+	fmt.Printf(utils.OK()+" Prompt installed to: %s\n", profilePath)
 	fmt.Printf("  Shell:      %s\n", shell)
-	fmt.Printf("  Components: ")
-	for i, comp := range components {
-		if i > 0 {
-			fmt.Print(", ")
+	fmt.Printf("  Components: %s\n", strings.Join(promptComponentNames(components), ", "))
+	fmt.Printf("\nReload your shell:\n  source %s\n", profilePath)
+	return nil
+}
+
+// promptStatus shows, for each shell, where the rw prompt is installed and
+// whether it matches the components recorded in the database
+func (c *CLI) promptStatus(pm *aws.PromptManager, fs *FlagSet) error {
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+
+	recorded := make(map[string][]aws.PromptComponent)
+	if c.dbRepo != nil {
+		integrations, err := c.dbRepo.GetShellIntegrations()
+		if err != nil {
+			return fmt.Errorf("failed to read recorded prompts: %w", err)
+		}
+		for _, si := range integrations {
+			components, err := aws.ParsePromptComponents(si.Components)
+			if err != nil {
+				return fmt.Errorf("recorded %s prompt: %w", si.Shell, err)
+			}
+			recorded[si.Shell] = components
+		}
+	}
+
+	var statuses []*aws.PromptStatus
+	for _, shell := range aws.PromptShells() {
+		status, err := pm.Status(shell, recorded[shell])
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+
+	if format == "json" {
+		return printJSON(statuses)
+	}
+
+	fmt.Printf("%-12s %-10s %-28s %s\n", "SHELL", "STATE", "COMPONENTS", "PROFILE")
+	for _, s := range statuses {
+		state := "-"
+		switch {
+		case s.Installed && s.Current:
+			state = "installed"
+		case s.Installed && s.Recorded:
+			state = "outdated"
+		case s.Installed:
+			state = "untracked"
+		case s.Recorded:
+			state = "missing"
+		}
+		components := "-"
+		if s.Recorded {
+			components = strings.Join(promptComponentNames(s.Components), ",")
+		}
+		fmt.Printf("%-12s %-10s %-28s %s\n", s.Shell, state, components, s.ProfilePath)
+	}
+
+	for _, s := range statuses {
+		if s.Recorded && !s.Current {
+			fmt.Println("\nRun 'rw set prompt --restore' to reinstall the recorded prompts.")
+			break
 		}
-		fmt.Print(string(comp))
 	}
-	fmt.Println()
-	fmt.Printf("\nReload your shell:\n  source %s\n", profilePath)
 	return nil
 }
 
+// restorePrompts reinstalls every prompt recorded in the database, at this
+// machine's profile paths, and returns how many it installed
+func restorePrompts(repo *db.ConfigRepository) (int, error) {
+	integrations, err := repo.GetShellIntegrations()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read recorded prompts: %w", err)
+	}
+
+	pm := aws.NewPromptManager()
+	for _, si := range integrations {
+		components, err := aws.ParsePromptComponents(si.Components)
+		if err != nil {
+			return 0, fmt.Errorf("recorded %s prompt: %w", si.Shell, err)
+		}
+		profilePath, err := pm.GetShellProfilePath(si.Shell)
+		if err != nil {
+			return 0, err
+		}
+		if err := pm.InstallPrompt(si.Shell, components); err != nil {
+			return 0, fmt.Errorf("failed to install the %s prompt: %w", si.Shell, err)
+		}
+		if profilePath != si.ProfilePath {
+			if err := repo.SaveShellIntegration(si.Shell, si.Components, profilePath); err != nil {
+				fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record the %s prompt: %v\n", si.Shell, err)
+			}
+		}
+		fmt.Printf(utils.OK()+" Installed the %s prompt (%s) to %s\n", si.Shell, strings.Join(si.Components, ", "), profilePath)
+	}
+	return len(integrations), nil
+}
+
+func promptComponentNames(components []aws.PromptComponent) []string {
+	names := make([]string, len(components))
+	for i, comp := range components {
+		names[i] = string(comp)
+	}
+	return names
+}
+
 func (c *CLI) initShell(args []string) error {
 	// Deprecated: shell integration is now handled by 'rw set prompt'.
 	return fmt.Errorf("'init-shell' has been removed. Use 'rw set prompt' instead")
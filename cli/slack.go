@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"rolewalkers/internal/slack"
+	"rolewalkers/internal/utils"
+)
+
+// slackSecretEnv holds the Slack app's signing secret for 'rw slack serve'
+const slackSecretEnv = "SLACK_SIGNING_SECRET"
+
+// slackCmd serves a Slack slash command for on-call engineers. Only
+// read-only operations are exposed.
+func (c *CLI) slackCmd(args []string) error {
+	if len(args) < 1 || args[0] != "serve" {
+		return fmt.Errorf("usage: rw slack serve [--addr <host:port>]\n\nServes a Slack slash command (POST /slack) with read-only commands:\n  status <env>, maintenance status <env>, replication status <env>, scale list <env>\n\nRequires: %s environment variable", slackSecretEnv)
+	}
+
+	fs := ParseFlags(args[1:])
+	addr := fs.String("addr", "127.0.0.1:8090")
+	secret := os.Getenv(slackSecretEnv)
+	if secret == "" {
+		return fmt.Errorf("%s is not set; copy it from your Slack app's Basic Information page", slackSecretEnv)
+	}
+
+	// Replies are shown in Slack, not a terminal
+	utils.SetNoColor(true)
+
+	mux := http.NewServeMux()
+	mux.Handle("/slack", slack.NewHandler(secret, c.slackCommands()...))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- server.Serve(listener) }()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	fmt.Printf("Serving the Slack slash command on http://%s/slack\n", listener.Addr())
+	fmt.Println("Point the slash command's Request URL at it (through a TLS reverse proxy).")
+	fmt.Println("\nPress Ctrl+C to stop...")
+
+	var runErr error
+	select {
+	case <-sigChan:
+		fmt.Println("\nStopping...")
+	case err := <-serveDone:
+		if !errors.Is(err, http.ErrServerClosed) {
+			runErr = fmt.Errorf("server stopped: %w", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+	return runErr
+}
+
+// slackCommands returns the read-only commands exposed to Slack
+func (c *CLI) slackCommands() []slack.Command {
+	return []slack.Command{
+		{Name: "status", Usage: "status <env>", Run: slackEnvCommand(c.slackStatus)},
+		{Name: "maintenance status", Usage: "maintenance status <env>", Run: slackEnvCommand(func(env string) (string, error) {
+			statuses, err := c.maintenanceManager.Status(env)
+			if err != nil {
+				return "", err
+			}
+			var sb strings.Builder
+			renderMaintenanceStatus(&sb, env, statuses)
+			return sb.String(), nil
+		})},
+		{Name: "replication status", Usage: "replication status <env>", Run: slackEnvCommand(c.replicationManager.Status)},
+		{Name: "scale list", Usage: "scale list <env>", Run: slackEnvCommand(func(env string) (string, error) {
+			// Never switch contexts or profiles under the user's feet
+			hpas, err := c.scalingManager.ListHPAsInContext(env)
+			if err != nil {
+				return "", err
+			}
+			var sb strings.Builder
+			renderHPAs(&sb, c.scalingManager.Namespace(), hpas)
+			return sb.String(), nil
+		})},
+	}
+}
+
+// slackEnvCommand adapts a command taking exactly one environment argument
+func slackEnvCommand(run func(env string) (string, error)) func([]string) (string, error) {
+	return func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("expected an environment, e.g. prod")
+		}
+		return run(args[0])
+	}
+}
+
+// slackStatus summarises an environment: where it lives and whether it is
+// in maintenance
+func (c *CLI) slackStatus(env string) (string, error) {
+	e, err := c.dbRepo.GetEnvironment(env)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Environment: %s (%s)\n", e.Name, e.DisplayName)
+	fmt.Fprintf(&sb, "Profile:     %s\n", e.AWSProfile)
+	if account, err := c.dbRepo.GetEnvironmentAccount(env); err == nil && account != "" {
+		fmt.Fprintf(&sb, "Account:     %s\n", account)
+	}
+	fmt.Fprintf(&sb, "Region:      %s\n", e.Region)
+	fmt.Fprintf(&sb, "Cluster:     %s\n", e.ClusterName)
+
+	statuses, err := c.maintenanceManager.Status(env)
+	if err != nil {
+		fmt.Fprintf(&sb, "Maintenance: unknown (%v)\n", err)
+		return sb.String(), nil
+	}
+	for _, s := range statuses {
+		state := "off"
+		switch {
+		case s.Error != "":
+			state = "unknown (" + s.Error + ")"
+		case s.Enabled:
+			state = "ON"
+		}
+		fmt.Fprintf(&sb, "Maintenance: %s %s\n", strings.ToUpper(s.ServiceType), state)
+	}
+	return sb.String(), nil
+}
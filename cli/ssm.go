@@ -1,10 +1,17 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"rolewalkers/aws"
+	appconfig "rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+	"sort"
+	"strings"
+)
 
 func (c *CLI) ssm(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw ssm <get|list> <path>\n\nSubcommands:\n  get <path>     Get parameter value\n  list <prefix>  List parameters under prefix\n\nExamples:\n  rw ssm get /dev/zenith/database/query/db-write-endpoint\n  rw ssm get /prod/zenith/redis/cluster-endpoint --decrypt\n  rw ssm list /dev/zenith/")
+		return fmt.Errorf("usage: rw ssm <get|list|browse> <path>\n\nSubcommands:\n  get <path>       Get parameter value\n  list <prefix>    List parameters under prefix\n                   [--max-depth <n>] [--contains <text>] [--values]\n  browse [prefix]  Browse parameters interactively\n\nExamples:\n  rw ssm get /dev/zenith/database/query/db-write-endpoint\n  rw ssm get /prod/zenith/redis/cluster-endpoint --decrypt\n  rw ssm list /dev/zenith/\n  rw ssm browse /dev/zenith/")
 	}
 
 	subCmd := args[0]
@@ -15,45 +22,301 @@ func (c *CLI) ssm(args []string) error {
 		return c.ssmGet(subArgs)
 	case "list", "ls":
 		return c.ssmList(subArgs)
+	case "browse", "b":
+		return c.ssmBrowse(subArgs)
 	default:
-		return fmt.Errorf("unknown ssm subcommand: %s\nUse: get, list", subCmd)
+		return fmt.Errorf("unknown ssm subcommand: %s\nUse: get, list, browse", subCmd)
 	}
 }
 
 func (c *CLI) ssmGet(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: rw ssm get <path> [--decrypt]\n\nExamples:\n  rw ssm get /dev/zenith/database/query/db-write-endpoint\n  rw ssm get /prod/zenith/redis/cluster-endpoint")
+	fs := ParseFlags(args)
+	path := fs.Arg(0)
+	if path == "" {
+		return fmt.Errorf("usage: rw ssm get <path> [--decrypt] [--copy]\n\nExamples:\n  rw ssm get /dev/zenith/database/query/db-write-endpoint\n  rw ssm get /prod/zenith/redis/cluster-endpoint\n  rw ssm get /prod/zenith/database/query/db-zenithmaster-password --copy")
 	}
 
-	value, err := c.ssmManager.GetParameter(args[0])
+	value, err := c.ssmManager.GetParameter(path)
 	if err != nil {
 		return err
 	}
 
+	if fs.Bool("copy") {
+		return copyOutput(value, true)
+	}
+	if utils.IsSecretPath(path) && !utils.ShowSecrets() {
+		fmt.Println(utils.RedactedValue)
+		fmt.Fprintln(utils.Stderr, utils.Warn()+" Secret value hidden. Use --copy to copy it, or --show-secrets to print it.")
+		return nil
+	}
 	fmt.Println(value)
 	return nil
 }
 
+// ssmList lists every parameter under a prefix, or those up to --max-depth
+// levels deep, optionally filtered by name and with their values fetched
+// in parallel
 func (c *CLI) ssmList(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: rw ssm list <prefix>\n\nExamples:\n  rw ssm list /dev/zenith/\n  rw ssm list /prod/zenith/database/")
+	fs := ParseFlags(args)
+	prefix := fs.Arg(0)
+	if prefix == "" {
+		return fmt.Errorf("usage: rw ssm list <prefix> [--max-depth <n>] [--contains <text>] [--values] [--format json]\n\nExamples:\n  rw ssm list /dev/zenith/\n  rw ssm list /prod/zenith/ --contains endpoint\n  rw ssm list /prod/zenith/database/ --max-depth 2 --values")
+	}
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+	maxDepth, err := fs.Int("max-depth", 0)
+	if err != nil || maxDepth < 0 {
+		return fmt.Errorf("--max-depth must be a positive number")
 	}
+	concurrency, err := fs.Int("concurrency", aws.DefaultSSMValueConcurrency)
+	if err != nil || concurrency < 1 {
+		return fmt.Errorf("--concurrency must be a positive number")
+	}
+	filter := aws.SSMListFilter{MaxDepth: maxDepth, Contains: fs.String("contains", "")}
+	// One level needs no recursive listing; --recursive is the default and
+	// only kept for scripts that pass it
+	recursive := maxDepth != 1
 
-	prefix := args[0]
-	params, err := c.ssmManager.ListParameters(prefix)
+	var params []aws.SSMParameterInfo
+	pages := 0
+	err = c.ssmManager.ListParameterPages(prefix, recursive, func(page []aws.SSMParameterInfo) error {
+		pages++
+		for _, p := range page {
+			if filter.Match(prefix, p) {
+				params = append(params, p)
+			}
+		}
+		if pages > 1 {
+			fmt.Fprintf(utils.Stderr, "  fetched %d pages, %d matching parameters...\n", pages, len(params))
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+
+	var values map[string]string
+	if fs.Bool("values") && len(params) > 0 {
+		names := make([]string, len(params))
+		for i, p := range params {
+			names[i] = p.Name
+		}
+		if values, err = c.ssmManager.GetParameterValues(names, concurrency); err != nil {
+			return err
+		}
+	}
+	shown := func(p aws.SSMParameterInfo) string {
+		if (p.IsSecure() || utils.IsSecretPath(p.Name)) && !utils.ShowSecrets() {
+			return utils.RedactedValue
+		}
+		return values[p.Name]
+	}
+
+	if format == formatJSON {
+		type entry struct {
+			Name  string `json:"name"`
+			Type  string `json:"type"`
+			Value string `json:"value,omitempty"`
+		}
+		entries := make([]entry, 0, len(params))
+		for _, p := range params {
+			e := entry{Name: p.Name, Type: p.Type}
+			if values != nil {
+				e.Value = shown(p)
+			}
+			entries = append(entries, e)
+		}
+		return printJSON(entries)
+	}
 
 	if len(params) == 0 {
 		fmt.Printf("No parameters found under: %s\n", prefix)
+		if maxDepth > 0 {
+			fmt.Printf("  Only %d level(s) were searched; raise or drop --max-depth to search deeper\n", maxDepth)
+		}
 		return nil
 	}
 
 	fmt.Printf("Parameters under %s:\n", prefix)
 	for _, p := range params {
-		fmt.Printf("  %s\n", p)
+		if values != nil {
+			fmt.Printf("  %s = %s\n", p.Name, shown(p))
+		} else {
+			fmt.Printf("  %s\n", p.Name)
+		}
+	}
+	if values != nil && !utils.ShowSecrets() {
+		for _, p := range params {
+			if p.IsSecure() || utils.IsSecretPath(p.Name) {
+				fmt.Fprintln(utils.Stderr, utils.Warn()+" Secret values hidden. Use --show-secrets to print them.")
+				break
+			}
+		}
 	}
-
 	return nil
 }
+
+// ssmBrowse is an interactive tree browser for SSM parameters. Folders are
+// loaded on first open; values are fetched only when a parameter is picked
+// and SecureStrings stay masked until revealed.
+func (c *CLI) ssmBrowse(args []string) error {
+	envs := c.environmentNames()
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	} else if env, err := c.pickEnvironment(); err == nil {
+		prefix = appconfig.Get().SSMPath(env, "")
+	} else {
+		return err
+	}
+
+	tree := aws.NewSSMTree(c.ssmManager)
+	prefix = aws.NormalizeSSMFolder(prefix)
+
+	itemUp := ".. (up)"
+	itemSwitch := utils.Emoji("⇄ ", "") + "switch environment"
+	itemRefresh := utils.Emoji("↻ ", "") + "refresh"
+
+	for {
+		fmt.Printf("Loading %s...\n", prefix)
+		folders, params, err := tree.Children(prefix)
+		if err != nil {
+			return err
+		}
+
+		items := []string{}
+		if prefix != "/" {
+			items = append(items, itemUp)
+		}
+		items = append(items, itemSwitch, itemRefresh)
+		items = append(items, folders...)
+		byLabel := make(map[string]aws.SSMParameterInfo)
+		for _, p := range params {
+			label := strings.TrimPrefix(p.Name, prefix)
+			if p.IsSecure() {
+				label += utils.Emoji("  🔒", "  (secure)")
+			}
+			byLabel[label] = p
+			items = append(items, label)
+		}
+		if len(folders) == 0 && len(params) == 0 {
+			fmt.Printf("No parameters found under: %s\n", prefix)
+		}
+
+		selected, ok := utils.SelectFromList(prefix, items)
+		if !ok {
+			return nil
+		}
+
+		switch {
+		case selected == itemUp:
+			prefix = aws.ParentSSMFolder(prefix)
+		case selected == itemSwitch:
+			if next, err := c.switchSSMEnvironment(prefix, envs); err != nil {
+				fmt.Fprintf(utils.Stdout, utils.Warn()+" %v\n", err)
+			} else {
+				prefix = next
+			}
+		case selected == itemRefresh:
+			tree.Refresh()
+		case strings.HasSuffix(selected, "/"):
+			prefix += selected
+		default:
+			param := byLabel[selected]
+			next, err := c.ssmBrowseParameter(param, envs)
+			if err != nil {
+				fmt.Fprintf(utils.Stdout, utils.Warn()+" %v\n", err)
+			}
+			if next != "" {
+				prefix = next
+			}
+		}
+	}
+}
+
+// ssmBrowseParameter previews a parameter value and offers actions on it.
+// It returns a folder to jump to, or "" to stay where the browser was.
+func (c *CLI) ssmBrowseParameter(param aws.SSMParameterInfo, envs []string) (string, error) {
+	value, err := c.ssmManager.GetParameter(param.Name)
+	if err != nil {
+		return "", err
+	}
+	if param.IsSecure() {
+		utils.TrackSecret(value)
+	}
+
+	const (
+		actionReveal = "Reveal value"
+		actionHide   = "Hide value"
+		actionCopy   = "Copy to clipboard"
+		actionSwitch = "Same parameter in another environment"
+		actionBack   = "Back"
+	)
+
+	masked := (param.IsSecure() || utils.IsSecretPath(param.Name)) && !utils.ShowSecrets()
+	for {
+		shown := value
+		if masked {
+			shown = utils.RedactedValue
+		}
+		fmt.Println()
+		fmt.Printf("  Name:  %s\n", param.Name)
+		fmt.Printf("  Type:  %s\n", param.Type)
+		fmt.Printf("  Value: %s\n", shown)
+		fmt.Println()
+
+		actions := []string{actionCopy, actionSwitch, actionBack}
+		if param.IsSecure() || utils.IsSecretPath(param.Name) {
+			toggle := actionReveal
+			if !masked {
+				toggle = actionHide
+			}
+			actions = append([]string{toggle}, actions...)
+		}
+
+		selected, ok := utils.SelectFromList("Action:", actions)
+		if !ok {
+			return "", nil
+		}
+
+		switch selected {
+		case actionReveal, actionHide:
+			masked = !masked
+		case actionCopy:
+			if err := copyOutput(value, param.IsSecure() || utils.IsSecretPath(param.Name)); err != nil {
+				return "", err
+			}
+		case actionSwitch:
+			target, err := c.switchSSMEnvironment(param.Name, envs)
+			if err != nil {
+				return "", err
+			}
+			return aws.ParentSSMFolder(target), nil
+		case actionBack:
+			return "", nil
+		}
+	}
+}
+
+// switchSSMEnvironment maps path to the same subpath in a picked environment
+func (c *CLI) switchSSMEnvironment(path string, envs []string) (string, error) {
+	current, _, ok := aws.SSMEnvironmentOf(path, envs)
+	if !ok {
+		return "", fmt.Errorf("%s is not under a known environment prefix", path)
+	}
+
+	var others []string
+	for _, e := range envs {
+		if e != current {
+			others = append(others, e)
+		}
+	}
+	target, ok := utils.SelectFromList(fmt.Sprintf("Switch from %s to:", current), others)
+	if !ok {
+		return "", fmt.Errorf("selection cancelled")
+	}
+	return aws.SwitchSSMEnvironment(path, envs, target)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"rolewalkers/internal/utils"
 	"rolewalkers/tray"
 )
 
@@ -47,19 +48,19 @@ func (c *CLI) trayStart() error {
 
 func (c *CLI) trayStop() error {
 	if err := tray.StopRunning(); err != nil {
-		fmt.Printf("⚠ %v\n", err)
+		fmt.Fprintf(utils.Stdout, utils.Warn()+" %v\n", err)
 		return nil
 	}
-	fmt.Println("✓ Tray stopped")
+	fmt.Println(utils.OK() + " Tray stopped")
 	return nil
 }
 
 func (c *CLI) trayStatus() error {
 	running, pid := tray.IsRunning()
 	if running {
-		fmt.Printf("✓ Tray is running (PID %d)\n", pid)
+		fmt.Printf(utils.OK()+" Tray is running (PID %d)\n", pid)
 	} else {
-		fmt.Println("✗ Tray is not running")
+		fmt.Println(utils.Fail() + " Tray is not running")
 		fmt.Println("  Start it with: rw tray start")
 	}
 	return nil
@@ -1,13 +1,21 @@
 package cli
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"os"
 	"rolewalkers/aws"
+	"rolewalkers/internal/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func (c *CLI) tunnel(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: rw tunnel <start|stop|list> [service] [env]\n\nSubcommands:\n  start <service> <env>  Start a tunnel\n  stop <service> <env>   Stop a specific tunnel\n  stop --all             Stop all tunnels\n  list                   List active tunnels\n  cleanup                Remove stale tunnel entries\n\nServices: %s\nEnvironments: snd, dev, sit, preprod, trg, prod, qa, stage", c.tunnelManager.GetSupportedServices())
+		return fmt.Errorf("usage: rw tunnel <start|stop|list|share|join> [service] [env]\n\nSubcommands:\n  start <service> <env>  Start a tunnel\n  stop <service> <env>   Stop a specific tunnel\n  stop --all             Stop all tunnels\n  list                   List active tunnels\n  cleanup                Remove stale tunnel entries\n  share <service> <env>  Print a manifest a teammate can join\n  join <manifest.json>   Start the tunnel described by a manifest\n\nServices: %s\nEnvironments: snd, dev, sit, preprod, trg, prod, qa, stage", c.tunnelManager.GetSupportedServices())
 	}
 
 	subCmd := args[0]
@@ -19,13 +27,30 @@ func (c *CLI) tunnel(args []string) error {
 	case "stop":
 		return c.tunnelStop(subArgs)
 	case "list", "ls":
-		fmt.Print(c.tunnelManager.List())
-		return nil
+		return c.tunnelList(subArgs)
 	case "cleanup":
 		return c.tunnelManager.CleanupStale()
+	case "share":
+		return c.tunnelShare(subArgs)
+	case "join":
+		return c.tunnelJoin(subArgs)
 	default:
-		return fmt.Errorf("unknown tunnel subcommand: %s\nUse: start, stop, list, cleanup", subCmd)
+		return fmt.Errorf("unknown tunnel subcommand: %s\nUse: start, stop, list, cleanup, share, join", subCmd)
+	}
+}
+
+func (c *CLI) tunnelList(args []string) error {
+	format, err := outputFormat(ParseFlags(args))
+	if err != nil {
+		return err
+	}
+
+	tunnels := c.tunnelManager.ListWithStatus()
+	if format == formatJSON {
+		return printJSON(tunnels)
 	}
+	renderTunnels(tunnels)
+	return nil
 }
 
 func (c *CLI) tunnelStart(args []string) error {
@@ -60,18 +85,136 @@ func (c *CLI) tunnelStart(args []string) error {
 		DBType:      "query",
 	}
 
+	reason := ""
+	assumeYes := utils.EnvBool(envVarAssumeYes)
 	for i := 2; i < len(args); i++ {
 		switch args[i] {
 		case "--write", "-w":
 			config.NodeType = "write"
 		case "--command", "-c":
 			config.DBType = "command"
+		case "--reason":
+			if i+1 < len(args) {
+				i++
+				reason = args[i]
+			}
+		case "--rate-limit":
+			if i+1 >= len(args) {
+				return fmt.Errorf("usage: --rate-limit <rate>, e.g. 10mbit")
+			}
+			i++
+			rate, err := aws.ParseRate(args[i])
+			if err != nil {
+				return err
+			}
+			config.RateLimit = rate
+		case "--keepalive":
+			if i+1 >= len(args) {
+				return fmt.Errorf("usage: --keepalive <duration>, e.g. 30s")
+			}
+			i++
+			keepalive, err := time.ParseDuration(args[i])
+			if err != nil || keepalive < time.Second {
+				return fmt.Errorf("invalid --keepalive %q: use a duration of at least 1s, e.g. 30s", args[i])
+			}
+			config.Keepalive = keepalive
+		case "--audit-connections":
+			config.AuditConnections = true
+		case "--yes", "-y":
+			assumeYes = true
+		}
+	}
+
+	if config.NodeType == "write" {
+		var err error
+		if config.Reason, err = c.quotaReason(aws.QuotaTunnelWrite, env, service, reason, assumeYes); err != nil {
+			return err
 		}
 	}
 
 	return c.tunnelManager.Start(config)
 }
 
+// tunnelShare prints (or writes) a manifest describing a tunnel setup so a
+// teammate can reproduce it with 'rw tunnel join'. Only configuration is
+// shared, never the connection or credentials.
+func (c *CLI) tunnelShare(args []string) error {
+	fs := ParseFlags(args)
+	output := fs.String("output", "")
+	if output == "" {
+		output = fs.String("o", "")
+	}
+	positional := fs.Positional()
+	if len(positional) < 2 {
+		return fmt.Errorf("usage: rw tunnel share <service> <env> [--output manifest.json] [--write] [--command]")
+	}
+
+	config := aws.TunnelConfig{
+		Service:     positional[0],
+		Environment: positional[1],
+		NodeType:    "read",
+		DBType:      "query",
+	}
+	if fs.Bool("write") || fs.Bool("w") {
+		config.NodeType = "write"
+	}
+	if fs.Bool("command") || fs.Bool("c") {
+		config.DBType = "command"
+	}
+
+	manifest, err := c.tunnelManager.ShareManifest(config)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	data = append(data, '\n')
+
+	if output == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	fmt.Printf(utils.OK()+" Manifest written to %s\n", output)
+	fmt.Printf("  Share it and run: rw tunnel join %s\n", output)
+	return nil
+}
+
+// tunnelJoin starts the tunnel described by a manifest from 'rw tunnel share'
+func (c *CLI) tunnelJoin(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rw tunnel join <manifest.json>")
+	}
+
+	manifest, err := aws.LoadTunnelManifest(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Joining tunnel shared by %s:\n", cmp.Or(manifest.CreatedBy, "unknown"))
+	fmt.Printf("  Service:     %s\n", manifest.Service)
+	fmt.Printf("  Environment: %s\n", manifest.Environment)
+	fmt.Printf("  Profile:     %s\n", manifest.Profile)
+	fmt.Printf("  Local port:  %d\n", manifest.LocalPort)
+
+	for _, w := range c.tunnelManager.JoinWarnings(manifest) {
+		fmt.Printf(utils.Warn()+" %s\n", w)
+	}
+	fmt.Println()
+
+	config := manifest.TunnelConfig()
+	if config.NodeType == "write" {
+		if config.Reason, err = c.quotaReason(aws.QuotaTunnelWrite, config.Environment, config.Service, "", utils.EnvBool(envVarAssumeYes)); err != nil {
+			return err
+		}
+	}
+	return c.tunnelManager.Start(config)
+}
+
 func (c *CLI) tunnelStop(args []string) error {
 	if len(args) > 0 && (args[0] == "--all" || args[0] == "-a") {
 		return c.tunnelManager.StopAll()
@@ -100,6 +243,17 @@ func (c *CLI) port(args []string) error {
 		return nil
 	}
 
+	fs := ParseFlags(args)
+	copyToClipboard := fs.Bool("copy")
+	format, err := outputFormat(fs)
+	if err != nil {
+		return err
+	}
+	if fs.Bool("json") {
+		format = formatJSON
+	}
+	args = fs.Positional()
+
 	service := ""
 	env := ""
 
@@ -123,18 +277,45 @@ func (c *CLI) port(args []string) error {
 		env = picked
 	}
 
-	ports, err := portConfig.GetPort(service, env)
+	ports, err := portConfig.GetNamedPorts(service, env)
 	if err != nil {
 		return err
 	}
-
-	for i, p := range ports {
-		if i > 0 {
-			fmt.Print("/")
+	if name := fs.String("name", ""); name != "" {
+		i := slices.IndexFunc(ports, func(p aws.NamedPort) bool { return p.Name == name })
+		if i < 0 {
+			return fmt.Errorf("%s has no %q port in %s\nPorts: %s", service, name, env, portNames(ports))
 		}
-		fmt.Print(p)
+		ports = ports[i : i+1]
 	}
-	fmt.Println()
 
+	// Scripts get a single port on one line, or JSON; see 'rw help' for
+	// the output contract
+	if format == formatJSON {
+		return printJSON(struct {
+			Service     string          `json:"service"`
+			Environment string          `json:"environment"`
+			Ports       []aws.NamedPort `json:"ports"`
+		}{strings.ToLower(service), strings.ToLower(env), ports})
+	}
+	if copyToClipboard {
+		return copyOutput(strconv.Itoa(ports[0].Port), false)
+	}
+	if len(ports) == 1 || fs.Bool("first") || !utils.StdoutIsTerminal() {
+		fmt.Println(ports[0].Port)
+		return nil
+	}
+	for _, p := range ports {
+		fmt.Printf("%-5d %s\n", p.Port, p.Name)
+	}
 	return nil
 }
+
+// portNames lists the names of a service's ports
+func portNames(ports []aws.NamedPort) string {
+	names := make([]string, len(ports))
+	for i, p := range ports {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
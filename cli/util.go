@@ -1,29 +1,85 @@
 package cli
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"fmt"
+	"os"
+	"rolewalkers/internal/keygen"
+	"rolewalkers/internal/utils"
 	"strconv"
+	"strings"
 )
 
 func (c *CLI) keygen(args []string) error {
+	fs := ParseFlags(args)
 	count := 1
-	if len(args) > 0 {
-		n, err := strconv.Atoi(args[0])
+	if arg := fs.Arg(0); arg != "" {
+		n, err := strconv.Atoi(arg)
 		if err != nil || n < 1 {
-			return fmt.Errorf("invalid count: %s (must be a positive integer)", args[0])
+			return fmt.Errorf("invalid count: %s (must be a positive integer)", arg)
 		}
 		count = n
 	}
 
+	format, err := keygen.ParseFormat(fs.String("format", ""))
+	if err != nil {
+		return err
+	}
+	nBytes, err := fs.Int("bytes", keygen.DefaultBytes)
+	if err != nil {
+		return fmt.Errorf("invalid --bytes value: %s", fs.String("bytes", ""))
+	}
+	words, err := fs.Int("words", keygen.DefaultWords)
+	if err != nil {
+		return fmt.Errorf("invalid --words value: %s", fs.String("words", ""))
+	}
+	opts := keygen.Options{
+		Format: format,
+		Bytes:  nBytes,
+		Words:  words,
+		Prefix: fs.String("prefix", ""),
+	}
+
+	keys := make([]string, 0, count)
 	for i := 0; i < count; i++ {
-		bytes := make([]byte, 16)
-		if _, err := rand.Read(bytes); err != nil {
-			return fmt.Errorf("failed to generate random key: %w", err)
+		key, err := keygen.Generate(opts)
+		if err != nil {
+			return err
 		}
-		fmt.Println(hex.EncodeToString(bytes))
+		keys = append(keys, key)
+	}
+
+	if output := fs.String("output", ""); output != "" {
+		return writeKeys(output, keys)
+	}
+	if fs.Bool("copy") {
+		return copyOutput(strings.Join(keys, "\n"), true)
+	}
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+
+	return nil
+}
+
+// writeKeys writes one key per line to a file readable only by the owner.
+// An existing file is overwritten and its permissions tightened.
+func writeKeys(path string, keys []string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer f.Close()
+
+	if err := f.Chmod(0600); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	if _, err := f.WriteString(strings.Join(keys, "\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to write keys: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write keys: %w", err)
 	}
 
+	fmt.Printf(utils.OK()+" Wrote %d key(s) to %s (mode 0600)\n", len(keys), path)
 	return nil
 }
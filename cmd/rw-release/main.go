@@ -0,0 +1,144 @@
+// Command rw-release packages the binaries from 'make build-all' into
+// release archives with shell completions and man pages, and writes the
+// Homebrew formula, Scoop manifest and nfpm (deb/rpm) configs for them.
+//
+//	go run ./cmd/rw-release --version 1.2.0 --repo owner/rolewalker
+//
+// Run it from the repository root; 'make release' builds and runs it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"rolewalkers/cli"
+	"rolewalkers/internal/release"
+)
+
+func main() {
+	version := flag.String("version", "", "release version, e.g. 1.2.0")
+	repo := flag.String("repo", "", "GitHub repository (owner/name) hosting the release archives")
+	binDir := flag.String("bin", "bin", "directory with the binaries from 'make build-all'")
+	outDir := flag.String("output", "dist", "output directory for archives and packaging metadata")
+	maintainer := flag.String("maintainer", "", "deb/rpm maintainer, \"Name <email>\"")
+	flag.Parse()
+
+	if err := run(strings.TrimPrefix(*version, "v"), *repo, *binDir, *outDir, *maintainer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(version, repo, binDir, outDir, maintainer string) error {
+	rel := release.Release{Version: version, Repo: repo}
+	if version == "" {
+		return fmt.Errorf("--version is required")
+	}
+	if repo == "" {
+		return fmt.Errorf("--repo is required (owner/name of the GitHub repository hosting releases)")
+	}
+
+	date, err := buildDate()
+	if err != nil {
+		return err
+	}
+	cli.Version = version
+	assets, err := generateAssets(date)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	for _, p := range release.Platforms {
+		dir, err := release.Stage(filepath.Join(binDir, p.BuildOutput()), p, version, assets, outDir)
+		if err != nil {
+			return fmt.Errorf("%w (run 'make build-all' first)", err)
+		}
+		artifact, err := release.Archive(dir, p, version, outDir, date)
+		if err != nil {
+			return err
+		}
+		rel.Artifacts = append(rel.Artifacts, artifact)
+		fmt.Printf("%s  %s\n", artifact.SHA256, artifact.Archive)
+	}
+
+	var checksums strings.Builder
+	for _, a := range rel.Artifacts {
+		fmt.Fprintf(&checksums, "%s  %s\n", a.SHA256, a.Archive)
+	}
+	files := map[string]string{"checksums.txt": checksums.String()}
+
+	if files[filepath.Join("packaging", "homebrew", "rw.rb")], err = release.Homebrew(rel); err != nil {
+		return err
+	}
+	if files[filepath.Join("packaging", "scoop", "rw.json")], err = release.Scoop(rel); err != nil {
+		return err
+	}
+	for _, a := range rel.Artifacts {
+		if a.Platform.OS != "linux" {
+			continue
+		}
+		name := filepath.Join("packaging", "nfpm", "rw_"+a.Platform.String()+".yaml")
+		if files[name], err = release.NFPM(rel, a, maintainer); err != nil {
+			return err
+		}
+	}
+
+	for name, content := range files {
+		path := filepath.Join(outDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+// generateAssets renders the completions and man pages shipped in every archive
+func generateAssets(date time.Time) (release.Assets, error) {
+	assets := release.Assets{}
+	for shell, path := range map[string]string{
+		"bash":       release.BashCompletionPath,
+		"zsh":        release.ZshCompletionPath,
+		"fish":       release.FishCompletionPath,
+		"powershell": release.PowerShellCompletionPath,
+	} {
+		script, err := cli.Completion(shell)
+		if err != nil {
+			return nil, err
+		}
+		assets[path] = []byte(script)
+	}
+
+	for name, page := range cli.ManPages(date) {
+		man, err := release.GzipManPage(page)
+		if err != nil {
+			return nil, err
+		}
+		assets[release.ManDir+"/"+name+".gz"] = man
+	}
+	return assets, nil
+}
+
+// buildDate honours SOURCE_DATE_EPOCH so archives are reproducible
+func buildDate() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now().UTC().Truncate(time.Second), nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %s", epoch)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"rolewalkers/cli"
+	"rolewalkers/internal/utils"
 	"rolewalkers/tray"
 )
 
@@ -16,6 +18,7 @@ func main() {
 		}
 	}
 
+	tray.Version = cli.Version
 	tray.Run()
 }
 
@@ -45,5 +48,5 @@ func daemonize() {
 		fmt.Fprintf(os.Stderr, "Warning: could not write PID file: %v\n", err)
 	}
 
-	fmt.Printf("✓ rw-tray started in background (PID %d)\n", cmd.Process.Pid)
+	fmt.Printf(utils.OK()+" rw-tray started in background (PID %d)\n", cmd.Process.Pid)
 }
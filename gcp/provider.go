@@ -0,0 +1,245 @@
+// Package gcp implements provider.Provider for Google Cloud, where profiles
+// are gcloud named configurations (gcloud config configurations list).
+package gcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"rolewalkers/provider"
+)
+
+// ProviderName qualifies gcloud configurations in the provider registry
+const ProviderName = "gcp"
+
+// Environment variables read by gcloud and Google client libraries
+const (
+	configDirEnv    = "CLOUDSDK_CONFIG"
+	activeConfigEnv = "CLOUDSDK_ACTIVE_CONFIG_NAME"
+	projectEnv      = "GOOGLE_CLOUD_PROJECT"
+)
+
+const configPrefix = "config_"
+
+// Provider reads gcloud configurations from the gcloud config directory and
+// uses the gcloud CLI to log in and switch
+type Provider struct {
+	configDir string
+}
+
+var _ provider.Provider = (*Provider)(nil)
+
+// NewProvider creates a provider for the default gcloud config directory:
+// $CLOUDSDK_CONFIG, %APPDATA%\gcloud on Windows, else ~/.config/gcloud
+func NewProvider() *Provider {
+	return NewProviderWithDir(defaultConfigDir())
+}
+
+// NewProviderWithDir creates a provider for a specific config directory
+func NewProviderWithDir(dir string) *Provider {
+	return &Provider{configDir: dir}
+}
+
+func defaultConfigDir() string {
+	if dir := os.Getenv(configDirEnv); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "gcloud")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud")
+}
+
+// Name returns "gcp"
+func (p *Provider) Name() string { return ProviderName }
+
+// DisplayName returns "GCP"
+func (p *Provider) DisplayName() string { return "GCP" }
+
+// Available reports whether gcloud configurations exist or gcloud is installed
+func (p *Provider) Available() bool {
+	if info, err := os.Stat(p.configurationsDir()); err == nil && info.IsDir() {
+		return true
+	}
+	_, err := exec.LookPath("gcloud")
+	return err == nil
+}
+
+func (p *Provider) configurationsDir() string {
+	return filepath.Join(p.configDir, "configurations")
+}
+
+// Profiles returns the gcloud configurations, sorted by name
+func (p *Provider) Profiles() ([]provider.Profile, error) {
+	entries, err := os.ReadDir(p.configurationsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gcloud configurations: %w", err)
+	}
+
+	active := p.ActiveProfile()
+	var profiles []provider.Profile
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), configPrefix)
+		if !ok || e.IsDir() || name == "" {
+			continue
+		}
+
+		props, err := readProperties(filepath.Join(p.configurationsDir(), e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, provider.Profile{
+			Provider: ProviderName,
+			Name:     name,
+			Account:  props["core/project"],
+			Identity: props["core/account"],
+			Region:   props["compute/region"],
+			Active:   name == active,
+		})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// ActiveProfile returns the active configuration: $CLOUDSDK_ACTIVE_CONFIG_NAME,
+// else the active_config file, else "default"
+func (p *Provider) ActiveProfile() string {
+	if name := os.Getenv(activeConfigEnv); name != "" {
+		return name
+	}
+	data, err := os.ReadFile(filepath.Join(p.configDir, "active_config"))
+	if err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name
+		}
+	}
+	return "default"
+}
+
+// Login runs 'gcloud auth login' for the configuration's account
+func (p *Provider) Login(profile string) error {
+	prof, err := provider.FindProfile(p, profile)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"auth", "login"}
+	if prof.Identity != "" {
+		args = append(args, prof.Identity)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return p.runInteractive(ctx, profile, args...)
+}
+
+// Switch activates the configuration for new shells and tools
+func (p *Provider) Switch(profile string) error {
+	if _, err := provider.FindProfile(p, profile); err != nil {
+		return err
+	}
+
+	if err := requireGcloud(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	out, err := p.command(ctx, "", "config", "configurations", "activate", profile).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gcloud config configurations activate failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ExportEnvironment selects the configuration (and its project, for client
+// libraries) in the current shell only
+func (p *Provider) ExportEnvironment(profile string) (map[string]string, error) {
+	prof, err := provider.FindProfile(p, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{activeConfigEnv: profile}
+	if prof.Account != "" {
+		env[projectEnv] = prof.Account
+	}
+	return env, nil
+}
+
+// command builds a gcloud command against this provider's config directory,
+// optionally pinned to a configuration
+func (p *Provider) command(ctx context.Context, profile string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "gcloud", args...)
+	cmd.Env = append(os.Environ(), configDirEnv+"="+p.configDir)
+	if profile != "" {
+		cmd.Env = append(cmd.Env, activeConfigEnv+"="+profile)
+	}
+	return cmd
+}
+
+func (p *Provider) runInteractive(ctx context.Context, profile string, args ...string) error {
+	if err := requireGcloud(); err != nil {
+		return err
+	}
+	cmd := p.command(ctx, profile, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func requireGcloud() error {
+	if _, err := exec.LookPath("gcloud"); err != nil {
+		return fmt.Errorf("gcloud CLI not found in PATH (install the Google Cloud SDK)")
+	}
+	return nil
+}
+
+// readProperties parses a gcloud configuration file into "section/key"
+// properties (e.g. "core/project")
+func readProperties(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	props := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			props[section+"/"+strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return props, scanner.Err()
+}
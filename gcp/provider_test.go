@@ -0,0 +1,82 @@
+package gcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, "configurations", "config_"+name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProviderProfiles(t *testing.T) {
+	t.Setenv(activeConfigEnv, "")
+	dir := t.TempDir()
+	writeConfig(t, dir, "default", "[core]\naccount = me@example.com\nproject = zenith-dev\n")
+	writeConfig(t, dir, "staging", "[core]\nproject = zenith-staging\n\n[compute]\nregion = europe-west2\n")
+	if err := os.WriteFile(filepath.Join(dir, "active_config"), []byte("staging\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProviderWithDir(dir)
+	if !p.Available() {
+		t.Fatal("Available() = false with configurations present")
+	}
+
+	profiles, err := p.Profiles()
+	if err != nil {
+		t.Fatalf("Profiles() error: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("Profiles() returned %d profiles, want 2", len(profiles))
+	}
+
+	def, staging := profiles[0], profiles[1]
+	if def.Name != "default" || def.Account != "zenith-dev" || def.Identity != "me@example.com" || def.Active {
+		t.Errorf("default profile = %+v", def)
+	}
+	if staging.Name != "staging" || staging.Region != "europe-west2" || !staging.Active {
+		t.Errorf("staging profile = %+v", staging)
+	}
+	if staging.QualifiedName() != "gcp:staging" {
+		t.Errorf("QualifiedName() = %q, want gcp:staging", staging.QualifiedName())
+	}
+}
+
+func TestProviderActiveProfileEnv(t *testing.T) {
+	t.Setenv(activeConfigEnv, "ci")
+	p := NewProviderWithDir(t.TempDir())
+	if got := p.ActiveProfile(); got != "ci" {
+		t.Errorf("ActiveProfile() = %q, want ci", got)
+	}
+
+	t.Setenv(activeConfigEnv, "")
+	if got := p.ActiveProfile(); got != "default" {
+		t.Errorf("ActiveProfile() = %q, want default", got)
+	}
+}
+
+func TestProviderExportEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "staging", "[core]\nproject = zenith-staging\n")
+
+	env, err := NewProviderWithDir(dir).ExportEnvironment("staging")
+	if err != nil {
+		t.Fatalf("ExportEnvironment() error: %v", err)
+	}
+	if env[activeConfigEnv] != "staging" || env[projectEnv] != "zenith-staging" {
+		t.Errorf("ExportEnvironment() = %v", env)
+	}
+
+	if _, err := NewProviderWithDir(dir).ExportEnvironment("missing"); err == nil {
+		t.Error("ExportEnvironment() should fail for an unknown configuration")
+	}
+}
@@ -0,0 +1,82 @@
+// Package awsarn parses and builds ARNs in any AWS partition, so GovCloud
+// and China accounts work wherever rw matches or constructs an ARN.
+package awsarn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AWS partitions
+const (
+	PartitionAWS      = "aws"
+	PartitionGovCloud = "aws-us-gov"
+	PartitionChina    = "aws-cn"
+)
+
+// Partitions lists the partitions rw supports
+var Partitions = []string{PartitionAWS, PartitionGovCloud, PartitionChina}
+
+// ValidPartition reports whether p is a supported partition
+func ValidPartition(p string) bool {
+	for _, known := range Partitions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PartitionForRegion returns the partition a region belongs to, e.g.
+// aws-us-gov for us-gov-west-1. Unknown and empty regions are in aws.
+func PartitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionGovCloud
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionChina
+	default:
+		return PartitionAWS
+	}
+}
+
+// ARN is a parsed Amazon Resource Name:
+// arn:partition:service:region:account:resource
+type ARN struct {
+	Partition string
+	Service   string
+	Region    string
+	Account   string
+	Resource  string
+}
+
+// Parse splits an ARN into its parts. It fails for strings that aren't
+// ARNs or whose partition isn't supported.
+func Parse(s string) (ARN, error) {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ARN{}, fmt.Errorf("not an ARN: %q", s)
+	}
+	if !ValidPartition(parts[1]) {
+		return ARN{}, fmt.Errorf("unknown partition %q in ARN %q (valid: %s)", parts[1], s, strings.Join(Partitions, ", "))
+	}
+	if parts[2] == "" || parts[5] == "" {
+		return ARN{}, fmt.Errorf("ARN %q has no service or resource", s)
+	}
+	return ARN{Partition: parts[1], Service: parts[2], Region: parts[3], Account: parts[4], Resource: parts[5]}, nil
+}
+
+// String formats the ARN. An empty partition is derived from the region.
+func (a ARN) String() string {
+	partition := a.Partition
+	if partition == "" {
+		partition = PartitionForRegion(a.Region)
+	}
+	return strings.Join([]string{"arn", partition, a.Service, a.Region, a.Account, a.Resource}, ":")
+}
+
+// Pattern is a regular expression fragment matching any supported
+// partition, for ARN matchers
+func Pattern() string {
+	return "(?:" + strings.Join(Partitions, "|") + ")"
+}
@@ -0,0 +1,47 @@
+package awsarn
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ARN
+	}{
+		{"arn:aws:eks:eu-west-2:123456789012:cluster/dev", ARN{"aws", "eks", "eu-west-2", "123456789012", "cluster/dev"}},
+		{"arn:aws-us-gov:iam::123456789012:role/admin", ARN{"aws-us-gov", "iam", "", "123456789012", "role/admin"}},
+		{"arn:aws-cn:rds:cn-north-1:123456789012:cluster:db", ARN{"aws-cn", "rds", "cn-north-1", "123456789012", "cluster:db"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("Parse(%q).String() = %q", tt.in, got.String())
+		}
+	}
+
+	for _, bad := range []string{"", "dev-cluster", "arn:aws-iso:eks:r:1:cluster/x", "arn:aws:eks:r:1:", "arn:aws:eks"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPartitionForRegion(t *testing.T) {
+	for region, want := range map[string]string{
+		"eu-west-2":      PartitionAWS,
+		"us-gov-west-1":  PartitionGovCloud,
+		"cn-northwest-1": PartitionChina,
+		"":               PartitionAWS,
+	} {
+		if got := PartitionForRegion(region); got != want {
+			t.Errorf("PartitionForRegion(%q) = %q, want %q", region, got, want)
+		}
+	}
+
+	a := ARN{Service: "rds", Region: "us-gov-east-1", Account: "1", Resource: "cluster:db"}
+	if got := a.String(); got != "arn:aws-us-gov:rds:us-gov-east-1:1:cluster:db" {
+		t.Errorf("String() without a partition = %q, want it derived from the region", got)
+	}
+}
@@ -1,6 +1,7 @@
 package awscli
 
 import (
+	"context"
 	"os/exec"
 	"runtime"
 )
@@ -23,3 +24,9 @@ func CreateCommand(args ...string) *exec.Cmd {
 func CreateKubectlCommand(args ...string) *exec.Cmd {
 	return exec.Command("kubectl", args...)
 }
+
+// CreateKubectlCommandContext creates a kubectl command that is killed when
+// ctx is done
+func CreateKubectlCommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "kubectl", args...)
+}
@@ -0,0 +1,90 @@
+// Package changelog holds rw's release notes, embedded in the binary so
+// 'rw changelog' can show what changed after an upgrade without network
+// access.
+package changelog
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"rolewalkers/internal/toolcheck"
+)
+
+//go:embed changelog.md
+var notes string
+
+// Release is one release's notes
+type Release struct {
+	Version string `json:"version"`
+	// Date is the release date as written in the notes, e.g. "2026-10-16"
+	Date string `json:"date,omitempty"`
+	// Notes is the release's markdown, without its heading
+	Notes string `json:"notes"`
+}
+
+// Releases returns the embedded release notes, newest first
+func Releases() ([]Release, error) {
+	return Parse(notes)
+}
+
+// Parse reads release notes: each release starts with a "## <version>"
+// heading, optionally followed by " - <date>". Text before the first
+// release is ignored.
+func Parse(text string) ([]Release, error) {
+	var releases []Release
+	var body []string
+	flush := func() {
+		if len(releases) > 0 {
+			releases[len(releases)-1].Notes = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		heading, ok := strings.CutPrefix(line, "## ")
+		if !ok {
+			body = append(body, line)
+			continue
+		}
+		flush()
+		version, date, _ := strings.Cut(heading, " - ")
+		version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+		if _, ok := toolcheck.ParseVersion(version); !ok {
+			return nil, fmt.Errorf("release heading %q has no version", line)
+		}
+		releases = append(releases, Release{Version: version, Date: strings.TrimSpace(date)})
+	}
+	flush()
+	return releases, nil
+}
+
+// Between returns the releases newer than after and no newer than upTo,
+// newest first. An empty after means no lower bound.
+func Between(releases []Release, after, upTo string) []Release {
+	var selected []Release
+	for _, r := range releases {
+		if after != "" && !Less(after, r.Version) {
+			continue
+		}
+		if Less(upTo, r.Version) {
+			continue
+		}
+		selected = append(selected, r)
+	}
+	return selected
+}
+
+// Less reports whether version a is older than b. Versions that can't be
+// parsed sort before every other version.
+func Less(a, b string) bool {
+	va, okA := toolcheck.ParseVersion(a)
+	vb, okB := toolcheck.ParseVersion(b)
+	switch {
+	case !okB:
+		return false
+	case !okA:
+		return true
+	}
+	return va.Less(vb)
+}
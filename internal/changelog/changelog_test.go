@@ -0,0 +1,85 @@
+package changelog
+
+import "testing"
+
+const sample = `# Release notes
+
+Intro text.
+
+## 1.2.0 - 2026-12-01
+
+### Changed
+
+- Something
+
+## v1.1.0
+
+- Other
+## 1.0.0 - 2026-10-16
+- First
+`
+
+func TestParse(t *testing.T) {
+	releases, err := Parse(sample)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(releases) != 3 {
+		t.Fatalf("Parse() = %d releases, want 3", len(releases))
+	}
+	if r := releases[0]; r.Version != "1.2.0" || r.Date != "2026-12-01" || r.Notes != "### Changed\n\n- Something" {
+		t.Errorf("releases[0] = %+v", r)
+	}
+	if r := releases[1]; r.Version != "1.1.0" || r.Date != "" || r.Notes != "- Other" {
+		t.Errorf("releases[1] = %+v", r)
+	}
+
+	if _, err := Parse("## unreleased\n"); err == nil {
+		t.Error("Parse() should reject a heading without a version")
+	}
+}
+
+func TestBetween(t *testing.T) {
+	releases, _ := Parse(sample)
+	tests := []struct {
+		after, upTo string
+		want        []string
+	}{
+		{"1.0.0", "1.2.0", []string{"1.2.0", "1.1.0"}},
+		{"1.1.0", "1.1.0", nil},
+		{"", "1.1.0", []string{"1.1.0", "1.0.0"}},
+		{"0.9.0", "1.0.5", []string{"1.0.0"}},
+	}
+	for _, tt := range tests {
+		got := Between(releases, tt.after, tt.upTo)
+		var versions []string
+		for _, r := range got {
+			versions = append(versions, r.Version)
+		}
+		if len(versions) != len(tt.want) {
+			t.Errorf("Between(%q, %q) = %v, want %v", tt.after, tt.upTo, versions, tt.want)
+			continue
+		}
+		for i := range versions {
+			if versions[i] != tt.want[i] {
+				t.Errorf("Between(%q, %q) = %v, want %v", tt.after, tt.upTo, versions, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestEmbeddedReleases(t *testing.T) {
+	releases, err := Releases()
+	if err != nil {
+		t.Fatalf("Releases() error: %v", err)
+	}
+	if len(releases) == 0 {
+		t.Fatal("Releases() is empty")
+	}
+	for i := 1; i < len(releases); i++ {
+		if !Less(releases[i].Version, releases[i-1].Version) {
+			t.Errorf("release %s is listed after older release %s", releases[i].Version, releases[i-1].Version)
+		}
+	}
+}
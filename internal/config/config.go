@@ -6,6 +6,7 @@ package config
 import (
 	"rolewalkers/internal/utils"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -35,11 +36,106 @@ type Config struct {
 	// ProfilePrefix is the prefix for AWS profile names (e.g. "zenith-").
 	ProfilePrefix string `yaml:"profile_prefix"`
 
+	// ProfileNameTemplate controls how profiles discovered by 'rw setup' are
+	// named. Placeholders: {account_name}, {account_id}, {role_name}, {role_short}.
+	// e.g. "{account_name}-{role_short}" → "zenith-dev-read-only"
+	ProfileNameTemplate string `yaml:"profile_name_template"`
+
 	// ProductionEnvs lists environment names that require confirmation prompts.
 	ProductionEnvs []string `yaml:"production_envs"`
 
 	// ProdLikeEnvs lists environments that have separate query/command DB clusters.
 	ProdLikeEnvs []string `yaml:"prod_like_envs"`
+
+	// UndoWindow is how long after a maintenance or scaling change 'rw undo'
+	// may revert it, as a Go duration string (default: "15m").
+	UndoWindow string `yaml:"undo_window"`
+
+	// ClipboardClear is how long a secret copied with --copy stays on the
+	// clipboard before it is cleared, as a Go duration string (default: "30s").
+	// Use "0s" to never clear.
+	ClipboardClear string `yaml:"clipboard_clear"`
+
+	// SessionIdleTimeout closes the active role session when no rw command
+	// has run for this long, as a Go duration string (default: "8h"). Use
+	// "0s" to keep sessions open until the next switch.
+	SessionIdleTimeout string `yaml:"session_idle_timeout"`
+
+	// IdPLoginURLs maps an sso-session name (or, for profiles without one,
+	// an SSO start URL) to an IdP-initiated login URL, e.g. an Azure AD
+	// My Apps or Okta app link. 'rw login' opens it instead of the AWS
+	// access portal start URL.
+	IdPLoginURLs map[string]string `yaml:"idp_login_urls"`
+
+	// Quotas are soft daily limits on risky operations, e.g. write tunnels
+	// to production. rw's database is per user, so quotas are per user.
+	Quotas []QuotaConfig `yaml:"quotas"`
+
+	// AuditTunnelConnections records the local process (name, PID, user)
+	// behind every connection to a tunnel for a production environment in
+	// the audit log, as 'rw tunnel start --audit-connections' does.
+	AuditTunnelConnections bool `yaml:"audit_tunnel_connections"`
+
+	// Kubectl limits how fast rw calls the Kubernetes API during bulk
+	// operations such as scaling every HPA.
+	Kubectl KubectlConfig `yaml:"kubectl"`
+
+	// AuditForward sends new audit log entries to a SIEM while rw-tray runs.
+	AuditForward AuditForwardConfig `yaml:"audit_forward"`
+}
+
+// AuditForwardConfig configures where rw-tray forwards audit log entries.
+// Either, both or neither sink may be set.
+type AuditForwardConfig struct {
+	// Webhook is a URL new entries are POSTed to as JSON lines, e.g. a
+	// Splunk HEC raw endpoint or the Datadog log intake.
+	Webhook string `yaml:"webhook"`
+
+	// Headers are sent with every webhook request, e.g. the
+	// "Authorization: Splunk <token>" or "DD-API-KEY" header.
+	Headers map[string]string `yaml:"headers"`
+
+	// Syslog is a syslog server as udp://host:514 or tcp://host:601. Entries
+	// are sent as CEF messages.
+	Syslog string `yaml:"syslog"`
+}
+
+// KubectlConfig rate-limits kubectl calls to the API server. Limits apply
+// per cluster and are shared by everything a single rw process runs.
+type KubectlConfig struct {
+	// QPS is the steady number of calls per second (default: 5). 0 turns
+	// rate limiting off.
+	QPS float64 `yaml:"qps"`
+
+	// Burst is how many calls may run back to back before QPS applies
+	// (default: 10).
+	Burst int `yaml:"burst"`
+
+	// MaxRetries is how often a call throttled by the API server (HTTP 429)
+	// is retried with exponential backoff (default: 5).
+	MaxRetries int `yaml:"max_retries"`
+}
+
+// QuotaConfig is a soft limit on how often an operation may run per day.
+type QuotaConfig struct {
+	// Operation is "tunnel_write" (tunnels to a database's write node) or
+	// "db_restore".
+	Operation string `yaml:"operation"`
+
+	// Environment the quota applies to. Empty applies it to every
+	// environment in ProductionEnvs.
+	Environment string `yaml:"environment"`
+
+	// Service limits a tunnel_write quota to one service (e.g. "db").
+	// Empty counts every service.
+	Service string `yaml:"service"`
+
+	// PerDay is how many times the operation may run per local calendar day.
+	PerDay int `yaml:"per_day"`
+
+	// OnExceed is "warn" (default) to print a warning, or "reason" to
+	// require a reason, which is recorded in the audit log.
+	OnExceed string `yaml:"on_exceed"`
 }
 
 // NamespaceConfig holds Kubernetes namespace settings.
@@ -90,12 +186,16 @@ type ImageConfig struct {
 // Defaults returns a Config with all default values.
 func Defaults() *Config {
 	return &Config{
-		Project:       "zenith",
-		Region:        "eu-west-2",
-		SSMPathPrefix: "/{env}/{project}",
-		ProfilePrefix: "zenith-",
-		ProductionEnvs: []string{"prod", "preprod", "trg", "live"},
-		ProdLikeEnvs:   []string{"prod", "qa", "stage", "preprod", "trg"},
+		Project:             "zenith",
+		Region:              "eu-west-2",
+		SSMPathPrefix:       "/{env}/{project}",
+		ProfilePrefix:       "zenith-",
+		ProfileNameTemplate: "{account_name}-{role_short}",
+		ProductionEnvs:      []string{"prod", "preprod", "trg", "live"},
+		ProdLikeEnvs:        []string{"prod", "qa", "stage", "preprod", "trg"},
+		UndoWindow:          "15m",
+		ClipboardClear:      "30s",
+		SessionIdleTimeout:  "8h",
 		Namespaces: NamespaceConfig{
 			App:         "zenith",
 			Tunnel:      "tunnel-access",
@@ -110,6 +210,11 @@ func Defaults() *Config {
 			RedisUser:    "zenithmaster",
 			RedisPort:    6379,
 		},
+		Kubectl: KubectlConfig{
+			QPS:        5,
+			Burst:      10,
+			MaxRetries: 5,
+		},
 		Images: ImageConfig{
 			Postgres: "postgres:15-alpine",
 			Redis:    "redis:7-alpine",
@@ -198,6 +303,36 @@ func (c *Config) IsProdLikeEnv(env string) bool {
 	return false
 }
 
+// UndoWindowDuration parses UndoWindow, falling back to 15 minutes when the
+// configured value is missing or invalid.
+func (c *Config) UndoWindowDuration() time.Duration {
+	d, err := time.ParseDuration(c.UndoWindow)
+	if err != nil || d <= 0 {
+		return 15 * time.Minute
+	}
+	return d
+}
+
+// ClipboardClearDuration parses ClipboardClear, falling back to 30 seconds
+// when the value is missing or invalid. Zero means never clear.
+func (c *Config) ClipboardClearDuration() time.Duration {
+	d, err := time.ParseDuration(c.ClipboardClear)
+	if err != nil || d < 0 {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// SessionIdleTimeoutDuration parses SessionIdleTimeout, falling back to 8
+// hours when the value is missing or invalid. Zero disables the timeout.
+func (c *Config) SessionIdleTimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(c.SessionIdleTimeout)
+	if err != nil || d < 0 {
+		return 8 * time.Hour
+	}
+	return d
+}
+
 // WriteDefault writes a default config file to ~/.rolewalkers/config.yaml
 // if one doesn't already exist.
 func WriteDefault() error {
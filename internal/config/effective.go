@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"rolewalkers/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources an effective setting's value can come from, highest precedence
+// first: a flag beats an environment variable, which beats config.yaml,
+// which beats what rw recorded in its state, which beats the built-in
+// default.
+const (
+	SourceFlag       = "flag"
+	SourceEnv        = "env"
+	SourceUserConfig = "user config"
+	SourceState      = "state"
+	SourceDefault    = "default"
+)
+
+// Setting is a setting's effective value and where it came from
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// Detail names the flag, variable or file behind Source
+	Detail string `json:"detail,omitempty"`
+}
+
+// Resolution is the effective config.yaml settings
+type Resolution struct {
+	// Path is config.yaml's path, whether or not it exists
+	Path     string
+	Settings []Setting
+	// Unknown lists keys in config.yaml that aren't settings, which are
+	// ignored; usually a typo
+	Unknown []string
+}
+
+// Effective resolves every config.yaml setting, by dotted key (e.g.
+// database.port), to its value and whether config.yaml or the default set
+// it. An invalid config.yaml is ignored by Load, so the error is returned
+// alongside the defaults rather than instead of them.
+func Effective() (*Resolution, error) {
+	res := &Resolution{Path: configFileName}
+	if dir, err := utils.StateDir(); err == nil {
+		res.Path = filepath.Join(dir, configFileName)
+	}
+
+	fileKeys := map[string]bool{}
+	var parseErr error
+	if data, err := utils.ReadRoleWalkersFile(configFileName); err == nil {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			parseErr = fmt.Errorf("%s is invalid and was ignored: %w", res.Path, err)
+		} else if len(doc.Content) > 0 {
+			flattenNode("", doc.Content[0], func(key, _ string) { fileKeys[key] = true })
+		}
+	}
+
+	var effective yaml.Node
+	if err := effective.Encode(Get()); err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	flattenNode("", &effective, func(key, value string) {
+		known[key] = true
+		s := Setting{Key: key, Value: value, Source: SourceDefault}
+		if fileKeys[key] {
+			s.Source, s.Detail = SourceUserConfig, res.Path
+		}
+		res.Settings = append(res.Settings, s)
+	})
+
+	for key := range fileKeys {
+		if !known[key] && !hasKeyPrefix(known, key+".") {
+			res.Unknown = append(res.Unknown, key)
+		}
+	}
+	slices.Sort(res.Unknown)
+	return res, parseErr
+}
+
+// flattenNode calls emit for every leaf of a YAML mapping, keyed by its
+// dotted path. Lists are leaves, printed in flow style.
+func flattenNode(prefix string, n *yaml.Node, emit func(key, value string)) {
+	switch {
+	case n.Kind == yaml.MappingNode && len(n.Content) > 0:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenNode(key, n.Content[i+1], emit)
+		}
+	case n.Kind == yaml.ScalarNode:
+		emit(prefix, n.Value)
+	default:
+		setFlowStyle(n)
+		out, err := yaml.Marshal(n)
+		if err != nil {
+			return
+		}
+		emit(prefix, strings.TrimSpace(string(out)))
+	}
+}
+
+func setFlowStyle(n *yaml.Node) {
+	n.Style = yaml.FlowStyle
+	for _, c := range n.Content {
+		setFlowStyle(c)
+	}
+}
+
+func hasKeyPrefix(keys map[string]bool, prefix string) bool {
+	for k := range keys {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
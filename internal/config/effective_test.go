@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEffective(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("RW_STATE_DIR", dir)
+	yaml := "region: us-east-1\ndatabase:\n  port: 6432\nproduction_envs: [prod, live]\nregoin: typo\n"
+	if err := os.WriteFile(filepath.Join(dir, configFileName), []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	Reset()
+	defer Reset()
+
+	res, err := Effective()
+	if err != nil {
+		t.Fatalf("Effective() error: %v", err)
+	}
+	if res.Path != filepath.Join(dir, configFileName) {
+		t.Errorf("Path = %q, want config.yaml in the state dir", res.Path)
+	}
+	if !slices.Equal(res.Unknown, []string{"regoin"}) {
+		t.Errorf("Unknown = %v, want [regoin]", res.Unknown)
+	}
+
+	want := map[string]Setting{
+		"region":          {Value: "us-east-1", Source: SourceUserConfig},
+		"database.port":   {Value: "6432", Source: SourceUserConfig},
+		"production_envs": {Value: "[prod, live]", Source: SourceUserConfig},
+		"project":         {Value: Defaults().Project, Source: SourceDefault},
+		"kubectl.burst":   {Value: "10", Source: SourceDefault},
+	}
+	for _, s := range res.Settings {
+		w, ok := want[s.Key]
+		if !ok {
+			continue
+		}
+		delete(want, s.Key)
+		if s.Value != w.Value || s.Source != w.Source {
+			t.Errorf("%s = %q from %s, want %q from %s", s.Key, s.Value, s.Source, w.Value, w.Source)
+		}
+	}
+	for key := range want {
+		t.Errorf("setting %s is missing", key)
+	}
+}
+
+func TestEffectiveInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("RW_STATE_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, configFileName), []byte("region: [unclosed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	Reset()
+	defer Reset()
+
+	res, err := Effective()
+	if err == nil {
+		t.Fatal("Effective() with an invalid config.yaml succeeded, want an error")
+	}
+	for _, s := range res.Settings {
+		if s.Source != SourceDefault {
+			t.Errorf("%s comes from %s, want every setting from the default", s.Key, s.Source)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package confirm asks the user to confirm risky operations. Every prompt
+// goes through a Prompter, so tests can supply the answers; the package
+// functions use Default, which reads stdin and writes stdout.
+package confirm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"rolewalkers/internal/messages"
+	"rolewalkers/internal/utils"
+)
+
+// Prompter reads answers from in and writes prompts to out
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// New creates a prompter
+func New(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Default prompts on the terminal
+var Default = New(os.Stdin, os.Stdout)
+
+// Operation is a risky operation described by catalog messages, shown as
+// warnings before the "Type 'yes'" prompt
+type Operation interface {
+	Warnings() []messages.Message
+}
+
+// DatabaseRestore restores a backup over an environment's database
+type DatabaseRestore struct {
+	Env   string
+	Input string
+}
+
+func (o DatabaseRestore) Warnings() []messages.Message {
+	return []messages.Message{messages.New(messages.ConfirmDatabaseRestore, "env", o.Env, "input", o.Input)}
+}
+
+// ReplicationSwitch switches traffic over to a Blue-Green deployment's target
+type ReplicationSwitch struct {
+	Deployment string
+	Source     string
+	Target     string
+}
+
+func (o ReplicationSwitch) Warnings() []messages.Message {
+	return []messages.Message{messages.New(messages.ConfirmReplicationSwitch,
+		"deployment", o.Deployment, "source", o.Source, "target", o.Target)}
+}
+
+// ReplicationCreate creates a Blue-Green deployment from a source cluster
+type ReplicationCreate struct {
+	Name   string
+	Source string
+}
+
+func (o ReplicationCreate) Warnings() []messages.Message {
+	return []messages.Message{messages.New(messages.ConfirmReplicationCreate, "name", o.Name, "source", o.Source)}
+}
+
+// ReplicationDelete deletes a Blue-Green deployment, and with DeleteTarget
+// its target cluster
+type ReplicationDelete struct {
+	Deployment   string
+	DeleteTarget bool
+}
+
+func (o ReplicationDelete) Warnings() []messages.Message {
+	warnings := []messages.Message{messages.New(messages.ConfirmReplicationDelete, "deployment", o.Deployment)}
+	if o.DeleteTarget {
+		warnings = append(warnings, messages.New(messages.ConfirmReplicationDeleteTarget))
+	}
+	return warnings
+}
+
+// Yes prints message and reports whether the user typed "yes"
+func (p *Prompter) Yes(message string) bool {
+	fmt.Fprint(p.out, message)
+	response, err := p.in.ReadString('\n')
+	if err != nil && response == "" {
+		return false
+	}
+	return strings.TrimSpace(strings.ToLower(response)) == "yes"
+}
+
+// Line prints label and returns the line typed, trimmed
+func (p *Prompter) Line(label string) (string, error) {
+	fmt.Fprint(p.out, label)
+	response, err := p.in.ReadString('\n')
+	if err != nil && response == "" {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// Confirm shows an operation's warnings and asks for "yes"
+func (p *Prompter) Confirm(op Operation) bool {
+	var sb strings.Builder
+	for i, w := range op.Warnings() {
+		if i == 0 {
+			fmt.Fprintf(&sb, "\n%s  %s", utils.Warn(), w)
+		} else {
+			fmt.Fprintf(&sb, "\n   %s  %s", utils.Warn(), w)
+		}
+	}
+	fmt.Fprintf(&sb, "\n\n   %s ", messages.Render(messages.ConfirmTypeYes, nil))
+	return p.Yes(sb.String())
+}
+
+// Production asks for "yes" below a warning banner when env is one of
+// prodEnvs. Other environments need no confirmation.
+func (p *Prompter) Production(env, operation string, prodEnvs ...string) bool {
+	if !IsProduction(env, prodEnvs...) {
+		return true
+	}
+
+	fmt.Fprintln(p.out)
+	if utils.PlainEnabled() {
+		// A single line; the banner's padding rows read as blank lines
+		fmt.Fprintln(p.out, messages.Render(messages.ConfirmProductionTitle, nil))
+	} else {
+		// The siren emoji is one rune but two columns wide
+		siren, width := "🚨", 66
+		if !utils.UnicodeEnabled() {
+			siren, width = "!!", 68
+		}
+		blank := strings.Repeat(" ", 68)
+		title := fmt.Sprintf("  %s  %s  %s", siren, messages.Render(messages.ConfirmProductionTitle, nil), siren)
+		fmt.Fprintln(p.out, utils.Danger(blank))
+		fmt.Fprintln(p.out, utils.Danger(fmt.Sprintf("%-*s", width, title)))
+		fmt.Fprintln(p.out, utils.Danger(blank))
+	}
+	fmt.Fprintln(p.out)
+
+	envLabel := messages.Render(messages.ConfirmProductionEnvironment, nil)
+	opLabel := messages.Render(messages.ConfirmProductionOperation, nil)
+	labelWidth := max(len(envLabel), len(opLabel))
+	fmt.Fprintf(p.out, "%s %s\n", utils.BoldRed(fmt.Sprintf("%-*s", labelWidth, envLabel)), strings.ToUpper(env))
+	fmt.Fprintf(p.out, "%s %s\n\n", utils.BoldRed(fmt.Sprintf("%-*s", labelWidth, opLabel)), operation)
+	fmt.Fprintln(p.out, messages.Render(messages.ConfirmProductionBody, nil))
+
+	return p.Yes(fmt.Sprintf("\n%s ", utils.BoldRed(messages.Render(messages.ConfirmTypeYes, nil))))
+}
+
+// IsProduction reports whether env is one of prodEnvs, ignoring case
+func IsProduction(env string, prodEnvs ...string) bool {
+	env = strings.ToLower(env)
+	for _, prodEnv := range prodEnvs {
+		if env == prodEnv {
+			return true
+		}
+	}
+	return false
+}
+
+// Yes prints message and reports whether the user typed "yes"
+func Yes(message string) bool { return Default.Yes(message) }
+
+// Line prints label and returns the line typed, trimmed
+func Line(label string) (string, error) { return Default.Line(label) }
+
+// Confirm shows an operation's warnings and asks for "yes"
+func Confirm(op Operation) bool { return Default.Confirm(op) }
+
+// Production asks for "yes" below a warning banner when env is one of
+// prodEnvs
+func Production(env, operation string, prodEnvs ...string) bool {
+	return Default.Production(env, operation, prodEnvs...)
+}
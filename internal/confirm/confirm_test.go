@@ -0,0 +1,103 @@
+package confirm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsProduction(t *testing.T) {
+	prodEnvs := []string{"prod", "preprod", "trg", "live"}
+
+	tests := []struct {
+		name     string
+		env      string
+		expected bool
+	}{
+		{"prod", "prod", true},
+		{"preprod", "preprod", true},
+		{"trg", "trg", true},
+		{"live", "live", true},
+		{"dev", "dev", false},
+		{"sit", "sit", false},
+		{"qa", "qa", false},
+		{"snd", "snd", false},
+		{"case insensitive", "PROD", true},
+		{"case insensitive preprod", "PreProd", true},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IsProduction(tt.env, prodEnvs...)
+			if result != tt.expected {
+				t.Errorf("IsProduction(%q) = %v, want %v", tt.env, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrompterYes(t *testing.T) {
+	for input, want := range map[string]bool{
+		"yes\n":   true,
+		" YES \n": true,
+		"yes":     true,
+		"y\n":     false,
+		"no\n":    false,
+		"":        false,
+	} {
+		var out bytes.Buffer
+		if got := New(strings.NewReader(input), &out).Yes("Continue? "); got != want {
+			t.Errorf("Yes() with input %q = %v, want %v", input, got, want)
+		}
+		if out.String() != "Continue? " {
+			t.Errorf("Yes() printed %q, want the message", out.String())
+		}
+	}
+}
+
+func TestPrompterConfirm(t *testing.T) {
+	var out bytes.Buffer
+	p := New(strings.NewReader("yes\n"), &out)
+	if !p.Confirm(ReplicationDelete{Deployment: "bgd-123", DeleteTarget: true}) {
+		t.Fatal("Confirm() = false, want true")
+	}
+	text := out.String()
+	if !strings.Contains(text, "bgd-123") || !strings.Contains(text, "Target cluster will also be DELETED!") {
+		t.Errorf("Confirm() printed %q, want both warnings", text)
+	}
+	if !strings.HasSuffix(text, "Type 'yes' to confirm: ") {
+		t.Errorf("Confirm() printed %q, want it to end with the prompt", text)
+	}
+
+	out.Reset()
+	p = New(strings.NewReader("no\n"), &out)
+	if p.Confirm(DatabaseRestore{Env: "dev", Input: "backup.sql"}) {
+		t.Error("Confirm() with 'no' = true, want false")
+	}
+	if !strings.Contains(out.String(), "Input file:  backup.sql") {
+		t.Errorf("Confirm() printed %q, want the restore details", out.String())
+	}
+}
+
+func TestPrompterProduction(t *testing.T) {
+	var out bytes.Buffer
+	if !New(strings.NewReader(""), &out).Production("dev", "Database Restore", "prod") {
+		t.Error("Production() outside production = false, want true without a prompt")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Production() outside production printed %q", out.String())
+	}
+
+	p := New(strings.NewReader("yes\n"), &out)
+	if !p.Production("prod", "Database Restore", "prod") {
+		t.Error("Production() with 'yes' = false, want true")
+	}
+	if !strings.Contains(out.String(), "PRODUCTION ENVIRONMENT DETECTED") || !strings.Contains(out.String(), "Database Restore") {
+		t.Errorf("Production() printed %q, want the banner and operation", out.String())
+	}
+
+	if New(strings.NewReader("\n"), &out).Production("prod", "Database Restore", "prod") {
+		t.Error("Production() with an empty answer = true, want false")
+	}
+}
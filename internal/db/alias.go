@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Alias represents a user-defined command shortcut
+type Alias struct {
+	ID        int
+	Name      string
+	Expansion string
+}
+
+// GetAlias retrieves an alias by name
+func (r *ConfigRepository) GetAlias(name string) (*Alias, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	alias := &Alias{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, expansion
+		FROM aliases
+		WHERE name = ?
+	`, name).Scan(&alias.ID, &alias.Name, &alias.Expansion)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("alias not found: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return alias, nil
+}
+
+// GetAllAliases retrieves all aliases ordered by name
+func (r *ConfigRepository) GetAllAliases() ([]Alias, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, expansion
+		FROM aliases
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []Alias
+	for rows.Next() {
+		var alias Alias
+		if err := rows.Scan(&alias.ID, &alias.Name, &alias.Expansion); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, rows.Err()
+}
+
+// SetAlias creates an alias or replaces the expansion of an existing one
+func (r *ConfigRepository) SetAlias(name, expansion string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO aliases (name, expansion) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET expansion = excluded.expansion
+	`, name, expansion)
+	return err
+}
+
+// DeleteAlias removes an alias by name
+func (r *ConfigRepository) DeleteAlias(name string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM aliases WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("alias not found: %s", name)
+	}
+
+	return nil
+}
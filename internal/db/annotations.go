@@ -0,0 +1,125 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ownership is descriptive metadata for an account or role
+type Ownership struct {
+	Description string
+	Owner       string
+	Team        string
+	Contact     string
+}
+
+// IsZero reports whether no field is set
+func (o Ownership) IsZero() bool {
+	return o == Ownership{}
+}
+
+// ProfileAnnotation is the ownership metadata of a profile's role and of
+// the account it belongs to
+type ProfileAnnotation struct {
+	ProfileName string
+	AccountID   string
+	AccountName string
+	Account     Ownership
+	Role        Ownership
+}
+
+// Effective returns the role's metadata, with unset owner, team and contact
+// inherited from the account
+func (a ProfileAnnotation) Effective() Ownership {
+	return Ownership{
+		Description: a.Role.Description,
+		Owner:       cmp.Or(a.Role.Owner, a.Account.Owner),
+		Team:        cmp.Or(a.Role.Team, a.Account.Team),
+		Contact:     cmp.Or(a.Role.Contact, a.Account.Contact),
+	}
+}
+
+// GetProfileAnnotations returns ownership metadata for every active role,
+// keyed by profile name
+func (r *ConfigRepository) GetProfileAnnotations() (map[string]ProfileAnnotation, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.profile_name, a.account_id, a.account_name,
+			COALESCE(a.description, ''), COALESCE(a.owner, ''), COALESCE(a.team, ''), COALESCE(a.contact, ''),
+			COALESCE(r.description, ''), COALESCE(r.owner, ''), COALESCE(r.team, ''), COALESCE(r.contact, '')
+		FROM aws_roles r
+		JOIN aws_accounts a ON r.account_id = a.id
+		WHERE r.active = 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	annotations := make(map[string]ProfileAnnotation)
+	for rows.Next() {
+		var a ProfileAnnotation
+		if err := rows.Scan(&a.ProfileName, &a.AccountID, &a.AccountName,
+			&a.Account.Description, &a.Account.Owner, &a.Account.Team, &a.Account.Contact,
+			&a.Role.Description, &a.Role.Owner, &a.Role.Team, &a.Role.Contact); err != nil {
+			return nil, err
+		}
+		annotations[a.ProfileName] = a
+	}
+
+	return annotations, rows.Err()
+}
+
+// ownershipColumns are the columns AnnotateRole and AnnotateAccount may set
+var ownershipColumns = map[string]bool{
+	"description": true, "owner": true, "team": true, "contact": true,
+}
+
+// AnnotateRole sets ownership columns on the role for a profile. An empty
+// value clears the column.
+func (r *ConfigRepository) AnnotateRole(profileName string, updates map[string]string) error {
+	return r.annotate("aws_roles", "profile_name", profileName, "role", updates)
+}
+
+// AnnotateAccount sets ownership columns on an account. An empty value
+// clears the column.
+func (r *ConfigRepository) AnnotateAccount(accountID string, updates map[string]string) error {
+	return r.annotate("aws_accounts", "account_id", accountID, "AWS account", updates)
+}
+
+func (r *ConfigRepository) annotate(table, keyColumn, key, kind string, updates map[string]string) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var setClauses []string
+	var args []any
+	for column, value := range updates {
+		if !ownershipColumns[column] {
+			return fmt.Errorf("invalid column name: %s", column)
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", column))
+		args = append(args, sql.NullString{String: value, Valid: value != ""})
+	}
+	setClauses = append(setClauses, "updated_at = CURRENT_TIMESTAMP")
+	args = append(args, key)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ? AND active = 1", table, strings.Join(setClauses, ", "), keyColumn)
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s not found: %s", kind, key)
+	}
+	return nil
+}
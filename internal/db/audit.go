@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"rolewalkers/internal/utils"
+	"strings"
+	"time"
+)
+
+// AuditEntry represents a single mutating operation recorded in the audit log
+type AuditEntry struct {
+	ID            int
+	Action        string
+	Environment   string
+	Target        string
+	PreviousState sql.NullString
+	NewState      sql.NullString
+	MessageID     sql.NullString
+	MessageParams sql.NullString
+	UndoneAt      sql.NullTime
+	CreatedAt     time.Time
+}
+
+// RecordAudit appends an entry to the audit log and returns its ID.
+// previousState and newState are free-form (usually JSON) and may be empty.
+// Tracked secrets are always masked before they reach the database.
+func (r *ConfigRepository) RecordAudit(action, environment, target, previousState, newState string) (int64, error) {
+	return r.RecordAuditMessage(action, environment, target, previousState, newState, "", "")
+}
+
+// RecordAuditMessage is RecordAudit with a description stored as a message
+// catalog ID and its JSON-encoded parameters (see internal/messages).
+func (r *ConfigRepository) RecordAuditMessage(action, environment, target, previousState, newState, messageID, messageParams string) (int64, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	target = utils.Redact(target)
+	previousState = utils.Redact(previousState)
+	newState = utils.Redact(newState)
+	messageParams = utils.Redact(messageParams)
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_log (action, environment, target, previous_state, new_state, message_id, message_params)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, action, environment, target,
+		sql.NullString{String: previousState, Valid: previousState != ""},
+		sql.NullString{String: newState, Valid: newState != ""},
+		sql.NullString{String: messageID, Valid: messageID != ""},
+		sql.NullString{String: messageParams, Valid: messageParams != ""})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// GetLastAuditEntry retrieves the most recent audit entry for one of the given
+// actions that has not been undone yet
+func (r *ConfigRepository) GetLastAuditEntry(actions ...string) (*AuditEntry, error) {
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("at least one action is required")
+	}
+
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(actions)), ",")
+	args := make([]interface{}, len(actions))
+	for i, a := range actions {
+		args[i] = a
+	}
+
+	entry := &AuditEntry{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, action, environment, target, previous_state, new_state, message_id, message_params, undone_at, created_at
+		FROM audit_log
+		WHERE action IN (`+placeholders+`) AND undone_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`, args...).Scan(&entry.ID, &entry.Action, &entry.Environment, &entry.Target,
+		&entry.PreviousState, &entry.NewState, &entry.MessageID, &entry.MessageParams, &entry.UndoneAt, &entry.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no audit entries found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// MarkAuditUndone flags an audit entry as reverted so it is not undone twice
+func (r *ConfigRepository) MarkAuditUndone(id int) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE audit_log SET undone_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND undone_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("audit entry not found or already undone: %d", id)
+	}
+
+	return nil
+}
+
+// CountAuditEntries counts the audit entries for an action in an
+// environment since the given time. An empty target counts every target.
+func (r *ConfigRepository) CountAuditEntries(action, environment, target string, since time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM audit_log
+		WHERE action = ? AND environment = ? AND (? = '' OR target = ?) AND created_at >= ?
+	`, action, environment, target, target, since.UTC().Format("2006-01-02 15:04:05")).Scan(&count)
+	return count, err
+}
+
+// ListAuditEntries returns audit entries recorded at or after since, oldest
+// first. A zero since returns the whole log.
+func (r *ConfigRepository) ListAuditEntries(since time.Time) ([]AuditEntry, error) {
+	// created_at is stored by SQLite as UTC "YYYY-MM-DD HH:MM:SS"
+	return r.listAuditEntries(`created_at >= ?`, since.UTC().Format("2006-01-02 15:04:05"))
+}
+
+// ListAuditEntriesAfter returns audit entries with an ID above afterID,
+// oldest first
+func (r *ConfigRepository) ListAuditEntriesAfter(afterID int) ([]AuditEntry, error) {
+	return r.listAuditEntries(`id > ?`, afterID)
+}
+
+// LastAuditID returns the ID of the newest audit entry, or 0 if the log is
+// empty
+func (r *ConfigRepository) LastAuditID() (int, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var id int
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM audit_log`).Scan(&id)
+	return id, err
+}
+
+func (r *ConfigRepository) listAuditEntries(where string, arg interface{}) ([]AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 30*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, action, environment, target, previous_state, new_state, message_id, message_params, undone_at, created_at
+		FROM audit_log
+		WHERE `+where+`
+		ORDER BY id
+	`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.Environment, &entry.Target,
+			&entry.PreviousState, &entry.NewState, &entry.MessageID, &entry.MessageParams, &entry.UndoneAt, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
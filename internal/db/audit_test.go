@@ -0,0 +1,139 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigRepository_AuditUndoRoundTrip(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	id, err := repo.RecordAudit("test-action", "test-env", "test-target", `{"min":1}`, `{"min":2}`)
+	if err != nil {
+		t.Fatalf("RecordAudit() error: %v", err)
+	}
+
+	entry, err := repo.GetLastAuditEntry("test-action")
+	if err != nil {
+		t.Fatalf("GetLastAuditEntry() error: %v", err)
+	}
+	if int64(entry.ID) != id {
+		t.Errorf("GetLastAuditEntry().ID = %d, want %d", entry.ID, id)
+	}
+	if entry.PreviousState.String != `{"min":1}` {
+		t.Errorf("GetLastAuditEntry().PreviousState = %q, want %q", entry.PreviousState.String, `{"min":1}`)
+	}
+
+	if err := repo.MarkAuditUndone(entry.ID); err != nil {
+		t.Fatalf("MarkAuditUndone() error: %v", err)
+	}
+	if err := repo.MarkAuditUndone(entry.ID); err == nil {
+		t.Error("MarkAuditUndone() should fail for an entry that is already undone")
+	}
+}
+
+func TestConfigRepository_RecordAuditMessage(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	defer database.Exec(`DELETE FROM audit_log WHERE action = 'test-message'`)
+
+	if _, err := repo.RecordAuditMessage("test-message", "test-env", "test-target", "", "",
+		"audit.scale.preset", `{"env":"test-env","preset":"normal"}`); err != nil {
+		t.Fatalf("RecordAuditMessage() error: %v", err)
+	}
+
+	entry, err := repo.GetLastAuditEntry("test-message")
+	if err != nil {
+		t.Fatalf("GetLastAuditEntry() error: %v", err)
+	}
+	if entry.MessageID.String != "audit.scale.preset" {
+		t.Errorf("MessageID = %q, want %q", entry.MessageID.String, "audit.scale.preset")
+	}
+	if entry.MessageParams.String != `{"env":"test-env","preset":"normal"}` {
+		t.Errorf("MessageParams = %q", entry.MessageParams.String)
+	}
+}
+
+func TestConfigRepository_ListAuditEntries(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	for _, target := range []string{"api", "web"} {
+		if _, err := repo.RecordAudit("scale", "prod", target, "", ""); err != nil {
+			t.Fatalf("RecordAudit() error: %v", err)
+		}
+	}
+
+	entries, err := repo.ListAuditEntries(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListAuditEntries() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Target != "api" || entries[1].Target != "web" {
+		t.Errorf("ListAuditEntries() = %+v, want api then web", entries)
+	}
+
+	entries, err = repo.ListAuditEntries(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ListAuditEntries() error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("ListAuditEntries(future) = %d entries, want 0", len(entries))
+	}
+}
+
+func TestConfigRepository_CountAuditEntries(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	for _, e := range []struct{ action, env, target string }{
+		{"tunnel_write", "prod", "db"},
+		{"tunnel_write", "prod", "db"},
+		{"tunnel_write", "prod", "redis"},
+		{"tunnel_write", "dev", "db"},
+		{"scale", "prod", "db"},
+	} {
+		if _, err := repo.RecordAudit(e.action, e.env, e.target, "", ""); err != nil {
+			t.Fatalf("RecordAudit() error: %v", err)
+		}
+	}
+
+	since := time.Now().Add(-time.Hour)
+	for _, tt := range []struct {
+		target string
+		want   int
+	}{{"", 3}, {"db", 2}, {"kafka", 0}} {
+		got, err := repo.CountAuditEntries("tunnel_write", "prod", tt.target, since)
+		if err != nil {
+			t.Fatalf("CountAuditEntries() error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("CountAuditEntries(target %q) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+
+	if got, _ := repo.CountAuditEntries("tunnel_write", "prod", "", time.Now().Add(time.Hour)); got != 0 {
+		t.Errorf("CountAuditEntries(future) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BackupRecord is a catalog entry for a database backup
+type BackupRecord struct {
+	ID          int
+	Environment string
+	Location    string // local file path or s3://bucket/key
+	SizeBytes   int64
+	SchemaOnly  bool
+	DurationMS  int64
+	ContentHash string // sha256 of the dump, hex encoded
+	Status      string // BackupComplete or BackupInterrupted
+	CreatedAt   time.Time
+}
+
+// Backup statuses
+const (
+	BackupComplete = "complete"
+	// BackupInterrupted backups stopped part-way; Location is the partial
+	// file and ContentHash is empty
+	BackupInterrupted = "interrupted"
+)
+
+// Interrupted reports whether the backup stopped part-way
+func (b BackupRecord) Interrupted() bool {
+	return b.Status == BackupInterrupted
+}
+
+const backupColumns = `id, environment, location, size_bytes, schema_only, duration_ms, content_hash, status, created_at`
+
+func scanBackup(scanner interface{ Scan(...any) error }, b *BackupRecord) error {
+	return scanner.Scan(&b.ID, &b.Environment, &b.Location, &b.SizeBytes,
+		&b.SchemaOnly, &b.DurationMS, &b.ContentHash, &b.Status, &b.CreatedAt)
+}
+
+// RecordBackup adds a backup to the catalog and returns its ID
+func (r *ConfigRepository) RecordBackup(b BackupRecord) (int64, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO backups (environment, location, size_bytes, schema_only, duration_ms, content_hash, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, b.Environment, b.Location, b.SizeBytes, b.SchemaOnly, b.DurationMS, b.ContentHash, cmp.Or(b.Status, BackupComplete))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetBackup retrieves a catalog entry by ID
+func (r *ConfigRepository) GetBackup(id int) (*BackupRecord, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	b := &BackupRecord{}
+	row := r.db.QueryRowContext(ctx, `SELECT `+backupColumns+` FROM backups WHERE id = ?`, id)
+	err := scanBackup(row, b)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("backup not found: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListBackups returns catalog entries, newest first. An empty environment
+// lists backups of all environments.
+func (r *ConfigRepository) ListBackups(environment string) ([]BackupRecord, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+backupColumns+`
+		FROM backups
+		WHERE ? = '' OR environment = ?
+		ORDER BY created_at DESC, id DESC
+	`, environment, environment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backups []BackupRecord
+	for rows.Next() {
+		var b BackupRecord
+		if err := scanBackup(rows, &b); err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	return backups, rows.Err()
+}
+
+// DeleteBackup removes a catalog entry. It does not touch the backup file.
+func (r *ConfigRepository) DeleteBackup(id int) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM backups WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("backup not found: %d", id)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package db
+
+import "testing"
+
+func TestConfigRepository_BackupCatalog(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	id, err := repo.RecordBackup(BackupRecord{
+		Environment: "test-backup-env",
+		Location:    "/tmp/test-backup.sql",
+		SizeBytes:   1234,
+		SchemaOnly:  true,
+		DurationMS:  42,
+		ContentHash: "abc123",
+	})
+	if err != nil {
+		t.Fatalf("RecordBackup() error: %v", err)
+	}
+	defer repo.DeleteBackup(int(id))
+
+	b, err := repo.GetBackup(int(id))
+	if err != nil {
+		t.Fatalf("GetBackup() error: %v", err)
+	}
+	if b.Location != "/tmp/test-backup.sql" || b.SizeBytes != 1234 || !b.SchemaOnly || b.ContentHash != "abc123" {
+		t.Errorf("GetBackup() = %+v, fields don't match what was recorded", b)
+	}
+	if b.Status != BackupComplete || b.Interrupted() {
+		t.Errorf("GetBackup().Status = %q, want %q by default", b.Status, BackupComplete)
+	}
+
+	partialID, err := repo.RecordBackup(BackupRecord{
+		Environment: "test-backup-env-partial",
+		Location:    "/tmp/test-backup.sql.partial",
+		Status:      BackupInterrupted,
+	})
+	if err != nil {
+		t.Fatalf("RecordBackup() interrupted error: %v", err)
+	}
+	defer repo.DeleteBackup(int(partialID))
+	if p, err := repo.GetBackup(int(partialID)); err != nil || !p.Interrupted() {
+		t.Errorf("GetBackup() interrupted = %+v, %v, want an interrupted backup", p, err)
+	}
+
+	backups, err := repo.ListBackups("test-backup-env")
+	if err != nil {
+		t.Fatalf("ListBackups() error: %v", err)
+	}
+	if len(backups) != 1 || backups[0].ID != int(id) {
+		t.Errorf("ListBackups() = %+v, want the recorded backup only", backups)
+	}
+
+	if err := repo.DeleteBackup(int(id)); err != nil {
+		t.Fatalf("DeleteBackup() error: %v", err)
+	}
+	if _, err := repo.GetBackup(int(id)); err == nil {
+		t.Error("GetBackup() should fail after DeleteBackup()")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"rolewalkers/internal/utils"
 	"text/tabwriter"
 )
 
@@ -15,17 +16,17 @@ func InitDB() error {
 	}
 	defer db.Close()
 
-	fmt.Println("✓ Database initialized successfully")
+	fmt.Println(utils.OK() + " Database initialized successfully")
 	return nil
 }
 
 // GetDBPath returns the path to the database file
 func GetDBPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	dir, err := utils.StateDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(homeDir, ".rolewalkers", "config.db"), nil
+	return filepath.Join(dir, "config.db"), nil
 }
 
 // ShowConfig displays the current configuration from the database
@@ -3,11 +3,17 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+
+	"rolewalkers/internal/awsarn"
 )
 
+// ErrRoleNotFound is returned when no active role has the profile name
+var ErrRoleNotFound = errors.New("role not found")
+
 // Environment represents an environment configuration
 type Environment struct {
 	ID          int
@@ -241,6 +247,55 @@ func (r *ConfigRepository) GetPortMappingsByService(serviceName string) ([]PortM
 	return mappings, rows.Err()
 }
 
+// GetAllPortMappings retrieves all active port mappings
+func (r *ConfigRepository) GetAllPortMappings() ([]PortMapping, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, service_id, environment_id, local_port, remote_port, description, active
+		FROM port_mappings
+		WHERE active = 1
+		ORDER BY local_port
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []PortMapping
+	for rows.Next() {
+		var pm PortMapping
+		if err := rows.Scan(&pm.ID, &pm.ServiceID, &pm.EnvironmentID, &pm.LocalPort, &pm.RemotePort, &pm.Description, &pm.Active); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, pm)
+	}
+
+	return mappings, rows.Err()
+}
+
+// AddPortMapping adds a port mapping for a service/environment, using the
+// service's default remote port. Existing mappings are left untouched.
+func (r *ConfigRepository) AddPortMapping(serviceName, envName string, localPort int, description string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO port_mappings (service_id, environment_id, local_port, remote_port, description)
+		SELECT s.id, e.id, ?, s.default_remote_port, ?
+		FROM services s, environments e
+		WHERE s.name = ? AND e.name = ?
+	`, localPort, description, serviceName, envName)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("port mapping for service %s in environment %s not added (unknown service/environment or already mapped)", serviceName, envName)
+	}
+	return nil
+}
+
 // GetScalingPreset retrieves a scaling preset by name
 func (r *ConfigRepository) GetScalingPreset(name string) (*ScalingPreset, error) {
 	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
@@ -355,6 +410,8 @@ type AWSAccount struct {
 	SSORegion    sql.NullString
 	Description  sql.NullString
 	Active       bool
+	// Partition is the account's AWS partition: aws, aws-us-gov or aws-cn
+	Partition string
 }
 
 // AWSRole represents an AWS role within an account
@@ -376,6 +433,8 @@ type UserSession struct {
 	SessionStart string
 	SessionEnd   sql.NullString
 	IsActive     bool
+	// LastSeen is when an rw command last ran during the session
+	LastSeen sql.NullString
 }
 
 // GetAWSAccount retrieves an AWS account by account ID
@@ -385,10 +444,10 @@ func (r *ConfigRepository) GetAWSAccount(accountID string) (*AWSAccount, error)
 
 	acc := &AWSAccount{}
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, account_id, account_name, sso_start_url, sso_region, description, active
+		SELECT id, account_id, account_name, sso_start_url, sso_region, description, active, aws_partition
 		FROM aws_accounts
 		WHERE account_id = ? AND active = 1
-	`, accountID).Scan(&acc.ID, &acc.AccountID, &acc.AccountName, &acc.SSOStartURL, &acc.SSORegion, &acc.Description, &acc.Active)
+	`, accountID).Scan(&acc.ID, &acc.AccountID, &acc.AccountName, &acc.SSOStartURL, &acc.SSORegion, &acc.Description, &acc.Active, &acc.Partition)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("AWS account not found: %s", accountID)
@@ -400,13 +459,36 @@ func (r *ConfigRepository) GetAWSAccount(accountID string) (*AWSAccount, error)
 	return acc, nil
 }
 
+// GetAccountByRowID retrieves an active AWS account by its database ID, as
+// stored in AWSRole.AccountID
+func (r *ConfigRepository) GetAccountByRowID(id int) (*AWSAccount, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	acc := &AWSAccount{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, account_id, account_name, sso_start_url, sso_region, description, active, aws_partition
+		FROM aws_accounts
+		WHERE id = ? AND active = 1
+	`, id).Scan(&acc.ID, &acc.AccountID, &acc.AccountName, &acc.SSOStartURL, &acc.SSORegion, &acc.Description, &acc.Active, &acc.Partition)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("AWS account not found for role (account row %d)", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
 // GetAllAWSAccounts retrieves all active AWS accounts
 func (r *ConfigRepository) GetAllAWSAccounts() ([]AWSAccount, error) {
 	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
 	defer cancel()
 
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, account_id, account_name, sso_start_url, sso_region, description, active
+		SELECT id, account_id, account_name, sso_start_url, sso_region, description, active, aws_partition
 		FROM aws_accounts
 		WHERE active = 1
 		ORDER BY account_name
@@ -419,7 +501,7 @@ func (r *ConfigRepository) GetAllAWSAccounts() ([]AWSAccount, error) {
 	var accounts []AWSAccount
 	for rows.Next() {
 		var acc AWSAccount
-		if err := rows.Scan(&acc.ID, &acc.AccountID, &acc.AccountName, &acc.SSOStartURL, &acc.SSORegion, &acc.Description, &acc.Active); err != nil {
+		if err := rows.Scan(&acc.ID, &acc.AccountID, &acc.AccountName, &acc.SSOStartURL, &acc.SSORegion, &acc.Description, &acc.Active, &acc.Partition); err != nil {
 			return nil, err
 		}
 		accounts = append(accounts, acc)
@@ -470,7 +552,7 @@ func (r *ConfigRepository) GetRoleByProfileName(profileName string) (*AWSRole, e
 	`, profileName).Scan(&role.ID, &role.AccountID, &role.RoleName, &role.RoleARN, &role.ProfileName, &role.Region, &role.Description, &role.Active)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("role not found: %s", profileName)
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, profileName)
 	}
 	if err != nil {
 		return nil, err
@@ -479,36 +561,142 @@ func (r *ConfigRepository) GetRoleByProfileName(profileName string) (*AWSRole, e
 	return role, nil
 }
 
-// CreateUserSession creates a new user session and deactivates previous ones
-func (r *ConfigRepository) CreateUserSession(roleID int) error {
+// CreateUserSession makes roleID the active session and returns it. It is
+// idempotent: when the role is already active the existing session is
+// returned and created is false, so repeated switches (e.g. a double-click
+// in the tray) don't start new sessions.
+func (r *ConfigRepository) CreateUserSession(roleID int) (session *UserSession, created bool, err error) {
 	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
 	defer cancel()
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 	defer tx.Rollback()
 
-	// Deactivate all previous sessions
+	// Deactivate sessions for other roles first: the write takes SQLite's
+	// lock, so a concurrent switch can't read the state in between
 	_, err = tx.ExecContext(ctx, `
-		UPDATE user_sessions 
+		UPDATE user_sessions
 		SET is_active = 0, session_end = CURRENT_TIMESTAMP
-		WHERE is_active = 1
-	`)
+		WHERE is_active = 1 AND role_id != ?
+	`, roleID)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 
-	// Create new session
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO user_sessions (role_id, is_active)
-		VALUES (?, 1)
-	`, roleID)
+	session, err = activeSessionForRole(ctx, tx, roleID)
+	if err == sql.ErrNoRows {
+		if _, err = tx.ExecContext(ctx, `
+			INSERT INTO user_sessions (role_id, is_active, last_seen)
+			VALUES (?, 1, CURRENT_TIMESTAMP)
+		`, roleID); err != nil {
+			return nil, false, err
+		}
+		created = true
+		session, err = activeSessionForRole(ctx, tx, roleID)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return session, created, nil
+}
+
+// activeSessionForRole returns the newest active session for a role
+func activeSessionForRole(ctx context.Context, tx *sql.Tx, roleID int) (*UserSession, error) {
+	session := &UserSession{}
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, role_id, session_start, session_end, is_active, last_seen
+		FROM user_sessions
+		WHERE role_id = ? AND is_active = 1
+		ORDER BY id DESC
+		LIMIT 1
+	`, roleID).Scan(&session.ID, &session.RoleID, &session.SessionStart, &session.SessionEnd, &session.IsActive, &session.LastSeen)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// TouchActiveSession records a heartbeat on the active session. Sessions
+// idle for longer than idleTimeout are closed first, ending at their last
+// heartbeat; zero disables the timeout. It returns how many were closed,
+// and writes nothing when no session is active.
+func (r *ConfigRepository) TouchActiveSession(idleTimeout time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	// Without an active session there is nothing to touch: don't take the
+	// write lock
+	var active int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_sessions WHERE is_active = 1`).Scan(&active); err != nil || active == 0 {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var closed int64
+	if idleTimeout > 0 {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE user_sessions
+			SET is_active = 0, session_end = COALESCE(last_seen, session_start)
+			WHERE is_active = 1
+			  AND COALESCE(last_seen, session_start) < datetime('now', ?)
+		`, fmt.Sprintf("-%d seconds", int64(idleTimeout.Seconds())))
+		if err != nil {
+			return 0, err
+		}
+		if closed, err = res.RowsAffected(); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_sessions SET last_seen = CURRENT_TIMESTAMP WHERE is_active = 1
+	`); err != nil {
+		return 0, err
+	}
+	return closed, tx.Commit()
+}
+
+// RestoreUserSession makes sessionID the only active session again, undoing
+// a switch: sessions opened since are closed and sessionID is reopened. A
+// sessionID of 0 closes every active session.
+func (r *ConfigRepository) RestoreUserSession(sessionID int) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET is_active = 0, session_end = CURRENT_TIMESTAMP
+		WHERE is_active = 1 AND id != ?
+	`, sessionID); err != nil {
+		return err
+	}
+	if sessionID != 0 {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE user_sessions
+			SET is_active = 1, session_end = NULL, last_seen = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, sessionID); err != nil {
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
@@ -523,19 +711,19 @@ func (r *ConfigRepository) GetActiveSession() (*UserSession, *AWSRole, *AWSAccou
 
 	err := r.db.QueryRowContext(ctx, `
 		SELECT 
-			s.id, s.role_id, s.session_start, s.session_end, s.is_active,
+			s.id, s.role_id, s.session_start, s.session_end, s.is_active, s.last_seen,
 			r.id, r.account_id, r.role_name, r.role_arn, r.profile_name, r.region, r.description, r.active,
-			a.id, a.account_id, a.account_name, a.sso_start_url, a.sso_region, a.description, a.active
+			a.id, a.account_id, a.account_name, a.sso_start_url, a.sso_region, a.description, a.active, a.aws_partition
 		FROM user_sessions s
 		JOIN aws_roles r ON s.role_id = r.id
 		JOIN aws_accounts a ON r.account_id = a.id
 		WHERE s.is_active = 1
-		ORDER BY s.session_start DESC
+		ORDER BY s.id DESC
 		LIMIT 1
 	`).Scan(
-		&session.ID, &session.RoleID, &session.SessionStart, &session.SessionEnd, &session.IsActive,
+		&session.ID, &session.RoleID, &session.SessionStart, &session.SessionEnd, &session.IsActive, &session.LastSeen,
 		&role.ID, &role.AccountID, &role.RoleName, &role.RoleARN, &role.ProfileName, &role.Region, &role.Description, &role.Active,
-		&account.ID, &account.AccountID, &account.AccountName, &account.SSOStartURL, &account.SSORegion, &account.Description, &account.Active,
+		&account.ID, &account.AccountID, &account.AccountName, &account.SSOStartURL, &account.SSORegion, &account.Description, &account.Active, &account.Partition,
 	)
 
 	if err == sql.ErrNoRows {
@@ -548,21 +736,85 @@ func (r *ConfigRepository) GetActiveSession() (*UserSession, *AWSRole, *AWSAccou
 	return session, role, account, nil
 }
 
-// AddAWSAccount adds a new AWS account
+// GetProfileKubeContext returns the named kubectl context stored for a
+// profile, or "" when none is set.
+func (r *ConfigRepository) GetProfileKubeContext(profileName string) (string, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var kubeContext sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT kube_context FROM aws_roles WHERE profile_name = ? AND active = 1
+	`, profileName).Scan(&kubeContext)
+
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("role not found: %s", profileName)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return kubeContext.String, nil
+}
+
+// SetProfileKubeContext stores the kubectl context for a profile. An empty
+// context clears it, so the context is derived from the environment again.
+func (r *ConfigRepository) SetProfileKubeContext(profileName, kubeContext string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE aws_roles SET kube_context = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE profile_name = ?
+	`, sql.NullString{String: kubeContext, Valid: kubeContext != ""}, profileName)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("role not found: %s", profileName)
+	}
+	return nil
+}
+
+// AddAWSAccount adds a new AWS account. Its partition is that of the SSO
+// region.
 func (r *ConfigRepository) AddAWSAccount(accountID, accountName, ssoStartURL, ssoRegion, description string) error {
 	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
 	defer cancel()
 
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO aws_accounts (account_id, account_name, sso_start_url, sso_region, description)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO aws_accounts (account_id, account_name, sso_start_url, sso_region, description, aws_partition)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`, accountID, accountName,
 		sql.NullString{String: ssoStartURL, Valid: ssoStartURL != ""},
 		sql.NullString{String: ssoRegion, Valid: ssoRegion != ""},
-		sql.NullString{String: description, Valid: description != ""})
+		sql.NullString{String: description, Valid: description != ""},
+		awsarn.PartitionForRegion(ssoRegion))
 	return err
 }
 
+// SetAccountPartition corrects the AWS partition recorded for an account
+func (r *ConfigRepository) SetAccountPartition(accountID, partition string) error {
+	if !awsarn.ValidPartition(partition) {
+		return fmt.Errorf("unknown partition %q (valid: %s)", partition, strings.Join(awsarn.Partitions, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE aws_accounts SET aws_partition = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE account_id = ? AND active = 1
+	`, partition, accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("AWS account not found: %s", accountID)
+	}
+	return nil
+}
+
 // AddAWSRole adds a new AWS role
 func (r *ConfigRepository) AddAWSRole(accountID int, roleName, roleARN, profileName, region, description string) error {
 	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
@@ -575,8 +827,18 @@ func (r *ConfigRepository) AddAWSRole(accountID int, roleName, roleARN, profileN
 		sql.NullString{String: roleARN, Valid: roleARN != ""},
 		profileName, region,
 		sql.NullString{String: description, Valid: description != ""})
+	if err != nil {
+		return err
+	}
+
+	// Environments added before their profile's role now know their account
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE environments SET account_id = (SELECT account_id FROM aws_accounts WHERE id = ?)
+		WHERE aws_profile = ? AND account_id IS NULL
+	`, accountID, profileName)
 	return err
 }
+
 // UpdateAWSRole updates specific fields on an existing AWS role
 func (r *ConfigRepository) UpdateAWSRole(roleID int, updates map[string]interface{}) error {
 	if len(updates) == 0 {
@@ -590,6 +852,7 @@ func (r *ConfigRepository) UpdateAWSRole(roleID int, updates map[string]interfac
 	allowedColumns := map[string]bool{
 		"role_name": true, "role_arn": true, "region": true,
 		"profile_name": true, "description": true, "account_id": true, "active": true,
+		"owner": true, "team": true, "contact": true,
 	}
 
 	var setClauses []string
@@ -645,20 +908,151 @@ func (r *ConfigRepository) AddEnvironment(name, displayName, region, awsProfile,
 	defer cancel()
 
 	_, err := r.db.ExecContext(ctx, `
-		INSERT OR IGNORE INTO environments (name, display_name, region, aws_profile, cluster_name)
-		VALUES (?, ?, ?, ?, ?)
-	`, name, displayName, region, awsProfile, clusterName)
+		INSERT OR IGNORE INTO environments (name, display_name, region, aws_profile, cluster_name, account_id)
+		VALUES (?, ?, ?, ?, ?, `+profileAccountID+`)
+	`, name, displayName, region, awsProfile, clusterName, awsProfile)
 	return err
 }
 
-// UpdateEnvironment updates the AWS profile and cluster name for an environment.
+// UpdateEnvironment updates the AWS profile and cluster name for an
+// environment, and its account when the new profile's account is known.
 func (r *ConfigRepository) UpdateEnvironment(name, awsProfile, clusterName string) error {
 	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
 	defer cancel()
 
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE environments SET aws_profile = ?, cluster_name = ?, updated_at = CURRENT_TIMESTAMP
+		UPDATE environments SET aws_profile = ?, cluster_name = ?,
+			account_id = COALESCE(`+profileAccountID+`, account_id),
+			updated_at = CURRENT_TIMESTAMP
 		WHERE name = ?
-	`, awsProfile, clusterName, name)
+	`, awsProfile, clusterName, awsProfile, name)
 	return err
 }
+
+// GetConfigVersion returns the configuration change counter. It increases
+// whenever environments, services, port mappings, presets, endpoints,
+// accounts or roles are modified by any process.
+func (r *ConfigRepository) GetConfigVersion() (int64, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var version int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT version FROM config_version WHERE id = 1
+	`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// CloneEnvironment creates target as a copy of the source environment in a
+// single transaction, along with the source's port mappings (using the
+// local port from localPorts, keyed by service ID) and cluster mapping
+// (pointed at target's cluster). A deactivated environment with the target
+// name is replaced. It returns the number of port mappings copied.
+func (r *ConfigRepository) CloneEnvironment(source string, target Environment, localPorts map[int]int) (int, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sourceID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM environments WHERE name = ? AND active = 1
+	`, source).Scan(&sourceID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("environment not found: %s", source)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var existingID int
+	var active bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, active FROM environments WHERE name = ?
+	`, target.Name).Scan(&existingID, &active)
+	switch {
+	case err == nil && active:
+		return 0, fmt.Errorf("environment already exists: %s", target.Name)
+	case err == nil:
+		// Replace a deactivated environment (e.g. an expired ephemeral one)
+		for _, q := range []string{
+			`DELETE FROM port_mappings WHERE environment_id = ?`,
+			`DELETE FROM cluster_mappings WHERE environment_id = ?`,
+			`DELETE FROM environments WHERE id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, q, existingID); err != nil {
+				return 0, err
+			}
+		}
+	case err != sql.ErrNoRows:
+		return 0, err
+	}
+
+	// The account is the target profile's, else the source's
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO environments (name, display_name, region, aws_profile, cluster_name, namespace, account_id)
+		VALUES (?, ?, ?, ?, ?, ?, COALESCE(`+profileAccountID+`, (SELECT account_id FROM environments WHERE id = ?)))
+	`, target.Name, target.DisplayName, target.Region, target.AWSProfile, target.ClusterName, target.Namespace,
+		target.AWSProfile, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	targetID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT service_id, local_port, remote_port, description
+		FROM port_mappings
+		WHERE environment_id = ? AND active = 1
+	`, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	var mappings []PortMapping
+	for rows.Next() {
+		var pm PortMapping
+		if err := rows.Scan(&pm.ServiceID, &pm.LocalPort, &pm.RemotePort, &pm.Description); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		mappings = append(mappings, pm)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, pm := range mappings {
+		localPort := pm.LocalPort
+		if port, ok := localPorts[pm.ServiceID]; ok {
+			localPort = port
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO port_mappings (service_id, environment_id, local_port, remote_port, description)
+			VALUES (?, ?, ?, ?, ?)
+		`, pm.ServiceID, targetID, localPort, pm.RemotePort, pm.Description); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO cluster_mappings (environment_id, cluster_prefix, cluster_suffix, full_cluster_name)
+		SELECT ?, CASE WHEN cluster_prefix = ? THEN ? ELSE cluster_prefix END, cluster_suffix, ?
+		FROM cluster_mappings
+		WHERE environment_id = ?
+	`, targetID, source, target.Name, target.ClusterName, sourceID); err != nil {
+		return 0, err
+	}
+
+	return len(mappings), tx.Commit()
+}
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewDB(t *testing.T) {
@@ -49,3 +51,379 @@ func TestConfigRepository_GetAllEnvironments(t *testing.T) {
 		t.Error("GetAllEnvironments() returned empty list, expected seeded data")
 	}
 }
+
+func TestConfigRepository_GetConfigVersionIncrements(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	before, err := repo.GetConfigVersion()
+	if err != nil {
+		t.Fatalf("GetConfigVersion() error: %v", err)
+	}
+
+	// A no-op update still fires the trigger
+	if _, err := database.Exec(`UPDATE environments SET name = name`); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+
+	after, err := repo.GetConfigVersion()
+	if err != nil {
+		t.Fatalf("GetConfigVersion() error: %v", err)
+	}
+	if after <= before {
+		t.Errorf("GetConfigVersion() = %d after update, want > %d", after, before)
+	}
+}
+
+func TestConfigRepository_CloneEnvironment(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	source, err := repo.GetEnvironment("sit")
+	if err != nil {
+		t.Fatalf("GetEnvironment(sit) error: %v", err)
+	}
+
+	target := *source
+	target.Name = "test-clone-sit"
+	target.ClusterName = "test-clone-sit-zenith-eks-cluster"
+	defer func() {
+		database.Exec(`DELETE FROM port_mappings WHERE environment_id IN (SELECT id FROM environments WHERE name = ?)`, target.Name)
+		database.Exec(`DELETE FROM environments WHERE name = ?`, target.Name)
+	}()
+
+	copied, err := repo.CloneEnvironment("sit", target, map[int]int{})
+	if err != nil {
+		t.Fatalf("CloneEnvironment() error: %v", err)
+	}
+
+	clone, err := repo.GetEnvironment(target.Name)
+	if err != nil {
+		t.Fatalf("GetEnvironment(clone) error: %v", err)
+	}
+	if clone.ClusterName != target.ClusterName || clone.AWSProfile != source.AWSProfile {
+		t.Errorf("clone = %+v, want cluster %s and profile %s", clone, target.ClusterName, source.AWSProfile)
+	}
+
+	mappings, err := repo.GetAllPortMappings()
+	if err != nil {
+		t.Fatalf("GetAllPortMappings() error: %v", err)
+	}
+	count := 0
+	for _, pm := range mappings {
+		if pm.EnvironmentID == clone.ID {
+			count++
+		}
+	}
+	if count != copied {
+		t.Errorf("clone has %d port mappings, CloneEnvironment() reported %d", count, copied)
+	}
+
+	if _, err := repo.CloneEnvironment("sit", target, nil); err == nil {
+		t.Error("CloneEnvironment() should fail when the target already exists")
+	}
+}
+
+func TestConfigRepository_ProfileAnnotations(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	const accountID, profile = "000000000042", "test-annotate-profile"
+	if err := repo.AddAWSAccount(accountID, "test-annotate", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	defer database.Exec(`DELETE FROM aws_accounts WHERE account_id = ?`, accountID)
+	account, err := repo.GetAWSAccount(accountID)
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	if err := repo.AddAWSRole(account.ID, "ReadOnly", "", profile, "eu-west-2", "Imported"); err != nil {
+		t.Fatalf("AddAWSRole() error: %v", err)
+	}
+	defer database.Exec(`DELETE FROM aws_roles WHERE profile_name = ?`, profile)
+
+	if err := repo.AnnotateAccount(accountID, map[string]string{"owner": "alice", "team": "platform"}); err != nil {
+		t.Fatalf("AnnotateAccount() error: %v", err)
+	}
+	if err := repo.AnnotateRole(profile, map[string]string{"owner": "bob", "description": ""}); err != nil {
+		t.Fatalf("AnnotateRole() error: %v", err)
+	}
+
+	annotations, err := repo.GetProfileAnnotations()
+	if err != nil {
+		t.Fatalf("GetProfileAnnotations() error: %v", err)
+	}
+	got := annotations[profile].Effective()
+	want := Ownership{Owner: "bob", Team: "platform"}
+	if got != want {
+		t.Errorf("Effective() = %+v, want %+v (role owner, account team, description cleared)", got, want)
+	}
+
+	if err := repo.AnnotateRole(profile, map[string]string{"active": "0"}); err == nil {
+		t.Error("AnnotateRole() should reject non-ownership columns")
+	}
+	if err := repo.AnnotateRole("no-such-profile", map[string]string{"owner": "x"}); err == nil {
+		t.Error("AnnotateRole() should fail for an unknown profile")
+	}
+}
+
+func TestConfigRepository_CreateUserSessionIdempotent(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	if err := repo.AddAWSAccount("000000000043", "test-session", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, err := repo.GetAWSAccount("000000000043")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	for role, profile := range map[string]string{"ReadOnly": "test-session-a", "Admin": "test-session-b"} {
+		if err := repo.AddAWSRole(account.ID, role, "", profile, "eu-west-2", ""); err != nil {
+			t.Fatalf("AddAWSRole() error: %v", err)
+		}
+	}
+	roleA, _ := repo.GetRoleByProfileName("test-session-a")
+	roleB, _ := repo.GetRoleByProfileName("test-session-b")
+
+	first, created, err := repo.CreateUserSession(roleA.ID)
+	if err != nil || !created {
+		t.Fatalf("CreateUserSession(a) = %v, %v, want a new session", created, err)
+	}
+	again, created, err := repo.CreateUserSession(roleA.ID)
+	if err != nil || created || again.ID != first.ID {
+		t.Errorf("CreateUserSession(a) again = %+v, %v, %v, want the existing session %d", again, created, err, first.ID)
+	}
+
+	second, created, err := repo.CreateUserSession(roleB.ID)
+	if err != nil || !created || second.ID == first.ID {
+		t.Fatalf("CreateUserSession(b) = %+v, %v, %v, want a new session", second, created, err)
+	}
+	session, role, _, err := repo.GetActiveSession()
+	if err != nil {
+		t.Fatalf("GetActiveSession() error: %v", err)
+	}
+	if session.ID != second.ID || role.ProfileName != "test-session-b" {
+		t.Errorf("GetActiveSession() = %d (%s), want %d (test-session-b)", session.ID, role.ProfileName, second.ID)
+	}
+
+	var active int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM user_sessions WHERE is_active = 1`).Scan(&active); err != nil {
+		t.Fatal(err)
+	}
+	if active != 1 {
+		t.Errorf("active sessions = %d, want 1", active)
+	}
+}
+
+func TestConfigRepository_TouchActiveSessionClosesIdle(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	if err := repo.AddAWSAccount("000000000044", "test-heartbeat", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, _ := repo.GetAWSAccount("000000000044")
+	if err := repo.AddAWSRole(account.ID, "ReadOnly", "", "test-heartbeat", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSRole() error: %v", err)
+	}
+	if closed, err := repo.TouchActiveSession(time.Hour); err != nil || closed != 0 {
+		t.Fatalf("TouchActiveSession() without a session = %d, %v, want nothing done", closed, err)
+	}
+
+	role, _ := repo.GetRoleByProfileName("test-heartbeat")
+	session, _, err := repo.CreateUserSession(role.ID)
+	if err != nil {
+		t.Fatalf("CreateUserSession() error: %v", err)
+	}
+
+	if closed, err := repo.TouchActiveSession(time.Hour); err != nil || closed != 0 {
+		t.Fatalf("TouchActiveSession() = %d, %v, want a fresh session kept open", closed, err)
+	}
+
+	const lastSeen = "2020-01-01 09:00:00"
+	if _, err := database.Exec(`UPDATE user_sessions SET last_seen = ? WHERE id = ?`, lastSeen, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if closed, err := repo.TouchActiveSession(0); err != nil || closed != 0 {
+		t.Fatalf("TouchActiveSession(0) = %d, %v, want the timeout disabled", closed, err)
+	}
+	if _, err := database.Exec(`UPDATE user_sessions SET last_seen = ? WHERE id = ?`, lastSeen, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if closed, err := repo.TouchActiveSession(time.Hour); err != nil || closed != 1 {
+		t.Fatalf("TouchActiveSession() = %d, %v, want the idle session closed", closed, err)
+	}
+	if _, _, _, err := repo.GetActiveSession(); err == nil {
+		t.Error("GetActiveSession() should find no session after it was closed")
+	}
+
+	var end string
+	if err := database.QueryRow(`SELECT session_end FROM user_sessions WHERE id = ?`, session.ID).Scan(&end); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(end, "2020-01-01") {
+		t.Errorf("session_end = %q, want the last heartbeat %s", end, lastSeen)
+	}
+}
+
+func TestGetAccountByRowID(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	if err := repo.AddAWSAccount("000000000071", "rowid", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	want, err := repo.GetAWSAccount("000000000071")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	got, err := repo.GetAccountByRowID(want.ID)
+	if err != nil || got.AccountID != want.AccountID {
+		t.Errorf("GetAccountByRowID(%d) = %+v, %v, want account %s", want.ID, got, err, want.AccountID)
+	}
+	if _, err := repo.GetAccountByRowID(want.ID + 1000); err == nil {
+		t.Error("GetAccountByRowID() should fail for a missing row")
+	}
+}
+
+func TestRoleQueriesUseIndexes(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	for query, index := range map[string]string{
+		`SELECT id FROM aws_roles WHERE active = 1 ORDER BY profile_name`:                 "idx_aws_roles_active_profile",
+		`SELECT id FROM aws_roles WHERE account_id = 1 AND active = 1 ORDER BY role_name`: "idx_aws_roles_account_active",
+		`SELECT id FROM port_mappings WHERE environment_id = 1 AND local_port = 5433`:     "idx_port_mappings_environment_port",
+	} {
+		rows, err := database.Query("EXPLAIN QUERY PLAN " + query)
+		if err != nil {
+			t.Fatalf("EXPLAIN %s: %v", query, err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatal(err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+		if !strings.Contains(strings.Join(plan, "; "), index) {
+			t.Errorf("plan for %q = %v, want it to use %s", query, plan, index)
+		}
+	}
+}
+
+func TestAccountPartition(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	for id, region := range map[string]string{"000000000091": "eu-west-2", "000000000092": "us-gov-west-1", "000000000093": ""} {
+		if err := repo.AddAWSAccount(id, "p"+id, "", region, ""); err != nil {
+			t.Fatalf("AddAWSAccount() error: %v", err)
+		}
+	}
+	for id, want := range map[string]string{"000000000091": "aws", "000000000092": "aws-us-gov", "000000000093": "aws"} {
+		if acc, err := repo.GetAWSAccount(id); err != nil || acc.Partition != want {
+			t.Errorf("account %s = %+v, %v, want partition %s", id, acc, err, want)
+		}
+	}
+
+	if err := repo.SetAccountPartition("000000000093", "aws-cn"); err != nil {
+		t.Fatalf("SetAccountPartition() error: %v", err)
+	}
+	if acc, _ := repo.GetAWSAccount("000000000093"); acc.Partition != "aws-cn" {
+		t.Errorf("partition after SetAccountPartition = %q, want aws-cn", acc.Partition)
+	}
+	if err := repo.SetAccountPartition("000000000093", "aws-iso"); err == nil {
+		t.Error("SetAccountPartition() should refuse unknown partitions")
+	}
+}
+
+func TestConfigRepository_RestoreUserSession(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	if err := repo.AddAWSAccount("000000000091", "restore", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, err := repo.GetAWSAccount("000000000091")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	for _, name := range []string{"First", "Second"} {
+		if err := repo.AddAWSRole(account.ID, name, "", "restore-"+name, "eu-west-2", ""); err != nil {
+			t.Fatalf("AddAWSRole() error: %v", err)
+		}
+	}
+	first, _ := repo.GetRoleByProfileName("restore-First")
+	second, _ := repo.GetRoleByProfileName("restore-Second")
+
+	before, _, err := repo.CreateUserSession(first.ID)
+	if err != nil {
+		t.Fatalf("CreateUserSession() error: %v", err)
+	}
+	if _, _, err := repo.CreateUserSession(second.ID); err != nil {
+		t.Fatalf("CreateUserSession() error: %v", err)
+	}
+
+	if err := repo.RestoreUserSession(before.ID); err != nil {
+		t.Fatalf("RestoreUserSession() error: %v", err)
+	}
+	if session, _, _, err := repo.GetActiveSession(); err != nil || session.ID != before.ID {
+		t.Errorf("GetActiveSession() = %+v, %v; want session %d back", session, err, before.ID)
+	}
+
+	if err := repo.RestoreUserSession(0); err != nil {
+		t.Fatalf("RestoreUserSession(0) error: %v", err)
+	}
+	if session, _, _, err := repo.GetActiveSession(); err == nil && session != nil {
+		t.Errorf("GetActiveSession() = %+v, want none after restoring no session", session)
+	}
+}
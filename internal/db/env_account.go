@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// profileAccountID is a subquery for the AWS account ID of the profile bound
+// to its one parameter, NULL when rw has no role for the profile
+const profileAccountID = `(
+	SELECT a.account_id FROM aws_roles r
+	JOIN aws_accounts a ON a.id = r.account_id
+	WHERE r.profile_name = ?
+	LIMIT 1
+)`
+
+// GetEnvironmentAccount returns the AWS account ID an environment is
+// expected to live in, or "" when none is recorded
+func (r *ConfigRepository) GetEnvironmentAccount(name string) (string, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var accountID sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT account_id FROM environments WHERE name = ? AND active = 1
+	`, name).Scan(&accountID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("environment not found: %s", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return accountID.String, nil
+}
+
+// SetEnvironmentAccount records the AWS account ID an environment lives in.
+// An empty accountID clears it.
+func (r *ConfigRepository) SetEnvironmentAccount(name, accountID string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE environments SET account_id = NULLIF(?, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE name = ? AND active = 1
+	`, accountID, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("environment not found: %s", name)
+	}
+	return nil
+}
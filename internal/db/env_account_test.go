@@ -0,0 +1,82 @@
+package db
+
+import "testing"
+
+func TestConfigRepository_EnvironmentAccount(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	if got, err := repo.GetEnvironmentAccount("dev"); err != nil || got != "" {
+		t.Fatalf("GetEnvironmentAccount(dev) = %q, %v; want no account on a fresh database", got, err)
+	}
+
+	if err := repo.SetEnvironmentAccount("dev", "611914608941"); err != nil {
+		t.Fatalf("SetEnvironmentAccount() error: %v", err)
+	}
+	if got, _ := repo.GetEnvironmentAccount("dev"); got != "611914608941" {
+		t.Errorf("GetEnvironmentAccount(dev) = %q, want 611914608941", got)
+	}
+
+	if err := repo.SetEnvironmentAccount("dev", ""); err != nil {
+		t.Fatalf("SetEnvironmentAccount() error: %v", err)
+	}
+	if got, _ := repo.GetEnvironmentAccount("dev"); got != "" {
+		t.Errorf("GetEnvironmentAccount(dev) = %q after clearing, want empty", got)
+	}
+
+	if err := repo.SetEnvironmentAccount("no-such-env", "1"); err == nil {
+		t.Error("SetEnvironmentAccount() should fail for an unknown environment")
+	}
+}
+
+func TestConfigRepository_EnvironmentAccountFromProfile(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	// An environment added before its profile's role gets the account
+	// once the role is added
+	if err := repo.AddEnvironment("synced", "Synced", "eu-west-2", "synced-admin", "synced-eks"); err != nil {
+		t.Fatalf("AddEnvironment() error: %v", err)
+	}
+	if got, _ := repo.GetEnvironmentAccount("synced"); got != "" {
+		t.Fatalf("GetEnvironmentAccount(synced) = %q before the role exists, want empty", got)
+	}
+	if err := repo.AddAWSAccount("000000000071", "synced", "", "", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+	account, err := repo.GetAWSAccount("000000000071")
+	if err != nil {
+		t.Fatalf("GetAWSAccount() error: %v", err)
+	}
+	if err := repo.AddAWSRole(account.ID, "Admin", "", "synced-admin", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSRole() error: %v", err)
+	}
+	if got, _ := repo.GetEnvironmentAccount("synced"); got != "000000000071" {
+		t.Errorf("GetEnvironmentAccount(synced) = %q, want 000000000071", got)
+	}
+
+	// Added after the role, and cloned, environments get it straight away
+	if err := repo.AddEnvironment("synced-2", "Synced 2", "eu-west-2", "synced-admin", "synced-eks"); err != nil {
+		t.Fatalf("AddEnvironment() error: %v", err)
+	}
+	if got, _ := repo.GetEnvironmentAccount("synced-2"); got != "000000000071" {
+		t.Errorf("GetEnvironmentAccount(synced-2) = %q, want 000000000071", got)
+	}
+	target := Environment{Name: "synced-clone", DisplayName: "Clone", Region: "eu-west-2", AWSProfile: "synced-admin", ClusterName: "synced-eks"}
+	if _, err := repo.CloneEnvironment("synced", target, nil); err != nil {
+		t.Fatalf("CloneEnvironment() error: %v", err)
+	}
+	if got, _ := repo.GetEnvironmentAccount("synced-clone"); got != "000000000071" {
+		t.Errorf("GetEnvironmentAccount(synced-clone) = %q, want 000000000071", got)
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProductionEnvironments returns the names of the active environments
+// flagged as production
+func (r *ConfigRepository) ProductionEnvironments() ([]string, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name FROM environments WHERE active = 1 AND is_production = 1 ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// SetEnvironmentProduction flags or unflags an environment as production
+func (r *ConfigRepository) SetEnvironmentProduction(name string, production bool) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE environments SET is_production = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ? AND active = 1
+	`, production, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("environment not found: %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConfigRepository_EnvironmentProduction(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	// Only the flag is stored; production_envs is applied by the caller
+	got, err := repo.ProductionEnvironments()
+	if err != nil {
+		t.Fatalf("ProductionEnvironments() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("ProductionEnvironments() = %v, want none", got)
+	}
+
+	if err := repo.SetEnvironmentProduction("dev", true); err != nil {
+		t.Fatalf("SetEnvironmentProduction() error: %v", err)
+	}
+	if err := repo.SetEnvironmentProduction("prod", true); err != nil {
+		t.Fatalf("SetEnvironmentProduction() error: %v", err)
+	}
+	if err := repo.SetEnvironmentProduction("prod", false); err != nil {
+		t.Fatalf("SetEnvironmentProduction() error: %v", err)
+	}
+	got, _ = repo.ProductionEnvironments()
+	if !slices.Contains(got, "dev") || slices.Contains(got, "prod") {
+		t.Errorf("ProductionEnvironments() = %v, want dev and not prod", got)
+	}
+
+	if err := repo.SetEnvironmentProduction("no-such-env", true); err == nil {
+		t.Error("SetEnvironmentProduction() should fail for an unknown environment")
+	}
+}
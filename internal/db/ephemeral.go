@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EnvironmentExpiry is the scheduled expiry of an ephemeral environment
+type EnvironmentExpiry struct {
+	Name      string
+	ExpiresAt time.Time
+}
+
+// SetEnvironmentExpiry schedules an environment to expire at the given time
+func (r *ConfigRepository) SetEnvironmentExpiry(name string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE environments SET expires_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ? AND active = 1
+	`, expiresAt.UTC(), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("environment not found: %s", name)
+	}
+	return nil
+}
+
+// GetEnvironmentExpiries returns the active environments that have an
+// expiry, soonest first
+func (r *ConfigRepository) GetEnvironmentExpiries() ([]EnvironmentExpiry, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, expires_at
+		FROM environments
+		WHERE active = 1 AND expires_at IS NOT NULL
+		ORDER BY expires_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expiries []EnvironmentExpiry
+	for rows.Next() {
+		var e EnvironmentExpiry
+		if err := rows.Scan(&e.Name, &e.ExpiresAt); err != nil {
+			return nil, err
+		}
+		expiries = append(expiries, e)
+	}
+
+	return expiries, rows.Err()
+}
+
+// DeactivateEnvironment marks an environment and its port mappings inactive.
+// The rows are kept for the audit trail; the name can be reused by a clone.
+func (r *ConfigRepository) DeactivateEnvironment(name string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE environments SET active = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ? AND active = 1
+	`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("environment not found: %s", name)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE port_mappings SET active = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE environment_id = (SELECT id FROM environments WHERE name = ?)
+	`, name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
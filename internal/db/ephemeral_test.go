@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigRepository_EnvironmentExpiry(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewConfigRepository(database)
+	source, err := repo.GetEnvironment("dev")
+	if err != nil {
+		t.Fatalf("GetEnvironment(dev) error: %v", err)
+	}
+
+	target := *source
+	target.Name = "test-ephemeral-dev"
+	defer func() {
+		database.Exec(`DELETE FROM port_mappings WHERE environment_id IN (SELECT id FROM environments WHERE name = ?)`, target.Name)
+		database.Exec(`DELETE FROM environments WHERE name = ?`, target.Name)
+	}()
+
+	if _, err := repo.CloneEnvironment("dev", target, nil); err != nil {
+		t.Fatalf("CloneEnvironment() error: %v", err)
+	}
+
+	expiresAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	if err := repo.SetEnvironmentExpiry(target.Name, expiresAt); err != nil {
+		t.Fatalf("SetEnvironmentExpiry() error: %v", err)
+	}
+
+	expiries, err := repo.GetEnvironmentExpiries()
+	if err != nil {
+		t.Fatalf("GetEnvironmentExpiries() error: %v", err)
+	}
+	found := false
+	for _, e := range expiries {
+		if e.Name == target.Name {
+			found = true
+			if !e.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("ExpiresAt = %v, want %v", e.ExpiresAt, expiresAt)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("GetEnvironmentExpiries() = %+v, missing %s", expiries, target.Name)
+	}
+
+	if err := repo.DeactivateEnvironment(target.Name); err != nil {
+		t.Fatalf("DeactivateEnvironment() error: %v", err)
+	}
+	if _, err := repo.GetEnvironment(target.Name); err == nil {
+		t.Error("GetEnvironment() should not return a deactivated environment")
+	}
+
+	// The name of a deactivated environment can be reused
+	if _, err := repo.CloneEnvironment("dev", target, nil); err != nil {
+		t.Errorf("CloneEnvironment() over a deactivated environment error: %v", err)
+	}
+}
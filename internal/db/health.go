@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+// Problem classifies why the database can't be used
+type Problem string
+
+const (
+	ProblemUnknown    Problem = "unknown"
+	ProblemLocked     Problem = "locked"
+	ProblemCorrupt    Problem = "corrupt"
+	ProblemReadOnly   Problem = "read-only"
+	ProblemPermission Problem = "permission"
+)
+
+// Description is a short human-readable explanation of the problem
+func (p Problem) Description() string {
+	switch p {
+	case ProblemLocked:
+		return "locked by another process"
+	case ProblemCorrupt:
+		return "the database file is damaged or not a SQLite database"
+	case ProblemReadOnly:
+		return "the state directory is read-only"
+	case ProblemPermission:
+		return "permission denied"
+	default:
+		return "could not be opened"
+	}
+}
+
+// UnavailableError is returned by NewDB when the database can't be opened
+// or migrated. Commands that need the database fail with it; commands that
+// only read ~/.aws/config keep working.
+type UnavailableError struct {
+	// Path is the database file, if the state directory could be resolved
+	Path string
+	Err  error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("database unavailable: %v", e.Err)
+	}
+	return fmt.Sprintf("database %s unavailable: %v", e.Path, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error { return e.Err }
+
+// Problem classifies the underlying error
+func (e *UnavailableError) Problem() Problem {
+	return Classify(e.Err)
+}
+
+// Classify maps a database open/migration error to a Problem
+func Classify(err error) Problem {
+	if err == nil {
+		return ProblemUnknown
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return ProblemLocked
+	case strings.Contains(msg, "file is not a database"), strings.Contains(msg, "malformed"),
+		strings.Contains(msg, "file is encrypted"):
+		return ProblemCorrupt
+	case errors.Is(err, syscall.EROFS), strings.Contains(msg, "read-only file system"),
+		strings.Contains(msg, "readonly database"):
+		return ProblemReadOnly
+	case errors.Is(err, fs.ErrPermission), strings.Contains(msg, "permission denied"),
+		strings.Contains(msg, "refusing to use"):
+		return ProblemPermission
+	}
+	return ProblemUnknown
+}
+
+// RepairSteps returns what to do about a problem with the database at path
+func RepairSteps(p Problem, path string) []string {
+	if path == "" {
+		path = "the database"
+	}
+	switch p {
+	case ProblemLocked:
+		return []string{
+			"Another rw process is holding the database. Stop the tray app with 'rw tray stop'",
+			"and close other running rw commands (e.g. long-running tunnels), then retry.",
+		}
+	case ProblemCorrupt:
+		return []string{
+			"Run 'rw doctor --fix' to move " + path + " aside and create a fresh database,",
+			"then re-import your profiles with 'rw config sync' (or 'rw bootstrap').",
+			"The damaged file is kept next to it for recovery with the sqlite3 CLI.",
+		}
+	case ProblemReadOnly:
+		return []string{
+			"Point rw at a writable state directory with --state-dir <dir> or " + utils.StateDirEnv + "=<dir>.",
+		}
+	case ProblemPermission:
+		return []string{
+			"Make " + path + " and its directory owned and writable by you (chown/chmod),",
+			"or use --state-dir <dir> / " + utils.StateDirEnv + "=<dir> to point rw at a directory you own.",
+		}
+	default:
+		return []string{
+			"Check that " + path + " is a readable, writable file, or move it aside so rw",
+			"can create a new one, then re-import your profiles with 'rw config sync'.",
+		}
+	}
+}
+
+// Health is the result of checking an open database
+type Health struct {
+	// Integrity is "ok", or the problems reported by PRAGMA quick_check
+	Integrity     string
+	SchemaVersion int
+}
+
+// Check runs SQLite's quick integrity check and reads the schema version
+func (db *DB) Check() (Health, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var h Health
+	rows, err := db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		return h, err
+	}
+	var results []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return h, err
+		}
+		results = append(results, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return h, err
+	}
+	h.Integrity = strings.Join(results, "; ")
+
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM migrations").Scan(&h.SchemaVersion); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// MoveAside renames a damaged database (with its -wal and -shm files) to
+// <path>.corrupt-<timestamp> so the next NewDB creates a fresh one. It
+// returns the new path of the database file.
+func MoveAside(path string) (string, error) {
+	return moveAside(path, "corrupt")
+}
+
+// moveAside renames the database at path and its -wal and -shm files to
+// <path>.<label>-<timestamp>
+func moveAside(path, label string) (string, error) {
+	target := fmt.Sprintf("%s.%s-%s", path, label, time.Now().Format("20060102-150405"))
+	if err := os.Rename(path, target); err != nil {
+		return "", fmt.Errorf("failed to move %s aside: %w", path, err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(path+suffix, target+suffix); err != nil && !os.IsNotExist(err) {
+			return target, fmt.Errorf("failed to move %s aside: %w", path+suffix, err)
+		}
+	}
+	return target, nil
+}
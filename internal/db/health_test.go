@@ -0,0 +1,89 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"rolewalkers/internal/utils"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Problem
+	}{
+		{nil, ProblemUnknown},
+		{errors.New("failed to enable WAL mode: database is locked"), ProblemLocked},
+		{errors.New("failed to enable WAL mode: file is not a database"), ProblemCorrupt},
+		{errors.New("failed to run migrations: database disk image is malformed"), ProblemCorrupt},
+		{fmt.Errorf("failed to create dir: %w", syscall.EROFS), ProblemReadOnly},
+		{errors.New("attempt to write a readonly database"), ProblemReadOnly},
+		{fmt.Errorf("open: %w", fs.ErrPermission), ProblemPermission},
+		{errors.New("refusing to use /home/x/.rolewalkers: it is owned by root"), ProblemPermission},
+		{errors.New("something else"), ProblemUnknown},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRepairSteps(t *testing.T) {
+	steps := strings.Join(RepairSteps(ProblemCorrupt, "/tmp/rw/config.db"), " ")
+	if !strings.Contains(steps, "rw doctor --fix") || !strings.Contains(steps, "/tmp/rw/config.db") {
+		t.Errorf("RepairSteps(corrupt) = %q, want the --fix command and path", steps)
+	}
+	steps = strings.Join(RepairSteps(ProblemReadOnly, ""), " ")
+	if !strings.Contains(steps, utils.StateDirEnv) {
+		t.Errorf("RepairSteps(read-only) = %q, want a pointer to %s", steps, utils.StateDirEnv)
+	}
+}
+
+func TestNewDB_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(utils.StateDirEnv, dir)
+	path := filepath.Join(dir, "config.db")
+	if err := os.WriteFile(path, []byte(strings.Repeat("not a sqlite database\n", 512)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewDB()
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("NewDB() error = %v, want *UnavailableError", err)
+	}
+	if unavailable.Path != path {
+		t.Errorf("UnavailableError.Path = %q, want %q", unavailable.Path, path)
+	}
+	if got := unavailable.Problem(); got != ProblemCorrupt {
+		t.Errorf("Problem() = %q, want %q (err: %v)", got, ProblemCorrupt, err)
+	}
+
+	moved, err := MoveAside(path)
+	if err != nil {
+		t.Fatalf("MoveAside() error: %v", err)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("moved database missing: %v", err)
+	}
+
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() after MoveAside error: %v", err)
+	}
+	defer database.Close()
+	health, err := database.Check()
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if health.Integrity != "ok" || health.SchemaVersion != LatestSchemaVersion() {
+		t.Errorf("Check() = %+v, want ok at schema v%d", health, LatestSchemaVersion())
+	}
+}
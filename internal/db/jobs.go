@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JobRecord is a long-running operation, such as a backup, run by an rw
+// process
+type JobRecord struct {
+	ID          int
+	Kind        string // JobBackup, JobRestore or JobSwitchover
+	Environment string
+	Target      string // what the job works on, e.g. a backup file or deployment
+	State       string
+	Progress    string // the step the job is on
+	Error       string // why a job failed
+	PID         int    // process running the job
+	CreatedAt   time.Time
+	StartedAt   sql.NullTime
+	EndedAt     sql.NullTime
+}
+
+// Job kinds
+const (
+	JobBackup     = "backup"
+	JobRestore    = "restore"
+	JobSwitchover = "switchover"
+)
+
+// Job states. A job is queued until its process starts it, then running
+// until it ends in one of the others.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+	// JobInterrupted jobs were running when their process died, e.g. in a
+	// crash or a kill -9, so whether they finished is unknown
+	JobInterrupted = "interrupted"
+)
+
+// Active reports whether the job hasn't ended
+func (j JobRecord) Active() bool {
+	return j.State == JobQueued || j.State == JobRunning
+}
+
+const jobColumns = `id, kind, environment, target, state, progress, error, pid, created_at, started_at, ended_at`
+
+func scanJob(scanner interface{ Scan(...any) error }, j *JobRecord) error {
+	return scanner.Scan(&j.ID, &j.Kind, &j.Environment, &j.Target, &j.State,
+		&j.Progress, &j.Error, &j.PID, &j.CreatedAt, &j.StartedAt, &j.EndedAt)
+}
+
+// CreateJob queues a job and returns its ID
+func (r *ConfigRepository) CreateJob(kind, environment, target string) (int64, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO jobs (kind, environment, target, state) VALUES (?, ?, ?, ?)
+	`, kind, environment, target, JobQueued)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// StartJob marks a queued job running in the process pid
+func (r *ConfigRepository) StartJob(id int64, pid int) error {
+	return r.updateJob(id, `state = ?, pid = ?, started_at = CURRENT_TIMESTAMP`,
+		[]any{JobRunning, pid}, JobQueued)
+}
+
+// UpdateJobProgress records the step a running job is on
+func (r *ConfigRepository) UpdateJobProgress(id int64, progress string) error {
+	return r.updateJob(id, `progress = ?`, []any{progress}, JobRunning)
+}
+
+// FinishJob ends an active job in state, one of JobSucceeded, JobFailed
+// and JobCancelled; errText says why a job failed
+func (r *ConfigRepository) FinishJob(id int64, state, errText string) error {
+	if !slices.Contains([]string{JobSucceeded, JobFailed, JobCancelled}, state) {
+		return fmt.Errorf("invalid final job state: %s", state)
+	}
+	return r.updateJob(id, `state = ?, error = ?, ended_at = CURRENT_TIMESTAMP`,
+		[]any{state, errText}, JobQueued, JobRunning)
+}
+
+// updateJob sets columns of a job that is in one of from, so a job that
+// already ended can't change state again
+func (r *ConfigRepository) updateJob(id int64, set string, args []any, from ...string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(from)), ",")
+	args = append(args, id)
+	for _, state := range from {
+		args = append(args, state)
+	}
+	result, err := r.db.ExecContext(ctx, `UPDATE jobs SET `+set+` WHERE id = ? AND state IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("job %d not found or not %s", id, strings.Join(from, " or "))
+	}
+	return nil
+}
+
+// GetJob retrieves a job by ID
+func (r *ConfigRepository) GetJob(id int) (*JobRecord, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	j := &JobRecord{}
+	err := scanJob(r.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id), j)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job not found: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// ListJobs returns up to limit jobs, newest first. With activeOnly it
+// lists only queued and running jobs.
+func (r *ConfigRepository) ListJobs(activeOnly bool, limit int) ([]JobRecord, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE ? = 0 OR state IN (?, ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`, activeOnly, JobQueued, JobRunning, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []JobRecord
+	for rows.Next() {
+		var j JobRecord
+		if err := scanJob(rows, &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// InterruptOrphanedJobs marks running jobs whose process is no longer
+// alive as interrupted, and returns them. Queued jobs are left alone; a
+// process starts its job straight after queueing it.
+func (r *ConfigRepository) InterruptOrphanedJobs(alive func(pid int) bool) ([]JobRecord, error) {
+	running, err := r.ListJobs(true, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var orphaned []JobRecord
+	for _, j := range running {
+		if j.State != JobRunning || alive(j.PID) {
+			continue
+		}
+		_, err := r.db.ExecContext(ctx, `
+			UPDATE jobs SET state = ?, ended_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND state = ?
+		`, JobInterrupted, j.ID, JobRunning)
+		if err != nil {
+			return orphaned, err
+		}
+		j.State = JobInterrupted
+		orphaned = append(orphaned, j)
+	}
+	return orphaned, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import "testing"
+
+func TestJobs(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	id, err := repo.CreateJob(JobBackup, "dev", "/tmp/dev.sql")
+	if err != nil {
+		t.Fatalf("CreateJob() error: %v", err)
+	}
+	if err := repo.UpdateJobProgress(id, "Running pg_dump"); err == nil {
+		t.Error("UpdateJobProgress() on a queued job succeeded, want an error")
+	}
+	if err := repo.StartJob(id, 4242); err != nil {
+		t.Fatalf("StartJob() error: %v", err)
+	}
+	if err := repo.UpdateJobProgress(id, "Running pg_dump"); err != nil {
+		t.Fatalf("UpdateJobProgress() error: %v", err)
+	}
+	j, err := repo.GetJob(int(id))
+	if err != nil || j.State != JobRunning || j.Progress != "Running pg_dump" || j.PID != 4242 || !j.StartedAt.Valid {
+		t.Errorf("GetJob() = %+v, %v, want a running job in process 4242", j, err)
+	}
+
+	if err := repo.FinishJob(id, JobRunning, ""); err == nil {
+		t.Error("FinishJob(running) succeeded, want an error")
+	}
+	if err := repo.FinishJob(id, JobFailed, "pg_dump failed"); err != nil {
+		t.Fatalf("FinishJob() error: %v", err)
+	}
+	if err := repo.FinishJob(id, JobSucceeded, ""); err == nil {
+		t.Error("FinishJob() on an ended job succeeded, want an error")
+	}
+	if j, _ := repo.GetJob(int(id)); j.State != JobFailed || j.Error != "pg_dump failed" || !j.EndedAt.Valid {
+		t.Errorf("GetJob() = %+v, want a failed job", j)
+	}
+
+	// A running job whose process died is interrupted; a live one is not
+	dead, _ := repo.CreateJob(JobRestore, "dev", "/tmp/dev.sql")
+	live, _ := repo.CreateJob(JobSwitchover, "prod", "bgd-abc123")
+	repo.StartJob(dead, 1)
+	repo.StartJob(live, 2)
+	orphaned, err := repo.InterruptOrphanedJobs(func(pid int) bool { return pid == 2 })
+	if err != nil || len(orphaned) != 1 || orphaned[0].ID != int(dead) {
+		t.Errorf("InterruptOrphanedJobs() = %+v, %v, want the restore job", orphaned, err)
+	}
+
+	active, err := repo.ListJobs(true, 10)
+	if err != nil || len(active) != 1 || active[0].ID != int(live) {
+		t.Errorf("ListJobs(active) = %+v, %v, want the switchover job", active, err)
+	}
+	all, err := repo.ListJobs(false, 10)
+	if err != nil || len(all) != 3 || all[0].ID != int(live) || all[1].State != JobInterrupted {
+		t.Errorf("ListJobs() = %+v, %v, want 3 jobs newest first", all, err)
+	}
+}
@@ -1,5 +1,12 @@
 package db
 
+import (
+	"fmt"
+	"strings"
+
+	"rolewalkers/internal/awsarn"
+)
+
 // migrateV1CreateEnvironments creates the environments table
 func migrateV1CreateEnvironments(db *DB) error {
 	_, err := db.Exec(`
@@ -371,3 +378,353 @@ func migrateV12FixSharedAccountEnvs(db *DB) error {
 	`)
 	return err
 }
+
+// migrateV13CreateAuditLog creates the audit_log table. Each row records a
+// mutating operation along with the state it replaced, so the change can be
+// reviewed or reverted later (see 'rw undo').
+func migrateV13CreateAuditLog(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			environment TEXT NOT NULL DEFAULT '',
+			target TEXT NOT NULL DEFAULT '',
+			previous_state TEXT,
+			new_state TEXT,
+			undone_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE INDEX idx_audit_log_created ON audit_log(created_at DESC)
+	`)
+	return err
+}
+
+// configVersionTables lists the tables whose changes bump config_version.
+var configVersionTables = []string{
+	"environments", "services", "port_mappings", "scaling_presets",
+	"api_endpoints", "cluster_mappings", "aws_accounts", "aws_roles",
+}
+
+// migrateV14CreateConfigVersion creates a single-row change counter that is
+// incremented by triggers whenever configuration tables change. Long-running
+// processes (e.g. the tray) poll it to pick up changes without a restart.
+func migrateV14CreateConfigVersion(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE config_version (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			version INTEGER NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`INSERT INTO config_version (id, version) VALUES (1, 0)`); err != nil {
+		return err
+	}
+
+	for _, table := range configVersionTables {
+		if err := createConfigVersionTriggers(db, table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createConfigVersionTriggers makes changes to table bump config_version.
+// Tables created after v14 call it from their own migration.
+func createConfigVersionTriggers(db *DB, table string) error {
+	for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+		_, err := db.Exec(fmt.Sprintf(`
+			CREATE TRIGGER trg_%s_%s_version AFTER %s ON %s
+			BEGIN
+				UPDATE config_version SET version = version + 1 WHERE id = 1;
+			END
+		`, table, strings.ToLower(event), event, table))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV15CreateAliases creates the aliases table for user-defined
+// command shortcuts (see 'rw alias').
+func migrateV15CreateAliases(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE aliases (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			expansion TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+func migrateV16CreateBackups(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE backups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			environment TEXT NOT NULL,
+			location TEXT NOT NULL,
+			size_bytes INTEGER NOT NULL DEFAULT 0,
+			schema_only BOOLEAN NOT NULL DEFAULT 0,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			content_hash TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE INDEX idx_backups_environment ON backups(environment, created_at DESC)
+	`)
+	return err
+}
+
+// migrateV17AddRoleKubeContext adds an optional named kubectl context per
+// profile, applied together with the profile on 'rw switch'.
+func migrateV17AddRoleKubeContext(db *DB) error {
+	_, err := db.Exec(`ALTER TABLE aws_roles ADD COLUMN kube_context TEXT`)
+	return err
+}
+
+// migrateV18AddEnvironmentExpiry adds an optional expiry time for ephemeral
+// environments; expired environments are deactivated by 'rw env expire'.
+func migrateV18AddEnvironmentExpiry(db *DB) error {
+	_, err := db.Exec(`ALTER TABLE environments ADD COLUMN expires_at TIMESTAMP`)
+	return err
+}
+
+// migrateV19AddOwnershipMetadata adds owner, team and contact columns to
+// accounts and roles, shown by 'rw list --long'.
+func migrateV19AddOwnershipMetadata(db *DB) error {
+	for _, table := range []string{"aws_accounts", "aws_roles"} {
+		for _, column := range []string{"owner", "team", "contact"} {
+			if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s TEXT`, table, column)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateV20AddAuditMessage stores audit descriptions as a message catalog ID
+// plus JSON parameters, so they are rendered (and translated) when displayed
+// rather than frozen as text.
+func migrateV20AddAuditMessage(db *DB) error {
+	for _, column := range []string{"message_id", "message_params"} {
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE audit_log ADD COLUMN %s TEXT`, column)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV21AddSessionLastSeen adds a heartbeat to user sessions, updated by
+// every rw command, so idle sessions can be closed.
+func migrateV21AddSessionLastSeen(db *DB) error {
+	if _, err := db.Exec(`ALTER TABLE user_sessions ADD COLUMN last_seen TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`UPDATE user_sessions SET last_seen = COALESCE(session_end, session_start)`)
+	return err
+}
+
+// migrateV22AddEnvironmentAccount records the AWS account each environment
+// lives in, so destructive operations can refuse to run against the wrong
+// account. It is backfilled from the account of the environment's profile.
+func migrateV22AddEnvironmentAccount(db *DB) error {
+	if _, err := db.Exec(`ALTER TABLE environments ADD COLUMN account_id TEXT`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`
+		UPDATE environments SET account_id = (
+			SELECT a.account_id FROM aws_roles r
+			JOIN aws_accounts a ON a.id = r.account_id
+			WHERE r.profile_name = environments.aws_profile
+			LIMIT 1
+		)
+	`)
+	return err
+}
+
+// migrateV23AddEnvironmentProduction flags production environments, which
+// get a banner on every command and a red prompt. Environments named in the
+// production_envs setting count as production whatever the flag says.
+func migrateV23AddEnvironmentProduction(db *DB) error {
+	_, err := db.Exec(`ALTER TABLE environments ADD COLUMN is_production BOOLEAN NOT NULL DEFAULT 0`)
+	return err
+}
+
+// migrateV24CreateSSOSessions stores [sso-session] blocks imported from
+// ~/.aws/config, so generated configs reproduce their registration scopes
+// and any other settings instead of a fixed default.
+func migrateV24CreateSSOSessions(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE sso_sessions (
+			name TEXT PRIMARY KEY,
+			start_url TEXT NOT NULL,
+			region TEXT,
+			registration_scopes TEXT,
+			settings TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	return createConfigVersionTriggers(db, "sso_sessions")
+}
+
+// migrateV25UniquePortMappingLocalPort stops two active port mappings in an
+// environment from sharing a local port, which only fails once both tunnels
+// are started. Triggers are used rather than a unique index so databases
+// that already hold conflicts still migrate; 'rw portmap conflicts' lists
+// them.
+func migrateV25UniquePortMappingLocalPort(db *DB) error {
+	// A service has one mapping per environment, so comparing service IDs
+	// rather than row IDs also lets an upsert update its own row
+	for _, event := range []string{"INSERT", "UPDATE OF environment_id, local_port, active"} {
+		name := strings.ToLower(strings.Fields(event)[0])
+		_, err := db.Exec(fmt.Sprintf(`
+			CREATE TRIGGER trg_port_mappings_%s_local_port BEFORE %s ON port_mappings
+			WHEN NEW.active = 1 AND EXISTS (
+				SELECT 1 FROM port_mappings
+				WHERE environment_id = NEW.environment_id AND local_port = NEW.local_port
+					AND active = 1 AND service_id != NEW.service_id
+			)
+			BEGIN
+				SELECT RAISE(ABORT, '%s');
+			END
+		`, name, event, errLocalPortInUse))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV26AddLookupIndexes indexes the columns role, account and port
+// lookups filter on. Unique constraints already cover aws_roles.profile_name,
+// aws_accounts.account_id and port_mappings(service_id, environment_id).
+func migrateV26AddLookupIndexes(db *DB) error {
+	for _, stmt := range []string{
+		`CREATE INDEX idx_aws_roles_active_profile ON aws_roles(active, profile_name)`,
+		`CREATE INDEX idx_aws_roles_account_active ON aws_roles(account_id, active, role_name)`,
+		`CREATE INDEX idx_aws_accounts_active_name ON aws_accounts(active, account_name)`,
+		`CREATE INDEX idx_user_sessions_role ON user_sessions(role_id)`,
+		`CREATE INDEX idx_port_mappings_environment_port ON port_mappings(environment_id, local_port)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV27AddAccountPartition records each account's AWS partition, so
+// ARNs for GovCloud and China accounts are built with the right prefix.
+// Existing accounts get the partition of their SSO region.
+func migrateV27AddAccountPartition(db *DB) error {
+	if _, err := db.Exec(`ALTER TABLE aws_accounts ADD COLUMN aws_partition TEXT NOT NULL DEFAULT 'aws'`); err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT id, COALESCE(sso_region, '') FROM aws_accounts`)
+	if err != nil {
+		return err
+	}
+	partitions := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var region string
+		if err := rows.Scan(&id, &region); err != nil {
+			rows.Close()
+			return err
+		}
+		partitions[id] = awsarn.PartitionForRegion(region)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, partition := range partitions {
+		if _, err := db.Exec(`UPDATE aws_accounts SET aws_partition = ? WHERE id = ?`, partition, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV28CreateShellIntegrations records which shells have the rw
+// prompt installed and with which components, so the prompt can be
+// reported on and reinstalled on another machine.
+func migrateV28CreateShellIntegrations(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE shell_integrations (
+			shell TEXT PRIMARY KEY,
+			components TEXT NOT NULL,
+			profile_path TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// migrateV29CreateSettings adds a key/value table for rw's own state, such
+// as the version that last ran
+func migrateV29CreateSettings(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// migrateV30AddBackupStatus marks backups that were interrupted part-way,
+// so their partial files are kept but never restored
+func migrateV30AddBackupStatus(db *DB) error {
+	_, err := db.Exec(`ALTER TABLE backups ADD COLUMN status TEXT NOT NULL DEFAULT 'complete'`)
+	return err
+}
+
+// migrateV31CreateJobs tracks long-running operations such as backups,
+// restores and switchovers, so any rw process can list them and notice
+// ones whose process died
+func migrateV31CreateJobs(db *DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			environment TEXT NOT NULL DEFAULT '',
+			target TEXT NOT NULL DEFAULT '',
+			state TEXT NOT NULL DEFAULT 'queued',
+			progress TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			pid INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			started_at TIMESTAMP,
+			ended_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX idx_jobs_state ON jobs(state)`)
+	return err
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errLocalPortInUse is raised by the port_mappings triggers when a local
+// port is already mapped in the environment
+const errLocalPortInUse = "local port already mapped in this environment"
+
+// maxLocalPort is the highest TCP port
+const maxLocalPort = 65535
+
+// LocalPortInUseError reports a local port already mapped to another service
+// in the environment
+type LocalPortInUseError struct {
+	Environment string
+	Port        int
+	Service     string
+	// Suggested is the next free local port in the environment, or 0
+	Suggested int
+}
+
+func (e *LocalPortInUseError) Error() string {
+	msg := fmt.Sprintf("local port %d is already mapped to %s in %s", e.Port, e.Service, e.Environment)
+	if e.Suggested > 0 {
+		msg += fmt.Sprintf("; the next free port is %d", e.Suggested)
+	}
+	return msg
+}
+
+// PortConflict is a local port mapped to several services in an environment
+type PortConflict struct {
+	Environment string
+	Port        int
+	Services    []string
+}
+
+// PortConflicts lists local ports shared by active mappings within an
+// environment, e.g. from before the database enforced unique ports
+func (r *ConfigRepository) PortConflicts() ([]PortConflict, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT e.name, pm.local_port, GROUP_CONCAT(s.name, ',')
+		FROM port_mappings pm
+		JOIN services s ON pm.service_id = s.id
+		JOIN environments e ON pm.environment_id = e.id
+		WHERE pm.active = 1
+		GROUP BY pm.environment_id, pm.local_port
+		HAVING COUNT(*) > 1
+		ORDER BY e.name, pm.local_port
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []PortConflict
+	for rows.Next() {
+		var c PortConflict
+		var services string
+		if err := rows.Scan(&c.Environment, &c.Port, &services); err != nil {
+			return nil, err
+		}
+		c.Services = strings.Split(services, ",")
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, rows.Err()
+}
+
+// localPortOwner returns the service mapped to port in env, other than
+// exceptService, or ""
+func (r *ConfigRepository) localPortOwner(ctx context.Context, envName string, port int, exceptService string) (string, error) {
+	var service string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT s.name
+		FROM port_mappings pm
+		JOIN services s ON pm.service_id = s.id
+		JOIN environments e ON pm.environment_id = e.id
+		WHERE e.name = ? AND pm.local_port = ? AND pm.active = 1 AND s.name != ?
+		LIMIT 1
+	`, envName, port, exceptService).Scan(&service)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return service, err
+}
+
+// NextFreeLocalPort returns the lowest port from from upwards that no
+// active mapping in env uses, ignoring exceptService's own mapping
+func (r *ConfigRepository) NextFreeLocalPort(envName string, from int, exceptService string) (int, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT pm.local_port
+		FROM port_mappings pm
+		JOIN services s ON pm.service_id = s.id
+		JOIN environments e ON pm.environment_id = e.id
+		WHERE e.name = ? AND pm.active = 1 AND pm.local_port >= ? AND s.name != ?
+	`, envName, from, exceptService)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	used := make(map[int]bool)
+	for rows.Next() {
+		var port int
+		if err := rows.Scan(&port); err != nil {
+			return 0, err
+		}
+		used[port] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for port := max(from, 1); port <= maxLocalPort; port++ {
+		if !used[port] {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free local port from %d in %s", from, envName)
+}
+
+// SetPortMapping maps a service's local port in an environment, creating
+// the mapping with the service's default remote port if needed. A port
+// already mapped to another service in the environment is refused with a
+// *LocalPortInUseError that suggests the next free port.
+func (r *ConfigRepository) SetPortMapping(serviceName, envName string, localPort int) error {
+	if localPort < 1 || localPort > maxLocalPort {
+		return fmt.Errorf("invalid local port %d", localPort)
+	}
+
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	owner, err := r.localPortOwner(ctx, envName, localPort, serviceName)
+	if err != nil {
+		return err
+	}
+	if owner != "" {
+		inUse := &LocalPortInUseError{Environment: envName, Port: localPort, Service: owner}
+		inUse.Suggested, _ = r.NextFreeLocalPort(envName, localPort+1, serviceName)
+		return inUse
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO port_mappings (service_id, environment_id, local_port, remote_port)
+		SELECT s.id, e.id, ?, s.default_remote_port
+		FROM services s, environments e
+		WHERE s.name = ? AND e.name = ? AND e.active = 1
+		ON CONFLICT(service_id, environment_id) DO UPDATE SET
+			local_port = excluded.local_port,
+			active = 1,
+			updated_at = CURRENT_TIMESTAMP
+	`, localPort, serviceName, envName)
+	if err != nil {
+		if strings.Contains(err.Error(), errLocalPortInUse) {
+			// Mapped concurrently since the check above
+			return &LocalPortInUseError{Environment: envName, Port: localPort, Service: "another service"}
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("unknown service %s or environment %s", serviceName, envName)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetPortMapping(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	// The seeded db mapping in dev is 5433, and 5434 is free there
+	err = repo.SetPortMapping("redis", "dev", 5433)
+	var inUse *LocalPortInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("SetPortMapping() onto db's port = %v, want a LocalPortInUseError", err)
+	}
+	if inUse.Service != "db" || inUse.Suggested != 5434 {
+		t.Errorf("LocalPortInUseError = %+v, want db and a suggestion of 5434", inUse)
+	}
+
+	if err := repo.SetPortMapping("redis", "dev", 5434); err != nil {
+		t.Fatalf("SetPortMapping() to a free port error: %v", err)
+	}
+	pm, err := repo.GetPortMapping("redis", "dev")
+	if err != nil || pm.LocalPort != 5434 {
+		t.Errorf("GetPortMapping() = %+v, %v, want local port 5434", pm, err)
+	}
+	// Setting a service's own port again is not a conflict
+	if err := repo.SetPortMapping("redis", "dev", 5434); err != nil {
+		t.Errorf("SetPortMapping() to the same port error: %v", err)
+	}
+
+	// Other writes are refused by the triggers
+	_, err = database.Exec(`UPDATE port_mappings SET local_port = 5433 WHERE id = ?`, pm.ID)
+	if err == nil || !strings.Contains(err.Error(), errLocalPortInUse) {
+		t.Errorf("UPDATE onto a used port = %v, want the trigger to refuse it", err)
+	}
+
+	if conflicts, err := repo.PortConflicts(); err != nil || len(conflicts) != 0 {
+		t.Errorf("PortConflicts() = %+v, %v, want none", conflicts, err)
+	}
+}
+
+func TestPortConflicts(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	// Conflicts that predate the triggers
+	if _, err := database.Exec(`DROP TRIGGER trg_port_mappings_update_local_port`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`
+		UPDATE port_mappings SET local_port = 5433
+		WHERE service_id = (SELECT id FROM services WHERE name = 'redis')
+			AND environment_id = (SELECT id FROM environments WHERE name = 'dev')
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts, err := repo.PortConflicts()
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("PortConflicts() = %+v, %v, want one conflict", conflicts, err)
+	}
+	c := conflicts[0]
+	if c.Environment != "dev" || c.Port != 5433 || len(c.Services) != 2 {
+		t.Errorf("PortConflicts() = %+v, want db and redis on dev:5433", c)
+	}
+
+	if port, err := repo.NextFreeLocalPort("dev", 5433, "redis"); err != nil || port != 5434 {
+		t.Errorf("NextFreeLocalPort() = %d, %v, want 5434", port, err)
+	}
+	if port, err := repo.NextFreeLocalPort("dev", 6380, "redis"); err != nil || port != 6380 {
+		t.Errorf("NextFreeLocalPort() from redis's own port = %d, %v, want 6380", port, err)
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// roleFilterFields are the fields a bulk edit can select roles by
+var roleFilterFields = map[string]bool{
+	"account_name": true, "account_id": true, "role_name": true, "profile_name": true, "region": true,
+}
+
+// roleEditColumns are the fields a bulk edit can set
+var roleEditColumns = map[string]bool{
+	"region": true, "description": true,
+}
+
+// RoleFilter selects roles for a bulk edit. Every condition must match;
+// values are compared case-insensitively and may contain * wildcards.
+type RoleFilter map[string]string
+
+// ParseRoleFilter parses comma-separated field=value conditions, e.g.
+// "account_name=Dev,role_name=Admin*"
+func ParseRoleFilter(s string) (RoleFilter, error) {
+	filter := make(RoleFilter)
+	for _, cond := range strings.Split(s, ",") {
+		field, value, ok := strings.Cut(strings.TrimSpace(cond), "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("invalid filter %q: expected field=value", cond)
+		}
+		if !roleFilterFields[field] {
+			return nil, fmt.Errorf("unknown filter field %q (valid: %s)", field, strings.Join(slices.Sorted(maps.Keys(roleFilterFields)), ", "))
+		}
+		filter[field] = strings.TrimSpace(value)
+	}
+	return filter, nil
+}
+
+// RoleEdit is one field of one role changed by a bulk edit
+type RoleEdit struct {
+	RoleID      int
+	ProfileName string
+	AccountName string
+	Field       string
+	Old         string
+	New         string
+}
+
+// PlanRoleEdits returns the changes setting field to value on every active
+// role matching the filter. Roles that already have the value are left out.
+func (r *ConfigRepository) PlanRoleEdits(filter RoleFilter, field, value string) ([]RoleEdit, error) {
+	if !roleEditColumns[field] {
+		return nil, fmt.Errorf("cannot bulk-edit %q (valid: %s)", field, strings.Join(slices.Sorted(maps.Keys(roleEditColumns)), ", "))
+	}
+	if field == "region" && value == "" {
+		return nil, fmt.Errorf("region cannot be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.id, r.profile_name, a.account_name, a.account_id, r.role_name, r.region, COALESCE(r.description, '')
+		FROM aws_roles r
+		JOIN aws_accounts a ON r.account_id = a.id
+		WHERE r.active = 1
+		ORDER BY r.profile_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edits []RoleEdit
+	for rows.Next() {
+		var e RoleEdit
+		fields := make(map[string]string)
+		var accountID, roleName, region, description string
+		if err := rows.Scan(&e.RoleID, &e.ProfileName, &e.AccountName, &accountID, &roleName, &region, &description); err != nil {
+			return nil, err
+		}
+		fields["account_name"], fields["account_id"], fields["role_name"] = e.AccountName, accountID, roleName
+		fields["profile_name"], fields["region"], fields["description"] = e.ProfileName, region, description
+
+		if !filter.matches(fields) || fields[field] == value {
+			continue
+		}
+		e.Field, e.Old, e.New = field, fields[field], value
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
+
+// matches reports whether every condition matches the role's fields
+func (f RoleFilter) matches(fields map[string]string) bool {
+	for field, pattern := range f {
+		matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(fields[field]))
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyRoleEdits applies planned edits in one transaction. If any role has
+// changed since the edits were planned, nothing is applied.
+func (r *ConfigRepository) ApplyRoleEdits(edits []RoleEdit) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range edits {
+		if !roleEditColumns[e.Field] {
+			return fmt.Errorf("invalid column name: %s", e.Field)
+		}
+		query := fmt.Sprintf(`
+			UPDATE aws_roles SET %[1]s = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND active = 1 AND COALESCE(%[1]s, '') = ?
+		`, e.Field)
+		res, err := tx.ExecContext(ctx, query, sql.NullString{String: e.New, Valid: e.New != ""}, e.RoleID, e.Old)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("role %s changed since the preview; nothing was applied", e.ProfileName)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoleBulkEdit(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	for _, acc := range []struct{ id, name string }{{"000000000061", "Dev"}, {"000000000062", "Prod"}} {
+		if err := repo.AddAWSAccount(acc.id, acc.name, "https://d-61.awsapps.com/start", "eu-west-2", ""); err != nil {
+			t.Fatalf("AddAWSAccount() error: %v", err)
+		}
+		account, err := repo.GetAWSAccount(acc.id)
+		if err != nil {
+			t.Fatalf("GetAWSAccount() error: %v", err)
+		}
+		for _, role := range []string{"Admin", "ReadOnly"} {
+			profile := strings.ToLower(acc.name + "-" + role)
+			if err := repo.AddAWSRole(account.ID, role, "", profile, "eu-west-2", ""); err != nil {
+				t.Fatalf("AddAWSRole() error: %v", err)
+			}
+		}
+	}
+
+	filter, err := ParseRoleFilter("account_name=dev")
+	if err != nil {
+		t.Fatalf("ParseRoleFilter() error: %v", err)
+	}
+	edits, err := repo.PlanRoleEdits(filter, "region", "eu-west-1")
+	if err != nil {
+		t.Fatalf("PlanRoleEdits() error: %v", err)
+	}
+	if len(edits) != 2 || edits[0].ProfileName != "dev-admin" || edits[0].Old != "eu-west-2" {
+		t.Fatalf("PlanRoleEdits() = %+v, want both Dev roles", edits)
+	}
+	if err := repo.ApplyRoleEdits(edits); err != nil {
+		t.Fatalf("ApplyRoleEdits() error: %v", err)
+	}
+	for profile, want := range map[string]string{"dev-admin": "eu-west-1", "dev-readonly": "eu-west-1", "prod-admin": "eu-west-2"} {
+		role, err := repo.GetRoleByProfileName(profile)
+		if err != nil || role.Region != want {
+			t.Errorf("%s region = %+v, %v, want %s", profile, role, err, want)
+		}
+	}
+	if edits, _ := repo.PlanRoleEdits(filter, "region", "eu-west-1"); len(edits) != 0 {
+		t.Errorf("PlanRoleEdits() after applying = %+v, want nothing left to change", edits)
+	}
+
+	// A role changed after the preview aborts the whole edit
+	filter, _ = ParseRoleFilter("role_name=Admin*")
+	edits, err = repo.PlanRoleEdits(filter, "description", "Break-glass")
+	if err != nil || len(edits) != 2 {
+		t.Fatalf("PlanRoleEdits() = %+v, %v, want both Admin roles", edits, err)
+	}
+	if err := repo.AnnotateRole("prod-admin", map[string]string{"description": "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ApplyRoleEdits(edits); err == nil {
+		t.Error("ApplyRoleEdits() after a concurrent change should fail")
+	}
+	if role, _ := repo.GetRoleByProfileName("dev-admin"); role.Description.Valid {
+		t.Errorf("dev-admin description = %q, want the failed edit rolled back", role.Description.String)
+	}
+
+	for _, bad := range []string{"account_name", "owner=alice"} {
+		if _, err := ParseRoleFilter(bad); err == nil {
+			t.Errorf("ParseRoleFilter(%q) should fail", bad)
+		}
+	}
+	if _, err := repo.PlanRoleEdits(filter, "role_arn", "x"); err == nil {
+		t.Error("PlanRoleEdits() should refuse fields other than region and description")
+	}
+}
@@ -3,8 +3,8 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"os"
 	"path/filepath"
+	"rolewalkers/internal/utils"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,19 +15,35 @@ type DB struct {
 	*sql.DB
 }
 
-// NewDB creates a new database connection
-func NewDB() (*DB, error) {
-	homeDir, err := os.UserHomeDir()
+// Path returns the database file path in the state directory, creating
+// the directory if needed
+func Path() (string, error) {
+	dbDir, err := utils.RoleWalkersDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
+	return filepath.Join(dbDir, "config.db"), nil
+}
 
-	dbDir := filepath.Join(homeDir, ".rolewalkers")
-	if err := os.MkdirAll(dbDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+// NewDB creates a new database connection. Failures are returned as
+// *UnavailableError, so callers can run in degraded mode and explain how to
+// repair the database.
+func NewDB() (*DB, error) {
+	dbPath, err := Path()
+	if err != nil {
+		return nil, &UnavailableError{Err: err}
 	}
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, &UnavailableError{Path: dbPath, Err: err}
+	}
+	return db, nil
+}
 
-	dbPath := filepath.Join(dbDir, "config.db")
+func openDB(dbPath string) (*DB, error) {
+	if err := utils.CheckOwnership(dbPath); err != nil {
+		return nil, err
+	}
 	sqlDB, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -60,6 +76,51 @@ func NewDB() (*DB, error) {
 	return db, nil
 }
 
+// migrations are applied in order by migrate; LatestSchemaVersion is the
+// last one
+var migrations = []struct {
+	version int
+	name    string
+	up      func(*DB) error
+}{
+	{1, "create_environments", migrateV1CreateEnvironments},
+	{2, "create_services", migrateV2CreateServices},
+	{3, "create_port_mappings", migrateV3CreatePortMappings},
+	{4, "create_scaling_presets", migrateV4CreateScalingPresets},
+	{5, "create_api_endpoints", migrateV5CreateAPIEndpoints},
+	{6, "create_cluster_mappings", migrateV6CreateClusterMappings},
+	{7, "seed_default_data", migrateV7SeedDefaultData},
+	{8, "create_aws_accounts", migrateV8CreateAWSAccounts},
+	{9, "create_aws_roles", migrateV9CreateAWSRoles},
+	{10, "create_user_sessions", migrateV10CreateUserSessions},
+	{11, "add_command_db_port_mappings", migrateV11AddCommandDBPortMappings},
+	{12, "fix_shared_account_envs", migrateV12FixSharedAccountEnvs},
+	{13, "create_audit_log", migrateV13CreateAuditLog},
+	{14, "create_config_version", migrateV14CreateConfigVersion},
+	{15, "create_aliases", migrateV15CreateAliases},
+	{16, "create_backups", migrateV16CreateBackups},
+	{17, "add_role_kube_context", migrateV17AddRoleKubeContext},
+	{18, "add_environment_expiry", migrateV18AddEnvironmentExpiry},
+	{19, "add_ownership_metadata", migrateV19AddOwnershipMetadata},
+	{20, "add_audit_message", migrateV20AddAuditMessage},
+	{21, "add_session_last_seen", migrateV21AddSessionLastSeen},
+	{22, "add_environment_account", migrateV22AddEnvironmentAccount},
+	{23, "add_environment_production", migrateV23AddEnvironmentProduction},
+	{24, "create_sso_sessions", migrateV24CreateSSOSessions},
+	{25, "unique_port_mapping_local_port", migrateV25UniquePortMappingLocalPort},
+	{26, "add_lookup_indexes", migrateV26AddLookupIndexes},
+	{27, "add_account_partition", migrateV27AddAccountPartition},
+	{28, "create_shell_integrations", migrateV28CreateShellIntegrations},
+	{29, "create_settings", migrateV29CreateSettings},
+	{30, "add_backup_status", migrateV30AddBackupStatus},
+	{31, "create_jobs", migrateV31CreateJobs},
+}
+
+// LatestSchemaVersion returns the schema version this build migrates to
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
 // migrate runs all database migrations
 func (db *DB) migrate() error {
 	// Create migrations table
@@ -67,26 +128,6 @@ func (db *DB) migrate() error {
 		return err
 	}
 
-	// Run migrations in order
-	migrations := []struct {
-		version int
-		name    string
-		up      func(*DB) error
-	}{
-		{1, "create_environments", migrateV1CreateEnvironments},
-		{2, "create_services", migrateV2CreateServices},
-		{3, "create_port_mappings", migrateV3CreatePortMappings},
-		{4, "create_scaling_presets", migrateV4CreateScalingPresets},
-		{5, "create_api_endpoints", migrateV5CreateAPIEndpoints},
-		{6, "create_cluster_mappings", migrateV6CreateClusterMappings},
-		{7, "seed_default_data", migrateV7SeedDefaultData},
-		{8, "create_aws_accounts", migrateV8CreateAWSAccounts},
-		{9, "create_aws_roles", migrateV9CreateAWSRoles},
-		{10, "create_user_sessions", migrateV10CreateUserSessions},
-		{11, "add_command_db_port_mappings", migrateV11AddCommandDBPortMappings},
-		{12, "fix_shared_account_envs", migrateV12FixSharedAccountEnvs},
-	}
-
 	for _, m := range migrations {
 		if err := db.runMigration(m.version, m.name, m.up); err != nil {
 			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
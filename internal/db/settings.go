@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Settings keys
+const (
+	// SettingLastRunVersion is the rw version that last ran against the database
+	SettingLastRunVersion = "last_run_version"
+	// SettingChangelogVersion is the newest release whose notes 'rw changelog' showed
+	SettingChangelogVersion = "changelog_version"
+	// SettingAuditForwardedPrefix, plus a sink name, holds the ID of the last
+	// audit entry rw-tray forwarded to that sink
+	SettingAuditForwardedPrefix = "audit_forwarded_"
+)
+
+// GetSetting returns a setting's value, or "" if it was never set
+func (r *ConfigRepository) GetSetting(key string) (string, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	var value string
+	err := r.db.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetSetting stores a setting's value
+func (r *ConfigRepository) SetSetting(key, value string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+	`, key, value)
+	return err
+}
@@ -0,0 +1,25 @@
+package db
+
+import "testing"
+
+func TestSettings(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	if got, err := repo.GetSetting(SettingLastRunVersion); err != nil || got != "" {
+		t.Errorf("GetSetting() unset = %q, %v, want empty", got, err)
+	}
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		if err := repo.SetSetting(SettingLastRunVersion, v); err != nil {
+			t.Fatalf("SetSetting(%q) error: %v", v, err)
+		}
+	}
+	if got, err := repo.GetSetting(SettingLastRunVersion); err != nil || got != "1.1.0" {
+		t.Errorf("GetSetting() = %q, %v, want 1.1.0", got, err)
+	}
+}
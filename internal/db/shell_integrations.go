@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ShellIntegration is the rw prompt as installed into one shell's profile
+type ShellIntegration struct {
+	Shell       string
+	Components  []string
+	ProfilePath string
+	UpdatedAt   time.Time
+}
+
+// GetShellIntegrations returns the recorded prompt installations by shell
+func (r *ConfigRepository) GetShellIntegrations() ([]ShellIntegration, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT shell, components, profile_path, updated_at
+		FROM shell_integrations
+		ORDER BY shell
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var integrations []ShellIntegration
+	for rows.Next() {
+		var si ShellIntegration
+		var components string
+		if err := rows.Scan(&si.Shell, &components, &si.ProfilePath, &si.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if components != "" {
+			si.Components = strings.Split(components, ",")
+		}
+		integrations = append(integrations, si)
+	}
+	return integrations, rows.Err()
+}
+
+// SaveShellIntegration records the prompt installed into a shell's profile,
+// replacing any earlier record for the shell
+func (r *ConfigRepository) SaveShellIntegration(shell string, components []string, profilePath string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO shell_integrations (shell, components, profile_path) VALUES (?, ?, ?)
+		ON CONFLICT(shell) DO UPDATE SET
+			components = excluded.components,
+			profile_path = excluded.profile_path,
+			updated_at = CURRENT_TIMESTAMP
+	`, shell, strings.Join(components, ","), profilePath)
+	return err
+}
+
+// DeleteShellIntegration forgets the prompt of a shell after it is removed
+func (r *ConfigRepository) DeleteShellIntegration(shell string) error {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM shell_integrations WHERE shell = ?`, shell)
+	return err
+}
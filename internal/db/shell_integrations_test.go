@@ -0,0 +1,46 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShellIntegrations(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+
+	if err := repo.SaveShellIntegration("zsh", []string{"aws", "k8s"}, "/home/a/.zshrc"); err != nil {
+		t.Fatalf("SaveShellIntegration() error: %v", err)
+	}
+	if err := repo.SaveShellIntegration("bash", []string{"git"}, "/home/a/.bashrc"); err != nil {
+		t.Fatalf("SaveShellIntegration() error: %v", err)
+	}
+	if err := repo.SaveShellIntegration("zsh", []string{"time", "aws"}, "/home/b/.zshrc"); err != nil {
+		t.Fatalf("SaveShellIntegration() replace error: %v", err)
+	}
+
+	integrations, err := repo.GetShellIntegrations()
+	if err != nil {
+		t.Fatalf("GetShellIntegrations() error: %v", err)
+	}
+	if len(integrations) != 2 || integrations[0].Shell != "bash" || integrations[1].Shell != "zsh" {
+		t.Fatalf("GetShellIntegrations() = %+v, want bash and zsh", integrations)
+	}
+	zsh := integrations[1]
+	if !slices.Equal(zsh.Components, []string{"time", "aws"}) || zsh.ProfilePath != "/home/b/.zshrc" {
+		t.Errorf("zsh integration = %+v, want the replacing record", zsh)
+	}
+
+	if err := repo.DeleteShellIntegration("zsh"); err != nil {
+		t.Fatalf("DeleteShellIntegration() error: %v", err)
+	}
+	integrations, err = repo.GetShellIntegrations()
+	if err != nil || len(integrations) != 1 || integrations[0].Shell != "bash" {
+		t.Errorf("GetShellIntegrations() after delete = %+v, %v, want only bash", integrations, err)
+	}
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// Snapshot writes a consistent copy of the database to path, which must
+// not exist yet. Role sessions are left out: they belong to this machine.
+func (db *DB) Snapshot(path string) error {
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to copy the database: %w", err)
+	}
+
+	snapshot, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to open the database copy: %w", err)
+	}
+	defer snapshot.Close()
+	if _, err := snapshot.Exec(`DELETE FROM user_sessions`); err != nil {
+		return fmt.Errorf("failed to clear sessions from the database copy: %w", err)
+	}
+	return nil
+}
+
+// Replace makes the database file at newPath the database at path. The
+// current database, if any, is kept as <path>.pre-import-<timestamp>,
+// whose path is returned. newPath is opened first, so a file that isn't a
+// usable database leaves the current one untouched, and if the installed
+// file still can't be opened the current one is put back. Close the
+// database before replacing it.
+func Replace(path, newPath string) (string, error) {
+	if err := checkOpens(newPath); err != nil {
+		return "", fmt.Errorf("the imported database cannot be opened: %w", err)
+	}
+
+	previous := ""
+	if _, err := os.Stat(path); err == nil {
+		moved, err := moveAside(path, "pre-import")
+		if err != nil {
+			return moved, err
+		}
+		previous = moved
+	}
+	installErr := os.Rename(newPath, path)
+	if installErr == nil {
+		if err := checkOpens(path); err != nil {
+			installErr = fmt.Errorf("the imported database cannot be opened: %w", err)
+		}
+	}
+	if installErr != nil {
+		if previous == "" {
+			return "", installErr
+		}
+		if err := putBack(previous, path); err != nil {
+			return previous, fmt.Errorf("%w; the previous database is at %s: %v", installErr, previous, err)
+		}
+		return "", fmt.Errorf("%w; the previous database was put back", installErr)
+	}
+	return previous, nil
+}
+
+// checkOpens opens and closes the database at path, applying migrations
+func checkOpens(path string) error {
+	database, err := openDB(path)
+	if err != nil {
+		return err
+	}
+	return database.Close()
+}
+
+// putBack undoes moveAside, replacing whatever is at path
+func putBack(moved, path string) error {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Rename(moved+suffix, path+suffix); err != nil && (suffix == "" || !os.IsNotExist(err)) {
+			return err
+		}
+	}
+	return nil
+}
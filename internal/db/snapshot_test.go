@@ -0,0 +1,85 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotAndReplace(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("RW_STATE_DIR", dir)
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	repo := NewConfigRepository(database)
+	if err := repo.SetAlias("pdb", "db connect prod --write"); err != nil {
+		t.Fatalf("SetAlias() error: %v", err)
+	}
+	res, err := database.Exec(`INSERT INTO aws_accounts (account_id, account_name) VALUES ('111111111111', 'snapshot-dev')`)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	accountID, _ := res.LastInsertId()
+	res, err = database.Exec(`INSERT INTO aws_roles (account_id, role_name, profile_name) VALUES (?, 'ReadOnly', 'snapshot-dev-ro')`, accountID)
+	if err != nil {
+		t.Fatalf("insert role: %v", err)
+	}
+	roleID, _ := res.LastInsertId()
+	if _, err := database.Exec(`INSERT INTO user_sessions (role_id) VALUES (?)`, roleID); err != nil {
+		t.Fatalf("insert session: %v", err)
+	}
+
+	// A file that isn't a database leaves the current one in place
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.db")
+	database.Close()
+	if previous, err := Replace(path, garbage); err == nil || previous != "" {
+		t.Fatalf("Replace() with a corrupt file = %q, %v, want an error", previous, err)
+	}
+	if database, err = NewDB(); err != nil {
+		t.Fatalf("NewDB() after a failed Replace() error: %v", err)
+	}
+	repo = NewConfigRepository(database)
+	if _, err := repo.GetAlias("pdb"); err != nil {
+		t.Fatalf("GetAlias(pdb) after a failed Replace(): %v", err)
+	}
+
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := database.Snapshot(snapshot); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	if err := repo.SetAlias("later", "status"); err != nil {
+		t.Fatalf("SetAlias() error: %v", err)
+	}
+	database.Close()
+
+	previous, err := Replace(path, snapshot)
+	if err != nil {
+		t.Fatalf("Replace() error: %v", err)
+	}
+	if _, err := os.Stat(previous); err != nil {
+		t.Errorf("previous database not kept at %q: %v", previous, err)
+	}
+
+	database, err = NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() after Replace() error: %v", err)
+	}
+	defer database.Close()
+	repo = NewConfigRepository(database)
+	if _, err := repo.GetAlias("pdb"); err != nil {
+		t.Errorf("GetAlias(pdb) after import: %v", err)
+	}
+	if _, err := repo.GetAlias("later"); err == nil {
+		t.Error("alias created after the snapshot should not be imported")
+	}
+	var sessions int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM user_sessions`).Scan(&sessions); err != nil || sessions != 0 {
+		t.Errorf("snapshot has %d sessions (err %v), want 0", sessions, err)
+	}
+}
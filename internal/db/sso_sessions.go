@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SSOSession is an [sso-session] block from ~/.aws/config
+type SSOSession struct {
+	Name               string
+	StartURL           string
+	Region             string
+	RegistrationScopes string
+	// Settings are the block's other keys, in file order
+	Settings []SSOSessionSetting
+}
+
+// SSOSessionSetting is a key = value line of an sso-session block
+type SSOSessionSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// pruneSSOSessions deletes stale sso-sessions: those whose start URL no
+// active account uses any more, e.g. after the accounts were removed. keep
+// names a session that is never pruned, the one being saved.
+func (r *ConfigRepository) pruneSSOSessions(ctx context.Context, keep string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM sso_sessions
+		WHERE name != ? AND start_url NOT IN (
+			SELECT sso_start_url FROM aws_accounts
+			WHERE active = 1 AND sso_start_url IS NOT NULL
+		)
+	`, keep)
+	return err
+}
+
+// GetAllSSOSessions retrieves all sso-session blocks ordered by name,
+// pruning stale ones first
+func (r *ConfigRepository) GetAllSSOSessions() ([]SSOSession, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	if err := r.pruneSSOSessions(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, start_url, region, registration_scopes, settings
+		FROM sso_sessions
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []SSOSession
+	for rows.Next() {
+		var s SSOSession
+		var region, scopes, settings sql.NullString
+		if err := rows.Scan(&s.Name, &s.StartURL, &region, &scopes, &settings); err != nil {
+			return nil, err
+		}
+		s.Region = region.String
+		s.RegistrationScopes = scopes.String
+		if settings.String != "" {
+			if err := json.Unmarshal([]byte(settings.String), &s.Settings); err != nil {
+				return nil, fmt.Errorf("sso-session %s: invalid settings: %w", s.Name, err)
+			}
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// SaveSSOSession creates an sso-session or replaces a stored one with the
+// same name, pruning other stale sessions. It reports whether anything
+// changed.
+func (r *ConfigRepository) SaveSSOSession(s SSOSession) (bool, error) {
+	ctx, cancel := context.WithTimeout(r.context(), 5*time.Second)
+	defer cancel()
+
+	if err := r.pruneSSOSessions(ctx, s.Name); err != nil {
+		return false, err
+	}
+
+	var settings sql.NullString
+	if len(s.Settings) > 0 {
+		data, err := json.Marshal(s.Settings)
+		if err != nil {
+			return false, err
+		}
+		settings = sql.NullString{String: string(data), Valid: true}
+	}
+	nullable := func(v string) sql.NullString { return sql.NullString{String: v, Valid: v != ""} }
+
+	// The WHERE clause skips no-op updates, so they don't bump config_version
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO sso_sessions (name, start_url, region, registration_scopes, settings)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			start_url = excluded.start_url,
+			region = excluded.region,
+			registration_scopes = excluded.registration_scopes,
+			settings = excluded.settings,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE sso_sessions.start_url IS NOT excluded.start_url
+			OR sso_sessions.region IS NOT excluded.region
+			OR sso_sessions.registration_scopes IS NOT excluded.registration_scopes
+			OR sso_sessions.settings IS NOT excluded.settings
+	`, s.Name, s.StartURL, nullable(s.Region), nullable(s.RegistrationScopes), settings)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
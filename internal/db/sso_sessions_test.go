@@ -0,0 +1,60 @@
+package db
+
+import "testing"
+
+func TestSSOSessions(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	database, err := NewDB()
+	if err != nil {
+		t.Fatalf("NewDB() error: %v", err)
+	}
+	defer database.Close()
+	repo := NewConfigRepository(database)
+	if err := repo.AddAWSAccount("000000000071", "corp", "https://d-123.awsapps.com/start", "eu-west-2", ""); err != nil {
+		t.Fatalf("AddAWSAccount() error: %v", err)
+	}
+
+	session := SSOSession{
+		Name:               "corp",
+		StartURL:           "https://d-123.awsapps.com/start",
+		Region:             "eu-west-2",
+		RegistrationScopes: "sso:account:access,codewhisperer:completions",
+		Settings:           []SSOSessionSetting{{Key: "sso_custom", Value: "1"}, {Key: "another", Value: "x = y"}},
+	}
+	if changed, err := repo.SaveSSOSession(session); err != nil || !changed {
+		t.Fatalf("SaveSSOSession() = %v, %v, want a new session", changed, err)
+	}
+	if changed, err := repo.SaveSSOSession(session); err != nil || changed {
+		t.Errorf("SaveSSOSession() unchanged = %v, %v, want no change", changed, err)
+	}
+
+	sessions, err := repo.GetAllSSOSessions()
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("GetAllSSOSessions() = %+v, %v, want one session", sessions, err)
+	}
+	got := sessions[0]
+	if got.RegistrationScopes != session.RegistrationScopes || len(got.Settings) != 2 || got.Settings[1] != session.Settings[1] {
+		t.Errorf("GetAllSSOSessions() = %+v, want %+v", got, session)
+	}
+
+	session.Region = "us-east-1"
+	session.Settings = nil
+	if changed, err := repo.SaveSSOSession(session); err != nil || !changed {
+		t.Fatalf("SaveSSOSession() update = %v, %v, want a change", changed, err)
+	}
+	sessions, _ = repo.GetAllSSOSessions()
+	if sessions[0].Region != "us-east-1" || len(sessions[0].Settings) != 0 {
+		t.Errorf("after update = %+v", sessions[0])
+	}
+
+	// A session no account uses any more is kept while it's saved, then
+	// pruned
+	stale := SSOSession{Name: "old", StartURL: "https://d-999.awsapps.com/start"}
+	if changed, err := repo.SaveSSOSession(stale); err != nil || !changed {
+		t.Fatalf("SaveSSOSession(stale) = %v, %v, want a new session", changed, err)
+	}
+	sessions, err = repo.GetAllSSOSessions()
+	if err != nil || len(sessions) != 1 || sessions[0].Name != "corp" {
+		t.Errorf("GetAllSSOSessions() = %+v, %v, want only corp", sessions, err)
+	}
+}
@@ -0,0 +1,299 @@
+// Package fastly provides a small typed client for the parts of the Fastly
+// API used by rolewalkers. It handles pagination, rate limiting (HTTP 429)
+// and per-request timeouts so callers only deal with typed results.
+package fastly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public Fastly API endpoint
+const DefaultBaseURL = "https://api.fastly.com"
+
+const (
+	// defaultTimeout bounds a single HTTP request, including retries' waits
+	defaultTimeout = 30 * time.Second
+
+	// defaultMaxRetries is how often a rate-limited or 5xx request is retried
+	defaultMaxRetries = 3
+
+	// maxRetryWait caps how long we honour a Retry-After header
+	maxRetryWait = 30 * time.Second
+
+	// pageSize is the number of items requested per page for list endpoints
+	pageSize = 100
+
+	// maxBodySize limits how much of a response body is read
+	maxBodySize = 10 * 1024 * 1024
+)
+
+// Service is a Fastly service as returned by the list and search endpoints
+type Service struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Version is a single configuration version of a service
+type Version struct {
+	Number int  `json:"number"`
+	Active bool `json:"active"`
+}
+
+// ServiceDetail is the detailed view of a service
+type ServiceDetail struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Versions []Version `json:"versions"`
+}
+
+// ActiveVersion returns the number of the active version
+func (sd ServiceDetail) ActiveVersion() (int, error) {
+	for _, v := range sd.Versions {
+		if v.Active {
+			return v.Number, nil
+		}
+	}
+	return 0, fmt.Errorf("no active version found")
+}
+
+// Dictionary is an edge dictionary attached to a service version
+type Dictionary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DictionaryItem is a single key/value entry of an edge dictionary
+type DictionaryItem struct {
+	DictionaryID string `json:"dictionary_id"`
+	ItemKey      string `json:"item_key"`
+	ItemValue    string `json:"item_value"`
+}
+
+// APIError is returned when Fastly responds with a non-2xx status
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Fastly API error: %s %s (HTTP %d): %s", e.Method, e.Path, e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// IsNotFound reports whether err is a Fastly 404 response
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client is a Fastly API client
+type Client struct {
+	baseURL    string
+	apiToken   string
+	httpClient *http.Client
+	timeout    time.Duration
+	maxRetries int
+
+	// sleep is replaced in tests to avoid real waits
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient creates a Fastly client. An empty baseURL uses DefaultBaseURL.
+func NewClient(baseURL, apiToken string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiToken:   apiToken,
+		httpClient: &http.Client{},
+		timeout:    defaultTimeout,
+		maxRetries: defaultMaxRetries,
+		sleep:      sleepContext,
+	}
+}
+
+// HasToken reports whether an API token is configured
+func (c *Client) HasToken() bool {
+	return c.apiToken != ""
+}
+
+// ListServices returns every service on the account, following pagination
+func (c *Client) ListServices(ctx context.Context) ([]Service, error) {
+	var all []Service
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(pageSize))
+
+		var services []Service
+		header, err := c.do(ctx, http.MethodGet, "/service?"+query.Encode(), nil, &services)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, services...)
+
+		if !hasNextPage(header, len(services)) {
+			return all, nil
+		}
+	}
+}
+
+// SearchService looks up a service by its exact name
+func (c *Client) SearchService(ctx context.Context, name string) (Service, error) {
+	var svc Service
+	_, err := c.do(ctx, http.MethodGet, "/service/search?name="+url.QueryEscape(name), nil, &svc)
+	return svc, err
+}
+
+// GetServiceDetail returns a service with its versions
+func (c *Client) GetServiceDetail(ctx context.Context, serviceID string) (ServiceDetail, error) {
+	var detail ServiceDetail
+	_, err := c.do(ctx, http.MethodGet, "/service/"+url.PathEscape(serviceID), nil, &detail)
+	return detail, err
+}
+
+// GetDictionary returns a named edge dictionary of a service version
+func (c *Client) GetDictionary(ctx context.Context, serviceID string, version int, name string) (Dictionary, error) {
+	var dict Dictionary
+	path := fmt.Sprintf("/service/%s/version/%d/dictionary/%s", url.PathEscape(serviceID), version, url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodGet, path, nil, &dict)
+	return dict, err
+}
+
+// GetDictionaryItem returns a single edge dictionary item
+func (c *Client) GetDictionaryItem(ctx context.Context, serviceID, dictionaryID, key string) (DictionaryItem, error) {
+	var item DictionaryItem
+	_, err := c.do(ctx, http.MethodGet, dictionaryItemPath(serviceID, dictionaryID, key), nil, &item)
+	return item, err
+}
+
+// UpdateDictionaryItem creates or updates an edge dictionary item
+func (c *Client) UpdateDictionaryItem(ctx context.Context, serviceID, dictionaryID, key, value string) error {
+	form := url.Values{}
+	form.Set("item_value", value)
+	_, err := c.do(ctx, http.MethodPut, dictionaryItemPath(serviceID, dictionaryID, key), form, nil)
+	return err
+}
+
+func dictionaryItemPath(serviceID, dictionaryID, key string) string {
+	return fmt.Sprintf("/service/%s/dictionary/%s/item/%s",
+		url.PathEscape(serviceID), url.PathEscape(dictionaryID), url.PathEscape(key))
+}
+
+// do performs a request, retrying on HTTP 429 and 5xx responses, and decodes
+// a successful JSON response into out (when non-nil).
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, out interface{}) (http.Header, error) {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := c.send(ctx, method, path, form)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if out != nil && len(body) > 0 {
+				if err := json.Unmarshal(body, out); err != nil {
+					return nil, fmt.Errorf("failed to parse Fastly response for %s %s: %w", method, path, err)
+				}
+			}
+			return resp.Header, nil
+		}
+
+		apiErr := &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Body: string(body)}
+		if !retryable(resp.StatusCode) || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		if err := c.sleep(ctx, retryDelay(resp.Header, attempt)); err != nil {
+			return nil, fmt.Errorf("%w (gave up waiting to retry: %v)", apiErr, err)
+		}
+	}
+}
+
+// send performs a single HTTP round trip and reads the response body
+func (c *Client) send(ctx context.Context, method, path string, form url.Values) (*http.Response, []byte, error) {
+	var reqBody io.Reader
+	if form != nil {
+		reqBody = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Fastly-Key", c.apiToken)
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryDelay honours Retry-After (seconds) or Fastly-RateLimit-Reset (Unix
+// time) when present and falls back to exponential backoff otherwise.
+func retryDelay(header http.Header, attempt int) time.Duration {
+	if s := header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, maxRetryWait)
+		}
+	}
+	if s := header.Get("Fastly-RateLimit-Reset"); s != "" {
+		if reset, err := strconv.ParseInt(s, 10, 64); err == nil {
+			if d := time.Until(time.Unix(reset, 0)); d > 0 {
+				return min(d, maxRetryWait)
+			}
+		}
+	}
+	return min(time.Duration(1<<attempt)*time.Second, maxRetryWait)
+}
+
+// hasNextPage reports whether a list endpoint has more results. Fastly
+// advertises further pages via a Link header; without one, a full page
+// means there may be more.
+func hasNextPage(header http.Header, count int) bool {
+	if link := header.Get("Link"); link != "" {
+		return strings.Contains(link, `rel="next"`)
+	}
+	return count >= pageSize
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
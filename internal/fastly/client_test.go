@@ -0,0 +1,147 @@
+package fastly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c := NewClient(srv.URL, "test-token")
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return c, &waits
+}
+
+func TestListServicesPaginates(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Fastly-Key") != "test-token" {
+			t.Errorf("missing Fastly-Key header")
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		// Two full pages followed by a partial one
+		count := pageSize
+		if page == 3 {
+			count = 5
+		}
+		fmt.Fprint(w, "[")
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":"id-%d-%d","name":"svc-%d-%d"}`, page, i, page, i)
+		}
+		fmt.Fprint(w, "]")
+	})
+
+	services, err := c.ListServices(context.Background())
+	if err != nil {
+		t.Fatalf("ListServices() error = %v", err)
+	}
+	if want := 2*pageSize + 5; len(services) != want {
+		t.Errorf("ListServices() returned %d services, want %d", len(services), want)
+	}
+}
+
+func TestRetriesOnRateLimit(t *testing.T) {
+	calls := 0
+	c, waits := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"item_value":"true"}`)
+	})
+
+	item, err := c.GetDictionaryItem(context.Background(), "svc", "dict", "maintenanceMode")
+	if err != nil {
+		t.Fatalf("GetDictionaryItem() error = %v", err)
+	}
+	if item.ItemValue != "true" {
+		t.Errorf("ItemValue = %q, want %q", item.ItemValue, "true")
+	}
+	if calls != 3 {
+		t.Errorf("server called %d times, want 3", calls)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 2*time.Second {
+		t.Errorf("waits = %v, want two 2s waits", *waits)
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := c.GetServiceDetail(context.Background(), "svc")
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("GetServiceDetail() error = %v, want 429 APIError", err)
+	}
+}
+
+func TestNotFoundIsNotRetried(t *testing.T) {
+	calls := 0
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"msg":"Record not found"}`)
+	})
+
+	_, err := c.GetDictionaryItem(context.Background(), "svc", "dict", "maintenanceMode")
+	if !IsNotFound(err) {
+		t.Errorf("error = %v, want not found", err)
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+}
+
+func TestUpdateDictionaryItemSendsForm(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if r.URL.Path != "/service/svc/dictionary/dict/item/maintenanceMode" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.PostForm.Get("item_value"); got != "false" {
+			t.Errorf("item_value = %q, want %q", got, "false")
+		}
+		fmt.Fprint(w, `{}`)
+	})
+
+	if err := c.UpdateDictionaryItem(context.Background(), "svc", "dict", "maintenanceMode", "false"); err != nil {
+		t.Fatalf("UpdateDictionaryItem() error = %v", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	h := http.Header{}
+	if d := retryDelay(h, 0); d != time.Second {
+		t.Errorf("backoff attempt 0 = %v, want 1s", d)
+	}
+	if d := retryDelay(h, 2); d != 4*time.Second {
+		t.Errorf("backoff attempt 2 = %v, want 4s", d)
+	}
+	h.Set("Retry-After", "600")
+	if d := retryDelay(h, 0); d != maxRetryWait {
+		t.Errorf("Retry-After 600 = %v, want capped at %v", d, maxRetryWait)
+	}
+}
@@ -0,0 +1,66 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfigFile is the subset of a kubeconfig file needed to find the
+// current context and its namespace
+type kubeconfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// KubeconfigPaths returns the files kubectl merges: $KUBECONFIG, else
+// ~/.kube/config
+func KubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// ReadKubeconfigContext returns the current kubectl context and namespace by
+// reading the kubeconfig files directly, without running kubectl. Like
+// kubectl, the first file to set a value wins. The namespace defaults to
+// "default"; the context is empty if none is set.
+func ReadKubeconfigContext() (string, string) {
+	current := ""
+	namespaces := make(map[string]string)
+	for _, path := range KubeconfigPaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var kc kubeconfigFile
+		if err := yaml.Unmarshal(data, &kc); err != nil {
+			continue
+		}
+		if current == "" {
+			current = kc.CurrentContext
+		}
+		for _, ctx := range kc.Contexts {
+			if _, seen := namespaces[ctx.Name]; !seen {
+				namespaces[ctx.Name] = ctx.Context.Namespace
+			}
+		}
+	}
+
+	namespace := namespaces[current]
+	if namespace == "" {
+		namespace = "default"
+	}
+	return current, namespace
+}
@@ -0,0 +1,219 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"rolewalkers/internal/awscli"
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+)
+
+const (
+	// maxThrottleBackoff caps the wait between retries of a throttled call
+	maxThrottleBackoff = 30 * time.Second
+
+	// firstThrottleBackoff is the wait before the first retry
+	firstThrottleBackoff = time.Second
+)
+
+// Runner runs kubectl commands that call the API server. Calls to the same
+// cluster share a rate limiter, so bulk operations queue up behind each
+// other instead of bursting, and a throttled response (HTTP 429) pauses the
+// whole queue for that cluster while the call is retried with backoff.
+// Local commands such as 'kubectl config' don't need a Runner.
+type Runner struct {
+	qps        float64
+	burst      int
+	maxRetries int
+
+	mu       sync.Mutex
+	clusters map[string]*clusterLimiter
+
+	// backoff is the wait before the first retry of a throttled call
+	backoff time.Duration
+	// exec runs kubectl with stdin, if any; replaced in tests
+	exec func(ctx context.Context, stdin []byte, args []string) (stdout, stderr []byte, err error)
+	// currentContext returns kubectl's current context; replaced in tests
+	currentContext func() string
+}
+
+// NewRunner creates a Runner allowing qps calls per second per cluster,
+// with bursts of up to burst calls. qps <= 0 disables rate limiting;
+// throttled calls are still retried up to maxRetries times.
+func NewRunner(qps float64, burst, maxRetries int) *Runner {
+	return &Runner{
+		qps:        qps,
+		burst:      max(burst, 1),
+		maxRetries: max(maxRetries, 0),
+		clusters:   make(map[string]*clusterLimiter),
+		backoff:    firstThrottleBackoff,
+		exec:       execKubectl,
+		currentContext: func() string {
+			current, _ := ReadKubeconfigContext()
+			return current
+		},
+	}
+}
+
+var sharedRunner = sync.OnceValue(func() *Runner {
+	k := config.Get().Kubectl
+	return NewRunner(k.QPS, k.Burst, k.MaxRetries)
+})
+
+// SharedRunner returns the process-wide Runner, configured by the kubectl
+// section of config.yaml
+func SharedRunner() *Runner {
+	return sharedRunner()
+}
+
+// Run runs kubectl with args against kubeContext ("" for the current
+// context) and returns its stdout. It waits for the cluster's turn first and
+// retries with exponential backoff while the API server throttles.
+func (r *Runner) Run(ctx context.Context, kubeContext string, args ...string) ([]byte, error) {
+	return r.RunInput(ctx, kubeContext, nil, args...)
+}
+
+// RunInput is Run with stdin for kubectl, e.g. a manifest for 'create -f -'
+// that holds secrets, which must not appear in kubectl's arguments
+func (r *Runner) RunInput(ctx context.Context, kubeContext string, stdin []byte, args ...string) ([]byte, error) {
+	// Resolve the current context up front, so the call shares the
+	// cluster's limiter with calls that name it, and runs against the
+	// cluster it was queued for even if the current context changes
+	if kubeContext == "" {
+		kubeContext = r.currentContext()
+	}
+	if kubeContext != "" {
+		args = append([]string{"--context", kubeContext}, args...)
+	}
+	limiter := r.limiter(kubeContext)
+	backoff := r.backoff
+
+	for attempt := 0; ; attempt++ {
+		if err := sleepContext(ctx, limiter.reserve(time.Now())); err != nil {
+			return nil, err
+		}
+		stdout, stderr, err := r.exec(ctx, stdin, args)
+		if err == nil {
+			return stdout, nil
+		}
+		kerr := &KubectlError{Stderr: strings.TrimSpace(string(stderr)), Err: err}
+		if !IsThrottled(kerr.Stderr) || attempt >= r.maxRetries {
+			return nil, kerr
+		}
+
+		// Back off every caller queued for this cluster, not just this one
+		wait := backoff/2 + rand.N(backoff/2+1)
+		limiter.pause(time.Now().Add(wait))
+		backoff = min(backoff*2, maxThrottleBackoff)
+	}
+}
+
+// KubectlError is a failed kubectl call. Its message is kubectl's error
+// output, so callers can wrap it with what they were doing.
+type KubectlError struct {
+	Stderr string
+	Err    error
+}
+
+func (e *KubectlError) Error() string {
+	if e.Stderr != "" {
+		return utils.Redact(e.Stderr)
+	}
+	return e.Err.Error()
+}
+
+func (e *KubectlError) Unwrap() error { return e.Err }
+
+// limiter returns the shared limiter for a cluster, creating it on first use
+func (r *Runner) limiter(kubeContext string) *clusterLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.clusters[kubeContext]
+	if !ok {
+		l = newClusterLimiter(r.qps, r.burst)
+		r.clusters[kubeContext] = l
+	}
+	return l
+}
+
+// IsThrottled reports whether kubectl's error output says the API server
+// (or an AWS API behind it, e.g. the EKS authenticator) rejected the call
+// for making too many requests
+func IsThrottled(stderr string) bool {
+	s := strings.ToLower(stderr)
+	return strings.Contains(s, "too many requests") ||
+		strings.Contains(s, "toomanyrequests") ||
+		strings.Contains(s, "throttl") ||
+		strings.Contains(s, "rate exceeded")
+}
+
+// clusterLimiter queues calls to one cluster with the generic cell rate
+// algorithm: each call reserves the next free slot, so callers go in the
+// order they arrived
+type clusterLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time between calls at the steady rate; 0 is unlimited
+	slack    time.Duration // how far ahead of the steady rate a burst may run
+	tat      time.Time     // theoretical arrival time of the next call
+}
+
+func newClusterLimiter(qps float64, burst int) *clusterLimiter {
+	if qps <= 0 {
+		return &clusterLimiter{}
+	}
+	interval := time.Duration(float64(time.Second) / qps)
+	return &clusterLimiter{interval: interval, slack: time.Duration(burst-1) * interval}
+}
+
+// reserve takes the next slot and returns how long to wait for it
+func (l *clusterLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	wait := max(tat.Sub(now)-l.slack, 0)
+	l.tat = tat.Add(l.interval)
+	return wait
+}
+
+// pause holds back every call not yet started until the given time
+func (l *clusterLimiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t := until.Add(l.slack); t.After(l.tat) {
+		l.tat = t
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func execKubectl(ctx context.Context, stdin []byte, args []string) ([]byte, []byte, error) {
+	cmd := awscli.CreateKubectlCommandContext(ctx, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
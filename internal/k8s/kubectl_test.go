@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClusterLimiterQueues(t *testing.T) {
+	l := newClusterLimiter(10, 3)
+	now := time.Now()
+	var waits []time.Duration
+	for range 5 {
+		waits = append(waits, l.reserve(now))
+	}
+	// The burst goes straight through, then one call every 100ms in order
+	want := []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond}
+	if !slices.Equal(waits, want) {
+		t.Errorf("waits = %v, want %v", waits, want)
+	}
+
+	l.pause(now.Add(time.Second))
+	if got := l.reserve(now); got != time.Second {
+		t.Errorf("wait after pause = %v, want 1s", got)
+	}
+
+	if got := newClusterLimiter(0, 1).reserve(now); got != 0 {
+		t.Errorf("unlimited limiter wait = %v, want 0", got)
+	}
+}
+
+// fakeRunner returns a Runner whose kubectl answers with the given stderr
+// (and fails) for the first calls, then succeeds
+func fakeRunner(maxRetries int, failures ...string) (*Runner, *[][]string) {
+	r := NewRunner(0, 1, maxRetries)
+	r.backoff = time.Millisecond
+	r.currentContext = func() string { return "" }
+	var calls [][]string
+	r.exec = func(_ context.Context, _ []byte, args []string) ([]byte, []byte, error) {
+		calls = append(calls, args)
+		if len(calls) <= len(failures) {
+			return nil, []byte(failures[len(calls)-1]), errors.New("exit status 1")
+		}
+		return []byte("ok"), nil, nil
+	}
+	return r, &calls
+}
+
+func TestRunnerRetriesThrottledCalls(t *testing.T) {
+	throttled := "Error from server (TooManyRequests): the server has received too many requests and has asked us to try again later"
+	r, calls := fakeRunner(5, throttled, throttled)
+
+	out, err := r.Run(context.Background(), "dev", "get", "hpa")
+	if err != nil || string(out) != "ok" {
+		t.Fatalf("Run() = %q, %v; want ok after retries", out, err)
+	}
+	if len(*calls) != 3 {
+		t.Errorf("kubectl ran %d times, want 3", len(*calls))
+	}
+	if got := strings.Join((*calls)[0], " "); got != "--context dev get hpa" {
+		t.Errorf("args = %q", got)
+	}
+}
+
+func TestRunnerGivesUp(t *testing.T) {
+	r, calls := fakeRunner(1, "Throttling: Rate exceeded", "Throttling: Rate exceeded", "")
+	if _, err := r.Run(context.Background(), "", "get", "pods"); err == nil || !strings.Contains(err.Error(), "Rate exceeded") {
+		t.Errorf("Run() error = %v, want the throttling error after retries run out", err)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("kubectl ran %d times, want 2", len(*calls))
+	}
+
+	// Other errors are returned at once, with kubectl's message
+	r, calls = fakeRunner(5, `Error from server (NotFound): pods "x" not found`)
+	_, err := r.Run(context.Background(), "", "get", "pod", "x")
+	var kerr *KubectlError
+	if !errors.As(err, &kerr) || err.Error() != `Error from server (NotFound): pods "x" not found` {
+		t.Errorf("Run() error = %v, want the NotFound error", err)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("kubectl ran %d times for a non-throttling error, want 1", len(*calls))
+	}
+}
+
+func TestRunnerResolvesCurrentContext(t *testing.T) {
+	r, calls := fakeRunner(0)
+	r.currentContext = func() string { return "dev" }
+
+	if _, err := r.Run(context.Background(), "", "get", "hpa"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if got := strings.Join((*calls)[0], " "); got != "--context dev get hpa" {
+		t.Errorf("args = %q, want the current context passed explicitly", got)
+	}
+	if _, ok := r.clusters[""]; ok || r.clusters["dev"] == nil {
+		t.Errorf("limiters = %v, want the current context to share dev's", r.clusters)
+	}
+}
+
+func TestIsThrottled(t *testing.T) {
+	for stderr, want := range map[string]bool{
+		"Error from server (TooManyRequests): the server has received too many requests": true,
+		"an error occurred (ThrottlingException) when calling the GetToken operation":    true,
+		"Rate exceeded": true,
+		`Error from server (NotFound): horizontalpodautoscalers.autoscaling "x" not found`: false,
+		"": false,
+	} {
+		if got := IsThrottled(stderr); got != want {
+			t.Errorf("IsThrottled(%q) = %v, want %v", stderr, got, want)
+		}
+	}
+}
@@ -20,26 +20,16 @@ func labelPairs(extras ...string) string {
 	return strings.Join(append(base, extras...), ",")
 }
 
-// CreatorLabels generates standard Kubernetes labels for pod creator identity.
-func CreatorLabels() string {
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	return labelPairs("created-at=" + timestamp)
-}
-
-// CreatorLabelsWithSession generates labels including a session ID for temporary pods.
-func CreatorLabelsWithSession() string {
-	sessionID := fmt.Sprintf("%d", os.Getpid())
-	return labelPairs("session-id=" + sessionID)
-}
-
-// CreatorLabelsWithOperation generates labels including an operation type.
-func CreatorLabelsWithOperation(operation string) string {
-	sessionID := fmt.Sprintf("%d", os.Getpid())
-	return labelPairs("session-id="+sessionID, "operation="+operation)
-}
-
-// CreatorLabelsWithName generates labels including a pod name.
-func CreatorLabelsWithName(podName string) string {
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	return labelPairs("name="+podName, "created-at="+timestamp)
+// podLabels generates the labels on every pod rw creates: who created it,
+// when, from which process, and for which operation if any.
+func podLabels(podName, operation string) string {
+	extras := []string{
+		"name=" + podName,
+		fmt.Sprintf("session-id=%d", os.Getpid()),
+		fmt.Sprintf("created-at=%d", time.Now().Unix()),
+	}
+	if operation != "" {
+		extras = append(extras, "operation="+operation)
+	}
+	return labelPairs(extras...)
 }
@@ -0,0 +1,157 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"rolewalkers/internal/utils"
+	"slices"
+)
+
+// defaultPodNamespace is where temporary pods run unless PodSpec says otherwise
+const defaultPodNamespace = "tunnel-access"
+
+// PodSpec describes a temporary Kubernetes pod; see PodSession.
+type PodSpec struct {
+	// Prefix for the generated pod name (e.g. "psql", "redis-temp", "dbtunnel").
+	NamePrefix string
+
+	// Name of the pod. Generated from NamePrefix if empty; set it to clean
+	// up the pod after an interrupted run.
+	Name string
+
+	// Container image (e.g. "postgres:15-alpine", "redis:7-alpine").
+	Image string
+
+	// Namespace to run in. Defaults to defaultPodNamespace if empty.
+	Namespace string
+
+	// Command to run inside the container (e.g. ["psql", "-h", "host"]).
+	Command []string
+
+	// Environment variables as name→value pairs, e.g. passwords. They are
+	// stored in a Secret that lives as long as the pod, sent to kubectl on
+	// stdin, and the pod reads them with secretKeyRef, so they never appear
+	// in the process list.
+	Env map[string]string
+
+	// Interactive means the pod needs stdin/tty attached (--rm -it).
+	// Non-interactive pods use --rm -i (for piped I/O).
+	Interactive bool
+
+	// Labels operation type (e.g. "backup", "restore"), shown by 'rw report
+	// tunnel-usage'.
+	Operation string
+
+	// Port the container listens on, for pods that are port-forwarded to.
+	Port int
+
+	// ImagePullPolicy overrides the cluster default (e.g. "IfNotPresent").
+	ImagePullPolicy string
+
+	// Keep leaves a pod started with Start running after rw exits, e.g. a
+	// UI reused by the next run. Other pods are deleted by the orphan sweep
+	// once the process that created them is gone.
+	Keep bool
+
+	// Stdin overrides os.Stdin when set (e.g. for piping a file).
+	Stdin io.Reader
+
+	// Stdout overrides os.Stdout when set (e.g. for capturing to a file).
+	Stdout io.Writer
+
+	// Stderr overrides os.Stderr when set.
+	Stderr io.Writer
+
+	// KubeContext is the kubectl context to create the pod in, or that an
+	// existing pod is in. Defaults to the current context when the pod is
+	// created.
+	KubeContext string
+
+	// Context stops kubectl when it is done. The pod itself may be left
+	// running until PodSession.Close deletes it.
+	Context context.Context
+}
+
+// GeneratePodName creates a unique pod name from the prefix and current user.
+func GeneratePodName(prefix string) string {
+	username := utils.GetCurrentUsernamePodSafe()
+	if username == "unknown" {
+		username = "user"
+	}
+	return fmt.Sprintf("%s-%s-%d", prefix, username, rand.IntN(10000))
+}
+
+// envSecretName is the Secret holding a pod's env vars
+func envSecretName(podName string) string {
+	return podName + "-env"
+}
+
+// buildEnvSecret creates the Secret manifest holding a pod's env vars
+func buildEnvSecret(podName, namespace string, env map[string]string) []byte {
+	secret := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]string{"name": envSecretName(podName), "namespace": namespace},
+		"type":       "Opaque",
+		"stringData": env,
+	}
+	data, _ := json.Marshal(secret)
+	return data
+}
+
+// buildOverrides creates the JSON pod spec override string. attached pods
+// keep stdin open for kubectl run -i. Env vars are references to the pod's
+// env Secret, never the values.
+func buildOverrides(podName string, spec PodSpec, attached bool) string {
+	container := map[string]interface{}{
+		"name":  podName,
+		"image": spec.Image,
+	}
+
+	if attached {
+		container["stdin"] = true
+	}
+	if attached && spec.Interactive {
+		container["tty"] = true
+	}
+	if spec.ImagePullPolicy != "" {
+		container["imagePullPolicy"] = spec.ImagePullPolicy
+	}
+	if spec.Port > 0 {
+		container["ports"] = []map[string]int{{"containerPort": spec.Port}}
+	}
+
+	if len(spec.Command) > 0 {
+		container["command"] = spec.Command
+	}
+
+	if len(spec.Env) > 0 {
+		var envVars []map[string]interface{}
+		keys := make([]string, 0, len(spec.Env))
+		for k := range spec.Env {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			envVars = append(envVars, map[string]interface{}{
+				"name": k,
+				"valueFrom": map[string]interface{}{
+					"secretKeyRef": map[string]string{"name": envSecretName(podName), "key": k},
+				},
+			})
+		}
+		container["env"] = envVars
+	}
+
+	override := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{container},
+		},
+	}
+
+	data, _ := json.Marshal(override)
+	return string(data)
+}
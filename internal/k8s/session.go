@@ -0,0 +1,400 @@
+package k8s
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+// podSessionsFile records the pods rw processes have created and not yet
+// deleted, so pods left behind by a killed process can be swept up
+const podSessionsFile = "pod_sessions.json"
+
+// orphanRecordMaxAge is how long a pod that couldn't be deleted stays
+// recorded, e.g. when its cluster is no longer reachable
+const orphanRecordMaxAge = 7 * 24 * time.Hour
+
+// PodSession is a temporary pod rw creates for one operation: a psql or
+// redis-cli shell, a pg_dump, a socat tunnel or a Kafka UI. It names and
+// labels the pod, creates it attached (Run) or detached (Start), waits for
+// it, forwards ports to it and deletes it (Close, meant to be deferred).
+// A pod with env vars gets a Secret holding them, deleted along with it.
+// Every kubectl call targets the context the pod was created in, so
+// switching context mid-operation doesn't lose track of it. While it exists
+// the pod is recorded, so one left behind by an rw process that was killed
+// is deleted by a later orphan sweep.
+type PodSession struct {
+	Name      string
+	Namespace string
+
+	spec PodSpec
+	// kubeContext is the context the pod was created in, so it's deleted
+	// from the same cluster after the current context changed
+	kubeContext string
+	closed      bool
+}
+
+// NewPodSession prepares a session for a pod; nothing is created until Run
+// or Start. A spec without a Name gets one generated from NamePrefix, and
+// one with a Name refers to an existing pod, e.g. to delete it.
+func NewPodSession(spec PodSpec) *PodSession {
+	spec.Namespace = cmp.Or(spec.Namespace, defaultPodNamespace)
+	if spec.Name == "" {
+		spec.Name = GeneratePodName(spec.NamePrefix)
+	}
+	return &PodSession{Name: spec.Name, Namespace: spec.Namespace, spec: spec, kubeContext: spec.KubeContext}
+}
+
+// KubeContext returns the kubectl context the pod is in, once created; ""
+// is the current context
+func (s *PodSession) KubeContext() string {
+	return s.kubeContext
+}
+
+// Run creates the pod and attaches to it until its command exits (kubectl
+// run --rm), with stdin/tty for interactive pods and piped I/O otherwise.
+// Returns nil on success or normal user exit (exit code 0).
+func (s *PodSession) Run() error {
+	s.begin()
+	if err := s.createEnvSecret(); err != nil {
+		s.finish()
+		return err
+	}
+
+	args := s.contextArgs("run", s.Name, "--rm")
+	if s.spec.Interactive {
+		args = append(args, "-it")
+	} else {
+		args = append(args, "-i")
+	}
+	args = append(args, s.runArgs(true)...)
+
+	cmd := exec.CommandContext(s.context(), "kubectl", args...)
+	if s.spec.Stdin != nil {
+		cmd.Stdin = s.spec.Stdin
+	} else if s.spec.Interactive {
+		cmd.Stdin = os.Stdin
+	}
+	flush := s.setOutputs(cmd)
+
+	err := cmd.Run()
+	flush()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+			err = nil
+		}
+	}
+	if err == nil {
+		// kubectl removed the pod itself
+		s.finish()
+	}
+	return err
+}
+
+// Start creates the pod detached; it keeps running until Close, or with
+// PodSpec.Keep until deleted by name later
+func (s *PodSession) Start() error {
+	s.begin()
+	if err := s.createEnvSecret(); err != nil {
+		s.finish()
+		return err
+	}
+	args := append([]string{"run", s.Name}, s.runArgs(false)...)
+	if _, err := SharedRunner().Run(s.context(), s.kubeContext, args...); err != nil {
+		s.finish()
+		return fmt.Errorf("failed to create pod %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// createEnvSecret stores the pod's env vars in its Secret. The manifest
+// goes to kubectl on stdin, so the values stay out of every process's
+// arguments.
+func (s *PodSession) createEnvSecret() error {
+	if len(s.spec.Env) == 0 {
+		return nil
+	}
+	manifest := buildEnvSecret(s.Name, s.Namespace, s.spec.Env)
+	if _, err := SharedRunner().RunInput(s.context(), s.kubeContext, manifest, "create", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to create secret %s: %w", envSecretName(s.Name), err)
+	}
+	return nil
+}
+
+// runArgs are the kubectl run flags shared by attached and detached pods
+func (s *PodSession) runArgs(attached bool) []string {
+	return []string{
+		"--restart=Never",
+		"--namespace=" + s.Namespace,
+		"--image=" + s.spec.Image,
+		"--labels", podLabels(s.Name, s.spec.Operation),
+		"--overrides", buildOverrides(s.Name, s.spec, attached),
+		"--override-type=strategic",
+	}
+}
+
+// WaitReady waits until the pod's containers are ready
+func (s *PodSession) WaitReady(timeout time.Duration) error {
+	_, err := SharedRunner().Run(s.context(), s.kubeContext, "-n", s.Namespace, "wait", "pod/"+s.Name,
+		"--for", "condition=Ready",
+		"--timeout", fmt.Sprintf("%.0fs", timeout.Seconds()),
+	)
+	if err != nil {
+		return fmt.Errorf("pod %s did not become ready: %w", s.Name, err)
+	}
+	return nil
+}
+
+// Exists reports whether the pod exists
+func (s *PodSession) Exists() bool {
+	_, err := SharedRunner().Run(s.context(), s.kubeContext, "get", "pod", s.Name, "-n", s.Namespace, "-o", "name")
+	return err == nil
+}
+
+// Phase returns the pod's status phase, e.g. Running
+func (s *PodSession) Phase() (string, error) {
+	out, err := SharedRunner().Run(s.context(), s.kubeContext, "get", "pod", s.Name,
+		"-n", s.Namespace,
+		"-o", "jsonpath={.status.phase}",
+	)
+	if err != nil {
+		return "", fmt.Errorf("kubectl get pod status failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PortForward forwards localPort to remotePort on the pod until ctx is
+// done or kubectl exits
+func (s *PodSession) PortForward(ctx context.Context, localPort, remotePort int) error {
+	cmd := exec.CommandContext(ctx, "kubectl", s.contextArgs("-n", s.Namespace, "port-forward",
+		"pod/"+s.Name,
+		fmt.Sprintf("%d:%d", localPort, remotePort),
+	)...)
+	flush := s.setOutputs(cmd)
+	defer flush()
+	return cmd.Run()
+}
+
+// setOutputs points kubectl's output at the spec's writers when set, else at
+// the console with tracked secrets masked. An interactive session keeps the
+// raw terminal so prompts and escape sequences pass through. The returned
+// func writes out any partial line once kubectl has exited.
+func (s *PodSession) setOutputs(cmd *exec.Cmd) (flush func()) {
+	var redacted []*utils.RedactWriter
+	console := func(f *os.File) io.Writer {
+		if s.spec.Interactive {
+			return f
+		}
+		w := utils.NewRedactWriter(f)
+		redacted = append(redacted, w)
+		return w
+	}
+
+	cmd.Stdout, cmd.Stderr = s.spec.Stdout, s.spec.Stderr
+	if cmd.Stdout == nil {
+		cmd.Stdout = console(os.Stdout)
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = console(os.Stderr)
+	}
+	return func() {
+		for _, w := range redacted {
+			w.Flush()
+		}
+	}
+}
+
+// Close deletes the pod, without waiting for it to terminate. A pod that
+// is already gone is not an error, and closing twice does nothing.
+func (s *PodSession) Close() error {
+	if s.closed {
+		return nil
+	}
+	_, err := SharedRunner().Run(context.Background(), s.kubeContext, deleteArgs(s.Name, s.Namespace, len(s.spec.Env) > 0)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete pod %s in namespace %s: %w", s.Name, s.Namespace, err)
+	}
+	s.closed = true
+	s.forget()
+	return nil
+}
+
+// deleteArgs deletes a pod, and its env Secret when it has one, without
+// waiting for them to go
+func deleteArgs(name, namespace string, secret bool) []string {
+	args := []string{"delete", "pod/" + name}
+	if secret {
+		args = append(args, "secret/"+envSecretName(name))
+	}
+	return append(args, "-n", namespace, "--ignore-not-found", "--wait=false")
+}
+
+// contextArgs prefixes args with --context for the session's kubectl
+// context, for the commands that don't go through the shared runner
+func (s *PodSession) contextArgs(args ...string) []string {
+	if s.kubeContext == "" {
+		return args
+	}
+	return append([]string{"--context", s.kubeContext}, args...)
+}
+
+// sweepOnce starts one background orphan sweep per process
+var sweepOnce sync.Once
+
+// begin pins the session to the current kubectl context and records the pod
+// before it's created. Pods orphaned by earlier processes are swept in the
+// background; one the sweep can't delete is retried by the next.
+func (s *PodSession) begin() {
+	sweepOnce.Do(func() {
+		go func() { _, _ = SweepOrphanPods() }()
+	})
+	if s.kubeContext == "" {
+		s.kubeContext, _ = ReadKubeconfigContext()
+	}
+	s.closed = false
+	if !s.spec.Keep {
+		if err := updatePodRecords(func(records []podRecord) []podRecord {
+			return append(records, podRecord{
+				Name: s.Name, Namespace: s.Namespace, Context: s.kubeContext,
+				Secret: len(s.spec.Env) > 0, PID: os.Getpid(), CreatedAt: time.Now().UTC(),
+			})
+		}); err != nil {
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to record pod %s for cleanup: %v\n", s.Name, err)
+		}
+	}
+}
+
+// finish forgets the pod once it's gone, deleting its env Secret first. A
+// Secret that can't be deleted stays recorded for the orphan sweep.
+func (s *PodSession) finish() {
+	s.closed = true
+	if len(s.spec.Env) > 0 {
+		_, err := SharedRunner().Run(context.Background(), s.kubeContext, "delete", "secret", envSecretName(s.Name),
+			"-n", s.Namespace, "--ignore-not-found", "--wait=false")
+		if err != nil {
+			fmt.Fprintf(utils.Stderr, utils.Warn()+" Failed to delete secret %s: %v\n", envSecretName(s.Name), err)
+			return
+		}
+	}
+	s.forget()
+}
+
+// forget removes the pod's record
+func (s *PodSession) forget() {
+	_ = updatePodRecords(func(records []podRecord) []podRecord {
+		return removePodRecord(records, s.Namespace, s.Name)
+	})
+}
+
+func (s *PodSession) context() context.Context {
+	if s.spec.Context != nil {
+		return s.spec.Context
+	}
+	return context.Background()
+}
+
+// podRecord is a pod created by an rw process that hasn't deleted it yet
+type podRecord struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Context   string    `json:"context,omitempty"`
+	Secret    bool      `json:"secret,omitempty"` // has an env Secret to delete with it
+	PID       int       `json:"pid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var podRecordsMu sync.Mutex
+
+// updatePodRecords applies update to the recorded pods and saves them
+func updatePodRecords(update func([]podRecord) []podRecord) error {
+	podRecordsMu.Lock()
+	defer podRecordsMu.Unlock()
+
+	records := update(loadPodRecords())
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteRoleWalkersFile(podSessionsFile, data)
+}
+
+// loadPodRecords reads the recorded pods. Caller must hold podRecordsMu.
+func loadPodRecords() []podRecord {
+	var records []podRecord
+	if data, err := utils.ReadRoleWalkersFile(podSessionsFile); err == nil {
+		_ = json.Unmarshal(data, &records)
+	}
+	return records
+}
+
+func removePodRecord(records []podRecord, namespace, name string) []podRecord {
+	kept := records[:0]
+	for _, r := range records {
+		if r.Namespace != namespace || r.Name != name {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// orphanedPods splits records into pods whose creating process has exited
+// and the rest
+func orphanedPods(records []podRecord, alive func(pid int) bool) (orphans, live []podRecord) {
+	for _, r := range records {
+		if alive(r.PID) {
+			live = append(live, r)
+		} else {
+			orphans = append(orphans, r)
+		}
+	}
+	return orphans, live
+}
+
+// SweepOrphanPods deletes the pods recorded by rw processes that exited
+// without deleting them, e.g. after a kill -9 or a crash, and returns
+// their names. A pod that can't be deleted stays recorded for the next
+// sweep, for up to a week. The records aren't locked while kubectl runs,
+// so sessions starting meanwhile aren't held up.
+func SweepOrphanPods() ([]string, error) {
+	podRecordsMu.Lock()
+	orphans, _ := orphanedPods(loadPodRecords(), utils.ProcessAlive)
+	podRecordsMu.Unlock()
+	if len(orphans) == 0 {
+		return nil, nil
+	}
+
+	var swept []string
+	var errs []error
+	var done []podRecord
+	for _, r := range orphans {
+		_, err := SharedRunner().Run(context.Background(), r.Context, deleteArgs(r.Name, r.Namespace, r.Secret)...)
+		if err == nil {
+			swept = append(swept, r.Name)
+			done = append(done, r)
+			continue
+		}
+		if time.Since(r.CreatedAt) >= orphanRecordMaxAge {
+			done = append(done, r)
+		}
+		errs = append(errs, fmt.Errorf("failed to delete orphaned pod %s: %w", r.Name, err))
+	}
+
+	err := updatePodRecords(func(records []podRecord) []podRecord {
+		for _, r := range done {
+			records = removePodRecord(records, r.Namespace, r.Name)
+		}
+		return records
+	})
+	return swept, errors.Join(err, errors.Join(errs...))
+}
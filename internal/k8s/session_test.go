@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"rolewalkers/internal/utils"
+)
+
+func TestOrphanedPods(t *testing.T) {
+	records := []podRecord{
+		{Name: "psql-a", PID: 100},
+		{Name: "dbtunnel-b", PID: 200},
+		{Name: "redis-temp-c", PID: 300},
+	}
+	orphans, live := orphanedPods(records, func(pid int) bool { return pid == 200 })
+	if len(orphans) != 2 || orphans[0].Name != "psql-a" || orphans[1].Name != "redis-temp-c" {
+		t.Errorf("orphans = %+v, want psql-a and redis-temp-c", orphans)
+	}
+	if len(live) != 1 || live[0].Name != "dbtunnel-b" {
+		t.Errorf("live = %+v, want dbtunnel-b", live)
+	}
+}
+
+func TestPodRecords(t *testing.T) {
+	t.Setenv(utils.StateDirEnv, t.TempDir())
+
+	add := func(namespace, name string) {
+		t.Helper()
+		if err := updatePodRecords(func(records []podRecord) []podRecord {
+			return append(records, podRecord{Name: name, Namespace: namespace, PID: 1})
+		}); err != nil {
+			t.Fatalf("updatePodRecords() = %v", err)
+		}
+	}
+	add("tunnel-access", "psql-a")
+	add("default", "psql-a")
+	add("tunnel-access", "pgdump-b")
+
+	if err := updatePodRecords(func(records []podRecord) []podRecord {
+		return removePodRecord(records, "tunnel-access", "psql-a")
+	}); err != nil {
+		t.Fatalf("updatePodRecords() = %v", err)
+	}
+
+	data, err := utils.ReadRoleWalkersFile(podSessionsFile)
+	if err != nil {
+		t.Fatalf("ReadRoleWalkersFile() = %v", err)
+	}
+	var records []podRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Namespace != "default" || records[1].Name != "pgdump-b" {
+		t.Errorf("records = %+v, want default/psql-a and tunnel-access/pgdump-b", records)
+	}
+}
+
+func TestBuildOverrides(t *testing.T) {
+	spec := PodSpec{Image: "postgres:15-alpine", Interactive: true, Port: 5432, Env: map[string]string{"PGPASSWORD": "s3cret"}}
+
+	attached := buildOverrides("psql-a", spec, true)
+	for _, want := range []string{`"stdin":true`, `"tty":true`, `"containerPort":5432`, `"name":"PGPASSWORD"`,
+		`"secretKeyRef":{"key":"PGPASSWORD","name":"psql-a-env"}`} {
+		if !strings.Contains(attached, want) {
+			t.Errorf("attached overrides %s missing %s", attached, want)
+		}
+	}
+	// The overrides are a kubectl argument, visible in the process list
+	if strings.Contains(attached, "s3cret") {
+		t.Errorf("overrides %s contain the secret value", attached)
+	}
+	var secret struct {
+		Metadata   map[string]string `json:"metadata"`
+		StringData map[string]string `json:"stringData"`
+	}
+	if err := json.Unmarshal(buildEnvSecret("psql-a", "tunnel-access", spec.Env), &secret); err != nil ||
+		secret.Metadata["name"] != "psql-a-env" || secret.StringData["PGPASSWORD"] != "s3cret" {
+		t.Errorf("buildEnvSecret() = %+v, %v, want psql-a-env holding PGPASSWORD", secret, err)
+	}
+
+	// A detached pod has nothing attached to keep stdin open for
+	detached := buildOverrides("psql-a", spec, false)
+	if strings.Contains(detached, "stdin") || strings.Contains(detached, "tty") {
+		t.Errorf("detached overrides %s should not request stdin or a tty", detached)
+	}
+}
+
+func TestDeleteArgs(t *testing.T) {
+	if got := strings.Join(deleteArgs("psql-a", "tunnel-access", true), " "); got != "delete pod/psql-a secret/psql-a-env -n tunnel-access --ignore-not-found --wait=false" {
+		t.Errorf("deleteArgs() with a secret = %q", got)
+	}
+	if got := strings.Join(deleteArgs("dbtunnel-b", "tunnel-access", false), " "); strings.Contains(got, "secret/") {
+		t.Errorf("deleteArgs() without a secret = %q", got)
+	}
+}
+
+func TestPodSessionContextArgs(t *testing.T) {
+	s := NewPodSession(PodSpec{Name: "dbtunnel-a", KubeContext: "dev-cluster"})
+	if s.KubeContext() != "dev-cluster" {
+		t.Errorf("KubeContext() = %q, want dev-cluster", s.KubeContext())
+	}
+	got := strings.Join(s.contextArgs("-n", s.Namespace, "port-forward", "pod/"+s.Name), " ")
+	if want := "--context dev-cluster -n tunnel-access port-forward pod/dbtunnel-a"; got != want {
+		t.Errorf("contextArgs() = %q, want %q", got, want)
+	}
+
+	current := NewPodSession(PodSpec{Name: "dbtunnel-b"})
+	if got := strings.Join(current.contextArgs("get", "pod"), " "); got != "get pod" {
+		t.Errorf("contextArgs() without a context = %q, want the args unchanged", got)
+	}
+}
@@ -0,0 +1,161 @@
+// Package keygen generates random keys, tokens and passphrases.
+package keygen
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Format is the encoding of a generated key
+type Format string
+
+const (
+	FormatHex        Format = "hex"
+	FormatBase64     Format = "base64"
+	FormatUUID       Format = "uuid"
+	FormatPassphrase Format = "passphrase"
+)
+
+const (
+	// DefaultBytes is the amount of randomness in hex and base64 keys
+	DefaultBytes = 16
+	// DefaultWords is the number of words in a passphrase (~77 bits of entropy)
+	DefaultWords = 6
+
+	minBytes = 8
+	maxBytes = 1024
+	maxWords = 64
+)
+
+// eff_large_wordlist.txt is the EFF large diceware wordlist (7776 words),
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var wordlistData string
+
+var wordlist = sync.OnceValue(func() []string {
+	words := make([]string, 0, 7776)
+	scanner := bufio.NewScanner(strings.NewReader(wordlistData))
+	for scanner.Scan() {
+		// Lines are "<dice rolls>\t<word>"
+		if _, word, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// Options controls key generation
+type Options struct {
+	Format Format
+	Bytes  int    // random bytes for hex/base64 (default 16)
+	Words  int    // words in a passphrase (default 6)
+	Prefix string // prepended verbatim, e.g. "zk_live_"
+}
+
+// ParseFormat validates a --format value
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatHex, FormatBase64, FormatUUID, FormatPassphrase:
+		return f, nil
+	case "":
+		return FormatHex, nil
+	default:
+		return "", fmt.Errorf("unknown format: %s (use hex, base64, uuid or passphrase)", s)
+	}
+}
+
+// Validate checks the options and fills in defaults
+func (o *Options) Validate() error {
+	if o.Format == "" {
+		o.Format = FormatHex
+	}
+	if o.Bytes == 0 {
+		o.Bytes = DefaultBytes
+	}
+	if o.Words == 0 {
+		o.Words = DefaultWords
+	}
+	if o.Bytes < minBytes || o.Bytes > maxBytes {
+		return fmt.Errorf("invalid byte length: %d (must be between %d and %d)", o.Bytes, minBytes, maxBytes)
+	}
+	if o.Words < 1 || o.Words > maxWords {
+		return fmt.Errorf("invalid word count: %d (must be between 1 and %d)", o.Words, maxWords)
+	}
+	return nil
+}
+
+// Generate returns a single key
+func Generate(opts Options) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	var key string
+	var err error
+	switch opts.Format {
+	case FormatHex:
+		var b []byte
+		if b, err = randomBytes(opts.Bytes); err == nil {
+			key = hex.EncodeToString(b)
+		}
+	case FormatBase64:
+		var b []byte
+		if b, err = randomBytes(opts.Bytes); err == nil {
+			key = base64.RawURLEncoding.EncodeToString(b)
+		}
+	case FormatUUID:
+		key, err = uuidV4()
+	case FormatPassphrase:
+		key, err = passphrase(opts.Words)
+	default:
+		_, err = ParseFormat(string(opts.Format))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return opts.Prefix + key, nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate random key: %w", err)
+	}
+	return b, nil
+}
+
+// uuidV4 returns a random (version 4, RFC 4122 variant) UUID
+func uuidV4() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// passphrase picks n words uniformly from the diceware wordlist
+func passphrase(n int) (string, error) {
+	words := wordlist()
+	max := big.NewInt(int64(len(words)))
+
+	picked := make([]string, n)
+	for i := range picked {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate passphrase: %w", err)
+		}
+		picked[i] = words[idx.Int64()]
+	}
+	return strings.Join(picked, "-"), nil
+}
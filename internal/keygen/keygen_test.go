@@ -0,0 +1,125 @@
+package keygen
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWordlist(t *testing.T) {
+	words := wordlist()
+	if len(words) != 7776 {
+		t.Fatalf("wordlist has %d words, want 7776", len(words))
+	}
+	if words[0] != "abacus" || words[len(words)-1] != "zoom" {
+		t.Errorf("wordlist bounds = %q..%q", words[0], words[len(words)-1])
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		match string
+	}{
+		{"default hex", Options{}, `^[0-9a-f]{32}$`},
+		{"hex bytes", Options{Format: FormatHex, Bytes: 32}, `^[0-9a-f]{64}$`},
+		{"prefix", Options{Prefix: "zk_live_"}, `^zk_live_[0-9a-f]{32}$`},
+		{"uuid", Options{Format: FormatUUID}, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Generate(tt.opts)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if !regexp.MustCompile(tt.match).MatchString(key) {
+				t.Errorf("Generate() = %q, want match %s", key, tt.match)
+			}
+		})
+	}
+}
+
+// splitsInto reports whether passphrase is n wordlist words joined by "-".
+// Words such as "t-shirt" contain the separator, so the passphrase can't
+// just be split on it.
+func splitsInto(passphrase string, n int, words map[string]bool) bool {
+	parts := strings.Split(passphrase, "-")
+	// counts[i] holds the word counts that make up parts[:i]
+	counts := make([]map[int]bool, len(parts)+1)
+	counts[0] = map[int]bool{0: true}
+	for i := 1; i <= len(parts); i++ {
+		counts[i] = make(map[int]bool)
+		for j := 0; j < i; j++ {
+			if !words[strings.Join(parts[j:i], "-")] {
+				continue
+			}
+			for c := range counts[j] {
+				counts[i][c+1] = true
+			}
+		}
+	}
+	return counts[len(parts)][n]
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	words := make(map[string]bool)
+	for _, w := range wordlist() {
+		words[w] = true
+	}
+	if !splitsInto("drop-down-t-shirt-yo-yo-zoom", 4, words) {
+		t.Fatal("splitsInto() = false for hyphenated words")
+	}
+
+	for range 50 {
+		key, err := Generate(Options{Format: FormatPassphrase, Words: 4})
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if !splitsInto(key, 4, words) {
+			t.Errorf("Generate() = %q, want 4 wordlist words", key)
+		}
+	}
+}
+
+func TestGenerateBase64(t *testing.T) {
+	key, err := Generate(Options{Format: FormatBase64, Bytes: 24})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil || len(b) != 24 {
+		t.Errorf("Generate() = %q, decodes to %d bytes (err %v), want 24", key, len(b), err)
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Bytes: 4}, "invalid byte length"},
+		{Options{Format: FormatPassphrase, Words: 100}, "invalid word count"},
+		{Options{Format: "rot13"}, "unknown format"},
+	}
+	for _, tt := range tests {
+		_, err := Generate(tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Generate(%+v) error = %v, want containing %q", tt.opts, err, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("UUID"); err != nil || f != FormatUUID {
+		t.Errorf("ParseFormat(UUID) = %q, %v", f, err)
+	}
+	if f, err := ParseFormat(""); err != nil || f != FormatHex {
+		t.Errorf("ParseFormat(\"\") = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("bcrypt"); err == nil {
+		t.Error("ParseFormat(bcrypt) expected error")
+	}
+}
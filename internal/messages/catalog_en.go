@@ -0,0 +1,108 @@
+package messages
+
+// Confirmation prompts for dangerous operations
+const (
+	ConfirmProductionTitle         ID = "confirm.production.title"
+	ConfirmProductionEnvironment   ID = "confirm.production.environment"
+	ConfirmProductionOperation     ID = "confirm.production.operation"
+	ConfirmProductionBody          ID = "confirm.production.body"
+	ConfirmTypeYes                 ID = "confirm.type_yes"
+	ConfirmDatabaseRestore         ID = "confirm.db.restore"
+	ConfirmBackupPrune             ID = "confirm.db.backup_prune"
+	ConfirmReplicationSwitch       ID = "confirm.replication.switch"
+	ConfirmReplicationCreate       ID = "confirm.replication.create"
+	ConfirmReplicationDelete       ID = "confirm.replication.delete"
+	ConfirmReplicationDeleteTarget ID = "confirm.replication.delete_target"
+	ConfirmUndo                    ID = "confirm.undo"
+	ConfirmConfigOverwrite         ID = "confirm.config.overwrite"
+	ConfirmConfigDelete            ID = "confirm.config.delete"
+	ConfirmSetup                   ID = "confirm.setup"
+	ConfirmDatabaseMoveAside       ID = "confirm.state_db.move_aside"
+	ConfirmMigrateImport           ID = "confirm.migrate.import"
+	ConfirmRoleBulkEdit            ID = "confirm.profile.bulk_edit"
+	OperationCancelled             ID = "confirm.cancelled"
+)
+
+// Operation names shown in production confirmations
+const (
+	OpMaintenanceEnable  ID = "op.maintenance.enable"
+	OpMaintenanceDisable ID = "op.maintenance.disable"
+	OpScalePreset        ID = "op.scale.preset"
+	OpScaleService       ID = "op.scale.service"
+	OpScaleServices      ID = "op.scale.services"
+	OpDatabaseRestore    ID = "op.db.restore"
+	OpUndo               ID = "op.undo"
+	OpFeatureFlagSet     ID = "op.flag.set"
+)
+
+// Audit log descriptions
+const (
+	AuditMaintenanceEnable  ID = "audit.maintenance.enable"
+	AuditMaintenanceDisable ID = "audit.maintenance.disable"
+	AuditScalePreset        ID = "audit.scale.preset"
+	AuditScaleService       ID = "audit.scale.service"
+	AuditScaleServices      ID = "audit.scale.services"
+	AuditConfigGenerate     ID = "audit.config.generate"
+	AuditFeatureFlagSet     ID = "audit.flag.set"
+	AuditTunnelWrite        ID = "audit.tunnel.write"
+	AuditDatabaseRestore    ID = "audit.db.restore"
+	AuditQuotaOverride      ID = "audit.quota.override"
+	AuditRoleBulkEdit       ID = "audit.profile.bulk_edit"
+	AuditTunnelConnect      ID = "audit.tunnel.connect"
+)
+
+var english = map[ID]string{
+	ConfirmProductionTitle:       "PRODUCTION ENVIRONMENT DETECTED",
+	ConfirmProductionEnvironment: "Environment:",
+	ConfirmProductionOperation:   "Operation:",
+	ConfirmProductionBody: "You are about to perform an operation in a PRODUCTION environment.\n" +
+		"Please ensure you have proper authorization and have reviewed the changes.",
+	ConfirmTypeYes: "Type 'yes' to confirm:",
+	ConfirmDatabaseRestore: "WARNING: You are about to restore a database backup!\n" +
+		"   Environment: {env}\n" +
+		"   Input file:  {input}\n\n" +
+		"   This operation may overwrite existing data.",
+	ConfirmBackupPrune: "Delete {count} backup(s) and their files?",
+	ConfirmReplicationSwitch: "WARNING: You are about to perform a Blue-Green switchover!\n" +
+		"   Deployment: {deployment}\n" +
+		"   Source:     {source}\n" +
+		"   Target:     {target}\n\n" +
+		"   This will switch production traffic to the target cluster.",
+	ConfirmReplicationCreate: "Creating a new Blue-Green deployment:\n" +
+		"   Name:   {name}\n" +
+		"   Source: {source}\n\n" +
+		"   This will create a clone of the source cluster.",
+	ConfirmReplicationDelete: "WARNING: You are about to delete a Blue-Green deployment!\n" +
+		"   Deployment: {deployment}",
+	ConfirmReplicationDeleteTarget: "Target cluster will also be DELETED!",
+	ConfirmUndo:                    "Type 'yes' to revert this change:",
+	ConfirmConfigOverwrite:         "Overwrite ~/.aws/config with the changes above? Type 'yes' to confirm:",
+	ConfirmConfigDelete:            "Delete ~/.aws/config? (rw will generate it when needed) Type 'yes' to confirm:",
+	ConfirmSetup:                   "Type 'yes' to continue:",
+	ConfirmDatabaseMoveAside:       "Move {path} aside and create a fresh database?",
+	ConfirmMigrateImport:           "Replace this machine's rolewalkers database and config.yaml with the bundle from {host} ({created})?",
+	ConfirmRoleBulkEdit:            "Apply the changes above to {count} roles? Type 'yes' to confirm:",
+	OperationCancelled:             "Operation cancelled.",
+
+	OpMaintenanceEnable:  "Enable Maintenance Mode",
+	OpMaintenanceDisable: "Disable Maintenance Mode",
+	OpScalePreset:        "Scale using preset '{preset}'",
+	OpScaleService:       "Scale service '{service}' to min={min} max={max}",
+	OpScaleServices:      "Scale {count} HPAs matching '{pattern}' to min={min} max={max}",
+	OpDatabaseRestore:    "Database Restore",
+	OpUndo:               "Undo: {change}",
+	OpFeatureFlagSet:     "Set feature flag '{flag}' to {value}",
+
+	AuditMaintenanceEnable:  "Enabled maintenance mode ({type}) on {env}",
+	AuditMaintenanceDisable: "Disabled maintenance mode ({type}) on {env}",
+	AuditScalePreset:        "Scaled {env} to preset '{preset}'",
+	AuditScaleService:       "Scaled {service} on {env} to min={min} max={max}",
+	AuditScaleServices:      "Scaled {count} HPAs matching '{pattern}' on {env} to min={min} max={max}",
+	AuditConfigGenerate:     "Regenerated {path} from the database",
+	AuditFeatureFlagSet:     "Set feature flag {flag} on {env} to {value}",
+	AuditTunnelWrite:        "Started a write tunnel to {service} on {env}",
+	AuditDatabaseRestore:    "Restored the {env} database from {source}",
+	AuditQuotaOverride:      "Exceeded the {operation} quota on {env} ({used}/{limit} today): {reason}",
+	AuditRoleBulkEdit:       "Set {field} to '{value}' on {count} roles matching {filter}",
+	AuditTunnelConnect:      "{command} (pid {pid}) connected to the {service} tunnel on {env} (localhost:{port})",
+}
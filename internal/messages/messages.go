@@ -0,0 +1,132 @@
+// Package messages is the catalog of user-facing text that deserves review:
+// confirmation prompts for dangerous operations, operation names and audit
+// log descriptions. Each message has a stable ID and named {placeholders},
+// so the wording lives in one place, can be translated, and the audit log
+// can store the ID and parameters instead of rendered text.
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ID identifies a message in the catalog. IDs are stored in the audit log,
+// so existing IDs must not be renamed.
+type ID string
+
+// Params are the named values substituted into a message's {placeholders}
+type Params map[string]string
+
+// Message is a catalog ID together with its parameters
+type Message struct {
+	ID     ID
+	Params Params
+}
+
+// LanguageEnv selects the catalog language (e.g. RW_LANG=de). Messages
+// missing from that catalog fall back to English.
+const LanguageEnv = "RW_LANG"
+
+// DefaultLanguage is the language every message is defined in
+const DefaultLanguage = "en"
+
+var catalogs = map[string]map[ID]string{
+	DefaultLanguage: english,
+}
+
+// New creates a message from alternating key, value pairs
+func New(id ID, keyValues ...string) Message {
+	params := make(Params, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		params[keyValues[i]] = keyValues[i+1]
+	}
+	return Message{ID: id, Params: params}
+}
+
+// String renders the message in the current language
+func (m Message) String() string {
+	return Render(m.ID, m.Params)
+}
+
+// Language returns the catalog language from $RW_LANG, or the default
+func Language() string {
+	lang := strings.ToLower(strings.TrimSpace(os.Getenv(LanguageEnv)))
+	// Accept locale-style values such as de_DE.UTF-8
+	if i := strings.IndexAny(lang, "_.-"); i > 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// Lookup returns the template for id in the current language
+func Lookup(id ID) (string, bool) {
+	if tmpl, ok := catalogs[Language()][id]; ok {
+		return tmpl, true
+	}
+	tmpl, ok := catalogs[DefaultLanguage][id]
+	return tmpl, ok
+}
+
+// Render returns the message for id with params substituted. Unknown IDs
+// (e.g. from a newer version's audit log) render as the ID and parameters
+// rather than failing.
+func Render(id ID, params Params) string {
+	tmpl, ok := Lookup(id)
+	if !ok {
+		return fallback(id, params)
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
+
+// fallback formats an unknown message as "id (key=value, ...)"
+func fallback(id ID, params Params) string {
+	if len(params) == 0 {
+		return string(id)
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%s", k, params[k])
+	}
+	return fmt.Sprintf("%s (%s)", id, strings.Join(parts, ", "))
+}
+
+// EncodeParams serializes params as JSON for storage
+func EncodeParams(params Params) (string, error) {
+	if len(params) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeParams parses params stored by EncodeParams
+func DecodeParams(data string) (Params, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var params Params
+	if err := json.Unmarshal([]byte(data), &params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
@@ -0,0 +1,61 @@
+package messages
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	got := New(OpScaleService, "service", "candidate", "min", "2", "max", "10").String()
+	want := "Scale service 'candidate' to min=2 max=10"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderUnknownID(t *testing.T) {
+	got := Render("audit.future.thing", Params{"env": "dev", "b": "2"})
+	want := "audit.future.thing (b=2, env=dev)"
+	if got != want {
+		t.Errorf("Render(unknown) = %q, want %q", got, want)
+	}
+}
+
+func TestLanguageFallback(t *testing.T) {
+	t.Setenv(LanguageEnv, "de_DE.UTF-8")
+	if got := Language(); got != DefaultLanguage {
+		t.Errorf("Language() = %q, want %q for a language without a catalog", got, DefaultLanguage)
+	}
+	if got := Render(OperationCancelled, nil); got != "Operation cancelled." {
+		t.Errorf("Render() = %q, want English fallback", got)
+	}
+}
+
+func TestParamsRoundTrip(t *testing.T) {
+	encoded, err := EncodeParams(Params{"env": "prod", "preset": "normal"})
+	if err != nil {
+		t.Fatalf("EncodeParams() error: %v", err)
+	}
+	decoded, err := DecodeParams(encoded)
+	if err != nil {
+		t.Fatalf("DecodeParams() error: %v", err)
+	}
+	if decoded["env"] != "prod" || decoded["preset"] != "normal" {
+		t.Errorf("DecodeParams() = %v, want env=prod preset=normal", decoded)
+	}
+
+	if encoded, _ := EncodeParams(nil); encoded != "" {
+		t.Errorf("EncodeParams(nil) = %q, want empty", encoded)
+	}
+}
+
+func TestCatalogTemplates(t *testing.T) {
+	for id, tmpl := range english {
+		if strings.TrimSpace(tmpl) == "" {
+			t.Errorf("message %s has an empty template", id)
+		}
+		if strings.Count(tmpl, "{") != strings.Count(tmpl, "}") {
+			t.Errorf("message %s has unbalanced placeholders: %q", id, tmpl)
+		}
+	}
+}
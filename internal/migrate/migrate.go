@@ -0,0 +1,129 @@
+// Package migrate packs rw's state into a passphrase-encrypted bundle, so
+// moving to a new machine is an export and an import rather than a fresh
+// 'rw setup'. Bundles never contain SSO tokens or AWS credentials: those
+// live outside rw's state directory and are recreated by 'rw login'.
+//
+// A bundle is a header, a random salt and nonce, and the gzipped JSON
+// contents sealed with AES-256-GCM. The key is derived from the passphrase
+// with PBKDF2-SHA256; the header is authenticated too.
+package migrate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// header identifies the bundle format and version
+const header = "RWMIGRATE1\n"
+
+const (
+	saltSize      = 16
+	nonceSize     = 12
+	keySize       = 32
+	kdfIterations = 600_000
+)
+
+// MinPassphraseLength is the shortest passphrase Seal accepts
+const MinPassphraseLength = 8
+
+// ErrDecrypt is returned by Open for a wrong passphrase or a damaged bundle;
+// AES-GCM can't tell the two apart
+var ErrDecrypt = errors.New("wrong passphrase or damaged bundle")
+
+// Bundle is the exported state: files from the state directory by name
+type Bundle struct {
+	CreatedAt time.Time         `json:"created_at"`
+	Hostname  string            `json:"hostname,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Files     map[string][]byte `json:"files"`
+}
+
+// Seal encrypts a bundle with passphrase
+func Seal(b *Bundle, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress bundle: %w", err)
+	}
+
+	out := make([]byte, len(header)+saltSize+nonceSize, len(header)+saltSize+nonceSize+plain.Len()+16)
+	copy(out, header)
+	salt := out[len(header) : len(header)+saltSize]
+	nonce := out[len(header)+saltSize:]
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plain.Bytes(), out[:len(header)]), nil
+}
+
+// Open decrypts a bundle sealed with passphrase
+func Open(data []byte, passphrase string) (*Bundle, error) {
+	if !bytes.HasPrefix(data, []byte(header)) {
+		return nil, errors.New("not a rolewalkers migration bundle")
+	}
+	if len(data) < len(header)+saltSize+nonceSize {
+		return nil, ErrDecrypt
+	}
+	salt := data[len(header) : len(header)+saltSize]
+	nonce := data[len(header)+saltSize : len(header)+saltSize+nonceSize]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, data[len(header)+saltSize+nonceSize:], data[:len(header)])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	contents, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	b := &Bundle{}
+	if err := json.Unmarshal(contents, b); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+	return b, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
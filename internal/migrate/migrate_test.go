@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSealOpen(t *testing.T) {
+	b := &Bundle{
+		CreatedAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		Hostname:  "old-laptop",
+		Files: map[string][]byte{
+			"config.db":   {0x53, 0x51, 0x4c, 0x00, 0xff},
+			"config.yaml": []byte("project: zenith\n"),
+		},
+	}
+
+	if _, err := Seal(b, "short"); err == nil {
+		t.Error("Seal() should reject a short passphrase")
+	}
+
+	sealed, err := Seal(b, "correct horse battery")
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	got, err := Open(sealed, "correct horse battery")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if !got.CreatedAt.Equal(b.CreatedAt) || got.Hostname != "old-laptop" ||
+		string(got.Files["config.yaml"]) != "project: zenith\n" || len(got.Files["config.db"]) != 5 {
+		t.Errorf("Open() = %+v, want the sealed bundle", got)
+	}
+
+	if _, err := Open(sealed, "wrong horse battery"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() with the wrong passphrase = %v, want ErrDecrypt", err)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := Open(tampered, "correct horse battery"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() of a tampered bundle = %v, want ErrDecrypt", err)
+	}
+
+	if _, err := Open([]byte("project: zenith\n"), "correct horse battery"); err == nil || errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() of a non-bundle = %v, want a format error", err)
+	}
+}
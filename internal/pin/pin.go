@@ -0,0 +1,121 @@
+// Package pin keeps the environment pinned in each terminal by 'rw pin'.
+// Commands use it when their environment argument is left out.
+package pin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+// fileName is the pins file in the state directory
+const fileName = "pins.json"
+
+// MaxAge is how long a pin lasts: about a working day, so a terminal left
+// open overnight doesn't silently keep targeting yesterday's environment
+const MaxAge = 12 * time.Hour
+
+// ErrNoTerminal is returned when rw isn't running in a terminal session
+// that a pin could belong to
+var ErrNoTerminal = errors.New("can't tell which terminal this is, so nothing can be pinned to it")
+
+// Pin is an environment pinned in one terminal
+type Pin struct {
+	Environment string    `json:"environment"`
+	PinnedAt    time.Time `json:"pinned_at"`
+}
+
+// Expired reports whether the pin is older than MaxAge
+func (p Pin) Expired(now time.Time) bool {
+	return now.Sub(p.PinnedAt) > MaxAge
+}
+
+// Get returns the pin of the current terminal, or nil
+func Get() (*Pin, error) {
+	session, ok := utils.TerminalSession()
+	if !ok {
+		return nil, nil
+	}
+	pins, err := load()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := pins[session]
+	if !ok || p.Expired(time.Now()) {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// Set pins env in the current terminal
+func Set(env string) error {
+	session, ok := utils.TerminalSession()
+	if !ok {
+		return ErrNoTerminal
+	}
+	pins, err := load()
+	if err != nil {
+		return err
+	}
+	pins[session] = Pin{Environment: env, PinnedAt: time.Now()}
+	return save(pins)
+}
+
+// Clear removes the current terminal's pin and returns it, or nil if there
+// was none
+func Clear() (*Pin, error) {
+	session, ok := utils.TerminalSession()
+	if !ok {
+		return nil, nil
+	}
+	pins, err := load()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := pins[session]
+	if !ok {
+		return nil, nil
+	}
+	delete(pins, session)
+	if err := save(pins); err != nil {
+		return nil, err
+	}
+	if p.Expired(time.Now()) {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func load() (map[string]Pin, error) {
+	pins := make(map[string]Pin)
+	data, err := utils.ReadRoleWalkersFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return pins, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return pins, nil
+}
+
+// save writes the pins, dropping those of closed terminals and expired ones
+func save(pins map[string]Pin) error {
+	now := time.Now()
+	for session, p := range pins {
+		if p.Expired(now) || !utils.TerminalSessionAlive(session) {
+			delete(pins, session)
+		}
+	}
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteRoleWalkersFile(fileName, data)
+}
@@ -0,0 +1,58 @@
+package pin
+
+import (
+	"testing"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+func TestPin(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	if _, ok := utils.TerminalSession(); !ok {
+		t.Skip("not running in a terminal session")
+	}
+
+	if p, err := Get(); err != nil || p != nil {
+		t.Fatalf("Get() with no pins = %+v, %v, want nil", p, err)
+	}
+	if err := Set("dev"); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+	if p, err := Get(); err != nil || p == nil || p.Environment != "dev" {
+		t.Fatalf("Get() = %+v, %v, want dev", p, err)
+	}
+
+	p, err := Clear()
+	if err != nil || p == nil || p.Environment != "dev" {
+		t.Fatalf("Clear() = %+v, %v, want the dev pin", p, err)
+	}
+	if p, _ := Get(); p != nil {
+		t.Errorf("Get() after Clear() = %+v, want nil", p)
+	}
+}
+
+func TestSaveDropsStalePins(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	session, ok := utils.TerminalSession()
+	if !ok {
+		t.Skip("not running in a terminal session")
+	}
+
+	now := time.Now()
+	err := save(map[string]Pin{
+		session:      {Environment: "dev", PinnedAt: now},
+		"sid-999999": {Environment: "prod", PinnedAt: now}, // no such session
+		"old":        {Environment: "stage", PinnedAt: now.Add(-MaxAge - time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+	pins, err := load()
+	if err != nil {
+		t.Fatalf("load() error: %v", err)
+	}
+	if len(pins) != 1 || pins[session].Environment != "dev" {
+		t.Errorf("load() = %+v, want only the current session's pin", pins)
+	}
+}
@@ -0,0 +1,443 @@
+// Package qrcode encodes short text, such as a login URL, as a QR code that
+// can be printed to a terminal. It supports byte mode at error correction
+// level L, versions 1-10 (up to 271 bytes), which covers URLs.
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// version describes the block structure of one QR version at level L
+type version struct {
+	ecPerBlock  int
+	blocks      []int // data codewords per block, short blocks first
+	alignCoords []int
+}
+
+// versions are indexed by version number - 1 (ISO/IEC 18004 tables 9 and E.1)
+var versions = []version{
+	{7, []int{19}, nil},
+	{10, []int{34}, []int{6, 18}},
+	{15, []int{55}, []int{6, 22}},
+	{20, []int{80}, []int{6, 26}},
+	{26, []int{108}, []int{6, 30}},
+	{18, []int{68, 68}, []int{6, 34}},
+	{20, []int{78, 78}, []int{6, 22, 38}},
+	{24, []int{97, 97}, []int{6, 24, 42}},
+	{30, []int{116, 116}, []int{6, 26, 46}},
+	{18, []int{68, 68, 69, 69}, []int{6, 28, 50}},
+}
+
+// formatBitsL are the error correction level bits for level L
+const formatBitsL = 1
+
+// Code is an encoded QR code
+type Code struct {
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// Encode encodes text in the smallest version that fits
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	for i, v := range versions {
+		capacity := 0
+		for _, n := range v.blocks {
+			capacity += n
+		}
+		countBits := 8
+		if i+1 >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*capacity {
+			continue
+		}
+
+		codewords := encodeData(data, countBits, capacity)
+		c := newCode(i + 1)
+		c.drawFunctionPatterns(i + 1)
+		c.drawCodewords(interleave(codewords, v))
+		c.applyBestMask()
+		return c, nil
+	}
+	return nil, fmt.Errorf("text too long for a QR code: %d bytes", len(data))
+}
+
+// Dark reports whether the module at column x, row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y][x]
+}
+
+// Terminal renders the code with half-block characters, two rows per line.
+// With color it is drawn black on white so it scans on both light and dark
+// terminal themes. Without color the light modules are drawn as blocks in
+// the terminal's text color, which scans on the usual dark theme.
+func (c *Code) Terminal(color bool) string {
+	const quiet = 2
+	// blocks maps a dark top and bottom module to a character
+	blocks := [2][2]string{{" ", "▄"}, {"▀", "█"}}
+	if !color {
+		blocks = [2][2]string{{"█", "▀"}, {"▄", " "}}
+	}
+	b := func(dark bool) int {
+		if dark {
+			return 1
+		}
+		return 0
+	}
+
+	var sb strings.Builder
+	for y := -quiet; y < c.Size+quiet; y += 2 {
+		if color {
+			sb.WriteString("\x1b[30;47m")
+		}
+		for x := -quiet; x < c.Size+quiet; x++ {
+			sb.WriteString(blocks[b(c.Dark(x, y))][b(c.Dark(x, y+1))])
+		}
+		if color {
+			sb.WriteString("\x1b[0m")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func newCode(ver int) *Code {
+	size := 4*ver + 17
+	c := &Code{Size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range size {
+		c.modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	return c
+}
+
+// encodeData builds the data codewords: byte mode indicator, character
+// count, the data, a terminator and padding
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var bits []bool
+	appendBits := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>i&1 == 1)
+		}
+	}
+	appendBits(0b0100, 4)
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	appendBits(0, min(4, 8*capacity-len(bits)))
+	appendBits(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// interleave splits data into blocks, adds error correction to each and
+// interleaves the result
+func interleave(data []byte, v version) []byte {
+	divisor := rsDivisor(v.ecPerBlock)
+	var dataBlocks, ecBlocks [][]byte
+	for _, n := range v.blocks {
+		dataBlocks = append(dataBlocks, data[:n])
+		ecBlocks = append(ecBlocks, rsRemainder(data[:n], divisor))
+		data = data[n:]
+	}
+
+	var out []byte
+	for i := range v.blocks[len(v.blocks)-1] {
+		for _, b := range dataBlocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := range v.ecPerBlock {
+		for _, b := range ecBlocks {
+			out = append(out, b[i])
+		}
+	}
+	return out
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(ver int) {
+	for i := range c.Size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	for _, p := range [][2]int{{3, 3}, {c.Size - 4, 3}, {3, c.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := p[0]+dx, p[1]+dy
+				if x >= 0 && y >= 0 && x < c.Size && y < c.Size {
+					dist := max(abs(dx), abs(dy))
+					c.set(x, y, dist != 2 && dist != 4)
+				}
+			}
+		}
+	}
+
+	coords := versions[ver-1].alignCoords
+	last := len(coords) - 1
+	for i, cy := range coords {
+		for j, cx := range coords {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // overlaps a finder pattern
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	c.drawFormatBits(0) // reserves the area; redrawn once the mask is chosen
+
+	if ver >= 7 {
+		rem := ver
+		for range 12 {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := ver<<12 | rem
+		for i := range 18 {
+			dark := bits>>i&1 == 1
+			a, b := c.Size-11+i%3, i/3
+			c.set(a, b, dark)
+			c.set(b, a, dark)
+		}
+	}
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	data := formatBitsL<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true) // the dark module
+}
+
+// drawCodewords places the data bits in the two-column zigzag, right to
+// left, skipping function modules and the vertical timing pattern
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.function[y][x] && i < len(data)*8 {
+					c.modules[y][x] = data[i>>3]>>(7-i&7)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.function[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask tries each mask and keeps the one with the lowest penalty.
+// Masks are XOR, so applying one twice undoes it.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+}
+
+// penalty scores the current modules with the four rules of ISO/IEC 18004
+// section 8.8.2: long runs, 2x2 blocks, finder-like patterns and imbalance
+func (c *Code) penalty() int {
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return c.modules[x][y]
+		}
+		return c.modules[y][x]
+	}
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	score := 0
+	for _, vertical := range []bool{false, true} {
+		for y := range c.Size {
+			run := 1
+			for x := 1; x <= c.Size; x++ {
+				if x < c.Size && at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+11 <= c.Size; x++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, dark := range pattern {
+						if at(x+k, y, vertical) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				v := c.modules[y][x]
+				if c.modules[y][x+1] == v && c.modules[y+1][x] == v && c.modules[y+1][x+1] == v {
+					score += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (c.Size * c.Size)
+	score += abs(percent-50) / 5 * 10
+	return score
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given
+// degree, highest coefficient first and the leading 1 omitted
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMul(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,312 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at 1-M, from the worked example in ISO/IEC 18004 annex I
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestEncodeData(t *testing.T) {
+	got := encodeData([]byte("hi"), 8, 19)
+	want := []byte{0x40, 0x26, 0x86, 0x90, 0xEC, 0x11, 0xEC}
+	if !bytes.Equal(got[:len(want)], want) || len(got) != 19 {
+		t.Errorf("encodeData() = %x, want prefix %x and 19 codewords", got, want)
+	}
+}
+
+func TestEncodeVersionAndPatterns(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+	}{
+		{"hi", 21},
+		{"https://device.sso.eu-west-2.amazonaws.com/?user_code=ABCD-EFGH", 33},
+		{strings.Repeat("x", 200), 53},
+	}
+	for _, tt := range tests {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes) error: %v", len(tt.text), err)
+		}
+		if c.Size != tt.size {
+			t.Errorf("Encode(%d bytes) size = %d, want %d", len(tt.text), c.Size, tt.size)
+		}
+		// Finder pattern corners and centers, timing pattern and dark module
+		for _, p := range [][2]int{{0, 0}, {3, 3}, {c.Size - 1, 0}, {c.Size - 4, 3}, {0, c.Size - 1}, {3, c.Size - 4}, {8, c.Size - 8}} {
+			if !c.Dark(p[0], p[1]) {
+				t.Errorf("size %d: module %v should be dark", c.Size, p)
+			}
+		}
+		if c.Dark(7, 7) || c.Dark(1, 1) || c.Dark(7, 6) || c.Dark(6, 9) || !c.Dark(6, 10) {
+			t.Errorf("size %d: separator or timing pattern is wrong", c.Size)
+		}
+		// Both copies of the format information must agree
+		for i := range 8 {
+			if c.Dark(c.Size-1-i, 8) != c.formatBit(i) {
+				t.Errorf("size %d: format bit %d differs between copies", c.Size, i)
+			}
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("x", 272)); err == nil {
+		t.Error("Encode() should fail for text longer than version 10 holds")
+	}
+}
+
+// formatBit reads bit i of the format information from its first copy
+func (c *Code) formatBit(i int) bool {
+	switch {
+	case i <= 5:
+		return c.Dark(8, i)
+	case i == 6:
+		return c.Dark(8, 7)
+	default:
+		return c.Dark(8, 8)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	// Level L, mask 0 is 111011111000100 (ISO/IEC 18004 table C.1)
+	c := newCode(1)
+	c.drawFormatBits(0)
+	var got strings.Builder
+	for i := 14; i >= 0; i-- {
+		dark := c.Dark(8, c.Size-15+i)
+		if i < 8 {
+			dark = c.Dark(c.Size-1-i, 8)
+		}
+		if dark {
+			got.WriteByte('1')
+		} else {
+			got.WriteByte('0')
+		}
+	}
+	if got.String() != "111011111000100" {
+		t.Errorf("format bits = %s, want 111011111000100", got.String())
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	c, err := Encode("hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, color := range []bool{true, false} {
+		out := c.Terminal(color)
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		if want := (c.Size + 4 + 1) / 2; len(lines) != want {
+			t.Errorf("Terminal(%v) has %d lines, want %d", color, len(lines), want)
+		}
+		if strings.Contains(out, "\x1b[") != color {
+			t.Errorf("Terminal(%v) ANSI escapes = %v, want %v", color, !color, color)
+		}
+	}
+}
+
+func TestEncodeDecodesToInput(t *testing.T) {
+	inputs := []string{
+		"hi",
+		"https://device.sso.eu-west-2.amazonaws.com/?user_code=ABCD-EFGH",
+		strings.Repeat("0123456789abcdef", 9), // version 7: version information
+		strings.Repeat("x", 200),              // version 10: four blocks, 16-bit count
+		"caf\u00e9 \u2601 \x00\xff bytes",     // non-ASCII and control bytes
+	}
+	for _, text := range inputs {
+		c, err := Encode(text)
+		if err != nil {
+			t.Fatalf("Encode(%q) error: %v", text, err)
+		}
+		for _, color := range []bool{true, false} {
+			got, err := decode(scanTerminal(t, c.Terminal(color), color))
+			if err != nil {
+				t.Errorf("decode(Encode(%q)) with color %v error: %v", text, color, err)
+				continue
+			}
+			if got != text {
+				t.Errorf("decode(Encode(%q)) with color %v = %q", text, color, got)
+			}
+		}
+	}
+}
+
+// scanTerminal reads the module grid back from Terminal's output, without
+// its quiet zone
+func scanTerminal(t *testing.T, out string, color bool) [][]bool {
+	t.Helper()
+	const quiet = 2
+	out = strings.NewReplacer("\x1b[30;47m", "", "\x1b[0m", "").Replace(out)
+	var rows [][]bool
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var top, bottom []bool
+		for _, r := range line {
+			up, down := r == '▀' || r == '█', r == '▄' || r == '█'
+			if !color {
+				up, down = !up, !down
+			}
+			top, bottom = append(top, up), append(bottom, down)
+		}
+		rows = append(rows, top, bottom)
+	}
+	size := len(rows[0]) - 2*quiet
+	grid := make([][]bool, size)
+	for y := range size {
+		grid[y] = rows[y+quiet][quiet : quiet+size]
+	}
+	return grid
+}
+
+// Level L block structure per version (ISO/IEC 18004 table 9): total
+// codewords, data codewords and number of blocks
+var (
+	testTotalCodewords = []int{26, 44, 70, 100, 134, 172, 196, 242, 292, 346}
+	testDataCodewords  = []int{19, 34, 55, 80, 108, 136, 156, 194, 232, 274}
+	testBlocks         = []int{1, 1, 1, 1, 1, 2, 2, 2, 2, 4}
+	testAlignment      = [][]int{nil, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+		{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50}}
+)
+
+// decode reads a byte-mode QR code the way a scanner does: format
+// information, unmasking, the codeword zigzag, de-interleaving, an error
+// correction check and the data segment
+func decode(grid [][]bool) (string, error) {
+	size := len(grid)
+	ver := (size - 17) / 4
+	if ver < 1 || ver > 10 || 4*ver+17 != size {
+		return "", fmt.Errorf("size %d is not a version 1-10 code", size)
+	}
+
+	// Format information, first copy, bit 0 first
+	pos := [][2]int{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8},
+		{7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}}
+	format := 0
+	for i, p := range pos {
+		if grid[p[1]][p[0]] {
+			format |= 1 << i
+		}
+	}
+	format ^= 0x5412
+	if level := format >> 13; level != 1 {
+		return "", fmt.Errorf("error correction level bits %02b, want L (01)", level)
+	}
+	mask := format >> 10 & 7
+
+	// Function modules
+	reserved := make([][]bool, size)
+	for y := range reserved {
+		reserved[y] = make([]bool, size)
+	}
+	fill := func(x0, y0, x1, y1 int) {
+		for y := max(y0, 0); y <= min(y1, size-1); y++ {
+			for x := max(x0, 0); x <= min(x1, size-1); x++ {
+				reserved[y][x] = true
+			}
+		}
+	}
+	fill(0, 0, 8, 8)           // top-left finder, separator and format
+	fill(size-8, 0, size-1, 8) // top-right
+	fill(0, size-8, 8, size-1) // bottom-left, including the dark module
+	fill(6, 0, 6, size-1)      // timing
+	fill(0, 6, size-1, 6)
+	coords := testAlignment[ver-1]
+	for i, cy := range coords {
+		for j, cx := range coords {
+			last := len(coords) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			fill(cx-2, cy-2, cx+2, cy+2)
+		}
+	}
+	if ver >= 7 {
+		fill(size-11, 0, size-9, 5)
+		fill(0, size-11, 5, size-9)
+	}
+
+	// Codewords from the zigzag, unmasked
+	var bits []bool
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range size {
+			for j := range 2 {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if !reserved[y][x] {
+					bits = append(bits, grid[y][x] != maskBit(mask, x, y))
+				}
+			}
+		}
+	}
+	total := testTotalCodewords[ver-1]
+	if len(bits) < 8*total {
+		return "", fmt.Errorf("%d data modules, want at least %d", len(bits), 8*total)
+	}
+	codewords := make([]byte, total)
+	for i := range 8 * total {
+		if bits[i] {
+			codewords[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+
+	// De-interleave the blocks and check their error correction
+	dataTotal, blocks := testDataCodewords[ver-1], testBlocks[ver-1]
+	ecLen := (total - dataTotal) / blocks
+	short := dataTotal / blocks
+	numShort := blocks - dataTotal%blocks
+	dataBlocks := make([][]byte, blocks)
+	k := 0
+	for i := range short + 1 {
+		for b := range blocks {
+			if i < short || b >= numShort {
+				dataBlocks[b] = append(dataBlocks[b], codewords[k])
+				k++
+			}
+		}
+	}
+	var data []byte
+	for b := range blocks {
+		ec := make([]byte, ecLen)
+		for i := range ecLen {
+			ec[i] = codewords[dataTotal+i*blocks+b]
+		}
+		if !bytes.Equal(ec, rsRemainder(dataBlocks[b], rsDivisor(ecLen))) {
+			return "", fmt.Errorf("block %d fails its error correction check", b)
+		}
+		data = append(data, dataBlocks[b]...)
+	}
+
+	// Byte mode segment
+	if data[0]>>4 != 0b0100 {
+		return "", fmt.Errorf("mode %04b, want byte mode", data[0]>>4)
+	}
+	read := func(bit, n int) int {
+		v := 0
+		for i := bit; i < bit+n; i++ {
+			v = v<<1 | int(data[i/8]>>(7-i%8)&1)
+		}
+		return v
+	}
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+	n := read(4, countBits)
+	out := make([]byte, n)
+	for i := range n {
+		out[i] = byte(read(4+countBits+8*i, 8))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,181 @@
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Assets maps archive paths (e.g. BashCompletionPath) to file contents
+type Assets map[string][]byte
+
+// GzipManPage compresses a roff man page for ManDir. The gzip header
+// carries no name or timestamp, so the output is reproducible.
+func GzipManPage(page string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(zw, page); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Stage lays out a platform's archive contents in outDir/rw_<version>_<os>_<arch>:
+// the binary, and the assets at their archive paths. It returns the
+// staging directory.
+func Stage(binary string, p Platform, version string, assets Assets, outDir string) (string, error) {
+	dir := filepath.Join(outDir, fmt.Sprintf("%s_%s_%s", BinaryName, version, p))
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(binary)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s binary: %w", p, err)
+	}
+	if err := writeFile(filepath.Join(dir, p.Executable()), data, 0755); err != nil {
+		return "", err
+	}
+	for path, content := range assets {
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(path)), content, 0644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// Archive packs a staging directory into outDir as a tar.gz (zip on
+// Windows) and returns the artifact with its SHA-256
+func Archive(dir string, p Platform, version, outDir string, modTime time.Time) (Artifact, error) {
+	files, err := stagedFiles(dir)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	name := p.ArchiveName(version)
+	path := filepath.Join(outDir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	w := io.MultiWriter(f, hash)
+	if p.OS == "windows" {
+		err = writeZip(w, dir, files, modTime)
+	} else {
+		err = writeTarGz(w, dir, files, modTime)
+	}
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := f.Close(); err != nil {
+		return Artifact{}, err
+	}
+
+	return Artifact{
+		Platform: p,
+		Archive:  name,
+		Dir:      dir,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// stagedFiles returns the slash-separated paths of the files under dir, sorted
+func stagedFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func writeTarGz(w io.Writer, dir string, files []string, modTime time.Time) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	for _, name := range files {
+		data, info, err := readStaged(dir, name)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    int64(info.Mode().Perm()),
+			Size:    int64(len(data)),
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZip(w io.Writer, dir string, files []string, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, name := range files {
+		data, info, err := readStaged(dir, name)
+		if err != nil {
+			return err
+		}
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+		hdr.SetMode(info.Mode().Perm())
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func readStaged(dir, name string) ([]byte, os.FileInfo, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	return data, info, err
+}
@@ -0,0 +1,227 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+var templateFuncs = template.FuncMap{
+	// quote renders a string as a double-quoted literal, valid in both
+	// Ruby and YAML
+	"quote": func(s string) string {
+		var sb strings.Builder
+		enc := json.NewEncoder(&sb)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(s)
+		return strings.TrimSuffix(sb.String(), "\n")
+	},
+	"src": func(dir, asset string) string {
+		return path.Join(strings.ReplaceAll(dir, "\\", "/"), asset)
+	},
+}
+
+var homebrewTemplate = template.Must(template.New("homebrew").Funcs(templateFuncs).Parse(`# Generated by 'make release'. Do not edit.
+class Rw < Formula
+  desc {{quote .Description}}
+  homepage {{quote .Release.Homepage}}
+  version {{quote .Release.Version}}
+{{- range .Groups}}
+
+  {{.Block}} do
+{{- range .Artifacts}}
+    {{.Block}} do
+      url {{quote .URL}}
+      sha256 {{quote .SHA256}}
+    end
+{{- end}}
+  end
+{{- end}}
+
+  def install
+    bin.install "rw"
+    bash_completion.install {{quote .BashCompletion}} => "rw"
+    zsh_completion.install {{quote .ZshCompletion}}
+    fish_completion.install {{quote .FishCompletion}}
+    man1.install Dir[{{quote .ManPages}}]
+  end
+
+  test do
+    assert_match version.to_s, shell_output("#{bin}/rw version")
+  end
+end
+`))
+
+type homebrewArtifact struct {
+	Block  string
+	URL    string
+	SHA256 string
+}
+
+type homebrewGroup struct {
+	Block     string
+	Artifacts []homebrewArtifact
+}
+
+// Homebrew renders a Homebrew formula installing the macOS and Linux
+// archives, with completions and man pages
+func Homebrew(r Release) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+
+	var groups []homebrewGroup
+	for _, goos := range []string{"darwin", "linux"} {
+		group := homebrewGroup{Block: map[string]string{"darwin": "on_macos", "linux": "on_linux"}[goos]}
+		for _, goarch := range []string{"arm64", "amd64"} {
+			a, ok := r.Artifact(goos, goarch)
+			if !ok {
+				continue
+			}
+			group.Artifacts = append(group.Artifacts, homebrewArtifact{
+				Block:  map[string]string{"arm64": "on_arm", "amd64": "on_intel"}[goarch],
+				URL:    r.URL(a),
+				SHA256: a.SHA256,
+			})
+		}
+		if len(group.Artifacts) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return "", fmt.Errorf("no macOS or Linux archives for the Homebrew formula")
+	}
+
+	var sb strings.Builder
+	err := homebrewTemplate.Execute(&sb, map[string]any{
+		"Description":    Description,
+		"Release":        r,
+		"Groups":         groups,
+		"BashCompletion": BashCompletionPath,
+		"ZshCompletion":  ZshCompletionPath,
+		"FishCompletion": FishCompletionPath,
+		"ManPages":       ManDir + "/*",
+	})
+	return sb.String(), err
+}
+
+type scoopArch struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash"`
+}
+
+type scoopManifest struct {
+	Version      string               `json:"version"`
+	Description  string               `json:"description"`
+	Homepage     string               `json:"homepage"`
+	Architecture map[string]scoopArch `json:"architecture"`
+	Bin          string               `json:"bin"`
+	Notes        []string             `json:"notes"`
+	CheckVer     string               `json:"checkver"`
+	AutoUpdate   map[string]any       `json:"autoupdate"`
+}
+
+// Scoop renders a Scoop manifest for the Windows archive. Scoop has no
+// completion hook, so the notes tell users how to load the PowerShell one.
+func Scoop(r Release) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	a, ok := r.Artifact("windows", "amd64")
+	if !ok {
+		return "", fmt.Errorf("no Windows archive for the Scoop manifest")
+	}
+
+	manifest := scoopManifest{
+		Version:     r.Version,
+		Description: Description,
+		Homepage:    r.Homepage(),
+		Architecture: map[string]scoopArch{
+			"64bit": {URL: r.URL(a), Hash: a.SHA256},
+		},
+		Bin: a.Platform.Executable(),
+		Notes: []string{
+			"For PowerShell tab completion, add this line to your $PROFILE:",
+			`  . "$dir\` + strings.ReplaceAll(PowerShellCompletionPath, "/", `\`) + `"`,
+		},
+		CheckVer: "github",
+		AutoUpdate: map[string]any{
+			"architecture": map[string]any{
+				"64bit": map[string]string{
+					"url": fmt.Sprintf("%s/releases/download/v$version/%s", r.Homepage(), a.Platform.ArchiveName("$version")),
+				},
+			},
+		},
+	}
+
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(manifest); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+var nfpmTemplate = template.Must(template.New("nfpm").Funcs(templateFuncs).Parse(`# Generated by 'make release'. Do not edit.
+# Build packages with: nfpm pkg --packager deb|rpm --config <this file>
+name: rw
+arch: {{.Platform.Arch}}
+platform: {{.Platform.OS}}
+version: {{quote .Release.Version}}
+section: utils
+priority: optional
+maintainer: {{quote .Maintainer}}
+description: {{quote .Description}}
+homepage: {{quote .Release.Homepage}}
+contents:
+  - src: {{quote (src .Dir .Executable)}}
+    dst: /usr/bin/rw
+    file_info:
+      mode: 0755
+  - src: {{quote (src .Dir .BashCompletion)}}
+    dst: /usr/share/bash-completion/completions/rw
+  - src: {{quote (src .Dir .ZshCompletion)}}
+    dst: /usr/share/zsh/vendor-completions/_rw
+    packager: deb
+  - src: {{quote (src .Dir .ZshCompletion)}}
+    dst: /usr/share/zsh/site-functions/_rw
+    packager: rpm
+  - src: {{quote (src .Dir .FishCompletion)}}
+    dst: /usr/share/fish/vendor_completions.d/rw.fish
+  - src: {{quote (src .Dir .ManPages)}}
+    dst: /usr/share/man/man1/
+`))
+
+// NFPM renders an nfpm config building deb and rpm packages from a Linux
+// artifact's staging directory. Paths are relative to where the release
+// generator ran, so nfpm must run from the same directory.
+func NFPM(r Release, a Artifact, maintainer string) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	if a.Platform.OS != "linux" {
+		return "", fmt.Errorf("nfpm packages are Linux only, got %s", a.Platform)
+	}
+	if maintainer == "" {
+		return "", fmt.Errorf("a maintainer (\"Name <email>\") is required for deb/rpm packages")
+	}
+
+	var sb strings.Builder
+	err := nfpmTemplate.Execute(&sb, map[string]any{
+		"Release":        r,
+		"Platform":       a.Platform,
+		"Dir":            a.Dir,
+		"Executable":     a.Platform.Executable(),
+		"Maintainer":     maintainer,
+		"Description":    Description,
+		"BashCompletion": BashCompletionPath,
+		"ZshCompletion":  ZshCompletionPath,
+		"FishCompletion": FishCompletionPath,
+		"ManPages":       ManDir + "/*",
+	})
+	return sb.String(), err
+}
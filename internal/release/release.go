@@ -0,0 +1,125 @@
+// Package release builds release archives for rw and renders the packaging
+// metadata that installs them: a Homebrew formula, a Scoop manifest and
+// nfpm configs for deb/rpm. Completions and the man page are generated by
+// the cli package, so packages pick up new commands without manual edits.
+package release
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BinaryName is the installed command name
+const BinaryName = "rw"
+
+// Description is the one-line package description
+const Description = "AWS profile & SSO manager with kubectl, tunnel and database helpers"
+
+// Paths of the generated assets inside each release archive
+const (
+	BashCompletionPath       = "completions/rw.bash"
+	ZshCompletionPath        = "completions/_rw"
+	FishCompletionPath       = "completions/rw.fish"
+	PowerShellCompletionPath = "completions/rw.ps1"
+	// ManDir holds gzipped man pages: rw.1.gz and one per command
+	ManDir = "man/man1"
+)
+
+// Platform is a GOOS/GOARCH pair rw is released for
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// Platforms are the release targets, matching 'make build-all'
+var Platforms = []Platform{
+	{OS: "darwin", Arch: "arm64"},
+	{OS: "darwin", Arch: "amd64"},
+	{OS: "linux", Arch: "amd64"},
+	{OS: "windows", Arch: "amd64"},
+}
+
+// String returns "os_arch"
+func (p Platform) String() string {
+	return p.OS + "_" + p.Arch
+}
+
+// Executable returns the binary file name inside the archive
+func (p Platform) Executable() string {
+	if p.OS == "windows" {
+		return BinaryName + ".exe"
+	}
+	return BinaryName
+}
+
+// BuildOutput returns the name 'make build-all' gives the binary in bin/
+func (p Platform) BuildOutput() string {
+	name := BinaryName + "-" + p.OS + "-" + p.Arch
+	if p.OS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// ArchiveName returns the release archive file name, e.g.
+// rw_1.2.0_darwin_arm64.tar.gz (.zip on Windows)
+func (p Platform) ArchiveName(version string) string {
+	ext := ".tar.gz"
+	if p.OS == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("%s_%s_%s%s", BinaryName, version, p, ext)
+}
+
+// Artifact is a built release archive
+type Artifact struct {
+	Platform Platform
+	// Archive is the archive file name
+	Archive string
+	// Dir is the staging directory the archive was built from
+	Dir    string
+	SHA256 string
+}
+
+// Release describes a version and its archives, for rendering packaging
+// metadata
+type Release struct {
+	Version string
+	// Repo is the GitHub repository (owner/name) hosting the release
+	Repo      string
+	Artifacts []Artifact
+}
+
+// Homepage returns the repository URL
+func (r Release) Homepage() string {
+	return "https://github.com/" + r.Repo
+}
+
+// URL returns the download URL of an artifact's archive
+func (r Release) URL(a Artifact) string {
+	return fmt.Sprintf("https://github.com/%s/releases/download/v%s/%s", r.Repo, r.Version, a.Archive)
+}
+
+// Artifact returns the archive built for a platform
+func (r Release) Artifact(goos, goarch string) (Artifact, bool) {
+	for _, a := range r.Artifacts {
+		if a.Platform.OS == goos && a.Platform.Arch == goarch {
+			return a, true
+		}
+	}
+	return Artifact{}, false
+}
+
+// Validate checks that the release can be rendered
+func (r Release) Validate() error {
+	if r.Version == "" || strings.HasPrefix(r.Version, "v") {
+		return fmt.Errorf("version must be set without a leading 'v' (got %q)", r.Version)
+	}
+	if owner, name, ok := strings.Cut(r.Repo, "/"); !ok || owner == "" || name == "" {
+		return fmt.Errorf("repo must be a GitHub owner/name (got %q)", r.Repo)
+	}
+	if len(r.Artifacts) == 0 {
+		return fmt.Errorf("no release archives")
+	}
+	return nil
+}
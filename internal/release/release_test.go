@@ -0,0 +1,175 @@
+package release
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRelease(t *testing.T) Release {
+	t.Helper()
+	r := Release{Version: "1.2.0", Repo: "acme/rolewalker"}
+	for _, p := range Platforms {
+		r.Artifacts = append(r.Artifacts, Artifact{
+			Platform: p,
+			Archive:  p.ArchiveName(r.Version),
+			Dir:      filepath.Join("dist", "rw_1.2.0_"+p.String()),
+			SHA256:   strings.Repeat(p.Arch[:1], 64),
+		})
+	}
+	return r
+}
+
+func TestHomebrew(t *testing.T) {
+	formula, err := Homebrew(testRelease(t))
+	if err != nil {
+		t.Fatalf("Homebrew: %v", err)
+	}
+	for _, want := range []string{
+		`version "1.2.0"`,
+		`url "https://github.com/acme/rolewalker/releases/download/v1.2.0/rw_1.2.0_darwin_arm64.tar.gz"`,
+		`url "https://github.com/acme/rolewalker/releases/download/v1.2.0/rw_1.2.0_linux_amd64.tar.gz"`,
+		"on_macos do", "on_arm do", "on_linux do",
+		`bash_completion.install "completions/rw.bash" => "rw"`,
+		`zsh_completion.install "completions/_rw"`,
+		`fish_completion.install "completions/rw.fish"`,
+		`man1.install Dir["man/man1/*"]`,
+	} {
+		if !strings.Contains(formula, want) {
+			t.Errorf("formula missing %q:\n%s", want, formula)
+		}
+	}
+	if strings.Contains(formula, "windows") {
+		t.Errorf("formula should not reference the Windows archive:\n%s", formula)
+	}
+}
+
+func TestScoop(t *testing.T) {
+	out, err := Scoop(testRelease(t))
+	if err != nil {
+		t.Fatalf("Scoop: %v", err)
+	}
+	var manifest scoopManifest
+	if err := json.Unmarshal([]byte(out), &manifest); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v\n%s", err, out)
+	}
+	if manifest.Version != "1.2.0" || manifest.Bin != "rw.exe" {
+		t.Errorf("version/bin = %q/%q", manifest.Version, manifest.Bin)
+	}
+	arch := manifest.Architecture["64bit"]
+	if !strings.HasSuffix(arch.URL, "/v1.2.0/rw_1.2.0_windows_amd64.zip") || arch.Hash != strings.Repeat("a", 64) {
+		t.Errorf("64bit = %+v", arch)
+	}
+	if !strings.Contains(strings.Join(manifest.Notes, "\n"), `completions\rw.ps1`) {
+		t.Errorf("notes should explain loading the PowerShell completion: %v", manifest.Notes)
+	}
+}
+
+func TestNFPM(t *testing.T) {
+	r := testRelease(t)
+	linux, _ := r.Artifact("linux", "amd64")
+
+	config, err := NFPM(r, linux, "Ops <ops@example.com>")
+	if err != nil {
+		t.Fatalf("NFPM: %v", err)
+	}
+	for _, want := range []string{
+		"arch: amd64",
+		`version: "1.2.0"`,
+		`maintainer: "Ops <ops@example.com>"`,
+		`src: "dist/rw_1.2.0_linux_amd64/rw"`,
+		"dst: /usr/bin/rw",
+		"dst: /usr/share/bash-completion/completions/rw",
+		"dst: /usr/share/fish/vendor_completions.d/rw.fish",
+		`src: "dist/rw_1.2.0_linux_amd64/man/man1/*"`,
+		"dst: /usr/share/man/man1/",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("nfpm config missing %q:\n%s", want, config)
+		}
+	}
+
+	darwin, _ := r.Artifact("darwin", "arm64")
+	if _, err := NFPM(r, darwin, "Ops <ops@example.com>"); err == nil {
+		t.Error("expected an error for a non-Linux artifact")
+	}
+	if _, err := NFPM(r, linux, ""); err == nil {
+		t.Error("expected an error without a maintainer")
+	}
+}
+
+func TestReleaseValidate(t *testing.T) {
+	r := testRelease(t)
+	r.Version = "v1.2.0"
+	if err := r.Validate(); err == nil {
+		t.Error("expected an error for a version with a leading v")
+	}
+	r = testRelease(t)
+	r.Repo = "rolewalker"
+	if err := r.Validate(); err == nil {
+		t.Error("expected an error for a repo without an owner")
+	}
+}
+
+func TestStageAndArchive(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "rw-linux-amd64")
+	if err := os.WriteFile(binary, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	man, err := GzipManPage(".TH RW 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manPath := ManDir + "/rw.1.gz"
+	assets := Assets{BashCompletionPath: []byte("complete -F _rw rw\n"), manPath: man}
+
+	p := Platform{OS: "linux", Arch: "amd64"}
+	staged, err := Stage(binary, p, "1.2.0", assets, dir)
+	if err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, err := Archive(staged, p, "1.2.0", dir, modTime)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	second, err := Archive(staged, p, "1.2.0", dir, modTime)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if first.SHA256 != second.SHA256 {
+		t.Errorf("archives are not reproducible: %s != %s", first.SHA256, second.SHA256)
+	}
+
+	f, err := os.Open(filepath.Join(dir, first.Archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "rw" && hdr.Mode&0111 == 0 {
+			t.Errorf("binary is not executable: mode %o", hdr.Mode)
+		}
+	}
+	want := []string{BashCompletionPath, manPath, "rw"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("archive entries = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,193 @@
+// Package slack serves a Slack slash command that runs a restricted set of
+// read-only rw operations. Requests are authenticated with Slack's signing
+// secret (https://api.slack.com/authentication/verifying-requests-from-slack).
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRequestAge rejects replayed requests older than Slack's recommended
+// five minutes
+const maxRequestAge = 5 * time.Minute
+
+// maxBody limits the size of a slash command request
+const maxBody = 64 * 1024
+
+// ackTimeout is how long a command may run before the request is answered
+// with an acknowledgement and the result is posted to the response URL.
+// Slack gives up on slash commands after three seconds.
+const ackTimeout = 2500 * time.Millisecond
+
+// responseURLPrefix is where Slack's delayed responses go; other response
+// URLs are refused so the server can't be used to make arbitrary requests
+const responseURLPrefix = "https://hooks.slack.com/"
+
+// Command is a slash command subcommand, e.g. "maintenance status"
+type Command struct {
+	Name  string
+	Usage string
+	// Run returns the text to post, given the words after Name
+	Run func(args []string) (string, error)
+}
+
+// Handler answers slash command requests
+type Handler struct {
+	secret   []byte
+	commands map[string]Command
+	client   *http.Client
+	now      func() time.Time
+	ack      time.Duration
+
+	// mu serialises commands: the managers behind them switch the kubectl
+	// context and are not safe for concurrent use
+	mu sync.Mutex
+}
+
+// NewHandler creates a handler that verifies requests with signingSecret
+func NewHandler(signingSecret string, commands ...Command) *Handler {
+	h := &Handler{
+		secret:   []byte(signingSecret),
+		commands: make(map[string]Command, len(commands)),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+		ack:      ackTimeout,
+	}
+	for _, c := range commands {
+		h.commands[c.Name] = c
+	}
+	return h
+}
+
+// response is a slash command reply
+type response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if err := Verify(h.secret, r.Header, body, h.now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	done := make(chan string, 1)
+	go func() { done <- h.run(form.Get("text")) }()
+
+	select {
+	case text := <-done:
+		writeResponse(w, text)
+	case <-time.After(h.ack):
+		responseURL := form.Get("response_url")
+		writeResponse(w, "Working on it…")
+		go func() {
+			text := <-done
+			if strings.HasPrefix(responseURL, responseURLPrefix) {
+				h.post(responseURL, text)
+			}
+		}()
+	}
+}
+
+// run dispatches the command text, e.g. "maintenance status prod"
+func (h *Handler) run(text string) string {
+	words := strings.Fields(text)
+	for n := min(2, len(words)); n >= 1; n-- {
+		cmd, ok := h.commands[strings.Join(words[:n], " ")]
+		if !ok {
+			continue
+		}
+		h.mu.Lock()
+		out, err := cmd.Run(words[n:])
+		h.mu.Unlock()
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		return "```\n" + strings.TrimRight(out, "\n") + "\n```"
+	}
+	return h.help(text)
+}
+
+func (h *Handler) help(text string) string {
+	var sb strings.Builder
+	if strings.TrimSpace(text) != "" {
+		fmt.Fprintf(&sb, "Unknown command: %s\n", text)
+	}
+	sb.WriteString("Available commands (read-only):\n")
+	names := make([]string, 0, len(h.commands))
+	for name := range h.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "• `%s`\n", h.commands[name].Usage)
+	}
+	return sb.String()
+}
+
+func (h *Handler) post(responseURL, text string) {
+	body, _ := json.Marshal(response{ResponseType: "ephemeral", Text: text})
+	resp, err := h.client.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+func writeResponse(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response{ResponseType: "ephemeral", Text: text})
+}
+
+// Verify checks a request's X-Slack-Signature against the signing secret
+// and rejects requests whose X-Slack-Request-Timestamp is too old
+func Verify(secret []byte, header http.Header, body []byte, now time.Time) error {
+	if len(secret) == 0 {
+		return errors.New("no signing secret configured")
+	}
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid request timestamp")
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return errors.New("request timestamp is too old")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid request signature")
+	}
+	return nil
+}
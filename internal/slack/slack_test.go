@@ -0,0 +1,128 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func signedRequest(t *testing.T, secret, text string, at time.Time) *http.Request {
+	t.Helper()
+	body := url.Values{"command": {"/rw"}, "text": {text}, "response_url": {"https://hooks.slack.com/commands/T1/2/x"}}.Encode()
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func serve(h *Handler, req *http.Request) (int, response) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var resp response
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp
+}
+
+func testHandler(calls *[]string) *Handler {
+	return NewHandler(testSecret,
+		Command{Name: "maintenance status", Usage: "maintenance status <env>", Run: func(args []string) (string, error) {
+			*calls = append(*calls, "maintenance status "+strings.Join(args, " "))
+			return "API: disabled\n", nil
+		}},
+		Command{Name: "scale list", Usage: "scale list <env>", Run: func(args []string) (string, error) {
+			return "", errors.New("invalid environment: nope")
+		}},
+	)
+}
+
+func TestHandlerRunsVerifiedCommands(t *testing.T) {
+	var calls []string
+	h := testHandler(&calls)
+
+	code, resp := serve(h, signedRequest(t, testSecret, "maintenance status prod", time.Now()))
+	if code != http.StatusOK || resp.ResponseType != "ephemeral" {
+		t.Fatalf("ServeHTTP() = %d %+v, want an ephemeral reply", code, resp)
+	}
+	if len(calls) != 1 || calls[0] != "maintenance status prod" {
+		t.Errorf("calls = %q, want the command run with its arguments", calls)
+	}
+	if resp.Text != "```\nAPI: disabled\n```" {
+		t.Errorf("Text = %q, want the output in a code block", resp.Text)
+	}
+
+	if _, resp := serve(h, signedRequest(t, testSecret, "scale list nope", time.Now())); !strings.HasPrefix(resp.Text, "Error: invalid environment") {
+		t.Errorf("Text = %q, want the command's error", resp.Text)
+	}
+	if _, resp := serve(h, signedRequest(t, testSecret, "scale prod --preset normal", time.Now())); !strings.Contains(resp.Text, "Unknown command") || !strings.Contains(resp.Text, "maintenance status <env>") {
+		t.Errorf("Text = %q, want help for a command that isn't exposed", resp.Text)
+	}
+}
+
+func TestHandlerRejectsUnverifiedRequests(t *testing.T) {
+	var calls []string
+	h := testHandler(&calls)
+
+	if code, _ := serve(h, signedRequest(t, "wrong-secret", "maintenance status prod", time.Now())); code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", code)
+	}
+	if code, _ := serve(h, signedRequest(t, testSecret, "maintenance status prod", time.Now().Add(-10*time.Minute))); code != http.StatusUnauthorized {
+		t.Errorf("replayed request: status %d, want 401", code)
+	}
+	if len(calls) != 0 {
+		t.Errorf("calls = %q, want none for rejected requests", calls)
+	}
+	if err := Verify(nil, http.Header{}, nil, time.Now()); err == nil {
+		t.Error("Verify() without a secret should fail")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHandlerAcknowledgesSlowCommands(t *testing.T) {
+	release := make(chan struct{})
+	h := NewHandler(testSecret, Command{Name: "status", Usage: "status <env>", Run: func([]string) (string, error) {
+		<-release
+		return "ok", nil
+	}})
+	h.ack = 10 * time.Millisecond
+
+	posted := make(chan string, 1)
+	h.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var resp response
+		json.NewDecoder(r.Body).Decode(&resp)
+		posted <- r.URL.Host + " " + resp.Text
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}
+
+	if _, resp := serve(h, signedRequest(t, testSecret, "status prod", time.Now())); resp.Text != "Working on it…" {
+		t.Errorf("Text = %q, want an acknowledgement", resp.Text)
+	}
+	close(release)
+
+	select {
+	case got := <-posted:
+		if got != "hooks.slack.com ```\nok\n```" {
+			t.Errorf("posted %q, want the result sent to the response URL", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the result was never posted to the response URL")
+	}
+}
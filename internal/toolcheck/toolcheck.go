@@ -0,0 +1,229 @@
+// Package toolcheck checks that the external tools rw shells out to are
+// installed and recent enough: the aws CLI (v2 is needed for sso-session
+// profiles), kubectl (within two minor versions of the cluster) and, if
+// installed, the session-manager-plugin.
+package toolcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"rolewalkers/internal/utils"
+)
+
+// cacheFile records when the tools were last checked
+const cacheFile = "tool_check.json"
+
+// Interval is how often CheckDaily checks (and warns)
+const Interval = 24 * time.Hour
+
+// commandTimeout bounds each version command; kubectl asks the cluster
+const commandTimeout = 5 * time.Second
+
+// maxKubectlSkew is how many minor versions kubectl may be behind the cluster
+const maxKubectlSkew = 2
+
+// Version is a major.minor.patch version
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is older than o
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion finds the first version number in s, e.g. "aws-cli/2.15.0
+// Python/3.11.6" or "v1.29.3-eks-1234"
+func ParseVersion(s string) (Version, bool) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, false
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, true
+}
+
+// Finding is a tool that is missing or too old
+type Finding struct {
+	Tool    string
+	Problem string
+	// Hint is how to install or upgrade the tool on this OS
+	Hint string
+}
+
+// Runner runs a command and returns its combined output
+type Runner func(name string, args ...string) (string, error)
+
+func runCommand(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+// Checker checks tool versions
+type Checker struct {
+	run      Runner
+	lookPath func(string) (string, error)
+	goos     string
+}
+
+// New creates a checker that runs the real tools
+func New() *Checker {
+	return &Checker{run: runCommand, lookPath: exec.LookPath, goos: runtime.GOOS}
+}
+
+// Check checks every tool now
+func (c *Checker) Check() []Finding {
+	var findings []Finding
+	for _, check := range []func() *Finding{c.checkAWS, c.checkKubectl, c.checkSessionManagerPlugin} {
+		if f := check(); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	return findings
+}
+
+var minAWS = Version{Major: 2}
+
+func (c *Checker) checkAWS() *Finding {
+	out, err := c.run("aws", "--version")
+	v, ok := ParseVersion(out)
+	switch {
+	case err != nil && !ok:
+		return c.finding("aws", "not installed or not working")
+	case !ok:
+		return nil
+	case v.Less(minAWS):
+		return c.finding("aws", fmt.Sprintf("version %s is below %s; aws CLI v1 doesn't support sso-session profiles", v, minAWS))
+	}
+	return nil
+}
+
+// kubectlVersion is the part of 'kubectl version -o json' needed here
+type kubectlVersion struct {
+	ClientVersion *struct {
+		GitVersion string `json:"gitVersion"`
+	} `json:"clientVersion"`
+	ServerVersion *struct {
+		GitVersion string `json:"gitVersion"`
+	} `json:"serverVersion"`
+}
+
+func (c *Checker) checkKubectl() *Finding {
+	// Without a reachable cluster kubectl exits non-zero but still prints
+	// its own version
+	out, _ := c.run("kubectl", "version", "-o", "json", "--request-timeout=3s")
+	start := strings.IndexByte(out, '{')
+	var kv kubectlVersion
+	if start < 0 || json.NewDecoder(strings.NewReader(out[start:])).Decode(&kv) != nil || kv.ClientVersion == nil {
+		return c.finding("kubectl", "not installed or not working")
+	}
+	client, ok := ParseVersion(kv.ClientVersion.GitVersion)
+	if !ok || kv.ServerVersion == nil {
+		return nil
+	}
+	server, ok := ParseVersion(kv.ServerVersion.GitVersion)
+	if !ok || client.Major != server.Major || server.Minor-client.Minor <= maxKubectlSkew {
+		return nil
+	}
+	return c.finding("kubectl", fmt.Sprintf("version %d.%d is more than %d minor versions behind the cluster (%d.%d)",
+		client.Major, client.Minor, maxKubectlSkew, server.Major, server.Minor))
+}
+
+var minSessionManagerPlugin = Version{Major: 1, Minor: 2, Patch: 0}
+
+// checkSessionManagerPlugin checks the plugin only when it is installed:
+// rw's tunnels don't need it, but an outdated one breaks 'aws ssm
+// start-session' for the same users
+func (c *Checker) checkSessionManagerPlugin() *Finding {
+	if _, err := c.lookPath("session-manager-plugin"); err != nil {
+		return nil
+	}
+	out, _ := c.run("session-manager-plugin", "--version")
+	v, ok := ParseVersion(out)
+	if ok && v.Less(minSessionManagerPlugin) {
+		return c.finding("session-manager-plugin", fmt.Sprintf("version %s is below %s", v, minSessionManagerPlugin))
+	}
+	return nil
+}
+
+// installHints are per tool and OS; "" is the fallback
+var installHints = map[string]map[string]string{
+	"aws": {
+		"darwin":  "brew install awscli",
+		"windows": "msiexec.exe /i https://awscli.amazonaws.com/AWSCLIV2.msi",
+		"":        "https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+	},
+	"kubectl": {
+		"darwin":  "brew install kubectl",
+		"windows": "winget install -e --id Kubernetes.kubectl",
+		"":        "https://kubernetes.io/docs/tasks/tools/",
+	},
+	"session-manager-plugin": {
+		"darwin": "brew install --cask session-manager-plugin",
+		"":       "https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html",
+	},
+}
+
+func (c *Checker) finding(tool, problem string) *Finding {
+	hints := installHints[tool]
+	hint, ok := hints[c.goos]
+	if !ok {
+		hint = hints[""]
+	}
+	return &Finding{Tool: tool, Problem: problem, Hint: hint}
+}
+
+// cache is what cacheFile holds
+type cache struct {
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CheckDaily checks the tools unless they were checked within Interval,
+// so problems are reported at most once a day. ran reports whether the
+// check ran.
+func CheckDaily(now time.Time) (findings []Finding, ran bool) {
+	if data, err := utils.ReadRoleWalkersFile(cacheFile); err == nil {
+		var last cache
+		if json.Unmarshal(data, &last) == nil && now.Sub(last.CheckedAt) < Interval {
+			return nil, false
+		}
+	}
+
+	findings = New().Check()
+	if data, err := json.Marshal(cache{CheckedAt: now}); err == nil {
+		_ = utils.WriteRoleWalkersFile(cacheFile, data)
+	}
+	return findings, true
+}
@@ -0,0 +1,107 @@
+package toolcheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fakeChecker(outputs map[string]string, installed ...string) *Checker {
+	return &Checker{
+		run: func(name string, args ...string) (string, error) {
+			out, ok := outputs[name]
+			if !ok {
+				return "", errors.New("executable file not found in $PATH")
+			}
+			return out, nil
+		},
+		lookPath: func(name string) (string, error) {
+			for _, n := range installed {
+				if n == name {
+					return "/usr/local/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		},
+		goos: "darwin",
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"aws-cli/2.15.0 Python/3.11.6 Darwin/23.1.0 source/arm64": {2, 15, 0},
+		"aws-cli/1.29.3 Python/3.9.6":                             {1, 29, 3},
+		"v1.29.3-eks-1234":                                        {1, 29, 3},
+		"1.2.553.0":                                               {1, 2, 553},
+		"v1.30":                                                   {1, 30, 0},
+	}
+	for s, want := range tests {
+		if got, ok := ParseVersion(s); !ok || got != want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", s, got, ok, want)
+		}
+	}
+	if _, ok := ParseVersion("unknown"); ok {
+		t.Error("ParseVersion(unknown) should fail")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	healthy := fakeChecker(map[string]string{
+		"aws":                    "aws-cli/2.15.0 Python/3.11.6",
+		"kubectl":                `{"clientVersion": {"gitVersion": "v1.29.3"}, "serverVersion": {"gitVersion": "v1.30.2-eks-1"}}`,
+		"session-manager-plugin": "1.2.553.0",
+	}, "session-manager-plugin")
+	if findings := healthy.Check(); len(findings) != 0 {
+		t.Errorf("Check() = %+v, want no findings", findings)
+	}
+
+	outdated := fakeChecker(map[string]string{
+		"aws":                    "aws-cli/1.29.3 Python/3.9.6",
+		"kubectl":                `{"clientVersion": {"gitVersion": "v1.26.0"}, "serverVersion": {"gitVersion": "v1.30.2-eks-1"}}`,
+		"session-manager-plugin": "1.1.61.0",
+	}, "session-manager-plugin")
+	findings := outdated.Check()
+	if len(findings) != 3 {
+		t.Fatalf("Check() = %+v, want aws, kubectl and session-manager-plugin", findings)
+	}
+	if !strings.Contains(findings[0].Problem, "sso-session") || findings[0].Hint != "brew install awscli" {
+		t.Errorf("aws finding = %+v", findings[0])
+	}
+	if !strings.Contains(findings[1].Problem, "1.26") || !strings.Contains(findings[1].Problem, "1.30") {
+		t.Errorf("kubectl finding = %+v", findings[1])
+	}
+
+	// No cluster reachable: only the client version is known; the plugin
+	// is optional
+	offline := fakeChecker(map[string]string{
+		"aws":     "aws-cli/2.15.0",
+		"kubectl": "{\n  \"clientVersion\": {\"gitVersion\": \"v1.26.0\"}\n}\nThe connection to the server was refused",
+	})
+	if findings := offline.Check(); len(findings) != 0 {
+		t.Errorf("Check() offline = %+v, want no findings", findings)
+	}
+
+	missing := fakeChecker(nil)
+	missing.goos = "plan9"
+	findings = missing.Check()
+	if len(findings) != 2 || findings[0].Problem != "not installed or not working" || !strings.HasPrefix(findings[1].Hint, "https://") {
+		t.Errorf("Check() with nothing installed = %+v, want aws and kubectl with fallback hints", findings)
+	}
+}
+
+func TestCheckDaily(t *testing.T) {
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	t.Setenv("PATH", "")
+	now := time.Now()
+
+	if _, ran := CheckDaily(now); !ran {
+		t.Error("first CheckDaily() should run")
+	}
+	if _, ran := CheckDaily(now.Add(time.Hour)); ran {
+		t.Error("CheckDaily() within a day should not run again")
+	}
+	if _, ran := CheckDaily(now.Add(Interval + time.Minute)); !ran {
+		t.Error("CheckDaily() after a day should run again")
+	}
+}
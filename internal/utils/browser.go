@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// OpenBrowser opens url in the default browser
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the launcher process without blocking the caller
+	go cmd.Wait()
+	return nil
+}
+
+// IsHeadless reports whether a browser opened here couldn't be used: an
+// SSH session, or a Linux/BSD machine with no graphical display
+func IsHeadless() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return true
+	}
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return false
+	}
+	return os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestIsHeadless(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 50000 10.0.0.2 22")
+	t.Setenv("SSH_TTY", "")
+	if !IsHeadless() {
+		t.Error("IsHeadless() = false in an SSH session")
+	}
+
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("DISPLAY", ":0")
+	if IsHeadless() {
+		t.Error("IsHeadless() = true with a display")
+	}
+
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	want := runtime.GOOS != "darwin" && runtime.GOOS != "windows"
+	if got := IsHeadless(); got != want {
+		t.Errorf("IsHeadless() without a display = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardTool is an external command used to access the OS clipboard
+type clipboardTool struct {
+	copy  []string
+	paste []string
+}
+
+// clipboardTools returns candidate clipboard commands for the current OS,
+// in order of preference
+func clipboardTools() []clipboardTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardTool{{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}}
+	case "windows":
+		return []clipboardTool{{
+			copy:  []string{"clip"},
+			paste: []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard"},
+		}}
+	default:
+		return []clipboardTool{
+			{copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+			{copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+			{copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}},
+		}
+	}
+}
+
+// findClipboardTool returns the first clipboard tool available on PATH
+func findClipboardTool() (clipboardTool, error) {
+	for _, tool := range clipboardTools() {
+		if _, err := exec.LookPath(tool.copy[0]); err == nil {
+			return tool, nil
+		}
+	}
+	if runtime.GOOS == "linux" {
+		return clipboardTool{}, fmt.Errorf("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+	}
+	return clipboardTool{}, fmt.Errorf("no clipboard tool found")
+}
+
+// CopyToClipboard places text on the OS clipboard
+func CopyToClipboard(text string) error {
+	tool, err := findClipboardTool()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(tool.copy[0], tool.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w: %s", err, Redact(strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+// ReadClipboard returns the current clipboard contents
+func ReadClipboard() (string, error) {
+	tool, err := findClipboardTool()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(tool.paste[0], tool.paste[1:]...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	// Get-Clipboard appends a newline
+	return strings.TrimRight(out.String(), "\r\n"), nil
+}
+
+// ClearClipboard empties the OS clipboard
+func ClearClipboard() error {
+	return CopyToClipboard("")
+}
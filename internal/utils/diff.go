@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffLine is a single line of an edit script: ' ' unchanged, '-' removed, '+' added.
+// aPos and bPos are the number of lines of each side consumed before this line.
+type diffLine struct {
+	kind byte
+	text string
+	aPos int
+	bPos int
+}
+
+// UnifiedDiff returns a unified diff between two texts, labelled with the
+// given file names. It returns an empty string when the texts are identical.
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	a := splitLines(from)
+	b := splitLines(to)
+	script := diffLines(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", fromName)
+	fmt.Fprintf(&sb, "+++ %s\n", toName)
+
+	for start := 0; start < len(script); {
+		// Find the next change
+		for start < len(script) && script[start].kind == ' ' {
+			start++
+		}
+		if start >= len(script) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		end := start
+		for i := start; i < len(script); i++ {
+			if script[i].kind != ' ' {
+				end = i
+			} else if i-end > 2*diffContext {
+				break
+			}
+		}
+
+		lo := max(start-diffContext, 0)
+		hi := min(end+diffContext+1, len(script))
+		writeHunk(&sb, script[lo:hi])
+		start = hi
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes one @@ hunk for a slice of the edit script
+func writeHunk(sb *strings.Builder, hunk []diffLine) {
+	aStart, bStart := hunk[0].aPos+1, hunk[0].bPos+1
+	var aLen, bLen int
+	for _, l := range hunk {
+		if l.kind != '+' {
+			aLen++
+		}
+		if l.kind != '-' {
+			bLen++
+		}
+	}
+	// An empty range refers to the line before it, as in GNU diff
+	if aLen == 0 {
+		aStart--
+	}
+	if bLen == 0 {
+		bStart--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, l := range hunk {
+		sb.WriteByte(l.kind)
+		sb.WriteString(l.text)
+		sb.WriteByte('\n')
+	}
+}
+
+// diffLines computes a line-based edit script using the longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var script []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			script = append(script, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			script = append(script, diffLine{'+', b[j], i, j})
+			j++
+		default:
+			script = append(script, diffLine{'-', a[i], i, j})
+			i++
+		}
+	}
+
+	return script
+}
+
+// splitLines splits text into lines, ignoring a single trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// CheckOwnership returns an error if path exists and is owned by a user other
+// than the effective user. On shared jump hosts this stops rw from reading
+// another engineer's state, or leaving root-owned files in their home when
+// run under sudo with HOME preserved. A missing path is not an error.
+func CheckOwnership(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	if int(st.Uid) == os.Geteuid() {
+		return nil
+	}
+	return fmt.Errorf("refusing to use %s: it is owned by %s, not the current user %s\n"+
+		"  Use --state-dir or %s to point rw at a directory you own",
+		path, userName(int(st.Uid)), userName(os.Geteuid()), StateDirEnv)
+}
+
+func userName(uid int) string {
+	id := strconv.Itoa(uid)
+	if u, err := user.LookupId(id); err == nil {
+		return fmt.Sprintf("%s (uid %s)", u.Username, id)
+	}
+	return "uid " + id
+}
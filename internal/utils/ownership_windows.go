@@ -0,0 +1,9 @@
+//go:build windows
+
+package utils
+
+// CheckOwnership is a no-op on Windows, where profile directories are
+// already protected per user by ACLs.
+func CheckOwnership(path string) error {
+	return nil
+}
@@ -6,39 +6,112 @@ import (
 	"path/filepath"
 )
 
-const rwDirName = ".rolewalkers"
+const (
+	rwDirName = ".rolewalkers"
+
+	// StateDirEnv overrides the state directory. The global --state-dir flag
+	// sets it so child processes (tray, clipboard clearing) inherit it.
+	StateDirEnv = "RW_STATE_DIR"
+)
+
+// StateDir resolves the per-user state directory without creating it:
+//
+//  1. $RW_STATE_DIR (or --state-dir)
+//  2. ~/.rolewalkers, if it already exists
+//  3. $XDG_STATE_HOME/rolewalkers, if XDG_STATE_HOME is set
+//  4. ~/.rolewalkers
+func StateDir() (string, error) {
+	if dir := os.Getenv(StateDirEnv); dir != "" {
+		return filepath.Abs(dir)
+	}
 
-// RoleWalkersDir returns the path to ~/.rolewalkers, creating it if needed.
-func RoleWalkersDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	dir := filepath.Join(homeDir, rwDirName)
+	legacy := filepath.Join(homeDir, rwDirName)
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy, nil
+	}
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "rolewalkers"), nil
+	}
+	return legacy, nil
+}
+
+// RoleWalkersDir returns the state directory, creating it if needed.
+// It refuses to use a directory owned by another user.
+func RoleWalkersDir() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+
 	if err := os.MkdirAll(dir, 0700); err != nil {
-		return "", fmt.Errorf("failed to create %s directory: %w", rwDirName, err)
+		return "", fmt.Errorf("failed to create %s directory: %w", dir, err)
+	}
+	if err := CheckOwnership(dir); err != nil {
+		return "", err
 	}
 
 	return dir, nil
 }
 
-// ReadRoleWalkersFile reads a file from ~/.rolewalkers/<name>.
+// ReadRoleWalkersFile reads a file from the state directory.
 // Returns the content or an error (including os.ErrNotExist).
 func ReadRoleWalkersFile(name string) ([]byte, error) {
-	homeDir, err := os.UserHomeDir()
+	dir, err := StateDir()
 	if err != nil {
 		return nil, err
 	}
-	return os.ReadFile(filepath.Join(homeDir, rwDirName, name))
+	path := filepath.Join(dir, name)
+	if err := CheckOwnership(path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
 }
 
-// WriteRoleWalkersFile writes data to ~/.rolewalkers/<name>, creating the
+// WriteRoleWalkersFile writes data to <state dir>/<name>, creating the
 // directory if needed. Uses 0600 permissions.
 func WriteRoleWalkersFile(name string, data []byte) error {
 	dir, err := RoleWalkersDir()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(dir, name), data, 0600)
+	path := filepath.Join(dir, name)
+	if err := CheckOwnership(path); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data, 0600)
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it over path, so a crash leaves either the old or the new content, never
+// a truncated file. A symlinked path is written through to its target.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
 }
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStateDir(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv(StateDirEnv, "")
+	t.Setenv("XDG_STATE_HOME", xdg)
+
+	// No legacy directory: XDG_STATE_HOME wins
+	if dir, err := StateDir(); err != nil || dir != filepath.Join(xdg, "rolewalkers") {
+		t.Errorf("StateDir() = %q, %v, want XDG state dir", dir, err)
+	}
+
+	// An existing ~/.rolewalkers keeps being used
+	legacy := filepath.Join(home, rwDirName)
+	if err := os.Mkdir(legacy, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if dir, err := StateDir(); err != nil || dir != legacy {
+		t.Errorf("StateDir() = %q, %v, want %q", dir, err, legacy)
+	}
+
+	// RW_STATE_DIR overrides both
+	override := t.TempDir()
+	t.Setenv(StateDirEnv, override)
+	if dir, err := StateDir(); err != nil || dir != override {
+		t.Errorf("StateDir() = %q, %v, want %q", dir, err, override)
+	}
+}
+
+func TestCheckOwnership(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckOwnership(dir); err != nil {
+		t.Errorf("CheckOwnership(own dir) error = %v", err)
+	}
+	if err := CheckOwnership(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("CheckOwnership(missing) error = %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic() error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("content = %q, want new", data)
+	}
+	if info, err := os.Stat(path); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("Stat() = %v, %v, want mode 0600", info, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want no leftover temporary files", len(entries))
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "config"), []byte("x"), 0600); err == nil {
+		t.Error("WriteFileAtomic() into a missing directory should fail")
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// IsPortAvailable reports whether a local TCP port can be bound on localhost
+func IsPortAvailable(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// FindFreePort returns the first available port in [start, start+attempts)
+func FindFreePort(start, attempts int) (int, error) {
+	for port := start; port < start+attempts && port <= 65535; port++ {
+		if IsPortAvailable(port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found in range %d-%d", start, start+attempts-1)
+}
+
+// PortOwner returns a best-effort description of the process listening on a
+// local port (e.g. "kubectl (pid 4242)"), or "" when it cannot be determined
+func PortOwner(port int) string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+
+	// lsof -F output: one field per line, prefixed by p (pid) and c (command)
+	cmd := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+
+	var pid, command string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			if pid == "" {
+				pid = line[1:]
+			}
+		case 'c':
+			if command == "" {
+				command = line[1:]
+			}
+		}
+	}
+
+	if pid == "" {
+		return ""
+	}
+	if command == "" {
+		return "pid " + pid
+	}
+	return fmt.Sprintf("%s (pid %s)", command, pid)
+}
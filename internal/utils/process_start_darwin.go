@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// processStartTime returns when a process started, in microseconds since
+// the epoch
+func processStartTime(pid int) (uint64, bool) {
+	kp, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil || kp.Proc.P_pid != int32(pid) {
+		return 0, false
+	}
+	tv := kp.Proc.P_starttime
+	return uint64(tv.Sec)*1e6 + uint64(tv.Usec), true
+}
+
+// controllingTerminal returns the device number of the process's
+// controlling terminal
+func controllingTerminal() (uint64, bool) {
+	kp, err := unix.SysctlKinfoProc("kern.proc.pid", os.Getpid())
+	if err != nil || kp.Eproc.Tdev == -1 {
+		return 0, false
+	}
+	return uint64(uint32(kp.Eproc.Tdev)), true
+}
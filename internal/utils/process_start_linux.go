@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// processStartTime returns when a process started, in clock ticks since
+// boot, from /proc/<pid>/stat
+func processStartTime(pid int) (uint64, bool) {
+	return procStatField(strconv.Itoa(pid), 19)
+}
+
+// controllingTerminal returns the device number of the process's
+// controlling terminal, tty_nr in /proc/self/stat
+func controllingTerminal() (uint64, bool) {
+	tty, ok := procStatField("self", 4)
+	return tty, ok && tty != 0
+}
+
+// procStatField returns a numeric field of /proc/<pid>/stat, counting from
+// the state after the command name
+func procStatField(pid string, i int) (uint64, bool) {
+	data, err := os.ReadFile("/proc/" + pid + "/stat")
+	if err != nil {
+		return 0, false
+	}
+	// The command name in parentheses can contain spaces
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, false
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) <= i {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(fields[i]), 10, 64)
+	return v, err == nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !linux && !darwin
+
+package utils
+
+// processStartTime isn't available on this platform, so terminal sessions
+// are identified by session ID only
+func processStartTime(pid int) (uint64, bool) {
+	return 0, false
+}
+
+// controllingTerminal isn't available on this platform either
+func controllingTerminal() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces secret values in output
+const RedactedValue = "********"
+
+// minSecretLength avoids masking short values that would mangle unrelated output
+const minSecretLength = 4
+
+// secretPathPattern matches SSM parameter paths (or names) that hold secrets
+var secretPathPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[-_]?key|private[-_]?key|credential|auth)`)
+
+var (
+	secretsMu   sync.RWMutex
+	secrets     []string
+	showSecrets bool
+)
+
+// IsSecretPath reports whether a parameter path looks like it holds a secret,
+// e.g. "/dev/zenith/database/query/db-zenithmaster-password"
+func IsSecretPath(path string) bool {
+	return secretPathPattern.MatchString(path)
+}
+
+// TrackSecret registers a secret value so that Redact masks it wherever it
+// appears later (error messages, console output, the audit log).
+func TrackSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+	// Longest first, so a secret containing another is masked as a whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// Redact masks every tracked secret in s
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, RedactedValue)
+	}
+	return s
+}
+
+// SetShowSecrets enables printing secret values (the --show-secrets flag)
+func SetShowSecrets(show bool) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	showSecrets = show
+}
+
+// ShowSecrets reports whether secret values may be printed
+func ShowSecrets() bool {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	return showSecrets
+}
+
+// RedactOutput masks tracked secrets in console output unless --show-secrets is set
+func RedactOutput(s string) string {
+	if ShowSecrets() {
+		return s
+	}
+	return Redact(s)
+}
+
+// Stdout and Stderr mask tracked secrets in console output unless
+// --show-secrets is set. Print warnings and anything carrying error text or
+// command output through them.
+var (
+	Stdout io.Writer = consoleWriter{&os.Stdout}
+	Stderr io.Writer = consoleWriter{&os.Stderr}
+)
+
+// consoleWriter resolves the file on each write so a redirected os.Stdout
+// (e.g. in tests) is still honoured
+type consoleWriter struct {
+	f **os.File
+}
+
+func (c consoleWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(*c.f, RedactOutput(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RedactWriter masks tracked secrets in a stream such as a child process's
+// stderr. Output is held back until a line is complete so a secret split
+// across writes is still masked; call Flush once the stream ends.
+type RedactWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// NewRedactWriter returns a RedactWriter that writes to w
+func NewRedactWriter(w io.Writer) *RedactWriter {
+	return &RedactWriter{w: w}
+}
+
+func (r *RedactWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf = append(r.buf, p...)
+	i := bytes.LastIndexByte(r.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	if _, err := io.WriteString(r.w, RedactOutput(string(r.buf[:i+1]))); err != nil {
+		return 0, err
+	}
+	r.buf = r.buf[:copy(r.buf, r.buf[i+1:])]
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line
+func (r *RedactWriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(r.w, RedactOutput(string(r.buf)))
+	r.buf = r.buf[:0]
+	return err
+}
+
+// resetSecrets clears tracked secrets (used by tests)
+func resetSecrets() {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets = nil
+	showSecrets = false
+}
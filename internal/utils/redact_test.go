@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsSecretPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/dev/zenith/database/query/db-zenithmaster-password", true},
+		{"/dev/zenith/redis/zenithmaster-password", true},
+		{"/prod/zenith/stripe/API_KEY", true},
+		{"/prod/zenith/jwt-secret", true},
+		{"/dev/zenith/database/query/db-write-endpoint", false},
+		{"/dev/zenith/redis/cluster-endpoint", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := IsSecretPath(tt.path); got != tt.expected {
+				t.Errorf("IsSecretPath(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	resetSecrets()
+	defer resetSecrets()
+
+	TrackSecret("hunter2hunter2")
+	TrackSecret("hunter2")
+	TrackSecret("abc") // too short to track
+
+	got := Redact("failed: PGPASSWORD=hunter2hunter2 other=hunter2 abc")
+	want := "failed: PGPASSWORD=******** other=******** abc"
+	if got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+
+	if got := RedactOutput("pw hunter2"); got != "pw ********" {
+		t.Errorf("RedactOutput() = %q, want masked", got)
+	}
+	SetShowSecrets(true)
+	if got := RedactOutput("pw hunter2"); got != "pw hunter2" {
+		t.Errorf("RedactOutput() with show secrets = %q, want unmasked", got)
+	}
+}
+
+func TestRedactWriter(t *testing.T) {
+	resetSecrets()
+	defer resetSecrets()
+
+	TrackSecret("hunter2")
+
+	var out strings.Builder
+	w := NewRedactWriter(&out)
+	// The secret straddles two writes and must still be masked
+	for _, chunk := range []string{"FATAL: password hun", "ter2 rejected\nretry", "ing hunter2"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := out.String(), "FATAL: password ******** rejected\n"; got != want {
+		t.Errorf("before Flush = %q, want %q", got, want)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "FATAL: password ******** rejected\nretrying ********"; got != want {
+		t.Errorf("after Flush = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// SelectFromList prompts the user to select an item from a list using arrow keys.
+// Supports type-to-search filtering. Returns the selected item and true,
+// or empty string and false if cancelled. With plain output it prints a
+// numbered list instead, see selectPlain.
+func SelectFromList(prompt string, items []string) (string, bool) {
+	if len(items) == 0 {
+		return "", false
+	}
+	if PlainEnabled() {
+		return selectPlain(os.Stdin, os.Stdout, prompt, items)
+	}
+
+	searcher := func(input string, index int) bool {
+		item := strings.ToLower(items[index])
+		input = strings.ToLower(strings.TrimSpace(input))
+		return strings.Contains(item, input)
+	}
+
+	p := promptui.Select{
+		Label:    prompt,
+		Items:    items,
+		Size:     15,
+		Searcher: searcher,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}",
+			Active:   "▸ {{ . | cyan }}",
+			Inactive: "  {{ . }}",
+			Selected: OK() + " {{ . | green }}",
+		},
+		HideHelp: true,
+	}
+
+	idx, _, err := p.Run()
+	if err != nil {
+		return "", false
+	}
+
+	return items[idx], true
+}
+
+// ReadPassphrase prompts for a secret without echoing it
+func ReadPassphrase(label string) (string, error) {
+	p := promptui.Prompt{
+		Label: label,
+		Mask:  '*',
+	}
+	return p.Run()
+}
+
+// selectPlain prints the items as a numbered list and reads a number, or
+// text matching a single item, from in. Nothing is redrawn, so it works
+// with screen readers. An empty answer or end of input cancels.
+func selectPlain(in io.Reader, out io.Writer, prompt string, items []string) (string, bool) {
+	fmt.Fprintln(out, prompt)
+	for i, item := range items {
+		fmt.Fprintf(out, "  %d. %s\n", i+1, item)
+	}
+	for {
+		fmt.Fprintf(out, "Enter a number (1-%d), or press Enter to cancel: ", len(items))
+		answer, err := readLine(in)
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return "", false
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(items) {
+			return items[n-1], true
+		}
+		var matches []string
+		for _, item := range items {
+			if strings.Contains(strings.ToLower(item), strings.ToLower(answer)) {
+				matches = append(matches, item)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0], true
+		}
+		if err != nil {
+			return "", false
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(out, "No item matches %q.\n", answer)
+		} else {
+			fmt.Fprintf(out, "%q matches %d items; enter a number.\n", answer, len(matches))
+		}
+	}
+}
+
+// readLine reads up to a newline one byte at a time, so input after the
+// line is left for the next reader of stdin
+func readLine(in io.Reader) (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return sb.String(), nil
+			}
+			sb.WriteByte(buf[0])
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectPlain(t *testing.T) {
+	items := []string{"candidate", "candidate-worker", "jobs"}
+
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"number", "2\n", "candidate-worker", true},
+		{"unique text", "jo\n", "jobs", true},
+		{"ambiguous then number", "cand\n1\n", "candidate", true},
+		{"out of range then text", "9\nworker\n", "candidate-worker", true},
+		{"empty cancels", "\n", "", false},
+		{"end of input cancels", "", "", false},
+		{"no trailing newline", "3", "jobs", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, ok := selectPlain(strings.NewReader(tt.input), &out, "Select a service:", items)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("selectPlain() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if !strings.Contains(out.String(), "  2. candidate-worker\n") {
+				t.Errorf("output %q doesn't list the numbered items", out.String())
+			}
+			if strings.Contains(out.String(), "\r") || strings.Contains(out.String(), "\033[") {
+				t.Errorf("output %q rewrites the terminal", out.String())
+			}
+		})
+	}
+}
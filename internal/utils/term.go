@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ANSI styles used for terminal output
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiRedBg  = "\033[41m"
+	ansiWhite  = "\033[97m"
+)
+
+// NoColorEnv disables colors when set to any value (https://no-color.org)
+const NoColorEnv = "NO_COLOR"
+
+// ASCIIEnv forces ASCII symbols when set to a true value
+const ASCIIEnv = "RW_ASCII"
+
+// PlainEnv turns on plain output when set to a true value
+const PlainEnv = "RW_PLAIN"
+
+var (
+	termMu      sync.RWMutex
+	noColorFlag bool
+	plainFlag   bool
+
+	stdoutIsTTY = sync.OnceValue(func() bool {
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	})
+	stdinIsTTY = sync.OnceValue(func() bool {
+		info, err := os.Stdin.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	})
+)
+
+// SetNoColor disables colors for this invocation (the --no-color flag)
+func SetNoColor(disable bool) {
+	termMu.Lock()
+	defer termMu.Unlock()
+	noColorFlag = disable
+}
+
+// SetPlain turns on plain output for this invocation (the --plain flag)
+func SetPlain(plain bool) {
+	termMu.Lock()
+	defer termMu.Unlock()
+	plainFlag = plain
+}
+
+// PlainEnabled reports whether output should be plain, for screen readers
+// and log files: no color, emoji or box drawing, and nothing that rewrites
+// the terminal in place, such as arrow-key pickers. Set by --plain or
+// $RW_PLAIN.
+func PlainEnabled() bool {
+	termMu.RLock()
+	plain := plainFlag
+	termMu.RUnlock()
+	return plain || EnvBool(PlainEnv)
+}
+
+// ColorEnabled reports whether output may use ANSI colors: stdout is a
+// terminal, and neither --no-color, --plain, $NO_COLOR nor TERM=dumb is set
+func ColorEnabled() bool {
+	termMu.RLock()
+	disabled := noColorFlag
+	termMu.RUnlock()
+
+	if disabled || PlainEnabled() || os.Getenv(NoColorEnv) != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return stdoutIsTTY()
+}
+
+// StdinIsTerminal reports whether stdin is a terminal, so the user can
+// answer a prompt rw didn't expect to show
+func StdinIsTerminal() bool { return stdinIsTTY() }
+
+// StdoutIsTerminal reports whether stdout is a terminal rather than a pipe
+// or file, so output read by scripts can be kept to a stable minimum
+func StdoutIsTerminal() bool { return stdoutIsTTY() }
+
+// UnicodeEnabled reports whether the terminal can be expected to render
+// symbols such as ✓ and →. Classic Windows consoles and non-UTF-8 locales
+// get ASCII fallbacks; $RW_ASCII and plain output force them.
+func UnicodeEnabled() bool {
+	if EnvBool(ASCIIEnv) || PlainEnabled() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// Windows Terminal and VS Code render Unicode; conhost often doesn't
+		return os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") != ""
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
+
+// symbol returns the Unicode or ASCII form of a symbol, colored if enabled
+func symbol(color, unicode, ascii string) string {
+	s := ascii
+	if UnicodeEnabled() {
+		s = unicode
+	}
+	return Colorize(color, s)
+}
+
+// OK is the success symbol (green ✓, or [ok])
+func OK() string { return symbol(ansiGreen, "✓", "[ok]") }
+
+// Warn is the warning symbol (yellow ⚠, or [!])
+func Warn() string { return symbol(ansiYellow, "⚠", "[!]") }
+
+// Fail is the failure symbol (red ✗, or [x])
+func Fail() string { return symbol(ansiRed, "✗", "[x]") }
+
+// Arrow is the mapping symbol (→, or ->)
+func Arrow() string { return symbol("", "→", "->") }
+
+// Emoji returns unicode, or ascii when Unicode symbols are off (including
+// with plain output); ascii may be "" to drop a decorative emoji
+func Emoji(unicode, ascii string) string {
+	if UnicodeEnabled() {
+		return unicode
+	}
+	return ascii
+}
+
+// Colorize wraps s in an ANSI style when colors are enabled
+func Colorize(style, s string) string {
+	if style == "" || !ColorEnabled() {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+// Bold renders s in bold when colors are enabled
+func Bold(s string) string { return Colorize(ansiBold, s) }
+
+// Red renders s in red when colors are enabled
+func Red(s string) string { return Colorize(ansiRed, s) }
+
+// Green renders s in green when colors are enabled
+func Green(s string) string { return Colorize(ansiGreen, s) }
+
+// Yellow renders s in yellow when colors are enabled
+func Yellow(s string) string { return Colorize(ansiYellow, s) }
+
+// BoldRed renders s in bold red when colors are enabled
+func BoldRed(s string) string { return Colorize(ansiBold+ansiRed, s) }
+
+// Danger renders s as a warning banner, bold white on red, when colors are
+// enabled
+func Danger(s string) string { return Colorize(ansiRedBg+ansiWhite+ansiBold, s) }
+
+// EnvBool reports whether an environment variable is set to a true value
+// ("1", "true", "yes", "on", ...).
+func EnvBool(name string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	if v == "yes" || v == "y" || v == "on" {
+		return true
+	}
+	b, err := strconv.ParseBool(v)
+	return err == nil && b
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestColorEnabledNoColor(t *testing.T) {
+	t.Setenv(NoColorEnv, "1")
+	if ColorEnabled() {
+		t.Error("ColorEnabled() = true with NO_COLOR set")
+	}
+	if got := Colorize(ansiRed, "x"); got != "x" {
+		t.Errorf("Colorize() = %q, want plain text", got)
+	}
+}
+
+func TestSetNoColor(t *testing.T) {
+	SetNoColor(true)
+	defer SetNoColor(false)
+	if ColorEnabled() {
+		t.Error("ColorEnabled() = true after SetNoColor(true)")
+	}
+}
+
+func TestSymbolsASCII(t *testing.T) {
+	t.Setenv(NoColorEnv, "1")
+	t.Setenv(ASCIIEnv, "1")
+
+	tests := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"OK", OK(), "[ok]"},
+		{"Warn", Warn(), "[!]"},
+		{"Fail", Fail(), "[x]"},
+		{"Arrow", Arrow(), "->"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s() = %q, want %q", tt.name, tt.got, tt.expected)
+		}
+	}
+}
+
+func TestUnicodeEnabledLocale(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("locale variables are not used on Windows")
+	}
+	t.Setenv(ASCIIEnv, "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_CTYPE", "")
+
+	t.Setenv("LANG", "C")
+	if UnicodeEnabled() {
+		t.Error("UnicodeEnabled() = true with LANG=C")
+	}
+
+	t.Setenv("LANG", "en_GB.UTF-8")
+	if !UnicodeEnabled() {
+		t.Error("UnicodeEnabled() = false with LANG=en_GB.UTF-8")
+	}
+
+	t.Setenv("LC_ALL", "POSIX")
+	if UnicodeEnabled() {
+		t.Error("UnicodeEnabled() = true with LC_ALL=POSIX overriding LANG")
+	}
+}
+
+func TestPlain(t *testing.T) {
+	t.Setenv(PlainEnv, "")
+	t.Setenv(ASCIIEnv, "")
+	SetPlain(true)
+	defer SetPlain(false)
+
+	if !PlainEnabled() {
+		t.Fatal("PlainEnabled() = false after SetPlain(true)")
+	}
+	if ColorEnabled() {
+		t.Error("ColorEnabled() = true with plain output")
+	}
+	if got := OK(); got != "[ok]" {
+		t.Errorf("OK() = %q with plain output, want [ok]", got)
+	}
+	if got := Emoji("🔒", "(secure)"); got != "(secure)" {
+		t.Errorf("Emoji() = %q with plain output, want the ASCII form", got)
+	}
+
+	SetPlain(false)
+	t.Setenv(PlainEnv, "1")
+	if !PlainEnabled() {
+		t.Error("PlainEnabled() = false with RW_PLAIN=1")
+	}
+}
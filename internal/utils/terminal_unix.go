@@ -0,0 +1,60 @@
+//go:build !windows
+
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// TerminalSession identifies the terminal rw runs in, so state can be kept
+// per terminal tab. The ID is the session ID, which the shell and every
+// command started from it share, plus the session leader's start time and
+// the controlling terminal's device: a session ID reused by a later
+// terminal then gets a different ID. ok is false outside a session.
+func TerminalSession() (id string, ok bool) {
+	sid, err := unix.Getsid(0)
+	if err != nil || sid <= 1 {
+		return "", false
+	}
+	id = "sid-" + strconv.Itoa(sid)
+	if start, ok := processStartTime(sid); ok {
+		id += "-start-" + strconv.FormatUint(start, 10)
+	}
+	if tty, ok := controllingTerminal(); ok {
+		id += "-tty-" + strconv.FormatUint(tty, 10)
+	}
+	return id, true
+}
+
+// TerminalSessionAlive reports whether the session's leader (usually the
+// terminal's shell) is still running, and is the same process that started
+// the session rather than a later one given the same ID
+func TerminalSessionAlive(id string) bool {
+	fields := strings.Split(strings.TrimPrefix(id, "sid-"), "-")
+	sid, err := strconv.Atoi(fields[0])
+	if err != nil || sid <= 1 || !ProcessAlive(sid) {
+		return false
+	}
+	for i := 1; i+1 < len(fields); i += 2 {
+		if fields[i] != "start" {
+			continue
+		}
+		start, ok := processStartTime(sid)
+		return ok && strconv.FormatUint(start, 10) == fields[i+1]
+	}
+	return true
+}
+
+// ProcessAlive reports whether a process with the given ID is running
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
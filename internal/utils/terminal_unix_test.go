@@ -0,0 +1,41 @@
+//go:build !windows
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerminalSessionAlive(t *testing.T) {
+	id, ok := TerminalSession()
+	if !ok {
+		t.Skip("not running in a terminal session")
+	}
+	if !TerminalSessionAlive(id) {
+		t.Errorf("TerminalSessionAlive(%q) = false for the current session", id)
+	}
+
+	sid, _, _ := strings.Cut(strings.TrimPrefix(id, "sid-"), "-")
+	if !strings.Contains(id, "-start-") {
+		t.Skip("process start times aren't available on this platform")
+	}
+	// The same session ID with another leader, as after PID reuse
+	if reused := "sid-" + sid + "-start-1"; TerminalSessionAlive(reused) {
+		t.Errorf("TerminalSessionAlive(%q) = true for a leader with another start time", reused)
+	}
+	if legacy := "sid-" + sid; !TerminalSessionAlive(legacy) {
+		t.Errorf("TerminalSessionAlive(%q) = false for an ID without a start time", legacy)
+	}
+}
+
+func TestControllingTerminal(t *testing.T) {
+	tty, ok := controllingTerminal()
+	if !ok {
+		t.Skip("no controlling terminal")
+	}
+	// /dev/tty itself is always device 5,0; the terminal behind it isn't
+	if tty == 5<<8 {
+		t.Errorf("controllingTerminal() = %d, the device of /dev/tty rather than the terminal", tty)
+	}
+}
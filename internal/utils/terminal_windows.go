@@ -0,0 +1,34 @@
+//go:build windows
+
+package utils
+
+import "os"
+
+// TerminalSession identifies the Windows Terminal tab rw runs in, so state
+// can be kept per tab. ok is false in other consoles.
+func TerminalSession() (id string, ok bool) {
+	if wt := os.Getenv("WT_SESSION"); wt != "" {
+		return "wt-" + wt, true
+	}
+	return "", false
+}
+
+// TerminalSessionAlive can't tell whether a Windows Terminal tab is still
+// open, so it assumes it is; state kept per tab has to expire instead
+func TerminalSessionAlive(id string) bool {
+	return true
+}
+
+// ProcessAlive reports whether a process with the given ID exists. Windows
+// can only open a handle to a process that hasn't exited.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
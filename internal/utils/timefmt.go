@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatTime renders t in the local time zone as RFC 3339, so timestamps
+// always carry a date and zone (e.g. 2026-03-01T14:05:00+01:00)
+func FormatTime(t time.Time) string {
+	return t.Local().Format(time.RFC3339)
+}
+
+// FormatDuration renders a duration compactly, rounded down to minutes:
+// "<1m", "42m", "3h 5m", "2d 4h"
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	minutes := int(d.Minutes())
+	hours, days := minutes/60, minutes/(60*24)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours%24)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes%60)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "<1m"
+	}
+}
+
+// FormatRelative renders t relative to now: "in 42m" or "3h 5m ago"
+func FormatRelative(t, now time.Time) string {
+	if t.After(now) {
+		return "in " + FormatDuration(t.Sub(now))
+	}
+	return FormatDuration(now.Sub(t)) + " ago"
+}
+
+// FormatTimeRelative renders both forms, e.g. "2026-03-01T14:05:00Z (in 42m)"
+func FormatTimeRelative(t, now time.Time) string {
+	return fmt.Sprintf("%s (%s)", FormatTime(t), FormatRelative(t, now))
+}
+
+// ParseDuration is time.ParseDuration plus a day unit for whole days, so
+// look-back windows can be written as "30d"
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		input    time.Duration
+		expected string
+	}{
+		{30 * time.Second, "<1m"},
+		{42 * time.Minute, "42m"},
+		{3*time.Hour + 5*time.Minute + 59*time.Second, "3h 5m"},
+		{52 * time.Hour, "2d 4h"},
+		{-90 * time.Minute, "1h 30m"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDuration(tt.input); got != tt.expected {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatRelative(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := FormatRelative(now.Add(42*time.Minute), now); got != "in 42m" {
+		t.Errorf("FormatRelative(future) = %q, want %q", got, "in 42m")
+	}
+	if got := FormatRelative(now.Add(-3*time.Hour), now); got != "3h 0m ago" {
+		t.Errorf("FormatRelative(past) = %q, want %q", got, "3h 0m ago")
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("X", 3600))
+	got := FormatTime(ts)
+	if parsed, err := time.Parse(time.RFC3339, got); err != nil || !parsed.Equal(ts) {
+		t.Errorf("FormatTime() = %q, not an RFC 3339 form of %v (err %v)", got, ts, err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"30d", 30 * 24 * time.Hour},
+		{"0d", 0},
+		{"36h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got, err := ParseDuration(tt.input); err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "d", "-1d", "1.5d", "week"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", input)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Package rolewalker is the embeddable API of rolewalkers, for tools such
+// as deployment bots and chatops that need profile switching, SSO status,
+// tunnels and config sync without shelling out to rw.
+//
+// The types here are stable: they are copied out of the internal managers
+// rather than aliased, so the managers can change without breaking callers.
+// Profile is the exception: it is the public provider.Profile, which has
+// the same guarantee.
+//
+// Every method takes a context. The managers underneath shell out to the
+// AWS CLI and kubectl without one. Methods that only read (Profiles,
+// ActiveProfile, LoginStatuses, Tunnels) return ctx.Err() as soon as ctx is
+// cancelled while the command they started runs to completion in the
+// background. Methods that change state (Switch, StopTunnel, SyncConfig)
+// only check ctx before they start; once started they finish and report
+// their own result, so a cancellation never hides a change that was made.
+// A Client runs one method at a time, so the next call waits for earlier
+// work to finish rather than racing it.
+package rolewalker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/db"
+	"rolewalkers/provider"
+)
+
+// Profile is an AWS profile from ~/.aws/config
+type Profile = provider.Profile
+
+// LoginStatus is a profile's SSO login state
+type LoginStatus struct {
+	LoggedIn bool `json:"logged_in"`
+	// ExpiresAt is when the SSO token expires; zero when not logged in
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Tunnel is an active tunnel started by rw or this package
+type Tunnel struct {
+	ID          string    `json:"id"`
+	Service     string    `json:"service"`
+	Environment string    `json:"environment"`
+	LocalPort   int       `json:"local_port"`
+	RemoteHost  string    `json:"remote_host"`
+	RemotePort  int       `json:"remote_port"`
+	StartedAt   time.Time `json:"started_at"`
+	// PodStatus is the phase of the tunnel's access pod ("Running", or
+	// "unknown" when the pod is gone)
+	PodStatus string `json:"pod_status"`
+}
+
+// TunnelOptions selects a tunnel to start
+type TunnelOptions struct {
+	Service     string
+	Environment string
+	// WriteNode and CommandDB select the database node and database for
+	// the db service (default: read node, query database)
+	WriteNode bool
+	CommandDB bool
+	// LocalPort overrides the configured local port when set
+	LocalPort int
+	// Reason is recorded in the audit log when a write tunnel exceeds a
+	// quota; a quota that requires one refuses the tunnel without it
+	Reason string
+}
+
+// SyncResult summarises a sync of ~/.aws/config into rw's database
+type SyncResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Removed  int      `json:"removed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Client gives access to rolewalkers from Go. It uses the same state as
+// the rw CLI (~/.aws/config, the rw database and tunnel state), so both can
+// be used side by side. A Client is safe for concurrent use; its methods
+// run one at a time.
+type Client struct {
+	configManager   *aws.ConfigManager
+	ssoManager      *aws.SSOManager
+	profileSwitcher *aws.ProfileSwitcher
+	kubeManager     *aws.KubeManager
+	tunnelManager   *aws.TunnelManager
+	configSync      *aws.ConfigSync
+	database        *db.DB
+
+	// busy holds a token while a method's work runs, including work a
+	// cancelled call left running
+	busy chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New creates a client. Close it when done to release the database.
+func New() (*Client, error) {
+	cm, err := aws.NewConfigManager()
+	if err != nil {
+		return nil, err
+	}
+	sm, err := aws.NewSSOManager(cm)
+	if err != nil {
+		return nil, err
+	}
+	ps := aws.NewProfileSwitcher(cm)
+
+	database, err := db.NewDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the rolewalkers database: %w", err)
+	}
+	repo := db.NewConfigRepository(database)
+
+	km := aws.NewKubeManagerWithRepo(repo)
+	tm, err := aws.NewTunnelManagerWithDeps(km, aws.NewSSMManagerWithRepo(repo), ps, repo)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	tm.SetOutput(io.Discard)
+	cs, err := aws.NewConfigSync(repo)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	return &Client{
+		configManager:   cm,
+		ssoManager:      sm,
+		profileSwitcher: ps,
+		kubeManager:     km,
+		tunnelManager:   tm,
+		configSync:      cs,
+		database:        database,
+		busy:            make(chan struct{}, 1),
+	}, nil
+}
+
+// Close releases the client's database connection, once work left running
+// by cancelled calls has finished. Calling it again returns the first
+// call's result.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.busy <- struct{}{}
+		c.closeErr = c.database.Close()
+	})
+	return c.closeErr
+}
+
+// Profiles returns the AWS profiles in ~/.aws/config
+func (c *Client) Profiles(ctx context.Context) ([]Profile, error) {
+	return call(ctx, c, func() ([]Profile, error) {
+		return aws.NewCloudProvider(c.configManager, c.profileSwitcher, c.ssoManager).Profiles()
+	})
+}
+
+// ActiveProfile returns the profile rw last switched to
+func (c *Client) ActiveProfile(ctx context.Context) (string, error) {
+	return call(ctx, c, func() (string, error) {
+		return c.configManager.GetActiveProfile(), nil
+	})
+}
+
+// Switch makes profile the default AWS profile and switches kubectl to its
+// cluster, as 'rw switch' does. If the kubectl context can't be switched,
+// the previous profile and context are restored.
+func (c *Client) Switch(ctx context.Context, profile string) error {
+	return run(ctx, c, func() error {
+		_, err := aws.SwitchProfileAndContext(c.profileSwitcher, c.kubeManager, profile, aws.SwitchOptions{})
+		return err
+	})
+}
+
+// LoginStatuses returns the SSO login state of the given profiles, or of
+// every SSO profile when none are given. It reads the SSO token cache once
+// and makes no AWS calls.
+func (c *Client) LoginStatuses(ctx context.Context, profiles ...string) (map[string]LoginStatus, error) {
+	return call(ctx, c, func() (map[string]LoginStatus, error) {
+		statuses, err := c.ssoManager.LoginStatuses(profiles...)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]LoginStatus, len(statuses))
+		for name, s := range statuses {
+			result[name] = LoginStatus{LoggedIn: s.LoggedIn, ExpiresAt: s.ExpiresAt}
+		}
+		return result, nil
+	})
+}
+
+// Tunnels returns the active tunnels with their pod status
+func (c *Client) Tunnels(ctx context.Context) ([]Tunnel, error) {
+	return call(ctx, c, func() ([]Tunnel, error) {
+		statuses := c.tunnelManager.ListWithStatus()
+		tunnels := make([]Tunnel, len(statuses))
+		for i, t := range statuses {
+			tunnels[i] = Tunnel{
+				ID:          t.ID,
+				Service:     t.Service,
+				Environment: t.Environment,
+				LocalPort:   t.LocalPort,
+				RemoteHost:  t.RemoteHost,
+				RemotePort:  t.RemotePort,
+				StartedAt:   t.StartedAt,
+				PodStatus:   t.PodStatus,
+			}
+		}
+		return tunnels, nil
+	})
+}
+
+// StartTunnel starts a tunnel, as 'rw tunnel start' does, and forwards its
+// local port until ctx is done, then deletes the tunnel's pod. The client
+// is busy until then, so use a separate Client for the other methods while
+// a tunnel runs. Nothing is printed.
+func (c *Client) StartTunnel(ctx context.Context, opts TunnelOptions) error {
+	config := aws.TunnelConfig{
+		Service:     opts.Service,
+		Environment: opts.Environment,
+		NodeType:    "read",
+		DBType:      "query",
+		LocalPort:   opts.LocalPort,
+		Reason:      opts.Reason,
+	}
+	if opts.WriteNode {
+		config.NodeType = "write"
+	}
+	if opts.CommandDB {
+		config.DBType = "command"
+	}
+
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.tunnelManager.StartContext(ctx, config)
+}
+
+// StopTunnel stops the tunnel for a service and environment
+func (c *Client) StopTunnel(ctx context.Context, service, env string) error {
+	return run(ctx, c, func() error {
+		return c.tunnelManager.Stop(service, env)
+	})
+}
+
+// SyncConfig imports the profiles in ~/.aws/config into rw's database, as
+// 'rw config sync' does
+func (c *Client) SyncConfig(ctx context.Context) (*SyncResult, error) {
+	var result *SyncResult
+	err := run(ctx, c, func() error {
+		r, err := c.configSync.SyncConfigToDB()
+		if err != nil {
+			return err
+		}
+		result = &SyncResult{Imported: r.Imported, Updated: r.Updated, Skipped: r.Skipped, Removed: r.Removed, Errors: r.Errors}
+		return nil
+	})
+	return result, err
+}
+
+// acquire waits until no other method's work is running, or ctx is done
+func (c *Client) acquire(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case c.busy <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	<-c.busy
+}
+
+// call runs fn once the client is free, unless ctx is done first, and stops
+// waiting for it when ctx is cancelled. fn keeps the client busy until it
+// returns, so work abandoned by a cancelled call never overlaps the next.
+func call[T any](ctx context.Context, c *Client, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := c.acquire(ctx); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer c.release()
+		v, err := fn()
+		done <- result{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		// Work that finished as ctx was cancelled still counts
+		select {
+		case r := <-done:
+			return r.value, r.err
+		default:
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// run runs fn, which changes state, once the client is free, unless ctx is
+// done first. Once fn starts it runs to completion and its result is
+// returned whatever happens to ctx.
+func run(ctx context.Context, c *Client, fn func() error) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return fn()
+}
@@ -0,0 +1,148 @@
+package rolewalker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("RW_STATE_DIR", t.TempDir())
+	t.Setenv("AWS_PROFILE", "")
+
+	config := `[profile zenith-dev]
+sso_start_url = https://example.awsapps.com/start
+sso_region = eu-west-2
+sso_account_id = 111111111111
+sso_role_name = Developer
+region = eu-west-2
+
+[profile ci]
+role_arn = arn:aws:iam::222222222222:role/ci
+source_profile = zenith-dev
+`
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New()
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientReadsLocalState(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	profiles, err := c.Profiles(ctx)
+	if err != nil {
+		t.Fatalf("Profiles() error: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("Profiles() = %+v, want zenith-dev and ci", profiles)
+	}
+
+	statuses, err := c.LoginStatuses(ctx, "zenith-dev")
+	if err != nil {
+		t.Fatalf("LoginStatuses() error: %v", err)
+	}
+	if s, ok := statuses["zenith-dev"]; !ok || s.LoggedIn {
+		t.Errorf("LoginStatuses() = %+v, want zenith-dev logged out", statuses)
+	}
+
+	tunnels, err := c.Tunnels(ctx)
+	if err != nil || len(tunnels) != 0 {
+		t.Errorf("Tunnels() = %+v, %v; want none", tunnels, err)
+	}
+}
+
+func TestClientCancelledContext(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.Profiles(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Profiles() error = %v, want context.Canceled", err)
+	}
+	if err := c.Switch(ctx, "zenith-dev"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Switch() error = %v, want context.Canceled", err)
+	}
+	if active, _ := c.ActiveProfile(context.Background()); active == "zenith-dev" {
+		t.Error("Switch() with a cancelled context should not switch")
+	}
+}
+
+func TestCallWaitsForCancelledWork(t *testing.T) {
+	c := &Client{busy: make(chan struct{}, 1)}
+
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := call(ctx, c, func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("call() error = %v, want context.Canceled", err)
+	}
+
+	// The abandoned work still holds the client
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	ran := false
+	if _, err := call(short, c, func() (int, error) { ran = true; return 2, nil }); !errors.Is(err, context.DeadlineExceeded) || ran {
+		t.Fatalf("call() while busy = %v (ran %v), want context.DeadlineExceeded without running", err, ran)
+	}
+
+	close(release)
+	if v, err := call(context.Background(), c, func() (int, error) { return 3, nil }); err != nil || v != 3 {
+		t.Fatalf("call() after the work finished = %d, %v, want 3", v, err)
+	}
+}
+
+func TestRunFinishesCancelledWork(t *testing.T) {
+	c := &Client{busy: make(chan struct{}, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := false
+	err := run(ctx, c, func() error {
+		cancel()
+		done = true
+		return nil
+	})
+	if err != nil || !done {
+		t.Fatalf("run() = %v (done %v), want the finished work's nil error", err, done)
+	}
+
+	// A call that hasn't started is still refused
+	if err := run(ctx, c, func() error { t.Error("run() ran with a cancelled context"); return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestClientCloseTwice(t *testing.T) {
+	c := newTestClient(t)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close() error: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Package provider defines the cloud provider abstraction: AWS is the
+// primary implementation (package aws), with others such as GCP (package
+// gcp) registered alongside it so mixed-cloud profiles can be listed,
+// logged into and switched through rw.
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Provider is a cloud whose profiles rw can list, log into and switch to
+type Provider interface {
+	// Name is the short identifier used to qualify profiles ("aws", "gcp")
+	Name() string
+	// DisplayName is the human-readable provider name
+	DisplayName() string
+	// Available reports whether the provider is configured on this machine
+	Available() bool
+	Profiles() ([]Profile, error)
+	ActiveProfile() string
+	Login(profile string) error
+	// Switch makes profile the default for new shells and tools
+	Switch(profile string) error
+	// ExportEnvironment returns the environment variables that select
+	// profile for the current shell only
+	ExportEnvironment(profile string) (map[string]string, error)
+}
+
+// Profile is a provider-neutral view of a profile (an AWS profile, a gcloud
+// configuration, ...)
+type Profile struct {
+	Provider string `json:"provider"`
+	Name     string `json:"name"`
+	Account  string `json:"account,omitempty"`  // AWS account ID, GCP project
+	Identity string `json:"identity,omitempty"` // AWS role, GCP account email
+	Region   string `json:"region,omitempty"`
+	Active   bool   `json:"active"`
+}
+
+// QualifiedName returns the profile name prefixed with its provider, e.g.
+// "gcp:staging", as accepted by Registry.Resolve
+func (p Profile) QualifiedName() string {
+	return p.Provider + Separator + p.Name
+}
+
+// Separator joins a provider name and a profile name
+const Separator = ":"
+
+// Registry holds the registered providers. The first one registered is the
+// default for unqualified profile names.
+type Registry struct {
+	providers []Provider
+}
+
+// NewRegistry creates a registry with the given providers
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds a provider; a provider with the same name is replaced
+func (r *Registry) Register(p Provider) {
+	for i, existing := range r.providers {
+		if existing.Name() == p.Name() {
+			r.providers[i] = p
+			return
+		}
+	}
+	r.providers = append(r.providers, p)
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (Provider, bool) {
+	for _, p := range r.providers {
+		if p.Name() == strings.ToLower(name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// All returns the registered providers in registration order
+func (r *Registry) All() []Provider {
+	return append([]Provider(nil), r.providers...)
+}
+
+// Names returns the registered provider names, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.providers))
+	for i, p := range r.providers {
+		names[i] = p.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default returns the provider used for unqualified profile names
+func (r *Registry) Default() Provider {
+	if len(r.providers) == 0 {
+		return nil
+	}
+	return r.providers[0]
+}
+
+// Split separates a qualified name such as "gcp:staging" into its provider
+// and profile. Names without a registered provider prefix belong to the
+// default provider and are returned unchanged.
+func (r *Registry) Split(name string) (Provider, string) {
+	if prefix, profile, ok := strings.Cut(name, Separator); ok {
+		if p, found := r.Get(prefix); found {
+			return p, profile
+		}
+	}
+	return r.Default(), name
+}
+
+// Resolve returns the provider and profile for a qualified name, checking
+// that the provider is available
+func (r *Registry) Resolve(name string) (Provider, string, error) {
+	p, profile := r.Split(name)
+	if p == nil {
+		return nil, "", fmt.Errorf("no cloud providers registered")
+	}
+	if profile == "" {
+		return nil, "", fmt.Errorf("profile name is required (e.g. %s%sstaging)", p.Name(), Separator)
+	}
+	if !p.Available() {
+		return nil, "", fmt.Errorf("%s is not configured on this machine", p.DisplayName())
+	}
+	return p, profile, nil
+}
+
+// FindProfile returns the named profile from a provider
+func FindProfile(p Provider, name string) (*Profile, error) {
+	profiles, err := p.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return &profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s profile not found: %s", p.DisplayName(), name)
+}
@@ -0,0 +1,77 @@
+package provider
+
+import "testing"
+
+type fakeProvider struct {
+	name      string
+	available bool
+	profiles  []Profile
+}
+
+func (f *fakeProvider) Name() string                 { return f.name }
+func (f *fakeProvider) DisplayName() string          { return f.name }
+func (f *fakeProvider) Available() bool              { return f.available }
+func (f *fakeProvider) Profiles() ([]Profile, error) { return f.profiles, nil }
+func (f *fakeProvider) ActiveProfile() string        { return "" }
+func (f *fakeProvider) Login(string) error           { return nil }
+func (f *fakeProvider) Switch(string) error          { return nil }
+func (f *fakeProvider) ExportEnvironment(string) (map[string]string, error) {
+	return nil, nil
+}
+
+func TestRegistrySplit(t *testing.T) {
+	r := NewRegistry(&fakeProvider{name: "aws", available: true}, &fakeProvider{name: "gcp", available: true})
+
+	tests := []struct {
+		input    string
+		provider string
+		profile  string
+	}{
+		{"zenith-dev", "aws", "zenith-dev"},
+		{"gcp:staging", "gcp", "staging"},
+		{"GCP:staging", "gcp", "staging"},
+		{"aws:zenith-dev", "aws", "zenith-dev"},
+		{"azure:sub", "aws", "azure:sub"},
+	}
+	for _, tt := range tests {
+		p, profile := r.Split(tt.input)
+		if p.Name() != tt.provider || profile != tt.profile {
+			t.Errorf("Split(%q) = (%s, %q), want (%s, %q)", tt.input, p.Name(), profile, tt.provider, tt.profile)
+		}
+	}
+}
+
+func TestRegistryResolveUnavailable(t *testing.T) {
+	r := NewRegistry(&fakeProvider{name: "aws", available: true}, &fakeProvider{name: "gcp"})
+	if _, _, err := r.Resolve("gcp:staging"); err == nil {
+		t.Error("Resolve() should fail for a provider that is not configured")
+	}
+	if _, _, err := r.Resolve("gcp:"); err == nil {
+		t.Error("Resolve() should fail without a profile name")
+	}
+}
+
+func TestRegistryRegisterReplaces(t *testing.T) {
+	r := NewRegistry(&fakeProvider{name: "aws"}, &fakeProvider{name: "gcp"})
+	r.Register(&fakeProvider{name: "gcp", available: true})
+
+	if got := len(r.All()); got != 2 {
+		t.Fatalf("len(All()) = %d, want 2", got)
+	}
+	if p, _ := r.Get("gcp"); !p.Available() {
+		t.Error("Register() should replace the provider with the same name")
+	}
+	if r.Default().Name() != "aws" {
+		t.Errorf("Default() = %s, want aws", r.Default().Name())
+	}
+}
+
+func TestFindProfile(t *testing.T) {
+	p := &fakeProvider{name: "gcp", profiles: []Profile{{Provider: "gcp", Name: "staging"}}}
+	if _, err := FindProfile(p, "staging"); err != nil {
+		t.Errorf("FindProfile() error: %v", err)
+	}
+	if _, err := FindProfile(p, "missing"); err == nil {
+		t.Error("FindProfile() should fail for an unknown profile")
+	}
+}
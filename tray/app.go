@@ -7,15 +7,21 @@ import (
 	"time"
 
 	"rolewalkers/aws"
+	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
 
 	"github.com/getlantern/systray"
 )
 
+// Version is the rw version, reported as the CEF device version of
+// forwarded audit entries. cmd/rw-tray sets it.
+var Version = "dev"
+
 // envItem pairs a systray menu item with its environment for dynamic updates.
 type envItem struct {
-	item *systray.MenuItem
-	env  db.Environment
+	item   *systray.MenuItem
+	env    db.Environment
+	hidden bool // removed from the DB since the menu was built
 }
 
 // app holds the tray application state.
@@ -29,6 +35,24 @@ type app struct {
 	mu     sync.Mutex
 	quit   chan struct{}
 
+	// configVersion is the last seen DB change counter; when it moves the
+	// environment list is reloaded without restarting the tray.
+	configVersion int64
+
+	// profilesChanged is signalled when ~/.aws/config is changed by
+	// something other than this tray, to refresh the menu straight away
+	profilesChanged chan struct{}
+
+	// cs regenerates ~/.aws/config while rw manages it; regenErr is the
+	// last regeneration error, logged once
+	cs       *aws.ConfigSync
+	regenErr string
+
+	// forwarder sends new audit entries to the audit_forward sinks;
+	// forwardErr is the last forwarding error, logged once
+	forwarder  *aws.AuditForwarder
+	forwardErr string
+
 	// Dynamic menu items that get refreshed
 	mStatus  *systray.MenuItem
 	mKube    *systray.MenuItem
@@ -42,7 +66,7 @@ func Run() {
 }
 
 func onReady() {
-	a := &app{quit: make(chan struct{})}
+	a := &app{quit: make(chan struct{}), profilesChanged: make(chan struct{}, 1)}
 
 	WritePIDFile(os.Getpid())
 
@@ -53,6 +77,12 @@ func onReady() {
 		return
 	}
 	a.cm = cm
+	cm.NotifyChanged(func() {
+		select {
+		case a.profilesChanged <- struct{}{}:
+		default:
+		}
+	})
 
 	sm, err := aws.NewSSOManager(cm)
 	if err != nil {
@@ -68,6 +98,14 @@ func onReady() {
 		a.database = database
 		a.dbRepo = db.NewConfigRepository(database)
 		a.km = aws.NewKubeManagerWithRepo(a.dbRepo)
+		a.configVersion, _ = a.dbRepo.GetConfigVersion()
+		// Jobs left running by an rw process that crashed
+		if orphaned, err := aws.InterruptOrphanedJobs(a.dbRepo); err == nil && len(orphaned) > 0 {
+			fmt.Fprintf(os.Stderr, "Marked %d orphaned job(s) as interrupted\n", len(orphaned))
+		}
+		if f := aws.NewAuditForwarder(a.dbRepo, config.Get().AuditForward, Version); f.Enabled() {
+			a.forwarder = f
+		}
 	} else {
 		a.km = aws.NewKubeManager()
 	}
@@ -77,14 +115,23 @@ func onReady() {
 
 	a.buildInitialMenu()
 
-	// Refresh every 15 seconds
+	// Refresh every 15 seconds, and as soon as ~/.aws/config changes: the
+	// watch only stats the file until it does
 	go func() {
 		ticker := time.NewTicker(15 * time.Second)
 		defer ticker.Stop()
+		watch := time.NewTicker(2 * time.Second)
+		defer watch.Stop()
 		for {
 			select {
 			case <-ticker.C:
 				a.refreshMenu()
+				a.forwardAudit()
+			case <-watch.C:
+				_, _ = a.cm.GetProfiles()
+			case <-a.profilesChanged:
+				fmt.Fprintln(os.Stderr, "AWS config changed, refreshing")
+				a.refreshMenu()
 			case <-a.quit:
 				return
 			}
@@ -92,6 +139,23 @@ func onReady() {
 	}()
 }
 
+// forwardAudit sends audit entries recorded since the last tick to the
+// audit_forward sinks. It runs outside a.mu so a slow SIEM doesn't hold up
+// the menu.
+func (a *app) forwardAudit() {
+	if a.forwarder == nil {
+		return
+	}
+	if _, err := a.forwarder.Forward(); err != nil {
+		if err.Error() != a.forwardErr {
+			fmt.Fprintf(os.Stderr, "Failed to forward audit log: %v\n", err)
+		}
+		a.forwardErr = err.Error()
+		return
+	}
+	a.forwardErr = ""
+}
+
 func onExit() {
 	RemovePIDFile()
 }
@@ -2,13 +2,14 @@ package tray
 
 import (
 	"fmt"
-	"math"
 	"os"
 	"strings"
 	"time"
 
+	"rolewalkers/aws"
 	"rolewalkers/internal/config"
 	"rolewalkers/internal/db"
+	"rolewalkers/internal/utils"
 
 	"github.com/getlantern/systray"
 )
@@ -25,6 +26,9 @@ func (a *app) buildInitialMenu() {
 
 	systray.AddSeparator()
 
+	// --- First run ---
+	a.addOnboardingItems()
+
 	// --- Environments ---
 	a.addEnvironmentItems()
 
@@ -66,23 +70,95 @@ func (a *app) addEnvironmentItems() {
 		return
 	}
 
-	for _, e := range envs {
-		env := e // capture
-		item := systray.AddMenuItem("", fmt.Sprintf("Switch to %s (%s)", env.DisplayName, env.Name))
-		a.envItems = append(a.envItems, envItem{item: item, env: env})
+	for _, env := range envs {
+		a.addEnvItem(env)
+	}
+}
 
-		go func() {
-			for {
-				<-item.ClickedCh
-				a.switchEnvironment(env)
-				a.refreshMenu()
-			}
-		}()
+// addEnvItem appends a menu item for an environment. The click handler looks
+// the environment up by index so reloads can replace it in place.
+func (a *app) addEnvItem(env db.Environment) {
+	idx := len(a.envItems)
+	item := systray.AddMenuItem("", fmt.Sprintf("Switch to %s (%s)", env.DisplayName, env.Name))
+	a.envItems = append(a.envItems, envItem{item: item, env: env})
+
+	go func() {
+		for {
+			<-item.ClickedCh
+			a.mu.Lock()
+			current := a.envItems[idx].env
+			a.mu.Unlock()
+			a.switchEnvironment(current)
+			a.refreshMenu()
+		}
+	}()
+}
+
+// reloadIfChanged re-reads environments from the database when the config
+// change counter has moved (e.g. after 'rw setup' or 'rw config sync').
+// systray can only append items, so existing items are updated in place,
+// surplus items are hidden and new environments are added at the bottom.
+// Caller must hold a.mu.
+func (a *app) reloadIfChanged() {
+	if a.dbRepo == nil {
+		return
+	}
+
+	version, err := a.dbRepo.GetConfigVersion()
+	if err != nil || version == a.configVersion {
+		return
+	}
+
+	envs, err := a.dbRepo.GetAllEnvironments()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to reload environments: %v\n", err)
+		return
+	}
+	a.configVersion = version
+
+	for i, env := range envs {
+		if i < len(a.envItems) {
+			a.envItems[i].env = env
+			a.envItems[i].item.SetTooltip(fmt.Sprintf("Switch to %s (%s)", env.DisplayName, env.Name))
+			a.envItems[i].hidden = false
+			a.envItems[i].item.Show()
+		} else {
+			a.addEnvItem(env)
+		}
+	}
+	for i := len(envs); i < len(a.envItems); i++ {
+		a.envItems[i].hidden = true
+		a.envItems[i].item.Hide()
+	}
+
+	fmt.Fprintf(os.Stderr, "Configuration changed, reloaded %d environments\n", len(envs))
+}
+
+// expireEnvironments cleans up ephemeral environments whose TTL has passed.
+// Deactivation bumps the config version, so reloadIfChanged drops them from
+// the menu. Caller must hold a.mu.
+func (a *app) expireEnvironments() {
+	if a.dbRepo == nil {
+		return
+	}
+
+	var tm aws.TunnelManagerI
+	if t, err := aws.NewTunnelManagerWithDeps(a.km, aws.NewSSMManagerWithRepo(a.dbRepo), a.ps, a.dbRepo); err == nil {
+		tm = t
+	}
+
+	cleanups, err := aws.ExpireEnvironments(a.dbRepo, tm, a.km, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to expire environments: %v\n", err)
+	}
+	for _, cl := range cleanups {
+		fmt.Fprintf(os.Stderr, "Ephemeral environment %s expired and was deactivated\n", cl.Environment)
 	}
 }
 
 // switchEnvironment handles switching to an environment from the tray.
-// It switches the AWS profile (if needed) and then the kube context.
+// It logs in if needed, then switches the AWS profile and kube context as
+// one transaction.
 func (a *app) switchEnvironment(env db.Environment) {
 	profileName := env.AWSProfile
 
@@ -98,17 +174,17 @@ func (a *app) switchEnvironment(env db.Environment) {
 		fmt.Fprintf(os.Stderr, "SSO login successful for %s\n", profileName)
 	}
 
-	// Switch AWS profile
-	if err := a.ps.SwitchProfile(profileName); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to switch profile to %s: %v\n", profileName, err)
+	// Switch the AWS profile and the environment's kube context together,
+	// rolling both back if the context can't be applied
+	outcome, err := aws.SwitchProfileAndContext(a.ps, a.km, profileName, aws.SwitchOptions{Environment: env.Name})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to switch to %s: %v\n", env.DisplayName, err)
+		if outcome != nil && outcome.Incomplete {
+			fmt.Fprintln(os.Stderr, "Run 'rw repair' to restore the previous state")
+		}
 		return
 	}
 
-	// Switch kube context to the environment's specific cluster
-	if err := a.km.SwitchContextForEnv(env.Name); err != nil {
-		fmt.Fprintf(os.Stderr, "Kube context switch to %s failed: %v\n", env.ClusterName, err)
-	}
-
 	fmt.Fprintf(os.Stderr, "Switched to: %s (profile: %s, cluster: %s)\n",
 		env.DisplayName, profileName, env.ClusterName)
 }
@@ -117,9 +193,42 @@ func (a *app) switchEnvironment(env db.Environment) {
 func (a *app) refreshMenu() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	a.expireEnvironments()
+	a.reloadIfChanged()
+	a.regenerateAWSConfig()
 	a.refreshLabels()
 }
 
+// regenerateAWSConfig keeps ~/.aws/config in step with the database while
+// rw manages it (see 'rw config manage'). A validation failure is logged
+// once, not on every refresh. Caller must hold a.mu.
+func (a *app) regenerateAWSConfig() {
+	if a.dbRepo == nil || !aws.AWSConfigManaged() {
+		return
+	}
+	if a.cs == nil {
+		cs, err := aws.NewConfigSync(a.dbRepo)
+		if err != nil {
+			return
+		}
+		a.cs = cs
+	}
+
+	written, err := a.cs.RegenerateAWSConfig()
+	if err != nil {
+		if err.Error() != a.regenErr {
+			fmt.Fprintf(os.Stderr, "Failed to regenerate AWS config: %v\n", err)
+		}
+		a.regenErr = err.Error()
+		return
+	}
+	a.regenErr = ""
+	if written {
+		a.cm.Invalidate()
+		fmt.Fprintf(os.Stderr, "Regenerated %s from the database\n", a.cs.GetConfigPath())
+	}
+}
+
 // refreshLabels updates the tray title and all menu item labels.
 func (a *app) refreshLabels() {
 	active := a.cm.GetActiveProfile()
@@ -144,24 +253,25 @@ func (a *app) refreshLabels() {
 
 	// Kube context
 	kubeCtx := "(none)"
-	if ctx, err := a.km.GetCurrentContext(); err == nil && ctx != "" {
+	ctx, kubeNS := aws.CachedKubeContext()
+	if ctx != "" {
 		if strings.Contains(ctx, "/") {
 			parts := strings.Split(ctx, "/")
 			ctx = parts[len(parts)-1]
 		}
 		kubeCtx = ctx
 	}
-	kubeNS := a.km.GetCurrentNamespace()
-	if kubeNS == "" {
-		kubeNS = "default"
-	}
 	a.mKube.SetTitle(fmt.Sprintf("⎈ %s / %s", kubeCtx, kubeNS))
 
-	// Environment items
+	// Environment items — SSO state for all of them from one cache scan
+	var logins map[string]aws.LoginStatus
+	if a.sm != nil {
+		logins, _ = a.sm.LoginStatuses()
+	}
 	for i := range a.envItems {
 		ei := &a.envItems[i]
 		isActive := (ei.env.Name == activeEnv)
-		ei.item.SetTitle(a.formatEnvLabel(ei.env, isActive))
+		ei.item.SetTitle(a.formatEnvLabel(ei.env, isActive, logins))
 	}
 
 	// Namespace items
@@ -186,7 +296,7 @@ func (a *app) resolveActiveEnv() string {
 	}
 
 	for _, ei := range a.envItems {
-		if strings.Contains(ctx, ei.env.ClusterName) {
+		if !ei.hidden && strings.Contains(ctx, ei.env.ClusterName) {
 			return ei.env.Name
 		}
 	}
@@ -197,7 +307,7 @@ func (a *app) resolveActiveEnv() string {
 		ctx = parts[len(parts)-1]
 	}
 	for _, ei := range a.envItems {
-		if strings.HasPrefix(ctx, ei.env.Name+"-") {
+		if !ei.hidden && strings.HasPrefix(ctx, ei.env.Name+"-") {
 			return ei.env.Name
 		}
 	}
@@ -206,7 +316,7 @@ func (a *app) resolveActiveEnv() string {
 }
 
 // formatEnvLabel builds the display label for an environment menu item.
-func (a *app) formatEnvLabel(env db.Environment, isActive bool) string {
+func (a *app) formatEnvLabel(env db.Environment, isActive bool, logins map[string]aws.LoginStatus) string {
 	label := fmt.Sprintf("%s (%s)", env.DisplayName, env.Name)
 	if isActive {
 		label = "✓ " + label
@@ -216,13 +326,8 @@ func (a *app) formatEnvLabel(env db.Environment, isActive bool) string {
 
 	// SSO status — check the profile this environment uses
 	if a.sm != nil {
-		if a.sm.IsLoggedIn(env.AWSProfile) {
-			remaining := a.getSessionTimeLeft(env.AWSProfile)
-			if remaining != "" {
-				label += fmt.Sprintf("  [%s]", remaining)
-			} else {
-				label += "  [SSO ✓]"
-			}
+		if login := logins[env.AWSProfile]; login.LoggedIn {
+			label += fmt.Sprintf("  [%s]", sessionTimeLeft(login.ExpiresAt))
 		} else {
 			label += "  [SSO ✗]"
 		}
@@ -231,32 +336,13 @@ func (a *app) formatEnvLabel(env db.Environment, isActive bool) string {
 	return label
 }
 
-// getSessionTimeLeft returns a human-readable string of time remaining.
-func (a *app) getSessionTimeLeft(profileName string) string {
-	if a.sm == nil {
-		return ""
-	}
-
-	expiry, err := a.sm.GetCredentialExpiry(profileName)
-	if err != nil || expiry == nil {
-		return ""
-	}
-
-	remaining := time.Until(*expiry)
+// sessionTimeLeft returns a human-readable string of time remaining.
+func sessionTimeLeft(expiry time.Time) string {
+	remaining := time.Until(expiry)
 	if remaining <= 0 {
 		return "expired"
 	}
-
-	hours := int(math.Floor(remaining.Hours()))
-	minutes := int(math.Floor(remaining.Minutes())) % 60
-
-	if hours > 0 {
-		return fmt.Sprintf("%dh %dm left", hours, minutes)
-	}
-	if minutes > 0 {
-		return fmt.Sprintf("%dm left", minutes)
-	}
-	return "< 1m left"
+	return utils.FormatDuration(remaining) + " left"
 }
 
 // addKubeSection adds namespace quick-switch items.
@@ -0,0 +1,174 @@
+package tray
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"rolewalkers/aws"
+	"rolewalkers/internal/config"
+	"rolewalkers/internal/utils"
+
+	"github.com/getlantern/systray"
+)
+
+// addOnboardingItems shows first-run steps when the database has no
+// accounts yet, mirroring 'rw setup' and 'rw bootstrap':
+//  1. import the profiles in ~/.aws/config
+//  2. log in to the SSO start URL found there with the device code flow
+//     and discover its accounts and roles, like 'rw setup', merging the
+//     new profiles into ~/.aws/config
+//  3. configure environments from EKS clusters and kube contexts and
+//     generate their port mappings, like 'rw bootstrap'
+//
+// A tray menu can't ask for a start URL, so without one in ~/.aws/config the
+// menu points to 'rw setup' instead. Nothing is added once accounts exist.
+func (a *app) addOnboardingItems() {
+	if a.dbRepo == nil {
+		return
+	}
+	cs, err := aws.NewConfigSync(a.dbRepo)
+	if err != nil || cs.HasExistingData() {
+		return
+	}
+
+	header := systray.AddMenuItem("Get started with rolewalkers", "No AWS accounts configured yet")
+	header.Disable()
+
+	if !cs.ConfigFileExists() {
+		hint := systray.AddMenuItem("  No ~/.aws/config found: run 'rw setup' in a terminal",
+			"rw setup discovers your SSO accounts, roles and EKS clusters")
+		hint.Disable()
+		systray.AddSeparator()
+		return
+	}
+
+	// steps serialises the onboarding steps: each runs aws CLI commands
+	// that can take minutes, so a second click while one runs is ignored
+	var steps sync.Mutex
+
+	analysis, err := cs.AnalyzeSync()
+	if err == nil && analysis.Imported > 0 {
+		mImport := systray.AddMenuItem(fmt.Sprintf("  1. Import %d profiles from ~/.aws/config", analysis.Imported),
+			"Import SSO profiles into rolewalkers, like 'rw config sync'")
+		go func() {
+			for range mImport.ClickedCh {
+				if !steps.TryLock() {
+					continue
+				}
+				a.mu.Lock()
+				result, err := cs.SyncConfigToDB()
+				a.mu.Unlock()
+				steps.Unlock()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+					mImport.SetTitle("  1. Import failed, see 'rw config sync'")
+					continue
+				}
+				for _, e := range result.Errors {
+					fmt.Fprintf(os.Stderr, "Import: %s\n", e)
+				}
+				fmt.Fprintf(os.Stderr, "Imported %d profiles from ~/.aws/config\n", result.Imported)
+				mImport.SetTitle(fmt.Sprintf("  1. Imported %d profiles", result.Imported))
+				mImport.Disable()
+				a.refreshMenu()
+				return
+			}
+		}()
+	}
+
+	startURL, ssoRegion := ssoStartURL(a.cm)
+	if startURL == "" {
+		hint := systray.AddMenuItem("  2. No SSO start URL in ~/.aws/config: run 'rw setup' in a terminal",
+			"rw setup discovers your SSO accounts, roles and EKS clusters")
+		hint.Disable()
+	} else {
+		mDiscover := systray.AddMenuItem("  2. Log in and discover SSO accounts",
+			fmt.Sprintf("Log in to %s and add every account and role, like 'rw setup'", startURL))
+		go func() {
+			for range mDiscover.ClickedCh {
+				if !steps.TryLock() {
+					continue
+				}
+				mDiscover.SetTitle("  2. Starting SSO login...")
+				// The device code flow needs no terminal, and merging keeps
+				// the profiles already in ~/.aws/config
+				result, err := aws.NewSetupManager(a.dbRepo).LoginAndDiscoverWithOptions(startURL, ssoRegion, aws.DiscoverOptions{
+					ShowDeviceCode: func(auth aws.DeviceAuthorization) {
+						mDiscover.SetTitle(fmt.Sprintf("  2. Confirm code %s in your browser...", auth.UserCode))
+						if err := utils.OpenBrowser(auth.URL()); err != nil {
+							fmt.Fprintf(os.Stderr, "Open %s and confirm the code %s\n", auth.URL(), auth.UserCode)
+						}
+					},
+					Merge: true,
+					Lock:  &a.mu,
+				})
+				steps.Unlock()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "SSO discovery failed: %v\n", err)
+					mDiscover.SetTitle("  2. Discovery failed, retry or run 'rw setup'")
+					continue
+				}
+				for _, e := range result.Errors {
+					fmt.Fprintf(os.Stderr, "SSO discovery: %s\n", e)
+				}
+				config.WriteDefault()
+				a.cm.Invalidate()
+				mDiscover.SetTitle(fmt.Sprintf("  2. Discovered %d accounts, %d roles", result.Accounts, result.Roles))
+				mDiscover.Disable()
+				a.refreshMenu()
+				return
+			}
+		}()
+	}
+
+	mEnvs := systray.AddMenuItem("  3. Configure environments from EKS clusters",
+		"Add an environment per EKS cluster and generate port mappings, like 'rw bootstrap'")
+	go func() {
+		for range mEnvs.ClickedCh {
+			if !steps.TryLock() {
+				continue
+			}
+			mEnvs.SetTitle("  3. Configuring environments...")
+			a.mu.Lock()
+			result, err := aws.NewSetupManager(a.dbRepo).Bootstrap(true)
+			a.mu.Unlock()
+			steps.Unlock()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Environment setup failed: %v\n", err)
+				mEnvs.SetTitle("  3. Environment setup failed, retry or run 'rw bootstrap'")
+				continue
+			}
+			for _, e := range result.Errors {
+				fmt.Fprintf(os.Stderr, "Environment setup: %s\n", e)
+			}
+			config.WriteDefault()
+			a.cm.Invalidate()
+			header.SetTitle("rolewalkers is set up")
+			mEnvs.SetTitle(fmt.Sprintf("  3. Added %d environments, %d port mappings", result.Environments, result.PortMappings))
+			mEnvs.Disable()
+			a.refreshMenu()
+			return
+		}
+	}()
+	systray.AddSeparator()
+}
+
+// ssoStartURL returns the SSO start URL and region of the first SSO profile
+// in ~/.aws/config, or "" when there is none
+func ssoStartURL(cm *aws.ConfigManager) (string, string) {
+	profiles, err := cm.GetProfiles()
+	if err != nil {
+		return "", ""
+	}
+	for _, p := range profiles {
+		if p.IsSSO && p.SSOStartURL != "" {
+			region := p.SSORegion
+			if region == "" {
+				region = config.Get().Region
+			}
+			return p.SSOStartURL, region
+		}
+	}
+	return "", ""
+}